@@ -0,0 +1,522 @@
+package gridon
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewBacktestService - 新しいバックテストサービスの取得
+func NewBacktestService() IBacktestService {
+	return &backtestService{}
+}
+
+// IBacktestService - バックテストサービスのインターフェース
+type IBacktestService interface {
+	Run(config BacktestConfig) (*BacktestResult, error)
+}
+
+// BacktestConfig - バックテストの設定
+type BacktestConfig struct {
+	Strategy   Strategy        // 検証する戦略
+	FourPrices []*FourPrice    // バックテスト開始前までの四本値
+	Prices     []BacktestPrice // 再生する価格
+}
+
+// BacktestPrice - バックテストで再生する価格
+type BacktestPrice struct {
+	DateTime time.Time // 日時
+	Price    float64   // 価格
+}
+
+// BacktestResult - バックテストの結果
+type BacktestResult struct {
+	Strategy    *Strategy   // バックテスト終了時の戦略
+	Cash        float64     // バックテスト終了時の運用中現金
+	Positions   []*Position // バックテスト終了時に保有しているポジション
+	Orders      []*Order    // バックテスト中に出した全ての注文
+	Contracts   []Contract  // バックテスト中の全ての約定
+	NetProfit   float64     // 純損益(終了時の評価額 - 開始時の運用中現金) 手数料等の費用は引かれている
+	Fee         float64     // 手数料等の費用の合計
+	MaxDrawdown float64     // 最大ドローダウン(評価額の最大値からの最大下落幅)
+	TradeCount  int         // 約定回数
+	Warnings    []string    // バックテスト中に各処理が返したエラー
+}
+
+// backtestService - バックテストサービス
+type backtestService struct{}
+
+// backtestRunner - 1回分のバックテストの実行に必要な状態
+type backtestRunner struct {
+	strategyCode     string
+	clock            *backtestClock
+	kabusAPI         *backtestKabusAPI
+	db               *backtestDB
+	strategyStore    IStrategyStore
+	orderStore       *orderStore
+	positionStore    *positionStore
+	fourPriceStore   IFourPriceStore
+	contractService  IContractService
+	gridService      IGridService
+	orderService     IOrderService
+	rebalanceService IRebalanceService
+	riskExitService  IRiskExitService
+	initialCash      float64
+	lastEquity       float64
+	maxEquity        float64
+	maxDrawdown      float64
+	warnings         []string
+}
+
+// Run - バックテストの実行
+// 価格を時系列順に再生し、本番と同じように約定確認、グリッド、リバランス、全取消、全エグジットを動かす
+func (s *backtestService) Run(config BacktestConfig) (*BacktestResult, error) {
+	if config.Strategy.Code == "" || len(config.Prices) == 0 {
+		return nil, ErrNilArgument
+	}
+
+	prices := make([]BacktestPrice, len(config.Prices))
+	copy(prices, config.Prices)
+	sort.SliceStable(prices, func(i, j int) bool {
+		return prices[i].DateTime.Before(prices[j].DateTime)
+	})
+
+	runner := newBacktestRunner(config.Strategy, config.FourPrices, prices[0])
+	runner.replay(prices)
+	return runner.result()
+}
+
+// newBacktestRunner - バックテスト用のサービス群を組み立てる
+func newBacktestRunner(strategy Strategy, fourPrices []*FourPrice, first BacktestPrice) *backtestRunner {
+	logger := &logger{
+		notice:   log.New(io.Discard, "", 0),
+		warning:  log.New(io.Discard, "", 0),
+		cashFlow: log.New(io.Discard, "", 0),
+	}
+	clock := &backtestClock{now: first.DateTime}
+	db := newBacktestDB(fourPrices)
+	kabusAPI := newBacktestKabusAPI(clock, strategy)
+
+	strategyStore := &strategyStore{store: map[string]*Strategy{strategy.Code: &strategy}, db: db, logger: logger}
+	orderStore := &orderStore{db: db, store: map[string]*Order{}}
+	positionStore := &positionStore{db: db, store: map[string]*Position{}}
+	fourPriceStore := &fourPriceStore{db: db, store: map[SymbolKey]*FourPrice{}}
+	// バックテストでは当日の損益を評価しないため、リスク管理は注文数と約定代金の上限だけを確認する
+	riskManager := newRiskManager(clock, kabusAPI, strategyStore, orderStore, positionStore, &tradeStore{db: db}, &riskSettingStore{db: db}, logger)
+	orderService := newOrderService(clock, kabusAPI, strategyStore, orderStore, positionStore, riskManager, logger)
+	priceBandService := newPriceBandService(clock, strategyStore, fourPriceStore, logger)
+	trendFilterService := newTrendFilterService(clock, strategyStore, fourPriceStore, logger)
+
+	return &backtestRunner{
+		strategyCode:     strategy.Code,
+		clock:            clock,
+		kabusAPI:         kabusAPI,
+		db:               db,
+		strategyStore:    strategyStore,
+		orderStore:       orderStore,
+		positionStore:    positionStore,
+		fourPriceStore:   fourPriceStore,
+		contractService:  newContractService(kabusAPI, strategyStore, orderStore, positionStore, &tradeStore{db: db}, &feeStore{db: db}, clock, priceBandService),
+		gridService:      newGridService(clock, newTick(), kabusAPI, orderService, strategyStore, fourPriceStore, positionStore, priceBandService, trendFilterService),
+		orderService:     orderService,
+		rebalanceService: newRebalanceService(clock, kabusAPI, positionStore, orderService),
+		riskExitService:  newRiskExitService(clock, kabusAPI, strategyStore, positionStore, orderService, logger),
+		initialCash:      strategy.Cash,
+		lastEquity:       strategy.Cash,
+		maxEquity:        strategy.Cash,
+		warnings:         []string{},
+	}
+}
+
+// replay - 価格を順に再生する
+func (r *backtestRunner) replay(prices []BacktestPrice) {
+	last := prices[0]
+	r.kabusAPI.setPrice(last)
+
+	// 最初の価格の日時以降で最初の0秒から注文のタスクを実行していく
+	nextMinute := last.DateTime.Truncate(time.Minute)
+	if nextMinute.Before(last.DateTime) {
+		nextMinute = nextMinute.Add(time.Minute)
+	}
+
+	for i, p := range prices {
+		// 日付が変わったら前日の引け処理をする
+		if i > 0 && !r.isSameDay(last.DateTime, p.DateTime) {
+			r.closeDay(last)
+			r.kabusAPI.setPrice(p)
+		}
+
+		// 今回の価格までにある分の切り替わりで注文のタスクを実行する
+		for ; !nextMinute.After(p.DateTime); nextMinute = nextMinute.Add(time.Minute) {
+			r.clock.now = nextMinute
+			r.orderTask()
+		}
+
+		// 価格を進めて約定させてから、約定確認のタスクを実行する
+		r.clock.now = p.DateTime
+		r.kabusAPI.setPrice(p)
+		r.contractTask()
+		r.recordEquity(p.Price)
+		last = p
+	}
+	r.closeDay(last)
+}
+
+// isSameDay - 2つの日時が同じ日付かどうか
+func (r *backtestRunner) isSameDay(a time.Time, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
+
+// contractTask - 約定確認のタスク
+func (r *backtestRunner) contractTask() {
+	strategy, err := r.strategyStore.GetByCode(r.strategyCode)
+	if err != nil {
+		r.warn("戦略取得", err)
+		return
+	}
+
+	if err := r.contractService.Confirm(strategy); err != nil {
+		r.warn("約定確認処理", err)
+		return
+	}
+
+	if err := r.contractService.ConfirmGridEnd(strategy); err != nil {
+		r.warn("グリッド終了時約定確認処理", err)
+		return
+	}
+
+	if err := r.riskExitService.Check(strategy); err != nil {
+		r.warn("損切り・利確処理", err)
+		return
+	}
+
+	if err := r.gridService.Leveling(strategy); err != nil {
+		r.warn("グリッド処理", err)
+	}
+}
+
+// orderTask - 注文のタスク
+func (r *backtestRunner) orderTask() {
+	strategy, err := r.strategyStore.GetByCode(r.strategyCode)
+	if err != nil {
+		r.warn("戦略取得", err)
+		return
+	}
+
+	if err := r.rebalanceService.Rebalance(strategy); err != nil {
+		r.warn("リバランス処理", err)
+	}
+
+	if err := r.orderService.CancelAll(strategy); err != nil {
+		r.warn("全取消処理", err)
+	}
+
+	if err := r.orderService.ExitAll(strategy); err != nil {
+		r.warn("全エグジット処理", err)
+	}
+}
+
+// closeDay - 引け処理
+// 引成注文を約定させ、残った注文を失効させて約定確認をし、その日の四本値を保存する
+func (r *backtestRunner) closeDay(last BacktestPrice) {
+	closing := time.Date(last.DateTime.Year(), last.DateTime.Month(), last.DateTime.Day(), 15, 0, 0, 0, last.DateTime.Location())
+	if closing.Before(last.DateTime) {
+		closing = last.DateTime
+	}
+
+	r.clock.now = closing
+	r.kabusAPI.book.Match(r.kabusAPI.symbol.Code, r.kabusAPI.symbol.Exchange, last.Price, closing)
+	r.kabusAPI.book.Expire(closing)
+
+	if strategy, err := r.strategyStore.GetByCode(r.strategyCode); err == nil {
+		if err := r.contractService.Confirm(strategy); err != nil {
+			r.warn("引け後約定確認処理", err)
+		}
+	}
+
+	// fourPriceStoreのDB保存は非同期なので、翌日以降の計算で確実に参照できるよう先にDBに保存しておく
+	fourPrice := r.kabusAPI.fourPrice
+	fourPrice.DateTime = closing
+	_ = r.db.SaveFourPrice(&fourPrice)
+	_ = r.fourPriceStore.Save(&fourPrice)
+
+	r.recordEquity(last.Price)
+}
+
+// equity - 指定した価格での評価額
+// 運用中現金に、保有しているポジションを指定した価格でエグジットした場合に戻ってくる現金を加える
+func (r *backtestRunner) equity(price float64) float64 {
+	strategy, err := r.strategyStore.GetByCode(r.strategyCode)
+	if err != nil {
+		return r.lastEquity
+	}
+
+	positions, err := r.positionStore.GetActivePositionsByStrategyCode(r.strategyCode)
+	if err != nil {
+		return r.lastEquity
+	}
+
+	equity := strategy.Cash
+	for _, p := range positions {
+		switch p.Side {
+		case SideBuy:
+			equity += price * p.OwnedQuantity
+		case SideSell:
+			equity += (p.Price + p.Price - price) * p.OwnedQuantity
+		}
+	}
+	return equity
+}
+
+// recordEquity - 評価額を記録し、最大ドローダウンを更新する
+func (r *backtestRunner) recordEquity(price float64) {
+	r.lastEquity = r.equity(price)
+	if r.maxEquity < r.lastEquity {
+		r.maxEquity = r.lastEquity
+	}
+	if r.maxDrawdown < r.maxEquity-r.lastEquity {
+		r.maxDrawdown = r.maxEquity - r.lastEquity
+	}
+}
+
+// warn - 処理のエラーを記録する
+func (r *backtestRunner) warn(process string, err error) {
+	r.warnings = append(r.warnings, fmt.Sprintf("%s %s でエラーが発生しました: %s", r.clock.Now().Format("2006-01-02 15:04:05"), process, err))
+}
+
+// result - バックテストの結果をまとめる
+func (r *backtestRunner) result() (*BacktestResult, error) {
+	strategy, err := r.strategyStore.GetByCode(r.strategyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	positions, err := r.positionStore.GetActivePositionsByStrategyCode(r.strategyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	r.orderStore.mtx.Lock()
+	orders := make([]*Order, 0)
+	for _, o := range r.orderStore.store {
+		orders = append(orders, o)
+	}
+	r.orderStore.mtx.Unlock()
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].Code < orders[j].Code
+	})
+
+	contracts := make([]Contract, 0)
+	for _, o := range orders {
+		contracts = append(contracts, o.Contracts...)
+	}
+	sort.SliceStable(contracts, func(i, j int) bool {
+		return contracts[i].ContractDateTime.Before(contracts[j].ContractDateTime)
+	})
+
+	var fee float64
+	r.db.mtx.Lock()
+	for _, f := range r.db.fees {
+		fee += f.Fee
+	}
+	r.db.mtx.Unlock()
+
+	return &BacktestResult{
+		Strategy:    strategy,
+		Cash:        strategy.Cash,
+		Positions:   positions,
+		Orders:      orders,
+		Contracts:   contracts,
+		NetProfit:   r.lastEquity - r.initialCash,
+		Fee:         fee,
+		MaxDrawdown: r.maxDrawdown,
+		TradeCount:  len(contracts),
+		Warnings:    r.warnings,
+	}, nil
+}
+
+// backtestClock - バックテスト用の時計
+// 現在時刻だけをバックテストの再生時刻にし、それ以外の計算は通常の時計と同じにする
+type backtestClock struct {
+	clock
+	now time.Time
+}
+
+func (c *backtestClock) Now() time.Time {
+	return c.now
+}
+
+// newBacktestKabusAPI - バックテスト用のkabusAPIの取得
+func newBacktestKabusAPI(clock IClock, strategy Strategy) *backtestKabusAPI {
+	return &backtestKabusAPI{
+		clock: clock,
+		book:  newOrderBook("backtest"),
+		symbol: Symbol{
+			Code:        strategy.SymbolCode,
+			Exchange:    strategy.Exchange,
+			TradingUnit: strategy.TradingUnit,
+			TickGroup:   strategy.TickGroup,
+		},
+		fourPrice: FourPrice{SymbolCode: strategy.SymbolCode, Exchange: strategy.Exchange},
+	}
+}
+
+// backtestKabusAPI - バックテスト用のkabusAPI
+// 再生中の価格を現在値とし、注文は疑似注文板で約定させる
+type backtestKabusAPI struct {
+	clock     IClock
+	book      *orderBook
+	symbol    Symbol
+	fourPrice FourPrice
+}
+
+// setPrice - 現在値を更新し、疑似注文板の約定判定をする
+func (k *backtestKabusAPI) setPrice(price BacktestPrice) {
+	// 日付が変わったら四本値を作り直す
+	if k.fourPrice.Open == 0 || k.fourPrice.DateTime.Format("20060102") != price.DateTime.Format("20060102") {
+		k.fourPrice = FourPrice{SymbolCode: k.symbol.Code, Exchange: k.symbol.Exchange, Open: price.Price, High: price.Price, Low: price.Price}
+	}
+	if k.fourPrice.High < price.Price {
+		k.fourPrice.High = price.Price
+	}
+	if price.Price < k.fourPrice.Low {
+		k.fourPrice.Low = price.Price
+	}
+	k.fourPrice.Close = price.Price
+	k.fourPrice.DateTime = price.DateTime
+
+	k.symbol.CurrentPrice = price.Price
+	k.symbol.OpeningPrice = k.fourPrice.Open
+	k.symbol.CurrentPriceDateTime = price.DateTime
+	k.symbol.BidPrice = price.Price
+	k.symbol.AskPrice = price.Price
+
+	k.book.Match(k.symbol.Code, k.symbol.Exchange, price.Price, price.DateTime)
+}
+
+func (k *backtestKabusAPI) GetSymbol(string, Exchange) (*Symbol, error) {
+	symbol := k.symbol
+	return &symbol, nil
+}
+
+func (k *backtestKabusAPI) GetOrders(product Product, symbolCode string, updateDateTime time.Time) ([]SecurityOrder, error) {
+	return k.book.Orders(product, symbolCode, updateDateTime), nil
+}
+
+func (k *backtestKabusAPI) CancelOrder(_ string, orderCode string) (OrderResult, error) {
+	return k.book.Cancel(orderCode, k.clock.Now())
+}
+
+func (k *backtestKabusAPI) SendOrder(strategy *Strategy, order *Order) (OrderResult, error) {
+	if strategy == nil || order == nil {
+		return OrderResult{}, ErrNilArgument
+	}
+	return k.book.Send(order, k.clock.Now(), k.symbol.BidPrice, k.symbol.AskPrice)
+}
+
+func (k *backtestKabusAPI) GetFourPrice(string, Exchange) (*FourPrice, error) {
+	fourPrice := k.fourPrice
+	return &fourPrice, nil
+}
+
+func (k *backtestKabusAPI) GetPositions(product Product, symbolCode string) ([]SecurityPosition, error) {
+	return k.book.Positions(product, symbolCode), nil
+}
+
+// newBacktestDB - バックテスト用のDBの取得
+func newBacktestDB(fourPrices []*FourPrice) *backtestDB {
+	db := &backtestDB{fourPrices: []*FourPrice{}}
+	for _, fp := range fourPrices {
+		if fp != nil {
+			_ = db.SaveFourPrice(fp)
+		}
+	}
+	return db
+}
+
+// backtestDB - バックテスト用のDB
+// 状態は各ストアが持っているので永続化はせず、過去データの参照が必要な四本値だけをメモリ上に保持する
+type backtestDB struct {
+	fourPrices []*FourPrice
+	fees       []*Fee
+	mtx        sync.Mutex
+}
+
+func (d *backtestDB) GetStrategies() ([]*Strategy, error)              { return []*Strategy{}, nil }
+func (d *backtestDB) SaveStrategy(*Strategy) error                     { return nil }
+func (d *backtestDB) DeleteStrategyByCode(string) error                { return nil }
+func (d *backtestDB) GetActiveOrders() ([]*Order, error)               { return []*Order{}, nil }
+func (d *backtestDB) SaveOrder(*Order) error                           { return nil }
+func (d *backtestDB) DeleteOrderByCode(string) error                   { return nil }
+func (d *backtestDB) GetActivePositions() ([]*Position, error)         { return []*Position{}, nil }
+func (d *backtestDB) SavePosition(*Position) error                     { return nil }
+func (d *backtestDB) CleanupOrders() error                             { return nil }
+func (d *backtestDB) CleanupPositions() error                          { return nil }
+func (d *backtestDB) GetTrades(time.Time, time.Time) ([]*Trade, error) { return []*Trade{}, nil }
+func (d *backtestDB) SaveTrade(*Trade) error                           { return nil }
+func (d *backtestDB) GetEquitySnapshotsByStrategyCode(string) ([]*EquitySnapshot, error) {
+	return []*EquitySnapshot{}, nil
+}
+func (d *backtestDB) SaveEquitySnapshot(*EquitySnapshot) error { return nil }
+func (d *backtestDB) GetRiskSetting() (*RiskSetting, error)    { return nil, ErrNoData }
+func (d *backtestDB) SaveRiskSetting(*RiskSetting) error       { return nil }
+
+// GetFees - 計上日時がfrom以降to未満の費用を取得する
+// 段階制の手数料の計算で使うので、費用だけはメモリに持っておく
+func (d *backtestDB) GetFees(from time.Time, to time.Time) ([]*Fee, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	result := make([]*Fee, 0)
+	for _, f := range d.fees {
+		if !f.DateTime.Before(from) && f.DateTime.Before(to) {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+// SaveFee - 費用を保存する
+func (d *backtestDB) SaveFee(fee *Fee) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.fees = append(d.fees, fee)
+	return nil
+}
+
+// GetFourPriceBySymbolCodeAndExchange - 四本値を銘柄検索し、後ろからnum本取得する
+func (d *backtestDB) GetFourPriceBySymbolCodeAndExchange(symbolCode string, exchange Exchange, num int) ([]*FourPrice, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	result := make([]*FourPrice, 0)
+	for i := len(d.fourPrices) - 1; i >= 0 && len(result) < num; i-- {
+		if d.fourPrices[i].SymbolCode == symbolCode && d.fourPrices[i].Exchange == exchange {
+			result = append(result, d.fourPrices[i])
+		}
+	}
+	return result, nil
+}
+
+// SaveFourPrice - 四本値の保存
+// 日時順に並ぶように保存し、同じ銘柄・日時のデータは上書きする
+func (d *backtestDB) SaveFourPrice(fourPrice *FourPrice) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	for i, fp := range d.fourPrices {
+		if fp.SymbolCode == fourPrice.SymbolCode && fp.Exchange == fourPrice.Exchange && fp.DateTime.Equal(fourPrice.DateTime) {
+			d.fourPrices[i] = fourPrice
+			return nil
+		}
+	}
+	d.fourPrices = append(d.fourPrices, fourPrice)
+	sort.SliceStable(d.fourPrices, func(i, j int) bool {
+		return d.fourPrices[i].DateTime.Before(d.fourPrices[j].DateTime)
+	})
+	return nil
+}
@@ -0,0 +1,72 @@
+package gridon
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ReadBacktestPrices - 日時(2006-01-02 15:04:05),価格 のCSVからバックテストで再生する価格を読み込む
+func ReadBacktestPrices(r io.Reader) ([]BacktestPrice, error) {
+	prices := make([]BacktestPrice, 0)
+	err := readCSV(r, func(record []string) error {
+		dt, err := time.ParseInLocation("2006-01-02 15:04:05", record[0], time.Local)
+		if err != nil {
+			return err
+		}
+		price, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return err
+		}
+		prices = append(prices, BacktestPrice{DateTime: dt, Price: price})
+		return nil
+	})
+	return prices, err
+}
+
+// ReadFourPrices - 日付(2006-01-02),始値,高値,安値,終値 のCSVから四本値を読み込む
+// 四本値の日時はその日の大引けの時刻にする
+func ReadFourPrices(r io.Reader, symbolCode string, exchange Exchange) ([]*FourPrice, error) {
+	fourPrices := make([]*FourPrice, 0)
+	err := readCSV(r, func(record []string) error {
+		d, err := time.ParseInLocation("2006-01-02", record[0], time.Local)
+		if err != nil {
+			return err
+		}
+		values := make([]float64, 4)
+		for i := range values {
+			if values[i], err = strconv.ParseFloat(record[i+1], 64); err != nil {
+				return err
+			}
+		}
+		fourPrices = append(fourPrices, &FourPrice{
+			SymbolCode: symbolCode,
+			Exchange:   exchange,
+			DateTime:   d.Add(15 * time.Hour),
+			Open:       values[0],
+			High:       values[1],
+			Low:        values[2],
+			Close:      values[3],
+		})
+		return nil
+	})
+	return fourPrices, err
+}
+
+// readCSV - CSVを1行ずつ読み込んでfnに渡す
+func readCSV(r io.Reader, fn func(record []string) error) error {
+	cr := csv.NewReader(r)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package gridon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ReadBacktestPrices(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		arg     string
+		want1   []BacktestPrice
+		wantErr bool
+	}{
+		{name: "空なら空配列", arg: "", want1: []BacktestPrice{}},
+		{name: "日時と価格を読み込む",
+			arg: "2022-01-25 09:00:00,2000\n2022-01-25 09:00:04,2001.5\n",
+			want1: []BacktestPrice{
+				{DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Price: 2000},
+				{DateTime: time.Date(2022, 1, 25, 9, 0, 4, 0, time.Local), Price: 2001.5},
+			}},
+		{name: "日時が読めなければエラー", arg: "2022/01/25 09:00:00,2000\n", want1: []BacktestPrice{}, wantErr: true},
+		{name: "価格が読めなければエラー", arg: "2022-01-25 09:00:00,abc\n", want1: []BacktestPrice{}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1, got2 := ReadBacktestPrices(strings.NewReader(test.arg))
+			if !reflect.DeepEqual(test.want1, got1) || (got2 != nil) != test.wantErr {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.wantErr, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_ReadFourPrices(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		arg     string
+		want1   []*FourPrice
+		wantErr bool
+	}{
+		{name: "日付と四本値を読み込み、日時は大引けにする",
+			arg: "2022-01-24,2000,2010,1990,2005\n",
+			want1: []*FourPrice{
+				{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 24, 15, 0, 0, 0, time.Local), Open: 2000, High: 2010, Low: 1990, Close: 2005},
+			}},
+		{name: "値が読めなければエラー", arg: "2022-01-24,2000,2010,abc,2005\n", want1: []*FourPrice{}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1, got2 := ReadFourPrices(strings.NewReader(test.arg), "1475", ExchangeToushou)
+			if !reflect.DeepEqual(test.want1, got1) || (got2 != nil) != test.wantErr {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.wantErr, got1, got2)
+			}
+		})
+	}
+}
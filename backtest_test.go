@@ -0,0 +1,174 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_NewBacktestService(t *testing.T) {
+	t.Parallel()
+	want1 := &backtestService{}
+	got1 := NewBacktestService()
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_backtestService_Run(t *testing.T) {
+	t.Parallel()
+	strategy := Strategy{
+		Code:            "strategy-code-001",
+		SymbolCode:      "1475",
+		Exchange:        ExchangeToushou,
+		Product:         ProductMargin,
+		MarginTradeType: MarginTradeTypeDay,
+		EntrySide:       SideBuy,
+		Cash:            4_000,
+		TickGroup:       TickGroupOther,
+		TradingUnit:     1,
+		GridStrategy: GridStrategy{
+			Runnable:      true,
+			Quantity:      1,
+			BaseWidth:     1,
+			NumberOfGrids: 1,
+			TimeRanges:    []TimeRange{{Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local), End: time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}},
+		},
+		RebalanceStrategy: RebalanceStrategy{
+			Runnable: true,
+			Timings:  []time.Time{time.Date(0, 1, 1, 9, 0, 0, 0, time.Local)},
+		},
+		Runnable: true,
+	}
+	withFee := strategy
+	withFee.Cash = 4_010
+	withFee.FeeStrategy = FeeStrategy{CommissionType: CommissionTypeFlat, FlatCommission: 1}
+
+	tests := []struct {
+		name          string
+		arg1          BacktestConfig
+		wantCash      float64
+		wantPositions int
+		wantContracts []float64
+		wantProfit    float64
+		wantDrawdown  float64
+		wantFee       float64
+		wantErr       error
+	}{
+		{name: "価格がなければエラー",
+			arg1:    BacktestConfig{Strategy: strategy},
+			wantErr: ErrNilArgument},
+		{name: "戦略コードがなければエラー",
+			arg1:    BacktestConfig{Prices: []BacktestPrice{{DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Price: 1000}}},
+			wantErr: ErrNilArgument},
+		{name: "リバランス後に価格がグリッドを往復したらエントリーとエグジットが約定し、利益が現金に反映される",
+			arg1: BacktestConfig{
+				Strategy: strategy,
+				Prices: []BacktestPrice{
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Price: 1000},
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 4, 0, time.Local), Price: 999},
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 8, 0, time.Local), Price: 1000},
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 12, 0, time.Local), Price: 1000},
+				}},
+			wantCash:      2_001,
+			wantPositions: 1,
+			wantContracts: []float64{1000, 999, 1000},
+			wantProfit:    1,
+			wantDrawdown:  2},
+		{name: "手数料の設定があれば約定ごとに現金から引かれ、純損益にも反映される",
+			arg1: BacktestConfig{
+				Strategy: withFee,
+				Prices: []BacktestPrice{
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Price: 1000},
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 4, 0, time.Local), Price: 999},
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 8, 0, time.Local), Price: 1000},
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 12, 0, time.Local), Price: 1000},
+				}},
+			wantCash:      2_008,
+			wantPositions: 1,
+			wantContracts: []float64{1000, 999, 1000},
+			wantProfit:    -2,
+			wantDrawdown:  4,
+			wantFee:       3},
+		{name: "価格が下がったままなら現金が尽きるまでエントリーしてポジションが残る",
+			arg1: BacktestConfig{
+				Strategy: strategy,
+				Prices: []BacktestPrice{
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Price: 1000},
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 4, 0, time.Local), Price: 999},
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 8, 0, time.Local), Price: 998},
+					{DateTime: time.Date(2022, 1, 25, 9, 0, 12, 0, time.Local), Price: 998},
+				}},
+			wantCash:      1_001,
+			wantPositions: 2,
+			wantContracts: []float64{1000, 999},
+			wantProfit:    -5,
+			wantDrawdown:  5},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &backtestService{}
+			got, err := service.Run(test.arg1)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			gotContracts := make([]float64, 0)
+			for _, c := range got.Contracts {
+				gotContracts = append(gotContracts, c.Price)
+			}
+			if test.wantCash != got.Cash || test.wantPositions != len(got.Positions) || !reflect.DeepEqual(test.wantContracts, gotContracts) ||
+				test.wantProfit != got.NetProfit || test.wantDrawdown != got.MaxDrawdown || len(test.wantContracts) != got.TradeCount ||
+				test.wantFee != got.Fee {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v, %+v, %+v\n", t.Name(),
+					test.wantCash, test.wantPositions, test.wantContracts, test.wantProfit, test.wantDrawdown, test.wantFee,
+					got.Cash, len(got.Positions), gotContracts, got.NetProfit, got.MaxDrawdown, got.Fee, got.TradeCount)
+			}
+		})
+	}
+}
+
+func Test_backtestDB_FourPrice(t *testing.T) {
+	t.Parallel()
+	db := newBacktestDB([]*FourPrice{
+		{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 21, 15, 0, 0, 0, time.Local), Close: 1981},
+		{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 19, 15, 0, 0, 0, time.Local), Close: 1973},
+		{SymbolCode: "1476", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 20, 15, 0, 0, 0, time.Local), Close: 2000},
+	})
+	_ = db.SaveFourPrice(&FourPrice{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 20, 15, 0, 0, 0, time.Local), Close: 1993})
+	_ = db.SaveFourPrice(&FourPrice{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 21, 15, 0, 0, 0, time.Local), Close: 1982})
+
+	want1 := []*FourPrice{
+		{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 21, 15, 0, 0, 0, time.Local), Close: 1982},
+		{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 20, 15, 0, 0, 0, time.Local), Close: 1993},
+	}
+	got1, got2 := db.GetFourPriceBySymbolCodeAndExchange("1475", ExchangeToushou, 2)
+	if !reflect.DeepEqual(want1, got1) || got2 != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), want1, got1, got2)
+	}
+}
+
+func Test_backtestKabusAPI_setPrice(t *testing.T) {
+	t.Parallel()
+	api := newBacktestKabusAPI(&backtestClock{}, Strategy{SymbolCode: "1475", Exchange: ExchangeToushou, TickGroup: TickGroupOther, TradingUnit: 1})
+	api.setPrice(BacktestPrice{DateTime: time.Date(2022, 1, 24, 14, 59, 0, 0, time.Local), Price: 2010})
+	api.setPrice(BacktestPrice{DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Price: 2000})
+	api.setPrice(BacktestPrice{DateTime: time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local), Price: 2005})
+	api.setPrice(BacktestPrice{DateTime: time.Date(2022, 1, 25, 9, 2, 0, 0, time.Local), Price: 1995})
+	api.setPrice(BacktestPrice{DateTime: time.Date(2022, 1, 25, 9, 3, 0, 0, time.Local), Price: 2001})
+
+	want1 := &FourPrice{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 25, 9, 3, 0, 0, time.Local), Open: 2000, High: 2005, Low: 1995, Close: 2001}
+	want2 := &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, TickGroup: TickGroupOther, CurrentPrice: 2001, CurrentPriceDateTime: time.Date(2022, 1, 25, 9, 3, 0, 0, time.Local), BidPrice: 2001, AskPrice: 2001, OpeningPrice: 2000}
+	got1, _ := api.GetFourPrice("1475", ExchangeToushou)
+	got2, _ := api.GetSymbol("1475", ExchangeToushou)
+	if !reflect.DeepEqual(want1, got1) || !reflect.DeepEqual(want2, got2) {
+		t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), want1, want2, got1, got2)
+	}
+}
@@ -0,0 +1,185 @@
+package gridon
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// newCircuitBreaker - 新しいサーキットブレーカーの取得
+func newCircuitBreaker(clock IClock, notifier INotifier, config CircuitBreakerConfig) ICircuitBreaker {
+	return &circuitBreaker{
+		clock:    clock,
+		notifier: notifier,
+		config:   config,
+		states:   map[string]*CircuitBreakerState{},
+	}
+}
+
+// ICircuitBreaker - サーキットブレーカーのインターフェース
+type ICircuitBreaker interface {
+	IsOpen(strategyCode string) bool
+	RecordSuccess(strategyCode string, operation CircuitOperation)
+	RecordFailure(strategyCode string, operation CircuitOperation, err error)
+	GetStates() []CircuitBreakerState
+}
+
+// circuitBreaker - 戦略ごとのサーキットブレーカー
+// 同じ種類のエラーが連続で閾値回発生したら開いて、冷却期間の間は戦略の注文を止める
+// 別の種類のエラーが挟まったり、失敗した処理が成功したりしたら連続失敗回数を数え直す
+// 冷却期間が過ぎたら半開にして試しに注文させ、成功すれば閉じ、失敗すればまた開く
+type circuitBreaker struct {
+	clock    IClock
+	notifier INotifier
+	config   CircuitBreakerConfig
+	states   map[string]*CircuitBreakerState
+	mtx      sync.Mutex
+}
+
+// circuitBreakerErrors - 種類を分けて数えるエラーの一覧
+// 一覧にないエラーはunknownとして数える
+var circuitBreakerErrors = []error{
+	ErrCannotGetBasePrice,
+	ErrNotEnoughCash,
+	ErrNotEnoughPosition,
+	ErrOrderCondition,
+	ErrCancelCondition,
+	ErrZeroGridWidth,
+	ErrNotExistsTimeRange,
+	ErrShortSellingRestriction,
+	ErrKabusTransient,
+	ErrInsufficientFunds,
+	ErrSymbolHalted,
+	ErrAuthExpired,
+	ErrDuplicateOrder,
+	ErrNotCancelable,
+	ErrPendingOrder,
+	ErrRiskLimitExceeded,
+	ErrKillSwitch,
+}
+
+// circuitBreakerErrorType - エラーの種類
+func circuitBreakerErrorType(err error) string {
+	for _, e := range circuitBreakerErrors {
+		if errors.Is(err, e) {
+			return e.Error()
+		}
+	}
+	return ErrUnknown.Error()
+}
+
+// IsOpen - 開いていて戦略の注文を止めるべきかどうか
+// 開いてから冷却期間が過ぎていれば半開にして、試しに注文させるためにfalseを返す
+func (s *circuitBreaker) IsOpen(strategyCode string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	state, ok := s.states[strategyCode]
+	if !ok || state.State != CircuitStateOpen {
+		return false
+	}
+
+	if s.clock.Now().Before(state.HalfOpenAt) {
+		return true
+	}
+
+	state.State = CircuitStateHalfOpen
+	s.notifier.Notify(fmt.Sprintf("%s のサーキットブレーカーを半開にします(reason = %s)", strategyCode, state.Reason))
+	return false
+}
+
+// RecordSuccess - 成功を記録する
+// グリッドの整地が成功したら連続失敗回数を消して閉じる
+// 約定確認は注文を出さないので閉じる判断には使わず、約定確認の失敗が続いていたときだけ連続失敗回数を消す
+func (s *circuitBreaker) RecordSuccess(strategyCode string, operation CircuitOperation) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	state, ok := s.states[strategyCode]
+	if !ok {
+		return
+	}
+
+	if operation != CircuitOperationLeveling {
+		if state.Operation == operation {
+			state.Failures = map[string]int{}
+			state.Operation = CircuitOperationUnspecified
+		}
+		return
+	}
+
+	if state.State != CircuitStateClosed {
+		s.notifier.Notify(fmt.Sprintf("%s のサーキットブレーカーを閉じます", strategyCode))
+	}
+	state.State = CircuitStateClosed
+	state.Failures = map[string]int{}
+	state.Operation = CircuitOperationUnspecified
+	state.Reason = ""
+	state.OpenedDateTime = time.Time{}
+	state.HalfOpenAt = time.Time{}
+}
+
+// RecordFailure - 同じ種類のエラーの連続失敗回数を数え、閾値に達するか半開中の失敗なら開く
+// 直前と違う種類のエラーなら、それまでの連続失敗回数を消して1回目から数える
+func (s *circuitBreaker) RecordFailure(strategyCode string, operation CircuitOperation, err error) {
+	if err == nil {
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	state, ok := s.states[strategyCode]
+	if !ok {
+		state = &CircuitBreakerState{StrategyCode: strategyCode, State: CircuitStateClosed, Failures: map[string]int{}}
+		s.states[strategyCode] = state
+	}
+
+	errorType := circuitBreakerErrorType(err)
+	if _, ok := state.Failures[errorType]; !ok {
+		state.Failures = map[string]int{}
+	}
+	state.Failures[errorType]++
+	state.Operation = operation
+
+	switch state.State {
+	case CircuitStateHalfOpen:
+		s.open(state, errorType, err)
+	case CircuitStateClosed:
+		if s.config.Threshold > 0 && state.Failures[errorType] >= s.config.Threshold {
+			s.open(state, errorType, err)
+		}
+	}
+}
+
+// open - サーキットブレーカーを開き、理由を記録して通知する
+func (s *circuitBreaker) open(state *CircuitBreakerState, errorType string, err error) {
+	now := s.clock.Now()
+	state.State = CircuitStateOpen
+	state.Reason = fmt.Sprintf("%s x%d: %v", errorType, state.Failures[errorType], err)
+	state.OpenedDateTime = now
+	state.HalfOpenAt = now.Add(s.config.CoolDown)
+	s.notifier.Notify(fmt.Sprintf("%s のサーキットブレーカーを開きます(reason = %s, half open at = %s)", state.StrategyCode, state.Reason, state.HalfOpenAt.Format("2006-01-02 15:04:05")))
+}
+
+// GetStates - 戦略ごとのサーキットブレーカーの状態を戦略コード順で返す
+func (s *circuitBreaker) GetStates() []CircuitBreakerState {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	states := make([]CircuitBreakerState, 0, len(s.states))
+	for _, state := range s.states {
+		st := *state
+		st.Failures = make(map[string]int)
+		for k, v := range state.Failures {
+			st.Failures[k] = v
+		}
+		states = append(states, st)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].StrategyCode < states[j].StrategyCode
+	})
+	return states
+}
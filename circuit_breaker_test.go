@@ -0,0 +1,361 @@
+package gridon
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testCircuitBreaker struct {
+	ICircuitBreaker
+	IsOpen1              bool
+	RecordSuccessCount   int
+	RecordFailureHistory []interface{}
+	GetStates1           []CircuitBreakerState
+}
+
+func (t *testCircuitBreaker) IsOpen(string) bool { return t.IsOpen1 }
+func (t *testCircuitBreaker) RecordSuccess(string, CircuitOperation) {
+	t.RecordSuccessCount++
+}
+func (t *testCircuitBreaker) RecordFailure(strategyCode string, operation CircuitOperation, err error) {
+	t.RecordFailureHistory = append(t.RecordFailureHistory, strategyCode, operation, err)
+}
+func (t *testCircuitBreaker) GetStates() []CircuitBreakerState { return t.GetStates1 }
+
+func Test_newCircuitBreaker(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{}
+	notifier := &testNotifier{}
+	config := CircuitBreakerConfig{Threshold: 5, CoolDown: 5 * time.Minute}
+	want1 := &circuitBreaker{
+		clock:    clock,
+		notifier: notifier,
+		config:   config,
+		states:   map[string]*CircuitBreakerState{},
+	}
+	got1 := newCircuitBreaker(clock, notifier, config)
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_circuitBreakerErrorType(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		arg   error
+		want1 string
+	}{
+		{name: "一覧にあるエラーならそのエラーの種類", arg: ErrCannotGetBasePrice, want1: "can not get base price"},
+		{name: "ラップされていてもエラーの種類がわかる", arg: fmt.Errorf("result=%+v: %w", OrderResult{}, ErrOrderCondition), want1: "order condition"},
+		{name: "一覧にないエラーならunknown", arg: fmt.Errorf("foo"), want1: "unknown"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := circuitBreakerErrorType(test.arg)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_circuitBreaker_IsOpen(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name            string
+		states          map[string]*CircuitBreakerState
+		arg             string
+		want1           bool
+		wantState       CircuitState
+		wantNotifyCount int
+	}{
+		{name: "状態がなければ閉じている",
+			states:    map[string]*CircuitBreakerState{},
+			arg:       "strategy-code-001",
+			want1:     false,
+			wantState: CircuitStateUnspecified},
+		{name: "閉じていればfalse",
+			states:    map[string]*CircuitBreakerState{"strategy-code-001": {StrategyCode: "strategy-code-001", State: CircuitStateClosed}},
+			arg:       "strategy-code-001",
+			want1:     false,
+			wantState: CircuitStateClosed},
+		{name: "開いていて冷却期間中ならtrue",
+			states:    map[string]*CircuitBreakerState{"strategy-code-001": {StrategyCode: "strategy-code-001", State: CircuitStateOpen, HalfOpenAt: now.Add(1 * time.Second)}},
+			arg:       "strategy-code-001",
+			want1:     true,
+			wantState: CircuitStateOpen},
+		{name: "開いていて冷却期間が過ぎていたら半開にして通知し、false",
+			states:          map[string]*CircuitBreakerState{"strategy-code-001": {StrategyCode: "strategy-code-001", State: CircuitStateOpen, HalfOpenAt: now}},
+			arg:             "strategy-code-001",
+			want1:           false,
+			wantState:       CircuitStateHalfOpen,
+			wantNotifyCount: 1},
+		{name: "半開ならfalse",
+			states:    map[string]*CircuitBreakerState{"strategy-code-001": {StrategyCode: "strategy-code-001", State: CircuitStateHalfOpen}},
+			arg:       "strategy-code-001",
+			want1:     false,
+			wantState: CircuitStateHalfOpen},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			notifier := &testNotifier{}
+			breaker := &circuitBreaker{clock: &testClock{Now1: now}, notifier: notifier, states: test.states}
+			got1 := breaker.IsOpen(test.arg)
+
+			var gotState CircuitState
+			if state, ok := breaker.states[test.arg]; ok {
+				gotState = state.State
+			}
+			if !reflect.DeepEqual(test.want1, got1) ||
+				!reflect.DeepEqual(test.wantState, gotState) ||
+				!reflect.DeepEqual(test.wantNotifyCount, notifier.NotifyCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantState, test.wantNotifyCount,
+					got1, gotState, notifier.NotifyCount)
+			}
+		})
+	}
+}
+
+func Test_circuitBreaker_RecordSuccess(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name            string
+		states          map[string]*CircuitBreakerState
+		arg             CircuitOperation
+		wantStates      map[string]*CircuitBreakerState
+		wantNotifyCount int
+	}{
+		{name: "状態がなければ何もしない",
+			states:     map[string]*CircuitBreakerState{},
+			arg:        CircuitOperationLeveling,
+			wantStates: map[string]*CircuitBreakerState{}},
+		{name: "閉じていれば連続失敗回数を消す",
+			states: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode: "strategy-code-001", State: CircuitStateClosed, Failures: map[string]int{"unknown": 2}, Operation: CircuitOperationLeveling}},
+			arg: CircuitOperationLeveling,
+			wantStates: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode: "strategy-code-001", State: CircuitStateClosed, Failures: map[string]int{}}}},
+		{name: "半開なら閉じて理由を消し、通知する",
+			states: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode:   "strategy-code-001",
+				State:          CircuitStateHalfOpen,
+				Failures:       map[string]int{"unknown": 5},
+				Operation:      CircuitOperationLeveling,
+				Reason:         "unknown x5: foo",
+				OpenedDateTime: now.Add(-5 * time.Minute),
+				HalfOpenAt:     now}},
+			arg: CircuitOperationLeveling,
+			wantStates: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode: "strategy-code-001", State: CircuitStateClosed, Failures: map[string]int{}}},
+			wantNotifyCount: 1},
+		{name: "約定確認の失敗が続いているときに約定確認が成功したら、連続失敗回数だけを消す",
+			states: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode:   "strategy-code-001",
+				State:          CircuitStateOpen,
+				Failures:       map[string]int{"kabus transient error": 3},
+				Operation:      CircuitOperationConfirm,
+				Reason:         "kabus transient error x3: kabus transient error",
+				OpenedDateTime: now.Add(-1 * time.Minute),
+				HalfOpenAt:     now.Add(4 * time.Minute)}},
+			arg: CircuitOperationConfirm,
+			wantStates: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode:   "strategy-code-001",
+				State:          CircuitStateOpen,
+				Failures:       map[string]int{},
+				Reason:         "kabus transient error x3: kabus transient error",
+				OpenedDateTime: now.Add(-1 * time.Minute),
+				HalfOpenAt:     now.Add(4 * time.Minute)}}},
+		{name: "グリッドの整地の失敗が続いているときに約定確認が成功しても、連続失敗回数は消さない",
+			states: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode: "strategy-code-001", State: CircuitStateClosed, Failures: map[string]int{"unknown": 2}, Operation: CircuitOperationLeveling}},
+			arg: CircuitOperationConfirm,
+			wantStates: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode: "strategy-code-001", State: CircuitStateClosed, Failures: map[string]int{"unknown": 2}, Operation: CircuitOperationLeveling}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			notifier := &testNotifier{}
+			breaker := &circuitBreaker{clock: &testClock{Now1: now}, notifier: notifier, states: test.states}
+			breaker.RecordSuccess("strategy-code-001", test.arg)
+			if !reflect.DeepEqual(test.wantStates, breaker.states) || !reflect.DeepEqual(test.wantNotifyCount, notifier.NotifyCount) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.wantStates, test.wantNotifyCount, breaker.states, notifier.NotifyCount)
+			}
+		})
+	}
+}
+
+func Test_circuitBreaker_RecordFailure(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name            string
+		config          CircuitBreakerConfig
+		states          map[string]*CircuitBreakerState
+		arg             error
+		wantStates      map[string]*CircuitBreakerState
+		wantNotifyCount int
+	}{
+		{name: "errがnilなら何もしない",
+			config:     CircuitBreakerConfig{Threshold: 3, CoolDown: 5 * time.Minute},
+			states:     map[string]*CircuitBreakerState{},
+			arg:        nil,
+			wantStates: map[string]*CircuitBreakerState{}},
+		{name: "状態がなければ作ってエラーの種類ごとに数える",
+			config: CircuitBreakerConfig{Threshold: 3, CoolDown: 5 * time.Minute},
+			states: map[string]*CircuitBreakerState{},
+			arg:    ErrCannotGetBasePrice,
+			wantStates: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode: "strategy-code-001", State: CircuitStateClosed, Failures: map[string]int{"can not get base price": 1}, Operation: CircuitOperationLeveling}}},
+		{name: "別の種類のエラーなら、それまでの連続失敗回数を消して数え直す",
+			config: CircuitBreakerConfig{Threshold: 3, CoolDown: 5 * time.Minute},
+			states: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode: "strategy-code-001", State: CircuitStateClosed, Failures: map[string]int{"can not get base price": 2}}},
+			arg: ErrNotEnoughCash,
+			wantStates: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode: "strategy-code-001", State: CircuitStateClosed, Failures: map[string]int{"not enough cash": 1}, Operation: CircuitOperationLeveling}}},
+		{name: "同じ種類のエラーが閾値に達したら開いて通知する",
+			config: CircuitBreakerConfig{Threshold: 3, CoolDown: 5 * time.Minute},
+			states: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode: "strategy-code-001", State: CircuitStateClosed, Failures: map[string]int{"can not get base price": 2}}},
+			arg: ErrCannotGetBasePrice,
+			wantStates: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode:   "strategy-code-001",
+				State:          CircuitStateOpen,
+				Failures:       map[string]int{"can not get base price": 3},
+				Operation:      CircuitOperationLeveling,
+				Reason:         "can not get base price x3: can not get base price",
+				OpenedDateTime: now,
+				HalfOpenAt:     now.Add(5 * time.Minute)}},
+			wantNotifyCount: 1},
+		{name: "閾値が0なら開かない",
+			config: CircuitBreakerConfig{},
+			states: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode: "strategy-code-001", State: CircuitStateClosed, Failures: map[string]int{"can not get base price": 10}}},
+			arg: ErrCannotGetBasePrice,
+			wantStates: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode: "strategy-code-001", State: CircuitStateClosed, Failures: map[string]int{"can not get base price": 11}, Operation: CircuitOperationLeveling}}},
+		{name: "半開中に失敗したら回数に関係なくまた開く",
+			config: CircuitBreakerConfig{Threshold: 3, CoolDown: 5 * time.Minute},
+			states: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode:   "strategy-code-001",
+				State:          CircuitStateHalfOpen,
+				Failures:       map[string]int{"can not get base price": 3},
+				Reason:         "can not get base price x3: can not get base price",
+				OpenedDateTime: now.Add(-5 * time.Minute),
+				HalfOpenAt:     now}},
+			arg: ErrOrderCondition,
+			wantStates: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode:   "strategy-code-001",
+				State:          CircuitStateOpen,
+				Failures:       map[string]int{"order condition": 1},
+				Operation:      CircuitOperationLeveling,
+				Reason:         "order condition x1: order condition",
+				OpenedDateTime: now,
+				HalfOpenAt:     now.Add(5 * time.Minute)}},
+			wantNotifyCount: 1},
+		{name: "開いている間の失敗は数えるだけで冷却期間は延ばさない",
+			config: CircuitBreakerConfig{Threshold: 3, CoolDown: 5 * time.Minute},
+			states: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode:   "strategy-code-001",
+				State:          CircuitStateOpen,
+				Failures:       map[string]int{"can not get base price": 3},
+				Reason:         "can not get base price x3: can not get base price",
+				OpenedDateTime: now.Add(-1 * time.Minute),
+				HalfOpenAt:     now.Add(4 * time.Minute)}},
+			arg: ErrCannotGetBasePrice,
+			wantStates: map[string]*CircuitBreakerState{"strategy-code-001": {
+				StrategyCode:   "strategy-code-001",
+				State:          CircuitStateOpen,
+				Failures:       map[string]int{"can not get base price": 4},
+				Operation:      CircuitOperationLeveling,
+				Reason:         "can not get base price x3: can not get base price",
+				OpenedDateTime: now.Add(-1 * time.Minute),
+				HalfOpenAt:     now.Add(4 * time.Minute)}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			notifier := &testNotifier{}
+			breaker := &circuitBreaker{clock: &testClock{Now1: now}, notifier: notifier, config: test.config, states: test.states}
+			breaker.RecordFailure("strategy-code-001", CircuitOperationLeveling, test.arg)
+			if !reflect.DeepEqual(test.wantStates, breaker.states) || !reflect.DeepEqual(test.wantNotifyCount, notifier.NotifyCount) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.wantStates, test.wantNotifyCount, breaker.states, notifier.NotifyCount)
+			}
+		})
+	}
+}
+
+func Test_circuitBreaker_RecordFailure_interleaved(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name            string
+		args            []error
+		wantState       CircuitState
+		wantFailures    map[string]int
+		wantNotifyCount int
+	}{
+		{name: "違う種類のエラーが交互に続いても開かない",
+			args:         []error{ErrCannotGetBasePrice, ErrNotEnoughCash, ErrCannotGetBasePrice, ErrNotEnoughCash, ErrCannotGetBasePrice, ErrNotEnoughCash},
+			wantState:    CircuitStateClosed,
+			wantFailures: map[string]int{"not enough cash": 1}},
+		{name: "違う種類のエラーの後に同じ種類のエラーが閾値回続いたら開く",
+			args:            []error{ErrCannotGetBasePrice, ErrNotEnoughCash, ErrCannotGetBasePrice, ErrNotEnoughCash, ErrNotEnoughCash, ErrNotEnoughCash},
+			wantState:       CircuitStateOpen,
+			wantFailures:    map[string]int{"not enough cash": 3},
+			wantNotifyCount: 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			notifier := &testNotifier{}
+			breaker := newCircuitBreaker(&testClock{Now1: now}, notifier, CircuitBreakerConfig{Threshold: 3, CoolDown: 5 * time.Minute})
+			for _, err := range test.args {
+				breaker.RecordFailure("strategy-code-001", CircuitOperationLeveling, err)
+			}
+			got := breaker.GetStates()[0]
+			if !reflect.DeepEqual(test.wantState, got.State) ||
+				!reflect.DeepEqual(test.wantFailures, got.Failures) ||
+				!reflect.DeepEqual(test.wantNotifyCount, notifier.NotifyCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					test.wantState, test.wantFailures, test.wantNotifyCount,
+					got.State, got.Failures, notifier.NotifyCount)
+			}
+		})
+	}
+}
+
+func Test_circuitBreaker_GetStates(t *testing.T) {
+	t.Parallel()
+	breaker := &circuitBreaker{states: map[string]*CircuitBreakerState{
+		"strategy-code-002": {StrategyCode: "strategy-code-002", State: CircuitStateClosed, Failures: map[string]int{}},
+		"strategy-code-001": {StrategyCode: "strategy-code-001", State: CircuitStateOpen, Failures: map[string]int{"unknown": 5}, Reason: "unknown x5: foo"},
+	}}
+	want1 := []CircuitBreakerState{
+		{StrategyCode: "strategy-code-001", State: CircuitStateOpen, Failures: map[string]int{"unknown": 5}, Reason: "unknown x5: foo"},
+		{StrategyCode: "strategy-code-002", State: CircuitStateClosed, Failures: map[string]int{}},
+	}
+	got1 := breaker.GetStates()
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"gitlab.com/tsuchinaga/gridon"
+)
+
+// 価格ファイルは 日時(2006-01-02 15:04:05),価格 のCSV
+// 四本値ファイルは 日付(2006-01-02),始値,高値,安値,終値 のCSV
+func main() {
+	strategyPath := flag.String("strategy", "strategy.json", "戦略のjsonファイル")
+	pricesPath := flag.String("prices", "prices.csv", "再生する価格のcsvファイル")
+	fourPricesPath := flag.String("four-prices", "", "バックテスト開始前までの四本値のcsvファイル")
+	flag.Parse()
+
+	var strategy gridon.Strategy
+	if err := readJSON(*strategyPath, &strategy); err != nil {
+		log.Fatalln(err)
+	}
+
+	prices, err := readPrices(*pricesPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var fourPrices []*gridon.FourPrice
+	if *fourPricesPath != "" {
+		fourPrices, err = readFourPrices(*fourPricesPath, strategy.SymbolCode, strategy.Exchange)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	result, err := gridon.NewBacktestService().Run(gridon.BacktestConfig{Strategy: strategy, FourPrices: fourPrices, Prices: prices})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func readJSON(path string, v interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewDecoder(f).Decode(v)
+}
+
+func readPrices(path string) ([]gridon.BacktestPrice, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return gridon.ReadBacktestPrices(f)
+}
+
+func readFourPrices(path string, symbolCode string, exchange gridon.Exchange) ([]*gridon.FourPrice, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return gridon.ReadFourPrices(f, symbolCode, exchange)
+}
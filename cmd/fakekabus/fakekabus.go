@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"gitlab.com/tsuchinaga/kabus-grpc-server/kabuspb"
+	"google.golang.org/grpc"
+
+	"gitlab.com/tsuchinaga/gridon"
+)
+
+// 価格ファイルは 銘柄コード,時刻(15:04:05),価格 のCSV で、時刻は起動した日の時刻として扱う
+// 銘柄は全て東証で、売買単位と呼値グループは全銘柄共通
+func main() {
+	addr := flag.String("addr", ":18082", "待ち受けるアドレス")
+	pricesPath := flag.String("prices", "prices.csv", "配信する価格のcsvファイル")
+	tradingUnit := flag.Float64("trading-unit", 1, "売買単位")
+	tickGroup := flag.String("tick-group", string(gridon.TickGroupOther), "呼値グループ")
+	flag.Parse()
+
+	symbols, err := readSymbols(*pricesPath, *tradingUnit, gridon.TickGroup(*tickGroup), time.Now())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	server := grpc.NewServer()
+	kabuspb.RegisterKabusServiceServer(server, gridon.NewFakeKabusServer(symbols))
+	log.Printf("fake kabus server listening on %s\n", listener.Addr())
+	log.Fatalln(server.Serve(listener))
+}
+
+func readSymbols(path string, tradingUnit float64, tickGroup gridon.TickGroup, today time.Time) ([]gridon.FakeKabusSymbol, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	symbols := make([]gridon.FakeKabusSymbol, 0)
+	index := map[string]int{}
+	r := csv.NewReader(f)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return symbols, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := time.ParseInLocation("15:04:05", record[1], time.Local)
+		if err != nil {
+			return nil, err
+		}
+		price, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, err
+		}
+
+		i, ok := index[record[0]]
+		if !ok {
+			i = len(symbols)
+			index[record[0]] = i
+			symbols = append(symbols, gridon.FakeKabusSymbol{
+				SymbolCode:  record[0],
+				Exchange:    gridon.ExchangeToushou,
+				TradingUnit: tradingUnit,
+				TickGroup:   tickGroup,
+			})
+		}
+		symbols[i].Prices = append(symbols[i].Prices, gridon.BacktestPrice{
+			DateTime: time.Date(today.Year(), today.Month(), today.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local),
+			Price:    price,
+		})
+	}
+}
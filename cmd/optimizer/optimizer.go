@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"gitlab.com/tsuchinaga/gridon"
+)
+
+// 価格ファイルは 日時(2006-01-02 15:04:05),価格 のCSV
+// 四本値ファイルは 日付(2006-01-02),始値,高値,安値,終値 のCSV
+// 範囲ファイルは OptimizerRanges のjsonで、Stepを指定しなかったパラメータは戦略の値のまま動かす
+func main() {
+	strategyPath := flag.String("strategy", "strategy.json", "戦略のjsonファイル")
+	rangesPath := flag.String("ranges", "ranges.json", "探索するパラメータの範囲のjsonファイル")
+	pricesPath := flag.String("prices", "prices.csv", "再生する価格のcsvファイル")
+	fourPricesPath := flag.String("four-prices", "", "最適化に使う価格より前の四本値のcsvファイル")
+	rankBy := flag.String("rank-by", string(gridon.OptimizerRankByNetProfit), "順位付けの基準(net_profit, max_drawdown, trade_count)")
+	walkForward := flag.Int("walk-forward", 0, "ウォークフォワードで検証する回数")
+	parallel := flag.Int("parallel", 0, "同時に実行するバックテストの数 0ならCPU数")
+	top := flag.Int("top", 10, "出力する上位の結果の数 0なら全て")
+	flag.Parse()
+
+	var strategy gridon.Strategy
+	if err := readJSON(*strategyPath, &strategy); err != nil {
+		log.Fatalln(err)
+	}
+
+	var ranges gridon.OptimizerRanges
+	if err := readJSON(*rangesPath, &ranges); err != nil {
+		log.Fatalln(err)
+	}
+
+	prices, err := readPrices(*pricesPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var fourPrices []*gridon.FourPrice
+	if *fourPricesPath != "" {
+		fourPrices, err = readFourPrices(*fourPricesPath, strategy.SymbolCode, strategy.Exchange)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	report, err := gridon.NewOptimizerService().Run(gridon.OptimizerConfig{
+		Strategy:          strategy,
+		FourPrices:        fourPrices,
+		Prices:            prices,
+		Ranges:            ranges,
+		RankBy:            gridon.OptimizerRankBy(*rankBy),
+		WalkForwardSplits: *walkForward,
+		Parallel:          *parallel,
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if 0 < *top && *top < len(report.Results) {
+		report.Results = report.Results[:*top]
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func readJSON(path string, v interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewDecoder(f).Decode(v)
+}
+
+func readPrices(path string) ([]gridon.BacktestPrice, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return gridon.ReadBacktestPrices(f)
+}
+
+func readFourPrices(path string, symbolCode string, exchange gridon.Exchange) ([]*gridon.FourPrice, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return gridon.ReadFourPrices(f, symbolCode, exchange)
+}
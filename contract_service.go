@@ -6,13 +6,16 @@ import (
 )
 
 // newContractService - 新しい約定管理サービスの取得
-func newContractService(kabusAPI IKabusAPI, strategyStore IStrategyStore, orderStore IOrderStore, positionStore IPositionStore, clock IClock) IContractService {
+func newContractService(kabusAPI IKabusAPI, strategyStore IStrategyStore, orderStore IOrderStore, positionStore IPositionStore, tradeStore ITradeStore, feeStore IFeeStore, clock IClock, priceBandService IPriceBandService) IContractService {
 	return &contractService{
-		kabusAPI:      kabusAPI,
-		strategyStore: strategyStore,
-		orderStore:    orderStore,
-		positionStore: positionStore,
-		clock:         clock,
+		kabusAPI:         kabusAPI,
+		strategyStore:    strategyStore,
+		orderStore:       orderStore,
+		positionStore:    positionStore,
+		tradeStore:       tradeStore,
+		feeStore:         feeStore,
+		clock:            clock,
+		priceBandService: priceBandService,
 	}
 }
 
@@ -24,11 +27,14 @@ type IContractService interface {
 
 // contractService - 約定管理サービス
 type contractService struct {
-	kabusAPI      IKabusAPI
-	strategyStore IStrategyStore
-	orderStore    IOrderStore
-	positionStore IPositionStore
-	clock         IClock
+	kabusAPI         IKabusAPI
+	strategyStore    IStrategyStore
+	orderStore       IOrderStore
+	positionStore    IPositionStore
+	tradeStore       ITradeStore
+	feeStore         IFeeStore
+	clock            IClock
+	priceBandService IPriceBandService
 }
 
 // Confirm - 約定確認
@@ -59,7 +65,7 @@ func (s *contractService) Confirm(strategy *Strategy) error {
 		return err
 	}
 
-	return s.confirm(strategy, orders, securityOrders)
+	return s.confirm(strategy, orders, strategySecurityOrders(strategy, securityOrders))
 }
 
 // ConfirmGridEnd - グリッド終了時の約定確認
@@ -103,7 +109,7 @@ func (s *contractService) ConfirmGridEnd(strategy *Strategy) error {
 		return err
 	}
 
-	return s.confirm(strategy, orders, securityOrders)
+	return s.confirm(strategy, orders, strategySecurityOrders(strategy, securityOrders))
 }
 
 // confirm - 渡された注文情報を使って約定確認を行なう
@@ -124,16 +130,19 @@ func (s *contractService) confirm(strategy *Strategy, storeOrders []*Order, secu
 			if so.Code != o.Code || o.IsEqualSecurityOrder(so) { // 違う注文か、同じ注文で内容が一致しているならスキップ
 				continue
 			}
+			if so.Status == OrderStatusUnspecified { // 状態が決まっていない注文(発火前の逆指値など)は反映すると無効な注文になってしまうのでスキップ
+				continue
+			}
 
 			newContracts := o.ContractDiff(so)
 			for _, c := range newContracts {
 				switch o.TradeType {
 				case TradeTypeEntry:
-					if err := s.entryContract(o, c); err != nil {
+					if err := s.entryContract(strategy, o, c); err != nil {
 						return err
 					}
 				case TradeTypeExit:
-					if err := s.exitContract(o, c); err != nil {
+					if err := s.exitContract(strategy, o, c); err != nil {
 						return err
 					}
 				}
@@ -164,9 +173,9 @@ func (s *contractService) confirm(strategy *Strategy, storeOrders []*Order, secu
 }
 
 // entryContract - エントリー注文の約定
-// ポジションの登録、現金余力の更新
-func (s *contractService) entryContract(order *Order, contract Contract) error {
-	if order == nil {
+// ポジションの登録、現金余力の更新、手数料の計上
+func (s *contractService) entryContract(strategy *Strategy, order *Order, contract Contract) error {
+	if strategy == nil || order == nil {
 		return ErrNilArgument
 	}
 
@@ -194,14 +203,19 @@ func (s *contractService) entryContract(order *Order, contract Contract) error {
 		return err
 	}
 
+	// 手数料の計上
+	if err := s.chargeCommission(strategy, order, contract); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // exitContract - エグジット注文の約定
-// ポジションの更新、損益の登録、現金余力の更新
+// ポジションの更新、損益の登録、現金余力の更新、手数料等の費用の計上
 // 引数の注文に副作用がある
-func (s *contractService) exitContract(order *Order, contract Contract) error {
-	if order == nil {
+func (s *contractService) exitContract(strategy *Strategy, order *Order, contract Contract) error {
+	if strategy == nil || order == nil {
 		return ErrNilArgument
 	}
 
@@ -244,11 +258,127 @@ func (s *contractService) exitContract(order *Order, contract Contract) error {
 		if err := s.strategyStore.AddStrategyCash(order.StrategyCode, price); err != nil {
 			return err
 		}
+
+		// 確定した取引の登録
+		trade := s.tradeFrom(order, contract, hp, cq)
+		if err := s.tradeStore.Save(trade); err != nil {
+			return err
+		}
+
+		// 信用建玉の金利か貸株料の計上
+		if err := s.chargeHoldingCost(strategy, order, contract, trade); err != nil {
+			return err
+		}
+	}
+
+	// 手数料の計上
+	if err := s.chargeCommission(strategy, order, contract); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// chargeCommission - 約定にかかる手数料を現金余力から引いて記録する
+// 段階制の手数料の判定に約定代金を使うため、手数料プランがあれば手数料が0円でも記録する
+func (s *contractService) chargeCommission(strategy *Strategy, order *Order, contract Contract) error {
+	if strategy.FeeStrategy.CommissionType == CommissionTypeUnspecified {
+		return nil
+	}
+
+	// 段階制ならその日のこれまでの約定代金を集計する
+	var dailyAmount float64
+	if strategy.FeeStrategy.CommissionType == CommissionTypeDailyTiered {
+		cdt := contract.ContractDateTime
+		from := time.Date(cdt.Year(), cdt.Month(), cdt.Day(), 0, 0, 0, 0, cdt.Location())
+		fees, err := s.feeStore.GetByStrategyCode(order.StrategyCode, from, from.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+		for _, f := range fees {
+			if f.FeeType == FeeTypeCommission {
+				dailyAmount += f.Amount
+			}
+		}
+	}
+
+	amount := contract.Price * contract.Quantity
+	return s.chargeFee(&Fee{
+		Code:         contract.PositionCode + "-" + string(FeeTypeCommission),
+		StrategyCode: order.StrategyCode,
+		OrderCode:    order.Code,
+		PositionCode: contract.PositionCode,
+		FeeType:      FeeTypeCommission,
+		Amount:       amount,
+		Fee:          strategy.FeeStrategy.Commission(dailyAmount, amount),
+		DateTime:     contract.ContractDateTime,
+	})
+}
+
+// chargeHoldingCost - 返済した信用建玉にかかる金利か貸株料を現金余力から引いて記録する
+func (s *contractService) chargeHoldingCost(strategy *Strategy, order *Order, contract Contract, trade *Trade) error {
+	amount := trade.EntryPrice * trade.Quantity
+	feeType, fee := strategy.FeeStrategy.HoldingCost(order.Product, trade.Side, amount, trade.EntryDateTime, trade.ExitDateTime)
+	if feeType == FeeTypeUnspecified {
+		return nil
+	}
+
+	return s.chargeFee(&Fee{
+		Code:         trade.Code + "-" + string(feeType),
+		StrategyCode: order.StrategyCode,
+		OrderCode:    order.Code,
+		PositionCode: contract.PositionCode,
+		FeeType:      feeType,
+		Amount:       amount,
+		Fee:          fee,
+		DateTime:     contract.ContractDateTime,
+	})
+}
+
+// chargeFee - 費用を現金余力から引いて記録する
+func (s *contractService) chargeFee(fee *Fee) error {
+	if fee.Fee != 0 {
+		if err := s.strategyStore.AddStrategyCash(fee.StrategyCode, -1*fee.Fee); err != nil {
+			return err
+		}
+	}
+	return s.feeStore.Save(fee)
+}
+
+// tradeFrom - エグジット約定で返済したポジションの取引を作る
+func (s *contractService) tradeFrom(order *Order, contract Contract, holdPosition HoldPosition, quantity float64) *Trade {
+	// エントリー約定日時はポジションから取るが、取れなくても取引の登録は続ける
+	var entryDateTime time.Time
+	if position, err := s.positionStore.GetByCode(holdPosition.PositionCode); err == nil && position != nil {
+		entryDateTime = position.ContractDateTime
+	}
+
+	var profit float64
+	switch order.Side {
+	case SideSell: // 売りエグジット = 買いエントリー
+		profit = (contract.Price - holdPosition.Price) * quantity
+	case SideBuy: // 買いエグジット = 売りエントリー
+		profit = (holdPosition.Price - contract.Price) * quantity
+	}
+
+	return &Trade{
+		Code:              contract.PositionCode + "-" + holdPosition.PositionCode,
+		StrategyCode:      order.StrategyCode,
+		SymbolCode:        order.SymbolCode,
+		Exchange:          order.Exchange,
+		Product:           order.Product,
+		Side:              order.Side.Turn(),
+		EntryPositionCode: holdPosition.PositionCode,
+		EntryPrice:        holdPosition.Price,
+		EntryDateTime:     entryDateTime,
+		ExitOrderCode:     order.Code,
+		ExitPrice:         contract.Price,
+		ExitDateTime:      contract.ContractDateTime,
+		Quantity:          quantity,
+		Profit:            profit,
+	}
+}
+
 // releaseHoldPositions - 注文に拘束されているポジションを解放する
 // 引数の注文に副作用がある
 func (s *contractService) releaseHoldPositions(order *Order) error {
@@ -278,6 +408,12 @@ func (s *contractService) updateContractPrice(strategy *Strategy, contractPrice
 		if err := s.strategyStore.SetContractPrice(strategy.Code, contractPrice, contractDateTime); err != nil {
 			return err
 		}
+
+		// 価格帯が有効なら、最終約定価格が価格帯の外に出たかを判定して戦略に記録する
+		// 価格帯が計算できなくても約定の反映は続け、エラーはグリッドの整地で扱う
+		if strategy.GridStrategy.PriceBand.Valid {
+			_, _ = s.priceBandService.Check(strategy, contractPrice)
+		}
 	}
 
 	// 実行可能なグリッド戦略がなければ終了
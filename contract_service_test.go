@@ -217,7 +217,7 @@ func Test_contractService_entryContract(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 			service := &contractService{positionStore: test.positionStore, strategyStore: test.strategyStore}
-			got1 := service.entryContract(test.arg1, test.arg2)
+			got1 := service.entryContract(&Strategy{}, test.arg1, test.arg2)
 			if !errors.Is(got1, test.want1) ||
 				!reflect.DeepEqual(test.wantSaveHistory, test.positionStore.SaveHistory) ||
 				!reflect.DeepEqual(test.wantAddStrategyCashHistory, test.strategyStore.AddStrategyCashHistory) {
@@ -238,16 +238,19 @@ func Test_contractService_exitContract(t *testing.T) {
 		name                       string
 		positionStore              *testPositionStore
 		strategyStore              *testStrategyStore
+		tradeStore                 *testTradeStore
 		arg1                       *Order
 		arg2                       Contract
 		want1                      error
 		wantExitContractHistory    []interface{}
 		wantAddStrategyCashHistory []interface{}
+		wantTradeSaveHistory       []interface{}
 		wantOrder                  *Order
 	}{
 		{name: "引数がnilならエラー",
 			positionStore:              &testPositionStore{},
 			strategyStore:              &testStrategyStore{},
+			tradeStore:                 &testTradeStore{},
 			arg1:                       nil,
 			arg2:                       Contract{},
 			want1:                      ErrNilArgument,
@@ -257,6 +260,7 @@ func Test_contractService_exitContract(t *testing.T) {
 		{name: "ポジションの返済約定登録に失敗したらエラー",
 			positionStore: &testPositionStore{ExitContract1: ErrUnknown},
 			strategyStore: &testStrategyStore{GetByCode1: &Strategy{}},
+			tradeStore:    &testTradeStore{},
 			arg1: &Order{HoldPositions: []HoldPosition{
 				{PositionCode: "position-code-001", HoldQuantity: 100, ContractQuantity: 80},
 				{PositionCode: "position-code-002", HoldQuantity: 100, ReleaseQuantity: 70},
@@ -274,6 +278,7 @@ func Test_contractService_exitContract(t *testing.T) {
 		{name: "現金余力の更新に失敗したらエラー",
 			positionStore: &testPositionStore{},
 			strategyStore: &testStrategyStore{GetByCode1: &Strategy{}, AddStrategyCash1: ErrUnknown},
+			tradeStore:    &testTradeStore{},
 			arg1: &Order{
 				StrategyCode: "strategy-code-001",
 				Side:         SideSell,
@@ -294,9 +299,35 @@ func Test_contractService_exitContract(t *testing.T) {
 					{PositionCode: "position-code-002", HoldQuantity: 100, ReleaseQuantity: 70, Price: 2050},
 					{PositionCode: "position-code-003", HoldQuantity: 100, Price: 2060},
 				}}},
-		{name: "エラーがなかったらnilが返される",
+		{name: "取引の保存に失敗したらエラー",
 			positionStore: &testPositionStore{},
 			strategyStore: &testStrategyStore{GetByCode1: &Strategy{}},
+			tradeStore:    &testTradeStore{Save1: ErrUnknown},
+			arg1: &Order{
+				StrategyCode: "strategy-code-001",
+				Side:         SideSell,
+				HoldPositions: []HoldPosition{
+					{PositionCode: "position-code-001", HoldQuantity: 100, ContractQuantity: 80, Price: 2040},
+					{PositionCode: "position-code-002", HoldQuantity: 100, Price: 2050},
+				}},
+			arg2:                       Contract{Price: 2070, Quantity: 100},
+			want1:                      ErrUnknown,
+			wantExitContractHistory:    []interface{}{"position-code-001", 20.0},
+			wantAddStrategyCashHistory: []interface{}{"strategy-code-001", 2070.0 * 20.0},
+			wantTradeSaveHistory: []interface{}{
+				&Trade{Code: "-position-code-001", StrategyCode: "strategy-code-001", Side: SideBuy, EntryPositionCode: "position-code-001", EntryPrice: 2040, ExitPrice: 2070, Quantity: 20, Profit: (2070.0 - 2040.0) * 20},
+			},
+			wantOrder: &Order{
+				StrategyCode: "strategy-code-001",
+				Side:         SideSell,
+				HoldPositions: []HoldPosition{
+					{PositionCode: "position-code-001", HoldQuantity: 100, ContractQuantity: 100, Price: 2040},
+					{PositionCode: "position-code-002", HoldQuantity: 100, Price: 2050},
+				}}},
+		{name: "エラーがなかったらnilが返される",
+			positionStore: &testPositionStore{GetByCode1: &Position{ContractDateTime: time.Date(2021, 9, 29, 9, 0, 0, 0, time.Local)}},
+			strategyStore: &testStrategyStore{GetByCode1: &Strategy{}},
+			tradeStore:    &testTradeStore{},
 			arg1: &Order{
 				StrategyCode: "strategy-code-001",
 				Side:         SideSell,
@@ -306,10 +337,15 @@ func Test_contractService_exitContract(t *testing.T) {
 					{PositionCode: "position-code-003", HoldQuantity: 100, ReleaseQuantity: 100, Price: 2050},
 					{PositionCode: "position-code-004", HoldQuantity: 100, Price: 2060},
 				}},
-			arg2:                       Contract{Price: 2070, Quantity: 100, ContractDateTime: time.Date(2021, 9, 29, 10, 0, 0, 0, time.Local)},
+			arg2:                       Contract{PositionCode: "contract-position-001", Price: 2070, Quantity: 100, ContractDateTime: time.Date(2021, 9, 29, 10, 0, 0, 0, time.Local)},
 			want1:                      nil,
 			wantExitContractHistory:    []interface{}{"position-code-001", 20.0, "position-code-002", 30.0, "position-code-004", 50.0},
 			wantAddStrategyCashHistory: []interface{}{"strategy-code-001", 2070.0 * 20.0, "strategy-code-001", 2070.0 * 30.0, "strategy-code-001", 2070.0 * 50.0},
+			wantTradeSaveHistory: []interface{}{
+				&Trade{Code: "contract-position-001-position-code-001", StrategyCode: "strategy-code-001", Side: SideBuy, EntryPositionCode: "position-code-001", EntryPrice: 2030, EntryDateTime: time.Date(2021, 9, 29, 9, 0, 0, 0, time.Local), ExitPrice: 2070, ExitDateTime: time.Date(2021, 9, 29, 10, 0, 0, 0, time.Local), Quantity: 20, Profit: (2070.0 - 2030.0) * 20},
+				&Trade{Code: "contract-position-001-position-code-002", StrategyCode: "strategy-code-001", Side: SideBuy, EntryPositionCode: "position-code-002", EntryPrice: 2040, EntryDateTime: time.Date(2021, 9, 29, 9, 0, 0, 0, time.Local), ExitPrice: 2070, ExitDateTime: time.Date(2021, 9, 29, 10, 0, 0, 0, time.Local), Quantity: 30, Profit: (2070.0 - 2040.0) * 30},
+				&Trade{Code: "contract-position-001-position-code-004", StrategyCode: "strategy-code-001", Side: SideBuy, EntryPositionCode: "position-code-004", EntryPrice: 2060, EntryDateTime: time.Date(2021, 9, 29, 9, 0, 0, 0, time.Local), ExitPrice: 2070, ExitDateTime: time.Date(2021, 9, 29, 10, 0, 0, 0, time.Local), Quantity: 50, Profit: (2070.0 - 2060.0) * 50},
+			},
 			wantOrder: &Order{
 				StrategyCode: "strategy-code-001",
 				Side:         SideSell,
@@ -322,6 +358,7 @@ func Test_contractService_exitContract(t *testing.T) {
 		{name: "売り建て = 買いエグジットなら戻す資産の計算ロジックが違う",
 			positionStore: &testPositionStore{},
 			strategyStore: &testStrategyStore{GetByCode1: &Strategy{}},
+			tradeStore:    &testTradeStore{},
 			arg1: &Order{
 				StrategyCode: "strategy-code-001",
 				Side:         SideBuy,
@@ -335,6 +372,11 @@ func Test_contractService_exitContract(t *testing.T) {
 			want1:                      nil,
 			wantExitContractHistory:    []interface{}{"position-code-001", 20.0, "position-code-002", 30.0, "position-code-004", 50.0},
 			wantAddStrategyCashHistory: []interface{}{"strategy-code-001", (2110.0*2 - 2070.0) * 20.0, "strategy-code-001", (2100.0*2 - 2070.0) * 30.0, "strategy-code-001", (2080.0*2 - 2070.0) * 50.0},
+			wantTradeSaveHistory: []interface{}{
+				&Trade{Code: "-position-code-001", StrategyCode: "strategy-code-001", Side: SideSell, EntryPositionCode: "position-code-001", EntryPrice: 2110, EntryDateTime: time.Time{}, ExitPrice: 2070, ExitDateTime: time.Date(2021, 9, 29, 10, 0, 0, 0, time.Local), Quantity: 20, Profit: (2110.0 - 2070.0) * 20},
+				&Trade{Code: "-position-code-002", StrategyCode: "strategy-code-001", Side: SideSell, EntryPositionCode: "position-code-002", EntryPrice: 2100, EntryDateTime: time.Time{}, ExitPrice: 2070, ExitDateTime: time.Date(2021, 9, 29, 10, 0, 0, 0, time.Local), Quantity: 30, Profit: (2100.0 - 2070.0) * 30},
+				&Trade{Code: "-position-code-004", StrategyCode: "strategy-code-001", Side: SideSell, EntryPositionCode: "position-code-004", EntryPrice: 2080, EntryDateTime: time.Time{}, ExitPrice: 2070, ExitDateTime: time.Date(2021, 9, 29, 10, 0, 0, 0, time.Local), Quantity: 50, Profit: (2080.0 - 2070.0) * 50},
+			},
 			wantOrder: &Order{
 				StrategyCode: "strategy-code-001",
 				Side:         SideBuy,
@@ -347,6 +389,7 @@ func Test_contractService_exitContract(t *testing.T) {
 		{name: "少量の約定時は全てのポジションをループすることなく必要最低限のチェックでループを抜けて処理を進められる",
 			positionStore: &testPositionStore{},
 			strategyStore: &testStrategyStore{GetByCode1: &Strategy{}},
+			tradeStore:    &testTradeStore{},
 			arg1: &Order{
 				StrategyCode: "strategy-code-001",
 				Side:         SideSell,
@@ -360,6 +403,9 @@ func Test_contractService_exitContract(t *testing.T) {
 			want1:                      nil,
 			wantExitContractHistory:    []interface{}{"position-code-001", 10.0},
 			wantAddStrategyCashHistory: []interface{}{"strategy-code-001", 2070 * 10.0},
+			wantTradeSaveHistory: []interface{}{
+				&Trade{Code: "-position-code-001", StrategyCode: "strategy-code-001", Side: SideBuy, EntryPositionCode: "position-code-001", EntryPrice: 2110, EntryDateTime: time.Time{}, ExitPrice: 2070, ExitDateTime: time.Date(2021, 9, 29, 10, 0, 0, 0, time.Local), Quantity: 10, Profit: (2070.0 - 2110.0) * 10},
+			},
 			wantOrder: &Order{
 				StrategyCode: "strategy-code-001",
 				Side:         SideSell,
@@ -375,19 +421,21 @@ func Test_contractService_exitContract(t *testing.T) {
 		test := test
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
-			service := &contractService{positionStore: test.positionStore, strategyStore: test.strategyStore}
-			got1 := service.exitContract(test.arg1, test.arg2)
+			service := &contractService{positionStore: test.positionStore, strategyStore: test.strategyStore, tradeStore: test.tradeStore}
+			got1 := service.exitContract(&Strategy{}, test.arg1, test.arg2)
 			if !errors.Is(got1, test.want1) ||
 				!reflect.DeepEqual(test.wantExitContractHistory, test.positionStore.ExitContractHistory) ||
 				!reflect.DeepEqual(test.wantAddStrategyCashHistory, test.strategyStore.AddStrategyCashHistory) ||
+				!reflect.DeepEqual(test.wantTradeSaveHistory, test.tradeStore.SaveHistory) ||
 				!reflect.DeepEqual(test.wantOrder, test.arg1) {
-				t.Errorf("%s error\nresult: %+v, %+v, %+v, %+v\nwant: %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v\n", t.Name(),
+				t.Errorf("%s error\nresult: %+v, %+v, %+v, %+v, %+v\nwant: %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v\n", t.Name(),
 					!errors.Is(got1, test.want1),
 					!reflect.DeepEqual(test.wantExitContractHistory, test.positionStore.ExitContractHistory),
 					!reflect.DeepEqual(test.wantAddStrategyCashHistory, test.strategyStore.AddStrategyCashHistory),
+					!reflect.DeepEqual(test.wantTradeSaveHistory, test.tradeStore.SaveHistory),
 					!reflect.DeepEqual(test.wantOrder, test.arg1),
-					test.want1, test.wantExitContractHistory, test.wantAddStrategyCashHistory, test.wantOrder,
-					got1, test.positionStore.ExitContractHistory, test.strategyStore.AddStrategyCashHistory, test.arg1)
+					test.want1, test.wantExitContractHistory, test.wantAddStrategyCashHistory, test.wantTradeSaveHistory, test.wantOrder,
+					got1, test.positionStore.ExitContractHistory, test.strategyStore.AddStrategyCashHistory, test.tradeStore.SaveHistory, test.arg1)
 			}
 		})
 	}
@@ -1046,6 +1094,7 @@ func Test_contractService_Confirm(t *testing.T) {
 				strategyStore: test.strategyStore,
 				orderStore:    test.orderStore,
 				positionStore: test.positionStore,
+				tradeStore:    &testTradeStore{},
 			}
 			got1 := service.Confirm(test.arg1)
 			if !errors.Is(got1, test.want1) ||
@@ -1072,15 +1121,21 @@ func Test_newContractService(t *testing.T) {
 	strategyStore := &strategyStore{}
 	orderStore := &orderStore{}
 	positionStore := &positionStore{}
+	tradeStore := &tradeStore{}
+	feeStore := &feeStore{}
 	clock := &testClock{}
+	priceBandService := &testPriceBandService{}
 	want1 := &contractService{
-		kabusAPI:      kabusAPI,
-		strategyStore: strategyStore,
-		orderStore:    orderStore,
-		positionStore: positionStore,
-		clock:         clock,
+		kabusAPI:         kabusAPI,
+		strategyStore:    strategyStore,
+		orderStore:       orderStore,
+		positionStore:    positionStore,
+		tradeStore:       tradeStore,
+		feeStore:         feeStore,
+		clock:            clock,
+		priceBandService: priceBandService,
 	}
-	got1 := newContractService(kabusAPI, strategyStore, orderStore, positionStore, clock)
+	got1 := newContractService(kabusAPI, strategyStore, orderStore, positionStore, tradeStore, feeStore, clock, priceBandService)
 	if !reflect.DeepEqual(want1, got1) {
 		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
 	}
@@ -1406,6 +1461,58 @@ func Test_contractService_updateContractPrice(t *testing.T) {
 	}
 }
 
+func Test_contractService_updateContractPrice_priceBand(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		priceBandService *testPriceBandService
+		arg1             *Strategy
+		want1            error
+		wantCheckHistory []interface{}
+	}{
+		{name: "価格帯が無効なら価格帯の判定はしない",
+			priceBandService: &testPriceBandService{},
+			arg1: &Strategy{
+				Code:                 "strategy-code-001",
+				LastContractDateTime: time.Date(2021, 12, 24, 13, 59, 0, 0, time.Local)},
+			want1: nil},
+		{name: "価格帯が有効なら約定価格で価格帯の判定をする",
+			priceBandService: &testPriceBandService{Check1: PriceBandState{Upper: 1900, OutOfBand: true}},
+			arg1: &Strategy{
+				Code:                 "strategy-code-001",
+				LastContractDateTime: time.Date(2021, 12, 24, 13, 59, 0, 0, time.Local),
+				GridStrategy:         GridStrategy{PriceBand: PriceBand{Valid: true, Upper: 1900}}},
+			want1: nil,
+			wantCheckHistory: []interface{}{&Strategy{
+				Code:                 "strategy-code-001",
+				LastContractDateTime: time.Date(2021, 12, 24, 13, 59, 0, 0, time.Local),
+				GridStrategy:         GridStrategy{PriceBand: PriceBand{Valid: true, Upper: 1900}}}, 2000.0}},
+		{name: "価格帯の判定でエラーがでても約定情報の更新は続ける",
+			priceBandService: &testPriceBandService{Check2: ErrNoData},
+			arg1: &Strategy{
+				Code:                 "strategy-code-001",
+				LastContractDateTime: time.Date(2021, 12, 24, 13, 59, 0, 0, time.Local),
+				GridStrategy:         GridStrategy{PriceBand: PriceBand{Valid: true, Upper: 1900}}},
+			want1: nil,
+			wantCheckHistory: []interface{}{&Strategy{
+				Code:                 "strategy-code-001",
+				LastContractDateTime: time.Date(2021, 12, 24, 13, 59, 0, 0, time.Local),
+				GridStrategy:         GridStrategy{PriceBand: PriceBand{Valid: true, Upper: 1900}}}, 2000.0}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &contractService{strategyStore: &testStrategyStore{}, priceBandService: test.priceBandService}
+			got1 := service.updateContractPrice(test.arg1, 2000, time.Date(2021, 12, 24, 14, 0, 0, 0, time.Local))
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantCheckHistory, test.priceBandService.CheckHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.wantCheckHistory, got1, test.priceBandService.CheckHistory)
+			}
+		})
+	}
+}
+
 func Test_contractService_Confirm_issue29(t *testing.T) {
 	t.Parallel()
 
@@ -1593,6 +1700,7 @@ func Test_contractService_Confirm_issue29(t *testing.T) {
 		orderStore:    orderStore,
 		positionStore: &testPositionStore{},
 		strategyStore: &testStrategyStore{},
+		tradeStore:    &testTradeStore{},
 		kabusAPI:      kabusAPI,
 	}
 	strategy := &Strategy{
@@ -1826,6 +1934,7 @@ func Test_contractService_ConfirmGridEnd(t *testing.T) {
 				strategyStore: test.strategyStore,
 				orderStore:    test.orderStore,
 				positionStore: test.positionStore,
+				tradeStore:    &testTradeStore{},
 				clock:         test.clock,
 			}
 			got1 := service.ConfirmGridEnd(test.arg1)
@@ -1875,3 +1984,115 @@ func Test_contractService_confirm(t *testing.T) {
 		})
 	}
 }
+
+func Test_contractService_chargeCommission(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                         string
+		strategyStore                *testStrategyStore
+		feeStore                     *testFeeStore
+		arg1                         *Strategy
+		want1                        error
+		wantAddStrategyCashHistory   []interface{}
+		wantGetByStrategyCodeHistory []interface{}
+		wantSaveHistory              []interface{}
+	}{
+		{name: "手数料プランがなければ何もしない",
+			strategyStore: &testStrategyStore{},
+			feeStore:      &testFeeStore{},
+			arg1:          &Strategy{},
+			want1:         nil},
+		{name: "定額なら手数料を現金余力から引いて記録する",
+			strategyStore:              &testStrategyStore{},
+			feeStore:                   &testFeeStore{},
+			arg1:                       &Strategy{FeeStrategy: FeeStrategy{CommissionType: CommissionTypeFlat, FlatCommission: 55}},
+			want1:                      nil,
+			wantAddStrategyCashHistory: []interface{}{"strategy-code-001", -55.0},
+			wantSaveHistory: []interface{}{&Fee{Code: "position-code-001-commission", StrategyCode: "strategy-code-001", OrderCode: "order-code-001", PositionCode: "position-code-001",
+				FeeType: FeeTypeCommission, Amount: 2070 * 4, Fee: 55, DateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local)}}},
+		{name: "段階制ならその日の約定代金から手数料を計算し、0円でも記録する",
+			strategyStore: &testStrategyStore{},
+			feeStore: &testFeeStore{GetByStrategyCode1: []*Fee{
+				{FeeType: FeeTypeCommission, Amount: 500_000},
+				{FeeType: FeeTypeMarginInterest, Amount: 1_000_000},
+			}},
+			arg1:                         &Strategy{FeeStrategy: FeeStrategy{CommissionType: CommissionTypeDailyTiered, DailyTiers: []CommissionTier{{Amount: 1_000_000, Commission: 0}, {Amount: 2_000_000, Commission: 1_000}}}},
+			want1:                        nil,
+			wantGetByStrategyCodeHistory: []interface{}{"strategy-code-001", time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local)},
+			wantSaveHistory: []interface{}{&Fee{Code: "position-code-001-commission", StrategyCode: "strategy-code-001", OrderCode: "order-code-001", PositionCode: "position-code-001",
+				FeeType: FeeTypeCommission, Amount: 2070 * 4, Fee: 0, DateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local)}}},
+		{name: "段階制でその日の費用が取れなければエラー",
+			strategyStore:                &testStrategyStore{},
+			feeStore:                     &testFeeStore{GetByStrategyCode2: ErrUnknown},
+			arg1:                         &Strategy{FeeStrategy: FeeStrategy{CommissionType: CommissionTypeDailyTiered}},
+			want1:                        ErrUnknown,
+			wantGetByStrategyCodeHistory: []interface{}{"strategy-code-001", time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local)}},
+		{name: "現金余力の更新に失敗したらエラー",
+			strategyStore:              &testStrategyStore{AddStrategyCash1: ErrUnknown},
+			feeStore:                   &testFeeStore{},
+			arg1:                       &Strategy{FeeStrategy: FeeStrategy{CommissionType: CommissionTypeFlat, FlatCommission: 55}},
+			want1:                      ErrUnknown,
+			wantAddStrategyCashHistory: []interface{}{"strategy-code-001", -55.0}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &contractService{strategyStore: test.strategyStore, feeStore: test.feeStore}
+			got1 := service.chargeCommission(test.arg1,
+				&Order{Code: "order-code-001", StrategyCode: "strategy-code-001"},
+				Contract{PositionCode: "position-code-001", Price: 2070, Quantity: 4, ContractDateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local)})
+			if !errors.Is(got1, test.want1) ||
+				!reflect.DeepEqual(test.wantAddStrategyCashHistory, test.strategyStore.AddStrategyCashHistory) ||
+				!reflect.DeepEqual(test.wantGetByStrategyCodeHistory, test.feeStore.GetByStrategyCodeHistory) ||
+				!reflect.DeepEqual(test.wantSaveHistory, test.feeStore.SaveHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantAddStrategyCashHistory, test.wantGetByStrategyCodeHistory, test.wantSaveHistory,
+					got1, test.strategyStore.AddStrategyCashHistory, test.feeStore.GetByStrategyCodeHistory, test.feeStore.SaveHistory)
+			}
+		})
+	}
+}
+
+func Test_contractService_chargeHoldingCost(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                       string
+		arg1                       *Strategy
+		arg2                       *Order
+		wantAddStrategyCashHistory []interface{}
+		wantSaveHistory            []interface{}
+	}{
+		{name: "現物なら何もしない",
+			arg1: &Strategy{FeeStrategy: FeeStrategy{MarginInterestRate: 0.0365}},
+			arg2: &Order{Code: "order-code-001", StrategyCode: "strategy-code-001", Product: ProductStock}},
+		{name: "信用の買建なら金利を現金余力から引いて記録する",
+			arg1:                       &Strategy{FeeStrategy: FeeStrategy{MarginInterestRate: 0.0365}},
+			arg2:                       &Order{Code: "order-code-001", StrategyCode: "strategy-code-001", Product: ProductMargin},
+			wantAddStrategyCashHistory: []interface{}{"strategy-code-001", -4.0},
+			wantSaveHistory: []interface{}{&Fee{Code: "contract-position-001-position-code-001-margin_interest", StrategyCode: "strategy-code-001", OrderCode: "order-code-001", PositionCode: "contract-position-001",
+				FeeType: FeeTypeMarginInterest, Amount: 20_000, Fee: 4, DateTime: time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			strategyStore := &testStrategyStore{}
+			feeStore := &testFeeStore{}
+			service := &contractService{strategyStore: strategyStore, feeStore: feeStore}
+			got1 := service.chargeHoldingCost(test.arg1, test.arg2,
+				Contract{PositionCode: "contract-position-001", Price: 2010, Quantity: 10, ContractDateTime: time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)},
+				&Trade{Code: "contract-position-001-position-code-001", Side: SideBuy, EntryPrice: 2000, EntryDateTime: time.Date(2022, 1, 24, 14, 0, 0, 0, time.Local),
+					ExitDateTime: time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local), Quantity: 10})
+			if got1 != nil ||
+				!reflect.DeepEqual(test.wantAddStrategyCashHistory, strategyStore.AddStrategyCashHistory) ||
+				!reflect.DeepEqual(test.wantSaveHistory, feeStore.SaveHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					nil, test.wantAddStrategyCashHistory, test.wantSaveHistory,
+					got1, strategyStore.AddStrategyCashHistory, feeStore.SaveHistory)
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@ package gridon
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/types"
@@ -57,6 +59,22 @@ func openDB(path string) (*genji.DB, error) {
 		// four_prices
 		`create table if not exists four_prices`,
 		`create unique index if not exists four_prices_symbolcode_exchange_datetime on four_prices (symbolcode, exchange, datetime)`,
+		// trades
+		`create table if not exists trades`,
+		`create unique index if not exists trades_code on trades (code)`,
+		`create index if not exists trades_strategy_code on trades (strategycode)`,
+		`create index if not exists trades_exit_date_time on trades (exitdatetime)`,
+		// fees
+		`create table if not exists fees`,
+		`create unique index if not exists fees_code on fees (code)`,
+		`create index if not exists fees_strategy_code on fees (strategycode)`,
+		`create index if not exists fees_date_time on fees (datetime)`,
+		// equity_snapshots
+		`create table if not exists equity_snapshots`,
+		`create unique index if not exists equity_snapshots_code on equity_snapshots (code)`,
+		`create index if not exists equity_snapshots_strategy_code on equity_snapshots (strategycode)`,
+		// risk_settings
+		`create table if not exists risk_settings`,
 	}
 
 	for _, sql := range sqlList {
@@ -75,12 +93,21 @@ type IDB interface {
 	DeleteStrategyByCode(code string) error
 	GetActiveOrders() ([]*Order, error)
 	SaveOrder(order *Order) error
+	DeleteOrderByCode(code string) error
 	GetActivePositions() ([]*Position, error)
 	SavePosition(position *Position) error
 	CleanupOrders() error
 	CleanupPositions() error
 	GetFourPriceBySymbolCodeAndExchange(symbolCode string, exchange Exchange, num int) ([]*FourPrice, error)
 	SaveFourPrice(fourPrice *FourPrice) error
+	GetTrades(from time.Time, to time.Time) ([]*Trade, error)
+	SaveTrade(trade *Trade) error
+	GetFees(from time.Time, to time.Time) ([]*Fee, error)
+	SaveFee(fee *Fee) error
+	GetEquitySnapshotsByStrategyCode(strategyCode string) ([]*EquitySnapshot, error)
+	SaveEquitySnapshot(equitySnapshot *EquitySnapshot) error
+	GetRiskSetting() (*RiskSetting, error)
+	SaveRiskSetting(riskSetting *RiskSetting) error
 }
 
 // db - データベース
@@ -165,7 +192,7 @@ func (d *db) DeleteStrategyByCode(code string) error {
 
 // GetActiveOrders - 有効な注文一覧の取得
 func (d *db) GetActiveOrders() ([]*Order, error) {
-	res, err := d.db.Query(`select * from orders where status = 'in_order'`)
+	res, err := d.db.Query(`select * from orders where status = 'in_order' or status = 'pending'`)
 	if err != nil {
 		return nil, d.wrapErr(err)
 	}
@@ -211,6 +238,17 @@ func (d *db) SaveOrder(order *Order) error {
 	return nil
 }
 
+// DeleteOrderByCode - 注文の削除
+func (d *db) DeleteOrderByCode(code string) error {
+	d.logger.Notice(fmt.Sprintf("delete order: %+v", code))
+
+	if err := d.db.Exec(`delete from orders where code = ?`, code); err != nil {
+		d.logger.Warning(err)
+		return d.wrapErr(err)
+	}
+	return nil
+}
+
 // GetActivePositions - 有効なポジション一覧の取得
 func (d *db) GetActivePositions() ([]*Position, error) {
 	res, err := d.db.Query(`select * from positions where ownedquantity > 0`)
@@ -261,7 +299,7 @@ func (d *db) SavePosition(position *Position) error {
 
 // CleanupOrders - 不要な注文データの削除
 func (d *db) CleanupOrders() error {
-	if err := d.db.Exec(`delete from orders where status != 'in_order'`); err != nil {
+	if err := d.db.Exec(`delete from orders where status != 'in_order' and status != 'pending'`); err != nil {
 		return err
 	}
 
@@ -326,3 +364,229 @@ func (d *db) SaveFourPrice(fourPrice *FourPrice) error {
 	_ = tx.Commit()
 	return nil
 }
+
+// GetTrades - エグジット約定日時がfrom以降to未満の取引の取得
+// 日時はRFC3339の文字列で保存されていて、タイムゾーンが違うと文字列の順序と日時の順序が一致しないため、
+// DBでは前後1日広げた範囲でインデックスから絞り込み、正確な範囲の比較は取り出した後に行なう
+func (d *db) GetTrades(from time.Time, to time.Time) ([]*Trade, error) {
+	res, err := d.db.Query(`select * from trades where exitdatetime >= ? and exitdatetime < ?`, d.rangeFrom(from), d.rangeTo(to))
+	if err != nil {
+		return nil, d.wrapErr(err)
+	}
+	defer res.Close()
+
+	result := make([]*Trade, 0)
+	err = res.Iterate(func(d types.Document) error {
+		var trade Trade
+		if err := document.StructScan(d, &trade); err != nil {
+			return err
+		}
+		if trade.ExitDateTime.Before(from) || !trade.ExitDateTime.Before(to) {
+			return nil
+		}
+		result = append(result, &trade)
+		return nil
+	})
+	if err != nil {
+		return nil, d.wrapErr(err)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ExitDateTime.Before(result[j].ExitDateTime)
+	})
+	return result, nil
+}
+
+// rangeFrom - 文字列で保存された日時をDBで絞り込むときの下限
+// 年が4桁に収まらないと文字列の順序が崩れるので、0年に丸める
+func (d *db) rangeFrom(from time.Time) string {
+	from = from.AddDate(0, 0, -1)
+	if from.Year() < 0 {
+		return time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339Nano)
+	}
+	return from.Format(time.RFC3339Nano)
+}
+
+// rangeTo - 文字列で保存された日時をDBで絞り込むときの上限
+// 年が4桁に収まらないと文字列の順序が崩れるので、9999年の末に丸める
+func (d *db) rangeTo(to time.Time) string {
+	to = to.AddDate(0, 0, 1)
+	if to.Year() > 9999 {
+		return time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC).Format(time.RFC3339Nano)
+	}
+	return to.Format(time.RFC3339Nano)
+}
+
+// SaveTrade - 取引の保存
+func (d *db) SaveTrade(trade *Trade) error {
+	d.logger.Notice(fmt.Sprintf("save trade: %+v", trade))
+
+	tx, err := d.db.Begin(true)
+	if err != nil {
+		return d.wrapErr(err)
+	}
+
+	if err := tx.Exec(`delete from trades where code = ?`, trade.Code); err != nil {
+		_ = tx.Rollback()
+		d.logger.Warning(err)
+		return d.wrapErr(err)
+	}
+
+	if err := tx.Exec(`insert into trades values ?`, trade); err != nil {
+		_ = tx.Rollback()
+		d.logger.Warning(err)
+		return d.wrapErr(err)
+	}
+
+	_ = tx.Commit()
+	return nil
+}
+
+// GetFees - 計上日時がfrom以降to未満の費用の取得
+// 取引と同じく、DBでは前後1日広げた範囲で絞り込み、正確な範囲の比較は取り出した後に行なう
+func (d *db) GetFees(from time.Time, to time.Time) ([]*Fee, error) {
+	res, err := d.db.Query(`select * from fees where datetime >= ? and datetime < ?`, d.rangeFrom(from), d.rangeTo(to))
+	if err != nil {
+		return nil, d.wrapErr(err)
+	}
+	defer res.Close()
+
+	result := make([]*Fee, 0)
+	err = res.Iterate(func(d types.Document) error {
+		var fee Fee
+		if err := document.StructScan(d, &fee); err != nil {
+			return err
+		}
+		if fee.DateTime.Before(from) || !fee.DateTime.Before(to) {
+			return nil
+		}
+		result = append(result, &fee)
+		return nil
+	})
+	if err != nil {
+		return nil, d.wrapErr(err)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].DateTime.Before(result[j].DateTime)
+	})
+	return result, nil
+}
+
+// SaveFee - 費用の保存
+func (d *db) SaveFee(fee *Fee) error {
+	d.logger.Notice(fmt.Sprintf("save fee: %+v", fee))
+
+	tx, err := d.db.Begin(true)
+	if err != nil {
+		return d.wrapErr(err)
+	}
+
+	if err := tx.Exec(`delete from fees where code = ?`, fee.Code); err != nil {
+		_ = tx.Rollback()
+		d.logger.Warning(err)
+		return d.wrapErr(err)
+	}
+
+	if err := tx.Exec(`insert into fees values ?`, fee); err != nil {
+		_ = tx.Rollback()
+		d.logger.Warning(err)
+		return d.wrapErr(err)
+	}
+
+	_ = tx.Commit()
+	return nil
+}
+
+// GetEquitySnapshotsByStrategyCode - 戦略を指定して評価額のスナップショットを日付順に取得
+func (d *db) GetEquitySnapshotsByStrategyCode(strategyCode string) ([]*EquitySnapshot, error) {
+	res, err := d.db.Query(`select * from equity_snapshots where strategycode = ?`, strategyCode)
+	if err != nil {
+		return nil, d.wrapErr(err)
+	}
+	defer res.Close()
+
+	result := make([]*EquitySnapshot, 0)
+	err = res.Iterate(func(d types.Document) error {
+		var equitySnapshot EquitySnapshot
+		if err := document.StructScan(d, &equitySnapshot); err != nil {
+			return err
+		}
+		result = append(result, &equitySnapshot)
+		return nil
+	})
+	if err != nil {
+		return nil, d.wrapErr(err)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Date.Before(result[j].Date)
+	})
+	return result, nil
+}
+
+// SaveEquitySnapshot - 評価額のスナップショットの保存
+func (d *db) SaveEquitySnapshot(equitySnapshot *EquitySnapshot) error {
+	d.logger.Notice(fmt.Sprintf("save equity snapshot: %+v", equitySnapshot))
+
+	tx, err := d.db.Begin(true)
+	if err != nil {
+		return d.wrapErr(err)
+	}
+
+	if err := tx.Exec(`delete from equity_snapshots where code = ?`, equitySnapshot.Code); err != nil {
+		_ = tx.Rollback()
+		d.logger.Warning(err)
+		return d.wrapErr(err)
+	}
+
+	if err := tx.Exec(`insert into equity_snapshots values ?`, equitySnapshot); err != nil {
+		_ = tx.Rollback()
+		d.logger.Warning(err)
+		return d.wrapErr(err)
+	}
+
+	_ = tx.Commit()
+	return nil
+}
+
+// GetRiskSetting - リスク管理の設定と状態の取得
+// 保存されていなければErrNoData
+func (d *db) GetRiskSetting() (*RiskSetting, error) {
+	doc, err := d.db.QueryDocument(`select * from risk_settings`)
+	if err != nil {
+		return nil, d.wrapErr(err)
+	}
+
+	var riskSetting RiskSetting
+	if err := document.StructScan(doc, &riskSetting); err != nil {
+		return nil, d.wrapErr(err)
+	}
+	return &riskSetting, nil
+}
+
+// SaveRiskSetting - リスク管理の設定と状態の保存
+// 1件しか持たないので、既存のものを消してから保存する
+func (d *db) SaveRiskSetting(riskSetting *RiskSetting) error {
+	d.logger.Notice(fmt.Sprintf("save risk setting: %+v", riskSetting))
+
+	tx, err := d.db.Begin(true)
+	if err != nil {
+		return d.wrapErr(err)
+	}
+
+	if err := tx.Exec(`delete from risk_settings`); err != nil {
+		_ = tx.Rollback()
+		d.logger.Warning(err)
+		return d.wrapErr(err)
+	}
+
+	if err := tx.Exec(`insert into risk_settings values ?`, riskSetting); err != nil {
+		_ = tx.Rollback()
+		d.logger.Warning(err)
+		return d.wrapErr(err)
+	}
+
+	_ = tx.Commit()
+	return nil
+}
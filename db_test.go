@@ -21,6 +21,9 @@ type testDB struct {
 	SaveOrder1                                 error
 	SaveOrderCount                             int
 	SaveOrderHistory                           []interface{}
+	DeleteOrderByCode1                         error
+	DeleteOrderByCodeCount                     int
+	DeleteOrderByCodeHistory                   []interface{}
 	SavePosition1                              error
 	SavePositionCount                          int
 	SavePositionHistory                        []interface{}
@@ -39,6 +42,24 @@ type testDB struct {
 	SaveFourPrice1                             error
 	SaveFourPriceCount                         int
 	SaveFourPriceHistory                       []interface{}
+	GetTrades1                                 []*Trade
+	GetTrades2                                 error
+	GetTradesHistory                           []interface{}
+	SaveTrade1                                 error
+	SaveTradeHistory                           []interface{}
+	GetFees1                                   []*Fee
+	GetFees2                                   error
+	GetFeesHistory                             []interface{}
+	SaveFee1                                   error
+	SaveFeeHistory                             []interface{}
+	GetEquitySnapshotsByStrategyCode1          []*EquitySnapshot
+	GetEquitySnapshotsByStrategyCode2          error
+	SaveEquitySnapshot1                        error
+	SaveEquitySnapshotHistory                  []interface{}
+	GetRiskSetting1                            *RiskSetting
+	GetRiskSetting2                            error
+	SaveRiskSetting1                           error
+	SaveRiskSettingHistory                     []interface{}
 }
 
 func (t *testDB) GetStrategies() ([]*Strategy, error) {
@@ -65,6 +86,11 @@ func (t *testDB) SaveOrder(order *Order) error {
 	t.SaveOrderCount++
 	return t.SaveOrder1
 }
+func (t *testDB) DeleteOrderByCode(code string) error {
+	t.DeleteOrderByCodeHistory = append(t.DeleteOrderByCodeHistory, code)
+	t.DeleteOrderByCodeCount++
+	return t.DeleteOrderByCode1
+}
 func (t *testDB) SavePosition(position *Position) error {
 	t.SavePositionHistory = append(t.SavePositionHistory, position)
 	t.SavePositionCount++
@@ -85,6 +111,41 @@ func (t *testDB) SaveFourPrice(fourPrice *FourPrice) error {
 	return t.SaveFourPrice1
 }
 
+func (t *testDB) GetTrades(from time.Time, to time.Time) ([]*Trade, error) {
+	t.GetTradesHistory = append(t.GetTradesHistory, from)
+	t.GetTradesHistory = append(t.GetTradesHistory, to)
+	return t.GetTrades1, t.GetTrades2
+}
+func (t *testDB) SaveTrade(trade *Trade) error {
+	t.SaveTradeHistory = append(t.SaveTradeHistory, trade)
+	return t.SaveTrade1
+}
+
+func (t *testDB) GetFees(from time.Time, to time.Time) ([]*Fee, error) {
+	t.GetFeesHistory = append(t.GetFeesHistory, from)
+	t.GetFeesHistory = append(t.GetFeesHistory, to)
+	return t.GetFees1, t.GetFees2
+}
+func (t *testDB) SaveFee(fee *Fee) error {
+	t.SaveFeeHistory = append(t.SaveFeeHistory, fee)
+	return t.SaveFee1
+}
+
+func (t *testDB) GetEquitySnapshotsByStrategyCode(string) ([]*EquitySnapshot, error) {
+	return t.GetEquitySnapshotsByStrategyCode1, t.GetEquitySnapshotsByStrategyCode2
+}
+func (t *testDB) SaveEquitySnapshot(equitySnapshot *EquitySnapshot) error {
+	t.SaveEquitySnapshotHistory = append(t.SaveEquitySnapshotHistory, equitySnapshot)
+	return t.SaveEquitySnapshot1
+}
+func (t *testDB) GetRiskSetting() (*RiskSetting, error) {
+	return t.GetRiskSetting1, t.GetRiskSetting2
+}
+func (t *testDB) SaveRiskSetting(riskSetting *RiskSetting) error {
+	t.SaveRiskSettingHistory = append(t.SaveRiskSettingHistory, riskSetting)
+	return t.SaveRiskSetting1
+}
+
 func Test_db_SaveStrategy(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -370,10 +431,12 @@ func Test_db_GetActiveOrders(t *testing.T) {
 				{Code: "order-code-003", Status: OrderStatusDone},
 				{Code: "order-code-004", Status: OrderStatusCanceled},
 				{Code: "order-code-005", Status: OrderStatusInOrder},
+				{Code: "order-code-006", Status: OrderStatusPending},
 			},
 			want1: []*Order{
 				{Code: "order-code-002", Status: OrderStatusInOrder},
 				{Code: "order-code-005", Status: OrderStatusInOrder},
+				{Code: "order-code-006", Status: OrderStatusPending},
 			},
 			want2: nil},
 	}
@@ -501,11 +564,13 @@ func Test_db_CleanupOrders(t *testing.T) {
 				{Code: "order-code-003", Status: OrderStatusCanceled},
 				{Code: "order-code-004", Status: OrderStatusUnspecified},
 				{Code: "order-code-005", Status: OrderStatusInOrder},
+				{Code: "order-code-006", Status: OrderStatusPending},
 			},
 			want: nil,
 			wantOrders: []*Order{
 				{Code: "order-code-002", Status: OrderStatusInOrder},
 				{Code: "order-code-005", Status: OrderStatusInOrder},
+				{Code: "order-code-006", Status: OrderStatusPending},
 			}},
 	}
 
@@ -844,3 +909,285 @@ func Test_db_GetFourPriceBySymbolCodeAndExchange(t *testing.T) {
 		})
 	}
 }
+
+func Test_db_SaveTrade(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		dataset    []*Trade
+		arg        *Trade
+		want       error
+		wantTrades []*Trade
+	}{
+		{name: "同じコードのデータがなければinsertされる",
+			dataset: []*Trade{
+				{Code: "contract-001-position-001", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local), Quantity: 1, Profit: 10},
+			},
+			arg:  &Trade{Code: "contract-002-position-002", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 24, 11, 0, 0, 0, time.Local), Quantity: 1, Profit: -5},
+			want: nil,
+			wantTrades: []*Trade{
+				{Code: "contract-001-position-001", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local), Quantity: 1, Profit: 10},
+				{Code: "contract-002-position-002", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 24, 11, 0, 0, 0, time.Local), Quantity: 1, Profit: -5},
+			}},
+		{name: "同じコードのデータがあったら上書きされる",
+			dataset: []*Trade{
+				{Code: "contract-001-position-001", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local), Quantity: 1, Profit: 10},
+			},
+			arg:  &Trade{Code: "contract-001-position-001", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local), Quantity: 2, Profit: 20},
+			want: nil,
+			wantTrades: []*Trade{
+				{Code: "contract-001-position-001", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local), Quantity: 2, Profit: 20},
+			}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			d, _ := openDB(":memory:")
+			defer d.Close()
+			for _, data := range test.dataset {
+				if err := d.Exec(`insert into trades values ?`, data); err != nil {
+					t.Errorf("%s insert error\n%+v\n", t.Name(), err)
+				}
+			}
+
+			db := &db{db: d, logger: &testLogger{}}
+			got := db.SaveTrade(test.arg)
+
+			trades := make([]*Trade, 0)
+			res, _ := d.Query("select * from trades order by code")
+			defer res.Close()
+			_ = res.Iterate(func(d types.Document) error {
+				var trade Trade
+				_ = document.StructScan(d, &trade)
+				trades = append(trades, &trade)
+				return nil
+			})
+
+			if !reflect.DeepEqual(test.wantTrades, trades) || !errors.Is(got, test.want) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want, test.wantTrades, got, trades)
+			}
+		})
+	}
+}
+
+func Test_db_GetTrades(t *testing.T) {
+	t.Parallel()
+	dataset := []*Trade{
+		{Code: "contract-003-position-003", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Profit: 30},
+		{Code: "contract-001-position-001", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local), Profit: 10},
+		{Code: "contract-002-position-002", StrategyCode: "strategy-002", ExitDateTime: time.Date(2022, 1, 24, 14, 0, 0, 0, time.Local), Profit: 20},
+	}
+	tests := []struct {
+		name  string
+		arg1  time.Time
+		arg2  time.Time
+		want1 []*Trade
+		want2 error
+	}{
+		{name: "範囲内にデータがなければ空スライスを返す",
+			arg1:  time.Date(2022, 1, 26, 0, 0, 0, 0, time.Local),
+			arg2:  time.Date(2022, 1, 27, 0, 0, 0, 0, time.Local),
+			want1: []*Trade{},
+			want2: nil},
+		{name: "fromは含み、toは含まず、エグジット約定日時順に並べて返す",
+			arg1: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local),
+			arg2: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local),
+			want1: []*Trade{
+				{Code: "contract-001-position-001", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local), Profit: 10},
+				{Code: "contract-002-position-002", StrategyCode: "strategy-002", ExitDateTime: time.Date(2022, 1, 24, 14, 0, 0, 0, time.Local), Profit: 20},
+			},
+			want2: nil},
+		{name: "期間の指定がなければ全ての取引を返す",
+			arg1: time.Time{},
+			arg2: time.Date(9999, 12, 31, 0, 0, 0, 0, time.Local),
+			want1: []*Trade{
+				{Code: "contract-001-position-001", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local), Profit: 10},
+				{Code: "contract-002-position-002", StrategyCode: "strategy-002", ExitDateTime: time.Date(2022, 1, 24, 14, 0, 0, 0, time.Local), Profit: 20},
+				{Code: "contract-003-position-003", StrategyCode: "strategy-001", ExitDateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Profit: 30},
+			},
+			want2: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			d, _ := openDB(":memory:")
+			defer d.Close()
+			for _, data := range dataset {
+				if err := d.Exec(`insert into trades values ?`, data); err != nil {
+					t.Errorf("%s insert error\n%+v\n", t.Name(), err)
+				}
+			}
+
+			db := &db{db: d, logger: &testLogger{}}
+			got1, got2 := db.GetTrades(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_db_SaveFee(t *testing.T) {
+	t.Parallel()
+	d, _ := openDB(":memory:")
+	defer d.Close()
+	if err := d.Exec(`insert into fees values ?`, &Fee{Code: "position-001-commission", StrategyCode: "strategy-001", Fee: 55, DateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local)}); err != nil {
+		t.Errorf("%s insert error\n%+v\n", t.Name(), err)
+	}
+
+	db := &db{db: d, logger: &testLogger{}}
+	got := db.SaveFee(&Fee{Code: "position-001-commission", StrategyCode: "strategy-001", Fee: 60, DateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local)})
+
+	fees := make([]*Fee, 0)
+	res, _ := d.Query("select * from fees order by code")
+	defer res.Close()
+	_ = res.Iterate(func(d types.Document) error {
+		var fee Fee
+		_ = document.StructScan(d, &fee)
+		fees = append(fees, &fee)
+		return nil
+	})
+
+	want := []*Fee{{Code: "position-001-commission", StrategyCode: "strategy-001", Fee: 60, DateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local)}}
+	if !reflect.DeepEqual(want, fees) || got != nil {
+		t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), nil, want, got, fees)
+	}
+}
+
+func Test_db_GetFees(t *testing.T) {
+	t.Parallel()
+	d, _ := openDB(":memory:")
+	defer d.Close()
+	for _, data := range []*Fee{
+		{Code: "position-003-commission", StrategyCode: "strategy-001", Fee: 30, DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)},
+		{Code: "position-002-commission", StrategyCode: "strategy-001", Fee: 20, DateTime: time.Date(2022, 1, 24, 14, 0, 0, 0, time.Local)},
+		{Code: "position-001-commission", StrategyCode: "strategy-001", Fee: 10, DateTime: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local)},
+	} {
+		if err := d.Exec(`insert into fees values ?`, data); err != nil {
+			t.Errorf("%s insert error\n%+v\n", t.Name(), err)
+		}
+	}
+
+	db := &db{db: d, logger: &testLogger{}}
+	got1, got2 := db.GetFees(time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local))
+	want1 := []*Fee{
+		{Code: "position-001-commission", StrategyCode: "strategy-001", Fee: 10, DateTime: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local)},
+		{Code: "position-002-commission", StrategyCode: "strategy-001", Fee: 20, DateTime: time.Date(2022, 1, 24, 14, 0, 0, 0, time.Local)},
+	}
+	if !reflect.DeepEqual(want1, got1) || got2 != nil {
+		t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), want1, nil, got1, got2)
+	}
+}
+
+func Test_db_SaveEquitySnapshot_GetEquitySnapshotsByStrategyCode(t *testing.T) {
+	t.Parallel()
+	d, _ := openDB(":memory:")
+	defer d.Close()
+
+	db := &db{db: d, logger: &testLogger{}}
+	for _, e := range []*EquitySnapshot{
+		{Code: "strategy-001-20220125", StrategyCode: "strategy-001", Date: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local), Equity: 1000},
+		{Code: "strategy-001-20220124", StrategyCode: "strategy-001", Date: time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), Equity: 900},
+		{Code: "strategy-002-20220124", StrategyCode: "strategy-002", Date: time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), Equity: 500},
+		{Code: "strategy-001-20220125", StrategyCode: "strategy-001", Date: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local), Equity: 1100},
+	} {
+		if err := db.SaveEquitySnapshot(e); err != nil {
+			t.Errorf("%s save error\n%+v\n", t.Name(), err)
+		}
+	}
+
+	got1, got2 := db.GetEquitySnapshotsByStrategyCode("strategy-001")
+	want1 := []*EquitySnapshot{
+		{Code: "strategy-001-20220124", StrategyCode: "strategy-001", Date: time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), Equity: 900},
+		{Code: "strategy-001-20220125", StrategyCode: "strategy-001", Date: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local), Equity: 1100},
+	}
+	if !reflect.DeepEqual(want1, got1) || got2 != nil {
+		t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), want1, nil, got1, got2)
+	}
+}
+
+func Test_db_SaveRiskSetting_GetRiskSetting(t *testing.T) {
+	t.Parallel()
+	d, _ := openDB(":memory:")
+	defer d.Close()
+	db := &db{db: d, logger: &testLogger{}}
+
+	_, got1 := db.GetRiskSetting()
+	if !errors.Is(got1, ErrNoData) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), ErrNoData, got1)
+	}
+
+	_ = db.SaveRiskSetting(&RiskSetting{Limit: RiskLimit{MaxDailyLoss: 10_000}})
+	got2 := db.SaveRiskSetting(&RiskSetting{
+		Limit:          RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000},
+		KillSwitch:     true,
+		KillReason:     "manual",
+		KilledDateTime: time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)})
+	got3, got4 := db.GetRiskSetting()
+
+	want3 := &RiskSetting{
+		Limit:          RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000},
+		KillSwitch:     true,
+		KillReason:     "manual",
+		KilledDateTime: time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)}
+	if got2 != nil || !reflect.DeepEqual(want3, got3) || got4 != nil {
+		t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(), nil, want3, nil, got2, got3, got4)
+	}
+}
+
+func Test_db_DeleteOrderByCode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		dataset    []*Order
+		arg        string
+		want       error
+		wantOrders []*Order
+	}{
+		{name: "同じコードのデータがなければなにもしない",
+			dataset:    []*Order{{Code: "order-code-001"}, {Code: "order-code-002"}},
+			arg:        "order-code-003",
+			want:       nil,
+			wantOrders: []*Order{{Code: "order-code-001"}, {Code: "order-code-002"}}},
+		{name: "同じコードのデータがあったら削除される",
+			dataset:    []*Order{{Code: "order-code-001"}, {Code: "pending-strategy-code-001-1"}, {Code: "order-code-002"}},
+			arg:        "pending-strategy-code-001-1",
+			want:       nil,
+			wantOrders: []*Order{{Code: "order-code-001"}, {Code: "order-code-002"}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			d, _ := openDB(":memory:")
+			defer d.Close()
+			for _, data := range test.dataset {
+				if err := d.Exec(`insert into orders values ?`, data); err != nil {
+					t.Errorf("%s insert error\n%+v\n", t.Name(), err)
+				}
+			}
+
+			db := &db{db: d, logger: &testLogger{}}
+			got := db.DeleteOrderByCode(test.arg)
+
+			orders := make([]*Order, 0)
+			res, _ := d.Query("select * from orders order by code")
+			defer res.Close()
+			_ = res.Iterate(func(d types.Document) error {
+				var order Order
+				_ = document.StructScan(d, &order)
+				orders = append(orders, &order)
+				return nil
+			})
+
+			if !reflect.DeepEqual(test.wantOrders, orders) || !errors.Is(got, test.want) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want, test.wantOrders, got, orders)
+			}
+		})
+	}
+}
@@ -7,29 +7,40 @@ import (
 
 // Strategy - 戦略
 type Strategy struct {
-	Code                 string            // 戦略コード
-	SymbolCode           string            // 銘柄コード
-	Exchange             Exchange          // 市場
-	Product              Product           // 商品種別
-	MarginTradeType      MarginTradeType   // 信用取引区分
-	EntrySide            Side              // エントリー方向
-	Cash                 float64           // 運用中現金
-	BasePrice            float64           // 基準価格
-	BasePriceDateTime    time.Time         // 基準価格日時
-	LastContractPrice    float64           // 最終約定価格
-	LastContractDateTime time.Time         // 最終約定価格日時
-	MaxContractPrice     float64           // 最大約定価格
-	MaxContractDateTime  time.Time         // 最大約定価格日時
-	MinContractPrice     float64           // 最小約定価格
-	MinContractDateTime  time.Time         // 最小約定価格日時
-	TickGroup            TickGroup         // 呼値グループ
-	TradingUnit          float64           // 売買単位
-	RebalanceStrategy    RebalanceStrategy // リバランス戦略
-	GridStrategy         GridStrategy      // グリッド戦略
-	CancelStrategy       CancelStrategy    // 全取消戦略
-	ExitStrategy         ExitStrategy      // 全エグジット戦略
-	Account              Account           // 口座情報
-	Runnable             bool              // 実行可能かどうか
+	Code                   string                 // 戦略コード
+	SymbolCode             string                 // 銘柄コード
+	Exchange               Exchange               // 市場
+	Product                Product                // 商品種別
+	MarginTradeType        MarginTradeType        // 信用取引区分
+	EntrySide              Side                   // エントリー方向
+	Cash                   float64                // 運用中現金
+	BasePrice              float64                // 基準価格
+	BasePriceDateTime      time.Time              // 基準価格日時
+	LastContractPrice      float64                // 最終約定価格
+	LastContractDateTime   time.Time              // 最終約定価格日時
+	MaxContractPrice       float64                // 最大約定価格
+	MaxContractDateTime    time.Time              // 最大約定価格日時
+	MinContractPrice       float64                // 最小約定価格
+	MinContractDateTime    time.Time              // 最小約定価格日時
+	TickGroup              TickGroup              // 呼値グループ
+	TradingUnit            float64                // 売買単位
+	RebalanceStrategy      RebalanceStrategy      // リバランス戦略
+	GridStrategy           GridStrategy           // グリッド戦略
+	CancelStrategy         CancelStrategy         // 全取消戦略
+	ExitStrategy           ExitStrategy           // 全エグジット戦略
+	ProtectiveStopStrategy ProtectiveStopStrategy // 保護用の逆指値戦略
+	RiskExitStrategy       RiskExitStrategy       // 損切り・利確戦略
+	RiskLimit              RiskLimit              // 戦略ごとの注文や損失の上限
+	FeeStrategy            FeeStrategy            // 手数料等の費用の設定
+	OrphanOrderStrategy    OrphanOrderStrategy    // 孤立注文の処理戦略
+	OrderExpireDay         ExpireDayType          // 指値注文の有効期限
+	Account                Account                // 口座情報
+	PaperTrading           bool                   // 仮想売買(証券会社に注文を送らずに手元で約定させる)かどうか
+	Runnable               bool                   // 実行可能かどうか
+	PauseReason            PauseReason            // 一時停止の理由
+	PausedDateTime         time.Time              // 一時停止日時
+	PriceBandState         PriceBandState         // 価格帯の判定結果
+	TrendFilterState       TrendFilterState       // トレンドフィルタの判定結果
 }
 
 func (e *Strategy) String() string {
@@ -41,7 +52,12 @@ func (e *Strategy) String() string {
 }
 
 func (e *Strategy) IsRunnable() bool {
-	return e.Runnable
+	return e.Runnable && !e.IsPaused()
+}
+
+// IsPaused - 一時停止中かどうか
+func (e *Strategy) IsPaused() bool {
+	return e.PauseReason != PauseReasonUnspecified
 }
 
 // Order - 注文
@@ -56,10 +72,12 @@ type Order struct {
 	TradeType        TradeType       // 取引種別
 	Side             Side            // 方向
 	ExecutionType    ExecutionType   // 執行条件
-	Price            float64         // 指値価格
+	Price            float64         // 指値価格 (逆指値では発火後の指値価格)
+	TriggerPrice     float64         // 逆指値の発火価格
 	OrderQuantity    float64         // 注文数量
 	ContractQuantity float64         // 約定数量
 	AccountType      AccountType     // 口座種別
+	ExpireDay        time.Time       // 有効期限(年月日) ゼロ値なら当日
 	OrderDateTime    time.Time       // 注文日時
 	ContractDateTime time.Time       // 約定日時
 	CancelDateTime   time.Time       // 取消日時
@@ -76,8 +94,14 @@ func (e *Order) String() string {
 }
 
 // IsActive - 有効な注文か (更新される可能性のある注文)
+// 送信中の注文も、証券会社に受け付けられている可能性があるため有効な注文として扱う
 func (e *Order) IsActive() bool {
-	return e.Status == OrderStatusInOrder
+	return e.Status == OrderStatusInOrder || e.Status == OrderStatusPending
+}
+
+// IsPending - 送信中の注文か
+func (e *Order) IsPending() bool {
+	return e.Status == OrderStatusPending
 }
 
 // IsEqualSecurityOrder - 証券会社の注文と一致しているか
@@ -164,3 +188,72 @@ type FourPrice struct {
 	Low        float64   // 安値
 	Close      float64   // 終値
 }
+
+// Trade - 確定した取引
+// エグジット約定で返済したエントリーポジションごとに1件作られる
+type Trade struct {
+	Code              string    // 取引コード(エグジットの約定コード-エントリーのポジションコード)
+	StrategyCode      string    // 戦略コード
+	SymbolCode        string    // 銘柄コード
+	Exchange          Exchange  // 市場
+	Product           Product   // 商品種別
+	Side              Side      // エントリーの売買方向
+	EntryPositionCode string    // エントリーのポジションコード
+	EntryPrice        float64   // エントリー約定値
+	EntryDateTime     time.Time // エントリー約定日時
+	ExitOrderCode     string    // エグジットの注文コード
+	ExitPrice         float64   // エグジット約定値
+	ExitDateTime      time.Time // エグジット約定日時
+	Quantity          float64   // 数量
+	Profit            float64   // 確定損益
+}
+
+func (e *Trade) String() string {
+	if b, err := json.Marshal(e); err != nil {
+		return err.Error()
+	} else {
+		return string(b)
+	}
+}
+
+// Fee - 約定によって確定した手数料等の費用
+type Fee struct {
+	Code         string    // 費用コード(約定のポジションコード-費用種別 か 約定のポジションコード-エントリーのポジションコード-費用種別)
+	StrategyCode string    // 戦略コード
+	OrderCode    string    // 注文コード
+	PositionCode string    // 約定のポジションコード
+	FeeType      FeeType   // 費用種別
+	Amount       float64   // 費用の計算対象の金額(売買手数料なら約定代金、金利や貸株料なら建玉の代金)
+	Fee          float64   // 費用
+	DateTime     time.Time // 計上日時
+}
+
+func (e *Fee) String() string {
+	if b, err := json.Marshal(e); err != nil {
+		return err.Error()
+	} else {
+		return string(b)
+	}
+}
+
+// EquitySnapshot - 引け後の戦略の評価額
+type EquitySnapshot struct {
+	Code          string    // スナップショットコード(戦略コード-yyyymmdd)
+	StrategyCode  string    // 戦略コード
+	Date          time.Time // 日付
+	Cash          float64   // 運用中現金
+	ClosePrice    float64   // 終値
+	PositionValue float64   // 終値で評価したポジションの評価額
+	Equity        float64   // 運用中現金 + ポジションの評価額
+	HeldQuantity  float64   // 保有しているポジションの数量
+	OpenQuantity  float64   // 注文中で約定していない数量
+	DateTime      time.Time // 記録日時
+}
+
+func (e *EquitySnapshot) String() string {
+	if b, err := json.Marshal(e); err != nil {
+		return err.Error()
+	} else {
+		return string(b)
+	}
+}
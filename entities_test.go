@@ -22,6 +22,9 @@ func Test_Order_IsActive(t *testing.T) {
 		{name: "取消済みの注文は有効ではない",
 			order: &Order{Status: OrderStatusCanceled},
 			want1: false},
+		{name: "送信中の注文は有効な注文",
+			order: &Order{Status: OrderStatusPending},
+			want1: true},
 	}
 
 	for _, test := range tests {
@@ -305,6 +308,7 @@ func Test_Strategy_IsRunnable(t *testing.T) {
 	}{
 		{name: "実行不可ならfalse", strategy: &Strategy{Runnable: false}, want1: false},
 		{name: "実行可能ならtrue", strategy: &Strategy{Runnable: true}, want1: true},
+		{name: "実行可能でも一時停止中ならfalse", strategy: &Strategy{Runnable: true, PauseReason: PauseReasonMaxLoss}, want1: false},
 	}
 
 	for _, test := range tests {
@@ -318,3 +322,50 @@ func Test_Strategy_IsRunnable(t *testing.T) {
 		})
 	}
 }
+
+func Test_Strategy_IsPaused(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		strategy *Strategy
+		want1    bool
+	}{
+		{name: "一時停止の理由がなければfalse", strategy: &Strategy{}, want1: false},
+		{name: "一時停止の理由があればtrue", strategy: &Strategy{PauseReason: PauseReasonTargetProfit}, want1: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.strategy.IsPaused()
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_Order_IsPending(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		order *Order
+		want1 bool
+	}{
+		{name: "送信中の注文ならtrue", order: &Order{Status: OrderStatusPending}, want1: true},
+		{name: "注文中の注文ならfalse", order: &Order{Status: OrderStatusInOrder}, want1: false},
+		{name: "未指定ならfalse", order: &Order{Status: OrderStatusUnspecified}, want1: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.order.IsPending()
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package gridon
 
-import "math"
+import (
+	"math"
+	"time"
+)
 
 // Exchange - 市場
 type Exchange string
@@ -51,6 +54,7 @@ const (
 	OrderStatusInOrder     OrderStatus = "in_order" // 注文中
 	OrderStatusDone        OrderStatus = "done"     // 約定済み
 	OrderStatusCanceled    OrderStatus = "canceled" // 取消済み
+	OrderStatusPending     OrderStatus = "pending"  // 送信中 (証券会社に受け付けられたかが確定していない)
 )
 
 // AccountType - 口座種別
@@ -91,8 +95,15 @@ const (
 	ExecutionTypeMarketMorningClose   ExecutionType = "market_morning_close"   // 前場引成
 	ExecutionTypeMarketAfternoonClose ExecutionType = "market_afternoon_close" // 後場引成
 	ExecutionTypeLimit                ExecutionType = "limit"                  // 指値
+	ExecutionTypeStopMarket           ExecutionType = "stop_market"            // 逆指値(成行)
+	ExecutionTypeStopLimit            ExecutionType = "stop_limit"             // 逆指値(指値)
 )
 
+// IsStop - 逆指値の執行条件かどうか
+func (e ExecutionType) IsStop() bool {
+	return e == ExecutionTypeStopMarket || e == ExecutionTypeStopLimit
+}
+
 // SortOrder - 並び順
 type SortOrder string
 
@@ -135,6 +146,87 @@ func (e Rounding) Calc(v float64) float64 {
 	}
 }
 
+// GridSpacing - グリッドの間隔の取り方
+type GridSpacing string
+
+const (
+	GridSpacingUnspecified GridSpacing = ""          // 未指定(ティック数)
+	GridSpacingTick        GridSpacing = "tick"      // ティック数
+	GridSpacingGeometric   GridSpacing = "geometric" // 1つ内側のグリッドからの割合
+)
+
+// PriceBandSource - 価格帯の基準
+type PriceBandSource string
+
+const (
+	PriceBandSourceUnspecified   PriceBandSource = ""               // 未指定(絶対価格)
+	PriceBandSourceAbsolute      PriceBandSource = "absolute"       // 絶対価格
+	PriceBandSourcePrevClose     PriceBandSource = "prev_close"     // 前日の終値
+	PriceBandSourceMovingAverage PriceBandSource = "moving_average" // 終値の移動平均
+)
+
+// PriceBandPolicy - 基準価格が価格帯を外れたときの対応
+type PriceBandPolicy string
+
+const (
+	PriceBandPolicyUnspecified   PriceBandPolicy = ""               // 未指定(何もしない)
+	PriceBandPolicyKeep          PriceBandPolicy = "keep"           // 注文もポジションもそのままにする
+	PriceBandPolicyCancelEntries PriceBandPolicy = "cancel_entries" // エントリー注文だけを取り消す
+	PriceBandPolicyFlatten       PriceBandPolicy = "flatten"        // 注文を全て取り消してポジションを全てエグジットする
+)
+
+// TrendFilterType - トレンドの判定方法
+type TrendFilterType string
+
+const (
+	TrendFilterTypeUnspecified TrendFilterType = ""             // 未指定(移動平均からの乖離)
+	TrendFilterTypeMASlope     TrendFilterType = "ma_slope"     // 現在値を含めた移動平均の傾き
+	TrendFilterTypeMADeviation TrendFilterType = "ma_deviation" // 移動平均からの現在値の乖離
+	TrendFilterTypeBreakout    TrendFilterType = "breakout"     // 前日の高値・安値のブレイク
+)
+
+// TrendFilterAction - トレンドフィルタが効いている間の対応
+type TrendFilterAction string
+
+const (
+	TrendFilterActionUnspecified TrendFilterAction = ""             // 未指定(エントリーを止める)
+	TrendFilterActionStopEntry   TrendFilterAction = "stop_entry"   // エントリー注文を取り消し、エントリー側のグリッドを置かない
+	TrendFilterActionReduceEntry TrendFilterAction = "reduce_entry" // エントリー側のグリッドの数量を減らす
+)
+
+// VolatilityType - ボラティリティの計算方法
+type VolatilityType string
+
+const (
+	VolatilityTypeUnspecified VolatilityType = ""          // 未指定(ATR)
+	VolatilityTypeATR         VolatilityType = "atr"       // 真の値幅の平均を最新の終値の呼値単位でtick数にする
+	VolatilityTypeATRTicks    VolatilityType = "atr_ticks" // 日ごとに真の値幅をtick数にしてから平均する
+	VolatilityTypeStdDev      VolatilityType = "stddev"    // 終値の騰落率の標準偏差を最新の終値にかけ、呼値単位でtick数にする
+)
+
+// QuantityProfileType - グリッドごとの数量の決め方
+type QuantityProfileType string
+
+const (
+	QuantityProfileTypeUnspecified QuantityProfileType = ""          // 未指定(一定で、現金による上限と売買単位の丸めをしない)
+	QuantityProfileTypeConstant    QuantityProfileType = "constant"  // 一定
+	QuantityProfileTypeLinear      QuantityProfileType = "linear"    // 基準価格から離れるごとに一定数量ずつ増やす
+	QuantityProfileTypeGeometric   QuantityProfileType = "geometric" // 基準価格から離れるごとに一定倍率ずつ増やす
+	QuantityProfileTypeCustom      QuantityProfileType = "custom"    // グリッドごとに指定する
+)
+
+// BasePriceSourceType - 基準価格の取り方
+type BasePriceSourceType string
+
+const (
+	BasePriceSourceTypeUnspecified   BasePriceSourceType = ""               // 未指定(最終約定価格)
+	BasePriceSourceTypeLastContract  BasePriceSourceType = "last_contract"  // 最終約定価格(約定がなければ現在値)
+	BasePriceSourceTypeVWAP          BasePriceSourceType = "vwap"           // 当日のVWAP
+	BasePriceSourceTypeMovingAverage BasePriceSourceType = "moving_average" // 四本値の終値の移動平均
+	BasePriceSourceTypeOpen          BasePriceSourceType = "open"           // 当日の始値
+	BasePriceSourceTypeMid           BasePriceSourceType = "mid"            // 最良気配の仲値
+)
+
 // Operation - 演算子
 type Operation string
 
@@ -170,3 +262,160 @@ func (e Operation) Calc(a, b float64) float64 {
 		return a + b
 	}
 }
+
+// OptimizerRankBy - 最適化結果の順位付けの基準
+type OptimizerRankBy string
+
+const (
+	OptimizerRankByUnspecified OptimizerRankBy = ""             // 未指定, 純損益
+	OptimizerRankByNetProfit   OptimizerRankBy = "net_profit"   // 純損益の大きい順
+	OptimizerRankByMaxDrawdown OptimizerRankBy = "max_drawdown" // 最大ドローダウンの小さい順
+	OptimizerRankByTradeCount  OptimizerRankBy = "trade_count"  // 約定回数の多い順
+)
+
+// CommissionType - 手数料プラン
+type CommissionType string
+
+const (
+	CommissionTypeUnspecified CommissionType = ""             // 未指定, 手数料なし
+	CommissionTypeFlat        CommissionType = "flat"         // 1約定ごとの定額
+	CommissionTypeDailyTiered CommissionType = "daily_tiered" // 1日の約定代金に応じた段階制
+)
+
+// FeeType - 費用種別
+type FeeType string
+
+const (
+	FeeTypeUnspecified    FeeType = ""                // 未指定
+	FeeTypeCommission     FeeType = "commission"      // 売買手数料
+	FeeTypeMarginInterest FeeType = "margin_interest" // 信用買建の金利
+	FeeTypeLendingFee     FeeType = "lending_fee"     // 信用売建の貸株料
+)
+
+// PositionMismatchType - ポジション照合の不一致の種類
+type PositionMismatchType string
+
+const (
+	PositionMismatchTypeUnspecified PositionMismatchType = ""         // 未指定
+	PositionMismatchTypeQuantity    PositionMismatchType = "quantity" // 保有数量の不一致
+	PositionMismatchTypePrice       PositionMismatchType = "price"    // 約定値の不一致
+	PositionMismatchTypeMissing     PositionMismatchType = "missing"  // 証券会社にポジションがない
+)
+
+// OrphanOrderPolicy - 孤立注文(証券会社にだけある注文)の処理方針
+type OrphanOrderPolicy string
+
+const (
+	OrphanOrderPolicyUnspecified OrphanOrderPolicy = ""       // 未指定, 何もしない
+	OrphanOrderPolicyAdopt       OrphanOrderPolicy = "adopt"  // 戦略の注文として取り込む
+	OrphanOrderPolicyCancel      OrphanOrderPolicy = "cancel" // 取り消す
+)
+
+// RequestCategory - kabuステーションAPIのリクエストの流量制限の区分
+type RequestCategory string
+
+const (
+	RequestCategoryUnspecified RequestCategory = ""      // 未指定
+	RequestCategoryOrder       RequestCategory = "order" // 発注系
+	RequestCategoryInfo        RequestCategory = "info"  // 情報系
+)
+
+// RequestPriority - 流量制限で待たされたリクエストを処理する優先度
+type RequestPriority string
+
+const (
+	RequestPriorityUnspecified RequestPriority = ""       // 未指定
+	RequestPriorityHigh        RequestPriority = "high"   // 高 (取消やエグジット)
+	RequestPriorityNormal      RequestPriority = "normal" // 中 (情報取得)
+	RequestPriorityLow         RequestPriority = "low"    // 低 (新規のエントリー)
+)
+
+// rank - 優先度の順位 (小さいほど先に処理する)
+func (e RequestPriority) rank() int {
+	switch e {
+	case RequestPriorityHigh:
+		return 0
+	case RequestPriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// DeliveryType - 受渡区分
+type DeliveryType string
+
+const (
+	DeliveryTypeUnspecified DeliveryType = ""     // 未指定
+	DeliveryTypeAuto        DeliveryType = "auto" // 自動振替
+	DeliveryTypeCash        DeliveryType = "cash" // お預かり金
+)
+
+// FundType - 資産区分(預り区分)
+type FundType string
+
+const (
+	FundTypeUnspecified      FundType = ""                  // 未指定
+	FundTypeProtected        FundType = "protected"         // 保護
+	FundTypeSubstituteMargin FundType = "substitute_margin" // 信用代用
+	FundTypeMarginTrading    FundType = "margin_trading"    // 信用取引
+)
+
+// ExpireDayType - 注文の有効期限の指定方法
+type ExpireDayType string
+
+const (
+	ExpireDayTypeUnspecified     ExpireDayType = ""                  // 未指定, 当日
+	ExpireDayTypeToday           ExpireDayType = "today"             // 当日
+	ExpireDayTypeNextBusinessDay ExpireDayType = "next_business_day" // 翌営業日
+)
+
+// ExpireDay - 注文日時から有効期限の日付を返す
+// 当日ならゼロ値を返し、証券会社の当日扱いに任せる
+// 翌営業日は土日に加えて祝日と年末年始も飛ばす
+func (e ExpireDayType) ExpireDay(now time.Time) time.Time {
+	switch e {
+	case ExpireDayTypeNextBusinessDay:
+		return nextBusinessDay(now)
+	}
+	return time.Time{}
+}
+
+// PauseReason - 戦略の一時停止理由
+type PauseReason string
+
+const (
+	PauseReasonUnspecified  PauseReason = ""              // 未指定, 一時停止していない
+	PauseReasonMaxLoss      PauseReason = "max_loss"      // 含み損が上限額を超えた
+	PauseReasonMaxLossRate  PauseReason = "max_loss_rate" // 含み損が運用中現金に対する上限割合を超えた
+	PauseReasonLowerPrice   PauseReason = "lower_price"   // 現在値が下限を下回った
+	PauseReasonUpperPrice   PauseReason = "upper_price"   // 現在値が上限を上回った
+	PauseReasonTargetProfit PauseReason = "target_profit" // 含み益が利確額に達した
+)
+
+// IsExit - ポジションを全てエグジットする一時停止理由か
+func (e PauseReason) IsExit() bool {
+	switch e {
+	case PauseReasonMaxLoss, PauseReasonMaxLossRate, PauseReasonLowerPrice, PauseReasonUpperPrice, PauseReasonTargetProfit:
+		return true
+	}
+	return false
+}
+
+// CircuitState - サーキットブレーカーの状態
+type CircuitState string
+
+const (
+	CircuitStateUnspecified CircuitState = ""          // 未指定
+	CircuitStateClosed      CircuitState = "closed"    // 閉(注文できる)
+	CircuitStateOpen        CircuitState = "open"      // 開(注文を止めている)
+	CircuitStateHalfOpen    CircuitState = "half_open" // 半開(試しに注文して結果を待っている)
+)
+
+// CircuitOperation - サーキットブレーカーに成功や失敗を記録する処理
+type CircuitOperation string
+
+const (
+	CircuitOperationUnspecified CircuitOperation = ""         // 未指定
+	CircuitOperationConfirm     CircuitOperation = "confirm"  // 約定確認
+	CircuitOperationLeveling    CircuitOperation = "leveling" // グリッドの整地
+)
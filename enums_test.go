@@ -3,6 +3,7 @@ package gridon
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_Side_Turn(t *testing.T) {
@@ -125,3 +126,86 @@ func Test_Operation_Calc(t *testing.T) {
 		})
 	}
 }
+
+func Test_ExpireDayType_ExpireDay(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		expireDayType ExpireDayType
+		arg1          time.Time
+		want1         time.Time
+	}{
+		{name: "未指定 ならゼロ値", expireDayType: ExpireDayTypeUnspecified, arg1: time.Date(2022, 1, 5, 10, 0, 0, 0, time.Local), want1: time.Time{}},
+		{name: "当日 ならゼロ値", expireDayType: ExpireDayTypeToday, arg1: time.Date(2022, 1, 5, 10, 0, 0, 0, time.Local), want1: time.Time{}},
+		{name: "翌営業日 なら翌日の日付", expireDayType: ExpireDayTypeNextBusinessDay, arg1: time.Date(2022, 1, 5, 10, 0, 0, 0, time.Local), want1: time.Date(2022, 1, 6, 0, 0, 0, 0, time.Local)},
+		{name: "翌営業日 で金曜日なら月曜日の日付", expireDayType: ExpireDayTypeNextBusinessDay, arg1: time.Date(2022, 1, 14, 10, 0, 0, 0, time.Local), want1: time.Date(2022, 1, 17, 0, 0, 0, 0, time.Local)},
+		{name: "翌営業日 で土曜日なら月曜日の日付", expireDayType: ExpireDayTypeNextBusinessDay, arg1: time.Date(2022, 1, 15, 10, 0, 0, 0, time.Local), want1: time.Date(2022, 1, 17, 0, 0, 0, 0, time.Local)},
+		{name: "翌営業日 で月末なら翌月の日付", expireDayType: ExpireDayTypeNextBusinessDay, arg1: time.Date(2022, 3, 31, 10, 0, 0, 0, time.Local), want1: time.Date(2022, 4, 1, 0, 0, 0, 0, time.Local)},
+		{name: "翌営業日 で翌日が祝日なら祝日の翌日の日付", expireDayType: ExpireDayTypeNextBusinessDay, arg1: time.Date(2022, 2, 10, 10, 0, 0, 0, time.Local), want1: time.Date(2022, 2, 14, 0, 0, 0, 0, time.Local)},
+		{name: "翌営業日 で大納会なら年明けの大発会の日付", expireDayType: ExpireDayTypeNextBusinessDay, arg1: time.Date(2021, 12, 30, 10, 0, 0, 0, time.Local), want1: time.Date(2022, 1, 4, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.expireDayType.ExpireDay(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_ExecutionType_IsStop(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg  ExecutionType
+		want bool
+	}{
+		{name: "未指定 はfalse", arg: ExecutionTypeUnspecified, want: false},
+		{name: "成行 はfalse", arg: ExecutionTypeMarket, want: false},
+		{name: "指値 はfalse", arg: ExecutionTypeLimit, want: false},
+		{name: "逆指値(成行) はtrue", arg: ExecutionTypeStopMarket, want: true},
+		{name: "逆指値(指値) はtrue", arg: ExecutionTypeStopLimit, want: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.arg.IsStop()
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_PauseReason_IsExit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg  PauseReason
+		want bool
+	}{
+		{name: "未指定 はfalse", arg: PauseReasonUnspecified, want: false},
+		{name: "含み損の上限額 はtrue", arg: PauseReasonMaxLoss, want: true},
+		{name: "含み損の上限割合 はtrue", arg: PauseReasonMaxLossRate, want: true},
+		{name: "現在値の下限 はtrue", arg: PauseReasonLowerPrice, want: true},
+		{name: "現在値の上限 はtrue", arg: PauseReasonUpperPrice, want: true},
+		{name: "利確 はtrue", arg: PauseReasonTargetProfit, want: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.arg.IsExit()
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
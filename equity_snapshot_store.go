@@ -0,0 +1,69 @@
+package gridon
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	equitySnapshotStoreSingleton    IEquitySnapshotStore
+	equitySnapshotStoreSingletonMtx sync.Mutex
+)
+
+// getEquitySnapshotStore - 評価額スナップショットストアの取得
+func getEquitySnapshotStore(db IDB) IEquitySnapshotStore {
+	equitySnapshotStoreSingletonMtx.Lock()
+	defer equitySnapshotStoreSingletonMtx.Unlock()
+
+	if equitySnapshotStoreSingleton == nil {
+		equitySnapshotStoreSingleton = &equitySnapshotStore{
+			db: db,
+		}
+	}
+
+	return equitySnapshotStoreSingleton
+}
+
+// IEquitySnapshotStore - 評価額スナップショットストアのインターフェース
+type IEquitySnapshotStore interface {
+	Save(equitySnapshot *EquitySnapshot) error
+	GetByStrategyCode(strategyCode string, from time.Time, to time.Time) ([]*EquitySnapshot, error)
+}
+
+// equitySnapshotStore - 評価額スナップショットストア
+// 1日1件しか増えず、参照も成績の計算時だけなので、メモリには持たない
+type equitySnapshotStore struct {
+	db  IDB
+	mtx sync.Mutex
+}
+
+// Save - 評価額スナップショットの保存
+func (s *equitySnapshotStore) Save(equitySnapshot *EquitySnapshot) error {
+	if equitySnapshot == nil {
+		return ErrNilArgument
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.db.SaveEquitySnapshot(equitySnapshot)
+}
+
+// GetByStrategyCode - 戦略を指定して、日付がfrom以降to未満の評価額スナップショットを日付順に取り出す
+func (s *equitySnapshotStore) GetByStrategyCode(strategyCode string, from time.Time, to time.Time) ([]*EquitySnapshot, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	snapshots, err := s.db.GetEquitySnapshotsByStrategyCode(strategyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*EquitySnapshot, 0)
+	for _, e := range snapshots {
+		if !e.Date.Before(from) && e.Date.Before(to) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,89 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_getEquitySnapshotStore(t *testing.T) {
+	t.Parallel()
+
+	db := &testDB{}
+	want1 := &equitySnapshotStore{db: db}
+	got1 := getEquitySnapshotStore(db)
+
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_equitySnapshotStore_Save(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                          string
+		db                            *testDB
+		arg                           *EquitySnapshot
+		want                          error
+		wantSaveEquitySnapshotHistory []interface{}
+	}{
+		{name: "nilならエラー", db: &testDB{}, arg: nil, want: ErrNilArgument},
+		{name: "DBの保存に失敗したらエラー",
+			db:                            &testDB{SaveEquitySnapshot1: ErrUnknown},
+			arg:                           &EquitySnapshot{Code: "strategy-code-001-20220125"},
+			want:                          ErrUnknown,
+			wantSaveEquitySnapshotHistory: []interface{}{&EquitySnapshot{Code: "strategy-code-001-20220125"}}},
+		{name: "DBに保存できたらnil",
+			db:                            &testDB{},
+			arg:                           &EquitySnapshot{Code: "strategy-code-001-20220125"},
+			want:                          nil,
+			wantSaveEquitySnapshotHistory: []interface{}{&EquitySnapshot{Code: "strategy-code-001-20220125"}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &equitySnapshotStore{db: test.db}
+			got := store.Save(test.arg)
+			if !errors.Is(got, test.want) || !reflect.DeepEqual(test.wantSaveEquitySnapshotHistory, test.db.SaveEquitySnapshotHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want, test.wantSaveEquitySnapshotHistory, got, test.db.SaveEquitySnapshotHistory)
+			}
+		})
+	}
+}
+
+func Test_equitySnapshotStore_GetByStrategyCode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		db    *testDB
+		want1 []*EquitySnapshot
+		want2 error
+	}{
+		{name: "DBがエラーを返したらエラー", db: &testDB{GetEquitySnapshotsByStrategyCode2: ErrUnknown}, want1: nil, want2: ErrUnknown},
+		{name: "fromは含み、toは含まない範囲のスナップショットを返す",
+			db: &testDB{GetEquitySnapshotsByStrategyCode1: []*EquitySnapshot{
+				{Date: time.Date(2022, 1, 23, 0, 0, 0, 0, time.Local)},
+				{Date: time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local)},
+				{Date: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local)},
+			}},
+			want1: []*EquitySnapshot{
+				{Date: time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local)},
+			},
+			want2: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &equitySnapshotStore{db: test.db}
+			got1, got2 := store.GetByStrategyCode("strategy-code-001", time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local))
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
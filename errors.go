@@ -20,4 +20,15 @@ var (
 	ErrCannotGetBasePrice      = errors.New("can not get base price")
 	ErrZeroGridWidth           = errors.New("zero grid width")
 	ErrShortSellingRestriction = errors.New("short selling restriction")
+	ErrInvalidRange            = errors.New("invalid range")
+	ErrNotEnoughPrices         = errors.New("not enough prices")
+	ErrKabusTransient          = errors.New("kabus transient")
+	ErrInsufficientFunds       = errors.New("insufficient funds")
+	ErrSymbolHalted            = errors.New("symbol halted")
+	ErrAuthExpired             = errors.New("auth expired")
+	ErrDuplicateOrder          = errors.New("duplicate order")
+	ErrNotCancelable           = errors.New("not cancelable")
+	ErrPendingOrder            = errors.New("pending order")
+	ErrRiskLimitExceeded       = errors.New("risk limit exceeded")
+	ErrKillSwitch              = errors.New("kill switch")
 )
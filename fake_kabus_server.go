@@ -0,0 +1,362 @@
+package gridon
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"gitlab.com/tsuchinaga/kabus-grpc-server/kabuspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FakeKabusSymbol - 疑似kabusサーバで扱う銘柄
+type FakeKabusSymbol struct {
+	SymbolCode  string          // 銘柄コード
+	Exchange    Exchange        // 市場
+	TradingUnit float64         // 売買単位
+	TickGroup   TickGroup       // 呼値グループ
+	Prices      []BacktestPrice // 価格の予定
+}
+
+// NewFakeKabusServer - 疑似kabusサーバの取得
+// kabuステーションのない環境で、gridonを丸ごと動かして確認するために使う
+func NewFakeKabusServer(symbols []FakeKabusSymbol) kabuspb.KabusServiceServer {
+	return newFakeKabusServer(symbols, newClock())
+}
+
+func newFakeKabusServer(symbols []FakeKabusSymbol, clock IClock) *fakeKabusServer {
+	store := map[SymbolKey]FakeKabusSymbol{}
+	for _, s := range symbols {
+		prices := make([]BacktestPrice, len(s.Prices))
+		copy(prices, s.Prices)
+		sort.SliceStable(prices, func(i, j int) bool { return prices[i].DateTime.Before(prices[j].DateTime) })
+		s.Prices = prices
+		store[SymbolKey{SymbolCode: s.SymbolCode, Exchange: s.Exchange}] = s
+	}
+
+	return &fakeKabusServer{
+		clock:    clock,
+		book:     newOrderBook("fake"),
+		symbols:  store,
+		kabusAPI: &kabusAPI{},
+	}
+}
+
+// fakeKabusServer - 疑似kabusサーバ
+// 予定された価格を時刻に合わせて配信し、注文は疑似注文板で約定させる
+type fakeKabusServer struct {
+	kabuspb.UnimplementedKabusServiceServer
+	clock    IClock
+	book     *orderBook
+	symbols  map[SymbolKey]FakeKabusSymbol
+	kabusAPI *kabusAPI // kabuspbとの値の変換に使う
+}
+
+// symbol - 銘柄の取得
+func (s *fakeKabusServer) symbol(symbolCode string, exchange Exchange) (FakeKabusSymbol, error) {
+	symbol, ok := s.symbols[SymbolKey{SymbolCode: symbolCode, Exchange: exchange}]
+	if !ok {
+		return FakeKabusSymbol{}, status.Errorf(codes.NotFound, "symbol not found: %s", symbolCode)
+	}
+	return symbol, nil
+}
+
+// fourPrice - 指定した日時時点の当日の四本値
+// 当日の価格がまだなければnilを返す
+func (s *fakeKabusServer) fourPrice(symbol FakeKabusSymbol, now time.Time) *FourPrice {
+	var fourPrice *FourPrice
+	for _, p := range symbol.Prices {
+		if now.Before(p.DateTime) {
+			break
+		}
+		if p.DateTime.Format("20060102") != now.Format("20060102") {
+			continue
+		}
+
+		if fourPrice == nil {
+			fourPrice = &FourPrice{SymbolCode: symbol.SymbolCode, Exchange: symbol.Exchange, Open: p.Price, High: p.Price, Low: p.Price}
+		}
+		if fourPrice.High < p.Price {
+			fourPrice.High = p.Price
+		}
+		if p.Price < fourPrice.Low {
+			fourPrice.Low = p.Price
+		}
+		fourPrice.Close = p.Price
+		fourPrice.DateTime = p.DateTime
+	}
+	return fourPrice
+}
+
+// match - 注文中の注文を現在値で約定判定し、期限切れの注文を失効させる
+func (s *fakeKabusServer) match(now time.Time) {
+	for _, key := range s.book.ActiveSymbols() {
+		symbol, ok := s.symbols[key]
+		if !ok {
+			continue
+		}
+		if fourPrice := s.fourPrice(symbol, now); fourPrice != nil {
+			s.book.Match(key.SymbolCode, key.Exchange, fourPrice.Close, now)
+		}
+	}
+	s.book.Expire(now)
+}
+
+// tickGroupTo - TickGroupをkabus用に変換
+func (s *fakeKabusServer) tickGroupTo(tickGroup TickGroup) string {
+	switch tickGroup {
+	case TickGroupOther:
+		return "10000"
+	case TickGroupTopix100:
+		return "10003"
+	}
+	return ""
+}
+
+// executionTypeFrom - kabusの執行条件をExecutionTypeに変換
+func (s *fakeKabusServer) executionTypeFrom(orderType kabuspb.StockOrderType, afterHitOrderType kabuspb.StockAfterHitOrderType) ExecutionType {
+	switch orderType {
+	case kabuspb.StockOrderType_STOCK_ORDER_TYPE_STOP:
+		switch afterHitOrderType {
+		case kabuspb.StockAfterHitOrderType_STOCK_AFTER_HIT_ORDER_TYPE_MO:
+			return ExecutionTypeStopMarket
+		case kabuspb.StockAfterHitOrderType_STOCK_AFTER_HIT_ORDER_TYPE_LO:
+			return ExecutionTypeStopLimit
+		}
+	case kabuspb.StockOrderType_STOCK_ORDER_TYPE_MO:
+		return ExecutionTypeMarket
+	case kabuspb.StockOrderType_STOCK_ORDER_TYPE_MOMC:
+		return ExecutionTypeMarketMorningClose
+	case kabuspb.StockOrderType_STOCK_ORDER_TYPE_MOAC:
+		return ExecutionTypeMarketAfternoonClose
+	case kabuspb.StockOrderType_STOCK_ORDER_TYPE_LO:
+		return ExecutionTypeLimit
+	}
+	return ExecutionTypeUnspecified
+}
+
+// orderTo - 疑似注文板の注文をkabus用に変換
+func (s *fakeKabusServer) orderTo(order SecurityOrder) *kabuspb.Order {
+	state := kabuspb.State_STATE_PROCESSED
+	orderState := kabuspb.OrderState_ORDER_STATE_PROCESSED
+	if order.Status != OrderStatusInOrder {
+		state = kabuspb.State_STATE_DONE
+		orderState = kabuspb.OrderState_ORDER_STATE_DONE
+	}
+
+	// 発火前の逆指値は、kabuステーションと同じく発注待機中で処理済みの詳細がない注文にする
+	detailState := kabuspb.OrderDetailState_ORDER_DETAIL_STATE_PROCESSED
+	if order.Status == OrderStatusInOrder && order.ExecutionType.IsStop() {
+		state = kabuspb.State_STATE_WAIT
+		orderState = kabuspb.OrderState_ORDER_STATE_WAIT
+		detailState = kabuspb.OrderDetailState_ORDER_DETAIL_STATE_WAIT
+	}
+
+	details := []*kabuspb.OrderDetail{{
+		SequenceNumber: 1,
+		Id:             order.Code + "-1",
+		RecordType:     kabuspb.RecordType_RECORD_TYPE_RECEIVE,
+		State:          detailState,
+		TransactTime:   timestamppb.New(order.OrderDateTime),
+		Price:          order.Price,
+		Quantity:       order.OrderQuantity,
+	}}
+	for _, c := range order.Contracts {
+		details = append(details, &kabuspb.OrderDetail{
+			SequenceNumber: int32(len(details) + 1),
+			Id:             order.Code + "-" + c.PositionCode,
+			RecordType:     kabuspb.RecordType_RECORD_TYPE_CONTRACTED,
+			State:          kabuspb.OrderDetailState_ORDER_DETAIL_STATE_PROCESSED,
+			TransactTime:   timestamppb.New(c.ContractDateTime),
+			Price:          c.Price,
+			Quantity:       c.Quantity,
+			ExecutionId:    c.PositionCode,
+			ExecutionDay:   timestamppb.New(c.ContractDateTime),
+		})
+	}
+	if order.Status == OrderStatusCanceled {
+		details = append(details, &kabuspb.OrderDetail{
+			SequenceNumber: int32(len(details) + 1),
+			Id:             order.Code + "-cancel",
+			RecordType:     kabuspb.RecordType_RECORD_TYPE_CANCELED,
+			State:          kabuspb.OrderDetailState_ORDER_DETAIL_STATE_PROCESSED,
+			TransactTime:   timestamppb.New(order.CancelDateTime),
+		})
+	}
+
+	return &kabuspb.Order{
+		Id:                 order.Code,
+		State:              state,
+		OrderState:         orderState,
+		ReceiveTime:        timestamppb.New(order.OrderDateTime),
+		SymbolCode:         order.SymbolCode,
+		Exchange:           kabuspb.OrderExchange(s.kabusAPI.exchangeTo(order.Exchange)),
+		Price:              order.Price,
+		OrderQuantity:      order.OrderQuantity,
+		CumulativeQuantity: order.ContractQuantity,
+		Side:               s.kabusAPI.sideTo(order.Side),
+		TradeType:          s.kabusAPI.tradeTypeTo(order.TradeType),
+		AccountType:        s.kabusAPI.accountTypeTo(order.AccountType),
+		ExpireDay:          timestamppb.New(order.ExpireDay),
+		MarginTradeType:    s.kabusAPI.marginTradeTypeTo(order.MarginTradeType),
+		Details:            details,
+	}
+}
+
+// sendOrder - 注文を疑似注文板に入れる
+func (s *fakeKabusServer) sendOrder(order *Order) (*kabuspb.OrderResponse, error) {
+	symbol, err := s.symbol(order.SymbolCode, order.Exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	s.match(now)
+	var price float64
+	if fourPrice := s.fourPrice(symbol, now); fourPrice != nil {
+		price = fourPrice.Close
+	}
+	if order.ExecutionType == ExecutionTypeMarket && price <= 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "no current price: %s", order.SymbolCode)
+	}
+
+	res, err := s.book.Send(order, now, price, price)
+	if err != nil {
+		return nil, err
+	}
+	return &kabuspb.OrderResponse{ResultCode: 0, OrderId: res.OrderCode}, nil
+}
+
+// SendStockOrder - 現物注文
+func (s *fakeKabusServer) SendStockOrder(_ context.Context, req *kabuspb.SendStockOrderRequest) (*kabuspb.OrderResponse, error) {
+	return s.sendOrder(&Order{
+		SymbolCode:    req.SymbolCode,
+		Exchange:      s.kabusAPI.exchangeFrom(kabuspb.Exchange(req.Exchange)),
+		Product:       ProductStock,
+		TradeType:     s.kabusAPI.tradeTypeFrom(kabuspb.Product_PRODUCT_STOCK, req.Side, kabuspb.TradeType_TRADE_TYPE_UNSPECIFIED),
+		Side:          s.kabusAPI.sideFrom(req.Side),
+		ExecutionType: s.executionTypeFrom(req.OrderType, req.StopOrder.GetAfterHitOrderType()),
+		Price:         req.Price + req.StopOrder.GetAfterHitPrice(),
+		TriggerPrice:  req.StopOrder.GetTriggerPrice(),
+		OrderQuantity: req.Quantity,
+		AccountType:   s.kabusAPI.accountTypeFrom(req.AccountType),
+	})
+}
+
+// SendMarginOrder - 信用注文
+func (s *fakeKabusServer) SendMarginOrder(_ context.Context, req *kabuspb.SendMarginOrderRequest) (*kabuspb.OrderResponse, error) {
+	return s.sendOrder(&Order{
+		SymbolCode:      req.SymbolCode,
+		Exchange:        s.kabusAPI.exchangeFrom(kabuspb.Exchange(req.Exchange)),
+		Product:         ProductMargin,
+		MarginTradeType: s.kabusAPI.marginTradeTypeFrom(req.MarginTradeType),
+		TradeType:       s.kabusAPI.tradeTypeFrom(kabuspb.Product_PRODUCT_MARGIN, req.Side, req.TradeType),
+		Side:            s.kabusAPI.sideFrom(req.Side),
+		ExecutionType:   s.executionTypeFrom(req.OrderType, req.StopOrder.GetAfterHitOrderType()),
+		Price:           req.Price + req.StopOrder.GetAfterHitPrice(),
+		TriggerPrice:    req.StopOrder.GetTriggerPrice(),
+		OrderQuantity:   req.Quantity,
+		AccountType:     s.kabusAPI.accountTypeFrom(req.AccountType),
+		HoldPositions:   s.closePositionsFrom(req.ClosePositions),
+	})
+}
+
+// closePositionsFrom - 返済するポジションの指定を拘束ポジションに変換
+func (s *fakeKabusServer) closePositionsFrom(closePositions []*kabuspb.ClosePosition) []HoldPosition {
+	if closePositions == nil {
+		return nil
+	}
+
+	res := make([]HoldPosition, len(closePositions))
+	for i, cp := range closePositions {
+		res[i] = HoldPosition{PositionCode: cp.ExecutionId, HoldQuantity: cp.Quantity}
+	}
+	return res
+}
+
+// CancelOrder - 注文の取消
+func (s *fakeKabusServer) CancelOrder(_ context.Context, req *kabuspb.CancelOrderRequest) (*kabuspb.OrderResponse, error) {
+	now := s.clock.Now()
+	s.match(now)
+	res, err := s.book.Cancel(req.OrderId, now)
+	if err != nil {
+		return nil, err
+	}
+	return &kabuspb.OrderResponse{ResultCode: 0, OrderId: res.OrderCode}, nil
+}
+
+// GetBoard - 板情報の取得
+func (s *fakeKabusServer) GetBoard(_ context.Context, req *kabuspb.GetBoardRequest) (*kabuspb.Board, error) {
+	symbol, err := s.symbol(req.SymbolCode, s.kabusAPI.exchangeFrom(req.Exchange))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	s.match(now)
+	board := &kabuspb.Board{SymbolCode: symbol.SymbolCode, Exchange: req.Exchange}
+	if fourPrice := s.fourPrice(symbol, now); fourPrice != nil {
+		board.CurrentPrice = fourPrice.Close
+		board.CurrentPriceTime = timestamppb.New(fourPrice.DateTime)
+		board.OpeningPrice = fourPrice.Open
+		board.HighPrice = fourPrice.High
+		board.LowPrice = fourPrice.Low
+		board.BidPrice = fourPrice.Close
+		board.AskPrice = fourPrice.Close
+	}
+	return board, nil
+}
+
+// GetSymbol - 銘柄情報の取得
+func (s *fakeKabusServer) GetSymbol(_ context.Context, req *kabuspb.GetSymbolRequest) (*kabuspb.Symbol, error) {
+	symbol, err := s.symbol(req.SymbolCode, s.kabusAPI.exchangeFrom(req.Exchange))
+	if err != nil {
+		return nil, err
+	}
+
+	return &kabuspb.Symbol{
+		Code:            symbol.SymbolCode,
+		Exchange:        req.Exchange,
+		TradingUnit:     symbol.TradingUnit,
+		PriceRangeGroup: s.tickGroupTo(symbol.TickGroup),
+	}, nil
+}
+
+// GetOrders - 注文一覧の取得
+func (s *fakeKabusServer) GetOrders(_ context.Context, req *kabuspb.GetOrdersRequest) (*kabuspb.Orders, error) {
+	s.match(s.clock.Now())
+
+	var updateDateTime time.Time
+	if req.UpdateTime != nil {
+		updateDateTime = req.UpdateTime.AsTime().In(time.Local)
+	}
+
+	orders := make([]*kabuspb.Order, 0)
+	for _, o := range s.book.Orders(s.kabusAPI.productFrom(req.Product), req.SymbolCode, updateDateTime) {
+		orders = append(orders, s.orderTo(o))
+	}
+	return &kabuspb.Orders{Orders: orders}, nil
+}
+
+// GetPositions - ポジション一覧の取得
+func (s *fakeKabusServer) GetPositions(_ context.Context, req *kabuspb.GetPositionsRequest) (*kabuspb.Positions, error) {
+	s.match(s.clock.Now())
+
+	positions := make([]*kabuspb.Position, 0)
+	for _, p := range s.book.Positions(s.kabusAPI.productFrom(req.Product), req.SymbolCode) {
+		positions = append(positions, &kabuspb.Position{
+			ExecutionId:     p.Code,
+			SymbolCode:      p.SymbolCode,
+			Exchange:        s.kabusAPI.exchangeTo(p.Exchange),
+			ExecutionDay:    timestamppb.New(p.ContractDateTime),
+			Price:           p.Price,
+			LeavesQuantity:  p.OwnedQuantity,
+			HoldQuantity:    p.HoldQuantity,
+			Side:            s.kabusAPI.sideTo(p.Side),
+			MarginTradeType: s.kabusAPI.marginTradeTypeTo(p.MarginTradeType),
+		})
+	}
+	return &kabuspb.Positions{Positions: positions}, nil
+}
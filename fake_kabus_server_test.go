@@ -0,0 +1,223 @@
+package gridon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"gitlab.com/tsuchinaga/kabus-grpc-server/kabuspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func Test_NewFakeKabusServer(t *testing.T) {
+	t.Parallel()
+	symbols := []FakeKabusSymbol{{SymbolCode: "1475", Exchange: ExchangeToushou, TradingUnit: 1, TickGroup: TickGroupOther}}
+	want1 := newFakeKabusServer(symbols, newClock())
+	got1 := NewFakeKabusServer(symbols)
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_fakeKabusServer_fourPrice(t *testing.T) {
+	t.Parallel()
+	symbol := FakeKabusSymbol{
+		SymbolCode: "1475",
+		Exchange:   ExchangeToushou,
+		Prices: []BacktestPrice{
+			{DateTime: time.Date(2022, 1, 24, 14, 59, 0, 0, time.Local), Price: 2010},
+			{DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Price: 2000},
+			{DateTime: time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local), Price: 2005},
+			{DateTime: time.Date(2022, 1, 25, 9, 2, 0, 0, time.Local), Price: 1995},
+			{DateTime: time.Date(2022, 1, 25, 9, 3, 0, 0, time.Local), Price: 2001},
+		},
+	}
+
+	tests := []struct {
+		name string
+		arg  time.Time
+		want *FourPrice
+	}{
+		{name: "当日の価格がまだなければnil", arg: time.Date(2022, 1, 25, 8, 59, 0, 0, time.Local), want: nil},
+		{name: "指定した日時までの当日の価格で四本値を作る",
+			arg:  time.Date(2022, 1, 25, 9, 2, 30, 0, time.Local),
+			want: &FourPrice{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 25, 9, 2, 0, 0, time.Local), Open: 2000, High: 2005, Low: 1995, Close: 1995}},
+		{name: "当日の全ての価格で四本値を作る",
+			arg:  time.Date(2022, 1, 25, 15, 0, 0, 0, time.Local),
+			want: &FourPrice{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 25, 9, 3, 0, 0, time.Local), Open: 2000, High: 2005, Low: 1995, Close: 2001}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := newFakeKabusServer([]FakeKabusSymbol{symbol}, &testClock{})
+			got := server.fourPrice(symbol, test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_fakeKabusServer_kabusAPI(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{Now1: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)}
+	server := newFakeKabusServer([]FakeKabusSymbol{{
+		SymbolCode:  "1475",
+		Exchange:    ExchangeToushou,
+		TradingUnit: 1,
+		TickGroup:   TickGroupOther,
+		Prices: []BacktestPrice{
+			{DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Price: 2000},
+			{DateTime: time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local), Price: 1998},
+		},
+	}}, clock)
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	kabuspb.RegisterKabusServiceServer(grpcServer, server)
+	go func() { _ = grpcServer.Serve(listener) }()
+	defer grpcServer.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("%s error\ngot: %+v\n", t.Name(), err)
+	}
+	defer conn.Close()
+	api := newKabusAPI(kabuspb.NewKabusServiceClient(conn), newRateLimiter(), RetryConfig{})
+	strategy := &Strategy{SymbolCode: "1475", Account: Account{Password: "Password1234", AccountType: AccountTypeSpecific}}
+
+	// 銘柄情報
+	wantSymbol := &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, TickGroup: TickGroupOther,
+		CurrentPrice: 2000, CurrentPriceDateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), BidPrice: 2000, AskPrice: 2000, OpeningPrice: 2000}
+	gotSymbol, err := api.GetSymbol("1475", ExchangeToushou)
+	if !reflect.DeepEqual(wantSymbol, gotSymbol) || err != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), wantSymbol, gotSymbol, err)
+	}
+
+	// 存在しない銘柄
+	if _, err := api.GetSymbol("0000", ExchangeToushou); err == nil {
+		t.Errorf("%s error\nwant: error\ngot: %+v\n", t.Name(), err)
+	}
+
+	// 信用の指値注文は価格が指値に達するまで注文中
+	gotResult, err := api.SendOrder(strategy, &Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay,
+		TradeType: TradeTypeEntry, Side: SideBuy, ExecutionType: ExecutionTypeLimit, Price: 1999, OrderQuantity: 1, AccountType: AccountTypeSpecific})
+	wantResult := OrderResult{Result: true, OrderCode: "fake-order-000001"}
+	if !reflect.DeepEqual(wantResult, gotResult) || err != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), wantResult, gotResult, err)
+	}
+
+	wantOrders := []SecurityOrder{{
+		Code:            "fake-order-000001",
+		Status:          OrderStatusInOrder,
+		SymbolCode:      "1475",
+		Exchange:        ExchangeToushou,
+		Product:         ProductMargin,
+		MarginTradeType: MarginTradeTypeDay,
+		TradeType:       TradeTypeEntry,
+		Side:            SideBuy,
+		ExecutionType:   ExecutionTypeLimit,
+		Price:           1999,
+		OrderQuantity:   1,
+		AccountType:     AccountTypeSpecific,
+		ExpireDay:       time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local),
+		OrderDateTime:   time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local),
+		Contracts:       []Contract{},
+	}}
+	gotOrders, err := api.GetOrders(ProductMargin, "1475", time.Time{})
+	if !reflect.DeepEqual(wantOrders, gotOrders) || err != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), wantOrders, gotOrders, err)
+	}
+
+	// 価格が指値を下回ったら指値で約定する
+	clock.Now1 = time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local)
+	wantOrders[0].Status = OrderStatusDone
+	wantOrders[0].ContractQuantity = 1
+	wantOrders[0].ContractDateTime = time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local)
+	wantOrders[0].Contracts = []Contract{{OrderCode: "fake-order-000001", PositionCode: "fake-contract-000001", Price: 1999, Quantity: 1, ContractDateTime: time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local)}}
+	gotOrders, err = api.GetOrders(ProductMargin, "1475", time.Time{})
+	if !reflect.DeepEqual(wantOrders, gotOrders) || err != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), wantOrders, gotOrders, err)
+	}
+
+	// 約定済みの注文は取り消せず、kabuステーションと同じエラーが取り消せない注文のエラーとして返される
+	_, err = api.CancelOrder("Password1234", "fake-order-000001")
+	var kabusErr *KabusError
+	if !errors.As(err, &kabusErr) || kabusErr.Code != 43 || !errors.Is(err, ErrNotCancelable) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), 43, err)
+	}
+
+	// 現物の成行注文は現在値で約定し、現物の注文一覧で取得できる
+	gotResult, err = api.SendOrder(strategy, &Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductStock,
+		Side: SideBuy, ExecutionType: ExecutionTypeMarket, OrderQuantity: 2, AccountType: AccountTypeSpecific})
+	wantResult = OrderResult{Result: true, OrderCode: "fake-order-000002"}
+	if !reflect.DeepEqual(wantResult, gotResult) || err != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), wantResult, gotResult, err)
+	}
+	gotOrders, err = api.GetOrders(ProductStock, "1475", time.Time{})
+	if len(gotOrders) != 1 || gotOrders[0].Status != OrderStatusDone || gotOrders[0].TradeType != TradeTypeEntry ||
+		!reflect.DeepEqual([]Contract{{OrderCode: "fake-order-000002", PositionCode: "fake-contract-000002", Price: 1998, Quantity: 2, ContractDateTime: time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local)}}, gotOrders[0].Contracts) ||
+		err != nil {
+		t.Errorf("%s error\ngot: %+v, %+v\n", t.Name(), gotOrders, err)
+	}
+
+	// 四本値
+	wantFourPrice := &FourPrice{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local), Open: 2000, High: 2000, Low: 1998, Close: 1998}
+	gotFourPrice, err := api.GetFourPrice("1475", ExchangeToushou)
+	if !reflect.DeepEqual(wantFourPrice, gotFourPrice) || err != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), wantFourPrice, gotFourPrice, err)
+	}
+
+	// 信用の建玉は約定した新規注文から組み立てられ、返済の注文中は拘束数量として返される
+	wantPositions := []SecurityPosition{{Code: "fake-contract-000001", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay,
+		Side: SideBuy, Price: 1999, OwnedQuantity: 1, ContractDateTime: time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local)}}
+	gotPositions, err := api.GetPositions(ProductMargin, "1475")
+	if !reflect.DeepEqual(wantPositions, gotPositions) || err != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), wantPositions, gotPositions, err)
+	}
+	_, _ = api.SendOrder(strategy, &Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay,
+		TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeLimit, Price: 2010, OrderQuantity: 1, AccountType: AccountTypeSpecific,
+		HoldPositions: []HoldPosition{{PositionCode: "fake-contract-000001", HoldQuantity: 1}}})
+	wantPositions[0].HoldQuantity = 1
+	gotPositions, err = api.GetPositions(ProductMargin, "1475")
+	if !reflect.DeepEqual(wantPositions, gotPositions) || err != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), wantPositions, gotPositions, err)
+	}
+
+	// 返済が約定したら建玉はなくなり、現物の保有だけが残る
+	_, _ = api.CancelOrder("Password1234", "fake-order-000003")
+	_, _ = api.SendOrder(strategy, &Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay,
+		TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeMarket, OrderQuantity: 1, AccountType: AccountTypeSpecific,
+		HoldPositions: []HoldPosition{{PositionCode: "fake-contract-000001", HoldQuantity: 1}}})
+	gotPositions, err = api.GetPositions(ProductMargin, "1475")
+	if !reflect.DeepEqual([]SecurityPosition{}, gotPositions) || err != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), []SecurityPosition{}, gotPositions, err)
+	}
+	wantPositions = []SecurityPosition{{Code: "fake-contract-000002", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductStock,
+		Side: SideBuy, Price: 1998, OwnedQuantity: 2, ContractDateTime: time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local)}}
+	gotPositions, err = api.GetPositions(ProductStock, "1475")
+	if !reflect.DeepEqual(wantPositions, gotPositions) || err != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), wantPositions, gotPositions, err)
+	}
+	// 発火前の逆指値は、注文一覧で逆指値の注文中として取得できる
+	gotResult, err = api.SendOrder(strategy, &Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay,
+		TradeType: TradeTypeEntry, Side: SideSell, ExecutionType: ExecutionTypeStopMarket, TriggerPrice: 1900, OrderQuantity: 1, AccountType: AccountTypeSpecific})
+	if err != nil {
+		t.Errorf("%s error\ngot: %+v, %+v\n", t.Name(), gotResult, err)
+	}
+	gotOrders, err = api.GetOrders(ProductMargin, "1475", time.Time{})
+	if err != nil || len(gotOrders) == 0 ||
+		gotOrders[len(gotOrders)-1].Code != gotResult.OrderCode ||
+		gotOrders[len(gotOrders)-1].Status != OrderStatusInOrder ||
+		gotOrders[len(gotOrders)-1].ExecutionType != ExecutionTypeStopMarket {
+		t.Errorf("%s error\ngot: %+v, %+v\n", t.Name(), gotOrders, err)
+	}
+}
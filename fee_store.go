@@ -0,0 +1,70 @@
+package gridon
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	feeStoreSingleton    IFeeStore
+	feeStoreSingletonMtx sync.Mutex
+)
+
+// getFeeStore - 費用ストアの取得
+func getFeeStore(db IDB) IFeeStore {
+	feeStoreSingletonMtx.Lock()
+	defer feeStoreSingletonMtx.Unlock()
+
+	if feeStoreSingleton == nil {
+		feeStoreSingleton = &feeStore{
+			db: db,
+		}
+	}
+
+	return feeStoreSingleton
+}
+
+// IFeeStore - 費用ストアのインターフェース
+type IFeeStore interface {
+	Save(fee *Fee) error
+	GetByStrategyCode(strategyCode string, from time.Time, to time.Time) ([]*Fee, error)
+}
+
+// feeStore - 費用ストア
+// 取引ストアと同じく、メモリには持たずに都度DBから読み込む
+type feeStore struct {
+	db  IDB
+	mtx sync.Mutex
+}
+
+// Save - 費用の保存
+// 段階制の手数料で直後の約定の計算に使うため、同期的にDBに保存する
+func (s *feeStore) Save(fee *Fee) error {
+	if fee == nil {
+		return ErrNilArgument
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.db.SaveFee(fee)
+}
+
+// GetByStrategyCode - 戦略を指定して、計上日時がfrom以降to未満の費用を取り出す
+func (s *feeStore) GetByStrategyCode(strategyCode string, from time.Time, to time.Time) ([]*Fee, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	fees, err := s.db.GetFees(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Fee, 0)
+	for _, f := range fees {
+		if f.StrategyCode == strategyCode {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
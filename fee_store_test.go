@@ -0,0 +1,110 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testFeeStore struct {
+	IFeeStore
+	Save1                    error
+	SaveHistory              []interface{}
+	GetByStrategyCode1       []*Fee
+	GetByStrategyCode2       error
+	GetByStrategyCodeHistory []interface{}
+}
+
+func (t *testFeeStore) Save(fee *Fee) error {
+	t.SaveHistory = append(t.SaveHistory, fee)
+	return t.Save1
+}
+func (t *testFeeStore) GetByStrategyCode(strategyCode string, from time.Time, to time.Time) ([]*Fee, error) {
+	t.GetByStrategyCodeHistory = append(t.GetByStrategyCodeHistory, strategyCode, from, to)
+	return t.GetByStrategyCode1, t.GetByStrategyCode2
+}
+
+func Test_getFeeStore(t *testing.T) {
+	t.Parallel()
+
+	db := &testDB{}
+	want1 := &feeStore{db: db}
+	got1 := getFeeStore(db)
+
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_feeStore_Save(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name               string
+		db                 *testDB
+		arg                *Fee
+		want               error
+		wantSaveFeeHistory []interface{}
+	}{
+		{name: "nilならエラー", db: &testDB{}, arg: nil, want: ErrNilArgument, wantSaveFeeHistory: nil},
+		{name: "DBの保存に失敗したらエラー",
+			db:                 &testDB{SaveFee1: ErrUnknown},
+			arg:                &Fee{Code: "fee-code-001"},
+			want:               ErrUnknown,
+			wantSaveFeeHistory: []interface{}{&Fee{Code: "fee-code-001"}}},
+		{name: "DBに保存できたらnil",
+			db:                 &testDB{},
+			arg:                &Fee{Code: "fee-code-001"},
+			want:               nil,
+			wantSaveFeeHistory: []interface{}{&Fee{Code: "fee-code-001"}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &feeStore{db: test.db}
+			got := store.Save(test.arg)
+			if !errors.Is(got, test.want) || !reflect.DeepEqual(test.wantSaveFeeHistory, test.db.SaveFeeHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want, test.wantSaveFeeHistory, got, test.db.SaveFeeHistory)
+			}
+		})
+	}
+}
+
+func Test_feeStore_GetByStrategyCode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		db    *testDB
+		arg1  string
+		want1 []*Fee
+		want2 error
+	}{
+		{name: "DBがエラーを返したらエラー", db: &testDB{GetFees2: ErrUnknown}, arg1: "strategy-code-001", want1: nil, want2: ErrUnknown},
+		{name: "指定した戦略の費用だけを返す",
+			db: &testDB{GetFees1: []*Fee{
+				{Code: "fee-code-001", StrategyCode: "strategy-code-001"},
+				{Code: "fee-code-002", StrategyCode: "strategy-code-002"},
+				{Code: "fee-code-003", StrategyCode: "strategy-code-001"},
+			}},
+			arg1: "strategy-code-001",
+			want1: []*Fee{
+				{Code: "fee-code-001", StrategyCode: "strategy-code-001"},
+				{Code: "fee-code-003", StrategyCode: "strategy-code-001"},
+			},
+			want2: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &feeStore{db: test.db}
+			got1, got2 := store.GetByStrategyCode(test.arg1, time.Time{}, time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local))
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
@@ -2,11 +2,15 @@ module gitlab.com/tsuchinaga/gridon
 
 go 1.17
 
-require gitlab.com/tsuchinaga/kabus-grpc-server v0.0.3
+require (
+	github.com/genjidb/genji v0.14.0
+	gitlab.com/tsuchinaga/kabus-grpc-server v0.0.3
+	google.golang.org/grpc v1.40.0
+	google.golang.org/protobuf v1.27.1
+)
 
 require (
 	github.com/buger/jsonparser v1.1.1 // indirect
-	github.com/genjidb/genji v0.14.0 // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/google/btree v1.0.1 // indirect
 	github.com/vmihailenco/msgpack/v5 v5.3.5 // indirect
@@ -16,6 +20,4 @@ require (
 	golang.org/x/sys v0.0.0-20211107104306-e0b2ad06fe42 // indirect
 	golang.org/x/text v0.3.5 // indirect
 	google.golang.org/genproto v0.0.0-20210903162649-d08c68adba83 // indirect
-	google.golang.org/grpc v1.40.0 // indirect
-	google.golang.org/protobuf v1.27.1 // indirect
 )
@@ -1,30 +1,54 @@
 package gridon
 
+import (
+	"math"
+	"time"
+)
+
 // newGridService - 新しいグリッドサービスの取得
-func newGridService(clock IClock, tick ITick, kabusAPI IKabusAPI, orderService IOrderService, strategyStore IStrategyStore, fourPriceStore IFourPriceStore) IGridService {
+func newGridService(clock IClock, tick ITick, kabusAPI IKabusAPI, orderService IOrderService, strategyStore IStrategyStore, fourPriceStore IFourPriceStore, positionStore IPositionStore, priceBandService IPriceBandService, trendFilterService ITrendFilterService) IGridService {
 	return &gridService{
-		clock:          clock,
-		tick:           tick,
-		kabusAPI:       kabusAPI,
-		orderService:   orderService,
-		strategyStore:  strategyStore,
-		fourPriceStore: fourPriceStore,
+		clock:              clock,
+		tick:               tick,
+		kabusAPI:           kabusAPI,
+		orderService:       orderService,
+		strategyStore:      strategyStore,
+		fourPriceStore:     fourPriceStore,
+		positionStore:      positionStore,
+		priceBandService:   priceBandService,
+		trendFilterService: trendFilterService,
 	}
 }
 
 // IGridService - グリッドサービスのインターフェース
 type IGridService interface {
+	IsRunnable(strategy *Strategy) bool
 	Leveling(strategy *Strategy) error
 }
 
 // gridService - グリッドサービス
 type gridService struct {
-	clock          IClock
-	tick           ITick
-	kabusAPI       IKabusAPI
-	orderService   IOrderService
-	strategyStore  IStrategyStore
-	fourPriceStore IFourPriceStore
+	clock              IClock
+	tick               ITick
+	kabusAPI           IKabusAPI
+	orderService       IOrderService
+	strategyStore      IStrategyStore
+	fourPriceStore     IFourPriceStore
+	positionStore      IPositionStore
+	priceBandService   IPriceBandService
+	trendFilterService ITrendFilterService
+}
+
+// IsRunnable - グリッドの整地を実行するタイミングか
+// 戦略とグリッド戦略が実行可能で、取引時間であれば整地で注文を出しにいく
+func (s *gridService) IsRunnable(strategy *Strategy) bool {
+	if strategy == nil || !strategy.IsRunnable() {
+		return false
+	}
+
+	// グリッド戦略が無効か、取引時間でないなら整地しない
+	now := s.clock.Now()
+	return strategy.GridStrategy.IsRunnable(now) && s.clock.IsTradingTime(now)
 }
 
 // Leveling - グリッドの整地
@@ -32,22 +56,12 @@ func (s *gridService) Leveling(strategy *Strategy) error {
 	if strategy == nil {
 		return ErrNilArgument
 	}
-	if !strategy.IsRunnable() {
+	if !s.IsRunnable(strategy) {
 		return nil
 	}
 
 	now := s.clock.Now()
 
-	// グリッド戦略が無効なら抜ける
-	if !strategy.GridStrategy.IsRunnable(now) {
-		return nil
-	}
-
-	// 取引時間でないなら抜ける
-	if !s.clock.IsTradingTime(now) {
-		return nil
-	}
-
 	// 注文中の注文から各グリッドに乗っている数量を取得
 	orders, err := s.orderService.GetActiveOrdersByStrategyCode(strategy.Code)
 	if err != nil {
@@ -61,20 +75,72 @@ func (s *gridService) Leveling(strategy *Strategy) error {
 		return err
 	}
 
+	// 基準価格が価格帯の外にあるかを判定する
+	// 価格帯が無効なら判定はされず、前回の判定結果が残っていれば消される
+	band, err := s.priceBandService.Check(strategy, basePrice)
+	if err != nil {
+		return err
+	}
+
+	// 基準価格が価格帯の外にあって、全エグジットする対応なら、注文を全て取り消してポジションを全てエグジットし、グリッドは置かない
+	// 取消したポジションの解放は約定確認を待つため、解放されたポジションは次の周回以降でエグジットする
+	if band.OutOfBand && strategy.GridStrategy.PriceBand.Policy == PriceBandPolicyFlatten {
+		for _, o := range orders {
+			if o.IsPending() || (o.ExecutionType != ExecutionTypeLimit && !o.ExecutionType.IsStop()) {
+				continue
+			}
+			if err := s.orderService.Cancel(strategy, o.Code); err != nil {
+				return err
+			}
+		}
+		return s.orderService.ForceExitAll(strategy)
+	}
+
+	// エントリーと逆方向の強いトレンドが出ているかを判定する
+	// トレンドフィルタが無効なら判定はされず、前回の判定結果が残っていれば消される
+	trend, err := s.trendFilterService.Check(strategy, basePrice)
+	if err != nil {
+		return err
+	}
+
+	// 基準価格が価格帯の外にあってエントリー注文だけを取り消す対応か、トレンドフィルタが効いていてエントリーを止める対応なら、
+	// エントリー注文を取り消してエントリー側のグリッドは置かない
+	stopEntry := (band.OutOfBand && strategy.GridStrategy.PriceBand.Policy == PriceBandPolicyCancelEntries) ||
+		(trend.Active && strategy.GridStrategy.TrendFilter.IsStopEntry())
+
+	// 値幅制限の外に注文を出しても証券会社で弾かれるので、値幅制限の範囲を出しておく
+	limit := s.priceLimit(strategy, now)
+
 	// グリッド幅の計算
 	width, _ := s.width(strategy) // 直前にstrategyのnilチェックをしているので、ここではエラーを無視できる
 	if width <= 0 {
 		return ErrZeroGridWidth
 	}
 
+	// 上下それぞれのグリッド幅、本数、数量を決める
+	upper, lower, err := s.sideGrids(strategy, width)
+	if err != nil {
+		return err
+	}
+
+	// トレンドフィルタが効いていてエントリーを減らす対応なら、エントリー側の数量を減らす
+	if trend.Active && !strategy.GridStrategy.TrendFilter.IsStopEntry() {
+		switch strategy.EntrySide {
+		case SideBuy:
+			lower.Quantity = strategy.GridStrategy.TrendFilter.entryQuantity(lower.Quantity, strategy.TradingUnit)
+		case SideSell:
+			upper.Quantity = strategy.GridStrategy.TrendFilter.entryQuantity(upper.Quantity, strategy.TradingUnit)
+		}
+	}
+
 	// 乗せるべきgridのリストを作っておく
+	uppers := s.gridPrices(strategy, basePrice, upper.Width, upper.NumberOfGrids, 1)
+	lowers := s.gridPrices(strategy, basePrice, lower.Width, lower.NumberOfGrids, -1)
 	grids := []float64{basePrice} // 基準価格も有効なグリッドなので追加しておく
-	for i := 1; i <= strategy.GridStrategy.NumberOfGrids; i++ {
-		grids = append(grids, s.tick.TickAddedPrice(strategy.TickGroup, basePrice, i*width))
-		grids = append(grids, s.tick.TickAddedPrice(strategy.TickGroup, basePrice, -1*i*width))
-	}
+	grids = append(grids, uppers...)
+	grids = append(grids, lowers...)
 
-	// 基準価格から最大グリッド数より外にある注文を特定して取り消す
+	// 基準価格から最大グリッド数より外にある注文と、価格帯や値幅制限の外にある注文と、エントリーを止めている間のエントリー注文を特定して取り消す
 	gridQuantities := make(map[float64]float64)
 	for _, o := range orders {
 		// 指値注文以外はスキップ
@@ -90,55 +156,233 @@ func (s *gridService) Leveling(strategy *Strategy) error {
 			}
 		}
 
-		if contain {
+		if contain && band.In(o.Price) && limit.In(o.Price) && !(stopEntry && o.TradeType == TradeTypeEntry) {
 			gridQuantities[o.Price] += o.OrderQuantity - o.ContractQuantity
-		} else {
+		} else if !o.IsPending() { // 送信中の注文は証券会社の注文コードがわかるまで取り消せない
 			if err := s.orderService.Cancel(strategy, o.Code); err != nil {
 				return err
 			}
 		}
 	}
 
+	// 保護用の逆指値の発火価格が変わっていたら取り消す
+	// 取消したポジションの解放は約定確認を待つため、取消した周回では新しい逆指値を置かない
+	outermost := basePrice
+	if strategy.EntrySide == SideBuy && len(lowers) > 0 {
+		outermost = lowers[len(lowers)-1]
+	} else if strategy.EntrySide == SideSell && len(uppers) > 0 {
+		outermost = uppers[len(uppers)-1]
+	}
+	stopTriggerPrice, stopPrice := s.protectiveStopPrices(strategy, outermost, limit)
+	hasStop, err := s.cancelProtectiveStops(strategy, orders, stopTriggerPrice, stopPrice)
+	if err != nil {
+		return err
+	}
+
 	// グリッドの中心から外に注文を確認していく
-	for i := 1; i <= strategy.GridStrategy.NumberOfGrids; i++ {
+	// 価格帯や値幅制限の外にあるグリッドと、エントリーを止めている間のエントリー側のグリッドには注文を置かない
+	for i := 0; i < len(uppers) || i < len(lowers); i++ {
 		// upper
-		{
-			upper := s.tick.TickAddedPrice(strategy.TickGroup, basePrice, i*width)
-			quantity := strategy.GridStrategy.Quantity - gridQuantities[upper]
+		if i < len(uppers) && band.In(uppers[i]) && limit.In(uppers[i]) && !(stopEntry && strategy.EntrySide == SideSell) {
+			ordered := gridQuantities[uppers[i]]
 			// 部分約定対策として、基準価格の隣の場合に限り基準価格に乗っている数量を減算する
-			if i == 1 {
-				quantity -= gridQuantities[basePrice]
+			if i == 0 {
+				ordered += gridQuantities[basePrice]
+			}
+
+			quantity, err := s.levelQuantity(strategy, uppers[i], i, upper.Quantity, ordered, strategy.EntrySide == SideSell)
+			if err != nil {
+				return err
 			}
 
 			// 注文数量があれば注文送信
 			if quantity > 0 {
-				if err := s.sendGridOrder(strategy, upper, basePrice, quantity); err != nil {
+				if err := s.sendGridOrder(strategy, uppers[i], basePrice, quantity); err != nil {
 					return err
 				}
 			}
 		}
 
 		// lower
-		{
-			lower := s.tick.TickAddedPrice(strategy.TickGroup, basePrice, -1*i*width)
-			quantity := strategy.GridStrategy.Quantity - gridQuantities[lower]
+		if i < len(lowers) && band.In(lowers[i]) && limit.In(lowers[i]) && !(stopEntry && strategy.EntrySide == SideBuy) {
+			ordered := gridQuantities[lowers[i]]
 			// 部分約定対策として、基準価格の隣の場合に限り基準価格に乗っている数量を減算する
-			if i == 1 {
-				quantity -= gridQuantities[basePrice]
+			if i == 0 {
+				ordered += gridQuantities[basePrice]
+			}
+
+			quantity, err := s.levelQuantity(strategy, lowers[i], i, lower.Quantity, ordered, strategy.EntrySide == SideBuy)
+			if err != nil {
+				return err
 			}
 
 			// 注文数量があれば注文送信
 			if quantity > 0 {
-				if err := s.sendGridOrder(strategy, lower, basePrice, quantity); err != nil {
+				if err := s.sendGridOrder(strategy, lowers[i], basePrice, quantity); err != nil {
 					return err
 				}
 			}
 		}
 	}
 
+	// グリッドの注文に拘束されなかったポジションに保護用の逆指値を置く
+	if strategy.ProtectiveStopStrategy.IsRunnable() && !hasStop {
+		if err := s.orderService.ExitStop(strategy.Code, strategy.ProtectiveStopStrategy.ExecutionType, stopTriggerPrice, stopPrice); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// sideGrids - 上下それぞれのグリッドの設定に、動的なグリッド幅と保有数量を反映したもの
+// 保有数量に応じてグリッドを減らす設定が有効なら、保有数量が多いほどエントリー側のグリッドを減らす
+func (s *gridService) sideGrids(strategy *Strategy, width int) (GridSideStrategy, GridSideStrategy, error) {
+	upper := strategy.GridStrategy.upperGrid()
+	lower := strategy.GridStrategy.lowerGrid()
+	upper.Width = strategy.GridStrategy.sideWidth(width, upper.Width)
+	lower.Width = strategy.GridStrategy.sideWidth(width, lower.Width)
+	if (upper.NumberOfGrids > 0 && upper.Width <= 0) || (lower.NumberOfGrids > 0 && lower.Width <= 0) {
+		return upper, lower, ErrZeroGridWidth
+	}
+
+	if strategy.GridStrategy.InventorySkew.Valid {
+		positions, err := s.positionStore.GetActivePositionsByStrategyCode(strategy.Code)
+		if err != nil {
+			return upper, lower, err
+		}
+		var owned float64
+		for _, p := range positions {
+			owned += p.OwnedQuantity
+		}
+
+		switch strategy.EntrySide {
+		case SideBuy:
+			lower.NumberOfGrids = strategy.GridStrategy.InventorySkew.numberOfGrids(lower.NumberOfGrids, owned)
+		case SideSell:
+			upper.NumberOfGrids = strategy.GridStrategy.InventorySkew.numberOfGrids(upper.NumberOfGrids, owned)
+		}
+	}
+	return upper, lower, nil
+}
+
+// levelQuantity - 基準価格の隣を0として、level本目のグリッドに追加で乗せる数量
+// 数量の決め方から乗せるべき数量を出し、すでに乗っている数量を引く
+// 数量の決め方が指定されていれば、エントリー側のグリッドはエントリーに使える現金で買える数量までにする
+func (s *gridService) levelQuantity(strategy *Strategy, price float64, level int, quantity float64, ordered float64, entry bool) (float64, error) {
+	profile := strategy.GridStrategy.QuantityProfile
+	q := profile.quantity(quantity, level, strategy.TradingUnit) - ordered
+	if q <= 0 || !entry || profile.Type == QuantityProfileTypeUnspecified {
+		return q, nil
+	}
+
+	max, err := s.orderService.MaxEntryQuantity(strategy.Code, price)
+	if err != nil {
+		return 0, err
+	}
+	if max < q {
+		q = max
+	}
+	return q, nil
+}
+
+// gridPrices - 基準価格から内側順に並べた片側のグリッドの価格
+// signが正なら基準価格より上、負なら基準価格より下に並べる
+// ティック数の間隔なら基準価格からグリッド幅のティック数ずつ離し、
+// 幾何級数の間隔なら1つ内側のグリッドから一定の割合ずつ離して呼値単位に丸める
+func (s *gridService) gridPrices(strategy *Strategy, basePrice float64, width int, numberOfGrids int, sign int) []float64 {
+	if numberOfGrids < 0 {
+		numberOfGrids = 0
+	}
+	if sign < 0 {
+		sign = -1
+	} else {
+		sign = 1
+	}
+	prices := make([]float64, 0, numberOfGrids)
+
+	if strategy.GridStrategy.Spacing != GridSpacingGeometric {
+		for i := 1; i <= numberOfGrids; i++ {
+			prices = append(prices, s.tick.TickAddedPrice(strategy.TickGroup, basePrice, sign*i*width))
+		}
+		return prices
+	}
+
+	rate := strategy.GridStrategy.geometricRate(width)
+	price, prev := basePrice, basePrice
+	for i := 1; i <= numberOfGrids; i++ {
+		price *= 1 + float64(sign)*rate
+
+		// 丸めた結果が1つ内側のグリッドと重なるなら、1ティック外側に置く
+		p := s.tick.RoundedPrice(strategy.TickGroup, price, RoundingRound)
+		if float64(sign)*(p-prev) <= 0 {
+			p = s.tick.TickAddedPrice(strategy.TickGroup, prev, sign)
+		}
+
+		prices = append(prices, p)
+		prev = p
+	}
+	return prices
+}
+
+// protectiveStopPrices - 保護用の逆指値の発火価格と発火後の指値価格
+// 発火価格はエントリー方向で最も外側のグリッドからさらに指定ティック外側で、指値は発火価格からさらに指定ティック外側
+func (s *gridService) protectiveStopPrices(strategy *Strategy, outermost float64, limit PriceLimit) (float64, float64) {
+	sign := 1
+	if strategy.EntrySide == SideBuy {
+		sign = -1
+	}
+
+	// 値幅制限の外の価格は注文できないので、値幅制限の端に寄せる
+	ps := strategy.ProtectiveStopStrategy
+	triggerPrice := limit.Clamp(s.tick.TickAddedPrice(strategy.TickGroup, outermost, sign*ps.Width))
+	if ps.ExecutionType != ExecutionTypeStopLimit {
+		return triggerPrice, 0
+	}
+	return triggerPrice, limit.Clamp(s.tick.TickAddedPrice(strategy.TickGroup, triggerPrice, sign*ps.LimitWidth))
+}
+
+// priceLimit - 前日の終値を基準値段にした当日の値幅制限
+// 引け後に当日の四本値が保存されていることもあるので、新しい方から2日分を見て当日より前の終値を使う
+// 当日より前の四本値がなければ値幅制限はわからないので、制限なしとして証券会社の判断に任せる
+func (s *gridService) priceLimit(strategy *Strategy, now time.Time) PriceLimit {
+	fps, err := s.fourPriceStore.GetBySymbolCodeAndExchange(strategy.SymbolCode, strategy.Exchange, 2)
+	if err != nil {
+		return PriceLimit{}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, fp := range fps {
+		if fp != nil && fp.DateTime.Before(today) {
+			return dailyPriceLimit(fp.Close)
+		}
+	}
+	return PriceLimit{}
+}
+
+// cancelProtectiveStops - 価格の合わない保護用の逆指値を取り消し、逆指値の注文が残っているかを返す
+// 保護用の逆指値戦略が実行できなければ全て取り消す
+func (s *gridService) cancelProtectiveStops(strategy *Strategy, orders []*Order, triggerPrice float64, price float64) (bool, error) {
+	var hasStop bool
+	for _, o := range orders {
+		if !o.ExecutionType.IsStop() {
+			continue
+		}
+		hasStop = true
+
+		if o.IsPending() { // 送信中の注文は証券会社の注文コードがわかるまで取り消せない
+			continue
+		}
+		if strategy.ProtectiveStopStrategy.IsRunnable() && o.TriggerPrice == triggerPrice && o.Price == price {
+			continue
+		}
+		if err := s.orderService.Cancel(strategy, o.Code); err != nil {
+			return hasStop, err
+		}
+	}
+	return hasStop, nil
+}
+
 // getBasePrice - 戦略から基準価格を取り出す
 // グリッド戦略の実行時刻範囲のうち、現在時刻と同じ範囲内の約定があればその価格を基準価格にし、
 // なければ銘柄情報を取得して現在値を基準価格とする
@@ -152,6 +396,12 @@ func (s *gridService) getBasePrice(strategy *Strategy) (float64, error) {
 	}
 
 	now := s.clock.Now()
+
+	// 最終約定価格以外の取り方なら、指定の間隔ごとに計算しなおす
+	if !strategy.GridStrategy.BasePriceSource.IsLastContract() {
+		return s.calcBasePrice(strategy, now)
+	}
+
 	for _, tr := range strategy.GridStrategy.TimeRanges {
 		if tr.In(now) && tr.In(strategy.BasePriceDateTime) {
 			return strategy.BasePrice, nil
@@ -176,6 +426,81 @@ func (s *gridService) getBasePrice(strategy *Strategy) (float64, error) {
 	return 0, ErrCannotGetBasePrice
 }
 
+// calcBasePrice - 最終約定価格以外の取り方で基準価格を計算し、戦略に保持する
+// 保持している基準価格が同じ戦略動作時刻範囲内で、計算しなおす間隔が過ぎていなければそのまま使う
+func (s *gridService) calcBasePrice(strategy *Strategy, now time.Time) (float64, error) {
+	source := strategy.GridStrategy.BasePriceSource
+	for _, tr := range strategy.GridStrategy.TimeRanges {
+		if tr.In(now) && tr.In(strategy.BasePriceDateTime) && now.Sub(strategy.BasePriceDateTime) < source.Interval {
+			return strategy.BasePrice, nil
+		}
+	}
+
+	price, err := s.sourcePrice(strategy, now)
+	if err != nil {
+		return 0, err
+	}
+
+	// グリッドは基準価格からティック単位で置くので、基準価格も呼値に丸めておく
+	price = s.tick.RoundedPrice(strategy.TickGroup, price, RoundingRound)
+	if err := s.strategyStore.SetBasePrice(strategy.Code, price, now); err != nil {
+		return 0, err
+	}
+	return price, nil
+}
+
+// sourcePrice - 基準価格の取り方に応じた丸める前の価格
+// VWAPと始値は当日の板情報でなければ前日の値なので使わない
+func (s *gridService) sourcePrice(strategy *Strategy, now time.Time) (float64, error) {
+	source := strategy.GridStrategy.BasePriceSource
+	if source.Type == BasePriceSourceTypeMovingAverage {
+		if source.Days <= 0 {
+			return 0, ErrCannotGetBasePrice
+		}
+		fps, err := s.fourPriceStore.GetBySymbolCodeAndExchange(strategy.SymbolCode, strategy.Exchange, source.Days)
+		if err != nil {
+			return 0, err
+		}
+		closes := make([]float64, 0, len(fps))
+		for _, fp := range fps {
+			if fp != nil {
+				closes = append(closes, fp.Close)
+			}
+		}
+		if len(closes) == 0 {
+			return 0, ErrCannotGetBasePrice
+		}
+		return average(closes), nil
+	}
+
+	symbol, err := s.kabusAPI.GetSymbol(strategy.SymbolCode, strategy.Exchange)
+	if err != nil {
+		return 0, err
+	}
+
+	today := symbol.CurrentPriceDateTime.Format("20060102") == now.Format("20060102")
+	var price float64
+	switch source.Type {
+	case BasePriceSourceTypeVWAP:
+		if today {
+			price = symbol.VWAP
+		}
+	case BasePriceSourceTypeOpen:
+		if today {
+			price = symbol.OpeningPrice
+		}
+	case BasePriceSourceTypeMid:
+		if symbol.BidPrice > 0 && symbol.AskPrice > 0 {
+			price = (symbol.BidPrice + symbol.AskPrice) / 2
+		}
+	}
+
+	if price <= 0 {
+		return 0, ErrCannotGetBasePrice
+	}
+	return price, nil
+}
+
 // sendGridOrder - グリッド注文を作成し、送信する
 func (s *gridService) sendGridOrder(strategy *Strategy, limitPrice float64, basePrice float64, quantity float64) error {
 	if strategy == nil {
@@ -220,6 +545,17 @@ func (s *gridService) width(strategy *Strategy) (int, error) {
 		}
 	}
 
+	// 過去数日のボラティリティからの動的なグリッド幅
+	if v := strategy.GridStrategy.DynamicGridVolatility; v.Valid && v.Days > 0 {
+		// 真の値幅と騰落率には前日の終値が必要なので、1日分多く取得する
+		fps, err := s.fourPriceStore.GetBySymbolCodeAndExchange(strategy.SymbolCode, strategy.Exchange, v.Days+1)
+		if err == nil {
+			if volatility, ok := s.volatility(strategy, fps); ok {
+				w = v.width(w, volatility)
+			}
+		}
+	}
+
 	// 最小・最大約定値からの動的なグリッド幅計算
 	if strategy.GridStrategy.DynamicGridMinMax.Valid {
 		// 高値と安値が現在のグリッド期間のものでないなら、グリッド幅をそのまま返す
@@ -237,3 +573,41 @@ func (s *gridService) width(strategy *Strategy) (int, error) {
 
 	return w, nil
 }
+
+// volatility - 新しい順に並んだ四本値から計算した、tick数でのボラティリティ
+// 最新の終値か、前日の終値がある日が1日もなければ計算できないのでfalseを返す
+func (s *gridService) volatility(strategy *Strategy, fourPrices []*FourPrice) (float64, bool) {
+	v := strategy.GridStrategy.DynamicGridVolatility
+	if len(fourPrices) == 0 || fourPrices[0] == nil || fourPrices[0].Close <= 0 {
+		return 0, false
+	}
+
+	var trueRanges, trueRangeTicks, returns []float64
+	for i := 0; i+1 < len(fourPrices); i++ {
+		fp, prev := fourPrices[i], fourPrices[i+1]
+		if fp == nil || prev == nil || prev.Close <= 0 {
+			continue
+		}
+
+		high, low := math.Max(fp.High, prev.Close), math.Min(fp.Low, prev.Close)
+		trueRanges = append(trueRanges, high-low)
+		trueRangeTicks = append(trueRangeTicks, float64(s.tick.Ticks(strategy.TickGroup, low, high)))
+		returns = append(returns, fp.Close/prev.Close-1)
+	}
+	if len(trueRanges) == 0 {
+		return 0, false
+	}
+
+	// 価格差を最新の終値の呼値単位でtick数にする
+	lastClose := fourPrices[0].Close
+	unit := s.tick.GetTick(strategy.TickGroup, lastClose)
+
+	switch v.Type {
+	case VolatilityTypeATRTicks:
+		return average(trimmedValues(trueRangeTicks, v.Trim)), true
+	case VolatilityTypeStdDev:
+		return standardDeviation(trimmedValues(returns, v.Trim)) * lastClose / unit, true
+	default:
+		return average(trimmedValues(trueRanges, v.Trim)) / unit, true
+	}
+}
@@ -9,9 +9,18 @@ import (
 
 type testGridService struct {
 	IGridService
-	Leveling1       error
-	LevelingCount   int
-	LevelingHistory []interface{}
+	IsRunnable1       bool
+	IsRunnableCount   int
+	IsRunnableHistory []interface{}
+	Leveling1         error
+	LevelingCount     int
+	LevelingHistory   []interface{}
+}
+
+func (t *testGridService) IsRunnable(strategy *Strategy) bool {
+	t.IsRunnableHistory = append(t.IsRunnableHistory, strategy)
+	t.IsRunnableCount++
+	return t.IsRunnable1
 }
 
 func (t *testGridService) Leveling(strategy *Strategy) error {
@@ -164,6 +173,156 @@ func Test_gridService_getBasePrice(t *testing.T) {
 	}
 }
 
+func Test_gridService_getBasePrice_source(t *testing.T) {
+	t.Parallel()
+	timeRanges := []TimeRange{
+		{Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local), End: time.Date(0, 1, 1, 11, 30, 0, 0, time.Local)},
+		{Start: time.Date(0, 1, 1, 12, 30, 0, 0, time.Local), End: time.Date(0, 1, 1, 15, 0, 0, 0, time.Local)},
+	}
+	symbol := &Symbol{Code: "1475", Exchange: ExchangeToushou, CurrentPrice: 2105, CurrentPriceDateTime: time.Date(2021, 11, 2, 10, 0, 0, 0, time.Local),
+		BidPrice: 2102, AskPrice: 2101, OpeningPrice: 2090, VWAP: 2097.4}
+	tests := []struct {
+		name                       string
+		kabusAPI                   *testKabusAPI
+		fourPriceStore             *testFourPriceStore
+		strategyStore              *testStrategyStore
+		arg1                       *Strategy
+		want1                      float64
+		want2                      error
+		wantSetBasePriceHistory    []interface{}
+		wantGetBySymbolCodeHistory []interface{}
+	}{
+		{name: "計算しなおす間隔が過ぎていなければ、保持している基準価格を返す",
+			kabusAPI:       &testKabusAPI{GetSymbol1: symbol},
+			fourPriceStore: &testFourPriceStore{},
+			strategyStore:  &testStrategyStore{},
+			arg1: &Strategy{Code: "strategy-code-001", BasePrice: 2095, BasePriceDateTime: time.Date(2021, 11, 2, 9, 58, 0, 0, time.Local),
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeVWAP, Interval: 5 * time.Minute}}},
+			want1: 2095,
+			want2: nil},
+		{name: "計算しなおす間隔が過ぎていれば、VWAPを呼値に丸めて基準価格にする",
+			kabusAPI:       &testKabusAPI{GetSymbol1: symbol},
+			fourPriceStore: &testFourPriceStore{},
+			strategyStore:  &testStrategyStore{},
+			arg1: &Strategy{Code: "strategy-code-001", BasePrice: 2095, BasePriceDateTime: time.Date(2021, 11, 2, 9, 55, 0, 0, time.Local),
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeVWAP, Interval: 5 * time.Minute}}},
+			want1:                   2097,
+			want2:                   nil,
+			wantSetBasePriceHistory: []interface{}{"strategy-code-001", 2097.0, time.Date(2021, 11, 2, 10, 0, 0, 0, time.Local)}},
+		{name: "保持している基準価格が別の時刻範囲のものなら、間隔に関係なく計算しなおす",
+			kabusAPI:       &testKabusAPI{GetSymbol1: symbol},
+			fourPriceStore: &testFourPriceStore{},
+			strategyStore:  &testStrategyStore{},
+			arg1: &Strategy{Code: "strategy-code-001", BasePrice: 2095, BasePriceDateTime: time.Date(2021, 11, 2, 8, 59, 0, 0, time.Local),
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeOpen, Interval: time.Hour}}},
+			want1:                   2090,
+			want2:                   nil,
+			wantSetBasePriceHistory: []interface{}{"strategy-code-001", 2090.0, time.Date(2021, 11, 2, 10, 0, 0, 0, time.Local)}},
+		{name: "間隔が0なら毎回計算しなおし、最良気配の仲値を基準価格にする",
+			kabusAPI:       &testKabusAPI{GetSymbol1: symbol},
+			fourPriceStore: &testFourPriceStore{},
+			strategyStore:  &testStrategyStore{},
+			arg1: &Strategy{Code: "strategy-code-001", BasePrice: 2095, BasePriceDateTime: time.Date(2021, 11, 2, 10, 0, 0, 0, time.Local),
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeMid}}},
+			want1:                   2102,
+			want2:                   nil,
+			wantSetBasePriceHistory: []interface{}{"strategy-code-001", 2102.0, time.Date(2021, 11, 2, 10, 0, 0, 0, time.Local)}},
+		{name: "移動平均なら指定日数の終値の平均を基準価格にする",
+			kabusAPI:       &testKabusAPI{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{{Close: 2100}, {Close: 2080}, {Close: 2090}}},
+			strategyStore:  &testStrategyStore{},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou,
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeMovingAverage, Days: 3}}},
+			want1:                      2090,
+			want2:                      nil,
+			wantSetBasePriceHistory:    []interface{}{"strategy-code-001", 2090.0, time.Date(2021, 11, 2, 10, 0, 0, 0, time.Local)},
+			wantGetBySymbolCodeHistory: []interface{}{"1475", ExchangeToushou, 3}},
+		{name: "移動平均で日数が指定されていなければエラー",
+			kabusAPI:       &testKabusAPI{},
+			fourPriceStore: &testFourPriceStore{},
+			strategyStore:  &testStrategyStore{},
+			arg1: &Strategy{Code: "strategy-code-001",
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeMovingAverage}}},
+			want1: 0,
+			want2: ErrCannotGetBasePrice},
+		{name: "移動平均で四本値の取得に失敗したらエラー",
+			kabusAPI:       &testKabusAPI{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange2: ErrUnknown},
+			strategyStore:  &testStrategyStore{},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou,
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeMovingAverage, Days: 3}}},
+			want1:                      0,
+			want2:                      ErrUnknown,
+			wantGetBySymbolCodeHistory: []interface{}{"1475", ExchangeToushou, 3}},
+		{name: "移動平均で四本値がなければエラー",
+			kabusAPI:       &testKabusAPI{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{}},
+			strategyStore:  &testStrategyStore{},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou,
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeMovingAverage, Days: 3}}},
+			want1:                      0,
+			want2:                      ErrCannotGetBasePrice,
+			wantGetBySymbolCodeHistory: []interface{}{"1475", ExchangeToushou, 3}},
+		{name: "銘柄情報の取得に失敗したらエラー",
+			kabusAPI:       &testKabusAPI{GetSymbol2: ErrUnknown},
+			fourPriceStore: &testFourPriceStore{},
+			strategyStore:  &testStrategyStore{},
+			arg1: &Strategy{Code: "strategy-code-001",
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeVWAP}}},
+			want1: 0,
+			want2: ErrUnknown},
+		{name: "板情報が前日のものなら、VWAPは使えないのでエラー",
+			kabusAPI: &testKabusAPI{GetSymbol1: &Symbol{CurrentPrice: 2105, CurrentPriceDateTime: time.Date(2021, 11, 1, 15, 0, 0, 0, time.Local),
+				BidPrice: 2102, AskPrice: 2101, OpeningPrice: 2090, VWAP: 2097.4}},
+			fourPriceStore: &testFourPriceStore{},
+			strategyStore:  &testStrategyStore{},
+			arg1: &Strategy{Code: "strategy-code-001",
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeVWAP}}},
+			want1: 0,
+			want2: ErrCannotGetBasePrice},
+		{name: "片側の気配がなければ、仲値は出せないのでエラー",
+			kabusAPI:       &testKabusAPI{GetSymbol1: &Symbol{CurrentPrice: 2105, CurrentPriceDateTime: time.Date(2021, 11, 2, 10, 0, 0, 0, time.Local), BidPrice: 2102}},
+			fourPriceStore: &testFourPriceStore{},
+			strategyStore:  &testStrategyStore{},
+			arg1: &Strategy{Code: "strategy-code-001",
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeMid}}},
+			want1: 0,
+			want2: ErrCannotGetBasePrice},
+		{name: "基準価格の保存に失敗したらエラー",
+			kabusAPI:       &testKabusAPI{GetSymbol1: symbol},
+			fourPriceStore: &testFourPriceStore{},
+			strategyStore:  &testStrategyStore{SetBasePrice1: ErrUnknown},
+			arg1: &Strategy{Code: "strategy-code-001",
+				GridStrategy: GridStrategy{TimeRanges: timeRanges, BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeOpen}}},
+			want1:                   0,
+			want2:                   ErrUnknown,
+			wantSetBasePriceHistory: []interface{}{"strategy-code-001", 2090.0, time.Date(2021, 11, 2, 10, 0, 0, 0, time.Local)}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &gridService{
+				clock:          &testClock{Now1: time.Date(2021, 11, 2, 10, 0, 0, 0, time.Local)},
+				tick:           &tick{},
+				kabusAPI:       test.kabusAPI,
+				strategyStore:  test.strategyStore,
+				fourPriceStore: test.fourPriceStore,
+			}
+			got1, got2 := service.getBasePrice(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) ||
+				!errors.Is(got2, test.want2) ||
+				!reflect.DeepEqual(test.wantSetBasePriceHistory, test.strategyStore.SetBasePriceHistory) ||
+				!reflect.DeepEqual(test.wantGetBySymbolCodeHistory, test.fourPriceStore.GetBySymbolCodeAndExchangeHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.want2, test.wantSetBasePriceHistory, test.wantGetBySymbolCodeHistory,
+					got1, got2, test.strategyStore.SetBasePriceHistory, test.fourPriceStore.GetBySymbolCodeAndExchangeHistory)
+			}
+		})
+	}
+}
+
 func Test_gridService_sendGridOrder(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -273,6 +432,54 @@ func Test_gridService_sendGridOrder(t *testing.T) {
 	}
 }
 
+func Test_gridService_IsRunnable(t *testing.T) {
+	t.Parallel()
+	grid := GridStrategy{
+		Runnable: true,
+		TimeRanges: []TimeRange{{
+			Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+			End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}}
+	tests := []struct {
+		name  string
+		clock *testClock
+		arg1  *Strategy
+		want1 bool
+	}{
+		{name: "引数がnilならfalse",
+			clock: &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			arg1:  nil,
+			want1: false},
+		{name: "戦略自体が実行不可ならfalse",
+			clock: &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			arg1:  &Strategy{GridStrategy: grid, Runnable: false},
+			want1: false},
+		{name: "グリッド戦略の時間外ならfalse",
+			clock: &testClock{Now1: time.Date(2021, 11, 5, 15, 0, 0, 0, time.Local), IsTradingTime1: true},
+			arg1:  &Strategy{GridStrategy: grid, Runnable: true},
+			want1: false},
+		{name: "取引時間でないならfalse",
+			clock: &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: false},
+			arg1:  &Strategy{GridStrategy: grid, Runnable: true},
+			want1: false},
+		{name: "戦略とグリッド戦略が実行可能で取引時間ならtrue",
+			clock: &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			arg1:  &Strategy{GridStrategy: grid, Runnable: true},
+			want1: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &gridService{clock: test.clock}
+			got1 := service.IsRunnable(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
 func Test_gridService_Leveling(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -287,6 +494,7 @@ func Test_gridService_Leveling(t *testing.T) {
 		wantCancelHistory     []interface{}
 		wantEntryLimitHistory []interface{}
 		wantExitLimitHistory  []interface{}
+		wantExitStopHistory   []interface{}
 	}{
 		{name: "引数がnilならエラー",
 			clock: &testClock{
@@ -514,6 +722,31 @@ func Test_gridService_Leveling(t *testing.T) {
 				Runnable: true},
 			want1:             nil,
 			wantCancelHistory: nil},
+		{name: "グリッドの範囲外でも送信中の注文は取消しない",
+			clock: &testClock{
+				Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+				IsTradingTime1: true},
+			orderService: &testOrderService{
+				GetActiveOrdersByStrategyCode1: []*Order{
+					{Code: "order-code-001", Price: 2102, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-002", Price: 2098, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "pending-001", Status: OrderStatusPending, Price: 2110, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit}}},
+			kabusAPI:      &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 2100, CurrentPriceDateTime: time.Date(2021, 11, 5, 9, 0, 0, 0, time.Local), BidPrice: 2101, AskPrice: 2099}},
+			strategyStore: &testStrategyStore{},
+			tick:          &tick{},
+			arg1: &Strategy{
+				Code: "strategy-code-001",
+				GridStrategy: GridStrategy{
+					Runnable:      true,
+					BaseWidth:     2,
+					Quantity:      4,
+					NumberOfGrids: 1,
+					TimeRanges: []TimeRange{{
+						Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+						End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}},
+				Runnable: true},
+			want1:             nil,
+			wantCancelHistory: nil},
 		{name: "グリッド本数が0本の場合、何もせずに終了",
 			clock: &testClock{
 				Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
@@ -618,6 +851,107 @@ func Test_gridService_Leveling(t *testing.T) {
 				"strategy-code-001", 2102.0, 4.0, SortOrderNewest,
 				"strategy-code-001", 2104.0, 4.0, SortOrderNewest,
 			}},
+		{name: "幾何級数の間隔なら、一定の割合ずつ離して呼値単位に丸めたグリッドに注文をのせる",
+			clock: &testClock{
+				Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+				IsTradingTime1: true},
+			orderService: &testOrderService{
+				GetActiveOrdersByStrategyCode1: []*Order{}},
+			kabusAPI:      &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 2100, CurrentPriceDateTime: time.Date(2021, 11, 5, 9, 0, 0, 0, time.Local), BidPrice: 2101, AskPrice: 2099}},
+			strategyStore: &testStrategyStore{},
+			tick:          &tick{},
+			arg1: &Strategy{
+				Code:      "strategy-code-001",
+				EntrySide: SideBuy,
+				GridStrategy: GridStrategy{
+					Runnable:      true,
+					BaseWidth:     2,
+					Quantity:      4,
+					NumberOfGrids: 2,
+					Spacing:       GridSpacingGeometric,
+					WidthRate:     0.01,
+					TimeRanges: []TimeRange{{
+						Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+						End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}},
+				Runnable: true},
+			want1: nil,
+			wantEntryLimitHistory: []interface{}{
+				"strategy-code-001", 2079.0, 4.0,
+				"strategy-code-001", 2058.0, 4.0,
+			},
+			wantExitLimitHistory: []interface{}{
+				"strategy-code-001", 2121.0, 4.0, SortOrderNewest,
+				"strategy-code-001", 2142.0, 4.0, SortOrderNewest,
+			}},
+		{name: "上下の設定が有効なら、上下それぞれの幅、本数、数量で注文をのせる",
+			clock: &testClock{
+				Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+				IsTradingTime1: true},
+			orderService: &testOrderService{
+				GetActiveOrdersByStrategyCode1: []*Order{}},
+			kabusAPI:      &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 2100, CurrentPriceDateTime: time.Date(2021, 11, 5, 9, 0, 0, 0, time.Local), BidPrice: 2101, AskPrice: 2099}},
+			strategyStore: &testStrategyStore{},
+			tick:          &tick{},
+			arg1: &Strategy{
+				Code:      "strategy-code-001",
+				EntrySide: SideBuy,
+				GridStrategy: GridStrategy{
+					Runnable:      true,
+					BaseWidth:     2,
+					Quantity:      4,
+					NumberOfGrids: 2,
+					Upper:         GridSideStrategy{Valid: true, Width: 3, NumberOfGrids: 1, Quantity: 8},
+					Lower:         GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 3, Quantity: 2},
+					TimeRanges: []TimeRange{{
+						Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+						End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}},
+				Runnable: true},
+			want1: nil,
+			wantEntryLimitHistory: []interface{}{
+				"strategy-code-001", 2098.0, 2.0,
+				"strategy-code-001", 2096.0, 2.0,
+				"strategy-code-001", 2094.0, 2.0,
+			},
+			wantExitLimitHistory: []interface{}{
+				"strategy-code-001", 2103.0, 8.0, SortOrderNewest,
+			}},
+		{name: "数量の決め方が指定されていれば、グリッドごとの数量を乗せ、基準価格に乗っている数量は隣のグリッドから減らす",
+			clock: &testClock{
+				Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+				IsTradingTime1: true},
+			orderService: &testOrderService{
+				GetActiveOrdersByStrategyCode1: []*Order{
+					{Code: "order-code-001", Price: 2100, OrderQuantity: 1, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+				},
+				MaxEntryQuantity1: 5},
+			kabusAPI:      &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 2100, CurrentPriceDateTime: time.Date(2021, 11, 5, 9, 0, 0, 0, time.Local), BidPrice: 2101, AskPrice: 2099}},
+			strategyStore: &testStrategyStore{},
+			tick:          &tick{},
+			arg1: &Strategy{
+				Code:        "strategy-code-001",
+				EntrySide:   SideBuy,
+				TradingUnit: 1,
+				GridStrategy: GridStrategy{
+					Runnable:        true,
+					BaseWidth:       2,
+					Quantity:        2,
+					NumberOfGrids:   3,
+					QuantityProfile: QuantityProfile{Type: QuantityProfileTypeLinear, Step: 2},
+					TimeRanges: []TimeRange{{
+						Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+						End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}},
+				Runnable: true},
+			want1: nil,
+			wantEntryLimitHistory: []interface{}{
+				"strategy-code-001", 2098.0, 1.0,
+				"strategy-code-001", 2096.0, 4.0,
+				"strategy-code-001", 2094.0, 5.0,
+			},
+			wantExitLimitHistory: []interface{}{
+				"strategy-code-001", 2102.0, 1.0, SortOrderNewest,
+				"strategy-code-001", 2104.0, 4.0, SortOrderNewest,
+				"strategy-code-001", 2106.0, 6.0, SortOrderNewest,
+			}},
 		{name: "エントリー注文でエラーがでたらエラー",
 			clock: &testClock{
 				Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
@@ -726,6 +1060,108 @@ func Test_gridService_Leveling(t *testing.T) {
 				Runnable: true},
 			want1:                ErrUnknown,
 			wantExitLimitHistory: []interface{}{"strategy-code-001", 2102.0, 1.0, SortOrderNewest}},
+		{name: "保護用の逆指値戦略が有効で、逆指値の注文がなければ最も外側のグリッドの外に逆指値を置く",
+			clock: &testClock{
+				Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+				IsTradingTime1: true},
+			orderService: &testOrderService{
+				GetActiveOrdersByStrategyCode1: []*Order{
+					{Code: "order-code-001", Price: 2102, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-002", Price: 2098, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-003", Price: 2104, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-004", Price: 2096, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+				}},
+			kabusAPI:      &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 2100, CurrentPriceDateTime: time.Date(2021, 11, 5, 9, 0, 0, 0, time.Local), BidPrice: 2101, AskPrice: 2099}},
+			strategyStore: &testStrategyStore{},
+			tick:          &tick{},
+			arg1: &Strategy{
+				Code:      "strategy-code-001",
+				EntrySide: SideBuy,
+				GridStrategy: GridStrategy{
+					Runnable:      true,
+					BaseWidth:     2,
+					Quantity:      4,
+					NumberOfGrids: 2,
+					TimeRanges: []TimeRange{{
+						Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+						End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}},
+				ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopMarket, Width: 3},
+				Runnable:               true},
+			want1:               nil,
+			wantExitStopHistory: []interface{}{"strategy-code-001", ExecutionTypeStopMarket, 2093.0, 0.0}},
+		{name: "保護用の逆指値戦略が有効で、価格の合う逆指値の注文があれば何もしない",
+			clock: &testClock{
+				Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+				IsTradingTime1: true},
+			orderService: &testOrderService{
+				GetActiveOrdersByStrategyCode1: []*Order{
+					{Code: "order-code-001", Price: 2102, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-002", Price: 2098, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-003", Price: 2104, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-004", Price: 2096, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-005", TriggerPrice: 2093, OrderQuantity: 8, ContractQuantity: 0, ExecutionType: ExecutionTypeStopMarket},
+				}},
+			kabusAPI:      &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 2100, CurrentPriceDateTime: time.Date(2021, 11, 5, 9, 0, 0, 0, time.Local), BidPrice: 2101, AskPrice: 2099}},
+			strategyStore: &testStrategyStore{},
+			tick:          &tick{},
+			arg1: &Strategy{
+				Code:      "strategy-code-001",
+				EntrySide: SideBuy,
+				GridStrategy: GridStrategy{
+					Runnable:      true,
+					BaseWidth:     2,
+					Quantity:      4,
+					NumberOfGrids: 2,
+					TimeRanges: []TimeRange{{
+						Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+						End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}},
+				ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopMarket, Width: 3},
+				Runnable:               true},
+			want1: nil},
+		{name: "保護用の逆指値戦略が有効で、価格の合わない逆指値の注文があれば取り消して、新しい逆指値は次の周回まで置かない",
+			clock: &testClock{
+				Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+				IsTradingTime1: true},
+			orderService: &testOrderService{
+				GetActiveOrdersByStrategyCode1: []*Order{
+					{Code: "order-code-001", Price: 2102, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-002", Price: 2098, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-003", Price: 2104, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-004", Price: 2096, OrderQuantity: 4, ContractQuantity: 0, ExecutionType: ExecutionTypeLimit},
+					{Code: "order-code-005", TriggerPrice: 2091, OrderQuantity: 8, ContractQuantity: 0, ExecutionType: ExecutionTypeStopMarket},
+				}},
+			kabusAPI:      &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 2100, CurrentPriceDateTime: time.Date(2021, 11, 5, 9, 0, 0, 0, time.Local), BidPrice: 2101, AskPrice: 2099}},
+			strategyStore: &testStrategyStore{},
+			tick:          &tick{},
+			arg1: &Strategy{
+				Code:      "strategy-code-001",
+				EntrySide: SideBuy,
+				GridStrategy: GridStrategy{
+					Runnable:      true,
+					BaseWidth:     2,
+					Quantity:      4,
+					NumberOfGrids: 2,
+					TimeRanges: []TimeRange{{
+						Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+						End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}},
+				ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopMarket, Width: 3},
+				Runnable:               true},
+			want1: nil,
+			wantCancelHistory: []interface{}{
+				&Strategy{
+					Code:      "strategy-code-001",
+					EntrySide: SideBuy,
+					GridStrategy: GridStrategy{
+						Runnable:      true,
+						BaseWidth:     2,
+						Quantity:      4,
+						NumberOfGrids: 2,
+						TimeRanges: []TimeRange{{
+							Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+							End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}},
+					ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopMarket, Width: 3},
+					Runnable:               true},
+				"order-code-005"}},
 	}
 
 	for _, test := range tests {
@@ -733,24 +1169,358 @@ func Test_gridService_Leveling(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 			service := &gridService{
-				clock:         test.clock,
-				tick:          test.tick,
-				kabusAPI:      test.kabusAPI,
-				strategyStore: test.strategyStore,
-				orderService:  test.orderService,
+				clock:              test.clock,
+				tick:               test.tick,
+				kabusAPI:           test.kabusAPI,
+				strategyStore:      test.strategyStore,
+				orderService:       test.orderService,
+				fourPriceStore:     &testFourPriceStore{},
+				priceBandService:   &testPriceBandService{},
+				trendFilterService: &testTrendFilterService{},
 			}
 			got1 := service.Leveling(test.arg1)
 			if !errors.Is(got1, test.want1) ||
 				!reflect.DeepEqual(test.wantCancelHistory, test.orderService.CancelHistory) ||
 				!reflect.DeepEqual(test.wantEntryLimitHistory, test.orderService.EntryLimitHistory) ||
-				!reflect.DeepEqual(test.wantExitLimitHistory, test.orderService.ExitLimitHistory) {
-				t.Errorf("%s error\nresult: %+v, %+v, %+v, %+v\nwant: %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v\n", t.Name(),
+				!reflect.DeepEqual(test.wantExitLimitHistory, test.orderService.ExitLimitHistory) ||
+				!reflect.DeepEqual(test.wantExitStopHistory, test.orderService.ExitStopHistory) {
+				t.Errorf("%s error\nresult: %+v, %+v, %+v, %+v, %+v\nwant: %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v\n", t.Name(),
 					!errors.Is(got1, test.want1),
 					!reflect.DeepEqual(test.wantCancelHistory, test.orderService.CancelHistory),
 					!reflect.DeepEqual(test.wantEntryLimitHistory, test.orderService.EntryLimitHistory),
 					!reflect.DeepEqual(test.wantExitLimitHistory, test.orderService.ExitLimitHistory),
-					test.want1, test.wantCancelHistory, test.wantEntryLimitHistory, test.wantExitLimitHistory,
-					got1, test.orderService.CancelHistory, test.orderService.EntryLimitHistory, test.orderService.ExitLimitHistory)
+					!reflect.DeepEqual(test.wantExitStopHistory, test.orderService.ExitStopHistory),
+					test.want1, test.wantCancelHistory, test.wantEntryLimitHistory, test.wantExitLimitHistory, test.wantExitStopHistory,
+					got1, test.orderService.CancelHistory, test.orderService.EntryLimitHistory, test.orderService.ExitLimitHistory, test.orderService.ExitStopHistory)
+			}
+		})
+	}
+}
+
+func Test_gridService_Leveling_PriceBand(t *testing.T) {
+	t.Parallel()
+	strategy := func(policy PriceBandPolicy) *Strategy {
+		return &Strategy{
+			Code:        "strategy-code-001",
+			EntrySide:   SideBuy,
+			TradingUnit: 1,
+			GridStrategy: GridStrategy{
+				Runnable:      true,
+				BaseWidth:     2,
+				Quantity:      4,
+				NumberOfGrids: 2,
+				TimeRanges: []TimeRange{{
+					Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+					End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}},
+				PriceBand: PriceBand{Valid: true, Source: PriceBandSourceAbsolute, Policy: policy}},
+			Runnable: true}
+	}
+	disabled := strategy(PriceBandPolicyKeep)
+	disabled.GridStrategy.PriceBand.Valid = false
+	tests := []struct {
+		name                    string
+		orderService            *testOrderService
+		priceBandService        *testPriceBandService
+		arg1                    *Strategy
+		want1                   error
+		wantCheckHistory        []interface{}
+		wantCancelHistory       []interface{}
+		wantEntryLimitHistory   []interface{}
+		wantExitLimitHistory    []interface{}
+		wantForceExitAllHistory []interface{}
+	}{
+		{name: "価格帯の判定でエラーがあればエラー",
+			orderService:     &testOrderService{},
+			priceBandService: &testPriceBandService{Check2: ErrNoData},
+			arg1:             strategy(PriceBandPolicyKeep),
+			want1:            ErrNoData,
+			wantCheckHistory: []interface{}{strategy(PriceBandPolicyKeep), 2100.0}},
+		{name: "価格帯の中なら、価格帯の外にあるグリッドにだけ注文を置かない",
+			orderService:          &testOrderService{},
+			priceBandService:      &testPriceBandService{Check1: PriceBandState{Lower: 2097, Upper: 2103}},
+			arg1:                  strategy(PriceBandPolicyKeep),
+			want1:                 nil,
+			wantCheckHistory:      []interface{}{strategy(PriceBandPolicyKeep), 2100.0},
+			wantEntryLimitHistory: []interface{}{"strategy-code-001", 2098.0, 4.0},
+			wantExitLimitHistory:  []interface{}{"strategy-code-001", 2102.0, 4.0, SortOrderNewest}},
+		{name: "価格帯の外でも維持する対応なら、エントリー注文は取り消さず、価格帯の中のグリッドに注文を置く",
+			orderService: &testOrderService{GetActiveOrdersByStrategyCode1: []*Order{
+				{Code: "order-code-001", TradeType: TradeTypeEntry, Price: 2098, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit}}},
+			priceBandService:      &testPriceBandService{Check1: PriceBandState{Upper: 2099, OutOfBand: true}},
+			arg1:                  strategy(PriceBandPolicyKeep),
+			want1:                 nil,
+			wantCheckHistory:      []interface{}{strategy(PriceBandPolicyKeep), 2100.0},
+			wantEntryLimitHistory: []interface{}{"strategy-code-001", 2096.0, 4.0}},
+		{name: "価格帯の外でエントリーを取り消す対応なら、エントリー注文を取り消してエントリー側のグリッドに注文を置かない",
+			orderService: &testOrderService{GetActiveOrdersByStrategyCode1: []*Order{
+				{Code: "order-code-001", TradeType: TradeTypeEntry, Price: 2098, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit},
+				{Code: "order-code-002", TradeType: TradeTypeExit, Price: 2102, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit}}},
+			priceBandService:     &testPriceBandService{Check1: PriceBandState{Upper: 2099.5, OutOfBand: true}},
+			arg1:                 strategy(PriceBandPolicyCancelEntries),
+			want1:                nil,
+			wantCheckHistory:     []interface{}{strategy(PriceBandPolicyCancelEntries), 2100.0},
+			wantCancelHistory:    []interface{}{strategy(PriceBandPolicyCancelEntries), "order-code-001", strategy(PriceBandPolicyCancelEntries), "order-code-002"},
+			wantExitLimitHistory: nil},
+		{name: "グリッド上にある注文でも、価格帯の外にあれば取り消す",
+			orderService: &testOrderService{GetActiveOrdersByStrategyCode1: []*Order{
+				{Code: "order-code-001", TradeType: TradeTypeEntry, Price: 2098, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit},
+				{Code: "order-code-002", TradeType: TradeTypeEntry, Price: 2096, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit},
+				{Code: "order-code-003", TradeType: TradeTypeExit, Price: 2102, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit},
+				{Code: "order-code-004", TradeType: TradeTypeExit, Price: 2104, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit}}},
+			priceBandService:  &testPriceBandService{Check1: PriceBandState{Lower: 2097, Upper: 2103}},
+			arg1:              strategy(PriceBandPolicyKeep),
+			want1:             nil,
+			wantCheckHistory:  []interface{}{strategy(PriceBandPolicyKeep), 2100.0},
+			wantCancelHistory: []interface{}{strategy(PriceBandPolicyKeep), "order-code-002", strategy(PriceBandPolicyKeep), "order-code-004"}},
+		{name: "価格帯の外でエントリーを取り消す対応なら、価格帯の中にあるエグジット側のグリッドには注文を置く",
+			orderService: &testOrderService{GetActiveOrdersByStrategyCode1: []*Order{
+				{Code: "order-code-001", TradeType: TradeTypeEntry, Price: 2098, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit}}},
+			priceBandService:     &testPriceBandService{Check1: PriceBandState{Lower: 2100.5, OutOfBand: true}},
+			arg1:                 strategy(PriceBandPolicyCancelEntries),
+			want1:                nil,
+			wantCheckHistory:     []interface{}{strategy(PriceBandPolicyCancelEntries), 2100.0},
+			wantCancelHistory:    []interface{}{strategy(PriceBandPolicyCancelEntries), "order-code-001"},
+			wantExitLimitHistory: []interface{}{"strategy-code-001", 2102.0, 4.0, SortOrderNewest, "strategy-code-001", 2104.0, 4.0, SortOrderNewest}},
+		{name: "価格帯の外で全エグジットする対応なら、送信中以外の指値と逆指値を取り消して全エグジットする",
+			orderService: &testOrderService{GetActiveOrdersByStrategyCode1: []*Order{
+				{Code: "order-code-001", TradeType: TradeTypeEntry, Price: 2098, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit},
+				{Code: "order-code-002", TradeType: TradeTypeExit, Price: 2102, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit, Status: OrderStatusPending},
+				{Code: "order-code-003", TradeType: TradeTypeExit, TriggerPrice: 2093, OrderQuantity: 4, ExecutionType: ExecutionTypeStopMarket},
+				{Code: "order-code-004", TradeType: TradeTypeExit, OrderQuantity: 4, ExecutionType: ExecutionTypeMarket}}},
+			priceBandService:        &testPriceBandService{Check1: PriceBandState{Upper: 2099, OutOfBand: true}},
+			arg1:                    strategy(PriceBandPolicyFlatten),
+			want1:                   nil,
+			wantCheckHistory:        []interface{}{strategy(PriceBandPolicyFlatten), 2100.0},
+			wantCancelHistory:       []interface{}{strategy(PriceBandPolicyFlatten), "order-code-001", strategy(PriceBandPolicyFlatten), "order-code-003"},
+			wantForceExitAllHistory: []interface{}{strategy(PriceBandPolicyFlatten)}},
+		{name: "価格帯の中なら、全エグジットする対応でもグリッドを置く",
+			orderService:          &testOrderService{},
+			priceBandService:      &testPriceBandService{Check1: PriceBandState{Lower: 2000, Upper: 2200}},
+			arg1:                  strategy(PriceBandPolicyFlatten),
+			want1:                 nil,
+			wantCheckHistory:      []interface{}{strategy(PriceBandPolicyFlatten), 2100.0},
+			wantEntryLimitHistory: []interface{}{"strategy-code-001", 2098.0, 4.0, "strategy-code-001", 2096.0, 4.0},
+			wantExitLimitHistory:  []interface{}{"strategy-code-001", 2102.0, 4.0, SortOrderNewest, "strategy-code-001", 2104.0, 4.0, SortOrderNewest}}, {name: "価格帯が無効でも、前回の判定結果を消すために判定を呼び、全てのグリッドに注文を置く",
+			orderService:          &testOrderService{},
+			priceBandService:      &testPriceBandService{},
+			arg1:                  disabled,
+			want1:                 nil,
+			wantCheckHistory:      []interface{}{disabled, 2100.0},
+			wantEntryLimitHistory: []interface{}{"strategy-code-001", 2098.0, 4.0, "strategy-code-001", 2096.0, 4.0},
+			wantExitLimitHistory:  []interface{}{"strategy-code-001", 2102.0, 4.0, SortOrderNewest, "strategy-code-001", 2104.0, 4.0, SortOrderNewest}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &gridService{
+				clock: &testClock{
+					Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+					IsTradingTime1: true},
+				tick:               &tick{},
+				kabusAPI:           &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 2100, CurrentPriceDateTime: time.Date(2021, 11, 5, 9, 0, 0, 0, time.Local)}},
+				strategyStore:      &testStrategyStore{},
+				orderService:       test.orderService,
+				fourPriceStore:     &testFourPriceStore{},
+				priceBandService:   test.priceBandService,
+				trendFilterService: &testTrendFilterService{},
+			}
+			got1 := service.Leveling(test.arg1)
+			if !errors.Is(got1, test.want1) ||
+				!reflect.DeepEqual(test.wantCheckHistory, test.priceBandService.CheckHistory) ||
+				!reflect.DeepEqual(test.wantCancelHistory, test.orderService.CancelHistory) ||
+				!reflect.DeepEqual(test.wantEntryLimitHistory, test.orderService.EntryLimitHistory) ||
+				!reflect.DeepEqual(test.wantExitLimitHistory, test.orderService.ExitLimitHistory) ||
+				!reflect.DeepEqual(test.wantForceExitAllHistory, test.orderService.ForceExitAllHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantCheckHistory, test.wantCancelHistory, test.wantEntryLimitHistory, test.wantExitLimitHistory, test.wantForceExitAllHistory,
+					got1, test.priceBandService.CheckHistory, test.orderService.CancelHistory, test.orderService.EntryLimitHistory, test.orderService.ExitLimitHistory, test.orderService.ForceExitAllHistory)
+			}
+		})
+	}
+}
+
+func Test_gridService_Leveling_TrendFilter(t *testing.T) {
+	t.Parallel()
+	strategy := func(action TrendFilterAction) *Strategy {
+		return &Strategy{
+			Code:        "strategy-code-001",
+			EntrySide:   SideBuy,
+			TradingUnit: 1,
+			GridStrategy: GridStrategy{
+				Runnable:      true,
+				BaseWidth:     2,
+				Quantity:      4,
+				NumberOfGrids: 2,
+				TimeRanges: []TimeRange{{
+					Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+					End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}},
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Days: 5, Threshold: 0.03, Action: action, QuantityRate: 0.5}},
+			Runnable: true}
+	}
+	disabled := strategy(TrendFilterActionStopEntry)
+	disabled.GridStrategy.TrendFilter.Valid = false
+	tests := []struct {
+		name                  string
+		orderService          *testOrderService
+		trendFilterService    *testTrendFilterService
+		arg1                  *Strategy
+		want1                 error
+		wantCheckHistory      []interface{}
+		wantCancelHistory     []interface{}
+		wantEntryLimitHistory []interface{}
+		wantExitLimitHistory  []interface{}
+	}{
+		{name: "トレンドの判定でエラーがあればエラー",
+			orderService:       &testOrderService{},
+			trendFilterService: &testTrendFilterService{Check2: ErrNoData},
+			arg1:               strategy(TrendFilterActionStopEntry),
+			want1:              ErrNoData,
+			wantCheckHistory:   []interface{}{strategy(TrendFilterActionStopEntry), 2100.0}},
+		{name: "フィルタが効いていなければ、通常通りグリッドを置く",
+			orderService:          &testOrderService{},
+			trendFilterService:    &testTrendFilterService{Check1: TrendFilterState{}},
+			arg1:                  strategy(TrendFilterActionStopEntry),
+			want1:                 nil,
+			wantCheckHistory:      []interface{}{strategy(TrendFilterActionStopEntry), 2100.0},
+			wantEntryLimitHistory: []interface{}{"strategy-code-001", 2098.0, 4.0, "strategy-code-001", 2096.0, 4.0},
+			wantExitLimitHistory:  []interface{}{"strategy-code-001", 2102.0, 4.0, SortOrderNewest, "strategy-code-001", 2104.0, 4.0, SortOrderNewest}},
+		{name: "フィルタが無効でも、前回の判定結果を消すために判定を呼び、通常通りグリッドを置く",
+			orderService:          &testOrderService{},
+			trendFilterService:    &testTrendFilterService{},
+			arg1:                  disabled,
+			want1:                 nil,
+			wantCheckHistory:      []interface{}{disabled, 2100.0},
+			wantEntryLimitHistory: []interface{}{"strategy-code-001", 2098.0, 4.0, "strategy-code-001", 2096.0, 4.0},
+			wantExitLimitHistory:  []interface{}{"strategy-code-001", 2102.0, 4.0, SortOrderNewest, "strategy-code-001", 2104.0, 4.0, SortOrderNewest}},
+		{name: "フィルタが効いていてエントリーを止める対応なら、エントリー注文を取り消してエグジット側のグリッドにだけ注文を置く",
+			orderService: &testOrderService{GetActiveOrdersByStrategyCode1: []*Order{
+				{Code: "order-code-001", TradeType: TradeTypeEntry, Price: 2098, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit},
+				{Code: "order-code-002", TradeType: TradeTypeExit, Price: 2102, OrderQuantity: 4, ExecutionType: ExecutionTypeLimit}}},
+			trendFilterService:   &testTrendFilterService{Check1: TrendFilterState{Active: true, Value: -0.05}},
+			arg1:                 strategy(TrendFilterActionStopEntry),
+			want1:                nil,
+			wantCheckHistory:     []interface{}{strategy(TrendFilterActionStopEntry), 2100.0},
+			wantCancelHistory:    []interface{}{strategy(TrendFilterActionStopEntry), "order-code-001"},
+			wantExitLimitHistory: []interface{}{"strategy-code-001", 2104.0, 4.0, SortOrderNewest}},
+		{name: "対応が未指定ならエントリーを止める",
+			orderService:         &testOrderService{},
+			trendFilterService:   &testTrendFilterService{Check1: TrendFilterState{Active: true, Value: -0.05}},
+			arg1:                 strategy(TrendFilterActionUnspecified),
+			want1:                nil,
+			wantCheckHistory:     []interface{}{strategy(TrendFilterActionUnspecified), 2100.0},
+			wantExitLimitHistory: []interface{}{"strategy-code-001", 2102.0, 4.0, SortOrderNewest, "strategy-code-001", 2104.0, 4.0, SortOrderNewest}},
+		{name: "フィルタが効いていてエントリーを減らす対応なら、エントリー側の数量を減らしてグリッドを置く",
+			orderService:          &testOrderService{},
+			trendFilterService:    &testTrendFilterService{Check1: TrendFilterState{Active: true, Value: -0.05}},
+			arg1:                  strategy(TrendFilterActionReduceEntry),
+			want1:                 nil,
+			wantCheckHistory:      []interface{}{strategy(TrendFilterActionReduceEntry), 2100.0},
+			wantEntryLimitHistory: []interface{}{"strategy-code-001", 2098.0, 2.0, "strategy-code-001", 2096.0, 2.0},
+			wantExitLimitHistory:  []interface{}{"strategy-code-001", 2102.0, 4.0, SortOrderNewest, "strategy-code-001", 2104.0, 4.0, SortOrderNewest}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &gridService{
+				clock: &testClock{
+					Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+					IsTradingTime1: true},
+				tick:               &tick{},
+				kabusAPI:           &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 2100, CurrentPriceDateTime: time.Date(2021, 11, 5, 9, 0, 0, 0, time.Local)}},
+				strategyStore:      &testStrategyStore{},
+				orderService:       test.orderService,
+				fourPriceStore:     &testFourPriceStore{},
+				priceBandService:   &testPriceBandService{},
+				trendFilterService: test.trendFilterService,
+			}
+			got1 := service.Leveling(test.arg1)
+			if !errors.Is(got1, test.want1) ||
+				!reflect.DeepEqual(test.wantCheckHistory, test.trendFilterService.CheckHistory) ||
+				!reflect.DeepEqual(test.wantCancelHistory, test.orderService.CancelHistory) ||
+				!reflect.DeepEqual(test.wantEntryLimitHistory, test.orderService.EntryLimitHistory) ||
+				!reflect.DeepEqual(test.wantExitLimitHistory, test.orderService.ExitLimitHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantCheckHistory, test.wantCancelHistory, test.wantEntryLimitHistory, test.wantExitLimitHistory,
+					got1, test.trendFilterService.CheckHistory, test.orderService.CancelHistory, test.orderService.EntryLimitHistory, test.orderService.ExitLimitHistory)
+			}
+		})
+	}
+}
+
+func Test_gridService_Leveling_PriceLimit(t *testing.T) {
+	t.Parallel()
+	strategy := &Strategy{
+		Code:        "strategy-code-001",
+		SymbolCode:  "1475",
+		Exchange:    ExchangeToushou,
+		EntrySide:   SideBuy,
+		TradingUnit: 1,
+		GridStrategy: GridStrategy{
+			Runnable:      true,
+			BaseWidth:     2,
+			Quantity:      4,
+			NumberOfGrids: 2,
+			TimeRanges: []TimeRange{{
+				Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.Local),
+				End:   time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}},
+		ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopMarket, Width: 3},
+		Runnable:               true}
+	tests := []struct {
+		name                  string
+		fourPriceStore        *testFourPriceStore
+		wantEntryLimitHistory []interface{}
+		wantExitLimitHistory  []interface{}
+		wantExitStopHistory   []interface{}
+	}{
+		{name: "前日の四本値がなければ値幅制限なしとしてグリッドを置く",
+			fourPriceStore:        &testFourPriceStore{GetBySymbolCodeAndExchange2: ErrNoData},
+			wantEntryLimitHistory: []interface{}{"strategy-code-001", 2098.0, 4.0, "strategy-code-001", 2096.0, 4.0},
+			wantExitLimitHistory:  []interface{}{"strategy-code-001", 2102.0, 4.0, SortOrderNewest, "strategy-code-001", 2104.0, 4.0, SortOrderNewest},
+			wantExitStopHistory:   []interface{}{"strategy-code-001", ExecutionTypeStopMarket, 2093.0, 0.0}},
+		{name: "値幅制限の外にあるグリッドには注文を置かず、保護用の逆指値は値幅下限に寄せる",
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{
+				{DateTime: time.Date(2021, 11, 4, 15, 0, 0, 0, time.Local), Close: 2597}}},
+			wantEntryLimitHistory: []interface{}{"strategy-code-001", 2098.0, 4.0},
+			wantExitLimitHistory:  []interface{}{"strategy-code-001", 2102.0, 4.0, SortOrderNewest, "strategy-code-001", 2104.0, 4.0, SortOrderNewest},
+			wantExitStopHistory:   []interface{}{"strategy-code-001", ExecutionTypeStopMarket, 2097.0, 0.0}},
+		{name: "当日の四本値が保存されていたら、それより前の日の終値を基準値段にして値幅上限より上には注文を置かない",
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{
+				{DateTime: time.Date(2021, 11, 5, 15, 0, 0, 0, time.Local), Close: 1600},
+				{DateTime: time.Date(2021, 11, 4, 15, 0, 0, 0, time.Local), Close: 1701}}},
+			wantEntryLimitHistory: []interface{}{"strategy-code-001", 2098.0, 4.0, "strategy-code-001", 2096.0, 4.0},
+			wantExitLimitHistory:  nil,
+			wantExitStopHistory:   []interface{}{"strategy-code-001", ExecutionTypeStopMarket, 2093.0, 0.0}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			orderService := &testOrderService{}
+			service := &gridService{
+				clock: &testClock{
+					Now1:           time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+					IsTradingTime1: true},
+				tick:               &tick{},
+				kabusAPI:           &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 2100, CurrentPriceDateTime: time.Date(2021, 11, 5, 9, 0, 0, 0, time.Local)}},
+				strategyStore:      &testStrategyStore{},
+				orderService:       orderService,
+				fourPriceStore:     test.fourPriceStore,
+				priceBandService:   &testPriceBandService{},
+				trendFilterService: &testTrendFilterService{},
+			}
+			got1 := service.Leveling(strategy)
+			if got1 != nil ||
+				!reflect.DeepEqual(test.wantEntryLimitHistory, orderService.EntryLimitHistory) ||
+				!reflect.DeepEqual(test.wantExitLimitHistory, orderService.ExitLimitHistory) ||
+				!reflect.DeepEqual(test.wantExitStopHistory, orderService.ExitStopHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v\n", t.Name(),
+					nil, test.wantEntryLimitHistory, test.wantExitLimitHistory, test.wantExitStopHistory,
+					got1, orderService.EntryLimitHistory, orderService.ExitLimitHistory, orderService.ExitStopHistory)
 			}
 		})
 	}
@@ -764,15 +1534,21 @@ func Test_newGridService(t *testing.T) {
 	orderService := &testOrderService{}
 	strategyStore := &testStrategyStore{}
 	fourPriceStore := &testFourPriceStore{}
+	positionStore := &testPositionStore{}
+	priceBandService := &testPriceBandService{}
+	trendFilterService := &testTrendFilterService{}
 	want1 := &gridService{
-		clock:          clock,
-		tick:           tick,
-		kabusAPI:       kabusAPI,
-		orderService:   orderService,
-		strategyStore:  strategyStore,
-		fourPriceStore: fourPriceStore,
-	}
-	got1 := newGridService(clock, tick, kabusAPI, orderService, strategyStore, fourPriceStore)
+		clock:              clock,
+		tick:               tick,
+		kabusAPI:           kabusAPI,
+		orderService:       orderService,
+		strategyStore:      strategyStore,
+		fourPriceStore:     fourPriceStore,
+		positionStore:      positionStore,
+		priceBandService:   priceBandService,
+		trendFilterService: trendFilterService,
+	}
+	got1 := newGridService(clock, tick, kabusAPI, orderService, strategyStore, fourPriceStore, positionStore, priceBandService, trendFilterService)
 	if !reflect.DeepEqual(want1, got1) {
 		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
 	}
@@ -959,6 +1735,43 @@ func Test_gridService_width(t *testing.T) {
 			},
 			want1: 2,
 			want2: nil},
+		{name: "DynamicGridVolatilityが有効で、四本値の取得に失敗したら基準グリッド幅が返される",
+			clock:          &testClock{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange2: ErrUnknown},
+			arg1: &Strategy{
+				GridStrategy: GridStrategy{
+					BaseWidth:             4,
+					DynamicGridVolatility: DynamicGridVolatility{Valid: true, Days: 5, Rate: 0.5, Rounding: RoundingRound, Operation: OperationOverwrite},
+				},
+			},
+			want1: 4,
+			want2: nil},
+		{name: "DynamicGridVolatilityが有効で、前日の終値がある四本値がなければ基準グリッド幅が返される",
+			clock:          &testClock{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{{High: 1030, Low: 1000, Close: 1020}}},
+			arg1: &Strategy{
+				GridStrategy: GridStrategy{
+					BaseWidth:             4,
+					DynamicGridVolatility: DynamicGridVolatility{Valid: true, Days: 5, Rate: 0.5, Rounding: RoundingRound, Operation: OperationOverwrite},
+				},
+			},
+			want1: 4,
+			want2: nil},
+		{name: "DynamicGridVolatilityが有効で、四本値が取得できればATRからグリッド幅を計算できる",
+			clock: &testClock{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{
+				{High: 1030, Low: 1000, Close: 1020},
+				{High: 1010, Low: 990, Close: 1000},
+				{High: 1005, Low: 995, Close: 1000}}},
+			arg1: &Strategy{
+				TickGroup: TickGroupOther,
+				GridStrategy: GridStrategy{
+					BaseWidth:             4,
+					DynamicGridVolatility: DynamicGridVolatility{Valid: true, Days: 2, Rate: 0.2, Rounding: RoundingRound, Operation: OperationOverwrite},
+				},
+			},
+			want1: 5,
+			want2: nil},
 	}
 
 	for _, test := range tests {
@@ -973,3 +1786,497 @@ func Test_gridService_width(t *testing.T) {
 		})
 	}
 }
+
+func Test_gridService_volatility(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		arg1  *Strategy
+		arg2  []*FourPrice
+		want1 float64
+		want2 bool
+	}{
+		{name: "四本値がなければ計算できない",
+			arg1:  &Strategy{TickGroup: TickGroupOther},
+			arg2:  []*FourPrice{},
+			want1: 0,
+			want2: false},
+		{name: "四本値が1日分だけなら計算できない",
+			arg1:  &Strategy{TickGroup: TickGroupOther},
+			arg2:  []*FourPrice{{High: 1030, Low: 1000, Close: 1020}},
+			want1: 0,
+			want2: false},
+		{name: "最新の終値が0なら計算できない",
+			arg1:  &Strategy{TickGroup: TickGroupOther},
+			arg2:  []*FourPrice{{}, {High: 1010, Low: 990, Close: 1000}},
+			want1: 0,
+			want2: false},
+		{name: "未指定ならATRを計算する",
+			arg1: &Strategy{TickGroup: TickGroupOther},
+			arg2: []*FourPrice{
+				{High: 1030, Low: 1000, Close: 1020},
+				{High: 1010, Low: 990, Close: 1000},
+				{High: 1005, Low: 995, Close: 1000}},
+			want1: 25,
+			want2: true},
+		{name: "真の値幅は前日の終値を含めた値幅で計算する",
+			arg1: &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{DynamicGridVolatility: DynamicGridVolatility{Type: VolatilityTypeATR}}},
+			arg2: []*FourPrice{
+				{High: 1030, Low: 1010, Close: 1020},
+				{High: 1000, Low: 980, Close: 990}},
+			want1: 40,
+			want2: true},
+		{name: "ATRは最新の終値の呼値単位でtick数にする",
+			arg1: &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{DynamicGridVolatility: DynamicGridVolatility{Type: VolatilityTypeATR}}},
+			arg2: []*FourPrice{
+				{High: 3020, Low: 2990, Close: 3010},
+				{High: 3000, Low: 2990, Close: 3000}},
+			want1: 6,
+			want2: true},
+		{name: "atr_ticksなら日ごとに真の値幅をtick数にしてから平均する",
+			arg1: &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{DynamicGridVolatility: DynamicGridVolatility{Type: VolatilityTypeATRTicks}}},
+			arg2: []*FourPrice{
+				{High: 3020, Low: 2990, Close: 3010},
+				{High: 3000, Low: 2990, Close: 3000}},
+			want1: 14,
+			want2: true},
+		{name: "stddevなら終値の騰落率の標準偏差を最新の終値にかけてtick数にする",
+			arg1: &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{DynamicGridVolatility: DynamicGridVolatility{Type: VolatilityTypeStdDev}}},
+			arg2: []*FourPrice{
+				{High: 1500, Low: 1000, Close: 1500},
+				{High: 1000, Low: 1000, Close: 1000},
+				{High: 1000, Low: 1000, Close: 1000}},
+			want1: 375,
+			want2: true},
+		{name: "外れ値を除く日数が指定されていれば、大きい方と小さい方から除いて平均する",
+			arg1: &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{DynamicGridVolatility: DynamicGridVolatility{Type: VolatilityTypeATR, Trim: 1}}},
+			arg2: []*FourPrice{
+				{High: 1200, Low: 1000, Close: 1000},
+				{High: 1040, Low: 1000, Close: 1000},
+				{High: 1030, Low: 1000, Close: 1000},
+				{High: 1020, Low: 1000, Close: 1000},
+				{High: 1010, Low: 1000, Close: 1000},
+				{High: 1000, Low: 1000, Close: 1000}},
+			want1: 30,
+			want2: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &gridService{tick: &tick{}}
+			got1, got2 := service.volatility(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) || !reflect.DeepEqual(test.want2, got2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_gridService_levelQuantity(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                        string
+		orderService                *testOrderService
+		arg1                        *Strategy
+		arg2                        float64
+		arg3                        int
+		arg4                        float64
+		arg5                        float64
+		arg6                        bool
+		want1                       float64
+		want2                       error
+		wantMaxEntryQuantityHistory []interface{}
+	}{
+		{name: "数量の決め方が未指定なら、乗っている数量を引いた数量を返し、現金での上限は確認しない",
+			orderService: &testOrderService{MaxEntryQuantity1: 1},
+			arg1:         &Strategy{Code: "strategy-code-001", TradingUnit: 100},
+			arg2:         2098,
+			arg3:         1,
+			arg4:         400,
+			arg5:         100,
+			arg6:         true,
+			want1:        300},
+		{name: "数量の決め方に応じた数量から乗っている数量を引いた数量を返す",
+			orderService:                &testOrderService{MaxEntryQuantity1: 10_000},
+			arg1:                        &Strategy{Code: "strategy-code-001", TradingUnit: 100, GridStrategy: GridStrategy{QuantityProfile: QuantityProfile{Type: QuantityProfileTypeLinear, Step: 100}}},
+			arg2:                        2096,
+			arg3:                        1,
+			arg4:                        100,
+			arg5:                        100,
+			arg6:                        true,
+			want1:                       100,
+			wantMaxEntryQuantityHistory: []interface{}{"strategy-code-001", 2096.0}},
+		{name: "すでに必要な数量が乗っていれば、現金での上限は確認しない",
+			orderService: &testOrderService{MaxEntryQuantity1: 10_000},
+			arg1:         &Strategy{Code: "strategy-code-001", TradingUnit: 100, GridStrategy: GridStrategy{QuantityProfile: QuantityProfile{Type: QuantityProfileTypeLinear, Step: 100}}},
+			arg2:         2096,
+			arg3:         1,
+			arg4:         100,
+			arg5:         200,
+			arg6:         true,
+			want1:        0},
+		{name: "エグジット側のグリッドなら、現金での上限は確認しない",
+			orderService: &testOrderService{MaxEntryQuantity1: 0},
+			arg1:         &Strategy{Code: "strategy-code-001", TradingUnit: 100, GridStrategy: GridStrategy{QuantityProfile: QuantityProfile{Type: QuantityProfileTypeGeometric, Rate: 2}}},
+			arg2:         2104,
+			arg3:         2,
+			arg4:         100,
+			arg5:         0,
+			arg6:         false,
+			want1:        400},
+		{name: "エントリー側のグリッドで、現金で買える数量が少なければ買える数量までにする",
+			orderService:                &testOrderService{MaxEntryQuantity1: 200},
+			arg1:                        &Strategy{Code: "strategy-code-001", TradingUnit: 100, GridStrategy: GridStrategy{QuantityProfile: QuantityProfile{Type: QuantityProfileTypeGeometric, Rate: 2}}},
+			arg2:                        2096,
+			arg3:                        2,
+			arg4:                        100,
+			arg5:                        0,
+			arg6:                        true,
+			want1:                       200,
+			wantMaxEntryQuantityHistory: []interface{}{"strategy-code-001", 2096.0}},
+		{name: "現金で買える数量の取得に失敗したらエラー",
+			orderService:                &testOrderService{MaxEntryQuantity2: ErrUnknown},
+			arg1:                        &Strategy{Code: "strategy-code-001", TradingUnit: 100, GridStrategy: GridStrategy{QuantityProfile: QuantityProfile{Type: QuantityProfileTypeConstant}}},
+			arg2:                        2096,
+			arg3:                        2,
+			arg4:                        100,
+			arg5:                        0,
+			arg6:                        true,
+			want1:                       0,
+			want2:                       ErrUnknown,
+			wantMaxEntryQuantityHistory: []interface{}{"strategy-code-001", 2096.0}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &gridService{orderService: test.orderService}
+			got1, got2 := service.levelQuantity(test.arg1, test.arg2, test.arg3, test.arg4, test.arg5, test.arg6)
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) || !reflect.DeepEqual(test.wantMaxEntryQuantityHistory, test.orderService.MaxEntryQuantityHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(), test.want1, test.want2, test.wantMaxEntryQuantityHistory, got1, got2, test.orderService.MaxEntryQuantityHistory)
+			}
+		})
+	}
+}
+
+func Test_gridService_gridPrices(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		arg1  *Strategy
+		arg2  float64
+		arg3  int
+		arg4  int
+		arg5  int
+		want1 []float64
+	}{
+		{name: "グリッド数が0なら空のリストを返す",
+			arg1:  &Strategy{TickGroup: TickGroupOther},
+			arg2:  2100,
+			arg3:  2,
+			arg4:  0,
+			arg5:  1,
+			want1: []float64{}},
+		{name: "間隔が未指定ならティック数の間隔で上にグリッドを並べる",
+			arg1:  &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{Spacing: GridSpacingUnspecified, WidthRate: 0.01}},
+			arg2:  2100,
+			arg3:  2,
+			arg4:  3,
+			arg5:  1,
+			want1: []float64{2102, 2104, 2106}},
+		{name: "ティック数の間隔ならグリッド幅のティック数ずつ離して下にグリッドを並べる",
+			arg1:  &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{Spacing: GridSpacingTick}},
+			arg2:  2100,
+			arg3:  2,
+			arg4:  3,
+			arg5:  -1,
+			want1: []float64{2098, 2096, 2094}},
+		{name: "幾何級数の間隔なら1つ内側のグリッドから一定の割合ずつ上に離し、呼値単位に丸めてグリッドを並べる",
+			arg1:  &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{Spacing: GridSpacingGeometric, BaseWidth: 2, WidthRate: 0.01}},
+			arg2:  2100,
+			arg3:  2,
+			arg4:  3,
+			arg5:  1,
+			want1: []float64{2121, 2142, 2164}},
+		{name: "幾何級数の間隔なら1つ内側のグリッドから一定の割合ずつ下に離し、呼値単位に丸めてグリッドを並べる",
+			arg1:  &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{Spacing: GridSpacingGeometric, BaseWidth: 2, WidthRate: 0.01}},
+			arg2:  2100,
+			arg3:  2,
+			arg4:  3,
+			arg5:  -1,
+			want1: []float64{2079, 2058, 2038}},
+		{name: "幾何級数の間隔で動的なグリッド幅がBaseWidthより広ければ、割合も同じ比で広げる",
+			arg1:  &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{Spacing: GridSpacingGeometric, BaseWidth: 2, WidthRate: 0.01}},
+			arg2:  2100,
+			arg3:  4,
+			arg4:  3,
+			arg5:  1,
+			want1: []float64{2142, 2185, 2229}},
+		{name: "幾何級数の間隔で価格帯をまたぐなら、価格帯ごとの呼値単位に丸める",
+			arg1:  &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{Spacing: GridSpacingGeometric, BaseWidth: 2, WidthRate: 0.02}},
+			arg2:  2900,
+			arg3:  2,
+			arg4:  3,
+			arg5:  1,
+			want1: []float64{2958, 3015, 3080}},
+		{name: "幾何級数の間隔で丸めた結果が1つ内側のグリッドと重なるなら、1ティック上に置く",
+			arg1:  &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{Spacing: GridSpacingGeometric, BaseWidth: 2, WidthRate: 0.001}},
+			arg2:  100,
+			arg3:  2,
+			arg4:  3,
+			arg5:  1,
+			want1: []float64{101, 102, 103}},
+		{name: "幾何級数の間隔で丸めた結果が1つ内側のグリッドと重なるなら、1ティック下に置く",
+			arg1:  &Strategy{TickGroup: TickGroupOther, GridStrategy: GridStrategy{Spacing: GridSpacingGeometric, BaseWidth: 2, WidthRate: 0.001}},
+			arg2:  100,
+			arg3:  2,
+			arg4:  3,
+			arg5:  -1,
+			want1: []float64{99, 98, 97}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &gridService{tick: &tick{}}
+			got1 := service.gridPrices(test.arg1, test.arg2, test.arg3, test.arg4, test.arg5)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_gridService_sideGrids(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		positionStore *testPositionStore
+		arg1          *Strategy
+		arg2          int
+		want1         GridSideStrategy
+		want2         GridSideStrategy
+		want3         error
+	}{
+		{name: "上下の設定が無効なら共通の設定を上下に使う",
+			positionStore: &testPositionStore{},
+			arg1:          &Strategy{GridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4}},
+			arg2:          2,
+			want1:         GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 3, Quantity: 4},
+			want2:         GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 3, Quantity: 4}},
+		{name: "上下の設定が有効なら、それぞれの設定を使う",
+			positionStore: &testPositionStore{},
+			arg1: &Strategy{GridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4,
+				Upper: GridSideStrategy{Valid: true, Width: 4, NumberOfGrids: 2, Quantity: 8},
+				Lower: GridSideStrategy{Valid: true, Width: 1, NumberOfGrids: 5, Quantity: 2}}},
+			arg2:  2,
+			want1: GridSideStrategy{Valid: true, Width: 4, NumberOfGrids: 2, Quantity: 8},
+			want2: GridSideStrategy{Valid: true, Width: 1, NumberOfGrids: 5, Quantity: 2}},
+		{name: "動的なグリッド幅はBaseWidthとの比で上下のグリッド幅に反映する",
+			positionStore: &testPositionStore{},
+			arg1: &Strategy{GridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4,
+				Upper: GridSideStrategy{Valid: true, Width: 4, NumberOfGrids: 2, Quantity: 8}}},
+			arg2:  3,
+			want1: GridSideStrategy{Valid: true, Width: 6, NumberOfGrids: 2, Quantity: 8},
+			want2: GridSideStrategy{Valid: true, Width: 3, NumberOfGrids: 3, Quantity: 4}},
+		{name: "グリッドを置く側のグリッド幅が0ならエラー",
+			positionStore: &testPositionStore{},
+			arg1: &Strategy{GridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4,
+				Lower: GridSideStrategy{Valid: true, Width: 0, NumberOfGrids: 2, Quantity: 4}}},
+			arg2:  2,
+			want1: GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 3, Quantity: 4},
+			want2: GridSideStrategy{Valid: true, Width: 0, NumberOfGrids: 2, Quantity: 4},
+			want3: ErrZeroGridWidth},
+		{name: "グリッドを置かない側のグリッド幅が0でもエラーにしない",
+			positionStore: &testPositionStore{},
+			arg1: &Strategy{GridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4,
+				Lower: GridSideStrategy{Valid: true, Width: 0, NumberOfGrids: 0, Quantity: 0}}},
+			arg2:  2,
+			want1: GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 3, Quantity: 4},
+			want2: GridSideStrategy{Valid: true, Width: 0, NumberOfGrids: 0, Quantity: 0}},
+		{name: "保有数量に応じた設定が有効で、ポジションの取得に失敗したらエラー",
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode2: ErrUnknown},
+			arg1: &Strategy{EntrySide: SideBuy, GridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4,
+				InventorySkew: InventorySkew{Valid: true, StepQuantity: 4, ReduceGrids: 1}}},
+			arg2:  2,
+			want1: GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 3, Quantity: 4},
+			want2: GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 3, Quantity: 4},
+			want3: ErrUnknown},
+		{name: "保有数量に応じた設定が有効で買いエントリーなら、保有数量に応じて下のグリッドを減らす",
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{OwnedQuantity: 4}, {OwnedQuantity: 4}}},
+			arg1: &Strategy{EntrySide: SideBuy, GridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4,
+				InventorySkew: InventorySkew{Valid: true, StepQuantity: 4, ReduceGrids: 1}}},
+			arg2:  2,
+			want1: GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 3, Quantity: 4},
+			want2: GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 1, Quantity: 4}},
+		{name: "保有数量に応じた設定が有効で売りエントリーなら、保有数量に応じて上のグリッドを減らす",
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{OwnedQuantity: 4}}},
+			arg1: &Strategy{EntrySide: SideSell, GridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4,
+				InventorySkew: InventorySkew{Valid: true, StepQuantity: 4, ReduceGrids: 1}}},
+			arg2:  2,
+			want1: GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 2, Quantity: 4},
+			want2: GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 3, Quantity: 4}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &gridService{positionStore: test.positionStore}
+			got1, got2, got3 := service.sideGrids(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) || !reflect.DeepEqual(test.want2, got2) || !errors.Is(got3, test.want3) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(), test.want1, test.want2, test.want3, got1, got2, got3)
+			}
+		})
+	}
+}
+
+func Test_gridService_protectiveStopPrices(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		arg1  *Strategy
+		arg2  float64
+		arg3  PriceLimit
+		want1 float64
+		want2 float64
+	}{
+		{name: "買いエントリーなら最も下のグリッドより指定ティック下に発火価格を置く",
+			arg1: &Strategy{
+				EntrySide:              SideBuy,
+				GridStrategy:           GridStrategy{NumberOfGrids: 3},
+				ProtectiveStopStrategy: ProtectiveStopStrategy{ExecutionType: ExecutionTypeStopMarket, Width: 2}},
+			arg2:  2094,
+			want1: 2092,
+			want2: 0},
+		{name: "売りエントリーなら最も上のグリッドより指定ティック上に発火価格を置く",
+			arg1: &Strategy{
+				EntrySide:              SideSell,
+				GridStrategy:           GridStrategy{NumberOfGrids: 3},
+				ProtectiveStopStrategy: ProtectiveStopStrategy{ExecutionType: ExecutionTypeStopMarket, Width: 2}},
+			arg2:  2106,
+			want1: 2108,
+			want2: 0},
+		{name: "逆指値(指値)なら発火価格から不利な方向に指値幅だけずらした指値価格を返す",
+			arg1: &Strategy{
+				EntrySide:              SideBuy,
+				GridStrategy:           GridStrategy{NumberOfGrids: 3},
+				ProtectiveStopStrategy: ProtectiveStopStrategy{ExecutionType: ExecutionTypeStopLimit, Width: 2, LimitWidth: 5}},
+			arg2:  2094,
+			want1: 2092,
+			want2: 2087},
+		{name: "発火価格が値幅下限より下なら値幅下限に寄せる",
+			arg1: &Strategy{
+				EntrySide:              SideBuy,
+				GridStrategy:           GridStrategy{NumberOfGrids: 3},
+				ProtectiveStopStrategy: ProtectiveStopStrategy{ExecutionType: ExecutionTypeStopMarket, Width: 2}},
+			arg2:  2094,
+			arg3:  PriceLimit{Lower: 2093, Upper: 2893},
+			want1: 2093,
+			want2: 0},
+		{name: "発火価格が値幅上限より上なら値幅上限に寄せる",
+			arg1: &Strategy{
+				EntrySide:              SideSell,
+				GridStrategy:           GridStrategy{NumberOfGrids: 3},
+				ProtectiveStopStrategy: ProtectiveStopStrategy{ExecutionType: ExecutionTypeStopMarket, Width: 2}},
+			arg2:  2106,
+			arg3:  PriceLimit{Lower: 1307, Upper: 2107},
+			want1: 2107,
+			want2: 0},
+		{name: "逆指値(指値)の指値価格が値幅制限の外なら値幅制限の端に寄せる",
+			arg1: &Strategy{
+				EntrySide:              SideBuy,
+				GridStrategy:           GridStrategy{NumberOfGrids: 3},
+				ProtectiveStopStrategy: ProtectiveStopStrategy{ExecutionType: ExecutionTypeStopLimit, Width: 2, LimitWidth: 5}},
+			arg2:  2094,
+			arg3:  PriceLimit{Lower: 2090, Upper: 2890},
+			want1: 2092,
+			want2: 2090},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &gridService{tick: &tick{}}
+			got1, got2 := service.protectiveStopPrices(test.arg1, test.arg2, test.arg3)
+			if !reflect.DeepEqual(test.want1, got1) || !reflect.DeepEqual(test.want2, got2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_gridService_cancelProtectiveStops(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name              string
+		orderService      *testOrderService
+		arg1              *Strategy
+		arg2              []*Order
+		arg3              float64
+		arg4              float64
+		want1             bool
+		want2             error
+		wantCancelHistory []interface{}
+	}{
+		{name: "逆指値の注文がなければfalse",
+			orderService: &testOrderService{},
+			arg1:         &Strategy{Code: "strategy-code-001", ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopMarket}},
+			arg2:         []*Order{{Code: "order-code-001", ExecutionType: ExecutionTypeLimit, Price: 2100}},
+			arg3:         2090,
+			want1:        false},
+		{name: "価格の合う逆指値の注文があれば取り消さずにtrue",
+			orderService: &testOrderService{},
+			arg1:         &Strategy{Code: "strategy-code-001", ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopLimit}},
+			arg2:         []*Order{{Code: "order-code-001", ExecutionType: ExecutionTypeStopLimit, TriggerPrice: 2090, Price: 2085}},
+			arg3:         2090,
+			arg4:         2085,
+			want1:        true},
+		{name: "発火後の指値価格が合わなければ取り消してtrue",
+			orderService:      &testOrderService{},
+			arg1:              &Strategy{Code: "strategy-code-001", ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopLimit}},
+			arg2:              []*Order{{Code: "order-code-001", ExecutionType: ExecutionTypeStopLimit, TriggerPrice: 2090, Price: 2080}},
+			arg3:              2090,
+			arg4:              2085,
+			want1:             true,
+			wantCancelHistory: []interface{}{&Strategy{Code: "strategy-code-001", ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopLimit}}, "order-code-001"}},
+		{name: "保護用の逆指値戦略が実行できなければ価格が合っていても取り消す",
+			orderService:      &testOrderService{},
+			arg1:              &Strategy{Code: "strategy-code-001", ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: false, ExecutionType: ExecutionTypeStopMarket}},
+			arg2:              []*Order{{Code: "order-code-001", ExecutionType: ExecutionTypeStopMarket, TriggerPrice: 2090}},
+			arg3:              2090,
+			want1:             true,
+			wantCancelHistory: []interface{}{&Strategy{Code: "strategy-code-001", ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: false, ExecutionType: ExecutionTypeStopMarket}}, "order-code-001"}},
+		{name: "送信中の逆指値の注文は取り消さない",
+			orderService: &testOrderService{},
+			arg1:         &Strategy{Code: "strategy-code-001", ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopMarket}},
+			arg2:         []*Order{{Code: "order-code-001", Status: OrderStatusPending, ExecutionType: ExecutionTypeStopMarket, TriggerPrice: 2080}},
+			arg3:         2090,
+			want1:        true},
+		{name: "取消に失敗したらエラー",
+			orderService:      &testOrderService{Cancel1: ErrUnknown},
+			arg1:              &Strategy{Code: "strategy-code-001", ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopMarket}},
+			arg2:              []*Order{{Code: "order-code-001", ExecutionType: ExecutionTypeStopMarket, TriggerPrice: 2080}},
+			arg3:              2090,
+			want1:             true,
+			want2:             ErrUnknown,
+			wantCancelHistory: []interface{}{&Strategy{Code: "strategy-code-001", ProtectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopMarket}}, "order-code-001"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &gridService{orderService: test.orderService}
+			got1, got2 := service.cancelProtectiveStops(test.arg1, test.arg2, test.arg3, test.arg4)
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) || !reflect.DeepEqual(test.wantCancelHistory, test.orderService.CancelHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(), test.want1, test.want2, test.wantCancelHistory, got1, got2, test.orderService.CancelHistory)
+			}
+		})
+	}
+}
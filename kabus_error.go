@@ -0,0 +1,101 @@
+package gridon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"gitlab.com/tsuchinaga/kabus-grpc-server/kabuspb"
+)
+
+// kabusErrorCodes - kabuステーションAPIのエラーコードと分類したエラーの対応
+var kabusErrorCodes = map[int32]error{
+	41:      ErrNotCancelable,     // 指定した注文に対してアクションが起こせない
+	42:      ErrNotCancelable,     // 指定した注文に対してアクションが起こせない
+	43:      ErrNotCancelable,     // 取消できない注文
+	44:      ErrNotCancelable,     // 指定した注文に対してアクションが起こせない
+	45:      ErrNotCancelable,     // 指定した注文に対してアクションが起こせない
+	47:      ErrNotCancelable,     // 取消の必要がない注文
+	4001007: ErrAuthExpired,       // ログイン認証エラー
+	4001009: ErrAuthExpired,       // APIキー不一致
+	4001017: ErrKabusTransient,    // 流量制限
+	100368:  ErrInsufficientFunds, // 買付余力不足
+	100378:  ErrInsufficientFunds, // 信用新規建余力不足
+	100220:  ErrSymbolHalted,      // 売買停止中の銘柄
+	100317:  ErrDuplicateOrder,    // 二重発注
+}
+
+// KabusError - 分類したkabuステーションAPIのエラー
+// errors.Isで分類したエラーと、errors.Unwrapで元のgRPCのエラーと比較できる
+type KabusError struct {
+	Err        error  // 分類したエラー
+	StatusCode int    // HTTPステータスコード
+	Code       int    // kabuステーションAPIのエラーコード
+	Message    string // エラーメッセージ
+	cause      error
+}
+
+func (e *KabusError) Error() string {
+	return fmt.Sprintf("%s(status code = %d, code = %d, message = %s): %s", e.Err, e.StatusCode, e.Code, e.Message, e.cause)
+}
+
+func (e *KabusError) Is(target error) bool {
+	return errors.Is(e.Err, target)
+}
+
+func (e *KabusError) Unwrap() error {
+	return e.cause
+}
+
+// kabusErrorFrom - kabusのエラーを分類する
+// 分類できないエラーはそのまま返し、分類済みのエラーは分類し直さない
+func kabusErrorFrom(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var kabusErr *KabusError
+	if errors.As(err, &kabusErr) {
+		return err
+	}
+
+	// 期限切れがgRPCのステータスになっていなくても、届いたかわからない一時的なエラーとして扱う
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &KabusError{Err: ErrKabusTransient, Message: err.Error(), cause: err}
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	// 詳細にkabuステーションAPIのエラーがあれば、エラーコードで分類する
+	for _, d := range st.Details() {
+		re, ok := d.(*kabuspb.RequestError)
+		if !ok {
+			continue
+		}
+
+		classified := kabusErrorCodes[re.Code]
+		switch {
+		case re.StatusCode == 401:
+			classified = ErrAuthExpired
+		case re.StatusCode == 429:
+			classified = ErrKabusTransient
+		}
+		if classified == nil {
+			return err
+		}
+		return &KabusError{Err: classified, StatusCode: int(re.StatusCode), Code: int(re.Code), Message: re.Message, cause: err}
+	}
+
+	// 詳細がなければ、kabusやkabuステーションに届かなかった一時的なエラーかをgRPCのコードで判断する
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return &KabusError{Err: ErrKabusTransient, Message: st.Message(), cause: err}
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package gridon
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"gitlab.com/tsuchinaga/kabus-grpc-server/kabuspb"
+)
+
+func Test_kabusErrorFrom(t *testing.T) {
+	t.Parallel()
+
+	withDetails := func(c codes.Code, details ...*kabuspb.RequestError) error {
+		st := status.New(c, "status")
+		for _, d := range details {
+			st, _ = st.WithDetails(d)
+		}
+		return st.Err()
+	}
+	notRequestErrSt, _ := status.New(codes.Internal, "not request status").WithDetails(&kabuspb.Order{})
+	classified := &KabusError{Err: ErrAuthExpired, StatusCode: 401, cause: ErrUnknown}
+
+	tests := []struct {
+		name           string
+		arg1           error
+		want1          error
+		wantClassified bool
+		wantCode       int
+	}{
+		{name: "nilならnil", arg1: nil, want1: nil},
+		{name: "gRPCのエラーでなければそのまま返す", arg1: ErrUnknown, want1: ErrUnknown},
+		{name: "分類済みのエラーはそのまま返す", arg1: classified, want1: ErrAuthExpired, wantClassified: true},
+		{name: "詳細がなくUnavailableなら一時的なエラー", arg1: withDetails(codes.Unavailable), want1: ErrKabusTransient, wantClassified: true},
+		{name: "詳細がなくDeadlineExceededなら一時的なエラー", arg1: withDetails(codes.DeadlineExceeded), want1: ErrKabusTransient, wantClassified: true},
+		{name: "contextの期限切れなら一時的なエラー", arg1: context.DeadlineExceeded, want1: ErrKabusTransient, wantClassified: true},
+		{name: "詳細がなくInternalなら分類しない", arg1: withDetails(codes.Internal), want1: nil},
+		{name: "詳細がkabuspb.RequestErrorでなければ分類しない", arg1: notRequestErrSt.Err(), want1: nil},
+		{name: "取り消せない注文のエラーコードなら取消不可のエラー",
+			arg1: withDetails(codes.Internal, &kabuspb.RequestError{StatusCode: 400, Code: 43}), want1: ErrNotCancelable, wantClassified: true, wantCode: 43},
+		{name: "余力不足のエラーコードなら余力不足のエラー",
+			arg1: withDetails(codes.Internal, &kabuspb.RequestError{StatusCode: 500, Code: 100368}), want1: ErrInsufficientFunds, wantClassified: true, wantCode: 100368},
+		{name: "売買停止のエラーコードなら売買停止のエラー",
+			arg1: withDetails(codes.Internal, &kabuspb.RequestError{StatusCode: 500, Code: 100220}), want1: ErrSymbolHalted, wantClassified: true, wantCode: 100220},
+		{name: "二重発注のエラーコードなら二重発注のエラー",
+			arg1: withDetails(codes.Internal, &kabuspb.RequestError{StatusCode: 500, Code: 100317}), want1: ErrDuplicateOrder, wantClassified: true, wantCode: 100317},
+		{name: "HTTPステータスが401なら認証切れのエラー",
+			arg1: withDetails(codes.Internal, &kabuspb.RequestError{StatusCode: 401, Code: 4001013}), want1: ErrAuthExpired, wantClassified: true, wantCode: 4001013},
+		{name: "HTTPステータスが429なら一時的なエラー",
+			arg1: withDetails(codes.Internal, &kabuspb.RequestError{StatusCode: 429, Code: 4001006}), want1: ErrKabusTransient, wantClassified: true, wantCode: 4001006},
+		{name: "対応のないエラーコードなら分類しない",
+			arg1: withDetails(codes.Unavailable, &kabuspb.RequestError{StatusCode: 400, Code: 2}), want1: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := kabusErrorFrom(test.arg1)
+			var kabusErr *KabusError
+			gotClassified := errors.As(got1, &kabusErr)
+			var gotCode int
+			if gotClassified {
+				gotCode = kabusErr.Code
+			}
+
+			// 分類しないエラーは引数のエラーがそのまま返される
+			want1 := test.want1
+			if !test.wantClassified {
+				want1 = test.arg1
+			}
+			if !errors.Is(got1, want1) || !errors.Is(got1, test.arg1) ||
+				!reflect.DeepEqual(test.wantClassified, gotClassified) ||
+				!reflect.DeepEqual(test.wantCode, gotCode) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(), want1, test.wantClassified, test.wantCode, got1, gotClassified, gotCode)
+			}
+		})
+	}
+}
+
+func Test_KabusError_Is(t *testing.T) {
+	t.Parallel()
+	cause := status.New(codes.Unavailable, "unavailable").Err()
+	err := &KabusError{Err: ErrKabusTransient, cause: cause}
+	want1 := []bool{true, true, false}
+	got1 := []bool{errors.Is(err, ErrKabusTransient), errors.Unwrap(err) == cause, errors.Is(err, ErrAuthExpired)}
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
@@ -2,6 +2,7 @@ package gridon
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -9,8 +10,8 @@ import (
 	"gitlab.com/tsuchinaga/kabus-grpc-server/kabuspb"
 )
 
-func newKabusAPI(kabucom kabuspb.KabusServiceClient) IKabusAPI {
-	return &kabusAPI{kabucom: kabucom}
+func newKabusAPI(kabucom kabuspb.KabusServiceClient, rateLimiter IRateLimiter, retryConfig RetryConfig) IKabusAPI {
+	return &kabusAPI{kabucom: kabucom, rateLimiter: rateLimiter, retryConfig: retryConfig}
 }
 
 // IKabusAPI - kabuステーションAPIのインターフェース
@@ -20,11 +21,58 @@ type IKabusAPI interface {
 	CancelOrder(orderPassword string, orderCode string) (OrderResult, error)
 	SendOrder(strategy *Strategy, order *Order) (OrderResult, error)
 	GetFourPrice(symbolCode string, exchange Exchange) (*FourPrice, error)
+	GetPositions(product Product, symbolCode string) ([]SecurityPosition, error)
 }
 
 // kabusAPI - kabuステーションAPI
 type kabusAPI struct {
-	kabucom kabuspb.KabusServiceClient
+	kabucom     kabuspb.KabusServiceClient
+	rateLimiter IRateLimiter
+	retryConfig RetryConfig
+}
+
+// wait - 流量制限に従ってリクエストを出してよくなるまで待つ
+func (k *kabusAPI) wait(category RequestCategory, priority RequestPriority) {
+	if k.rateLimiter == nil {
+		return
+	}
+	k.rateLimiter.Wait(category, priority)
+}
+
+// retry - 参照系のリクエストを、一時的なエラーなら間隔を空けて再試行する
+// 1回ごとに流量制限に従い、設定された期限のcontextを渡す
+func (k *kabusAPI) retry(fn func(ctx context.Context) error) error {
+	interval := k.retryConfig.InitialInterval
+	for i := 0; ; i++ {
+		k.wait(RequestCategoryInfo, RequestPriorityNormal)
+		err := k.call(fn)
+		if err == nil || i >= k.retryConfig.MaxRetries || !errors.Is(err, ErrKabusTransient) {
+			return err
+		}
+
+		time.Sleep(interval)
+		interval = k.retryConfig.NextInterval(interval)
+	}
+}
+
+// call - 設定された期限のcontextでリクエストを出し、エラーを分類する
+func (k *kabusAPI) call(fn func(ctx context.Context) error) error {
+	ctx := context.Background()
+	if k.retryConfig.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, k.retryConfig.Timeout)
+		defer cancel()
+	}
+	return kabusErrorFrom(fn(ctx))
+}
+
+// sendOrderPriority - 注文の送信の優先度
+// エグジットは建玉を閉じる注文なので、新規のエントリーより先に出す
+func (k *kabusAPI) sendOrderPriority(order *Order) RequestPriority {
+	if order.TradeType == TradeTypeExit {
+		return RequestPriorityHigh
+	}
+	return RequestPriorityLow
 }
 
 func (k *kabusAPI) exchangeTo(exchange Exchange) kabuspb.Exchange {
@@ -177,15 +225,23 @@ func (k *kabusAPI) securityOrderFrom(product kabuspb.Product, order *kabuspb.Ord
 		}
 	}
 
+	// 発火前の逆指値注文は処理済みの詳細がなく状態が決まらないため、発注待機中なら注文中として扱う
+	status := k.orderStatusFrom(lastRecordType, order.OrderQuantity, order.CumulativeQuantity)
+	waitingStop := status == OrderStatusUnspecified && order.OrderState == kabuspb.OrderState_ORDER_STATE_WAIT
+	if waitingStop {
+		status = OrderStatusInOrder
+	}
+
 	return SecurityOrder{
 		Code:             order.Id,
-		Status:           k.orderStatusFrom(lastRecordType, order.OrderQuantity, order.CumulativeQuantity),
+		Status:           status,
 		SymbolCode:       order.SymbolCode,
 		Exchange:         k.exchangeFrom(kabuspb.Exchange(order.Exchange)),
 		Product:          k.productFrom(product),
 		MarginTradeType:  k.marginTradeTypeFrom(order.MarginTradeType),
 		TradeType:        k.tradeTypeFrom(product, order.Side, order.TradeType),
 		Side:             k.sideFrom(order.Side),
+		ExecutionType:    k.executionTypeFrom(waitingStop, order.Price),
 		Price:            order.Price,
 		OrderQuantity:    order.OrderQuantity,
 		ContractQuantity: order.CumulativeQuantity,
@@ -198,14 +254,37 @@ func (k *kabusAPI) securityOrderFrom(product kabuspb.Product, order *kabuspb.Ord
 	}
 }
 
+// executionTypeFrom - kabusの注文の執行条件を判定する
+// kabusの注文一覧には逆指値の条件が含まれないため、発火前の逆指値かどうかと価格の有無で判定する
+// 発火価格は取得できないので、逆指値でも証券会社の注文の発火価格は0のままになる
+func (k *kabusAPI) executionTypeFrom(waitingStop bool, price float64) ExecutionType {
+	switch {
+	case waitingStop && price > 0:
+		return ExecutionTypeStopLimit
+	case waitingStop:
+		return ExecutionTypeStopMarket
+	case price > 0:
+		return ExecutionTypeLimit
+	}
+	return ExecutionTypeMarket
+}
+
 // GetSymbol - 銘柄情報の取得
 func (k *kabusAPI) GetSymbol(symbolCode string, exchange Exchange) (*Symbol, error) {
-	symbol, err := k.kabucom.GetSymbol(context.Background(), &kabuspb.GetSymbolRequest{SymbolCode: symbolCode, Exchange: k.exchangeTo(exchange)})
-	if err != nil {
+	var symbol *kabuspb.Symbol
+	if err := k.retry(func(ctx context.Context) error {
+		var err error
+		symbol, err = k.kabucom.GetSymbol(ctx, &kabuspb.GetSymbolRequest{SymbolCode: symbolCode, Exchange: k.exchangeTo(exchange)})
+		return err
+	}); err != nil {
 		return nil, err
 	}
-	board, err := k.kabucom.GetBoard(context.Background(), &kabuspb.GetBoardRequest{SymbolCode: symbolCode, Exchange: k.exchangeTo(exchange)})
-	if err != nil {
+	var board *kabuspb.Board
+	if err := k.retry(func(ctx context.Context) error {
+		var err error
+		board, err = k.kabucom.GetBoard(ctx, &kabuspb.GetBoardRequest{SymbolCode: symbolCode, Exchange: k.exchangeTo(exchange)})
+		return err
+	}); err != nil {
 		return nil, err
 	}
 	return &Symbol{
@@ -217,19 +296,27 @@ func (k *kabusAPI) GetSymbol(symbolCode string, exchange Exchange) (*Symbol, err
 		BidPrice:             board.BidPrice,
 		AskPrice:             board.AskPrice,
 		TickGroup:            k.priceRangeGroupFrom(symbol.PriceRangeGroup),
+		UpperLimit:           symbol.UpperLimit,
+		LowerLimit:           symbol.LowerLimit,
+		OpeningPrice:         board.OpeningPrice,
+		VWAP:                 board.Vwap,
 	}, nil
 }
 
 // GetOrders - 注文一覧の取得
 func (k *kabusAPI) GetOrders(product Product, symbolCode string, updateDateTime time.Time) ([]SecurityOrder, error) {
 	kabusProduct := k.productTo(product)
-	res, err := k.kabucom.GetOrders(context.Background(), &kabuspb.GetOrdersRequest{
-		Product:    kabusProduct,
-		SymbolCode: symbolCode,
-		UpdateTime: timestamppb.New(updateDateTime),
-		GetDetails: true,
-	})
-	if err != nil {
+	var res *kabuspb.Orders
+	if err := k.retry(func(ctx context.Context) error {
+		var err error
+		res, err = k.kabucom.GetOrders(ctx, &kabuspb.GetOrdersRequest{
+			Product:    kabusProduct,
+			SymbolCode: symbolCode,
+			UpdateTime: timestamppb.New(updateDateTime),
+			GetDetails: true,
+		})
+		return err
+	}); err != nil {
 		return nil, err
 	}
 
@@ -240,10 +327,58 @@ func (k *kabusAPI) GetOrders(product Product, symbolCode string, updateDateTime
 	return result, nil
 }
 
+// securityPositionFrom - kabusのポジションを証券会社のポジションに変換
+// kabusのポジションには商品種別がないため、信用取引区分の有無で判断する
+func (k *kabusAPI) securityPositionFrom(position *kabuspb.Position) SecurityPosition {
+	product := ProductStock
+	if position.MarginTradeType != kabuspb.MarginTradeType_MARGIN_TRADE_TYPE_UNSPECIFIED {
+		product = ProductMargin
+	}
+
+	return SecurityPosition{
+		Code:             position.ExecutionId,
+		SymbolCode:       position.SymbolCode,
+		Exchange:         k.exchangeFrom(position.Exchange),
+		Product:          product,
+		MarginTradeType:  k.marginTradeTypeFrom(position.MarginTradeType),
+		Side:             k.sideFrom(position.Side),
+		Price:            position.Price,
+		OwnedQuantity:    position.LeavesQuantity,
+		HoldQuantity:     position.HoldQuantity,
+		ContractDateTime: position.ExecutionDay.AsTime().In(time.Local),
+	}
+}
+
+// GetPositions - ポジション一覧の取得
+func (k *kabusAPI) GetPositions(product Product, symbolCode string) ([]SecurityPosition, error) {
+	var res *kabuspb.Positions
+	if err := k.retry(func(ctx context.Context) error {
+		var err error
+		res, err = k.kabucom.GetPositions(ctx, &kabuspb.GetPositionsRequest{
+			Product:    k.productTo(product),
+			SymbolCode: symbolCode,
+		})
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	result := make([]SecurityPosition, 0)
+	for _, p := range res.Positions {
+		result = append(result, k.securityPositionFrom(p))
+	}
+	return result, nil
+}
+
 // CancelOrder - 注文の取消
 func (k *kabusAPI) CancelOrder(orderPassword string, orderCode string) (OrderResult, error) {
-	res, err := k.kabucom.CancelOrder(context.Background(), &kabuspb.CancelOrderRequest{Password: orderPassword, OrderId: orderCode})
-	if err != nil {
+	k.wait(RequestCategoryOrder, RequestPriorityHigh)
+	var res *kabuspb.OrderResponse
+	if err := k.call(func(ctx context.Context) error {
+		var err error
+		res, err = k.kabucom.CancelOrder(ctx, &kabuspb.CancelOrderRequest{Password: orderPassword, OrderId: orderCode})
+		return err
+	}); err != nil {
 		return OrderResult{}, err
 	}
 	return OrderResult{
@@ -288,10 +423,139 @@ func (k *kabusAPI) orderTypeTo(executionType ExecutionType) kabuspb.StockOrderTy
 		return kabuspb.StockOrderType_STOCK_ORDER_TYPE_MOAC
 	case ExecutionTypeLimit:
 		return kabuspb.StockOrderType_STOCK_ORDER_TYPE_LO
+	case ExecutionTypeStopMarket, ExecutionTypeStopLimit:
+		return kabuspb.StockOrderType_STOCK_ORDER_TYPE_STOP
 	}
 	return kabuspb.StockOrderType_STOCK_ORDER_TYPE_UNSPECIFIED
 }
 
+// orderPriceTo - 注文価格をkabus用に変換
+// 逆指値では発火後の指値価格として逆指値条件に入れるため、注文価格は指定しない
+func (k *kabusAPI) orderPriceTo(order *Order) float64 {
+	if order.ExecutionType.IsStop() {
+		return 0
+	}
+	return order.Price
+}
+
+// underOverTo - 逆指値の発火条件をkabus用に変換
+// 買いは発火価格以上、売りは発火価格以下で発火する
+func (k *kabusAPI) underOverTo(side Side) kabuspb.UnderOver {
+	switch side {
+	case SideBuy:
+		return kabuspb.UnderOver_UNDER_OVER_OVER
+	case SideSell:
+		return kabuspb.UnderOver_UNDER_OVER_UNDER
+	}
+	return kabuspb.UnderOver_UNDER_OVER_UNSPECIFIED
+}
+
+// afterHitOrderTypeTo - 逆指値の発火後の執行条件をkabus用に変換
+func (k *kabusAPI) afterHitOrderTypeTo(executionType ExecutionType) kabuspb.StockAfterHitOrderType {
+	switch executionType {
+	case ExecutionTypeStopMarket:
+		return kabuspb.StockAfterHitOrderType_STOCK_AFTER_HIT_ORDER_TYPE_MO
+	case ExecutionTypeStopLimit:
+		return kabuspb.StockAfterHitOrderType_STOCK_AFTER_HIT_ORDER_TYPE_LO
+	}
+	return kabuspb.StockAfterHitOrderType_STOCK_AFTER_HIT_ORDER_TYPE_UNSPECIFIED
+}
+
+// afterHitPriceTo - 逆指値の発火後の指値価格
+func (k *kabusAPI) afterHitPriceTo(order *Order) float64 {
+	if order.ExecutionType != ExecutionTypeStopLimit {
+		return 0
+	}
+	return order.Price
+}
+
+// stockStopOrderTo - 現物注文の逆指値条件をkabus用に変換
+// 逆指値でなければnilを返す
+func (k *kabusAPI) stockStopOrderTo(order *Order) *kabuspb.StockStopOrder {
+	if !order.ExecutionType.IsStop() {
+		return nil
+	}
+	return &kabuspb.StockStopOrder{
+		TriggerType:       kabuspb.TriggerType_TRIGGER_TYPE_ORDER_SYMBOL,
+		TriggerPrice:      order.TriggerPrice,
+		UnderOver:         k.underOverTo(order.Side),
+		AfterHitOrderType: k.afterHitOrderTypeTo(order.ExecutionType),
+		AfterHitPrice:     k.afterHitPriceTo(order),
+	}
+}
+
+// marginStopOrderTo - 信用注文の逆指値条件をkabus用に変換
+// 逆指値でなければnilを返す
+func (k *kabusAPI) marginStopOrderTo(order *Order) *kabuspb.MarginStopOrder {
+	if !order.ExecutionType.IsStop() {
+		return nil
+	}
+	return &kabuspb.MarginStopOrder{
+		TriggerType:       kabuspb.TriggerType_TRIGGER_TYPE_ORDER_SYMBOL,
+		TriggerPrice:      order.TriggerPrice,
+		UnderOver:         k.underOverTo(order.Side),
+		AfterHitOrderType: k.afterHitOrderTypeTo(order.ExecutionType),
+		AfterHitPrice:     k.afterHitPriceTo(order),
+	}
+}
+
+// deliveryTypeTo - DeliveryTypeをkabus用に変換
+func (k *kabusAPI) deliveryTypeTo(deliveryType DeliveryType) kabuspb.DeliveryType {
+	switch deliveryType {
+	case DeliveryTypeAuto:
+		return kabuspb.DeliveryType_DELIVERY_TYPE_AUTO
+	case DeliveryTypeCash:
+		return kabuspb.DeliveryType_DELIVERY_TYPE_CASH
+	}
+	return kabuspb.DeliveryType_DELIVERY_TYPE_UNSPECIFIED
+}
+
+// fundTypeTo - FundTypeをkabus用に変換
+func (k *kabusAPI) fundTypeTo(fundType FundType) kabuspb.FundType {
+	switch fundType {
+	case FundTypeProtected:
+		return kabuspb.FundType_FUND_TYPE_PROTECTED
+	case FundTypeSubstituteMargin:
+		return kabuspb.FundType_FUND_TYPE_SUBSTITUTE_MARGIN
+	case FundTypeMarginTrading:
+		return kabuspb.FundType_FUND_TYPE_MARGIN_TRADING
+	}
+	return kabuspb.FundType_FUND_TYPE_UNSPECIFIED
+}
+
+// stockDeliveryTypeTo - 現物注文の受渡区分
+// 売りは指定なし、買いは口座の指定で、口座の指定がなければお預かり金
+func (k *kabusAPI) stockDeliveryTypeTo(side Side, account Account) kabuspb.DeliveryType {
+	if side != SideBuy {
+		return kabuspb.DeliveryType_DELIVERY_TYPE_UNSPECIFIED
+	}
+	if account.DeliveryType == DeliveryTypeUnspecified {
+		return kabuspb.DeliveryType_DELIVERY_TYPE_CASH
+	}
+	return k.deliveryTypeTo(account.DeliveryType)
+}
+
+// stockFundTypeTo - 現物注文の資産区分
+// 売りは指定なし、買いは口座の指定で、口座の指定がなければ信用代用
+func (k *kabusAPI) stockFundTypeTo(side Side, account Account) kabuspb.FundType {
+	if side != SideBuy {
+		return kabuspb.FundType_FUND_TYPE_UNSPECIFIED
+	}
+	if account.FundType == FundTypeUnspecified {
+		return kabuspb.FundType_FUND_TYPE_SUBSTITUTE_MARGIN
+	}
+	return k.fundTypeTo(account.FundType)
+}
+
+// expireDayTo - 有効期限をkabus用に変換
+// ゼロ値なら当日として指定しない
+func (k *kabusAPI) expireDayTo(expireDay time.Time) *timestamppb.Timestamp {
+	if expireDay.IsZero() {
+		return nil
+	}
+	return timestamppb.New(expireDay)
+}
+
 func (k *kabusAPI) tradeTypeTo(tradeType TradeType) kabuspb.TradeType {
 	switch tradeType {
 	case TradeTypeEntry:
@@ -333,38 +597,46 @@ func (k *kabusAPI) SendOrder(strategy *Strategy, order *Order) (OrderResult, err
 		return result, ErrNilArgument
 	}
 
+	k.wait(RequestCategoryOrder, k.sendOrderPriority(order))
+	// 期限切れで証券会社に届いたかわからなくても、二重発注を避けるため再試行はしない
 	var res *kabuspb.OrderResponse
-	var err error
-	if order.Product == ProductStock {
-		res, err = k.kabucom.SendStockOrder(context.Background(), &kabuspb.SendStockOrderRequest{
-			Password:     strategy.Account.Password,
-			SymbolCode:   order.SymbolCode,
-			Exchange:     kabuspb.StockExchange(k.exchangeTo(order.Exchange)),
-			Side:         k.sideTo(order.Side),
-			DeliveryType: kabuspb.DeliveryType_DELIVERY_TYPE_CASH,      // お預かり金 多分固定で大丈夫
-			FundType:     kabuspb.FundType_FUND_TYPE_SUBSTITUTE_MARGIN, // 信用代用 多分固定で大丈夫
-			AccountType:  k.accountTypeTo(order.AccountType),
-			Quantity:     order.OrderQuantity,
-			OrderType:    k.orderTypeTo(order.ExecutionType),
-			ExpireDay:    nil,
-		})
-	} else if order.Product == ProductMargin {
-		res, err = k.kabucom.SendMarginOrder(context.Background(), &kabuspb.SendMarginOrderRequest{
-			Password:        strategy.Account.Password,
-			SymbolCode:      strategy.SymbolCode,
-			Exchange:        kabuspb.StockExchange(k.exchangeTo(order.Exchange)),
-			Side:            k.sideTo(order.Side),
-			TradeType:       k.tradeTypeTo(order.TradeType),
-			MarginTradeType: k.marginTradeTypeTo(order.MarginTradeType),
-			DeliveryType:    kabuspb.DeliveryType_DELIVERY_TYPE_CASH, // お預かり金 多分固定で大丈夫
-			AccountType:     k.accountTypeTo(strategy.Account.AccountType),
-			Quantity:        order.OrderQuantity,
-			ClosePositions:  k.closePositionsTo(order.HoldPositions),
-			OrderType:       k.orderTypeTo(order.ExecutionType),
-			Price:           order.Price,
-			ExpireDay:       nil,
-		})
-	}
+	err := k.call(func(ctx context.Context) error {
+		var err error
+		if order.Product == ProductStock {
+			res, err = k.kabucom.SendStockOrder(ctx, &kabuspb.SendStockOrderRequest{
+				Password:     strategy.Account.Password,
+				SymbolCode:   order.SymbolCode,
+				Exchange:     kabuspb.StockExchange(k.exchangeTo(order.Exchange)),
+				Side:         k.sideTo(order.Side),
+				DeliveryType: k.stockDeliveryTypeTo(order.Side, strategy.Account),
+				FundType:     k.stockFundTypeTo(order.Side, strategy.Account),
+				AccountType:  k.accountTypeTo(order.AccountType),
+				Quantity:     order.OrderQuantity,
+				OrderType:    k.orderTypeTo(order.ExecutionType),
+				Price:        k.orderPriceTo(order),
+				ExpireDay:    k.expireDayTo(order.ExpireDay),
+				StopOrder:    k.stockStopOrderTo(order),
+			})
+		} else if order.Product == ProductMargin {
+			res, err = k.kabucom.SendMarginOrder(ctx, &kabuspb.SendMarginOrderRequest{
+				Password:        strategy.Account.Password,
+				SymbolCode:      strategy.SymbolCode,
+				Exchange:        kabuspb.StockExchange(k.exchangeTo(order.Exchange)),
+				Side:            k.sideTo(order.Side),
+				TradeType:       k.tradeTypeTo(order.TradeType),
+				MarginTradeType: k.marginTradeTypeTo(order.MarginTradeType),
+				DeliveryType:    kabuspb.DeliveryType_DELIVERY_TYPE_CASH, // お預かり金 多分固定で大丈夫
+				AccountType:     k.accountTypeTo(strategy.Account.AccountType),
+				Quantity:        order.OrderQuantity,
+				ClosePositions:  k.closePositionsTo(order.HoldPositions),
+				OrderType:       k.orderTypeTo(order.ExecutionType),
+				Price:           k.orderPriceTo(order),
+				ExpireDay:       k.expireDayTo(order.ExpireDay),
+				StopOrder:       k.marginStopOrderTo(order),
+			})
+		}
+		return err
+	})
 	if err != nil {
 		return result, err
 	}
@@ -388,8 +660,12 @@ func (k *kabusAPI) priceRangeGroupFrom(priceRangeGroup string) TickGroup {
 
 // GetFourPrice - 四本値の取得
 func (k *kabusAPI) GetFourPrice(symbolCode string, exchange Exchange) (*FourPrice, error) {
-	board, err := k.kabucom.GetBoard(context.Background(), &kabuspb.GetBoardRequest{SymbolCode: symbolCode, Exchange: k.exchangeTo(exchange)})
-	if err != nil {
+	var board *kabuspb.Board
+	if err := k.retry(func(ctx context.Context) error {
+		var err error
+		board, err = k.kabucom.GetBoard(ctx, &kabuspb.GetBoardRequest{SymbolCode: symbolCode, Exchange: k.exchangeTo(exchange)})
+		return err
+	}); err != nil {
 		return nil, err
 	}
 
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -38,6 +39,10 @@ type testKabusAPI struct {
 	GetFourPrice2       error
 	GetFourPriceCount   int
 	GetFourPriceHistory []interface{}
+	GetPositions1       []SecurityPosition
+	GetPositions2       error
+	GetPositionsCount   int
+	GetPositionsHistory []interface{}
 }
 
 func (t *testKabusAPI) GetSymbol(symbolCode string, exchange Exchange) (*Symbol, error) {
@@ -70,6 +75,12 @@ func (t *testKabusAPI) GetFourPrice(symbolCode string, exchange Exchange) (*Four
 	t.GetFourPriceHistory = append(t.GetFourPriceHistory, exchange)
 	return t.GetFourPrice1, t.GetFourPrice2
 }
+func (t *testKabusAPI) GetPositions(product Product, symbolCode string) ([]SecurityPosition, error) {
+	t.GetPositionsCount++
+	t.GetPositionsHistory = append(t.GetPositionsHistory, product)
+	t.GetPositionsHistory = append(t.GetPositionsHistory, symbolCode)
+	return t.GetPositions1, t.GetPositions2
+}
 
 type testKabusServiceClient struct {
 	GetBoard1              *kabuspb.Board
@@ -87,6 +98,9 @@ type testKabusServiceClient struct {
 	SendMarginOrder1       *kabuspb.OrderResponse
 	SendMarginOrder2       error
 	SendMarginOrderHistory []interface{}
+	GetPositions1          *kabuspb.Positions
+	GetPositions2          error
+	GetPositionsHistory    []interface{}
 	kabuspb.KabusServiceClient
 }
 
@@ -111,6 +125,10 @@ func (t *testKabusServiceClient) SendMarginOrder(_ context.Context, in *kabuspb.
 	t.SendMarginOrderHistory = append(t.SendMarginOrderHistory, in)
 	return t.SendMarginOrder1, t.SendMarginOrder2
 }
+func (t *testKabusServiceClient) GetPositions(_ context.Context, in *kabuspb.GetPositionsRequest, _ ...grpc.CallOption) (*kabuspb.Positions, error) {
+	t.GetPositionsHistory = append(t.GetPositionsHistory, in)
+	return t.GetPositions1, t.GetPositions2
+}
 
 func Test_kabusAPI_exchangeTo(t *testing.T) {
 	t.Parallel()
@@ -202,6 +220,8 @@ func Test_kabusAPI_GetSymbol(t *testing.T) {
 				CurrentPriceDateTime: time.Date(2021, 11, 19, 10, 0, 0, 0, time.Local),
 				BidPrice:             2075,
 				AskPrice:             2077,
+				UpperLimit:           2576,
+				LowerLimit:           1576,
 			}},
 	}
 
@@ -516,6 +536,7 @@ func Test_kabusAPI_securityOrderFrom(t *testing.T) {
 				MarginTradeType:  MarginTradeTypeUnspecified,
 				TradeType:        TradeTypeExit,
 				Side:             SideSell,
+				ExecutionType:    ExecutionTypeLimit,
 				Price:            2048,
 				OrderQuantity:    4,
 				ContractQuantity: 0,
@@ -526,6 +547,48 @@ func Test_kabusAPI_securityOrderFrom(t *testing.T) {
 				CancelDateTime:   time.Time{},
 				Contracts:        []Contract{},
 			}},
+		{name: "発火前の逆指値注文は注文中として変換できる",
+			arg1: kabuspb.Product_PRODUCT_MARGIN,
+			arg2: &kabuspb.Order{
+				Id:                 "20211022A02N21800824",
+				State:              kabuspb.State_STATE_WAIT,
+				OrderState:         kabuspb.OrderState_ORDER_STATE_WAIT,
+				OrderType:          kabuspb.OrderType_ORDER_TYPE_ZARABA,
+				ReceiveTime:        &timestamppb.Timestamp{Seconds: 1634860820, Nanos: 785189700},
+				SymbolCode:         "1475",
+				Exchange:           kabuspb.OrderExchange_ORDER_EXCHANGE_TOUSHOU,
+				Price:              0,
+				OrderQuantity:      4,
+				CumulativeQuantity: 0,
+				Side:               kabuspb.Side_SIDE_SELL,
+				TradeType:          kabuspb.TradeType_TRADE_TYPE_EXIT,
+				MarginTradeType:    kabuspb.MarginTradeType_MARGIN_TRADE_TYPE_GENERAL_DAY,
+				AccountType:        kabuspb.AccountType_ACCOUNT_TYPE_SPECIFIC,
+				ExpireDay:          &timestamppb.Timestamp{Seconds: 1634828400},
+				Details: []*kabuspb.OrderDetail{
+					{SequenceNumber: 1, Id: "20211022A02N21800824", RecordType: kabuspb.RecordType_RECORD_TYPE_RECEIVE, State: kabuspb.OrderDetailState_ORDER_DETAIL_STATE_WAIT, TransactTime: &timestamppb.Timestamp{Seconds: 1634860820, Nanos: 785189700}, OrderType: kabuspb.OrderType_ORDER_TYPE_ZARABA, Quantity: 4, ExecutionDay: &timestamppb.Timestamp{Seconds: -62135596800}, DeliveryDay: &timestamppb.Timestamp{Seconds: 1635174000}},
+				},
+			},
+			want: SecurityOrder{
+				Code:             "20211022A02N21800824",
+				Status:           OrderStatusInOrder,
+				SymbolCode:       "1475",
+				Exchange:         ExchangeToushou,
+				Product:          ProductMargin,
+				MarginTradeType:  MarginTradeTypeDay,
+				TradeType:        TradeTypeExit,
+				Side:             SideSell,
+				ExecutionType:    ExecutionTypeStopMarket,
+				Price:            0,
+				OrderQuantity:    4,
+				ContractQuantity: 0,
+				AccountType:      AccountTypeSpecific,
+				ExpireDay:        time.Date(2021, 10, 22, 0, 0, 0, 0, time.Local),
+				OrderDateTime:    time.Date(2021, 10, 22, 9, 0, 20, 785189700, time.Local),
+				ContractDateTime: time.Time{},
+				CancelDateTime:   time.Time{},
+				Contracts:        []Contract{},
+			}},
 		{name: "部分約定した注文を変換できる",
 			arg1: kabuspb.Product_PRODUCT_MARGIN,
 			arg2: &kabuspb.Order{
@@ -561,6 +624,7 @@ func Test_kabusAPI_securityOrderFrom(t *testing.T) {
 				MarginTradeType:  MarginTradeTypeDay,
 				TradeType:        TradeTypeEntry,
 				Side:             SideSell,
+				ExecutionType:    ExecutionTypeLimit,
 				Price:            2048,
 				OrderQuantity:    4,
 				ContractQuantity: 2,
@@ -607,6 +671,7 @@ func Test_kabusAPI_securityOrderFrom(t *testing.T) {
 				MarginTradeType:  MarginTradeTypeUnspecified,
 				TradeType:        TradeTypeExit,
 				Side:             SideSell,
+				ExecutionType:    ExecutionTypeLimit,
 				Price:            2048,
 				OrderQuantity:    4,
 				ContractQuantity: 4,
@@ -654,6 +719,7 @@ func Test_kabusAPI_securityOrderFrom(t *testing.T) {
 				MarginTradeType:  MarginTradeTypeUnspecified,
 				TradeType:        TradeTypeEntry,
 				Side:             SideBuy,
+				ExecutionType:    ExecutionTypeLimit,
 				Price:            2040,
 				OrderQuantity:    4,
 				ContractQuantity: 0,
@@ -702,6 +768,7 @@ func Test_kabusAPI_securityOrderFrom(t *testing.T) {
 				MarginTradeType:  MarginTradeTypeDay,
 				TradeType:        TradeTypeEntry,
 				Side:             SideSell,
+				ExecutionType:    ExecutionTypeLimit,
 				Price:            2066,
 				OrderQuantity:    4,
 				ContractQuantity: 0,
@@ -745,6 +812,7 @@ func Test_kabusAPI_securityOrderFrom(t *testing.T) {
 				MarginTradeType:  MarginTradeTypeUnspecified,
 				TradeType:        TradeTypeExit,
 				Side:             SideSell,
+				ExecutionType:    ExecutionTypeLimit,
 				Price:            2066,
 				OrderQuantity:    4,
 				ContractQuantity: 0,
@@ -854,6 +922,7 @@ func Test_kabusAPI_GetOrders(t *testing.T) {
 					MarginTradeType:  MarginTradeTypeUnspecified,
 					TradeType:        TradeTypeEntry,
 					Side:             SideBuy,
+					ExecutionType:    ExecutionTypeLimit,
 					Price:            2048,
 					OrderQuantity:    4,
 					ContractQuantity: 4,
@@ -876,6 +945,7 @@ func Test_kabusAPI_GetOrders(t *testing.T) {
 					MarginTradeType:  MarginTradeTypeUnspecified,
 					TradeType:        TradeTypeExit,
 					Side:             SideSell,
+					ExecutionType:    ExecutionTypeLimit,
 					Price:            2040,
 					OrderQuantity:    4,
 					ContractQuantity: 0,
@@ -1039,6 +1109,8 @@ func Test_kabusAPI_orderTypeTo(t *testing.T) {
 		{name: "前場引成 を変換できる", arg1: ExecutionTypeMarketMorningClose, want: kabuspb.StockOrderType_STOCK_ORDER_TYPE_MOMC},
 		{name: "後場引成 を変換できる", arg1: ExecutionTypeMarketAfternoonClose, want: kabuspb.StockOrderType_STOCK_ORDER_TYPE_MOAC},
 		{name: "指値 を変換できる", arg1: ExecutionTypeLimit, want: kabuspb.StockOrderType_STOCK_ORDER_TYPE_LO},
+		{name: "逆指値(成行) を変換できる", arg1: ExecutionTypeStopMarket, want: kabuspb.StockOrderType_STOCK_ORDER_TYPE_STOP},
+		{name: "逆指値(指値) を変換できる", arg1: ExecutionTypeStopLimit, want: kabuspb.StockOrderType_STOCK_ORDER_TYPE_STOP},
 	}
 
 	for _, test := range tests {
@@ -1392,6 +1464,80 @@ func Test_kabusAPI_SendOrder(t *testing.T) {
 				Quantity:        5,
 				OrderType:       kabuspb.StockOrderType_STOCK_ORDER_TYPE_MO,
 			}}},
+		{name: "現物の指値の買い注文は口座の受渡区分と資産区分、価格、有効期限を指定する",
+			kabusServiceClient: &testKabusServiceClient{
+				SendStockOrder1: &kabuspb.OrderResponse{ResultCode: 0, OrderId: "ORDER-ID-001"},
+			},
+			arg1: &Strategy{
+				Code:       "strategy-1475",
+				SymbolCode: "1475",
+				Exchange:   ExchangeToushou,
+				Product:    ProductStock,
+				Account:    Account{Password: "Password1234", AccountType: AccountTypeGeneral, DeliveryType: DeliveryTypeAuto, FundType: FundTypeProtected},
+			},
+			arg2: &Order{
+				StrategyCode:  "strategy-1475",
+				SymbolCode:    "1475",
+				Exchange:      ExchangeToushou,
+				Product:       ProductStock,
+				ExecutionType: ExecutionTypeLimit,
+				Side:          SideBuy,
+				TradeType:     TradeTypeEntry,
+				Price:         2100,
+				OrderQuantity: 5,
+				AccountType:   AccountTypeGeneral,
+				ExpireDay:     time.Date(2022, 1, 7, 0, 0, 0, 0, time.Local),
+			},
+			want1: OrderResult{Result: true, ResultCode: 0, OrderCode: "ORDER-ID-001"},
+			wantSendStockOrderHistory: []interface{}{&kabuspb.SendStockOrderRequest{
+				Password:     "Password1234",
+				SymbolCode:   "1475",
+				Exchange:     kabuspb.StockExchange_STOCK_EXCHANGE_TOUSHOU,
+				Side:         kabuspb.Side_SIDE_BUY,
+				DeliveryType: kabuspb.DeliveryType_DELIVERY_TYPE_AUTO,
+				FundType:     kabuspb.FundType_FUND_TYPE_PROTECTED,
+				AccountType:  kabuspb.AccountType_ACCOUNT_TYPE_GENERAL,
+				Quantity:     5,
+				OrderType:    kabuspb.StockOrderType_STOCK_ORDER_TYPE_LO,
+				Price:        2100,
+				ExpireDay:    timestamppb.New(time.Date(2022, 1, 7, 0, 0, 0, 0, time.Local)),
+			}}},
+		{name: "現物の指値の売り注文は受渡区分と資産区分を指定しない",
+			kabusServiceClient: &testKabusServiceClient{
+				SendStockOrder1: &kabuspb.OrderResponse{ResultCode: 0, OrderId: "ORDER-ID-001"},
+			},
+			arg1: &Strategy{
+				Code:       "strategy-1475",
+				SymbolCode: "1475",
+				Exchange:   ExchangeToushou,
+				Product:    ProductStock,
+				Account:    Account{Password: "Password1234", AccountType: AccountTypeSpecific, DeliveryType: DeliveryTypeCash, FundType: FundTypeProtected},
+			},
+			arg2: &Order{
+				StrategyCode:  "strategy-1475",
+				SymbolCode:    "1475",
+				Exchange:      ExchangeToushou,
+				Product:       ProductStock,
+				ExecutionType: ExecutionTypeLimit,
+				Side:          SideSell,
+				TradeType:     TradeTypeExit,
+				Price:         2110,
+				OrderQuantity: 5,
+				AccountType:   AccountTypeSpecific,
+			},
+			want1: OrderResult{Result: true, ResultCode: 0, OrderCode: "ORDER-ID-001"},
+			wantSendStockOrderHistory: []interface{}{&kabuspb.SendStockOrderRequest{
+				Password:     "Password1234",
+				SymbolCode:   "1475",
+				Exchange:     kabuspb.StockExchange_STOCK_EXCHANGE_TOUSHOU,
+				Side:         kabuspb.Side_SIDE_SELL,
+				DeliveryType: kabuspb.DeliveryType_DELIVERY_TYPE_UNSPECIFIED,
+				FundType:     kabuspb.FundType_FUND_TYPE_UNSPECIFIED,
+				AccountType:  kabuspb.AccountType_ACCOUNT_TYPE_SPECIFIC,
+				Quantity:     5,
+				OrderType:    kabuspb.StockOrderType_STOCK_ORDER_TYPE_LO,
+				Price:        2110,
+			}}},
 	}
 
 	for _, test := range tests {
@@ -1415,8 +1561,10 @@ func Test_kabusAPI_SendOrder(t *testing.T) {
 func Test_newKabusAPI(t *testing.T) {
 	t.Parallel()
 	kabucom := &testKabusServiceClient{}
-	want1 := &kabusAPI{kabucom: kabucom}
-	got1 := newKabusAPI(kabucom)
+	rateLimiter := &testRateLimiter{}
+	retryConfig := RetryConfig{MaxRetries: 3, InitialInterval: 100 * time.Millisecond, MaxInterval: 1 * time.Second, Multiplier: 2, Timeout: 3 * time.Second}
+	want1 := &kabusAPI{kabucom: kabucom, rateLimiter: rateLimiter, retryConfig: retryConfig}
+	got1 := newKabusAPI(kabucom, rateLimiter, retryConfig)
 	if !reflect.DeepEqual(want1, got1) {
 		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
 	}
@@ -1541,3 +1689,567 @@ func Test_kabusAPI_GetFourPrice(t *testing.T) {
 		})
 	}
 }
+
+func Test_kabusAPI_GetPositions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                    string
+		kabusServiceClient      *testKabusServiceClient
+		arg1                    Product
+		arg2                    string
+		want1                   []SecurityPosition
+		want2                   error
+		wantGetPositionsHistory []interface{}
+	}{
+		{name: "errが返されたらerrを返す",
+			kabusServiceClient:      &testKabusServiceClient{GetPositions2: ErrUnknown},
+			arg1:                    ProductMargin,
+			arg2:                    "1475",
+			want2:                   ErrUnknown,
+			wantGetPositionsHistory: []interface{}{&kabuspb.GetPositionsRequest{Product: kabuspb.Product_PRODUCT_MARGIN, SymbolCode: "1475"}}},
+		{name: "positionsが空なら空配列を返す",
+			kabusServiceClient:      &testKabusServiceClient{GetPositions1: &kabuspb.Positions{Positions: []*kabuspb.Position{}}},
+			arg1:                    ProductMargin,
+			arg2:                    "1475",
+			want1:                   []SecurityPosition{},
+			wantGetPositionsHistory: []interface{}{&kabuspb.GetPositionsRequest{Product: kabuspb.Product_PRODUCT_MARGIN, SymbolCode: "1475"}}},
+		{name: "positionsの中身を変換して返す",
+			kabusServiceClient: &testKabusServiceClient{GetPositions1: &kabuspb.Positions{Positions: []*kabuspb.Position{
+				{
+					ExecutionId:     "E20220125001",
+					AccountType:     kabuspb.AccountType_ACCOUNT_TYPE_SPECIFIC,
+					SymbolCode:      "1475",
+					Exchange:        kabuspb.Exchange_EXCHANGE_TOUSHOU,
+					ExecutionDay:    timestamppb.New(time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)),
+					Price:           2000,
+					LeavesQuantity:  4,
+					HoldQuantity:    1,
+					Side:            kabuspb.Side_SIDE_SELL,
+					MarginTradeType: kabuspb.MarginTradeType_MARGIN_TRADE_TYPE_GENERAL_DAY,
+				},
+				{
+					SymbolCode:     "1475",
+					Exchange:       kabuspb.Exchange_EXCHANGE_TOUSHOU,
+					ExecutionDay:   timestamppb.New(time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local)),
+					Price:          1990,
+					LeavesQuantity: 10,
+					Side:           kabuspb.Side_SIDE_BUY,
+				},
+			}}},
+			arg1: ProductUnspecified,
+			arg2: "1475",
+			want1: []SecurityPosition{
+				{Code: "E20220125001", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay, Side: SideSell, Price: 2000, OwnedQuantity: 4, HoldQuantity: 1, ContractDateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)},
+				{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductStock, Side: SideBuy, Price: 1990, OwnedQuantity: 10, ContractDateTime: time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local)},
+			},
+			wantGetPositionsHistory: []interface{}{&kabuspb.GetPositionsRequest{Product: kabuspb.Product_PRODUCT_UNSPECIFIED, SymbolCode: "1475"}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabuAPI := &kabusAPI{kabucom: test.kabusServiceClient}
+			got1, got2 := kabuAPI.GetPositions(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) || !reflect.DeepEqual(test.wantGetPositionsHistory, test.kabusServiceClient.GetPositionsHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.want2, test.wantGetPositionsHistory,
+					got1, got2, test.kabusServiceClient.GetPositionsHistory)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_wait(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		rateLimiter *testRateLimiter
+		want1       []interface{}
+	}{
+		{name: "流量制限がなければ待たない", rateLimiter: nil, want1: nil},
+		{name: "流量制限があれば区分と優先度を渡して待つ", rateLimiter: &testRateLimiter{}, want1: []interface{}{RequestCategoryOrder, RequestPriorityHigh}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			if test.rateLimiter != nil {
+				kabus.rateLimiter = test.rateLimiter
+			}
+			kabus.wait(RequestCategoryOrder, RequestPriorityHigh)
+			var got1 []interface{}
+			if test.rateLimiter != nil {
+				got1 = test.rateLimiter.WaitHistory
+			}
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_sendOrderPriority(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		arg1  *Order
+		want1 RequestPriority
+	}{
+		{name: "エグジットなら優先度は高", arg1: &Order{TradeType: TradeTypeExit}, want1: RequestPriorityHigh},
+		{name: "エントリーなら優先度は低", arg1: &Order{TradeType: TradeTypeEntry}, want1: RequestPriorityLow},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got1 := kabus.sendOrderPriority(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_CancelOrder_rateLimit(t *testing.T) {
+	t.Parallel()
+	rateLimiter := &testRateLimiter{}
+	kabus := &kabusAPI{kabucom: &testKabusServiceClient{CancelOrder1: &kabuspb.OrderResponse{}}, rateLimiter: rateLimiter}
+	_, _ = kabus.CancelOrder("Password1234", "order-code-001")
+	want1 := []interface{}{RequestCategoryOrder, RequestPriorityHigh}
+	if !reflect.DeepEqual(want1, rateLimiter.WaitHistory) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, rateLimiter.WaitHistory)
+	}
+}
+
+type testHangingKabusServiceClient struct {
+	kabuspb.KabusServiceClient
+	SendStockOrderCount int
+	CancelOrderCount    int
+}
+
+func (t *testHangingKabusServiceClient) SendStockOrder(ctx context.Context, _ *kabuspb.SendStockOrderRequest, _ ...grpc.CallOption) (*kabuspb.OrderResponse, error) {
+	t.SendStockOrderCount++
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+func (t *testHangingKabusServiceClient) CancelOrder(ctx context.Context, _ *kabuspb.CancelOrderRequest, _ ...grpc.CallOption) (*kabuspb.OrderResponse, error) {
+	t.CancelOrderCount++
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func Test_kabusAPI_SendOrder_timeout(t *testing.T) {
+	t.Parallel()
+	kabucom := &testHangingKabusServiceClient{}
+	kabus := &kabusAPI{kabucom: kabucom, rateLimiter: &testRateLimiter{}, retryConfig: RetryConfig{MaxRetries: 3, InitialInterval: time.Millisecond, Timeout: 10 * time.Millisecond}}
+	_, got1 := kabus.SendOrder(&Strategy{}, &Order{Product: ProductStock})
+	if !errors.Is(got1, ErrKabusTransient) || !reflect.DeepEqual(1, kabucom.SendStockOrderCount) {
+		t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), ErrKabusTransient, 1, got1, kabucom.SendStockOrderCount)
+	}
+}
+
+func Test_kabusAPI_CancelOrder_timeout(t *testing.T) {
+	t.Parallel()
+	kabucom := &testHangingKabusServiceClient{}
+	kabus := &kabusAPI{kabucom: kabucom, rateLimiter: &testRateLimiter{}, retryConfig: RetryConfig{Timeout: 10 * time.Millisecond}}
+	_, got1 := kabus.CancelOrder("Password1234", "order-code-001")
+	if !errors.Is(got1, ErrKabusTransient) || !reflect.DeepEqual(1, kabucom.CancelOrderCount) {
+		t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), ErrKabusTransient, 1, got1, kabucom.CancelOrderCount)
+	}
+}
+
+func Test_kabusAPI_retry(t *testing.T) {
+	t.Parallel()
+	unavailable := status.New(codes.Unavailable, "unavailable").Err()
+	tests := []struct {
+		name        string
+		retryConfig RetryConfig
+		errs        []error
+		want1       error
+		wantCount   int
+		wantWait    []interface{}
+	}{
+		{name: "成功すれば再試行しない",
+			retryConfig: RetryConfig{MaxRetries: 3},
+			errs:        []error{nil},
+			want1:       nil,
+			wantCount:   1,
+			wantWait:    []interface{}{RequestCategoryInfo, RequestPriorityNormal}},
+		{name: "一時的でないエラーは再試行しない",
+			retryConfig: RetryConfig{MaxRetries: 3},
+			errs:        []error{ErrUnknown},
+			want1:       ErrUnknown,
+			wantCount:   1,
+			wantWait:    []interface{}{RequestCategoryInfo, RequestPriorityNormal}},
+		{name: "一時的なエラーなら成功するまで再試行する",
+			retryConfig: RetryConfig{MaxRetries: 3, InitialInterval: time.Millisecond, Multiplier: 2},
+			errs:        []error{unavailable, unavailable, nil},
+			want1:       nil,
+			wantCount:   3,
+			wantWait:    []interface{}{RequestCategoryInfo, RequestPriorityNormal, RequestCategoryInfo, RequestPriorityNormal, RequestCategoryInfo, RequestPriorityNormal}},
+		{name: "最大再試行回数まで失敗したら分類したエラーを返す",
+			retryConfig: RetryConfig{MaxRetries: 1, InitialInterval: time.Millisecond},
+			errs:        []error{unavailable, unavailable, nil},
+			want1:       ErrKabusTransient,
+			wantCount:   2,
+			wantWait:    []interface{}{RequestCategoryInfo, RequestPriorityNormal, RequestCategoryInfo, RequestPriorityNormal}},
+		{name: "再試行の設定がなければ一時的なエラーでも再試行しない",
+			retryConfig: RetryConfig{},
+			errs:        []error{unavailable, nil},
+			want1:       ErrKabusTransient,
+			wantCount:   1,
+			wantWait:    []interface{}{RequestCategoryInfo, RequestPriorityNormal}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			rateLimiter := &testRateLimiter{}
+			kabus := &kabusAPI{rateLimiter: rateLimiter, retryConfig: test.retryConfig}
+			var count int
+			got1 := kabus.retry(func(context.Context) error {
+				err := test.errs[count]
+				count++
+				return err
+			})
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantCount, count) || !reflect.DeepEqual(test.wantWait, rateLimiter.WaitHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(), test.want1, test.wantCount, test.wantWait, got1, count, rateLimiter.WaitHistory)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_call(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		retryConfig  RetryConfig
+		wantDeadline bool
+	}{
+		{name: "期限の設定がなければ期限なしのcontextを渡す", retryConfig: RetryConfig{}, wantDeadline: false},
+		{name: "期限の設定があれば期限付きのcontextを渡す", retryConfig: RetryConfig{Timeout: time.Second}, wantDeadline: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{retryConfig: test.retryConfig}
+			var gotDeadline bool
+			_ = kabus.call(func(ctx context.Context) error {
+				_, gotDeadline = ctx.Deadline()
+				return nil
+			})
+			if !reflect.DeepEqual(test.wantDeadline, gotDeadline) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.wantDeadline, gotDeadline)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_deliveryTypeTo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 DeliveryType
+		want kabuspb.DeliveryType
+	}{
+		{name: "未指定 を変換できる", arg1: DeliveryTypeUnspecified, want: kabuspb.DeliveryType_DELIVERY_TYPE_UNSPECIFIED},
+		{name: "自動振替 を変換できる", arg1: DeliveryTypeAuto, want: kabuspb.DeliveryType_DELIVERY_TYPE_AUTO},
+		{name: "お預かり金 を変換できる", arg1: DeliveryTypeCash, want: kabuspb.DeliveryType_DELIVERY_TYPE_CASH},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got := kabus.deliveryTypeTo(test.arg1)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_fundTypeTo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 FundType
+		want kabuspb.FundType
+	}{
+		{name: "未指定 を変換できる", arg1: FundTypeUnspecified, want: kabuspb.FundType_FUND_TYPE_UNSPECIFIED},
+		{name: "保護 を変換できる", arg1: FundTypeProtected, want: kabuspb.FundType_FUND_TYPE_PROTECTED},
+		{name: "信用代用 を変換できる", arg1: FundTypeSubstituteMargin, want: kabuspb.FundType_FUND_TYPE_SUBSTITUTE_MARGIN},
+		{name: "信用取引 を変換できる", arg1: FundTypeMarginTrading, want: kabuspb.FundType_FUND_TYPE_MARGIN_TRADING},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got := kabus.fundTypeTo(test.arg1)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_stockDeliveryTypeTo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 Side
+		arg2 Account
+		want kabuspb.DeliveryType
+	}{
+		{name: "売りなら指定なし", arg1: SideSell, arg2: Account{DeliveryType: DeliveryTypeAuto}, want: kabuspb.DeliveryType_DELIVERY_TYPE_UNSPECIFIED},
+		{name: "買いで口座の指定がなければお預かり金", arg1: SideBuy, arg2: Account{}, want: kabuspb.DeliveryType_DELIVERY_TYPE_CASH},
+		{name: "買いで口座の指定があればその受渡区分", arg1: SideBuy, arg2: Account{DeliveryType: DeliveryTypeAuto}, want: kabuspb.DeliveryType_DELIVERY_TYPE_AUTO},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got := kabus.stockDeliveryTypeTo(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_stockFundTypeTo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 Side
+		arg2 Account
+		want kabuspb.FundType
+	}{
+		{name: "売りなら指定なし", arg1: SideSell, arg2: Account{FundType: FundTypeProtected}, want: kabuspb.FundType_FUND_TYPE_UNSPECIFIED},
+		{name: "買いで口座の指定がなければ信用代用", arg1: SideBuy, arg2: Account{}, want: kabuspb.FundType_FUND_TYPE_SUBSTITUTE_MARGIN},
+		{name: "買いで口座の指定があればその資産区分", arg1: SideBuy, arg2: Account{FundType: FundTypeProtected}, want: kabuspb.FundType_FUND_TYPE_PROTECTED},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got := kabus.stockFundTypeTo(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_expireDayTo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 time.Time
+		want *timestamppb.Timestamp
+	}{
+		{name: "ゼロ値ならnil", arg1: time.Time{}, want: nil},
+		{name: "日付があれば変換する", arg1: time.Date(2022, 1, 7, 0, 0, 0, 0, time.Local), want: timestamppb.New(time.Date(2022, 1, 7, 0, 0, 0, 0, time.Local))},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got := kabus.expireDayTo(test.arg1)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_orderPriceTo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 *Order
+		want float64
+	}{
+		{name: "指値なら指値価格", arg1: &Order{ExecutionType: ExecutionTypeLimit, Price: 2100}, want: 2100},
+		{name: "逆指値(指値)なら0", arg1: &Order{ExecutionType: ExecutionTypeStopLimit, Price: 2100, TriggerPrice: 2105}, want: 0},
+		{name: "逆指値(成行)なら0", arg1: &Order{ExecutionType: ExecutionTypeStopMarket, TriggerPrice: 2105}, want: 0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got := kabus.orderPriceTo(test.arg1)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_underOverTo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 Side
+		want kabuspb.UnderOver
+	}{
+		{name: "未指定 を変換できる", arg1: SideUnspecified, want: kabuspb.UnderOver_UNDER_OVER_UNSPECIFIED},
+		{name: "買い は以上", arg1: SideBuy, want: kabuspb.UnderOver_UNDER_OVER_OVER},
+		{name: "売り は以下", arg1: SideSell, want: kabuspb.UnderOver_UNDER_OVER_UNDER},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got := kabus.underOverTo(test.arg1)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_executionTypeFrom(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 bool
+		arg2 float64
+		want ExecutionType
+	}{
+		{name: "発火前の逆指値で価格があれば逆指値(指値)", arg1: true, arg2: 1992, want: ExecutionTypeStopLimit},
+		{name: "発火前の逆指値で価格がなければ逆指値(成行)", arg1: true, arg2: 0, want: ExecutionTypeStopMarket},
+		{name: "価格があれば指値", arg1: false, arg2: 2000, want: ExecutionTypeLimit},
+		{name: "価格がなければ成行", arg1: false, arg2: 0, want: ExecutionTypeMarket},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got := kabus.executionTypeFrom(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_afterHitOrderTypeTo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 ExecutionType
+		want kabuspb.StockAfterHitOrderType
+	}{
+		{name: "逆指値以外 は未指定", arg1: ExecutionTypeLimit, want: kabuspb.StockAfterHitOrderType_STOCK_AFTER_HIT_ORDER_TYPE_UNSPECIFIED},
+		{name: "逆指値(成行) は成行", arg1: ExecutionTypeStopMarket, want: kabuspb.StockAfterHitOrderType_STOCK_AFTER_HIT_ORDER_TYPE_MO},
+		{name: "逆指値(指値) は指値", arg1: ExecutionTypeStopLimit, want: kabuspb.StockAfterHitOrderType_STOCK_AFTER_HIT_ORDER_TYPE_LO},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got := kabus.afterHitOrderTypeTo(test.arg1)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_stockStopOrderTo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 *Order
+		want *kabuspb.StockStopOrder
+	}{
+		{name: "逆指値でなければnil", arg1: &Order{ExecutionType: ExecutionTypeLimit, Side: SideSell, Price: 2100}, want: nil},
+		{name: "逆指値(成行)なら発火後の価格は指定しない",
+			arg1: &Order{ExecutionType: ExecutionTypeStopMarket, Side: SideSell, TriggerPrice: 2090},
+			want: &kabuspb.StockStopOrder{
+				TriggerType:       kabuspb.TriggerType_TRIGGER_TYPE_ORDER_SYMBOL,
+				TriggerPrice:      2090,
+				UnderOver:         kabuspb.UnderOver_UNDER_OVER_UNDER,
+				AfterHitOrderType: kabuspb.StockAfterHitOrderType_STOCK_AFTER_HIT_ORDER_TYPE_MO,
+			}},
+		{name: "逆指値(指値)なら発火後の価格に指値価格を指定する",
+			arg1: &Order{ExecutionType: ExecutionTypeStopLimit, Side: SideBuy, Price: 2115, TriggerPrice: 2110},
+			want: &kabuspb.StockStopOrder{
+				TriggerType:       kabuspb.TriggerType_TRIGGER_TYPE_ORDER_SYMBOL,
+				TriggerPrice:      2110,
+				UnderOver:         kabuspb.UnderOver_UNDER_OVER_OVER,
+				AfterHitOrderType: kabuspb.StockAfterHitOrderType_STOCK_AFTER_HIT_ORDER_TYPE_LO,
+				AfterHitPrice:     2115,
+			}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got := kabus.stockStopOrderTo(test.arg1)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_kabusAPI_marginStopOrderTo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 *Order
+		want *kabuspb.MarginStopOrder
+	}{
+		{name: "逆指値でなければnil", arg1: &Order{ExecutionType: ExecutionTypeMarket, Side: SideSell}, want: nil},
+		{name: "逆指値(指値)なら発火後の価格に指値価格を指定する",
+			arg1: &Order{ExecutionType: ExecutionTypeStopLimit, Side: SideSell, Price: 2085, TriggerPrice: 2090},
+			want: &kabuspb.MarginStopOrder{
+				TriggerType:       kabuspb.TriggerType_TRIGGER_TYPE_ORDER_SYMBOL,
+				TriggerPrice:      2090,
+				UnderOver:         kabuspb.UnderOver_UNDER_OVER_UNDER,
+				AfterHitOrderType: kabuspb.StockAfterHitOrderType_STOCK_AFTER_HIT_ORDER_TYPE_LO,
+				AfterHitPrice:     2085,
+			}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			kabus := &kabusAPI{}
+			got := kabus.marginStopOrderTo(test.arg1)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
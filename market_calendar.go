@@ -0,0 +1,108 @@
+package gridon
+
+import (
+	"time"
+)
+
+// isBusinessDay - 東証の営業日かどうか
+// 土日、祝日(振替休日と国民の休日を含む)、年末年始(12/31〜1/3)は休業日
+func isBusinessDay(d time.Time) bool {
+	switch {
+	case d.Weekday() == time.Saturday || d.Weekday() == time.Sunday:
+		return false
+	case d.Month() == time.January && d.Day() <= 3, d.Month() == time.December && d.Day() == 31:
+		return false
+	}
+	return !isHoliday(d)
+}
+
+// nextBusinessDay - 指定した日の翌営業日の日付
+func nextBusinessDay(d time.Time) time.Time {
+	next := time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, d.Location())
+	for !isBusinessDay(next) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// isHoliday - 祝日、振替休日、国民の休日のいずれかかどうか
+func isHoliday(d time.Time) bool {
+	if isNationalHoliday(d) {
+		return true
+	}
+	if d.Weekday() == time.Sunday {
+		return false
+	}
+
+	// 振替休日: 日曜日の祝日から祝日が続いた後の最初の平日
+	for p := d.AddDate(0, 0, -1); isNationalHoliday(p); p = p.AddDate(0, 0, -1) {
+		if p.Weekday() == time.Sunday {
+			return true
+		}
+	}
+
+	// 国民の休日: 前日と翌日が祝日に挟まれた日
+	return isNationalHoliday(d.AddDate(0, 0, -1)) && isNationalHoliday(d.AddDate(0, 0, 1))
+}
+
+// isNationalHoliday - 国民の祝日に関する法律で定められた祝日かどうか
+// 2007年以降の祝日法と、2019年から2021年の特例に対応している
+// 春分の日と秋分の日は官報での公示ではなく近似式で求めるため、1980年から2099年までしか正しくない
+func isNationalHoliday(d time.Time) bool {
+	year, month, day := d.Date()
+	switch month {
+	case time.January:
+		return day == 1 || isNthMonday(d, 2) // 元日、成人の日
+	case time.February:
+		return day == 11 || (year >= 2020 && day == 23) // 建国記念の日、天皇誕生日
+	case time.March:
+		return day == vernalEquinoxDay(year) // 春分の日
+	case time.April:
+		return day == 29 // 昭和の日
+	case time.May:
+		return (3 <= day && day <= 5) || (year == 2019 && day == 1) // 憲法記念日、みどりの日、こどもの日、天皇の即位の日
+	case time.July:
+		switch year {
+		case 2020:
+			return day == 23 || day == 24 // 海の日、スポーツの日
+		case 2021:
+			return day == 22 || day == 23 // 海の日、スポーツの日
+		}
+		return isNthMonday(d, 3) // 海の日
+	case time.August:
+		switch year {
+		case 2020:
+			return day == 10 // 山の日
+		case 2021:
+			return day == 8 // 山の日
+		}
+		return year >= 2016 && day == 11 // 山の日
+	case time.September:
+		return isNthMonday(d, 3) || day == autumnalEquinoxDay(year) // 敬老の日、秋分の日
+	case time.October:
+		if year == 2019 && day == 22 { // 即位礼正殿の儀の行われる日
+			return true
+		}
+		return year != 2020 && year != 2021 && isNthMonday(d, 2) // 体育の日(スポーツの日)
+	case time.November:
+		return day == 3 || day == 23 // 文化の日、勤労感謝の日
+	case time.December:
+		return year <= 2018 && day == 23 // 天皇誕生日
+	}
+	return false
+}
+
+// isNthMonday - その月の第n月曜日かどうか
+func isNthMonday(d time.Time, n int) bool {
+	return d.Weekday() == time.Monday && (d.Day()-1)/7 == n-1
+}
+
+// vernalEquinoxDay - 春分の日の日付
+func vernalEquinoxDay(year int) int {
+	return int(20.8431+0.242194*float64(year-1980)) - (year-1980)/4
+}
+
+// autumnalEquinoxDay - 秋分の日の日付
+func autumnalEquinoxDay(year int) int {
+	return int(23.2488+0.242194*float64(year-1980)) - (year-1980)/4
+}
@@ -0,0 +1,79 @@
+package gridon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_isBusinessDay(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg  time.Time
+		want bool
+	}{
+		{name: "平日は営業日", arg: time.Date(2022, 1, 5, 0, 0, 0, 0, time.Local), want: true},
+		{name: "土曜日は休業日", arg: time.Date(2022, 1, 8, 0, 0, 0, 0, time.Local), want: false},
+		{name: "日曜日は休業日", arg: time.Date(2022, 1, 9, 0, 0, 0, 0, time.Local), want: false},
+		{name: "大発会の前の1/3は休業日", arg: time.Date(2022, 1, 3, 0, 0, 0, 0, time.Local), want: false},
+		{name: "大納会の翌日の12/31は休業日", arg: time.Date(2021, 12, 31, 0, 0, 0, 0, time.Local), want: false},
+		{name: "成人の日(第2月曜日)は休業日", arg: time.Date(2022, 1, 10, 0, 0, 0, 0, time.Local), want: false},
+		{name: "第2月曜日でなければ営業日", arg: time.Date(2022, 1, 17, 0, 0, 0, 0, time.Local), want: true},
+		{name: "建国記念の日は休業日", arg: time.Date(2022, 2, 11, 0, 0, 0, 0, time.Local), want: false},
+		{name: "2020年以降の天皇誕生日は休業日", arg: time.Date(2022, 2, 23, 0, 0, 0, 0, time.Local), want: false},
+		{name: "2018年までの天皇誕生日は休業日", arg: time.Date(2016, 12, 23, 0, 0, 0, 0, time.Local), want: false},
+		{name: "2019年以降の12/23は営業日", arg: time.Date(2020, 12, 23, 0, 0, 0, 0, time.Local), want: true},
+		{name: "春分の日は休業日", arg: time.Date(2022, 3, 21, 0, 0, 0, 0, time.Local), want: false},
+		{name: "2023年の春分の日は休業日", arg: time.Date(2023, 3, 21, 0, 0, 0, 0, time.Local), want: false},
+		{name: "秋分の日は休業日", arg: time.Date(2022, 9, 23, 0, 0, 0, 0, time.Local), want: false},
+		{name: "日曜日の祝日の翌日は振替休日", arg: time.Date(2023, 1, 2, 0, 0, 0, 0, time.Local), want: false},
+		{name: "5/3が日曜日なら5/6が振替休日", arg: time.Date(2020, 5, 6, 0, 0, 0, 0, time.Local), want: false},
+		{name: "敬老の日と秋分の日に挟まれた日は国民の休日", arg: time.Date(2015, 9, 22, 0, 0, 0, 0, time.Local), want: false},
+		{name: "2019年の即位の日の前後は国民の休日", arg: time.Date(2019, 4, 30, 0, 0, 0, 0, time.Local), want: false},
+		{name: "2019年の即位礼正殿の儀の日は休業日", arg: time.Date(2019, 10, 22, 0, 0, 0, 0, time.Local), want: false},
+		{name: "2021年の東京オリンピックの海の日は休業日", arg: time.Date(2021, 7, 22, 0, 0, 0, 0, time.Local), want: false},
+		{name: "2021年の7月の第3月曜日は営業日", arg: time.Date(2021, 7, 19, 0, 0, 0, 0, time.Local), want: true},
+		{name: "2021年の山の日の振替休日は休業日", arg: time.Date(2021, 8, 9, 0, 0, 0, 0, time.Local), want: false},
+		{name: "2021年の10月の第2月曜日は営業日", arg: time.Date(2021, 10, 11, 0, 0, 0, 0, time.Local), want: true},
+		{name: "スポーツの日は休業日", arg: time.Date(2022, 10, 10, 0, 0, 0, 0, time.Local), want: false},
+		{name: "文化の日は休業日", arg: time.Date(2022, 11, 3, 0, 0, 0, 0, time.Local), want: false},
+		{name: "勤労感謝の日は休業日", arg: time.Date(2022, 11, 23, 0, 0, 0, 0, time.Local), want: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := isBusinessDay(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_nextBusinessDay(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg  time.Time
+		want time.Time
+	}{
+		{name: "翌日が営業日なら翌日", arg: time.Date(2022, 1, 5, 10, 0, 0, 0, time.Local), want: time.Date(2022, 1, 6, 0, 0, 0, 0, time.Local)},
+		{name: "金曜日なら土日を飛ばして月曜日", arg: time.Date(2022, 1, 14, 10, 0, 0, 0, time.Local), want: time.Date(2022, 1, 17, 0, 0, 0, 0, time.Local)},
+		{name: "連休の前ならゴールデンウィーク明け", arg: time.Date(2022, 5, 2, 10, 0, 0, 0, time.Local), want: time.Date(2022, 5, 6, 0, 0, 0, 0, time.Local)},
+		{name: "大納会なら大発会", arg: time.Date(2022, 12, 30, 10, 0, 0, 0, time.Local), want: time.Date(2023, 1, 4, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := nextBusinessDay(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
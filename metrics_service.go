@@ -0,0 +1,195 @@
+package gridon
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// tradingDaysPerYear - 年率換算に使う1年の営業日数
+const tradingDaysPerYear = 245
+
+// newMetricsService - 新しい成績サービスの取得
+func newMetricsService(clock IClock, positionStore IPositionStore, orderStore IOrderStore, fourPriceStore IFourPriceStore, equitySnapshotStore IEquitySnapshotStore, tradeStore ITradeStore) IMetricsService {
+	return &metricsService{
+		clock:               clock,
+		positionStore:       positionStore,
+		orderStore:          orderStore,
+		fourPriceStore:      fourPriceStore,
+		equitySnapshotStore: equitySnapshotStore,
+		tradeStore:          tradeStore,
+	}
+}
+
+// IMetricsService - 成績サービスのインターフェース
+type IMetricsService interface {
+	SaveEquitySnapshot(strategy *Strategy) error
+	GetMetrics(strategyCode string, from time.Time, to time.Time) (*StrategyMetrics, error)
+}
+
+// metricsService - 成績サービス
+type metricsService struct {
+	clock               IClock
+	positionStore       IPositionStore
+	orderStore          IOrderStore
+	fourPriceStore      IFourPriceStore
+	equitySnapshotStore IEquitySnapshotStore
+	tradeStore          ITradeStore
+}
+
+// SaveEquitySnapshot - 最新の終値で戦略の評価額を計算して保存する
+func (s *metricsService) SaveEquitySnapshot(strategy *Strategy) error {
+	if strategy == nil {
+		return ErrNilArgument
+	}
+
+	fourPrice, err := s.fourPriceStore.GetLastBySymbolCodeAndExchange(strategy.SymbolCode, strategy.Exchange)
+	if err != nil {
+		return err
+	}
+
+	positions, err := s.positionStore.GetActivePositionsByStrategyCode(strategy.Code)
+	if err != nil {
+		return err
+	}
+
+	orders, err := s.orderStore.GetActiveOrdersByStrategyCode(strategy.Code)
+	if err != nil {
+		return err
+	}
+
+	// ポジションの評価額
+	//   買いポジションの場合: 終値 x 数量
+	//   売りポジションの場合: (エントリー約定値 + エントリー約定値 - 終値) x 数量
+	//   現金余力の計算と合わせ、返済したときに現金に戻る金額で評価する
+	var positionValue, heldQuantity float64
+	for _, p := range positions {
+		switch p.Side {
+		case SideBuy:
+			positionValue += fourPrice.Close * p.OwnedQuantity
+		case SideSell:
+			positionValue += (p.Price + p.Price - fourPrice.Close) * p.OwnedQuantity
+		}
+		heldQuantity += p.OwnedQuantity
+	}
+
+	var openQuantity float64
+	for _, o := range orders {
+		openQuantity += o.OrderQuantity - o.ContractQuantity
+	}
+
+	now := s.clock.Now()
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return s.equitySnapshotStore.Save(&EquitySnapshot{
+		Code:          fmt.Sprintf("%s-%s", strategy.Code, date.Format("20060102")),
+		StrategyCode:  strategy.Code,
+		Date:          date,
+		Cash:          strategy.Cash,
+		ClosePrice:    fourPrice.Close,
+		PositionValue: positionValue,
+		Equity:        strategy.Cash + positionValue,
+		HeldQuantity:  heldQuantity,
+		OpenQuantity:  openQuantity,
+		DateTime:      now,
+	})
+}
+
+// GetMetrics - 日付がfrom以降to未満のスナップショットと取引から戦略の成績を計算する
+func (s *metricsService) GetMetrics(strategyCode string, from time.Time, to time.Time) (*StrategyMetrics, error) {
+	snapshots, err := s.equitySnapshotStore.GetByStrategyCode(strategyCode, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	trades, err := s.tradeStore.GetByStrategyCode(strategyCode, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.metrics(strategyCode, snapshots, trades), nil
+}
+
+// metrics - 日付順のスナップショットと取引から成績を計算する
+func (s *metricsService) metrics(strategyCode string, snapshots []*EquitySnapshot, trades []*Trade) *StrategyMetrics {
+	metrics := &StrategyMetrics{StrategyCode: strategyCode, Days: len(snapshots), TradeCount: len(trades)}
+
+	// 取引の成績
+	if len(trades) > 0 {
+		var win int
+		var profit float64
+		for _, t := range trades {
+			if t.Profit > 0 {
+				win++
+			}
+			profit += t.Profit
+		}
+		metrics.WinRate = float64(win) / float64(len(trades))
+		metrics.AverageProfit = profit / float64(len(trades))
+	}
+
+	if len(snapshots) == 0 {
+		return metrics
+	}
+
+	first, last := snapshots[0], snapshots[len(snapshots)-1]
+	metrics.From, metrics.To = first.Date, last.Date
+	metrics.StartEquity, metrics.EndEquity = first.Equity, last.Equity
+	if first.Equity != 0 {
+		metrics.Return = last.Equity/first.Equity - 1
+	}
+
+	// 最大ドローダウンと日次収益率
+	peak := first.Equity
+	returns := make([]float64, 0, len(snapshots)-1)
+	for i, e := range snapshots {
+		if peak < e.Equity {
+			peak = e.Equity
+		}
+		if metrics.MaxDrawdown < peak-e.Equity {
+			metrics.MaxDrawdown = peak - e.Equity
+			if peak != 0 {
+				metrics.MaxDrawdownRate = (peak - e.Equity) / peak
+			}
+		}
+		if i > 0 && snapshots[i-1].Equity != 0 {
+			returns = append(returns, e.Equity/snapshots[i-1].Equity-1)
+		}
+	}
+
+	metrics.Sharpe, metrics.Sortino = s.ratios(returns)
+	return metrics
+}
+
+// ratios - 日次収益率から年率換算したシャープレシオとソルティノレシオを計算する
+// 無リスク金利は0とし、計算できない場合は0を返す
+func (s *metricsService) ratios(returns []float64) (float64, float64) {
+	if len(returns) < 2 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, r := range returns {
+		sum += r
+	}
+	mean := sum / float64(len(returns))
+
+	var variance, downside float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+	downsideDeviation := math.Sqrt(downside / float64(len(returns)))
+
+	annualize := math.Sqrt(tradingDaysPerYear)
+	var sharpe, sortino float64
+	if std > 0 {
+		sharpe = mean / std * annualize
+	}
+	if downsideDeviation > 0 {
+		sortino = mean / downsideDeviation * annualize
+	}
+	return sharpe, sortino
+}
@@ -0,0 +1,259 @@
+package gridon
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testMetricsService struct {
+	IMetricsService
+	SaveEquitySnapshot1     error
+	SaveEquitySnapshotCount int
+	GetMetrics1             *StrategyMetrics
+	GetMetrics2             error
+	GetMetricsHistory       []interface{}
+	mtx                     sync.Mutex
+}
+
+func (t *testMetricsService) SaveEquitySnapshot(*Strategy) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.SaveEquitySnapshotCount++
+	return t.SaveEquitySnapshot1
+}
+func (t *testMetricsService) GetMetrics(strategyCode string, from time.Time, to time.Time) (*StrategyMetrics, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.GetMetricsHistory = append(t.GetMetricsHistory, strategyCode, from, to)
+	return t.GetMetrics1, t.GetMetrics2
+}
+
+type testEquitySnapshotStore struct {
+	IEquitySnapshotStore
+	Save1                    error
+	SaveHistory              []interface{}
+	GetByStrategyCode1       []*EquitySnapshot
+	GetByStrategyCode2       error
+	GetByStrategyCodeHistory []interface{}
+}
+
+func (t *testEquitySnapshotStore) Save(equitySnapshot *EquitySnapshot) error {
+	t.SaveHistory = append(t.SaveHistory, equitySnapshot)
+	return t.Save1
+}
+func (t *testEquitySnapshotStore) GetByStrategyCode(strategyCode string, from time.Time, to time.Time) ([]*EquitySnapshot, error) {
+	t.GetByStrategyCodeHistory = append(t.GetByStrategyCodeHistory, strategyCode, from, to)
+	return t.GetByStrategyCode1, t.GetByStrategyCode2
+}
+
+func Test_newMetricsService(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{}
+	positionStore := &testPositionStore{}
+	orderStore := &testOrderStore{}
+	fourPriceStore := &testFourPriceStore{}
+	equitySnapshotStore := &testEquitySnapshotStore{}
+	tradeStore := &testTradeStore{}
+	want1 := &metricsService{
+		clock:               clock,
+		positionStore:       positionStore,
+		orderStore:          orderStore,
+		fourPriceStore:      fourPriceStore,
+		equitySnapshotStore: equitySnapshotStore,
+		tradeStore:          tradeStore,
+	}
+	got1 := newMetricsService(clock, positionStore, orderStore, fourPriceStore, equitySnapshotStore, tradeStore)
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_metricsService_SaveEquitySnapshot(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		fourPriceStore  *testFourPriceStore
+		positionStore   *testPositionStore
+		orderStore      *testOrderStore
+		arg1            *Strategy
+		want1           error
+		wantSaveHistory []interface{}
+	}{
+		{name: "引数がnilならエラー",
+			fourPriceStore: &testFourPriceStore{},
+			positionStore:  &testPositionStore{},
+			orderStore:     &testOrderStore{},
+			arg1:           nil,
+			want1:          ErrNilArgument},
+		{name: "四本値が取れなければエラー",
+			fourPriceStore: &testFourPriceStore{GetLastBySymbolCodeAndExchange2: ErrNoData},
+			positionStore:  &testPositionStore{},
+			orderStore:     &testOrderStore{},
+			arg1:           &Strategy{Code: "strategy-code-001"},
+			want1:          ErrNoData},
+		{name: "ポジションが取れなければエラー",
+			fourPriceStore: &testFourPriceStore{GetLastBySymbolCodeAndExchange1: &FourPrice{Close: 1000}},
+			positionStore:  &testPositionStore{GetActivePositionsByStrategyCode2: ErrUnknown},
+			orderStore:     &testOrderStore{},
+			arg1:           &Strategy{Code: "strategy-code-001"},
+			want1:          ErrUnknown},
+		{name: "注文が取れなければエラー",
+			fourPriceStore: &testFourPriceStore{GetLastBySymbolCodeAndExchange1: &FourPrice{Close: 1000}},
+			positionStore:  &testPositionStore{},
+			orderStore:     &testOrderStore{GetActiveOrdersByStrategyCode2: ErrUnknown},
+			arg1:           &Strategy{Code: "strategy-code-001"},
+			want1:          ErrUnknown},
+		{name: "終値でポジションを評価し、現金と合わせた評価額を保存する",
+			fourPriceStore: &testFourPriceStore{GetLastBySymbolCodeAndExchange1: &FourPrice{Close: 1000}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
+				{Side: SideBuy, Price: 990, OwnedQuantity: 2},
+				{Side: SideSell, Price: 1010, OwnedQuantity: 1},
+			}},
+			orderStore: &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{
+				{OrderQuantity: 3, ContractQuantity: 1},
+				{OrderQuantity: 1},
+			}},
+			arg1:  &Strategy{Code: "strategy-code-001", Cash: 5000},
+			want1: nil,
+			wantSaveHistory: []interface{}{&EquitySnapshot{
+				Code:          "strategy-code-001-20220125",
+				StrategyCode:  "strategy-code-001",
+				Date:          time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local),
+				Cash:          5000,
+				ClosePrice:    1000,
+				PositionValue: 1000*2 + 1020*1,
+				Equity:        5000 + 1000*2 + 1020*1,
+				HeldQuantity:  3,
+				OpenQuantity:  3,
+				DateTime:      time.Date(2022, 1, 25, 15, 1, 0, 0, time.Local),
+			}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			equitySnapshotStore := &testEquitySnapshotStore{}
+			service := &metricsService{
+				clock:               &testClock{Now1: time.Date(2022, 1, 25, 15, 1, 0, 0, time.Local)},
+				positionStore:       test.positionStore,
+				orderStore:          test.orderStore,
+				fourPriceStore:      test.fourPriceStore,
+				equitySnapshotStore: equitySnapshotStore,
+			}
+			got1 := service.SaveEquitySnapshot(test.arg1)
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantSaveHistory, equitySnapshotStore.SaveHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.wantSaveHistory, got1, equitySnapshotStore.SaveHistory)
+			}
+		})
+	}
+}
+
+func Test_metricsService_GetMetrics(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                string
+		equitySnapshotStore *testEquitySnapshotStore
+		tradeStore          *testTradeStore
+		want1               *StrategyMetrics
+		want2               error
+	}{
+		{name: "スナップショットが取れなければエラー",
+			equitySnapshotStore: &testEquitySnapshotStore{GetByStrategyCode2: ErrUnknown},
+			tradeStore:          &testTradeStore{},
+			want2:               ErrUnknown},
+		{name: "取引が取れなければエラー",
+			equitySnapshotStore: &testEquitySnapshotStore{},
+			tradeStore:          &testTradeStore{GetByStrategyCode2: ErrUnknown},
+			want2:               ErrUnknown},
+		{name: "スナップショットと取引がなければ件数だけの成績を返す",
+			equitySnapshotStore: &testEquitySnapshotStore{},
+			tradeStore:          &testTradeStore{},
+			want1:               &StrategyMetrics{StrategyCode: "strategy-code-001"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &metricsService{equitySnapshotStore: test.equitySnapshotStore, tradeStore: test.tradeStore}
+			got1, got2 := service.GetMetrics("strategy-code-001", time.Time{}, time.Date(2022, 1, 26, 0, 0, 0, 0, time.Local))
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_metricsService_metrics(t *testing.T) {
+	t.Parallel()
+	snapshots := []*EquitySnapshot{
+		{Date: time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), Equity: 10000},
+		{Date: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local), Equity: 11000},
+		{Date: time.Date(2022, 1, 26, 0, 0, 0, 0, time.Local), Equity: 9900},
+		{Date: time.Date(2022, 1, 27, 0, 0, 0, 0, time.Local), Equity: 10890},
+	}
+	trades := []*Trade{{Profit: 30}, {Profit: -10}, {Profit: 0}, {Profit: 20}}
+
+	service := &metricsService{}
+	got := service.metrics("strategy-code-001", snapshots, trades)
+
+	// 日次収益率は 0.1, -0.1, 0.1
+	mean := 0.1 / 3
+	std := math.Sqrt((math.Pow(0.1-mean, 2)*2 + math.Pow(-0.1-mean, 2)) / 2)
+	downside := math.Sqrt(0.01 / 3)
+	want := &StrategyMetrics{
+		StrategyCode:    "strategy-code-001",
+		From:            time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local),
+		To:              time.Date(2022, 1, 27, 0, 0, 0, 0, time.Local),
+		Days:            4,
+		StartEquity:     10000,
+		EndEquity:       10890,
+		Return:          10890.0/10000 - 1,
+		MaxDrawdown:     1100,
+		MaxDrawdownRate: 0.1,
+		Sharpe:          mean / std * math.Sqrt(tradingDaysPerYear),
+		Sortino:         mean / downside * math.Sqrt(tradingDaysPerYear),
+		TradeCount:      4,
+		WinRate:         0.5,
+		AverageProfit:   10,
+	}
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	if got.StrategyCode != want.StrategyCode || !got.From.Equal(want.From) || !got.To.Equal(want.To) || got.Days != want.Days ||
+		got.StartEquity != want.StartEquity || got.EndEquity != want.EndEquity || !near(got.Return, want.Return) ||
+		got.MaxDrawdown != want.MaxDrawdown || !near(got.MaxDrawdownRate, want.MaxDrawdownRate) ||
+		!near(got.Sharpe, want.Sharpe) || !near(got.Sortino, want.Sortino) ||
+		got.TradeCount != want.TradeCount || got.WinRate != want.WinRate || got.AverageProfit != want.AverageProfit {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want, got)
+	}
+}
+
+func Test_metricsService_ratios(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		arg1  []float64
+		want1 float64
+		want2 float64
+	}{
+		{name: "収益率が2件未満なら0", arg1: []float64{0.1}, want1: 0, want2: 0},
+		{name: "ばらつきがなく損失もなければ0", arg1: []float64{0.01, 0.01}, want1: 0, want2: 0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &metricsService{}
+			got1, got2 := service.ratios(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) || !reflect.DeepEqual(test.want2, got2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package gridon
+
+// newLogNotifier - ログに書き出す通知の取得
+func newLogNotifier(logger ILogger) INotifier {
+	return &logNotifier{logger: logger}
+}
+
+// INotifier - 運用者への通知のインターフェース
+type INotifier interface {
+	Notify(message string)
+}
+
+// logNotifier - noticeログに書き出す通知
+type logNotifier struct {
+	logger ILogger
+}
+
+// Notify - 通知する
+func (n *logNotifier) Notify(message string) {
+	n.logger.Notice(message)
+}
@@ -0,0 +1,38 @@
+package gridon
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testNotifier struct {
+	INotifier
+	NotifyCount   int
+	NotifyHistory []string
+}
+
+func (t *testNotifier) Notify(message string) {
+	t.NotifyHistory = append(t.NotifyHistory, message)
+	t.NotifyCount++
+}
+
+func Test_newLogNotifier(t *testing.T) {
+	t.Parallel()
+	logger := &testLogger{}
+	want1 := &logNotifier{logger: logger}
+	got1 := newLogNotifier(logger)
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_logNotifier_Notify(t *testing.T) {
+	t.Parallel()
+	logger := &testLogger{}
+	notifier := &logNotifier{logger: logger}
+	notifier.Notify("message")
+	want := []interface{}{"message"}
+	if !reflect.DeepEqual(want, logger.NoticeHistory) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want, logger.NoticeHistory)
+	}
+}
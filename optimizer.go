@@ -0,0 +1,370 @@
+package gridon
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewOptimizerService - 新しい最適化サービスの取得
+func NewOptimizerService() IOptimizerService {
+	return &optimizerService{backtestService: NewBacktestService()}
+}
+
+// IOptimizerService - 最適化サービスのインターフェース
+type IOptimizerService interface {
+	Run(config OptimizerConfig) (*OptimizerReport, error)
+}
+
+// OptimizerConfig - 最適化の設定
+type OptimizerConfig struct {
+	Strategy          Strategy        // 最適化する戦略
+	FourPrices        []*FourPrice    // 最適化に使う価格より前の四本値
+	Prices            []BacktestPrice // 再生する価格
+	Ranges            OptimizerRanges // 探索するパラメータの範囲
+	RankBy            OptimizerRankBy // 順位付けの基準
+	WalkForwardSplits int             // ウォークフォワードで検証する回数 0ならウォークフォワードをしない
+	Parallel          int             // 同時に実行するバックテストの数 0ならCPU数
+}
+
+// OptimizerRanges - 探索するパラメータの範囲
+type OptimizerRanges struct {
+	BaseWidth                       OptimizerRange // グリッド幅
+	NumberOfGrids                   OptimizerRange // グリッドの本数
+	Quantity                        OptimizerRange // 1グリッドに乗せる数量
+	DynamicGridPrevDayRate          OptimizerRange // 前日の価格幅からの動的なグリッド幅の対象にする割合
+	DynamicGridPrevDayNumberOfGrids OptimizerRange // 前日の価格幅に置くことを考えるグリッドの本数
+	DynamicGridMinMaxDivide         OptimizerRange // 最小・最大約定値の差を割る値
+}
+
+// params - 範囲内の全てのパラメータの組み合わせ
+func (v OptimizerRanges) params(strategy Strategy) ([]OptimizerParams, error) {
+	baseWidths, err := v.BaseWidth.values(float64(strategy.GridStrategy.BaseWidth))
+	if err != nil {
+		return nil, err
+	}
+	numberOfGrids, err := v.NumberOfGrids.values(float64(strategy.GridStrategy.NumberOfGrids))
+	if err != nil {
+		return nil, err
+	}
+	quantities, err := v.Quantity.values(strategy.GridStrategy.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	prevDayRates, err := v.DynamicGridPrevDayRate.values(strategy.GridStrategy.DynamicGridPrevDay.Rate)
+	if err != nil {
+		return nil, err
+	}
+	prevDayNumberOfGrids, err := v.DynamicGridPrevDayNumberOfGrids.values(float64(strategy.GridStrategy.DynamicGridPrevDay.NumberOfGrids))
+	if err != nil {
+		return nil, err
+	}
+	minMaxDivides, err := v.DynamicGridMinMaxDivide.values(strategy.GridStrategy.DynamicGridMinMax.Divide)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]OptimizerParams, 0)
+	for _, bw := range baseWidths {
+		for _, ng := range numberOfGrids {
+			for _, q := range quantities {
+				for _, pr := range prevDayRates {
+					for _, png := range prevDayNumberOfGrids {
+						for _, d := range minMaxDivides {
+							params = append(params, OptimizerParams{
+								BaseWidth:                       int(bw),
+								NumberOfGrids:                   int(ng),
+								Quantity:                        q,
+								DynamicGridPrevDayRate:          pr,
+								DynamicGridPrevDayNumberOfGrids: int(png),
+								DynamicGridMinMaxDivide:         d,
+							})
+						}
+					}
+				}
+			}
+		}
+	}
+	return params, nil
+}
+
+// OptimizerRange - 探索する値の範囲
+// Stepが0なら範囲を探索せず戦略に設定されている値を使う
+type OptimizerRange struct {
+	Min  float64 // 最小値
+	Max  float64 // 最大値
+	Step float64 // 刻み幅
+}
+
+// values - 範囲内の値の一覧
+func (v OptimizerRange) values(current float64) ([]float64, error) {
+	if v.Step == 0 {
+		return []float64{current}, nil
+	}
+	if v.Step < 0 || v.Max < v.Min {
+		return nil, ErrInvalidRange
+	}
+
+	// 刻み幅の足し込みで誤差が溜まらないよう、何本目かから計算する
+	n := int(math.Floor((v.Max-v.Min)/v.Step+1e-9)) + 1
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.Round((v.Min+float64(i)*v.Step)*1e9) / 1e9
+	}
+	return values, nil
+}
+
+// OptimizerParams - 1回のバックテストで使うパラメータ
+type OptimizerParams struct {
+	BaseWidth                       int     // グリッド幅
+	NumberOfGrids                   int     // グリッドの本数
+	Quantity                        float64 // 1グリッドに乗せる数量
+	DynamicGridPrevDayRate          float64 // 前日の価格幅からの動的なグリッド幅の対象にする割合
+	DynamicGridPrevDayNumberOfGrids int     // 前日の価格幅に置くことを考えるグリッドの本数
+	DynamicGridMinMaxDivide         float64 // 最小・最大約定値の差を割る値
+}
+
+// apply - パラメータを戦略に反映したものを返す
+func (v OptimizerParams) apply(strategy Strategy) Strategy {
+	strategy.GridStrategy.BaseWidth = v.BaseWidth
+	strategy.GridStrategy.NumberOfGrids = v.NumberOfGrids
+	strategy.GridStrategy.Quantity = v.Quantity
+	strategy.GridStrategy.DynamicGridPrevDay.Rate = v.DynamicGridPrevDayRate
+	strategy.GridStrategy.DynamicGridPrevDay.NumberOfGrids = v.DynamicGridPrevDayNumberOfGrids
+	strategy.GridStrategy.DynamicGridMinMax.Divide = v.DynamicGridMinMaxDivide
+	return strategy
+}
+
+// OptimizerResult - 1つのパラメータでのバックテストの結果
+type OptimizerResult struct {
+	Params       OptimizerParams // パラメータ
+	NetProfit    float64         // 純損益
+	MaxDrawdown  float64         // 最大ドローダウン
+	TradeCount   int             // 約定回数
+	WarningCount int             // バックテスト中に各処理が返したエラーの数
+}
+
+// OptimizerReport - 最適化の結果
+type OptimizerReport struct {
+	Results []*OptimizerResult // 全期間での全てのパラメータの結果(順位順)
+	Folds   []*WalkForwardFold // ウォークフォワードの各回の結果
+}
+
+// WalkForwardFold - ウォークフォワードの1回分の結果
+// 最適化期間で最も良かったパラメータを、その直後の検証期間で動かした結果を持つ
+type WalkForwardFold struct {
+	InSampleStart    time.Time        // 最適化期間の開始日時
+	InSampleEnd      time.Time        // 最適化期間の終了日時
+	OutOfSampleStart time.Time        // 検証期間の開始日時
+	OutOfSampleEnd   time.Time        // 検証期間の終了日時
+	InSample         *OptimizerResult // 最適化期間で最も良かった結果
+	OutOfSample      *OptimizerResult // 最も良かったパラメータの検証期間での結果
+}
+
+// optimizerService - 最適化サービス
+type optimizerService struct {
+	backtestService IBacktestService
+}
+
+// Run - 最適化の実行
+// 全てのパラメータの組み合わせでバックテストを並列に実行して順位付けし、
+// ウォークフォワードの指定があれば期間を分割して最適化と検証を繰り返す
+func (s *optimizerService) Run(config OptimizerConfig) (*OptimizerReport, error) {
+	if config.Strategy.Code == "" || len(config.Prices) == 0 {
+		return nil, ErrNilArgument
+	}
+
+	params, err := config.Ranges.params(config.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make([]BacktestPrice, len(config.Prices))
+	copy(prices, config.Prices)
+	sort.SliceStable(prices, func(i, j int) bool {
+		return prices[i].DateTime.Before(prices[j].DateTime)
+	})
+
+	results, err := s.runAll(config, params, prices, config.FourPrices)
+	if err != nil {
+		return nil, err
+	}
+
+	folds, err := s.walkForward(config, params, prices)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OptimizerReport{Results: results, Folds: folds}, nil
+}
+
+// walkForward - 期間を日単位でWalkForwardSplits+1個に分割し、各期間で最適化して直後の期間で検証する
+func (s *optimizerService) walkForward(config OptimizerConfig, params []OptimizerParams, prices []BacktestPrice) ([]*WalkForwardFold, error) {
+	folds := make([]*WalkForwardFold, 0)
+	if config.WalkForwardSplits <= 0 {
+		return folds, nil
+	}
+
+	segments := s.splitByDay(prices, config.WalkForwardSplits+1)
+	if segments == nil {
+		return nil, ErrNotEnoughPrices
+	}
+
+	for i := 0; i < config.WalkForwardSplits; i++ {
+		inSample, outOfSample := segments[i], segments[i+1]
+
+		results, err := s.runAll(config, params, inSample, s.fourPricesBefore(config, prices, inSample[0].DateTime))
+		if err != nil {
+			return nil, err
+		}
+
+		best := results[0]
+		verified, err := s.runAll(config, []OptimizerParams{best.Params}, outOfSample, s.fourPricesBefore(config, prices, outOfSample[0].DateTime))
+		if err != nil {
+			return nil, err
+		}
+
+		folds = append(folds, &WalkForwardFold{
+			InSampleStart:    inSample[0].DateTime,
+			InSampleEnd:      inSample[len(inSample)-1].DateTime,
+			OutOfSampleStart: outOfSample[0].DateTime,
+			OutOfSampleEnd:   outOfSample[len(outOfSample)-1].DateTime,
+			InSample:         best,
+			OutOfSample:      verified[0],
+		})
+	}
+	return folds, nil
+}
+
+// splitByDay - 日時順の価格を、日をまたがないようにnum個の期間に分割する
+// 日数がnumに満たなければnilを返す
+func (s *optimizerService) splitByDay(prices []BacktestPrice, num int) [][]BacktestPrice {
+	// 各日の最初の価格の位置
+	starts := make([]int, 0)
+	for i, p := range prices {
+		if i == 0 || p.DateTime.Format("20060102") != prices[i-1].DateTime.Format("20060102") {
+			starts = append(starts, i)
+		}
+	}
+	if len(starts) < num {
+		return nil
+	}
+	starts = append(starts, len(prices))
+
+	days := len(starts) - 1
+	segments := make([][]BacktestPrice, num)
+	for i := range segments {
+		segments[i] = prices[starts[i*days/num]:starts[(i+1)*days/num]]
+	}
+	return segments
+}
+
+// fourPricesBefore - 指定した日時より前の四本値
+// 設定された四本値に、指定日時より前の日の価格から作った日足を加える
+func (s *optimizerService) fourPricesBefore(config OptimizerConfig, prices []BacktestPrice, dateTime time.Time) []*FourPrice {
+	fourPrices := make([]*FourPrice, len(config.FourPrices))
+	copy(fourPrices, config.FourPrices)
+
+	start := time.Date(dateTime.Year(), dateTime.Month(), dateTime.Day(), 0, 0, 0, 0, dateTime.Location())
+	var fourPrice *FourPrice
+	for _, p := range prices {
+		if !p.DateTime.Before(start) {
+			break
+		}
+
+		if fourPrice == nil || fourPrice.DateTime.Format("20060102") != p.DateTime.Format("20060102") {
+			fourPrice = &FourPrice{
+				SymbolCode: config.Strategy.SymbolCode,
+				Exchange:   config.Strategy.Exchange,
+				DateTime:   time.Date(p.DateTime.Year(), p.DateTime.Month(), p.DateTime.Day(), 15, 0, 0, 0, p.DateTime.Location()),
+				Open:       p.Price,
+				High:       p.Price,
+				Low:        p.Price,
+			}
+			fourPrices = append(fourPrices, fourPrice)
+		}
+		fourPrice.High = math.Max(fourPrice.High, p.Price)
+		fourPrice.Low = math.Min(fourPrice.Low, p.Price)
+		fourPrice.Close = p.Price
+	}
+	return fourPrices
+}
+
+// runAll - 全てのパラメータでバックテストを並列に実行し、順位順に並べて返す
+func (s *optimizerService) runAll(config OptimizerConfig, params []OptimizerParams, prices []BacktestPrice, fourPrices []*FourPrice) ([]*OptimizerResult, error) {
+	parallel := config.Parallel
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+
+	results := make([]*OptimizerResult, len(params))
+	errs := make([]error, len(params))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = s.run(config.Strategy, params[i], prices, fourPrices)
+			}
+		}()
+	}
+	for i := range params {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.rank(results, config.RankBy)
+	return results, nil
+}
+
+// run - 1つのパラメータでバックテストを実行する
+func (s *optimizerService) run(strategy Strategy, params OptimizerParams, prices []BacktestPrice, fourPrices []*FourPrice) (*OptimizerResult, error) {
+	res, err := s.backtestService.Run(BacktestConfig{Strategy: params.apply(strategy), FourPrices: fourPrices, Prices: prices})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OptimizerResult{
+		Params:       params,
+		NetProfit:    res.NetProfit,
+		MaxDrawdown:  res.MaxDrawdown,
+		TradeCount:   res.TradeCount,
+		WarningCount: len(res.Warnings),
+	}, nil
+}
+
+// rank - 結果を順位順に並べる
+// 基準が同じなら、純損益の大きい順、最大ドローダウンの小さい順、約定回数の多い順で比べる
+func (s *optimizerService) rank(results []*OptimizerResult, rankBy OptimizerRankBy) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch rankBy {
+		case OptimizerRankByMaxDrawdown:
+			if a.MaxDrawdown != b.MaxDrawdown {
+				return a.MaxDrawdown < b.MaxDrawdown
+			}
+		case OptimizerRankByTradeCount:
+			if a.TradeCount != b.TradeCount {
+				return a.TradeCount > b.TradeCount
+			}
+		}
+
+		if a.NetProfit != b.NetProfit {
+			return a.NetProfit > b.NetProfit
+		}
+		if a.MaxDrawdown != b.MaxDrawdown {
+			return a.MaxDrawdown < b.MaxDrawdown
+		}
+		return a.TradeCount > b.TradeCount
+	})
+}
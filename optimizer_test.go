@@ -0,0 +1,256 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testBacktestService struct {
+	IBacktestService
+	Run1       *BacktestResult
+	Run2       error
+	RunCount   int
+	RunHistory []interface{}
+	mtx        sync.Mutex
+}
+
+func (t *testBacktestService) Run(config BacktestConfig) (*BacktestResult, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.RunHistory = append(t.RunHistory, config)
+	t.RunCount++
+	return t.Run1, t.Run2
+}
+
+func Test_NewOptimizerService(t *testing.T) {
+	t.Parallel()
+	want1 := &optimizerService{backtestService: &backtestService{}}
+	got1 := NewOptimizerService()
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_OptimizerRange_values(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		rng   OptimizerRange
+		arg   float64
+		want1 []float64
+		want2 error
+	}{
+		{name: "Stepが0なら戦略の値だけ", rng: OptimizerRange{Min: 1, Max: 5}, arg: 3, want1: []float64{3}},
+		{name: "Stepが負ならエラー", rng: OptimizerRange{Min: 1, Max: 5, Step: -1}, want2: ErrInvalidRange},
+		{name: "MaxがMinより小さければエラー", rng: OptimizerRange{Min: 5, Max: 1, Step: 1}, want2: ErrInvalidRange},
+		{name: "MinからMaxまでStep刻みで返す", rng: OptimizerRange{Min: 1, Max: 5, Step: 2}, want1: []float64{1, 3, 5}},
+		{name: "Maxを超える値は含めない", rng: OptimizerRange{Min: 1, Max: 6, Step: 2}, want1: []float64{1, 3, 5}},
+		{name: "小数の刻みでも誤差が出ない", rng: OptimizerRange{Min: 0.1, Max: 0.3, Step: 0.1}, want1: []float64{0.1, 0.2, 0.3}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1, got2 := test.rng.values(test.arg)
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_OptimizerRanges_params(t *testing.T) {
+	t.Parallel()
+	strategy := Strategy{GridStrategy: GridStrategy{Quantity: 4, BaseWidth: 2, NumberOfGrids: 3,
+		DynamicGridPrevDay: DynamicGridPrevDay{Rate: 0.8, NumberOfGrids: 6}, DynamicGridMinMax: DynamicGridMinMax{Divide: 5}}}
+	ranges := OptimizerRanges{BaseWidth: OptimizerRange{Min: 1, Max: 2, Step: 1}, NumberOfGrids: OptimizerRange{Min: 2, Max: 4, Step: 2}}
+	want1 := []OptimizerParams{
+		{BaseWidth: 1, NumberOfGrids: 2, Quantity: 4, DynamicGridPrevDayRate: 0.8, DynamicGridPrevDayNumberOfGrids: 6, DynamicGridMinMaxDivide: 5},
+		{BaseWidth: 1, NumberOfGrids: 4, Quantity: 4, DynamicGridPrevDayRate: 0.8, DynamicGridPrevDayNumberOfGrids: 6, DynamicGridMinMaxDivide: 5},
+		{BaseWidth: 2, NumberOfGrids: 2, Quantity: 4, DynamicGridPrevDayRate: 0.8, DynamicGridPrevDayNumberOfGrids: 6, DynamicGridMinMaxDivide: 5},
+		{BaseWidth: 2, NumberOfGrids: 4, Quantity: 4, DynamicGridPrevDayRate: 0.8, DynamicGridPrevDayNumberOfGrids: 6, DynamicGridMinMaxDivide: 5},
+	}
+	got1, got2 := ranges.params(strategy)
+	if !reflect.DeepEqual(want1, got1) || got2 != nil {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v, %+v\n", t.Name(), want1, got1, got2)
+	}
+}
+
+func Test_OptimizerParams_apply(t *testing.T) {
+	t.Parallel()
+	params := OptimizerParams{BaseWidth: 1, NumberOfGrids: 2, Quantity: 3, DynamicGridPrevDayRate: 0.5, DynamicGridPrevDayNumberOfGrids: 4, DynamicGridMinMaxDivide: 5}
+	want1 := Strategy{Code: "strategy-code-001", GridStrategy: GridStrategy{Runnable: true, Quantity: 3, BaseWidth: 1, NumberOfGrids: 2,
+		DynamicGridPrevDay: DynamicGridPrevDay{Valid: true, Rate: 0.5, NumberOfGrids: 4}, DynamicGridMinMax: DynamicGridMinMax{Valid: true, Divide: 5}}}
+	got1 := params.apply(Strategy{Code: "strategy-code-001", GridStrategy: GridStrategy{Runnable: true,
+		DynamicGridPrevDay: DynamicGridPrevDay{Valid: true}, DynamicGridMinMax: DynamicGridMinMax{Valid: true}}})
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_optimizerService_splitByDay(t *testing.T) {
+	t.Parallel()
+	prices := []BacktestPrice{
+		{DateTime: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local), Price: 1},
+		{DateTime: time.Date(2022, 1, 24, 9, 1, 0, 0, time.Local), Price: 2},
+		{DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Price: 3},
+		{DateTime: time.Date(2022, 1, 26, 9, 0, 0, 0, time.Local), Price: 4},
+		{DateTime: time.Date(2022, 1, 26, 9, 1, 0, 0, time.Local), Price: 5},
+	}
+
+	tests := []struct {
+		name string
+		arg  int
+		want [][]BacktestPrice
+	}{
+		{name: "日数が分割数に満たなければnil", arg: 4, want: nil},
+		{name: "日数と分割数が同じなら1日ずつ", arg: 3, want: [][]BacktestPrice{prices[0:2], prices[2:3], prices[3:5]}},
+		{name: "日をまたがないように分割する", arg: 2, want: [][]BacktestPrice{prices[0:2], prices[2:5]}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &optimizerService{}
+			got := service.splitByDay(prices, test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_optimizerService_fourPricesBefore(t *testing.T) {
+	t.Parallel()
+	config := OptimizerConfig{
+		Strategy:   Strategy{SymbolCode: "1475", Exchange: ExchangeToushou},
+		FourPrices: []*FourPrice{{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 21, 15, 0, 0, 0, time.Local), Close: 1990}},
+	}
+	prices := []BacktestPrice{
+		{DateTime: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local), Price: 2000},
+		{DateTime: time.Date(2022, 1, 24, 9, 1, 0, 0, time.Local), Price: 2010},
+		{DateTime: time.Date(2022, 1, 24, 9, 2, 0, 0, time.Local), Price: 1995},
+		{DateTime: time.Date(2022, 1, 24, 9, 3, 0, 0, time.Local), Price: 2001},
+		{DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Price: 2005},
+	}
+	want1 := []*FourPrice{
+		{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 21, 15, 0, 0, 0, time.Local), Close: 1990},
+		{SymbolCode: "1475", Exchange: ExchangeToushou, DateTime: time.Date(2022, 1, 24, 15, 0, 0, 0, time.Local), Open: 2000, High: 2010, Low: 1995, Close: 2001},
+	}
+	got1 := (&optimizerService{}).fourPricesBefore(config, prices, time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local))
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_optimizerService_rank(t *testing.T) {
+	t.Parallel()
+	a := &OptimizerResult{Params: OptimizerParams{BaseWidth: 1}, NetProfit: 100, MaxDrawdown: 50, TradeCount: 10}
+	b := &OptimizerResult{Params: OptimizerParams{BaseWidth: 2}, NetProfit: 100, MaxDrawdown: 30, TradeCount: 5}
+	c := &OptimizerResult{Params: OptimizerParams{BaseWidth: 3}, NetProfit: 50, MaxDrawdown: 10, TradeCount: 20}
+
+	tests := []struct {
+		name string
+		arg  OptimizerRankBy
+		want []*OptimizerResult
+	}{
+		{name: "未指定なら純損益順で、同じなら最大ドローダウンの小さい順", arg: OptimizerRankByUnspecified, want: []*OptimizerResult{b, a, c}},
+		{name: "純損益順", arg: OptimizerRankByNetProfit, want: []*OptimizerResult{b, a, c}},
+		{name: "最大ドローダウンの小さい順", arg: OptimizerRankByMaxDrawdown, want: []*OptimizerResult{c, b, a}},
+		{name: "約定回数の多い順", arg: OptimizerRankByTradeCount, want: []*OptimizerResult{c, a, b}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := []*OptimizerResult{a, b, c}
+			(&optimizerService{}).rank(got, test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_optimizerService_Run(t *testing.T) {
+	t.Parallel()
+	prices := []BacktestPrice{
+		{DateTime: time.Date(2022, 1, 26, 9, 0, 0, 0, time.Local), Price: 3},
+		{DateTime: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local), Price: 1},
+		{DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Price: 2},
+	}
+	ranges := OptimizerRanges{BaseWidth: OptimizerRange{Min: 1, Max: 3, Step: 1}}
+
+	tests := []struct {
+		name            string
+		backtestService *testBacktestService
+		arg             OptimizerConfig
+		wantResults     int
+		wantFolds       []*WalkForwardFold
+		wantRunCount    int
+		wantErr         error
+	}{
+		{name: "価格がなければエラー",
+			backtestService: &testBacktestService{},
+			arg:             OptimizerConfig{Strategy: Strategy{Code: "strategy-code-001"}},
+			wantErr:         ErrNilArgument},
+		{name: "範囲が不正ならエラー",
+			backtestService: &testBacktestService{},
+			arg:             OptimizerConfig{Strategy: Strategy{Code: "strategy-code-001"}, Prices: prices, Ranges: OptimizerRanges{BaseWidth: OptimizerRange{Min: 3, Max: 1, Step: 1}}},
+			wantErr:         ErrInvalidRange},
+		{name: "バックテストがエラーを返したらエラー",
+			backtestService: &testBacktestService{Run2: ErrNilArgument},
+			arg:             OptimizerConfig{Strategy: Strategy{Code: "strategy-code-001"}, Prices: prices, Ranges: ranges},
+			wantRunCount:    3,
+			wantErr:         ErrNilArgument},
+		{name: "ウォークフォワードの分割数より日数が少なければエラー",
+			backtestService: &testBacktestService{Run1: &BacktestResult{}},
+			arg:             OptimizerConfig{Strategy: Strategy{Code: "strategy-code-001"}, Prices: prices, Ranges: ranges, WalkForwardSplits: 3},
+			wantRunCount:    3,
+			wantErr:         ErrNotEnoughPrices},
+		{name: "全ての組み合わせでバックテストする",
+			backtestService: &testBacktestService{Run1: &BacktestResult{}},
+			arg:             OptimizerConfig{Strategy: Strategy{Code: "strategy-code-001"}, Prices: prices, Ranges: ranges, Parallel: 2},
+			wantResults:     3,
+			wantFolds:       []*WalkForwardFold{},
+			wantRunCount:    3},
+		{name: "ウォークフォワードでは期間ごとに最適化と検証をする",
+			backtestService: &testBacktestService{Run1: &BacktestResult{}},
+			arg:             OptimizerConfig{Strategy: Strategy{Code: "strategy-code-001"}, Prices: prices, Ranges: ranges, WalkForwardSplits: 2},
+			wantResults:     3,
+			wantFolds: []*WalkForwardFold{
+				{InSampleStart: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local), InSampleEnd: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local),
+					OutOfSampleStart: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), OutOfSampleEnd: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local),
+					InSample: &OptimizerResult{Params: OptimizerParams{BaseWidth: 1}}, OutOfSample: &OptimizerResult{Params: OptimizerParams{BaseWidth: 1}}},
+				{InSampleStart: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), InSampleEnd: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local),
+					OutOfSampleStart: time.Date(2022, 1, 26, 9, 0, 0, 0, time.Local), OutOfSampleEnd: time.Date(2022, 1, 26, 9, 0, 0, 0, time.Local),
+					InSample: &OptimizerResult{Params: OptimizerParams{BaseWidth: 1}}, OutOfSample: &OptimizerResult{Params: OptimizerParams{BaseWidth: 1}}},
+			},
+			wantRunCount: 3 + (3+1)*2},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &optimizerService{backtestService: test.backtestService}
+			got, err := service.Run(test.arg)
+			if !errors.Is(err, test.wantErr) || test.wantRunCount != test.backtestService.RunCount {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.wantErr, test.wantRunCount, err, test.backtestService.RunCount)
+			}
+			if err != nil {
+				return
+			}
+
+			if test.wantResults != len(got.Results) || !reflect.DeepEqual(test.wantFolds, got.Folds) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.wantResults, test.wantFolds, len(got.Results), got.Folds)
+			}
+		})
+	}
+}
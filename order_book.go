@@ -0,0 +1,352 @@
+package gridon
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"gitlab.com/tsuchinaga/kabus-grpc-server/kabuspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newOrderBook - 新しい疑似注文板の取得
+// prefixは注文コードや約定コードの接頭辞で、実際の証券会社のコードと重複しないようにするために使う
+func newOrderBook(prefix string) *orderBook {
+	return &orderBook{
+		prefix: prefix,
+		orders: []*bookOrder{},
+	}
+}
+
+// orderBook - 疑似注文板
+// 証券会社に注文を送らずに手元で保持し、価格の変化に合わせて約定させる
+type orderBook struct {
+	prefix      string
+	orders      []*bookOrder
+	orderSeq    int
+	contractSeq int
+	mtx         sync.Mutex
+}
+
+// bookOrder - 疑似注文板にある注文
+type bookOrder struct {
+	SecurityOrder
+	ExecutionType  ExecutionType  // 執行条件
+	HoldPositions  []HoldPosition // 返済するポジション
+	UpdateDateTime time.Time      // 更新日時
+}
+
+// errCancelOrder - 取り消せない注文に対する取消のエラー
+// kabuステーションAPIと同じ形でエラーを返すことで、取消エラーのハンドリングをそのまま使えるようにする
+func (b *orderBook) errCancelOrder(orderCode string) error {
+	st, err := status.New(codes.InvalidArgument, fmt.Sprintf("can not cancel order: %s", orderCode)).
+		WithDetails(&kabuspb.RequestError{StatusCode: 400, Code: 43, Message: "取消できない注文です"})
+	if err != nil {
+		return ErrCancelCondition
+	}
+	return st.Err()
+}
+
+// Send - 注文を受け付ける
+// 成行注文は受付時点の売り気配値・買い気配値で約定させる
+func (b *orderBook) Send(order *Order, now time.Time, bidPrice float64, askPrice float64) (OrderResult, error) {
+	if order == nil {
+		return OrderResult{}, ErrNilArgument
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.orderSeq++
+	o := &bookOrder{
+		SecurityOrder: SecurityOrder{
+			Code:            fmt.Sprintf("%s-order-%06d", b.prefix, b.orderSeq),
+			Status:          OrderStatusInOrder,
+			SymbolCode:      order.SymbolCode,
+			Exchange:        order.Exchange,
+			Product:         order.Product,
+			MarginTradeType: order.MarginTradeType,
+			TradeType:       order.TradeType,
+			Side:            order.Side,
+			Price:           order.Price,
+			TriggerPrice:    order.TriggerPrice,
+			OrderQuantity:   order.OrderQuantity,
+			AccountType:     order.AccountType,
+			ExpireDay:       b.expireDay(order.ExpireDay, now),
+			OrderDateTime:   now,
+			Contracts:       []Contract{},
+		},
+		ExecutionType:  order.ExecutionType,
+		UpdateDateTime: now,
+	}
+	if order.HoldPositions != nil {
+		o.HoldPositions = make([]HoldPosition, len(order.HoldPositions))
+		copy(o.HoldPositions, order.HoldPositions)
+	}
+	b.orders = append(b.orders, o)
+
+	if o.ExecutionType == ExecutionTypeMarket {
+		switch o.Side {
+		case SideBuy:
+			b.contract(o, askPrice, now)
+		case SideSell:
+			b.contract(o, bidPrice, now)
+		}
+	}
+
+	return OrderResult{Result: true, OrderCode: o.Code}, nil
+}
+
+// Cancel - 注文を取り消す
+func (b *orderBook) Cancel(orderCode string, now time.Time) (OrderResult, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for _, o := range b.orders {
+		if o.Code != orderCode {
+			continue
+		}
+		if o.Status != OrderStatusInOrder {
+			return OrderResult{}, b.errCancelOrder(orderCode)
+		}
+
+		o.Status = OrderStatusCanceled
+		o.CancelDateTime = now
+		o.UpdateDateTime = now
+		return OrderResult{Result: true, OrderCode: orderCode}, nil
+	}
+
+	return OrderResult{}, b.errCancelOrder(orderCode)
+}
+
+// Match - 現在値で約定判定をする
+// 指値注文は現在値が指値に達したら指値で約定させ、引成注文は引けの時刻を過ぎたら現在値で約定させる
+// 逆指値注文は現在値が発火価格に達したら、成行なら現在値で約定させ、指値なら指値注文に切り替えて約定判定をする
+func (b *orderBook) Match(symbolCode string, exchange Exchange, price float64, now time.Time) {
+	if price <= 0 {
+		return
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for _, o := range b.orders {
+		if o.Status != OrderStatusInOrder || o.SymbolCode != symbolCode || o.Exchange != exchange {
+			continue
+		}
+
+		// 発火した逆指値(指値)は指値注文として扱う
+		if o.ExecutionType == ExecutionTypeStopLimit && b.triggered(o, price) {
+			o.ExecutionType = ExecutionTypeLimit
+			o.UpdateDateTime = now
+		}
+
+		switch o.ExecutionType {
+		case ExecutionTypeStopMarket:
+			if b.triggered(o, price) {
+				b.contract(o, price, now)
+			}
+		case ExecutionTypeLimit:
+			if (o.Side == SideBuy && price <= o.Price) || (o.Side == SideSell && o.Price <= price) {
+				b.contract(o, o.Price, now)
+			}
+		case ExecutionTypeMarket:
+			b.contract(o, price, now)
+		case ExecutionTypeMarketMorningClose:
+			if !now.Before(time.Date(now.Year(), now.Month(), now.Day(), 11, 30, 0, 0, now.Location())) {
+				b.contract(o, price, now)
+			}
+		case ExecutionTypeMarketAfternoonClose:
+			if !now.Before(time.Date(now.Year(), now.Month(), now.Day(), 15, 0, 0, 0, now.Location())) {
+				b.contract(o, price, now)
+			}
+		}
+	}
+}
+
+// expireDay - 注文の有効期限日
+// 注文で指定されていればその日、指定されていなければ当日
+func (b *orderBook) expireDay(expireDay time.Time, now time.Time) time.Time {
+	if expireDay.IsZero() {
+		expireDay = now
+	}
+	return time.Date(expireDay.Year(), expireDay.Month(), expireDay.Day(), 0, 0, 0, 0, expireDay.Location())
+}
+
+// triggered - 逆指値注文が現在値で発火するか
+// 買いは現在値が発火価格以上、売りは現在値が発火価格以下で発火する
+func (b *orderBook) triggered(order *bookOrder, price float64) bool {
+	return (order.Side == SideBuy && order.TriggerPrice <= price) || (order.Side == SideSell && price <= order.TriggerPrice)
+}
+
+// Expire - 有効期限日の引けを過ぎた注文を失効させる
+func (b *orderBook) Expire(now time.Time) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for _, o := range b.orders {
+		closing := time.Date(o.ExpireDay.Year(), o.ExpireDay.Month(), o.ExpireDay.Day(), 15, 0, 0, 0, o.ExpireDay.Location())
+		if o.Status != OrderStatusInOrder || now.Before(closing) {
+			continue
+		}
+		o.Status = OrderStatusCanceled
+		o.CancelDateTime = now
+		o.UpdateDateTime = now
+	}
+}
+
+// Has - 指定したコードの注文が疑似注文板にあるか
+func (b *orderBook) Has(orderCode string) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for _, o := range b.orders {
+		if o.Code == orderCode {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveSymbols - 注文中の注文がある銘柄の一覧
+func (b *orderBook) ActiveSymbols() []SymbolKey {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	symbols := make([]SymbolKey, 0)
+	exists := map[SymbolKey]bool{}
+	for _, o := range b.orders {
+		key := SymbolKey{SymbolCode: o.SymbolCode, Exchange: o.Exchange}
+		if o.Status != OrderStatusInOrder || exists[key] {
+			continue
+		}
+		exists[key] = true
+		symbols = append(symbols, key)
+	}
+	return symbols
+}
+
+// contract - 注文の残数量を全て約定させる
+func (b *orderBook) contract(order *bookOrder, price float64, now time.Time) {
+	quantity := order.OrderQuantity - order.ContractQuantity
+	if quantity <= 0 {
+		return
+	}
+
+	b.contractSeq++
+	order.Contracts = append(order.Contracts, Contract{
+		OrderCode:        order.Code,
+		PositionCode:     fmt.Sprintf("%s-contract-%06d", b.prefix, b.contractSeq),
+		Price:            price,
+		Quantity:         quantity,
+		ContractDateTime: now,
+	})
+	order.ContractQuantity += quantity
+	order.Status = OrderStatusDone
+	order.ContractDateTime = now
+	order.UpdateDateTime = now
+}
+
+// Orders - 注文一覧の取得
+// 更新日時が指定された日時以降の注文を返す
+func (b *orderBook) Orders(product Product, symbolCode string, updateDateTime time.Time) []SecurityOrder {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	orders := make([]SecurityOrder, 0)
+	for _, o := range b.orders {
+		if o.Product != product || o.SymbolCode != symbolCode || o.UpdateDateTime.Before(updateDateTime) {
+			continue
+		}
+
+		so := o.SecurityOrder
+		so.ExecutionType = o.ExecutionType
+		so.Contracts = make([]Contract, len(o.Contracts))
+		copy(so.Contracts, o.Contracts)
+		orders = append(orders, so)
+	}
+	return orders
+}
+
+// Positions - 約定した注文から保有中のポジションを組み立てる
+// 返済注文は拘束したポジションから数量を減らし、拘束したポジションがなければ古いポジションから減らす
+// 商品種別が未指定なら全ての商品種別のポジションを返す
+func (b *orderBook) Positions(product Product, symbolCode string) []SecurityPosition {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	// エントリーの約定からポジションを作る
+	positions := make([]*SecurityPosition, 0)
+	store := map[string]*SecurityPosition{}
+	exits := make([]*bookOrder, 0)
+	for _, o := range b.orders {
+		if (product != ProductUnspecified && o.Product != product) || o.SymbolCode != symbolCode {
+			continue
+		}
+
+		switch o.TradeType {
+		case TradeTypeEntry:
+			for _, c := range o.Contracts {
+				p := &SecurityPosition{
+					Code:             c.PositionCode,
+					SymbolCode:       o.SymbolCode,
+					Exchange:         o.Exchange,
+					Product:          o.Product,
+					MarginTradeType:  o.MarginTradeType,
+					Side:             o.Side,
+					Price:            c.Price,
+					OwnedQuantity:    c.Quantity,
+					ContractDateTime: c.ContractDateTime,
+				}
+				positions = append(positions, p)
+				store[p.Code] = p
+			}
+		case TradeTypeExit:
+			exits = append(exits, o)
+		}
+	}
+	sort.SliceStable(positions, func(i, j int) bool { return positions[i].ContractDateTime.Before(positions[j].ContractDateTime) })
+
+	// エグジットの注文と約定をポジションに反映する
+	for _, o := range exits {
+		if len(o.HoldPositions) > 0 {
+			for _, hp := range o.HoldPositions {
+				p, ok := store[hp.PositionCode]
+				if !ok {
+					continue
+				}
+				switch o.Status {
+				case OrderStatusInOrder:
+					p.HoldQuantity += hp.HoldQuantity
+				case OrderStatusDone:
+					p.OwnedQuantity -= hp.HoldQuantity
+				}
+			}
+			continue
+		}
+
+		quantity := o.ContractQuantity
+		for _, p := range positions {
+			if quantity <= 0 {
+				break
+			}
+			if p.Exchange != o.Exchange || p.Product != o.Product || p.Side != o.Side.Turn() || p.OwnedQuantity <= 0 {
+				continue
+			}
+			q := math.Min(quantity, p.OwnedQuantity)
+			p.OwnedQuantity -= q
+			quantity -= q
+		}
+	}
+
+	res := make([]SecurityPosition, 0)
+	for _, p := range positions {
+		if p.OwnedQuantity > 0 {
+			res = append(res, *p)
+		}
+	}
+	return res
+}
@@ -0,0 +1,368 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gitlab.com/tsuchinaga/kabus-grpc-server/kabuspb"
+	"google.golang.org/grpc/status"
+)
+
+func Test_newOrderBook(t *testing.T) {
+	t.Parallel()
+	want1 := &orderBook{prefix: "paper", orders: []*bookOrder{}}
+	got1 := newOrderBook("paper")
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_orderBook_Send(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name       string
+		arg1       *Order
+		want1      OrderResult
+		want2      error
+		wantOrders []SecurityOrder
+	}{
+		{name: "注文がnilならエラー",
+			arg1:       nil,
+			want1:      OrderResult{},
+			want2:      ErrNilArgument,
+			wantOrders: []SecurityOrder{}},
+		{name: "指値注文は注文中で受け付けられる",
+			arg1:  &Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, TradeType: TradeTypeEntry, Side: SideBuy, ExecutionType: ExecutionTypeLimit, Price: 2000, OrderQuantity: 3},
+			want1: OrderResult{Result: true, OrderCode: "test-order-000001"},
+			want2: nil,
+			wantOrders: []SecurityOrder{
+				{Code: "test-order-000001", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, TradeType: TradeTypeEntry, Side: SideBuy, ExecutionType: ExecutionTypeLimit, Price: 2000, OrderQuantity: 3, ExpireDay: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local), OrderDateTime: now, Contracts: []Contract{}},
+			}},
+		{name: "有効期限日が指定されていれば、その日を有効期限日にする",
+			arg1:  &Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, TradeType: TradeTypeEntry, Side: SideBuy, ExecutionType: ExecutionTypeLimit, Price: 2000, OrderQuantity: 3, ExpireDay: time.Date(2022, 1, 27, 0, 0, 0, 0, time.Local)},
+			want1: OrderResult{Result: true, OrderCode: "test-order-000001"},
+			want2: nil,
+			wantOrders: []SecurityOrder{
+				{Code: "test-order-000001", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, TradeType: TradeTypeEntry, Side: SideBuy, ExecutionType: ExecutionTypeLimit, Price: 2000, OrderQuantity: 3, ExpireDay: time.Date(2022, 1, 27, 0, 0, 0, 0, time.Local), OrderDateTime: now, Contracts: []Contract{}},
+			}},
+		{name: "買いの成行注文は売り気配値で約定する",
+			arg1:  &Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, TradeType: TradeTypeEntry, Side: SideBuy, ExecutionType: ExecutionTypeMarket, OrderQuantity: 3},
+			want1: OrderResult{Result: true, OrderCode: "test-order-000001"},
+			want2: nil,
+			wantOrders: []SecurityOrder{
+				{Code: "test-order-000001", Status: OrderStatusDone, SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, TradeType: TradeTypeEntry, Side: SideBuy, ExecutionType: ExecutionTypeMarket, OrderQuantity: 3, ContractQuantity: 3, ExpireDay: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local), OrderDateTime: now, ContractDateTime: now,
+					Contracts: []Contract{{OrderCode: "test-order-000001", PositionCode: "test-contract-000001", Price: 2001, Quantity: 3, ContractDateTime: now}}},
+			}},
+		{name: "売りの成行注文は買い気配値で約定する",
+			arg1:  &Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeMarket, OrderQuantity: 3},
+			want1: OrderResult{Result: true, OrderCode: "test-order-000001"},
+			want2: nil,
+			wantOrders: []SecurityOrder{
+				{Code: "test-order-000001", Status: OrderStatusDone, SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeMarket, OrderQuantity: 3, ContractQuantity: 3, ExpireDay: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local), OrderDateTime: now, ContractDateTime: now,
+					Contracts: []Contract{{OrderCode: "test-order-000001", PositionCode: "test-contract-000001", Price: 1999, Quantity: 3, ContractDateTime: now}}},
+			}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			book := newOrderBook("test")
+			got1, got2 := book.Send(test.arg1, now, 1999, 2001)
+			gotOrders := book.Orders(ProductMargin, "1475", time.Time{})
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) || !reflect.DeepEqual(test.wantOrders, gotOrders) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(), test.want1, test.want2, test.wantOrders, got1, got2, gotOrders)
+			}
+		})
+	}
+}
+
+func Test_orderBook_Cancel(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name       string
+		orders     []*bookOrder
+		arg1       string
+		want1      OrderResult
+		wantCode   int32
+		wantStatus OrderStatus
+	}{
+		{name: "注文がなければ取消できないエラー",
+			orders:   []*bookOrder{},
+			arg1:     "test-order-000001",
+			want1:    OrderResult{},
+			wantCode: 43},
+		{name: "注文中でなければ取消できないエラー",
+			orders:     []*bookOrder{{SecurityOrder: SecurityOrder{Code: "test-order-000001", Status: OrderStatusDone}}},
+			arg1:       "test-order-000001",
+			want1:      OrderResult{},
+			wantCode:   43,
+			wantStatus: OrderStatusDone},
+		{name: "注文中なら取消済みにする",
+			orders:     []*bookOrder{{SecurityOrder: SecurityOrder{Code: "test-order-000001", Status: OrderStatusInOrder}}},
+			arg1:       "test-order-000001",
+			want1:      OrderResult{Result: true, OrderCode: "test-order-000001"},
+			wantStatus: OrderStatusCanceled},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			book := &orderBook{orders: test.orders}
+			got1, got2 := book.Cancel(test.arg1, now)
+
+			var gotCode int32
+			if st, ok := status.FromError(got2); ok {
+				for _, d := range st.Details() {
+					if e, ok := d.(*kabuspb.RequestError); ok {
+						gotCode = e.Code
+					}
+				}
+			}
+			var gotStatus OrderStatus
+			if len(book.orders) > 0 {
+				gotStatus = book.orders[0].Status
+			}
+
+			if !reflect.DeepEqual(test.want1, got1) || test.wantCode != gotCode || test.wantStatus != gotStatus {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(), test.want1, test.wantCode, test.wantStatus, got1, gotCode, gotStatus)
+			}
+		})
+	}
+}
+
+func Test_orderBook_Match(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		order         *bookOrder
+		arg1          float64
+		arg2          time.Time
+		wantStatus    OrderStatus
+		wantContracts []Contract
+	}{
+		{name: "買い指値は価格が指値以下になったら指値で約定する",
+			order:      &bookOrder{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Side: SideBuy, Price: 2000, OrderQuantity: 2, Contracts: []Contract{}}, ExecutionType: ExecutionTypeLimit},
+			arg1:       1998,
+			arg2:       time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local),
+			wantStatus: OrderStatusDone,
+			wantContracts: []Contract{
+				{OrderCode: "o1", PositionCode: "test-contract-000001", Price: 2000, Quantity: 2, ContractDateTime: time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)}}},
+		{name: "買い指値は価格が指値より高ければ約定しない",
+			order:         &bookOrder{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Side: SideBuy, Price: 2000, OrderQuantity: 2, Contracts: []Contract{}}, ExecutionType: ExecutionTypeLimit},
+			arg1:          2001,
+			arg2:          time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local),
+			wantStatus:    OrderStatusInOrder,
+			wantContracts: []Contract{}},
+		{name: "売り指値は価格が指値以上になったら指値で約定する",
+			order:      &bookOrder{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Side: SideSell, Price: 2000, OrderQuantity: 2, Contracts: []Contract{}}, ExecutionType: ExecutionTypeLimit},
+			arg1:       2000,
+			arg2:       time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local),
+			wantStatus: OrderStatusDone,
+			wantContracts: []Contract{
+				{OrderCode: "o1", PositionCode: "test-contract-000001", Price: 2000, Quantity: 2, ContractDateTime: time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)}}},
+		{name: "後場引成は15時前なら約定しない",
+			order:         &bookOrder{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Side: SideSell, OrderQuantity: 2, Contracts: []Contract{}}, ExecutionType: ExecutionTypeMarketAfternoonClose},
+			arg1:          2000,
+			arg2:          time.Date(2022, 1, 25, 14, 59, 59, 0, time.Local),
+			wantStatus:    OrderStatusInOrder,
+			wantContracts: []Contract{}},
+		{name: "後場引成は15時以降なら現在値で約定する",
+			order:      &bookOrder{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Side: SideSell, OrderQuantity: 2, Contracts: []Contract{}}, ExecutionType: ExecutionTypeMarketAfternoonClose},
+			arg1:       2003,
+			arg2:       time.Date(2022, 1, 25, 15, 0, 0, 0, time.Local),
+			wantStatus: OrderStatusDone,
+			wantContracts: []Contract{
+				{OrderCode: "o1", PositionCode: "test-contract-000001", Price: 2003, Quantity: 2, ContractDateTime: time.Date(2022, 1, 25, 15, 0, 0, 0, time.Local)}}},
+		{name: "売りの逆指値(成行)は価格が発火価格より高ければ約定しない",
+			order:         &bookOrder{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Side: SideSell, TriggerPrice: 1990, OrderQuantity: 2, Contracts: []Contract{}}, ExecutionType: ExecutionTypeStopMarket},
+			arg1:          1991,
+			arg2:          time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local),
+			wantStatus:    OrderStatusInOrder,
+			wantContracts: []Contract{}},
+		{name: "売りの逆指値(成行)は価格が発火価格以下になったら現在値で約定する",
+			order:      &bookOrder{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Side: SideSell, TriggerPrice: 1990, OrderQuantity: 2, Contracts: []Contract{}}, ExecutionType: ExecutionTypeStopMarket},
+			arg1:       1980,
+			arg2:       time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local),
+			wantStatus: OrderStatusDone,
+			wantContracts: []Contract{
+				{OrderCode: "o1", PositionCode: "test-contract-000001", Price: 1980, Quantity: 2, ContractDateTime: time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)}}},
+		{name: "買いの逆指値(成行)は価格が発火価格以上になったら現在値で約定する",
+			order:      &bookOrder{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Side: SideBuy, TriggerPrice: 2010, OrderQuantity: 2, Contracts: []Contract{}}, ExecutionType: ExecutionTypeStopMarket},
+			arg1:       2010,
+			arg2:       time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local),
+			wantStatus: OrderStatusDone,
+			wantContracts: []Contract{
+				{OrderCode: "o1", PositionCode: "test-contract-000001", Price: 2010, Quantity: 2, ContractDateTime: time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)}}},
+		{name: "売りの逆指値(指値)は発火しても指値に届かなければ約定しない",
+			order:         &bookOrder{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Side: SideSell, Price: 1992, TriggerPrice: 1995, OrderQuantity: 2, Contracts: []Contract{}}, ExecutionType: ExecutionTypeStopLimit},
+			arg1:          1980,
+			arg2:          time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local),
+			wantStatus:    OrderStatusInOrder,
+			wantContracts: []Contract{}},
+		{name: "売りの逆指値(指値)は発火して指値に届いたら指値で約定する",
+			order:      &bookOrder{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Side: SideSell, Price: 1990, TriggerPrice: 1995, OrderQuantity: 2, Contracts: []Contract{}}, ExecutionType: ExecutionTypeStopLimit},
+			arg1:       1994,
+			arg2:       time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local),
+			wantStatus: OrderStatusDone,
+			wantContracts: []Contract{
+				{OrderCode: "o1", PositionCode: "test-contract-000001", Price: 1990, Quantity: 2, ContractDateTime: time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)}}},
+		{name: "別の銘柄の注文は約定しない",
+			order:         &bookOrder{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1476", Exchange: ExchangeToushou, Side: SideBuy, Price: 2000, OrderQuantity: 2, Contracts: []Contract{}}, ExecutionType: ExecutionTypeLimit},
+			arg1:          1998,
+			arg2:          time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local),
+			wantStatus:    OrderStatusInOrder,
+			wantContracts: []Contract{}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			book := &orderBook{prefix: "test", orders: []*bookOrder{test.order}}
+			book.Match("1475", ExchangeToushou, test.arg1, test.arg2)
+			if test.wantStatus != test.order.Status || !reflect.DeepEqual(test.wantContracts, test.order.Contracts) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.wantStatus, test.wantContracts, test.order.Status, test.order.Contracts)
+			}
+		})
+	}
+}
+
+func Test_orderBook_Expire(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		arg1  time.Time
+		want1 []OrderStatus
+	}{
+		{name: "有効期限日の引け前なら失効させない",
+			arg1:  time.Date(2022, 1, 25, 14, 59, 59, 0, time.Local),
+			want1: []OrderStatus{OrderStatusInOrder, OrderStatusInOrder, OrderStatusDone}},
+		{name: "有効期限日の引けを過ぎたら失効させる",
+			arg1:  time.Date(2022, 1, 25, 15, 0, 0, 0, time.Local),
+			want1: []OrderStatus{OrderStatusCanceled, OrderStatusInOrder, OrderStatusDone}},
+		{name: "有効期限日の翌日以降なら、引け前でも失効させる",
+			arg1:  time.Date(2022, 1, 26, 9, 0, 0, 0, time.Local),
+			want1: []OrderStatus{OrderStatusCanceled, OrderStatusInOrder, OrderStatusDone}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			book := &orderBook{orders: []*bookOrder{
+				{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, ExpireDay: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local)}},
+				{SecurityOrder: SecurityOrder{Code: "o2", Status: OrderStatusInOrder, ExpireDay: time.Date(2022, 1, 26, 0, 0, 0, 0, time.Local)}},
+				{SecurityOrder: SecurityOrder{Code: "o3", Status: OrderStatusDone, ExpireDay: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local)}},
+			}}
+			book.Expire(test.arg1)
+
+			got1 := []OrderStatus{book.orders[0].Status, book.orders[1].Status, book.orders[2].Status}
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_orderBook_Orders(t *testing.T) {
+	t.Parallel()
+	book := &orderBook{orders: []*bookOrder{
+		{SecurityOrder: SecurityOrder{Code: "o1", Product: ProductMargin, SymbolCode: "1475", Contracts: []Contract{}}, UpdateDateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)},
+		{SecurityOrder: SecurityOrder{Code: "o2", Product: ProductMargin, SymbolCode: "1475", Contracts: []Contract{}}, UpdateDateTime: time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)},
+		{SecurityOrder: SecurityOrder{Code: "o3", Product: ProductStock, SymbolCode: "1475", Contracts: []Contract{}}, UpdateDateTime: time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)},
+		{SecurityOrder: SecurityOrder{Code: "o4", Product: ProductMargin, SymbolCode: "1476", Contracts: []Contract{}}, UpdateDateTime: time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)},
+	}}
+	want1 := []SecurityOrder{{Code: "o2", Product: ProductMargin, SymbolCode: "1475", Contracts: []Contract{}}}
+	got1 := book.Orders(ProductMargin, "1475", time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local))
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_orderBook_Has(t *testing.T) {
+	t.Parallel()
+	book := &orderBook{orders: []*bookOrder{{SecurityOrder: SecurityOrder{Code: "o1"}}}}
+	want1 := []bool{true, false}
+	got1 := []bool{book.Has("o1"), book.Has("o2")}
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_orderBook_ActiveSymbols(t *testing.T) {
+	t.Parallel()
+	book := &orderBook{orders: []*bookOrder{
+		{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou}},
+		{SecurityOrder: SecurityOrder{Code: "o2", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou}},
+		{SecurityOrder: SecurityOrder{Code: "o3", Status: OrderStatusDone, SymbolCode: "1476", Exchange: ExchangeToushou}},
+		{SecurityOrder: SecurityOrder{Code: "o4", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeMeishou}},
+	}}
+	want1 := []SymbolKey{{SymbolCode: "1475", Exchange: ExchangeToushou}, {SymbolCode: "1475", Exchange: ExchangeMeishou}}
+	got1 := book.ActiveSymbols()
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_orderBook_Positions(t *testing.T) {
+	t.Parallel()
+	book := &orderBook{orders: []*bookOrder{
+		{SecurityOrder: SecurityOrder{Code: "o1", Status: OrderStatusDone, Product: ProductMargin, SymbolCode: "1475", Exchange: ExchangeToushou, TradeType: TradeTypeEntry, Side: SideBuy,
+			Contracts: []Contract{{PositionCode: "c1", Price: 2000, Quantity: 3, ContractDateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)}}}},
+		{SecurityOrder: SecurityOrder{Code: "o2", Status: OrderStatusDone, Product: ProductMargin, SymbolCode: "1475", Exchange: ExchangeToushou, TradeType: TradeTypeEntry, Side: SideBuy,
+			Contracts: []Contract{{PositionCode: "c2", Price: 1999, Quantity: 2, ContractDateTime: time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local)}}}},
+		{SecurityOrder: SecurityOrder{Code: "o3", Status: OrderStatusDone, Product: ProductMargin, SymbolCode: "1475", Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell, ContractQuantity: 1},
+			HoldPositions: []HoldPosition{{PositionCode: "c2", HoldQuantity: 1}}},
+		{SecurityOrder: SecurityOrder{Code: "o4", Status: OrderStatusInOrder, Product: ProductMargin, SymbolCode: "1475", Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell},
+			HoldPositions: []HoldPosition{{PositionCode: "c2", HoldQuantity: 1}}},
+		{SecurityOrder: SecurityOrder{Code: "o5", Status: OrderStatusDone, Product: ProductMargin, SymbolCode: "1475", Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell, ContractQuantity: 2}},
+		{SecurityOrder: SecurityOrder{Code: "o6", Status: OrderStatusDone, Product: ProductStock, SymbolCode: "1475", Exchange: ExchangeToushou, TradeType: TradeTypeEntry, Side: SideBuy,
+			Contracts: []Contract{{PositionCode: "c3", Price: 1998, Quantity: 1, ContractDateTime: time.Date(2022, 1, 25, 9, 2, 0, 0, time.Local)}}}},
+		{SecurityOrder: SecurityOrder{Code: "o7", Status: OrderStatusDone, Product: ProductMargin, SymbolCode: "1476", Exchange: ExchangeToushou, TradeType: TradeTypeEntry, Side: SideBuy,
+			Contracts: []Contract{{PositionCode: "c4", Price: 1000, Quantity: 1, ContractDateTime: time.Date(2022, 1, 25, 9, 3, 0, 0, time.Local)}}}},
+	}}
+
+	tests := []struct {
+		name  string
+		arg1  Product
+		arg2  string
+		want1 []SecurityPosition
+	}{
+		{name: "返済の約定は拘束したポジションから減らし、拘束がなければ古いポジションから減らす",
+			arg1: ProductMargin,
+			arg2: "1475",
+			want1: []SecurityPosition{
+				{Code: "c1", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, Side: SideBuy, Price: 2000, OwnedQuantity: 1, ContractDateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)},
+				{Code: "c2", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, Side: SideBuy, Price: 1999, OwnedQuantity: 1, HoldQuantity: 1, ContractDateTime: time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local)},
+			}},
+		{name: "商品種別が未指定なら全ての商品種別のポジションを返す",
+			arg1: ProductUnspecified,
+			arg2: "1475",
+			want1: []SecurityPosition{
+				{Code: "c1", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, Side: SideBuy, Price: 2000, OwnedQuantity: 1, ContractDateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)},
+				{Code: "c2", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, Side: SideBuy, Price: 1999, OwnedQuantity: 1, HoldQuantity: 1, ContractDateTime: time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local)},
+				{Code: "c3", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductStock, Side: SideBuy, Price: 1998, OwnedQuantity: 1, ContractDateTime: time.Date(2022, 1, 25, 9, 2, 0, 0, time.Local)},
+			}},
+		{name: "ポジションがなければ空配列を返す",
+			arg1:  ProductMargin,
+			arg2:  "0000",
+			want1: []SecurityPosition{}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := book.Positions(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
@@ -1,22 +1,23 @@
 package gridon
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"sort"
-
-	"gitlab.com/tsuchinaga/kabus-grpc-server/kabuspb"
-	"google.golang.org/grpc/status"
+	"sync/atomic"
+	"time"
 )
 
 // newOrderService - 新しい注文サービスの取得
-func newOrderService(clock IClock, kabusAPI IKabusAPI, strategyStore IStrategyStore, orderStore IOrderStore, positionStore IPositionStore, logger ILogger) IOrderService {
+func newOrderService(clock IClock, kabusAPI IKabusAPI, strategyStore IStrategyStore, orderStore IOrderStore, positionStore IPositionStore, riskManager IRiskManager, logger ILogger) IOrderService {
 	return &orderService{
 		clock:         clock,
 		kabusAPI:      kabusAPI,
 		strategyStore: strategyStore,
 		orderStore:    orderStore,
 		positionStore: positionStore,
+		riskManager:   riskManager,
 		logger:        logger,
 	}
 }
@@ -24,15 +25,25 @@ func newOrderService(clock IClock, kabusAPI IKabusAPI, strategyStore IStrategySt
 // IOrderService - 注文サービスのインターフェース
 type IOrderService interface {
 	GetActiveOrdersByStrategyCode(strategyCode string) ([]*Order, error)
+	MaxEntryQuantity(strategyCode string, price float64) (float64, error)
 	EntryLimit(strategyCode string, price float64, quantity float64) error
 	ExitLimit(strategyCode string, price float64, quantity float64, sortOrder SortOrder) error
 	EntryMarket(strategyCode string, quantity float64) error
 	ExitMarket(strategyCode string, quantity float64, sortOrder SortOrder) error
+	ExitStop(strategyCode string, executionType ExecutionType, triggerPrice float64, price float64) error
 	Cancel(strategy *Strategy, orderCode string) error
 	CancelAll(strategy *Strategy) error
 	ExitAll(strategy *Strategy) error
+	ForceCancelAll(strategy *Strategy) error
+	ForceExitAll(strategy *Strategy) error
+	SettlePendingOrders(strategy *Strategy) error
+	ExpirePaperOrders(strategy *Strategy) error
 }
 
+// pendingOrderGracePeriod - 送信中の注文が証券会社の注文一覧に現れるのを待つ期間
+// この期間を過ぎても見つからない送信中の注文は、証券会社に届かなかったものとして破棄する
+const pendingOrderGracePeriod = 1 * time.Minute
+
 // orderService - 注文サービス
 type orderService struct {
 	clock         IClock
@@ -40,7 +51,9 @@ type orderService struct {
 	strategyStore IStrategyStore
 	orderStore    IOrderStore
 	positionStore IPositionStore
+	riskManager   IRiskManager
 	logger        ILogger
+	pendingSeq    uint32
 }
 
 // GetActiveOrdersByStrategyCode - 戦略を指定して有効な注文を取り出す
@@ -76,6 +89,7 @@ func (s *orderService) EntryLimit(strategyCode string, price float64, quantity f
 		Price:           price,
 		OrderQuantity:   quantity,
 		AccountType:     strategy.Account.AccountType,
+		ExpireDay:       strategy.OrderExpireDay.ExpireDay(s.clock.Now()),
 		OrderDateTime:   s.clock.Now(),
 	}
 
@@ -102,6 +116,7 @@ func (s *orderService) ExitLimit(strategyCode string, price float64, quantity fl
 		Price:           price,
 		OrderQuantity:   quantity,
 		AccountType:     strategy.Account.AccountType,
+		ExpireDay:       strategy.OrderExpireDay.ExpireDay(s.clock.Now()),
 		OrderDateTime:   s.clock.Now(),
 	}
 
@@ -141,6 +156,20 @@ func (s *orderService) CancelAll(strategy *Strategy) error {
 		return nil
 	}
 
+	return s.cancelAll(strategy)
+}
+
+// ForceCancelAll - 全取消戦略の実行タイミングに関係なく、戦略に関連する全ての注文を取り消す
+func (s *orderService) ForceCancelAll(strategy *Strategy) error {
+	if strategy == nil {
+		return ErrNilArgument
+	}
+
+	return s.cancelAll(strategy)
+}
+
+// cancelAll - 戦略に関連する全ての注文を取り消す
+func (s *orderService) cancelAll(strategy *Strategy) error {
 	// 有効な注文を取り出す
 	orders, err := s.orderStore.GetActiveOrdersByStrategyCode(strategy.Code)
 	if err != nil {
@@ -148,7 +177,11 @@ func (s *orderService) CancelAll(strategy *Strategy) error {
 	}
 
 	// キャンセルに流す
+	//   送信中の注文は証券会社の注文コードがわかるまで取り消せないのでスキップする
 	for _, o := range orders {
+		if o.IsPending() {
+			continue
+		}
 		_, err := s.kabusAPI.CancelOrder(strategy.Account.Password, o.Code)
 		if err != nil {
 			if err := s.handleCancelOrderError(err, o.Code); err != nil {
@@ -160,34 +193,15 @@ func (s *orderService) CancelAll(strategy *Strategy) error {
 }
 
 // handleCancelOrderError - 取消注文のエラーをハンドリングする
+// 指定した注文に対してアクションが起こせない、起こす必要がない場合は問題になるエラーでないのでnilを返す
 func (s *orderService) handleCancelOrderError(err error, orderCode string) error {
 	if err == nil {
 		return nil
 	}
 
-	if st, ok := status.FromError(err); ok { // grpcのエラーならハンドリング処理に入る
-		// 詳細がなければそのままエラーを返す
-		if len(st.Details()) == 0 {
-			return err
-		}
-
-		// 詳細をループする
-		for _, d := range st.Details() {
-			switch e := d.(type) {
-			case *kabuspb.RequestError:
-				switch e.Code {
-				case 41, 42, 43, 44, 45, 47: // 指定した注文に対してアクションが起こせない、起こす必要がない場合
-					s.logger.Warning(fmt.Errorf("cancel order error(order code = %s):, %w", orderCode, err))
-					continue
-				default:
-					return err
-				}
-			default:
-				return err
-			}
-		}
-
-		return nil // grpcのエラーでここまでこれたら問題になるエラーでないのでnilを返す
+	if errors.Is(kabusErrorFrom(err), ErrNotCancelable) {
+		s.logger.Warning(fmt.Errorf("cancel order error(order code = %s):, %w", orderCode, err))
+		return nil
 	}
 
 	return err
@@ -204,6 +218,33 @@ func (s *orderService) ExitAll(strategy *Strategy) error {
 		return nil
 	}
 
+	return s.exitAll(strategy, strategy.ExitStrategy.ExecutionType(now))
+}
+
+// ForceExitAll - 全エグジット戦略の実行タイミングに関係なく、戦略に関連する拘束されていないポジションを全て成行でエグジットする
+// 拘束されていない数量がなければ何もしない
+func (s *orderService) ForceExitAll(strategy *Strategy) error {
+	if strategy == nil {
+		return ErrNilArgument
+	}
+
+	positions, err := s.positionStore.GetActivePositionsByStrategyCode(strategy.Code)
+	if err != nil {
+		return err
+	}
+	var quantity float64
+	for _, p := range positions {
+		quantity += p.LeaveQuantity()
+	}
+	if quantity <= 0 {
+		return nil
+	}
+
+	return s.exitAll(strategy, ExecutionTypeMarket)
+}
+
+// exitAll - 戦略に関連する拘束されていないポジションを全て指定した執行条件でエグジットする
+func (s *orderService) exitAll(strategy *Strategy, executionType ExecutionType) error {
 	// 保有中のポジションを取り出す
 	positions, err := s.positionStore.GetActivePositionsByStrategyCode(strategy.Code)
 	if err != nil {
@@ -225,7 +266,7 @@ func (s *orderService) ExitAll(strategy *Strategy) error {
 		MarginTradeType: strategy.MarginTradeType,
 		TradeType:       TradeTypeExit,
 		Side:            strategy.EntrySide.Turn(),
-		ExecutionType:   strategy.ExitStrategy.ExecutionType(now),
+		ExecutionType:   executionType,
 		Price:           0,
 		OrderQuantity:   0,
 		AccountType:     strategy.Account.AccountType,
@@ -234,6 +275,9 @@ func (s *orderService) ExitAll(strategy *Strategy) error {
 	}
 	for _, p := range positions {
 		leave := p.LeaveQuantity()
+		if leave <= 0 {
+			continue
+		}
 		order.OrderQuantity += leave
 		if err := s.positionStore.Hold(p.Code, leave); err != nil {
 			// 拘束したポジションを解放する
@@ -247,28 +291,10 @@ func (s *orderService) ExitAll(strategy *Strategy) error {
 	}
 
 	// 注文の送信
-	res, err := s.kabusAPI.SendOrder(strategy, order)
-	if err != nil {
-		// 拘束したポジションを解放する
-		// ただし、解放の処理でエラーがでたら対応できない
-		for _, hp := range order.HoldPositions {
-			_ = s.positionStore.Release(hp.PositionCode, hp.HoldQuantity)
-		}
+	// キルスイッチが入っていてもポジションを閉じられるよう、リスク管理の確認はしない
+	if err := s.submit(strategy, order); err != nil {
 		return err
 	}
-	if res.Result {
-		order.Code = res.OrderCode
-		if err := s.orderStore.Save(order); err != nil {
-			return fmt.Errorf("order=%+v: %w", order, err)
-		}
-	} else {
-		// 拘束したポジションを解放する
-		// ただし、解放の処理でエラーがでたら対応できない
-		for _, hp := range order.HoldPositions {
-			_ = s.positionStore.Release(hp.PositionCode, hp.HoldQuantity)
-		}
-		return fmt.Errorf("result=%+v, order=%+v: %w", res, order, ErrOrderCondition)
-	}
 
 	return nil
 }
@@ -328,22 +354,109 @@ func (s *orderService) ExitMarket(strategyCode string, quantity float64, sortOrd
 	return s.sendOrder(strategy, order)
 }
 
+// ExitStop - エグジットの逆指値注文
+// 他の注文に拘束されていないポジションを全て拘束して注文し、拘束できるポジションがなければ何もしない
+func (s *orderService) ExitStop(strategyCode string, executionType ExecutionType, triggerPrice float64, price float64) error {
+	if !executionType.IsStop() {
+		return ErrUndecidableValue
+	}
+
+	strategy, err := s.strategyStore.GetByCode(strategyCode)
+	if err != nil {
+		return err
+	}
+
+	positions, err := s.positionStore.GetActivePositionsByStrategyCode(strategyCode)
+	if err != nil {
+		return err
+	}
+	var quantity float64
+	for _, p := range positions {
+		quantity += p.LeaveQuantity()
+	}
+	if quantity <= 0 {
+		return nil
+	}
+
+	order := &Order{
+		StrategyCode:    strategy.Code,
+		SymbolCode:      strategy.SymbolCode,
+		Exchange:        strategy.Exchange,
+		Status:          OrderStatusInOrder,
+		Product:         strategy.Product,
+		MarginTradeType: strategy.MarginTradeType,
+		TradeType:       TradeTypeExit,
+		Side:            strategy.EntrySide.Turn(),
+		ExecutionType:   executionType,
+		Price:           price,
+		TriggerPrice:    triggerPrice,
+		OrderQuantity:   quantity,
+		AccountType:     strategy.Account.AccountType,
+		ExpireDay:       strategy.OrderExpireDay.ExpireDay(s.clock.Now()),
+		OrderDateTime:   s.clock.Now(),
+	}
+	hp, err := s.holdPositions(strategyCode, quantity, SortOrderLatest)
+	if err != nil {
+		return err
+	}
+	order.HoldPositions = hp
+
+	return s.sendOrder(strategy, order)
+}
+
 // checkEntryCash - エントリーするために必要な現金があるか
 func (s *orderService) checkEntryCash(strategyCode string, cash float64, limitPrice float64, orderQuantity float64) (bool, error) {
-	orders, err := s.orderStore.GetActiveOrdersByStrategyCode(strategyCode)
+	available, err := s.availableEntryCash(strategyCode, cash)
 	if err != nil {
 		return false, err
 	}
+
+	if available < limitPrice*orderQuantity {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// availableEntryCash - 現金から注文中の注文の代金を除いた、エントリーに使える現金
+func (s *orderService) availableEntryCash(strategyCode string, cash float64) (float64, error) {
+	orders, err := s.orderStore.GetActiveOrdersByStrategyCode(strategyCode)
+	if err != nil {
+		return 0, err
+	}
 	var totalLimitOrderPrice float64
 	for _, o := range orders {
 		totalLimitOrderPrice += o.Price * (o.OrderQuantity - o.ContractQuantity)
 	}
 
-	if cash < totalLimitOrderPrice+limitPrice*orderQuantity {
-		return false, nil
+	return cash - totalLimitOrderPrice, nil
+}
+
+// MaxEntryQuantity - 指定した価格でエントリーできる最大の数量
+// エントリーに使える現金で買える数量を売買単位の倍数に切り捨てる
+// checkEntryCashを必ず通る数量にするため、浮動小数点の誤差は補正せずに切り捨てる
+func (s *orderService) MaxEntryQuantity(strategyCode string, price float64) (float64, error) {
+	if price <= 0 {
+		return 0, nil
 	}
 
-	return true, nil
+	strategy, err := s.strategyStore.GetByCode(strategyCode)
+	if err != nil {
+		return 0, err
+	}
+
+	available, err := s.availableEntryCash(strategyCode, strategy.Cash)
+	if err != nil {
+		return 0, err
+	}
+	q := available / price
+	if strategy.TradingUnit > 0 {
+		q = math.Floor(q/strategy.TradingUnit) * strategy.TradingUnit
+	}
+	if q < 0 {
+		return 0, nil
+	}
+	return q, nil
 }
 
 // holdPositions - 注文に必要なポジションを拘束する
@@ -412,33 +525,233 @@ func (s *orderService) sendOrder(strategy *Strategy, order *Order) error {
 		return err
 	}
 
+	// リスク管理の上限に達していたら、拘束したポジションを解放して注文しない
+	if err := s.riskManager.Check(strategy, order); err != nil {
+		s.releaseHoldPositions(order)
+		return err
+	}
+
+	return s.submit(strategy, order)
+}
+
+// submit - 送信中の注文を記録してから注文を送信し、結果に応じて注文を保存するか、拘束したポジションを解放する
+// 証券会社に届いたかわからない失敗なら、送信中の注文と拘束したポジションを残したまま証券会社の注文から送信した注文を探す
+// 証券会社に届いた注文だけを、リスク管理の注文数に記録する
+func (s *orderService) submit(strategy *Strategy, order *Order) error {
+	pending := *order
+	pending.Code = s.pendingOrderCode(order)
+	pending.Status = OrderStatusPending
+	if err := s.orderStore.SavePending(&pending); err != nil {
+		s.releaseHoldPositions(order)
+		return fmt.Errorf("order=%+v: %w", order, err)
+	}
+
 	res, err := s.kabusAPI.SendOrder(strategy, order)
 	if err != nil {
-		// 拘束したポジションを解放する
-		// ただし、解放の処理でエラーがでたら対応できない
-		for _, hp := range order.HoldPositions {
-			_ = s.positionStore.Release(hp.PositionCode, hp.HoldQuantity)
+		if errors.Is(err, ErrKabusTransient) {
+			if err := s.settlePendingOrder(strategy, &pending, make(map[string]bool)); err != nil {
+				return fmt.Errorf("order=%+v: %w", pending, err)
+			}
+			if o, err := s.orderStore.GetByCode(pending.Code); err == nil && o.IsPending() {
+				return fmt.Errorf("order=%+v: %w", pending, ErrPendingOrder)
+			}
+			s.riskManager.Record(strategy)
+			return nil
 		}
+
+		s.discardPendingOrder(&pending)
 		return err
 	}
 
-	if res.Result {
-		order.Code = res.OrderCode
-		if err := s.orderStore.Save(order); err != nil {
-			return fmt.Errorf("order=%+v: %w", order, err)
+	if !res.Result {
+		s.discardPendingOrder(&pending)
+		return fmt.Errorf("result=%+v, order=%+v: %w", res, order, ErrOrderCondition)
+	}
+	s.riskManager.Record(strategy)
+
+	order.Code = res.OrderCode
+	if err := s.orderStore.Save(order); err != nil {
+		return fmt.Errorf("order=%+v: %w", order, err)
+	}
+	return s.orderStore.DeleteByCode(pending.Code)
+}
+
+// pendingOrderCode - 送信中の注文のコード
+// 証券会社の注文コードと重ならないよう接頭辞をつけ、同時刻の注文と重ならないよう連番をつける
+func (s *orderService) pendingOrderCode(order *Order) string {
+	return fmt.Sprintf("pending-%s-%d-%d", order.StrategyCode, s.clock.Now().UnixNano(), atomic.AddUint32(&s.pendingSeq, 1))
+}
+
+// releaseHoldPositions - 注文で拘束したポジションを解放する
+// ただし、解放の処理でエラーがでたら対応できない
+func (s *orderService) releaseHoldPositions(order *Order) {
+	for _, hp := range order.HoldPositions {
+		_ = s.positionStore.Release(hp.PositionCode, hp.HoldQuantity)
+	}
+}
+
+// discardPendingOrder - 証券会社に届かなかった送信中の注文を破棄し、拘束したポジションを解放する
+func (s *orderService) discardPendingOrder(pending *Order) {
+	s.releaseHoldPositions(pending)
+	if err := s.orderStore.DeleteByCode(pending.Code); err != nil {
+		s.logger.Warning(fmt.Errorf("送信中の注文の削除でエラーが発生しました(order = %+v): %w", pending, err))
+	}
+}
+
+// SettlePendingOrders - 戦略の送信中の注文の結果を確定させる
+func (s *orderService) SettlePendingOrders(strategy *Strategy) error {
+	if strategy == nil {
+		return ErrNilArgument
+	}
+
+	orders, err := s.orderStore.GetActiveOrdersByStrategyCode(strategy.Code)
+	if err != nil {
+		return err
+	}
+
+	// 先に送信した注文から、先に受け付けられた証券会社の注文を割り当てる
+	pendings := make([]*Order, 0)
+	for _, o := range orders {
+		if o.IsPending() {
+			pendings = append(pendings, o)
 		}
-	} else {
-		// 拘束したポジションを解放する
-		// ただし、解放の処理でエラーがでたら対応できない
-		for _, hp := range order.HoldPositions {
-			_ = s.positionStore.Release(hp.PositionCode, hp.HoldQuantity)
+	}
+	sort.SliceStable(pendings, func(i, j int) bool {
+		return pendings[i].OrderDateTime.Before(pendings[j].OrderDateTime)
+	})
+
+	claimed := make(map[string]bool)
+	for _, o := range pendings {
+		if err := s.settlePendingOrder(strategy, o, claimed); err != nil {
+			return err
 		}
-		return fmt.Errorf("result=%+v, order=%+v: %w", res, order, ErrOrderCondition)
+	}
+	return nil
+}
+
+// ExpirePaperOrders - 仮想売買の戦略に残っている再起動前の疑似注文板の注文を失効させる
+// 疑似注文板はプロセス内にだけあり、再起動前の注文は約定も取消もできないので、取消として扱い拘束したポジションを解放する
+// 疑似注文板に新しい注文が入る前の起動時に実行する
+func (s *orderService) ExpirePaperOrders(strategy *Strategy) error {
+	if strategy == nil {
+		return ErrNilArgument
+	}
+	if !strategy.PaperTrading {
+		return nil
+	}
+
+	orders, err := s.orderStore.GetActiveOrdersByStrategyCode(strategy.Code)
+	if err != nil {
+		return err
 	}
 
+	now := s.clock.Now()
+	for _, o := range orders {
+		if o.IsPending() || !isPaperOrderCode(o.Code) {
+			continue
+		}
+
+		for i, hp := range o.HoldPositions {
+			leave := hp.LeaveQuantity()
+			if leave <= 0 {
+				continue
+			}
+			if err := s.positionStore.Release(hp.PositionCode, leave); err != nil {
+				return err
+			}
+			o.HoldPositions[i].ReleaseQuantity += leave
+		}
+
+		o.Status = OrderStatusCanceled
+		o.CancelDateTime = now
+		if err := s.orderStore.Save(o); err != nil {
+			return err
+		}
+		s.logger.Notice(fmt.Sprintf("再起動前の疑似注文板の注文を失効させました: %s", o))
+	}
 	return nil
 }
 
+// settlePendingOrder - 送信中の注文を証券会社の注文から探して結果を確定させる
+// 見つかれば証券会社の注文コードで注文中の注文として保存し、猶予期間を過ぎても見つからなければ破棄する
+// 猶予期間内で見つからなければ、送信中のまま次の確認を待つ
+// 同じ周回で確定に使った証券会社の注文はclaimedに記録し、1つの証券会社の注文で複数の送信中の注文を確定させない
+func (s *orderService) settlePendingOrder(strategy *Strategy, pending *Order, claimed map[string]bool) error {
+	securityOrders, err := s.kabusAPI.GetOrders(strategy.Product, strategy.SymbolCode, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	if so, ok := s.findSecurityOrder(pending, strategySecurityOrders(strategy, securityOrders), claimed); ok {
+		claimed[so.Code] = true
+		order := *pending
+		order.Code = so.Code
+		order.Status = OrderStatusInOrder
+		if err := s.orderStore.Save(&order); err != nil {
+			return err
+		}
+		s.logger.Notice(fmt.Sprintf("送信中の注文を証券会社の注文で確定しました: %s", &order))
+		return s.orderStore.DeleteByCode(pending.Code)
+	}
+
+	if s.clock.Now().Sub(pending.OrderDateTime) < pendingOrderGracePeriod {
+		return nil
+	}
+
+	s.discardPendingOrder(pending)
+	s.logger.Notice(fmt.Sprintf("証券会社に届かなかった送信中の注文を破棄しました: %s", pending))
+	return nil
+}
+
+// findSecurityOrder - 送信中の注文と内容が一致し、まだ手元にない証券会社の注文を探す
+// 注文日時は送信中の注文の前後の猶予期間内に限り、claimedに含まれる注文は対象外にする
+// 複数見つかった場合は、注文日時が最も早いものを返す
+func (s *orderService) findSecurityOrder(pending *Order, securityOrders []SecurityOrder, claimed map[string]bool) (SecurityOrder, bool) {
+	var found SecurityOrder
+	var ok bool
+	for _, so := range securityOrders {
+		if so.Exchange != pending.Exchange ||
+			so.TradeType != pending.TradeType ||
+			so.Side != pending.Side ||
+			so.MarginTradeType != pending.MarginTradeType ||
+			!s.samePrice(pending, so) ||
+			so.OrderQuantity != pending.OrderQuantity ||
+			so.OrderDateTime.Before(pending.OrderDateTime.Add(-1*pendingOrderGracePeriod)) ||
+			so.OrderDateTime.After(pending.OrderDateTime.Add(pendingOrderGracePeriod)) ||
+			claimed[so.Code] {
+			continue
+		}
+		if _, err := s.orderStore.GetByCode(so.Code); err == nil {
+			continue
+		}
+
+		if !ok || so.OrderDateTime.Before(found.OrderDateTime) {
+			found, ok = so, true
+		}
+	}
+	return found, ok
+}
+
+// samePrice - 送信中の注文と証券会社の注文の価格が、執行条件ごとの証券会社での価格の意味で一致するか
+// 成行は価格を持たないので比較しない
+// 逆指値は価格0で送信しているため、証券会社の注文の価格は0か発火後の指値価格になり、発火価格は取れた場合だけ比較する
+func (s *orderService) samePrice(pending *Order, so SecurityOrder) bool {
+	switch pending.ExecutionType {
+	case ExecutionTypeMarket, ExecutionTypeMarketMorningClose, ExecutionTypeMarketAfternoonClose:
+		return true
+	case ExecutionTypeStopMarket, ExecutionTypeStopLimit:
+		if so.TriggerPrice != 0 && so.TriggerPrice != pending.TriggerPrice {
+			return false
+		}
+		afterHitPrice := 0.0
+		if pending.ExecutionType == ExecutionTypeStopLimit {
+			afterHitPrice = pending.Price
+		}
+		return so.Price == 0 || so.Price == afterHitPrice
+	}
+	return so.Price == pending.Price
+}
+
 // validation - 注文が有効か、注文して問題ないかをチェックする:
 //   ただし、証券会社でチェックできるものは証券会社に送信して結果を得たほうが確実なので、ここではチェックしない
 func (s *orderService) validation(strategy *Strategy, order *Order) error {
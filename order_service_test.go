@@ -2,6 +2,7 @@ package gridon
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -24,6 +25,10 @@ type testOrderService struct {
 	GetActiveOrdersByStrategyCode2       error
 	GetActiveOrdersByStrategyCodeCount   int
 	GetActiveOrdersByStrategyCodeHistory []interface{}
+	MaxEntryQuantity1                    float64
+	MaxEntryQuantity2                    error
+	MaxEntryQuantityCount                int
+	MaxEntryQuantityHistory              []interface{}
 	Cancel1                              error
 	CancelCount                          int
 	CancelHistory                        []interface{}
@@ -33,12 +38,39 @@ type testOrderService struct {
 	ExitMarket1                          error
 	ExitMarketCount                      int
 	ExitMarketHistory                    []interface{}
+	ExitStop1                            error
+	ExitStopCount                        int
+	ExitStopHistory                      []interface{}
 	CancelAll1                           error
 	CancelAllCount                       int
 	CancelAllHistory                     []interface{}
 	ExitAll1                             error
 	ExitAllCount                         int
 	ExitAllHistory                       []interface{}
+	ForceCancelAll1                      error
+	ForceCancelAllCount                  int
+	ForceCancelAllHistory                []interface{}
+	ForceExitAll1                        error
+	ForceExitAllCount                    int
+	ForceExitAllHistory                  []interface{}
+	SettlePendingOrders1                 error
+	SettlePendingOrdersCount             int
+	SettlePendingOrdersHistory           []interface{}
+	ExpirePaperOrders1                   error
+	ExpirePaperOrdersCount               int
+	ExpirePaperOrdersHistory             []interface{}
+}
+
+func (t *testOrderService) ExpirePaperOrders(strategy *Strategy) error {
+	t.ExpirePaperOrdersHistory = append(t.ExpirePaperOrdersHistory, strategy)
+	t.ExpirePaperOrdersCount++
+	return t.ExpirePaperOrders1
+}
+
+func (t *testOrderService) SettlePendingOrders(strategy *Strategy) error {
+	t.SettlePendingOrdersHistory = append(t.SettlePendingOrdersHistory, strategy)
+	t.SettlePendingOrdersCount++
+	return t.SettlePendingOrders1
 }
 
 func (t *testOrderService) GetActiveOrdersByStrategyCode(strategyCode string) ([]*Order, error) {
@@ -46,6 +78,12 @@ func (t *testOrderService) GetActiveOrdersByStrategyCode(strategyCode string) ([
 	t.GetActiveOrdersByStrategyCodeCount++
 	return t.GetActiveOrdersByStrategyCode1, t.GetActiveOrdersByStrategyCode2
 }
+func (t *testOrderService) MaxEntryQuantity(strategyCode string, price float64) (float64, error) {
+	t.MaxEntryQuantityHistory = append(t.MaxEntryQuantityHistory, strategyCode)
+	t.MaxEntryQuantityHistory = append(t.MaxEntryQuantityHistory, price)
+	t.MaxEntryQuantityCount++
+	return t.MaxEntryQuantity1, t.MaxEntryQuantity2
+}
 func (t *testOrderService) EntryLimit(strategyCode string, price float64, quantity float64) error {
 	t.EntryLimitHistory = append(t.EntryLimitHistory, strategyCode)
 	t.EntryLimitHistory = append(t.EntryLimitHistory, price)
@@ -80,6 +118,14 @@ func (t *testOrderService) ExitMarket(strategyCode string, quantity float64, sor
 	t.ExitMarketCount++
 	return t.ExitMarket1
 }
+func (t *testOrderService) ExitStop(strategyCode string, executionType ExecutionType, triggerPrice float64, price float64) error {
+	t.ExitStopHistory = append(t.ExitStopHistory, strategyCode)
+	t.ExitStopHistory = append(t.ExitStopHistory, executionType)
+	t.ExitStopHistory = append(t.ExitStopHistory, triggerPrice)
+	t.ExitStopHistory = append(t.ExitStopHistory, price)
+	t.ExitStopCount++
+	return t.ExitStop1
+}
 func (t *testOrderService) CancelAll(strategy *Strategy) error {
 	t.CancelAllHistory = append(t.CancelAllHistory, strategy)
 	t.CancelAllCount++
@@ -90,6 +136,16 @@ func (t *testOrderService) ExitAll(strategy *Strategy) error {
 	t.ExitAllCount++
 	return t.ExitAll1
 }
+func (t *testOrderService) ForceCancelAll(strategy *Strategy) error {
+	t.ForceCancelAllHistory = append(t.ForceCancelAllHistory, strategy)
+	t.ForceCancelAllCount++
+	return t.ForceCancelAll1
+}
+func (t *testOrderService) ForceExitAll(strategy *Strategy) error {
+	t.ForceExitAllHistory = append(t.ForceExitAllHistory, strategy)
+	t.ForceExitAllCount++
+	return t.ForceExitAll1
+}
 
 func Test_orderService_CancelAll(t *testing.T) {
 	t.Parallel()
@@ -195,6 +251,14 @@ func Test_orderService_CancelAll(t *testing.T) {
 			arg1:                   &Strategy{Code: "strategy-code-001", Account: Account{Password: "Password1234"}, CancelStrategy: CancelStrategy{Runnable: true, Timings: []time.Time{time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}},
 			want1:                  requestErr2StDt.Err(),
 			wantCancelOrderHistory: []interface{}{"Password1234", "order-code-001"}},
+		{name: "送信中の注文は取り消さずにスキップする",
+			clock:                  &testClock{Now1: time.Date(2021, 11, 10, 14, 55, 0, 0, time.Local)},
+			orderStore:             &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{{Code: "order-code-001"}, {Code: "pending-001", Status: OrderStatusPending}, {Code: "order-code-003"}}},
+			kabusAPI:               &testKabusAPI{},
+			logger:                 &testLogger{},
+			arg1:                   &Strategy{Code: "strategy-code-001", Account: Account{Password: "Password1234"}, CancelStrategy: CancelStrategy{Runnable: true, Timings: []time.Time{time.Date(0, 1, 1, 14, 55, 0, 0, time.Local)}}},
+			want1:                  nil,
+			wantCancelOrderHistory: []interface{}{"Password1234", "order-code-001", "Password1234", "order-code-003"}},
 	}
 
 	for _, test := range tests {
@@ -576,7 +640,7 @@ func Test_ExitAll(t *testing.T) {
 		test := test
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
-			service := &orderService{kabusAPI: test.kabusAPI, orderStore: test.orderStore, positionStore: test.positionStore, clock: test.clock}
+			service := &orderService{kabusAPI: test.kabusAPI, orderStore: test.orderStore, positionStore: test.positionStore, clock: test.clock, riskManager: &testRiskManager{}}
 			got1 := service.ExitAll(test.arg1)
 			if !errors.Is(got1, test.want1) ||
 				!reflect.DeepEqual(test.wantGetActivePositionsByStrategyCodeCount, test.positionStore.GetActivePositionsByStrategyCodeCount) ||
@@ -721,6 +785,51 @@ func Test_orderService_EntryLimit(t *testing.T) {
 					OrderDateTime:    time.Date(2021, 11, 2, 14, 0, 0, 0, time.Local),
 				},
 			}},
+		{name: "有効期限の指定があれば注文に有効期限を設定する",
+			kabusAPI:   &testKabusAPI{SendOrder2: ErrUnknown},
+			orderStore: &testOrderStore{},
+			strategyStore: &testStrategyStore{GetByCode1: &Strategy{
+				Code:           "strategy-code-001",
+				SymbolCode:     "1475",
+				Exchange:       ExchangeToushou,
+				Product:        ProductStock,
+				EntrySide:      SideBuy,
+				Cash:           10_000,
+				OrderExpireDay: ExpireDayTypeNextBusinessDay,
+				Account:        Account{AccountType: AccountTypeSpecific, FundType: FundTypeProtected}}},
+			clock: &testClock{Now1: time.Date(2021, 11, 5, 14, 0, 0, 0, time.Local)},
+			arg1:  "strategy-code-001",
+			arg2:  2100,
+			arg3:  4,
+			want1: ErrUnknown,
+			wantSendOrderHistory: []interface{}{
+				&Strategy{
+					Code:           "strategy-code-001",
+					SymbolCode:     "1475",
+					Exchange:       ExchangeToushou,
+					Product:        ProductStock,
+					EntrySide:      SideBuy,
+					Cash:           10_000,
+					OrderExpireDay: ExpireDayTypeNextBusinessDay,
+					Account:        Account{AccountType: AccountTypeSpecific, FundType: FundTypeProtected},
+				},
+				&Order{
+					StrategyCode:     "strategy-code-001",
+					SymbolCode:       "1475",
+					Exchange:         ExchangeToushou,
+					Status:           OrderStatusInOrder,
+					Product:          ProductStock,
+					TradeType:        TradeTypeEntry,
+					Side:             SideBuy,
+					ExecutionType:    ExecutionTypeLimit,
+					Price:            2100,
+					OrderQuantity:    4,
+					ContractQuantity: 0,
+					AccountType:      AccountTypeSpecific,
+					ExpireDay:        time.Date(2021, 11, 8, 0, 0, 0, 0, time.Local),
+					OrderDateTime:    time.Date(2021, 11, 5, 14, 0, 0, 0, time.Local),
+				},
+			}},
 		{name: "注文に失敗したらerror",
 			kabusAPI:   &testKabusAPI{SendOrder1: OrderResult{Result: false, ResultCode: 4}},
 			orderStore: &testOrderStore{},
@@ -909,6 +1018,7 @@ func Test_orderService_EntryLimit(t *testing.T) {
 				orderStore:    test.orderStore,
 				strategyStore: test.strategyStore,
 				clock:         test.clock,
+				riskManager:   &testRiskManager{},
 			}
 			got1 := service.EntryLimit(test.arg1, test.arg2, test.arg3)
 			if !errors.Is(got1, test.want1) ||
@@ -1366,6 +1476,7 @@ func Test_orderService_ExitLimit(t *testing.T) {
 				positionStore: test.positionStore,
 				strategyStore: test.strategyStore,
 				clock:         test.clock,
+				riskManager:   &testRiskManager{},
 			}
 			got1 := service.ExitLimit(test.arg1, test.arg2, test.arg3, test.arg4)
 			if !errors.Is(got1, test.want1) ||
@@ -1514,6 +1625,72 @@ func Test_orderService_checkEntryCash(t *testing.T) {
 	}
 }
 
+func Test_orderService_MaxEntryQuantity(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		strategyStore *testStrategyStore
+		orderStore    *testOrderStore
+		arg1          string
+		arg2          float64
+		want1         float64
+		want2         error
+	}{
+		{name: "価格が0なら0",
+			strategyStore: &testStrategyStore{GetByCode1: &Strategy{Code: "strategy-code-001", Cash: 100_000, TradingUnit: 100}},
+			orderStore:    &testOrderStore{},
+			arg1:          "strategy-code-001",
+			arg2:          0,
+			want1:         0},
+		{name: "戦略の取得に失敗したらエラー",
+			strategyStore: &testStrategyStore{GetByCode2: ErrNoData},
+			orderStore:    &testOrderStore{},
+			arg1:          "strategy-code-001",
+			arg2:          100,
+			want1:         0,
+			want2:         ErrNoData},
+		{name: "注文一覧の取得に失敗したらエラー",
+			strategyStore: &testStrategyStore{GetByCode1: &Strategy{Code: "strategy-code-001", Cash: 100_000, TradingUnit: 100}},
+			orderStore:    &testOrderStore{GetActiveOrdersByStrategyCode2: ErrUnknown},
+			arg1:          "strategy-code-001",
+			arg2:          100,
+			want1:         0,
+			want2:         ErrUnknown},
+		{name: "注文中の代金を除いた現金で買える数量を売買単位に切り捨てて返す",
+			strategyStore: &testStrategyStore{GetByCode1: &Strategy{Code: "strategy-code-001", Cash: 100_000, TradingUnit: 100}},
+			orderStore: &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{
+				{Price: 110, OrderQuantity: 300, ContractQuantity: 100}}},
+			arg1:  "strategy-code-001",
+			arg2:  100,
+			want1: 700},
+		{name: "売買単位がなければ切り捨てない",
+			strategyStore: &testStrategyStore{GetByCode1: &Strategy{Code: "strategy-code-001", Cash: 1_000, TradingUnit: 0}},
+			orderStore:    &testOrderStore{},
+			arg1:          "strategy-code-001",
+			arg2:          400,
+			want1:         2.5},
+		{name: "注文中の代金が現金を超えていたら0",
+			strategyStore: &testStrategyStore{GetByCode1: &Strategy{Code: "strategy-code-001", Cash: 10_000, TradingUnit: 1}},
+			orderStore: &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{
+				{Price: 20_000, OrderQuantity: 1}}},
+			arg1:  "strategy-code-001",
+			arg2:  100,
+			want1: 0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &orderService{strategyStore: test.strategyStore, orderStore: test.orderStore}
+			got1, got2 := service.MaxEntryQuantity(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
 func Test_orderService_holdPositions(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -1629,15 +1806,18 @@ func Test_orderService_sendOrder(t *testing.T) {
 		kabusAPI         *testKabusAPI
 		orderStore       *testOrderStore
 		positionStore    *testPositionStore
+		riskManager      *testRiskManager
 		arg1             *Strategy
 		arg2             *Order
 		want1            error
 		wantReleaseCount int
+		wantSendCount    int
 	}{
 		{name: "arg1がnilならエラー",
 			kabusAPI:      &testKabusAPI{},
 			orderStore:    &testOrderStore{},
 			positionStore: &testPositionStore{},
+			riskManager:   &testRiskManager{},
 			arg1:          nil,
 			arg2:          &Order{},
 			want1:         ErrNilArgument},
@@ -1645,6 +1825,7 @@ func Test_orderService_sendOrder(t *testing.T) {
 			kabusAPI:      &testKabusAPI{},
 			orderStore:    &testOrderStore{},
 			positionStore: &testPositionStore{},
+			riskManager:   &testRiskManager{},
 			arg1:          &Strategy{},
 			arg2:          nil,
 			want1:         ErrNilArgument},
@@ -1654,49 +1835,72 @@ func Test_orderService_sendOrder(t *testing.T) {
 			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
 				{Side: SideSell, OwnedQuantity: 50},
 			}},
-			arg1:  &Strategy{},
-			arg2:  &Order{TradeType: TradeTypeEntry, Side: SideSell, OrderQuantity: 1},
-			want1: ErrShortSellingRestriction},
+			riskManager: &testRiskManager{},
+			arg1:        &Strategy{},
+			arg2:        &Order{TradeType: TradeTypeEntry, Side: SideSell, OrderQuantity: 1},
+			want1:       ErrShortSellingRestriction},
 		{name: "注文送信に失敗したらエラー",
 			kabusAPI:      &testKabusAPI{SendOrder2: ErrUnknown},
 			orderStore:    &testOrderStore{},
 			positionStore: &testPositionStore{},
+			riskManager:   &testRiskManager{},
 			arg1:          &Strategy{},
 			arg2:          &Order{},
-			want1:         ErrUnknown},
+			want1:         ErrUnknown,
+			wantSendCount: 1},
 		{name: "注文に成功しても保存に失敗したらエラー",
 			kabusAPI:      &testKabusAPI{SendOrder1: OrderResult{Result: true, ResultCode: 0, OrderCode: "order-code-001"}},
 			orderStore:    &testOrderStore{Save1: ErrUnknown},
 			positionStore: &testPositionStore{},
+			riskManager:   &testRiskManager{},
 			arg1:          &Strategy{},
 			arg2:          &Order{},
-			want1:         ErrUnknown},
+			want1:         ErrUnknown,
+			wantSendCount: 1},
 		{name: "注文に成功して保存に成功したらnil",
 			kabusAPI:      &testKabusAPI{SendOrder1: OrderResult{Result: true, ResultCode: 0, OrderCode: "order-code-001"}},
 			orderStore:    &testOrderStore{Save1: nil},
 			positionStore: &testPositionStore{},
+			riskManager:   &testRiskManager{},
 			arg1:          &Strategy{},
 			arg2:          &Order{},
-			want1:         nil},
+			want1:         nil,
+			wantSendCount: 1},
 		{name: "注文に失敗したらReleaseしてエラー",
 			kabusAPI:      &testKabusAPI{SendOrder1: OrderResult{Result: false, ResultCode: 4, OrderCode: ""}},
 			orderStore:    &testOrderStore{},
 			positionStore: &testPositionStore{},
+			riskManager:   &testRiskManager{},
 			arg1:          &Strategy{},
 			arg2: &Order{HoldPositions: []HoldPosition{
 				{PositionCode: "position-code-001", HoldQuantity: 4},
 				{PositionCode: "position-code-002", HoldQuantity: 4},
 				{PositionCode: "position-code-003", HoldQuantity: 4}}},
 			want1:            ErrOrderCondition,
-			wantReleaseCount: 3},
+			wantReleaseCount: 3,
+			wantSendCount:    1},
 		{name: "注文に失敗してもHoldしたらポジションがなければReleaseはせずにエラー",
 			kabusAPI:         &testKabusAPI{SendOrder1: OrderResult{Result: false, ResultCode: 4, OrderCode: ""}},
 			orderStore:       &testOrderStore{},
 			positionStore:    &testPositionStore{},
+			riskManager:      &testRiskManager{},
 			arg1:             &Strategy{},
 			arg2:             &Order{HoldPositions: nil},
 			want1:            ErrOrderCondition,
-			wantReleaseCount: 0},
+			wantReleaseCount: 0,
+			wantSendCount:    1},
+		{name: "リスク管理で拒否されたら注文を送信せず、拘束したポジションをReleaseしてエラー",
+			kabusAPI:      &testKabusAPI{SendOrder1: OrderResult{Result: true, ResultCode: 0, OrderCode: "order-code-001"}},
+			orderStore:    &testOrderStore{},
+			positionStore: &testPositionStore{},
+			riskManager:   &testRiskManager{Check1: ErrRiskLimitExceeded},
+			arg1:          &Strategy{},
+			arg2: &Order{HoldPositions: []HoldPosition{
+				{PositionCode: "position-code-001", HoldQuantity: 4},
+				{PositionCode: "position-code-002", HoldQuantity: 4}}},
+			want1:            ErrRiskLimitExceeded,
+			wantReleaseCount: 2,
+			wantSendCount:    0},
 	}
 
 	for _, test := range tests {
@@ -1704,13 +1908,16 @@ func Test_orderService_sendOrder(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 			service := &orderService{
+				clock:         &testClock{Now1: time.Date(2021, 11, 1, 10, 0, 0, 0, time.Local)},
 				kabusAPI:      test.kabusAPI,
 				orderStore:    test.orderStore,
 				positionStore: test.positionStore,
+				logger:        &testLogger{},
+				riskManager:   test.riskManager,
 			}
 			got1 := service.sendOrder(test.arg1, test.arg2)
-			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantReleaseCount, test.positionStore.ReleaseCount) {
-				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.wantReleaseCount, got1, test.positionStore.ReleaseCount)
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantReleaseCount, test.positionStore.ReleaseCount) || !reflect.DeepEqual(test.wantSendCount, test.kabusAPI.SendOrderCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(), test.want1, test.wantReleaseCount, test.wantSendCount, got1, test.positionStore.ReleaseCount, test.kabusAPI.SendOrderCount)
 			}
 		})
 	}
@@ -1790,6 +1997,7 @@ func Test_orderService_EntryMarket(t *testing.T) {
 				kabusAPI:      test.kabusAPI,
 				orderStore:    test.orderStore,
 				strategyStore: test.strategyStore,
+				riskManager:   &testRiskManager{},
 			}
 			got1 := service.EntryMarket(test.arg1, test.arg2)
 			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantSendOrderHistory, test.kabusAPI.SendOrderHistory) {
@@ -1913,6 +2121,7 @@ func Test_orderService_ExitMarket(t *testing.T) {
 				orderStore:    test.orderStore,
 				positionStore: test.positionStore,
 				strategyStore: test.strategyStore,
+				riskManager:   &testRiskManager{},
 			}
 			got1 := service.ExitMarket(test.arg1, test.arg2, test.arg3)
 			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantSendOrderHistory, test.kabusAPI.SendOrderHistory) {
@@ -1922,6 +2131,266 @@ func Test_orderService_ExitMarket(t *testing.T) {
 	}
 }
 
+func Test_orderService_ExitStop(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                 string
+		clock                IClock
+		kabusAPI             *testKabusAPI
+		strategyStore        *testStrategyStore
+		orderStore           *testOrderStore
+		positionStore        *testPositionStore
+		arg1                 string
+		arg2                 ExecutionType
+		arg3                 float64
+		arg4                 float64
+		want1                error
+		wantSendOrderHistory []interface{}
+	}{
+		{name: "逆指値以外の執行条件ならエラー",
+			clock:         &testClock{Now1: time.Date(2021, 11, 4, 10, 0, 0, 0, time.Local)},
+			kabusAPI:      &testKabusAPI{},
+			strategyStore: &testStrategyStore{},
+			orderStore:    &testOrderStore{},
+			positionStore: &testPositionStore{},
+			arg1:          "strategy-code-001",
+			arg2:          ExecutionTypeLimit,
+			arg3:          2090,
+			arg4:          2090,
+			want1:         ErrUndecidableValue},
+		{name: "戦略取得に失敗したらエラー",
+			clock:         &testClock{Now1: time.Date(2021, 11, 4, 10, 0, 0, 0, time.Local)},
+			kabusAPI:      &testKabusAPI{},
+			strategyStore: &testStrategyStore{GetByCode2: ErrNoData},
+			orderStore:    &testOrderStore{},
+			positionStore: &testPositionStore{},
+			arg1:          "strategy-code-001",
+			arg2:          ExecutionTypeStopMarket,
+			arg3:          2090,
+			want1:         ErrNoData},
+		{name: "ポジション一覧の取得に失敗したらエラー",
+			clock:         &testClock{Now1: time.Date(2021, 11, 4, 10, 0, 0, 0, time.Local)},
+			kabusAPI:      &testKabusAPI{},
+			strategyStore: &testStrategyStore{GetByCode1: &Strategy{Code: "strategy-code-001"}},
+			orderStore:    &testOrderStore{},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode2: ErrUnknown},
+			arg1:          "strategy-code-001",
+			arg2:          ExecutionTypeStopMarket,
+			arg3:          2090,
+			want1:         ErrUnknown},
+		{name: "拘束されていないポジションがなければ何もしない",
+			clock:         &testClock{Now1: time.Date(2021, 11, 4, 10, 0, 0, 0, time.Local)},
+			kabusAPI:      &testKabusAPI{},
+			strategyStore: &testStrategyStore{GetByCode1: &Strategy{Code: "strategy-code-001"}},
+			orderStore:    &testOrderStore{},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
+				{Code: "position-code-001", OwnedQuantity: 4, HoldQuantity: 4, Price: 100}}},
+			arg1:  "strategy-code-001",
+			arg2:  ExecutionTypeStopMarket,
+			arg3:  2090,
+			want1: nil},
+		{name: "拘束されていないポジションを全て拘束して逆指値注文を送信する",
+			clock:    &testClock{Now1: time.Date(2021, 11, 4, 10, 0, 0, 0, time.Local)},
+			kabusAPI: &testKabusAPI{SendOrder1: OrderResult{Result: true, ResultCode: 0, OrderCode: "order-code-001"}},
+			strategyStore: &testStrategyStore{GetByCode1: &Strategy{
+				Code:            "strategy-code-001",
+				SymbolCode:      "1475",
+				Exchange:        ExchangeToushou,
+				Product:         ProductMargin,
+				MarginTradeType: MarginTradeTypeDay,
+				EntrySide:       SideBuy,
+				Cash:            100_000,
+				Account:         Account{AccountType: AccountTypeSpecific},
+			}},
+			orderStore: &testOrderStore{},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
+				{Code: "position-code-001", OwnedQuantity: 4, HoldQuantity: 4, Price: 100},
+				{Code: "position-code-002", OwnedQuantity: 4, HoldQuantity: 3, Price: 101},
+				{Code: "position-code-003", OwnedQuantity: 4, HoldQuantity: 2, Price: 102},
+			}},
+			arg1:  "strategy-code-001",
+			arg2:  ExecutionTypeStopLimit,
+			arg3:  2090,
+			arg4:  2085,
+			want1: nil,
+			wantSendOrderHistory: []interface{}{
+				&Strategy{
+					Code:            "strategy-code-001",
+					SymbolCode:      "1475",
+					Exchange:        ExchangeToushou,
+					Product:         ProductMargin,
+					MarginTradeType: MarginTradeTypeDay,
+					EntrySide:       SideBuy,
+					Cash:            100_000,
+					Account:         Account{AccountType: AccountTypeSpecific}},
+				&Order{
+					Code:            "order-code-001",
+					StrategyCode:    "strategy-code-001",
+					SymbolCode:      "1475",
+					Exchange:        ExchangeToushou,
+					Status:          OrderStatusInOrder,
+					Product:         ProductMargin,
+					MarginTradeType: MarginTradeTypeDay,
+					TradeType:       TradeTypeExit,
+					Side:            SideSell,
+					ExecutionType:   ExecutionTypeStopLimit,
+					Price:           2085,
+					TriggerPrice:    2090,
+					OrderQuantity:   3.0,
+					AccountType:     AccountTypeSpecific,
+					OrderDateTime:   time.Date(2021, 11, 4, 10, 0, 0, 0, time.Local),
+					HoldPositions: []HoldPosition{
+						{PositionCode: "position-code-002", HoldQuantity: 1, Price: 101},
+						{PositionCode: "position-code-003", HoldQuantity: 2, Price: 102},
+					},
+				},
+			}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &orderService{
+				clock:         test.clock,
+				kabusAPI:      test.kabusAPI,
+				orderStore:    test.orderStore,
+				positionStore: test.positionStore,
+				strategyStore: test.strategyStore,
+				riskManager:   &testRiskManager{},
+			}
+			got1 := service.ExitStop(test.arg1, test.arg2, test.arg3, test.arg4)
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantSendOrderHistory, test.kabusAPI.SendOrderHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.wantSendOrderHistory, got1, test.kabusAPI.SendOrderHistory)
+			}
+		})
+	}
+}
+
+func Test_orderService_ForceCancelAll(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                   string
+		orderStore             *testOrderStore
+		kabusAPI               *testKabusAPI
+		arg1                   *Strategy
+		want1                  error
+		wantCancelOrderHistory []interface{}
+	}{
+		{name: "引数がnilならエラー",
+			orderStore: &testOrderStore{},
+			kabusAPI:   &testKabusAPI{},
+			arg1:       nil,
+			want1:      ErrNilArgument},
+		{name: "全取消戦略が実行不可でも送信中以外の注文を全て取り消す",
+			orderStore: &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{{Code: "order-code-001"}, {Code: "pending-001", Status: OrderStatusPending}, {Code: "order-code-002"}}},
+			kabusAPI:   &testKabusAPI{CancelOrder1: OrderResult{Result: true}},
+			arg1:       &Strategy{Code: "strategy-code-001", Account: Account{Password: "Password1234"}, CancelStrategy: CancelStrategy{Runnable: false}},
+			want1:      nil,
+			wantCancelOrderHistory: []interface{}{
+				"Password1234", "order-code-001",
+				"Password1234", "order-code-002"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &orderService{clock: &testClock{}, orderStore: test.orderStore, kabusAPI: test.kabusAPI, logger: &testLogger{}}
+			got1 := service.ForceCancelAll(test.arg1)
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantCancelOrderHistory, test.kabusAPI.CancelOrderHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.wantCancelOrderHistory, got1, test.kabusAPI.CancelOrderHistory)
+			}
+		})
+	}
+}
+
+func Test_orderService_ForceExitAll(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                 string
+		kabusAPI             *testKabusAPI
+		positionStore        *testPositionStore
+		arg1                 *Strategy
+		want1                error
+		wantSendOrderHistory []interface{}
+	}{
+		{name: "引数がnilならエラー",
+			kabusAPI:      &testKabusAPI{},
+			positionStore: &testPositionStore{},
+			arg1:          nil,
+			want1:         ErrNilArgument},
+		{name: "ポジションの取得に失敗したらエラー",
+			kabusAPI:      &testKabusAPI{},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode2: ErrUnknown},
+			arg1:          &Strategy{Code: "strategy-code-001"},
+			want1:         ErrUnknown},
+		{name: "拘束されていない数量がなければ何もしない",
+			kabusAPI:      &testKabusAPI{},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Code: "position-code-001", OwnedQuantity: 4, HoldQuantity: 4, Price: 100}}},
+			arg1:          &Strategy{Code: "strategy-code-001"},
+			want1:         nil},
+		{name: "全エグジット戦略が実行不可でも拘束されていないポジションを成行でエグジットする",
+			kabusAPI: &testKabusAPI{SendOrder1: OrderResult{Result: true, ResultCode: 0, OrderCode: "order-code-001"}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
+				{Code: "position-code-001", OwnedQuantity: 4, HoldQuantity: 4, Price: 100},
+				{Code: "position-code-002", OwnedQuantity: 4, HoldQuantity: 1, Price: 101}}},
+			arg1: &Strategy{
+				Code:            "strategy-code-001",
+				SymbolCode:      "1475",
+				Exchange:        ExchangeToushou,
+				Product:         ProductMargin,
+				MarginTradeType: MarginTradeTypeDay,
+				EntrySide:       SideBuy,
+				Account:         Account{AccountType: AccountTypeSpecific}},
+			want1: nil,
+			wantSendOrderHistory: []interface{}{
+				&Strategy{
+					Code:            "strategy-code-001",
+					SymbolCode:      "1475",
+					Exchange:        ExchangeToushou,
+					Product:         ProductMargin,
+					MarginTradeType: MarginTradeTypeDay,
+					EntrySide:       SideBuy,
+					Account:         Account{AccountType: AccountTypeSpecific}},
+				&Order{
+					Code:            "order-code-001",
+					StrategyCode:    "strategy-code-001",
+					SymbolCode:      "1475",
+					Exchange:        ExchangeToushou,
+					Status:          OrderStatusInOrder,
+					Product:         ProductMargin,
+					MarginTradeType: MarginTradeTypeDay,
+					TradeType:       TradeTypeExit,
+					Side:            SideSell,
+					ExecutionType:   ExecutionTypeMarket,
+					OrderQuantity:   3,
+					AccountType:     AccountTypeSpecific,
+					OrderDateTime:   time.Date(2021, 11, 4, 10, 0, 0, 0, time.Local),
+					HoldPositions: []HoldPosition{
+						{PositionCode: "position-code-002", HoldQuantity: 3, Price: 101}}}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &orderService{
+				clock:         &testClock{Now1: time.Date(2021, 11, 4, 10, 0, 0, 0, time.Local)},
+				kabusAPI:      test.kabusAPI,
+				orderStore:    &testOrderStore{},
+				positionStore: test.positionStore,
+				logger:        &testLogger{},
+				riskManager:   &testRiskManager{},
+			}
+			got1 := service.ForceExitAll(test.arg1)
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantSendOrderHistory, test.kabusAPI.SendOrderHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.wantSendOrderHistory, got1, test.kabusAPI.SendOrderHistory)
+			}
+		})
+	}
+}
+
 func Test_newOrderService(t *testing.T) {
 	t.Parallel()
 	clock := &testClock{}
@@ -1929,6 +2398,7 @@ func Test_newOrderService(t *testing.T) {
 	strategyStore := &testStrategyStore{}
 	orderStore := &testOrderStore{}
 	positionStore := &testPositionStore{}
+	riskManager := &testRiskManager{}
 	logger := &testLogger{}
 	want1 := &orderService{
 		clock:         clock,
@@ -1936,9 +2406,10 @@ func Test_newOrderService(t *testing.T) {
 		strategyStore: strategyStore,
 		orderStore:    orderStore,
 		positionStore: positionStore,
+		riskManager:   riskManager,
 		logger:        logger,
 	}
-	got1 := newOrderService(clock, kabusAPI, strategyStore, orderStore, positionStore, logger)
+	got1 := newOrderService(clock, kabusAPI, strategyStore, orderStore, positionStore, riskManager, logger)
 	if !reflect.DeepEqual(want1, got1) {
 		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
 	}
@@ -2082,3 +2553,459 @@ func Test_orderService_handleCancelOrderError(t *testing.T) {
 		})
 	}
 }
+
+func Test_orderService_submit_pending(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2021, 11, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name             string
+		kabusAPI         *testKabusAPI
+		arg1             *Strategy
+		arg2             *Order
+		want1            error
+		wantStore        map[string]*Order
+		wantReleaseCount int
+		wantRecordCount  int
+	}{
+		{name: "一時的なエラーで証券会社の注文に見つかれば、証券会社の注文コードで保存してnil",
+			kabusAPI: &testKabusAPI{
+				SendOrder2: ErrKabusTransient,
+				GetOrders1: []SecurityOrder{{Code: "order-code-001", TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now}}},
+			arg1:            &Strategy{Code: "strategy-code-001"},
+			arg2:            &Order{StrategyCode: "strategy-code-001", TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+			want1:           nil,
+			wantStore:       map[string]*Order{"order-code-001": {Code: "order-code-001", Status: OrderStatusInOrder, StrategyCode: "strategy-code-001", TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now}},
+			wantRecordCount: 1},
+		{name: "逆指値の送信が一時的なエラーでも、価格0で受け付けられた証券会社の注文に見つかれば確定する",
+			kabusAPI: &testKabusAPI{
+				SendOrder2: ErrKabusTransient,
+				GetOrders1: []SecurityOrder{{Code: "order-code-001", TradeType: TradeTypeExit, Side: SideSell, Price: 0, OrderQuantity: 1, OrderDateTime: now.Add(time.Second)}}},
+			arg1: &Strategy{Code: "strategy-code-001"},
+			arg2: &Order{StrategyCode: "strategy-code-001", TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeStopMarket, TriggerPrice: 990, OrderQuantity: 1, OrderDateTime: now,
+				HoldPositions: []HoldPosition{{PositionCode: "position-code-001", HoldQuantity: 1}}},
+			want1: nil,
+			wantStore: map[string]*Order{"order-code-001": {Code: "order-code-001", Status: OrderStatusInOrder, StrategyCode: "strategy-code-001", TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeStopMarket, TriggerPrice: 990, OrderQuantity: 1, OrderDateTime: now,
+				HoldPositions: []HoldPosition{{PositionCode: "position-code-001", HoldQuantity: 1}}}},
+			wantRecordCount: 1},
+		{name: "一時的なエラーで証券会社の注文に見つからなければ、送信中のまま残してエラー",
+			kabusAPI: &testKabusAPI{SendOrder2: ErrKabusTransient},
+			arg1:     &Strategy{Code: "strategy-code-001"},
+			arg2: &Order{StrategyCode: "strategy-code-001", TradeType: TradeTypeExit, Side: SideSell, Price: 1000, OrderQuantity: 1, OrderDateTime: now,
+				HoldPositions: []HoldPosition{{PositionCode: "position-code-001", HoldQuantity: 1}}},
+			want1: ErrPendingOrder,
+			wantStore: map[string]*Order{fmt.Sprintf("pending-strategy-code-001-%d-1", now.UnixNano()): {
+				Code: fmt.Sprintf("pending-strategy-code-001-%d-1", now.UnixNano()), Status: OrderStatusPending, StrategyCode: "strategy-code-001", TradeType: TradeTypeExit, Side: SideSell, Price: 1000, OrderQuantity: 1, OrderDateTime: now,
+				HoldPositions: []HoldPosition{{PositionCode: "position-code-001", HoldQuantity: 1}}}}},
+		{name: "一時的なエラーで注文一覧の取得にも失敗したら、送信中のまま残してエラー",
+			kabusAPI: &testKabusAPI{SendOrder2: ErrKabusTransient, GetOrders2: ErrUnknown},
+			arg1:     &Strategy{Code: "strategy-code-001"},
+			arg2:     &Order{StrategyCode: "strategy-code-001", OrderDateTime: now},
+			want1:    ErrUnknown,
+			wantStore: map[string]*Order{fmt.Sprintf("pending-strategy-code-001-%d-1", now.UnixNano()): {
+				Code: fmt.Sprintf("pending-strategy-code-001-%d-1", now.UnixNano()), Status: OrderStatusPending, StrategyCode: "strategy-code-001", OrderDateTime: now}}},
+		{name: "一時的でないエラーなら、送信中の注文を破棄してポジションを解放してエラー",
+			kabusAPI: &testKabusAPI{SendOrder2: ErrInsufficientFunds},
+			arg1:     &Strategy{Code: "strategy-code-001"},
+			arg2: &Order{StrategyCode: "strategy-code-001", OrderDateTime: now,
+				HoldPositions: []HoldPosition{{PositionCode: "position-code-001", HoldQuantity: 1}}},
+			want1:            ErrInsufficientFunds,
+			wantStore:        map[string]*Order{},
+			wantReleaseCount: 1},
+		{name: "注文が受け付けられなかったら、送信中の注文を破棄して注文数に記録せずにエラー",
+			kabusAPI:  &testKabusAPI{SendOrder1: OrderResult{Result: false}},
+			arg1:      &Strategy{Code: "strategy-code-001"},
+			arg2:      &Order{StrategyCode: "strategy-code-001", OrderDateTime: now},
+			want1:     ErrOrderCondition,
+			wantStore: map[string]*Order{}},
+		{name: "注文に成功したら、送信中の注文を消して証券会社の注文コードで保存し、注文数に記録する",
+			kabusAPI:        &testKabusAPI{SendOrder1: OrderResult{Result: true, OrderCode: "order-code-001"}},
+			arg1:            &Strategy{Code: "strategy-code-001"},
+			arg2:            &Order{StrategyCode: "strategy-code-001", OrderDateTime: now},
+			want1:           nil,
+			wantStore:       map[string]*Order{"order-code-001": {Code: "order-code-001", StrategyCode: "strategy-code-001", OrderDateTime: now}},
+			wantRecordCount: 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			orderStore := &orderStore{store: map[string]*Order{}, db: &testDB{}}
+			positionStore := &testPositionStore{}
+			riskManager := &testRiskManager{}
+			service := &orderService{
+				clock:         &testClock{Now1: now},
+				kabusAPI:      test.kabusAPI,
+				orderStore:    orderStore,
+				positionStore: positionStore,
+				logger:        &testLogger{},
+				riskManager:   riskManager,
+			}
+			got1 := service.submit(test.arg1, test.arg2)
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantStore, orderStore.store) || !reflect.DeepEqual(test.wantReleaseCount, positionStore.ReleaseCount) ||
+				!reflect.DeepEqual(test.wantRecordCount, riskManager.RecordCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v\n", t.Name(), test.want1, test.wantStore, test.wantReleaseCount, test.wantRecordCount,
+					got1, orderStore.store, positionStore.ReleaseCount, riskManager.RecordCount)
+			}
+		})
+	}
+}
+
+func Test_orderService_SettlePendingOrders(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2021, 11, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name                    string
+		orderStore              *testOrderStore
+		kabusAPI                *testKabusAPI
+		arg1                    *Strategy
+		want1                   error
+		wantGetOrdersCount      int
+		wantDeleteByCodeHistory []interface{}
+	}{
+		{name: "引数がnilならエラー",
+			orderStore: &testOrderStore{},
+			kabusAPI:   &testKabusAPI{},
+			arg1:       nil,
+			want1:      ErrNilArgument},
+		{name: "有効な注文の取得に失敗したらエラー",
+			orderStore: &testOrderStore{GetActiveOrdersByStrategyCode2: ErrUnknown},
+			kabusAPI:   &testKabusAPI{},
+			arg1:       &Strategy{Code: "strategy-code-001"},
+			want1:      ErrUnknown},
+		{name: "送信中の注文がなければ何もしない",
+			orderStore: &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{{Code: "order-code-001", Status: OrderStatusInOrder}}},
+			kabusAPI:   &testKabusAPI{},
+			arg1:       &Strategy{Code: "strategy-code-001"},
+			want1:      nil},
+		{name: "注文一覧の取得に失敗したらエラー",
+			orderStore:         &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{{Code: "pending-001", Status: OrderStatusPending, OrderDateTime: now}}},
+			kabusAPI:           &testKabusAPI{GetOrders2: ErrUnknown},
+			arg1:               &Strategy{Code: "strategy-code-001"},
+			want1:              ErrUnknown,
+			wantGetOrdersCount: 1},
+		{name: "送信中の注文だけ確認し、猶予期間を過ぎたものは破棄する",
+			orderStore: &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{
+				{Code: "order-code-001", Status: OrderStatusInOrder},
+				{Code: "pending-001", Status: OrderStatusPending, OrderDateTime: now.Add(-2 * time.Minute)},
+				{Code: "pending-002", Status: OrderStatusPending, OrderDateTime: now.Add(-30 * time.Second)},
+			}},
+			kabusAPI:                &testKabusAPI{},
+			arg1:                    &Strategy{Code: "strategy-code-001"},
+			want1:                   nil,
+			wantGetOrdersCount:      2,
+			wantDeleteByCodeHistory: []interface{}{"pending-001"}},
+		{name: "1つの証券会社の注文では、先に送信した送信中の注文だけを確定させる",
+			orderStore: &testOrderStore{
+				GetActiveOrdersByStrategyCode1: []*Order{
+					{Code: "pending-001", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(-10 * time.Second)},
+					{Code: "pending-002", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(-20 * time.Second)},
+				},
+				GetByCode2: ErrNoData},
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{
+				{Code: "order-code-001", Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(-19 * time.Second)}}},
+			arg1:                    &Strategy{Code: "strategy-code-001"},
+			want1:                   nil,
+			wantGetOrdersCount:      2,
+			wantDeleteByCodeHistory: []interface{}{"pending-002"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &orderService{
+				clock:         &testClock{Now1: now},
+				kabusAPI:      test.kabusAPI,
+				orderStore:    test.orderStore,
+				positionStore: &testPositionStore{},
+				logger:        &testLogger{},
+				riskManager:   &testRiskManager{},
+			}
+			got1 := service.SettlePendingOrders(test.arg1)
+			if !errors.Is(got1, test.want1) ||
+				!reflect.DeepEqual(test.wantGetOrdersCount, test.kabusAPI.GetOrdersCount) ||
+				!reflect.DeepEqual(test.wantDeleteByCodeHistory, test.orderStore.DeleteByCodeHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantGetOrdersCount, test.wantDeleteByCodeHistory,
+					got1, test.kabusAPI.GetOrdersCount, test.orderStore.DeleteByCodeHistory)
+			}
+		})
+	}
+}
+
+func Test_orderService_ExpirePaperOrders(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2021, 11, 1, 9, 0, 0, 0, time.Local)
+	tests := []struct {
+		name               string
+		orderStore         *testOrderStore
+		positionStore      *testPositionStore
+		arg1               *Strategy
+		want1              error
+		wantSaveHistory    []interface{}
+		wantReleaseHistory []interface{}
+		wantNoticeCount    int
+	}{
+		{name: "引数がnilならエラー",
+			orderStore:    &testOrderStore{},
+			positionStore: &testPositionStore{},
+			arg1:          nil,
+			want1:         ErrNilArgument},
+		{name: "仮想売買の戦略でなければ何もしない",
+			orderStore:    &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{{Code: "paper-20211101085000-order-000001", Status: OrderStatusInOrder}}},
+			positionStore: &testPositionStore{},
+			arg1:          &Strategy{Code: "strategy-code-001"},
+			want1:         nil},
+		{name: "有効な注文の取得に失敗したらエラー",
+			orderStore:    &testOrderStore{GetActiveOrdersByStrategyCode2: ErrUnknown},
+			positionStore: &testPositionStore{},
+			arg1:          &Strategy{Code: "strategy-code-001", PaperTrading: true},
+			want1:         ErrUnknown},
+		{name: "疑似注文板の注文中の注文を取消にし、エグジットなら拘束していたポジションの残りを解放する",
+			orderStore: &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{
+				{Code: "paper-20211101085000-order-000001", Status: OrderStatusInOrder, TradeType: TradeTypeEntry},
+				{Code: "paper-20211101085000-order-000002", Status: OrderStatusInOrder, TradeType: TradeTypeExit,
+					HoldPositions: []HoldPosition{{PositionCode: "paper-20211101085000-contract-000001", HoldQuantity: 2, ReleaseQuantity: 1}}},
+				{Code: "pending-001", Status: OrderStatusPending},
+			}},
+			positionStore: &testPositionStore{},
+			arg1:          &Strategy{Code: "strategy-code-001", PaperTrading: true},
+			want1:         nil,
+			wantSaveHistory: []interface{}{
+				&Order{Code: "paper-20211101085000-order-000001", Status: OrderStatusCanceled, TradeType: TradeTypeEntry, CancelDateTime: now},
+				&Order{Code: "paper-20211101085000-order-000002", Status: OrderStatusCanceled, TradeType: TradeTypeExit, CancelDateTime: now,
+					HoldPositions: []HoldPosition{{PositionCode: "paper-20211101085000-contract-000001", HoldQuantity: 2, ReleaseQuantity: 2}}},
+			},
+			wantReleaseHistory: []interface{}{"paper-20211101085000-contract-000001", 1.0},
+			wantNoticeCount:    2},
+		{name: "ポジションの解放に失敗したらエラー",
+			orderStore: &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{
+				{Code: "paper-20211101085000-order-000001", Status: OrderStatusInOrder, TradeType: TradeTypeExit,
+					HoldPositions: []HoldPosition{{PositionCode: "paper-20211101085000-contract-000001", HoldQuantity: 1}}},
+			}},
+			positionStore:      &testPositionStore{Release1: ErrUnknown},
+			arg1:               &Strategy{Code: "strategy-code-001", PaperTrading: true},
+			want1:              ErrUnknown,
+			wantReleaseHistory: []interface{}{"paper-20211101085000-contract-000001", 1.0}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			logger := &testLogger{}
+			service := &orderService{
+				clock:         &testClock{Now1: now},
+				orderStore:    test.orderStore,
+				positionStore: test.positionStore,
+				logger:        logger,
+			}
+			got1 := service.ExpirePaperOrders(test.arg1)
+			if !errors.Is(got1, test.want1) ||
+				!reflect.DeepEqual(test.wantSaveHistory, test.orderStore.SaveHistory) ||
+				!reflect.DeepEqual(test.wantReleaseHistory, test.positionStore.ReleaseHistory) ||
+				!reflect.DeepEqual(test.wantNoticeCount, logger.NoticeCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantSaveHistory, test.wantReleaseHistory, test.wantNoticeCount,
+					got1, test.orderStore.SaveHistory, test.positionStore.ReleaseHistory, logger.NoticeCount)
+			}
+		})
+	}
+}
+
+func Test_orderService_settlePendingOrder(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2021, 11, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name             string
+		store            map[string]*Order
+		kabusAPI         *testKabusAPI
+		paperTrading     bool
+		arg              *Order
+		want1            error
+		wantStore        map[string]*Order
+		wantReleaseCount int
+		wantNoticeCount  int
+	}{
+		{name: "証券会社の注文に見つかれば、証券会社の注文コードで注文中として保存し、送信中の注文を消す",
+			store: map[string]*Order{"pending-001": {Code: "pending-001", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now}},
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{
+				{Code: "order-code-001", Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(time.Second)}}},
+			arg:             &Order{Code: "pending-001", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+			want1:           nil,
+			wantStore:       map[string]*Order{"order-code-001": {Code: "order-code-001", Status: OrderStatusInOrder, Price: 1000, OrderQuantity: 1, OrderDateTime: now}},
+			wantNoticeCount: 1},
+		{name: "猶予期間内で見つからなければ、送信中のまま残す",
+			store:     map[string]*Order{"pending-001": {Code: "pending-001", Status: OrderStatusPending, OrderDateTime: now.Add(-59 * time.Second)}},
+			kabusAPI:  &testKabusAPI{},
+			arg:       &Order{Code: "pending-001", Status: OrderStatusPending, OrderDateTime: now.Add(-59 * time.Second)},
+			want1:     nil,
+			wantStore: map[string]*Order{"pending-001": {Code: "pending-001", Status: OrderStatusPending, OrderDateTime: now.Add(-59 * time.Second)}}},
+		{name: "猶予期間を過ぎても見つからなければ、ポジションを解放して送信中の注文を破棄する",
+			store: map[string]*Order{"pending-001": {Code: "pending-001", Status: OrderStatusPending, OrderDateTime: now.Add(-1 * time.Minute),
+				HoldPositions: []HoldPosition{{PositionCode: "position-code-001", HoldQuantity: 1}, {PositionCode: "position-code-002", HoldQuantity: 1}}}},
+			kabusAPI: &testKabusAPI{},
+			arg: &Order{Code: "pending-001", Status: OrderStatusPending, OrderDateTime: now.Add(-1 * time.Minute),
+				HoldPositions: []HoldPosition{{PositionCode: "position-code-001", HoldQuantity: 1}, {PositionCode: "position-code-002", HoldQuantity: 1}}},
+			want1:            nil,
+			wantStore:        map[string]*Order{},
+			wantReleaseCount: 2,
+			wantNoticeCount:  1},
+		{name: "仮想売買の戦略なら、内容が一致しても証券会社の注文では確定させない",
+			store: map[string]*Order{"pending-001": {Code: "pending-001", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now}},
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{
+				{Code: "order-code-001", Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(time.Second)}}},
+			paperTrading: true,
+			arg:          &Order{Code: "pending-001", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+			want1:        nil,
+			wantStore:    map[string]*Order{"pending-001": {Code: "pending-001", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now}}},
+		{name: "仮想売買の戦略なら、疑似注文板の注文で確定させる",
+			store: map[string]*Order{"pending-001": {Code: "pending-001", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now}},
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{
+				{Code: "order-code-001", Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+				{Code: "paper-20211101090000-order-000001", Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(time.Second)}}},
+			paperTrading:    true,
+			arg:             &Order{Code: "pending-001", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+			want1:           nil,
+			wantStore:       map[string]*Order{"paper-20211101090000-order-000001": {Code: "paper-20211101090000-order-000001", Status: OrderStatusInOrder, Price: 1000, OrderQuantity: 1, OrderDateTime: now}},
+			wantNoticeCount: 1},
+		{name: "仮想売買でない戦略なら、内容が一致しても疑似注文板の注文では確定させない",
+			store: map[string]*Order{"pending-001": {Code: "pending-001", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now}},
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{
+				{Code: "paper-20211101090000-order-000001", Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(time.Second)}}},
+			arg:       &Order{Code: "pending-001", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+			want1:     nil,
+			wantStore: map[string]*Order{"pending-001": {Code: "pending-001", Status: OrderStatusPending, Price: 1000, OrderQuantity: 1, OrderDateTime: now}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			orderStore := &orderStore{store: test.store, db: &testDB{}}
+			positionStore := &testPositionStore{}
+			logger := &testLogger{}
+			service := &orderService{
+				clock:         &testClock{Now1: now},
+				kabusAPI:      test.kabusAPI,
+				orderStore:    orderStore,
+				positionStore: positionStore,
+				logger:        logger,
+				riskManager:   &testRiskManager{},
+			}
+			got1 := service.settlePendingOrder(&Strategy{Code: "strategy-code-001", PaperTrading: test.paperTrading}, test.arg, map[string]bool{})
+			if !errors.Is(got1, test.want1) ||
+				!reflect.DeepEqual(test.wantStore, orderStore.store) ||
+				!reflect.DeepEqual(test.wantReleaseCount, positionStore.ReleaseCount) ||
+				!reflect.DeepEqual(test.wantNoticeCount, logger.NoticeCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantStore, test.wantReleaseCount, test.wantNoticeCount,
+					got1, orderStore.store, positionStore.ReleaseCount, logger.NoticeCount)
+			}
+		})
+	}
+}
+
+func Test_orderService_findSecurityOrder(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2021, 11, 1, 10, 0, 0, 0, time.Local)
+	pending := &Order{Code: "pending-001", Status: OrderStatusPending, Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, ExecutionType: ExecutionTypeLimit, Price: 1000, OrderQuantity: 1, OrderDateTime: now}
+	tests := []struct {
+		name    string
+		store   map[string]*Order
+		pending *Order
+		arg     []SecurityOrder
+		claimed map[string]bool
+		want1   SecurityOrder
+		want2   bool
+	}{
+		{name: "注文がなければfalse",
+			store:   map[string]*Order{},
+			pending: pending,
+			arg:     []SecurityOrder{},
+			want1:   SecurityOrder{},
+			want2:   false},
+		{name: "内容が一致しない注文は対象外",
+			store:   map[string]*Order{},
+			pending: pending,
+			arg: []SecurityOrder{
+				{Exchange: ExchangeToushou, Code: "order-code-001", MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeExit, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+				{Exchange: ExchangeToushou, Code: "order-code-002", MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideSell, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+				{Exchange: ExchangeToushou, Code: "order-code-003", MarginTradeType: MarginTradeTypeSystem, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+				{Exchange: ExchangeToushou, Code: "order-code-004", MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1001, OrderQuantity: 1, OrderDateTime: now},
+				{Exchange: ExchangeToushou, Code: "order-code-005", MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 2, OrderDateTime: now},
+				{Exchange: ExchangeToushou, Code: "order-code-006", MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(-61 * time.Second)},
+			},
+			want1: SecurityOrder{},
+			want2: false},
+		{name: "すでに手元にある注文は対象外",
+			store:   map[string]*Order{"order-code-001": {Exchange: ExchangeToushou, Code: "order-code-001"}},
+			pending: pending,
+			arg: []SecurityOrder{
+				{Exchange: ExchangeToushou, Code: "order-code-001", MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+			},
+			want1: SecurityOrder{},
+			want2: false},
+		{name: "複数見つかったら注文日時が最も早いものを返す",
+			store:   map[string]*Order{},
+			pending: pending,
+			arg: []SecurityOrder{
+				{Exchange: ExchangeToushou, Code: "order-code-001", MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(2 * time.Second)},
+				{Exchange: ExchangeToushou, Code: "order-code-002", MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(1 * time.Second)},
+				{Exchange: ExchangeToushou, Code: "order-code-003", MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(3 * time.Second)},
+			},
+			want1: SecurityOrder{Exchange: ExchangeToushou, Code: "order-code-002", MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(1 * time.Second)},
+			want2: true},
+		{name: "市場が違う注文と、送信中の注文より猶予期間以上あとの注文は対象外",
+			store:   map[string]*Order{},
+			pending: pending,
+			arg: []SecurityOrder{
+				{Code: "order-code-001", Exchange: ExchangeUnspecified, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+				{Code: "order-code-002", Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(61 * time.Second)},
+			},
+			want1: SecurityOrder{},
+			want2: false},
+		{name: "同じ周回で確定に使った注文は対象外",
+			store:   map[string]*Order{},
+			pending: pending,
+			arg: []SecurityOrder{
+				{Code: "order-code-001", Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+				{Code: "order-code-002", Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(time.Second)},
+			},
+			claimed: map[string]bool{"order-code-001": true},
+			want1:   SecurityOrder{Code: "order-code-002", Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, Price: 1000, OrderQuantity: 1, OrderDateTime: now.Add(time.Second)},
+			want2:   true},
+		{name: "成行なら価格を比較しない",
+			store:   map[string]*Order{},
+			pending: &Order{Code: "pending-001", Status: OrderStatusPending, Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeMarket, OrderQuantity: 1, OrderDateTime: now},
+			arg: []SecurityOrder{
+				{Code: "order-code-001", Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+			},
+			want1: SecurityOrder{Code: "order-code-001", Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell, Price: 1000, OrderQuantity: 1, OrderDateTime: now},
+			want2: true},
+		{name: "逆指値なら価格0か発火後の指値価格で一致とし、発火価格が取れれば発火価格も比較する",
+			store:   map[string]*Order{},
+			pending: &Order{Code: "pending-001", Status: OrderStatusPending, Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeStopLimit, Price: 985, TriggerPrice: 990, OrderQuantity: 1, OrderDateTime: now},
+			arg: []SecurityOrder{
+				{Code: "order-code-001", Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell, Price: 990, OrderQuantity: 1, OrderDateTime: now},
+				{Code: "order-code-002", Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell, Price: 985, TriggerPrice: 995, OrderQuantity: 1, OrderDateTime: now},
+				{Code: "order-code-003", Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell, Price: 985, TriggerPrice: 990, OrderQuantity: 1, OrderDateTime: now.Add(2 * time.Second)},
+				{Code: "order-code-004", Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell, Price: 0, OrderQuantity: 1, OrderDateTime: now.Add(time.Second)},
+			},
+			want1: SecurityOrder{Code: "order-code-004", Exchange: ExchangeToushou, TradeType: TradeTypeExit, Side: SideSell, Price: 0, OrderQuantity: 1, OrderDateTime: now.Add(time.Second)},
+			want2: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &orderService{orderStore: &orderStore{store: test.store, db: &testDB{}}}
+			got1, got2 := service.findSecurityOrder(test.pending, test.arg, test.claimed)
+			if !reflect.DeepEqual(test.want1, got1) || !reflect.DeepEqual(test.want2, got2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
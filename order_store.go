@@ -30,6 +30,9 @@ type IOrderStore interface {
 	DeployFromDB() error
 	GetActiveOrdersByStrategyCode(strategyCode string) ([]*Order, error)
 	Save(order *Order) error
+	SavePending(order *Order) error
+	GetByCode(orderCode string) (*Order, error)
+	DeleteByCode(orderCode string) error
 }
 
 // orderStore - 注文ストア
@@ -98,3 +101,41 @@ func (s *orderStore) Save(order *Order) error {
 
 	return nil
 }
+
+// SavePending - 送信中の注文の保存
+// 送信前に確実に残しておく必要があるため、DBへの保存を待つ
+func (s *orderStore) SavePending(order *Order) error {
+	if order == nil {
+		return ErrNilArgument
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.store[order.Code] = order
+	return s.db.SaveOrder(order)
+}
+
+// GetByCode - コードを指定して取り出す
+func (s *orderStore) GetByCode(orderCode string) (*Order, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if o, ok := s.store[orderCode]; ok {
+		return o, nil
+	}
+	return nil, ErrNoData
+}
+
+// DeleteByCode - コードを指定して削除する
+// 送信中の注文を確定させたときに使うため、DBからの削除を待つ
+func (s *orderStore) DeleteByCode(orderCode string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.store[orderCode]; !ok {
+		return nil
+	}
+	delete(s.store, orderCode)
+	return s.db.DeleteOrderByCode(orderCode)
+}
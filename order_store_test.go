@@ -18,6 +18,27 @@ type testOrderStore struct {
 	SaveHistory                          []interface{}
 	DeployFromDB1                        error
 	DeployFromDBCount                    int
+	GetByCode1                           *Order
+	GetByCode2                           error
+	GetByCodeHistory                     []interface{}
+	SavePending1                         error
+	SavePendingCount                     int
+	SavePendingHistory                   []interface{}
+	DeleteByCode1                        error
+	DeleteByCodeCount                    int
+	DeleteByCodeHistory                  []interface{}
+}
+
+func (t *testOrderStore) SavePending(order *Order) error {
+	t.SavePendingHistory = append(t.SavePendingHistory, order)
+	t.SavePendingCount++
+	return t.SavePending1
+}
+
+func (t *testOrderStore) DeleteByCode(orderCode string) error {
+	t.DeleteByCodeHistory = append(t.DeleteByCodeHistory, orderCode)
+	t.DeleteByCodeCount++
+	return t.DeleteByCode1
 }
 
 func (t *testOrderStore) GetActiveOrdersByStrategyCode(strategyCode string) ([]*Order, error) {
@@ -34,6 +55,10 @@ func (t *testOrderStore) DeployFromDB() error {
 	t.DeployFromDBCount++
 	return t.DeployFromDB1
 }
+func (t *testOrderStore) GetByCode(orderCode string) (*Order, error) {
+	t.GetByCodeHistory = append(t.GetByCodeHistory, orderCode)
+	return t.GetByCode1, t.GetByCode2
+}
 
 func Test_orderStore_Save(t *testing.T) {
 	t.Parallel()
@@ -208,3 +233,127 @@ func Test_getOrderStore(t *testing.T) {
 		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
 	}
 }
+
+func Test_orderStore_GetByCode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		store map[string]*Order
+		arg1  string
+		want1 *Order
+		want2 error
+	}{
+		{name: "指定したコードの注文がなければエラー",
+			store: map[string]*Order{"order-code-001": {Code: "order-code-001"}},
+			arg1:  "order-code-000",
+			want2: ErrNoData},
+		{name: "指定したコードの注文があれば返す",
+			store: map[string]*Order{"order-code-001": {Code: "order-code-001"}, "order-code-002": {Code: "order-code-002"}},
+			arg1:  "order-code-002",
+			want1: &Order{Code: "order-code-002"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &orderStore{store: test.store}
+			got1, got2 := store.GetByCode(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_orderStore_SavePending(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                 string
+		db                   *testDB
+		store                map[string]*Order
+		arg1                 *Order
+		want1                error
+		wantStore            map[string]*Order
+		wantSaveOrderHistory []interface{}
+	}{
+		{name: "引数がnilならエラー",
+			db:        &testDB{},
+			store:     map[string]*Order{},
+			arg1:      nil,
+			want1:     ErrNilArgument,
+			wantStore: map[string]*Order{}},
+		{name: "DBへの保存に失敗したらエラー",
+			db:                   &testDB{SaveOrder1: ErrUnknown},
+			store:                map[string]*Order{},
+			arg1:                 &Order{Code: "pending-001", Status: OrderStatusPending},
+			want1:                ErrUnknown,
+			wantStore:            map[string]*Order{"pending-001": {Code: "pending-001", Status: OrderStatusPending}},
+			wantSaveOrderHistory: []interface{}{&Order{Code: "pending-001", Status: OrderStatusPending}}},
+		{name: "ストアに追加し、DBへの保存を待つ",
+			db:                   &testDB{},
+			store:                map[string]*Order{"order-code-001": {Code: "order-code-001"}},
+			arg1:                 &Order{Code: "pending-001", Status: OrderStatusPending},
+			want1:                nil,
+			wantStore:            map[string]*Order{"order-code-001": {Code: "order-code-001"}, "pending-001": {Code: "pending-001", Status: OrderStatusPending}},
+			wantSaveOrderHistory: []interface{}{&Order{Code: "pending-001", Status: OrderStatusPending}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &orderStore{store: test.store, db: test.db}
+			got1 := store.SavePending(test.arg1)
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantStore, store.store) || !reflect.DeepEqual(test.wantSaveOrderHistory, test.db.SaveOrderHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(), test.want1, test.wantStore, test.wantSaveOrderHistory, got1, store.store, test.db.SaveOrderHistory)
+			}
+		})
+	}
+}
+
+func Test_orderStore_DeleteByCode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                         string
+		db                           *testDB
+		store                        map[string]*Order
+		arg1                         string
+		want1                        error
+		wantStore                    map[string]*Order
+		wantDeleteOrderByCodeHistory []interface{}
+	}{
+		{name: "ストアになければ何もしない",
+			db:        &testDB{},
+			store:     map[string]*Order{"order-code-001": {Code: "order-code-001"}},
+			arg1:      "pending-001",
+			want1:     nil,
+			wantStore: map[string]*Order{"order-code-001": {Code: "order-code-001"}}},
+		{name: "DBからの削除に失敗したらエラー",
+			db:                           &testDB{DeleteOrderByCode1: ErrUnknown},
+			store:                        map[string]*Order{"pending-001": {Code: "pending-001"}},
+			arg1:                         "pending-001",
+			want1:                        ErrUnknown,
+			wantStore:                    map[string]*Order{},
+			wantDeleteOrderByCodeHistory: []interface{}{"pending-001"}},
+		{name: "ストアとDBから削除する",
+			db:                           &testDB{},
+			store:                        map[string]*Order{"order-code-001": {Code: "order-code-001"}, "pending-001": {Code: "pending-001"}},
+			arg1:                         "pending-001",
+			want1:                        nil,
+			wantStore:                    map[string]*Order{"order-code-001": {Code: "order-code-001"}},
+			wantDeleteOrderByCodeHistory: []interface{}{"pending-001"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &orderStore{store: test.store, db: test.db}
+			got1 := store.DeleteByCode(test.arg1)
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantStore, store.store) || !reflect.DeepEqual(test.wantDeleteOrderByCodeHistory, test.db.DeleteOrderByCodeHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(), test.want1, test.wantStore, test.wantDeleteOrderByCodeHistory, got1, store.store, test.db.DeleteOrderByCodeHistory)
+			}
+		})
+	}
+}
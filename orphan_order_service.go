@@ -0,0 +1,234 @@
+package gridon
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// newOrphanOrderService - 新しい孤立注文サービスの取得
+func newOrphanOrderService(clock IClock, kabusAPI IKabusAPI, orderStore IOrderStore, positionStore IPositionStore, logger ILogger) IOrphanOrderService {
+	return &orphanOrderService{
+		clock:         clock,
+		kabusAPI:      kabusAPI,
+		orderStore:    orderStore,
+		positionStore: positionStore,
+		logger:        logger,
+	}
+}
+
+// IOrphanOrderService - 孤立注文サービスのインターフェース
+type IOrphanOrderService interface {
+	Resolve(strategies []*Strategy) error
+}
+
+// orphanOrderService - 孤立注文サービス
+// 注文の送信後、保存前に落ちたときに証券会社にだけ残る注文を見つけて処理する
+type orphanOrderService struct {
+	clock         IClock
+	kabusAPI      IKabusAPI
+	orderStore    IOrderStore
+	positionStore IPositionStore
+	logger        ILogger
+}
+
+// Resolve - 証券会社にだけある注文中の注文を探し、該当する戦略の処理方針に従って取り込むか取り消す
+// 該当する戦略がないか、複数の戦略に該当する注文は、どの戦略のものか判断できないためログを出すだけにする
+// 注文の送信から保存までの間の注文を孤立注文と誤認しないよう、注文を出す処理が動き出す前に実行する
+func (s *orphanOrderService) Resolve(strategies []*Strategy) error {
+	type productSymbol struct {
+		product    Product
+		symbolCode string
+	}
+
+	// 商品種別と銘柄ごとに戦略をまとめる
+	keys := make([]productSymbol, 0)
+	groups := make(map[productSymbol][]*Strategy)
+	for _, strategy := range strategies {
+		// 仮想売買の戦略は証券会社に注文を出さないので、孤立注文の対象にしない
+		if strategy.PaperTrading {
+			continue
+		}
+		key := productSymbol{product: strategy.Product, symbolCode: strategy.SymbolCode}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], strategy)
+	}
+
+	now := s.clock.Now()
+	for _, key := range keys {
+		securityOrders, err := s.kabusAPI.GetOrders(key.product, key.symbolCode, time.Time{})
+		if err != nil {
+			return err
+		}
+
+		for _, so := range securityOrders {
+			// 注文中でないか、疑似注文板の注文か、手元にある注文ならスキップ
+			if so.Status != OrderStatusInOrder || isPaperOrderCode(so.Code) {
+				continue
+			}
+			if _, err := s.orderStore.GetByCode(so.Code); err == nil {
+				continue
+			}
+
+			targets := make([]*Strategy, 0)
+			for _, strategy := range groups[key] {
+				if s.isTarget(strategy, so, now) {
+					targets = append(targets, strategy)
+				}
+			}
+
+			switch len(targets) {
+			case 0:
+				s.logger.Warning(fmt.Errorf("孤立注文に該当する戦略がありません: %+v", so))
+			case 1:
+				s.handle(targets[0], so)
+			default:
+				s.logger.Warning(fmt.Errorf("孤立注文が複数の戦略に該当するため処理しません: %+v", so))
+			}
+		}
+	}
+
+	return nil
+}
+
+// isTarget - 孤立注文が戦略の処理対象になるか
+func (s *orphanOrderService) isTarget(strategy *Strategy, order SecurityOrder, now time.Time) bool {
+	if strategy.Exchange != order.Exchange || strategy.MarginTradeType != order.MarginTradeType {
+		return false
+	}
+
+	switch order.TradeType {
+	case TradeTypeEntry:
+		if order.Side != strategy.EntrySide {
+			return false
+		}
+	case TradeTypeExit:
+		if order.Side != strategy.EntrySide.Turn() {
+			return false
+		}
+	default:
+		return false
+	}
+
+	return strategy.OrphanOrderStrategy.IsTarget(strategy.BasePrice, order, now)
+}
+
+// handle - 戦略の処理方針に従って孤立注文を処理し、結果をログに残す
+func (s *orphanOrderService) handle(strategy *Strategy, order SecurityOrder) {
+	switch strategy.OrphanOrderStrategy.Policy {
+	case OrphanOrderPolicyAdopt:
+		adopted, err := s.adopt(strategy, order)
+		if err != nil {
+			s.logger.Warning(fmt.Errorf("%s の孤立注文の取り込みでエラーが発生しました(order = %+v): %w", strategy.Code, order, err))
+			return
+		}
+		s.logger.Notice(fmt.Sprintf("%s の注文として孤立注文を取り込みました: %s", strategy.Code, adopted))
+	case OrphanOrderPolicyCancel:
+		if err := s.cancel(strategy, order); err != nil {
+			s.logger.Warning(fmt.Errorf("%s の孤立注文の取消でエラーが発生しました(order = %+v): %w", strategy.Code, order, err))
+			return
+		}
+		s.logger.Notice(fmt.Sprintf("%s の孤立注文を取り消しました: %+v", strategy.Code, order))
+	}
+}
+
+// adopt - 孤立注文を戦略の注文として保存する
+// 約定は未反映の状態で保存し、次の約定確認でポジションや現金に反映させる
+// エグジットの注文なら、戦略のポジションを古いものから拘束し直す
+func (s *orphanOrderService) adopt(strategy *Strategy, securityOrder SecurityOrder) (*Order, error) {
+	// 証券会社の注文の執行条件がわからなければ、指値価格の有無で指値か成行かを判断する
+	executionType := securityOrder.ExecutionType
+	if executionType == ExecutionTypeUnspecified {
+		executionType = ExecutionTypeLimit
+		if securityOrder.Price <= 0 {
+			executionType = ExecutionTypeMarket
+		}
+	}
+
+	order := &Order{
+		Code:            securityOrder.Code,
+		StrategyCode:    strategy.Code,
+		SymbolCode:      strategy.SymbolCode,
+		Exchange:        strategy.Exchange,
+		Status:          OrderStatusInOrder,
+		Product:         securityOrder.Product,
+		MarginTradeType: securityOrder.MarginTradeType,
+		TradeType:       securityOrder.TradeType,
+		Side:            securityOrder.Side,
+		ExecutionType:   executionType,
+		Price:           securityOrder.Price,
+		TriggerPrice:    securityOrder.TriggerPrice,
+		OrderQuantity:   securityOrder.OrderQuantity,
+		AccountType:     securityOrder.AccountType,
+		OrderDateTime:   securityOrder.OrderDateTime,
+	}
+
+	if order.TradeType == TradeTypeExit {
+		hp, err := s.holdPositions(strategy.Code, order.OrderQuantity)
+		if err != nil {
+			return nil, err
+		}
+		order.HoldPositions = hp
+	}
+
+	if err := s.orderStore.Save(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// holdPositions - 孤立注文のエグジットに必要なポジションを古いものから拘束する
+func (s *orphanOrderService) holdPositions(strategyCode string, quantity float64) ([]HoldPosition, error) {
+	positions, err := s.positionStore.GetActivePositionsByStrategyCode(strategyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	hp := make([]HoldPosition, 0)
+	q := quantity
+	for _, p := range positions {
+		hq := math.Min(q, p.LeaveQuantity())
+		if hq <= 0 {
+			continue
+		}
+		if err := s.positionStore.Hold(p.Code, hq); err != nil {
+			// 拘束したポジションを解放する
+			// ただし、解放の処理でエラーがでたら対応できない
+			for _, h := range hp {
+				_ = s.positionStore.Release(h.PositionCode, h.HoldQuantity)
+			}
+			return nil, err
+		}
+		hp = append(hp, HoldPosition{PositionCode: p.Code, Price: p.Price, HoldQuantity: hq})
+		q -= hq
+
+		// 必要数拘束したところで抜ける
+		if q <= 0 {
+			break
+		}
+	}
+
+	// 必要数を拘束できないならエラー
+	if q > 0 {
+		for _, h := range hp {
+			_ = s.positionStore.Release(h.PositionCode, h.HoldQuantity)
+		}
+		return nil, ErrNotEnoughPosition
+	}
+
+	return hp, nil
+}
+
+// cancel - 孤立注文を取り消す
+func (s *orphanOrderService) cancel(strategy *Strategy, order SecurityOrder) error {
+	res, err := s.kabusAPI.CancelOrder(strategy.Account.Password, order.Code)
+	if err != nil {
+		return err
+	}
+	if !res.Result {
+		return fmt.Errorf("result=%+v: %w", res, ErrCancelCondition)
+	}
+	return nil
+}
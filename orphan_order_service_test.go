@@ -0,0 +1,281 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testOrphanOrderService struct {
+	IOrphanOrderService
+	Resolve1       error
+	ResolveCount   int
+	ResolveHistory []interface{}
+}
+
+func (t *testOrphanOrderService) Resolve(strategies []*Strategy) error {
+	t.ResolveHistory = append(t.ResolveHistory, strategies)
+	t.ResolveCount++
+	return t.Resolve1
+}
+
+func Test_newOrphanOrderService(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{}
+	kabusAPI := &testKabusAPI{}
+	orderStore := &testOrderStore{}
+	positionStore := &testPositionStore{}
+	logger := &testLogger{}
+	want1 := &orphanOrderService{
+		clock:         clock,
+		kabusAPI:      kabusAPI,
+		orderStore:    orderStore,
+		positionStore: positionStore,
+		logger:        logger,
+	}
+	got1 := newOrphanOrderService(clock, kabusAPI, orderStore, positionStore, logger)
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_orphanOrderService_Resolve(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)
+	adopt := &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay,
+		EntrySide: SideBuy, BasePrice: 2000, OrphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyAdopt, TimeWindow: 60, PriceRange: 10}}
+	cancel := &Strategy{Code: "strategy-code-002", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay,
+		EntrySide: SideSell, BasePrice: 2000, OrphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyCancel}, Account: Account{Password: "Password1234"}}
+	entry := SecurityOrder{Code: "order-code-101", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay,
+		TradeType: TradeTypeEntry, Side: SideBuy, Price: 1995, OrderQuantity: 2, AccountType: AccountTypeSpecific, OrderDateTime: time.Date(2022, 1, 25, 9, 30, 0, 0, time.Local)}
+
+	tests := []struct {
+		name                   string
+		kabusAPI               *testKabusAPI
+		positionStore          *testPositionStore
+		store                  map[string]*Order
+		arg1                   []*Strategy
+		want1                  error
+		wantStore              map[string]*Order
+		wantGetOrdersCount     int
+		wantCancelOrderHistory []interface{}
+		wantHoldHistory        []interface{}
+		wantNoticeCount        int
+		wantWarningCount       int
+	}{
+		{name: "注文一覧の取得に失敗したらエラー",
+			kabusAPI:           &testKabusAPI{GetOrders2: ErrUnknown},
+			positionStore:      &testPositionStore{},
+			store:              map[string]*Order{},
+			arg1:               []*Strategy{adopt},
+			want1:              ErrUnknown,
+			wantStore:          map[string]*Order{},
+			wantGetOrdersCount: 1},
+		{name: "商品種別と銘柄が同じ戦略は、注文一覧を1回だけ取得する",
+			kabusAPI:           &testKabusAPI{GetOrders1: []SecurityOrder{}},
+			positionStore:      &testPositionStore{},
+			store:              map[string]*Order{},
+			arg1:               []*Strategy{adopt, cancel, {Code: "strategy-code-003", SymbolCode: "1476", Product: ProductMargin}},
+			wantStore:          map[string]*Order{},
+			wantGetOrdersCount: 2},
+		{name: "注文中でない注文と、手元にある注文は孤立注文にしない",
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{
+				{Code: "order-code-001", Status: OrderStatusDone},
+				{Code: "order-code-002", Status: OrderStatusInOrder}}},
+			positionStore:      &testPositionStore{},
+			store:              map[string]*Order{"order-code-002": {Code: "order-code-002"}},
+			arg1:               []*Strategy{adopt},
+			wantStore:          map[string]*Order{"order-code-002": {Code: "order-code-002"}},
+			wantGetOrdersCount: 1},
+		{name: "仮想売買の戦略は孤立注文の対象にせず、注文一覧も取得しない",
+			kabusAPI:      &testKabusAPI{GetOrders1: []SecurityOrder{entry}},
+			positionStore: &testPositionStore{},
+			store:         map[string]*Order{},
+			arg1: []*Strategy{{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay,
+				EntrySide: SideBuy, BasePrice: 2000, PaperTrading: true, OrphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyAdopt, TimeWindow: 60, PriceRange: 10}}},
+			wantStore: map[string]*Order{}},
+		{name: "疑似注文板の注文は孤立注文にしない",
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{{Code: "paper-20220125090000-order-000001", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay,
+				TradeType: TradeTypeEntry, Side: SideBuy, Price: 1995, OrderQuantity: 2, AccountType: AccountTypeSpecific, OrderDateTime: time.Date(2022, 1, 25, 9, 30, 0, 0, time.Local)}}},
+			positionStore:      &testPositionStore{},
+			store:              map[string]*Order{},
+			arg1:               []*Strategy{adopt},
+			wantStore:          map[string]*Order{},
+			wantGetOrdersCount: 1},
+		{name: "該当する戦略がなければログだけ出す",
+			kabusAPI:           &testKabusAPI{GetOrders1: []SecurityOrder{entry}},
+			positionStore:      &testPositionStore{},
+			store:              map[string]*Order{},
+			arg1:               []*Strategy{cancel},
+			wantStore:          map[string]*Order{},
+			wantGetOrdersCount: 1,
+			wantWarningCount:   1},
+		{name: "複数の戦略に該当したらログだけ出す",
+			kabusAPI:           &testKabusAPI{GetOrders1: []SecurityOrder{entry}},
+			positionStore:      &testPositionStore{},
+			store:              map[string]*Order{},
+			arg1:               []*Strategy{adopt, {Code: "strategy-code-004", SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, MarginTradeType: MarginTradeTypeDay, EntrySide: SideBuy, OrphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyCancel}}},
+			wantStore:          map[string]*Order{},
+			wantGetOrdersCount: 1,
+			wantWarningCount:   1},
+		{name: "取り込む戦略に該当したエントリーは、約定未反映の注文として保存する",
+			kabusAPI:      &testKabusAPI{GetOrders1: []SecurityOrder{entry}},
+			positionStore: &testPositionStore{},
+			store:         map[string]*Order{},
+			arg1:          []*Strategy{adopt, cancel},
+			wantStore: map[string]*Order{"order-code-101": {Code: "order-code-101", StrategyCode: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, Status: OrderStatusInOrder,
+				Product: ProductMargin, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy, ExecutionType: ExecutionTypeLimit, Price: 1995, OrderQuantity: 2,
+				AccountType: AccountTypeSpecific, OrderDateTime: time.Date(2022, 1, 25, 9, 30, 0, 0, time.Local)}},
+			wantGetOrdersCount: 1,
+			wantNoticeCount:    1},
+		{name: "取り込む戦略に該当したエグジットは、ポジションを拘束し直して保存する",
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{{Code: "order-code-102", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin,
+				MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeExit, Side: SideSell, OrderQuantity: 3, ContractQuantity: 1, OrderDateTime: time.Date(2022, 1, 25, 9, 30, 0, 0, time.Local)}}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
+				{Code: "position-code-001", Price: 1990, OwnedQuantity: 2, HoldQuantity: 1},
+				{Code: "position-code-002", Price: 1995, OwnedQuantity: 2}}},
+			store: map[string]*Order{},
+			arg1:  []*Strategy{adopt},
+			wantStore: map[string]*Order{"order-code-102": {Code: "order-code-102", StrategyCode: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, Status: OrderStatusInOrder,
+				Product: ProductMargin, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeMarket, OrderQuantity: 3,
+				OrderDateTime: time.Date(2022, 1, 25, 9, 30, 0, 0, time.Local),
+				HoldPositions: []HoldPosition{{PositionCode: "position-code-001", Price: 1990, HoldQuantity: 1}, {PositionCode: "position-code-002", Price: 1995, HoldQuantity: 2}}}},
+			wantGetOrdersCount: 1,
+			wantHoldHistory:    []interface{}{"position-code-001", 1.0, "position-code-002", 2.0},
+			wantNoticeCount:    1},
+		{name: "逆指値の孤立注文は、執行条件と発火価格を引き継いで取り込む",
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{{Code: "order-code-104", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin,
+				MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeStopLimit, Price: 1992, TriggerPrice: 1995, OrderQuantity: 2,
+				OrderDateTime: time.Date(2022, 1, 25, 9, 30, 0, 0, time.Local)}}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Code: "position-code-001", Price: 1990, OwnedQuantity: 2}}},
+			store:         map[string]*Order{},
+			arg1:          []*Strategy{adopt},
+			wantStore: map[string]*Order{"order-code-104": {Code: "order-code-104", StrategyCode: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, Status: OrderStatusInOrder,
+				Product: ProductMargin, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeStopLimit, Price: 1992, TriggerPrice: 1995, OrderQuantity: 2,
+				OrderDateTime: time.Date(2022, 1, 25, 9, 30, 0, 0, time.Local),
+				HoldPositions: []HoldPosition{{PositionCode: "position-code-001", Price: 1990, HoldQuantity: 2}}}},
+			wantGetOrdersCount: 1,
+			wantHoldHistory:    []interface{}{"position-code-001", 2.0},
+			wantNoticeCount:    1},
+		{name: "発火価格のわからない逆指値(成行)の孤立注文も、逆指値として取り込む",
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{{Code: "order-code-105", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin,
+				MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeStopMarket, OrderQuantity: 2,
+				OrderDateTime: time.Date(2022, 1, 25, 9, 30, 0, 0, time.Local)}}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Code: "position-code-001", Price: 1990, OwnedQuantity: 2}}},
+			store:         map[string]*Order{},
+			arg1:          []*Strategy{adopt},
+			wantStore: map[string]*Order{"order-code-105": {Code: "order-code-105", StrategyCode: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, Status: OrderStatusInOrder,
+				Product: ProductMargin, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeExit, Side: SideSell, ExecutionType: ExecutionTypeStopMarket, OrderQuantity: 2,
+				OrderDateTime: time.Date(2022, 1, 25, 9, 30, 0, 0, time.Local),
+				HoldPositions: []HoldPosition{{PositionCode: "position-code-001", Price: 1990, HoldQuantity: 2}}}},
+			wantGetOrdersCount: 1,
+			wantHoldHistory:    []interface{}{"position-code-001", 2.0},
+			wantNoticeCount:    1},
+		{name: "エグジットに必要なポジションを拘束できなければ取り込まずにログを出す",
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{{Code: "order-code-102", Status: OrderStatusInOrder, SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin,
+				MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeExit, Side: SideSell, OrderQuantity: 3, OrderDateTime: time.Date(2022, 1, 25, 9, 30, 0, 0, time.Local)}}},
+			positionStore:      &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Code: "position-code-001", Price: 1990, OwnedQuantity: 2}}},
+			store:              map[string]*Order{},
+			arg1:               []*Strategy{adopt},
+			wantStore:          map[string]*Order{},
+			wantGetOrdersCount: 1,
+			wantHoldHistory:    []interface{}{"position-code-001", 2.0},
+			wantWarningCount:   1},
+		{name: "取り消す戦略に該当したら取り消す",
+			kabusAPI: &testKabusAPI{
+				GetOrders1:   []SecurityOrder{{Code: "order-code-103", Status: OrderStatusInOrder, Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideSell}},
+				CancelOrder1: OrderResult{Result: true, OrderCode: "order-code-103"}},
+			positionStore:          &testPositionStore{},
+			store:                  map[string]*Order{},
+			arg1:                   []*Strategy{adopt, cancel},
+			wantStore:              map[string]*Order{},
+			wantGetOrdersCount:     1,
+			wantCancelOrderHistory: []interface{}{"Password1234", "order-code-103"},
+			wantNoticeCount:        1},
+		{name: "取消に失敗したらログを出す",
+			kabusAPI: &testKabusAPI{
+				GetOrders1:   []SecurityOrder{{Code: "order-code-103", Status: OrderStatusInOrder, Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideSell}},
+				CancelOrder1: OrderResult{Result: false, ResultCode: 1}},
+			positionStore:          &testPositionStore{},
+			store:                  map[string]*Order{},
+			arg1:                   []*Strategy{cancel},
+			wantStore:              map[string]*Order{},
+			wantGetOrdersCount:     1,
+			wantCancelOrderHistory: []interface{}{"Password1234", "order-code-103"},
+			wantWarningCount:       1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			logger := &testLogger{}
+			service := &orphanOrderService{
+				clock:         &testClock{Now1: now},
+				kabusAPI:      test.kabusAPI,
+				orderStore:    &orderStore{store: test.store, db: &testDB{}},
+				positionStore: test.positionStore,
+				logger:        logger,
+			}
+			got1 := service.Resolve(test.arg1)
+			if !errors.Is(got1, test.want1) ||
+				!reflect.DeepEqual(test.wantStore, test.store) ||
+				!reflect.DeepEqual(test.wantGetOrdersCount, test.kabusAPI.GetOrdersCount) ||
+				!reflect.DeepEqual(test.wantCancelOrderHistory, test.kabusAPI.CancelOrderHistory) ||
+				!reflect.DeepEqual(test.wantHoldHistory, test.positionStore.HoldHistory) ||
+				!reflect.DeepEqual(test.wantNoticeCount, logger.NoticeCount) ||
+				!reflect.DeepEqual(test.wantWarningCount, logger.WarningCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantStore, test.wantGetOrdersCount, test.wantCancelOrderHistory, test.wantHoldHistory, test.wantNoticeCount, test.wantWarningCount,
+					got1, test.store, test.kabusAPI.GetOrdersCount, test.kabusAPI.CancelOrderHistory, test.positionStore.HoldHistory, logger.NoticeCount, logger.WarningCount)
+			}
+		})
+	}
+}
+
+func Test_orphanOrderService_isTarget(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)
+	strategy := &Strategy{Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, EntrySide: SideBuy, BasePrice: 2000,
+		OrphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyAdopt}}
+	tests := []struct {
+		name  string
+		arg1  SecurityOrder
+		want1 bool
+	}{
+		{name: "市場が違えば対象外",
+			arg1:  SecurityOrder{Exchange: ExchangeMeishou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy},
+			want1: false},
+		{name: "信用取引区分が違えば対象外",
+			arg1:  SecurityOrder{Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeSystem, TradeType: TradeTypeEntry, Side: SideBuy},
+			want1: false},
+		{name: "エントリーで方向がエントリー方向と違えば対象外",
+			arg1:  SecurityOrder{Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideSell},
+			want1: false},
+		{name: "エグジットで方向がエントリー方向の反対と違えば対象外",
+			arg1:  SecurityOrder{Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeExit, Side: SideBuy},
+			want1: false},
+		{name: "取引種別が未指定なら対象外",
+			arg1:  SecurityOrder{Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, Side: SideBuy},
+			want1: false},
+		{name: "エントリーで方向が一致すれば対象",
+			arg1:  SecurityOrder{Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeEntry, Side: SideBuy},
+			want1: true},
+		{name: "エグジットで方向がエントリー方向の反対なら対象",
+			arg1:  SecurityOrder{Exchange: ExchangeToushou, MarginTradeType: MarginTradeTypeDay, TradeType: TradeTypeExit, Side: SideSell},
+			want1: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &orphanOrderService{}
+			got1 := service.isTarget(strategy, test.arg1, now)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package gridon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// paperCodePrefix - 疑似注文板の注文コードや約定コードの接頭辞
+const paperCodePrefix = "paper-"
+
+// newPaperKabusAPI - 仮想売買に対応したkabusAPIの取得
+// 注文コードが再起動前の注文と重複しないよう、起動時刻を注文コードの接頭辞に含める
+func newPaperKabusAPI(kabusAPI IKabusAPI, clock IClock) IKabusAPI {
+	return &paperKabusAPI{
+		kabusAPI: kabusAPI,
+		clock:    clock,
+		book:     newOrderBook(fmt.Sprintf("%s%s", paperCodePrefix, clock.Now().Format("20060102150405"))),
+	}
+}
+
+// isPaperOrderCode - 疑似注文板で採番した注文コードかどうか
+func isPaperOrderCode(orderCode string) bool {
+	return strings.HasPrefix(orderCode, paperCodePrefix)
+}
+
+// strategySecurityOrders - 戦略が扱う注文だけに絞り込む
+// 注文一覧には証券会社の注文と疑似注文板の注文が混ざるので、仮想売買の戦略なら疑似注文板の注文だけ、そうでなければ証券会社の注文だけを返す
+func strategySecurityOrders(strategy *Strategy, securityOrders []SecurityOrder) []SecurityOrder {
+	orders := make([]SecurityOrder, 0)
+	for _, so := range securityOrders {
+		if isPaperOrderCode(so.Code) == strategy.PaperTrading {
+			orders = append(orders, so)
+		}
+	}
+	return orders
+}
+
+// paperKabusAPI - 仮想売買に対応したkabusAPI
+// 価格の取得は証券会社から行ない、仮想売買の戦略の注文だけを疑似注文板で約定させる
+// 仮想売買の注文はプロセス内にだけ保持するため、再起動前の注文は起動時に注文サービスで失効させる
+type paperKabusAPI struct {
+	kabusAPI IKabusAPI
+	clock    IClock
+	book     *orderBook
+}
+
+// GetSymbol - 銘柄情報の取得
+func (k *paperKabusAPI) GetSymbol(symbolCode string, exchange Exchange) (*Symbol, error) {
+	return k.kabusAPI.GetSymbol(symbolCode, exchange)
+}
+
+// GetOrders - 注文一覧の取得
+// 証券会社の注文に、現在値で約定判定をした疑似注文板の注文を加えて返す
+// 戦略ごとの処理ではstrategySecurityOrdersで戦略が扱う注文だけに絞り込んで使う
+func (k *paperKabusAPI) GetOrders(product Product, symbolCode string, updateDateTime time.Time) ([]SecurityOrder, error) {
+	orders, err := k.kabusAPI.GetOrders(product, symbolCode, updateDateTime)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range k.book.ActiveSymbols() {
+		if key.SymbolCode != symbolCode {
+			continue
+		}
+
+		symbol, err := k.kabusAPI.GetSymbol(key.SymbolCode, key.Exchange)
+		if err != nil {
+			return nil, err
+		}
+		k.book.Match(key.SymbolCode, key.Exchange, symbol.CurrentPrice, k.clock.Now())
+	}
+	k.book.Expire(k.clock.Now())
+
+	return append(orders, k.book.Orders(product, symbolCode, updateDateTime)...), nil
+}
+
+// CancelOrder - 注文の取消
+// 疑似注文板の注文コードなら疑似注文板で取り消し、それ以外は証券会社に取消を送る
+// 再起動前の疑似注文板の注文は疑似注文板にないので、取り消せない注文として扱う
+func (k *paperKabusAPI) CancelOrder(orderPassword string, orderCode string) (OrderResult, error) {
+	if isPaperOrderCode(orderCode) {
+		return k.book.Cancel(orderCode, k.clock.Now())
+	}
+	return k.kabusAPI.CancelOrder(orderPassword, orderCode)
+}
+
+// SendOrder - 注文の送信
+// 仮想売買の戦略の注文なら疑似注文板に入れ、それ以外は証券会社に送る
+func (k *paperKabusAPI) SendOrder(strategy *Strategy, order *Order) (OrderResult, error) {
+	if strategy == nil || order == nil {
+		return OrderResult{}, ErrNilArgument
+	}
+
+	if !strategy.PaperTrading {
+		return k.kabusAPI.SendOrder(strategy, order)
+	}
+
+	symbol, err := k.kabusAPI.GetSymbol(order.SymbolCode, order.Exchange)
+	if err != nil {
+		return OrderResult{}, err
+	}
+	return k.book.Send(order, k.clock.Now(), symbol.BidPrice, symbol.AskPrice)
+}
+
+// GetFourPrice - 四本値の取得
+func (k *paperKabusAPI) GetFourPrice(symbolCode string, exchange Exchange) (*FourPrice, error) {
+	return k.kabusAPI.GetFourPrice(symbolCode, exchange)
+}
+
+// GetPositions - ポジション一覧の取得
+// 証券会社のポジションに、疑似注文板の約定から組み立てたポジションを加えて返す
+func (k *paperKabusAPI) GetPositions(product Product, symbolCode string) ([]SecurityPosition, error) {
+	positions, err := k.kabusAPI.GetPositions(product, symbolCode)
+	if err != nil {
+		return nil, err
+	}
+	return append(positions, k.book.Positions(product, symbolCode)...), nil
+}
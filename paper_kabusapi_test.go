@@ -0,0 +1,296 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_newPaperKabusAPI(t *testing.T) {
+	t.Parallel()
+	kabusAPI := &testKabusAPI{}
+	clock := &testClock{Now1: time.Date(2022, 1, 25, 8, 30, 0, 0, time.Local)}
+	want1 := &paperKabusAPI{kabusAPI: kabusAPI, clock: clock, book: newOrderBook("paper-20220125083000")}
+	got1 := newPaperKabusAPI(kabusAPI, clock)
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_isPaperOrderCode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg  string
+		want bool
+	}{
+		{name: "疑似注文板の注文コードならtrue", arg: "paper-20220125083000-order-000001", want: true},
+		{name: "証券会社の注文コードならfalse", arg: "20220125A02N00000001", want: false},
+		{name: "空文字ならfalse", arg: "", want: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := isPaperOrderCode(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_strategySecurityOrders(t *testing.T) {
+	t.Parallel()
+	orders := []SecurityOrder{{Code: "20220125A02N00000001"}, {Code: "paper-20220125083000-order-000001"}, {Code: "20220125A02N00000002"}}
+	tests := []struct {
+		name  string
+		arg1  *Strategy
+		arg2  []SecurityOrder
+		want1 []SecurityOrder
+	}{
+		{name: "仮想売買の戦略なら疑似注文板の注文だけを返す",
+			arg1:  &Strategy{PaperTrading: true},
+			arg2:  orders,
+			want1: []SecurityOrder{{Code: "paper-20220125083000-order-000001"}}},
+		{name: "仮想売買でない戦略なら証券会社の注文だけを返す",
+			arg1:  &Strategy{},
+			arg2:  orders,
+			want1: []SecurityOrder{{Code: "20220125A02N00000001"}, {Code: "20220125A02N00000002"}}},
+		{name: "注文がなければ空のスライスを返す",
+			arg1:  &Strategy{},
+			arg2:  nil,
+			want1: []SecurityOrder{}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := strategySecurityOrders(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_paperKabusAPI_SendOrder(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                 string
+		kabusAPI             *testKabusAPI
+		arg1                 *Strategy
+		arg2                 *Order
+		want1                OrderResult
+		want2                error
+		wantSendOrderCount   int
+		wantBookOrderCodes   []string
+		wantBookContractSize int
+	}{
+		{name: "引数がnilならエラー",
+			kabusAPI:           &testKabusAPI{},
+			want2:              ErrNilArgument,
+			wantBookOrderCodes: []string{}},
+		{name: "仮想売買でなければ証券会社に注文を送る",
+			kabusAPI:           &testKabusAPI{SendOrder1: OrderResult{Result: true, OrderCode: "order-code-001"}},
+			arg1:               &Strategy{},
+			arg2:               &Order{SymbolCode: "1475", Exchange: ExchangeToushou, ExecutionType: ExecutionTypeLimit, Price: 2000, OrderQuantity: 1},
+			want1:              OrderResult{Result: true, OrderCode: "order-code-001"},
+			wantSendOrderCount: 1,
+			wantBookOrderCodes: []string{}},
+		{name: "仮想売買で銘柄情報の取得に失敗したらエラー",
+			kabusAPI:           &testKabusAPI{GetSymbol2: ErrUnknown},
+			arg1:               &Strategy{PaperTrading: true},
+			arg2:               &Order{SymbolCode: "1475", Exchange: ExchangeToushou, ExecutionType: ExecutionTypeLimit, Price: 2000, OrderQuantity: 1},
+			want2:              ErrUnknown,
+			wantBookOrderCodes: []string{}},
+		{name: "仮想売買の指値注文は疑似注文板に入る",
+			kabusAPI:           &testKabusAPI{GetSymbol1: &Symbol{BidPrice: 2010, AskPrice: 2011}},
+			arg1:               &Strategy{PaperTrading: true},
+			arg2:               &Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, Side: SideBuy, ExecutionType: ExecutionTypeLimit, Price: 2000, OrderQuantity: 1},
+			want1:              OrderResult{Result: true, OrderCode: "paper-order-000001"},
+			wantBookOrderCodes: []string{"paper-order-000001"}},
+		{name: "仮想売買の成行注文は受付時点の気配値で約定する",
+			kabusAPI:             &testKabusAPI{GetSymbol1: &Symbol{BidPrice: 2010, AskPrice: 2011}},
+			arg1:                 &Strategy{PaperTrading: true},
+			arg2:                 &Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, Side: SideBuy, ExecutionType: ExecutionTypeMarket, OrderQuantity: 1},
+			want1:                OrderResult{Result: true, OrderCode: "paper-order-000001"},
+			wantBookOrderCodes:   []string{"paper-order-000001"},
+			wantBookContractSize: 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			api := &paperKabusAPI{kabusAPI: test.kabusAPI, clock: &testClock{Now1: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)}, book: newOrderBook("paper")}
+			got1, got2 := api.SendOrder(test.arg1, test.arg2)
+
+			gotBookOrderCodes := make([]string, 0)
+			gotBookContractSize := 0
+			for _, o := range api.book.Orders(ProductMargin, "1475", time.Time{}) {
+				gotBookOrderCodes = append(gotBookOrderCodes, o.Code)
+				gotBookContractSize += len(o.Contracts)
+			}
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) || test.wantSendOrderCount != test.kabusAPI.SendOrderCount ||
+				!reflect.DeepEqual(test.wantBookOrderCodes, gotBookOrderCodes) || test.wantBookContractSize != gotBookContractSize {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.want2, test.wantSendOrderCount, test.wantBookOrderCodes, test.wantBookContractSize,
+					got1, got2, test.kabusAPI.SendOrderCount, gotBookOrderCodes, gotBookContractSize)
+			}
+		})
+	}
+}
+
+func Test_paperKabusAPI_CancelOrder(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                   string
+		kabusAPI               *testKabusAPI
+		arg                    string
+		want1                  OrderResult
+		want2                  error
+		wantCancelOrderHistory []interface{}
+	}{
+		{name: "疑似注文板にある注文なら疑似注文板で取り消す",
+			kabusAPI: &testKabusAPI{},
+			arg:      "paper-order-000001",
+			want1:    OrderResult{Result: true, OrderCode: "paper-order-000001"}},
+		{name: "疑似注文板にない注文なら証券会社に取消を送る",
+			kabusAPI:               &testKabusAPI{CancelOrder1: OrderResult{Result: true, OrderCode: "order-code-001"}},
+			arg:                    "order-code-001",
+			want1:                  OrderResult{Result: true, OrderCode: "order-code-001"},
+			wantCancelOrderHistory: []interface{}{"Password1234", "order-code-001"}},
+		{name: "疑似注文板の注文コードで疑似注文板にない注文なら、証券会社に送らずに取り消せない注文としてエラー",
+			kabusAPI: &testKabusAPI{},
+			arg:      "paper-20220124090000-order-000001",
+			want1:    OrderResult{},
+			want2:    (&orderBook{}).errCancelOrder("paper-20220124090000-order-000001")},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			now := time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)
+			book := newOrderBook("paper")
+			_, _ = book.Send(&Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, Side: SideBuy, ExecutionType: ExecutionTypeLimit, Price: 2000, OrderQuantity: 1}, now, 2010, 2011)
+			api := &paperKabusAPI{kabusAPI: test.kabusAPI, clock: &testClock{Now1: now}, book: book}
+			got1, got2 := api.CancelOrder("Password1234", test.arg)
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) || !reflect.DeepEqual(test.wantCancelOrderHistory, test.kabusAPI.CancelOrderHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.want2, test.wantCancelOrderHistory, got1, got2, test.kabusAPI.CancelOrderHistory)
+			}
+		})
+	}
+}
+
+func Test_paperKabusAPI_GetOrders(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		kabusAPI   *testKabusAPI
+		now        time.Time
+		wantCodes  []string
+		wantStatus []OrderStatus
+		wantErr    error
+	}{
+		{name: "証券会社の注文一覧の取得に失敗したらエラー",
+			kabusAPI: &testKabusAPI{GetOrders2: ErrUnknown},
+			now:      time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local),
+			wantErr:  ErrUnknown},
+		{name: "銘柄情報の取得に失敗したらエラー",
+			kabusAPI: &testKabusAPI{GetOrders1: []SecurityOrder{}, GetSymbol2: ErrUnknown},
+			now:      time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local),
+			wantErr:  ErrUnknown},
+		{name: "現在値が指値に達していなければ注文中のまま証券会社の注文と合わせて返す",
+			kabusAPI:   &testKabusAPI{GetOrders1: []SecurityOrder{{Code: "order-code-001", Status: OrderStatusDone}}, GetSymbol1: &Symbol{CurrentPrice: 2001}},
+			now:        time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local),
+			wantCodes:  []string{"order-code-001", "paper-order-000001"},
+			wantStatus: []OrderStatus{OrderStatusDone, OrderStatusInOrder}},
+		{name: "現在値が指値に達していれば約定させて返す",
+			kabusAPI:   &testKabusAPI{GetOrders1: []SecurityOrder{}, GetSymbol1: &Symbol{CurrentPrice: 2000}},
+			now:        time.Date(2022, 1, 25, 9, 1, 0, 0, time.Local),
+			wantCodes:  []string{"paper-order-000001"},
+			wantStatus: []OrderStatus{OrderStatusDone}},
+		{name: "有効期限日の引けを過ぎた注文は失効させて返す",
+			kabusAPI:   &testKabusAPI{GetOrders1: []SecurityOrder{}, GetSymbol1: &Symbol{CurrentPrice: 2001}},
+			now:        time.Date(2022, 1, 25, 15, 0, 0, 0, time.Local),
+			wantCodes:  []string{"paper-order-000001"},
+			wantStatus: []OrderStatus{OrderStatusCanceled}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			book := newOrderBook("paper")
+			_, _ = book.Send(&Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, Side: SideBuy, ExecutionType: ExecutionTypeLimit, Price: 2000, OrderQuantity: 1},
+				time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), 2010, 2011)
+			api := &paperKabusAPI{kabusAPI: test.kabusAPI, clock: &testClock{Now1: test.now}, book: book}
+			got, err := api.GetOrders(ProductMargin, "1475", time.Time{})
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			gotCodes := make([]string, 0)
+			gotStatus := make([]OrderStatus, 0)
+			for _, o := range got {
+				gotCodes = append(gotCodes, o.Code)
+				gotStatus = append(gotStatus, o.Status)
+			}
+			if !reflect.DeepEqual(test.wantCodes, gotCodes) || !reflect.DeepEqual(test.wantStatus, gotStatus) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.wantCodes, test.wantStatus, gotCodes, gotStatus)
+			}
+		})
+	}
+}
+
+func Test_paperKabusAPI_GetPositions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		kabusAPI  *testKabusAPI
+		wantCodes []string
+		wantErr   error
+	}{
+		{name: "証券会社のポジション一覧の取得に失敗したらエラー",
+			kabusAPI: &testKabusAPI{GetPositions2: ErrUnknown},
+			wantErr:  ErrUnknown},
+		{name: "証券会社のポジションに疑似注文板のポジションを加えて返す",
+			kabusAPI:  &testKabusAPI{GetPositions1: []SecurityPosition{{Code: "position-code-001"}}},
+			wantCodes: []string{"position-code-001", "paper-contract-000001"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			book := newOrderBook("paper")
+			_, _ = book.Send(&Order{SymbolCode: "1475", Exchange: ExchangeToushou, Product: ProductMargin, TradeType: TradeTypeEntry, Side: SideBuy, ExecutionType: ExecutionTypeMarket, OrderQuantity: 1},
+				time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), 2000, 2001)
+			api := &paperKabusAPI{kabusAPI: test.kabusAPI, clock: &testClock{}, book: book}
+			got, err := api.GetPositions(ProductMargin, "1475")
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			gotCodes := make([]string, 0)
+			for _, p := range got {
+				gotCodes = append(gotCodes, p.Code)
+			}
+			if !reflect.DeepEqual(test.wantCodes, gotCodes) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.wantCodes, gotCodes)
+			}
+		})
+	}
+}
@@ -32,7 +32,9 @@ type IPositionStore interface {
 	ExitContract(positionCode string, quantity float64) error
 	Release(positionCode string, quantity float64) error
 	GetActivePositionsByStrategyCode(strategyCode string) ([]*Position, error)
+	GetByCode(positionCode string) (*Position, error)
 	Hold(positionCode string, quantity float64) error
+	Correct(positionCode string, price float64, quantity float64) error
 }
 
 // positionStore - ポジションストア
@@ -132,6 +134,17 @@ func (s *positionStore) GetActivePositionsByStrategyCode(strategyCode string) ([
 	return positions, nil
 }
 
+// GetByCode - コードを指定して取り出す
+func (s *positionStore) GetByCode(positionCode string) (*Position, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if p, ok := s.store[positionCode]; ok {
+		return p, nil
+	}
+	return nil, ErrNoData
+}
+
 // Hold - 指定したポジションを拘束する
 func (s *positionStore) Hold(positionCode string, quantity float64) error {
 	s.mtx.Lock()
@@ -145,3 +158,22 @@ func (s *positionStore) Hold(positionCode string, quantity float64) error {
 
 	return nil
 }
+
+// Correct - 指定したポジションの約定値と保有数量を訂正する
+// 拘束数量が保有数量を超える場合は、保有数量まで減らす
+func (s *positionStore) Correct(positionCode string, price float64, quantity float64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.store[positionCode]; ok {
+		s.store[positionCode].Price = price
+		s.store[positionCode].OwnedQuantity = quantity
+		if s.store[positionCode].HoldQuantity > quantity {
+			s.store[positionCode].HoldQuantity = quantity
+		}
+
+		go s.db.SavePosition(s.store[positionCode])
+	}
+
+	return nil
+}
@@ -22,11 +22,17 @@ type testPositionStore struct {
 	GetActivePositionsByStrategyCode2       error
 	GetActivePositionsByStrategyCodeCount   int
 	GetActivePositionsByStrategyCodeHistory []interface{}
+	GetByCode1                              *Position
+	GetByCode2                              error
+	GetByCodeHistory                        []interface{}
 	Hold1                                   error
 	HoldCount                               int
 	HoldHistory                             []interface{}
 	DeployFromDB1                           error
 	DeployFromDBCount                       int
+	Correct1                                error
+	CorrectCount                            int
+	CorrectHistory                          []interface{}
 }
 
 func (t *testPositionStore) Save(position *Position) error {
@@ -40,6 +46,10 @@ func (t *testPositionStore) ExitContract(positionCode string, quantity float64)
 	t.ExitContractCount++
 	return t.ExitContract1
 }
+func (t *testPositionStore) GetByCode(positionCode string) (*Position, error) {
+	t.GetByCodeHistory = append(t.GetByCodeHistory, positionCode)
+	return t.GetByCode1, t.GetByCode2
+}
 func (t *testPositionStore) Release(positionCode string, quantity float64) error {
 	t.ReleaseHistory = append(t.ReleaseHistory, positionCode)
 	t.ReleaseHistory = append(t.ReleaseHistory, quantity)
@@ -61,6 +71,13 @@ func (t *testPositionStore) DeployFromDB() error {
 	t.DeployFromDBCount++
 	return t.DeployFromDB1
 }
+func (t *testPositionStore) Correct(positionCode string, price float64, quantity float64) error {
+	t.CorrectHistory = append(t.CorrectHistory, positionCode)
+	t.CorrectHistory = append(t.CorrectHistory, price)
+	t.CorrectHistory = append(t.CorrectHistory, quantity)
+	t.CorrectCount++
+	return t.Correct1
+}
 
 func Test_positionStore_Save(t *testing.T) {
 	t.Parallel()
@@ -300,6 +317,43 @@ func Test_positionStore_GetActivePositionsByStrategyCode(t *testing.T) {
 	}
 }
 
+func Test_positionStore_GetByCode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		store map[string]*Position
+		arg1  string
+		want1 *Position
+		want2 error
+	}{
+		{name: "ポジションがなければエラー",
+			store: map[string]*Position{},
+			arg1:  "position-code-001",
+			want1: nil,
+			want2: ErrNoData},
+		{name: "ポジションがあれば返す",
+			store: map[string]*Position{
+				"position-code-001": {Code: "position-code-001", StrategyCode: "strategy-code-001", OwnedQuantity: 100},
+				"position-code-002": {Code: "position-code-002", StrategyCode: "strategy-code-001", OwnedQuantity: 0},
+			},
+			arg1:  "position-code-002",
+			want1: &Position{Code: "position-code-002", StrategyCode: "strategy-code-001", OwnedQuantity: 0},
+			want2: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &positionStore{store: test.store}
+			got1, got2 := store.GetByCode(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
 func Test_positionStore_Hold(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -416,3 +470,72 @@ func Test_getPositionStore(t *testing.T) {
 		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
 	}
 }
+
+func Test_positionStore_Correct(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		db                    *testDB
+		name                  string
+		store                 map[string]*Position
+		arg1                  string
+		arg2                  float64
+		arg3                  float64
+		want1                 error
+		wantStore             map[string]*Position
+		wantSavePositionCount int
+	}{
+		{name: "指定したpositionCodeがなければ何もしない",
+			db: &testDB{},
+			store: map[string]*Position{
+				"position-code-001": {Code: "position-code-001", Price: 1000, OwnedQuantity: 100, HoldQuantity: 0},
+				"position-code-002": {Code: "position-code-002", Price: 1000, OwnedQuantity: 200, HoldQuantity: 0}},
+			arg1:  "position-code-000",
+			arg2:  1001,
+			arg3:  50,
+			want1: nil,
+			wantStore: map[string]*Position{
+				"position-code-001": {Code: "position-code-001", Price: 1000, OwnedQuantity: 100, HoldQuantity: 0},
+				"position-code-002": {Code: "position-code-002", Price: 1000, OwnedQuantity: 200, HoldQuantity: 0}}},
+		{name: "指定したpositionCodeがあれば、約定値と保有数量を上書きする",
+			db: &testDB{},
+			store: map[string]*Position{
+				"position-code-001": {Code: "position-code-001", Price: 1000, OwnedQuantity: 100, HoldQuantity: 30},
+				"position-code-002": {Code: "position-code-002", Price: 1000, OwnedQuantity: 200, HoldQuantity: 0}},
+			arg1:  "position-code-001",
+			arg2:  1001,
+			arg3:  150,
+			want1: nil,
+			wantStore: map[string]*Position{
+				"position-code-001": {Code: "position-code-001", Price: 1001, OwnedQuantity: 150, HoldQuantity: 30},
+				"position-code-002": {Code: "position-code-002", Price: 1000, OwnedQuantity: 200, HoldQuantity: 0}},
+			wantSavePositionCount: 1},
+		{name: "拘束数量が訂正後の保有数量を超えていたら、保有数量まで減らす",
+			db: &testDB{},
+			store: map[string]*Position{
+				"position-code-001": {Code: "position-code-001", Price: 1000, OwnedQuantity: 100, HoldQuantity: 80}},
+			arg1:  "position-code-001",
+			arg2:  1000,
+			arg3:  50,
+			want1: nil,
+			wantStore: map[string]*Position{
+				"position-code-001": {Code: "position-code-001", Price: 1000, OwnedQuantity: 50, HoldQuantity: 50}},
+			wantSavePositionCount: 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &positionStore{store: test.store, db: test.db}
+			got1 := store.Correct(test.arg1, test.arg2, test.arg3)
+
+			time.Sleep(100 * time.Millisecond)
+
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantStore, store.store) || !reflect.DeepEqual(test.wantSavePositionCount, test.db.SavePositionCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantStore, test.wantSavePositionCount,
+					got1, store.store, test.db.SavePositionCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package gridon
+
+import "fmt"
+
+// newPriceBandService - 新しい価格帯サービスの取得
+func newPriceBandService(clock IClock, strategyStore IStrategyStore, fourPriceStore IFourPriceStore, logger ILogger) IPriceBandService {
+	return &priceBandService{
+		clock:          clock,
+		strategyStore:  strategyStore,
+		fourPriceStore: fourPriceStore,
+		logger:         logger,
+	}
+}
+
+// IPriceBandService - 価格帯サービスのインターフェース
+type IPriceBandService interface {
+	Check(strategy *Strategy, price float64) (PriceBandState, error)
+}
+
+// priceBandService - 価格帯サービス
+type priceBandService struct {
+	clock          IClock
+	strategyStore  IStrategyStore
+	fourPriceStore IFourPriceStore
+	logger         ILogger
+}
+
+// Check - 戦略の価格帯を計算し、価格が価格帯の外にあるかを判定する
+// 判定結果が変わっていれば戦略に記録し、価格帯の内外が変わったときは通知する
+func (s *priceBandService) Check(strategy *Strategy, price float64) (PriceBandState, error) {
+	if strategy == nil {
+		return PriceBandState{}, ErrNilArgument
+	}
+
+	prev := strategy.PriceBandState
+	band := strategy.GridStrategy.PriceBand
+
+	// 価格帯が無効になっていたら、前回の判定結果を消しておく
+	if !band.Valid {
+		if prev != (PriceBandState{}) {
+			if err := s.strategyStore.SetPriceBandState(strategy.Code, PriceBandState{}); err != nil {
+				return PriceBandState{}, err
+			}
+		}
+		return PriceBandState{}, nil
+	}
+
+	reference, err := s.reference(strategy)
+	if err != nil {
+		return PriceBandState{}, err
+	}
+
+	state := PriceBandState{ChangedDateTime: prev.ChangedDateTime}
+	state.Lower, state.Upper = band.bounds(reference)
+	state.OutOfBand = !state.In(price)
+	if state.OutOfBand != prev.OutOfBand {
+		state.ChangedDateTime = s.clock.Now()
+		if state.OutOfBand {
+			s.logger.Notice(fmt.Sprintf("%s の価格が価格帯の外に出ました(price = %.2f, lower = %.2f, upper = %.2f, policy = %s)", strategy.Code, price, state.Lower, state.Upper, band.Policy))
+		} else {
+			s.logger.Notice(fmt.Sprintf("%s の価格が価格帯の中に戻りました(price = %.2f, lower = %.2f, upper = %.2f)", strategy.Code, price, state.Lower, state.Upper))
+		}
+	}
+
+	if state != prev {
+		if err := s.strategyStore.SetPriceBandState(strategy.Code, state); err != nil {
+			return state, err
+		}
+	}
+	return state, nil
+}
+
+// reference - 価格帯の基準になる価格
+// 絶対価格なら基準の価格は使わない
+func (s *priceBandService) reference(strategy *Strategy) (float64, error) {
+	band := strategy.GridStrategy.PriceBand
+	switch band.Source {
+	case PriceBandSourcePrevClose:
+		fp, err := s.fourPriceStore.GetLastBySymbolCodeAndExchange(strategy.SymbolCode, strategy.Exchange)
+		if err != nil {
+			return 0, err
+		}
+		return fp.Close, nil
+	case PriceBandSourceMovingAverage:
+		if band.Days <= 0 {
+			return 0, ErrNoData
+		}
+		fps, err := s.fourPriceStore.GetBySymbolCodeAndExchange(strategy.SymbolCode, strategy.Exchange, band.Days)
+		if err != nil {
+			return 0, err
+		}
+		closes := make([]float64, 0, len(fps))
+		for _, fp := range fps {
+			if fp != nil {
+				closes = append(closes, fp.Close)
+			}
+		}
+		if len(closes) == 0 {
+			return 0, ErrNoData
+		}
+		return average(closes), nil
+	}
+	return 0, nil
+}
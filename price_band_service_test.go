@@ -0,0 +1,208 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testPriceBandService struct {
+	IPriceBandService
+	Check1       PriceBandState
+	Check2       error
+	CheckCount   int
+	CheckHistory []interface{}
+}
+
+func (t *testPriceBandService) Check(strategy *Strategy, price float64) (PriceBandState, error) {
+	t.CheckHistory = append(t.CheckHistory, strategy)
+	t.CheckHistory = append(t.CheckHistory, price)
+	t.CheckCount++
+	return t.Check1, t.Check2
+}
+
+func Test_newPriceBandService(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{}
+	strategyStore := &testStrategyStore{}
+	fourPriceStore := &testFourPriceStore{}
+	logger := &testLogger{}
+	want1 := &priceBandService{
+		clock:          clock,
+		strategyStore:  strategyStore,
+		fourPriceStore: fourPriceStore,
+		logger:         logger,
+	}
+	got1 := newPriceBandService(clock, strategyStore, fourPriceStore, logger)
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_priceBandService_Check(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local)
+	before := time.Date(2021, 11, 5, 9, 30, 0, 0, time.Local)
+	tests := []struct {
+		name                           string
+		clock                          *testClock
+		strategyStore                  *testStrategyStore
+		fourPriceStore                 *testFourPriceStore
+		arg1                           *Strategy
+		arg2                           float64
+		want1                          PriceBandState
+		want2                          error
+		wantSetPriceBandStateHistory   []interface{}
+		wantNoticeCount                int
+		wantGetBySymbolCodeAndExchange []interface{}
+	}{
+		{name: "strategyがnilならエラー",
+			clock:          &testClock{Now1: now},
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{},
+			arg1:           nil,
+			arg2:           1000,
+			want1:          PriceBandState{},
+			want2:          ErrNilArgument},
+		{name: "価格帯が無効で判定結果がなければ何もしない",
+			clock:          &testClock{Now1: now},
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{},
+			arg1:           &Strategy{Code: "strategy-code-001"},
+			arg2:           1000,
+			want1:          PriceBandState{},
+			want2:          nil},
+		{name: "価格帯が無効で前回の判定結果が残っていれば消す",
+			clock:                        &testClock{Now1: now},
+			strategyStore:                &testStrategyStore{},
+			fourPriceStore:               &testFourPriceStore{},
+			arg1:                         &Strategy{Code: "strategy-code-001", PriceBandState: PriceBandState{Lower: 900, Upper: 1100, OutOfBand: true, ChangedDateTime: before}},
+			arg2:                         1000,
+			want1:                        PriceBandState{},
+			want2:                        nil,
+			wantSetPriceBandStateHistory: []interface{}{"strategy-code-001", PriceBandState{}}},
+		{name: "絶対価格の価格帯の中なら、範囲だけを記録する",
+			clock:          &testClock{Now1: now},
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{},
+			arg1: &Strategy{Code: "strategy-code-001", GridStrategy: GridStrategy{
+				PriceBand: PriceBand{Valid: true, Source: PriceBandSourceAbsolute, Lower: 900, Upper: 1100}}},
+			arg2:                         1000,
+			want1:                        PriceBandState{Lower: 900, Upper: 1100},
+			want2:                        nil,
+			wantSetPriceBandStateHistory: []interface{}{"strategy-code-001", PriceBandState{Lower: 900, Upper: 1100}}},
+		{name: "絶対価格の価格帯の外に出たら、日時を記録して通知する",
+			clock:          &testClock{Now1: now},
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{},
+			arg1: &Strategy{Code: "strategy-code-001", GridStrategy: GridStrategy{
+				PriceBand: PriceBand{Valid: true, Source: PriceBandSourceAbsolute, Lower: 900, Upper: 1100}}},
+			arg2:                         1101,
+			want1:                        PriceBandState{Lower: 900, Upper: 1100, OutOfBand: true, ChangedDateTime: now},
+			want2:                        nil,
+			wantSetPriceBandStateHistory: []interface{}{"strategy-code-001", PriceBandState{Lower: 900, Upper: 1100, OutOfBand: true, ChangedDateTime: now}},
+			wantNoticeCount:              1},
+		{name: "価格帯の中に戻ったら、日時を記録して通知する",
+			clock:          &testClock{Now1: now},
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{},
+			arg1: &Strategy{Code: "strategy-code-001",
+				GridStrategy:   GridStrategy{PriceBand: PriceBand{Valid: true, Lower: 900, Upper: 1100}},
+				PriceBandState: PriceBandState{Lower: 900, Upper: 1100, OutOfBand: true, ChangedDateTime: before}},
+			arg2:                         1000,
+			want1:                        PriceBandState{Lower: 900, Upper: 1100, OutOfBand: false, ChangedDateTime: now},
+			want2:                        nil,
+			wantSetPriceBandStateHistory: []interface{}{"strategy-code-001", PriceBandState{Lower: 900, Upper: 1100, OutOfBand: false, ChangedDateTime: now}},
+			wantNoticeCount:              1},
+		{name: "判定結果が変わらなければ記録も通知もしない",
+			clock:          &testClock{Now1: now},
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{},
+			arg1: &Strategy{Code: "strategy-code-001",
+				GridStrategy:   GridStrategy{PriceBand: PriceBand{Valid: true, Lower: 900, Upper: 1100}},
+				PriceBandState: PriceBandState{Lower: 900, Upper: 1100, OutOfBand: true, ChangedDateTime: before}},
+			arg2:  850,
+			want1: PriceBandState{Lower: 900, Upper: 1100, OutOfBand: true, ChangedDateTime: before},
+			want2: nil},
+		{name: "前日終値基準なら前日終値からの割合で範囲を決める",
+			clock:          &testClock{Now1: now},
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetLastBySymbolCodeAndExchange1: &FourPrice{SymbolCode: "1475", Exchange: ExchangeToushou, Close: 2000}},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, GridStrategy: GridStrategy{
+				PriceBand: PriceBand{Valid: true, Source: PriceBandSourcePrevClose, Lower: 0.1, Upper: 0.05}}},
+			arg2:                         2000,
+			want1:                        PriceBandState{Lower: 1800, Upper: 2100},
+			want2:                        nil,
+			wantSetPriceBandStateHistory: []interface{}{"strategy-code-001", PriceBandState{Lower: 1800, Upper: 2100}}},
+		{name: "前日終値が取れなければエラー",
+			clock:          &testClock{Now1: now},
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetLastBySymbolCodeAndExchange2: ErrNoData},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, GridStrategy: GridStrategy{
+				PriceBand: PriceBand{Valid: true, Source: PriceBandSourcePrevClose, Lower: 0.1, Upper: 0.05}}},
+			arg2:  2000,
+			want1: PriceBandState{},
+			want2: ErrNoData},
+		{name: "移動平均基準なら指定日数の終値の平均からの割合で範囲を決める",
+			clock:         &testClock{Now1: now},
+			strategyStore: &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{
+				{Close: 2100}, {Close: 2000}, {Close: 1900}}},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, GridStrategy: GridStrategy{
+				PriceBand: PriceBand{Valid: true, Source: PriceBandSourceMovingAverage, Days: 3, Lower: 0.1, Upper: 0.1}}},
+			arg2:                           2000,
+			want1:                          PriceBandState{Lower: 1800, Upper: 2200},
+			want2:                          nil,
+			wantSetPriceBandStateHistory:   []interface{}{"strategy-code-001", PriceBandState{Lower: 1800, Upper: 2200}},
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 3}},
+		{name: "移動平均基準で日数が指定されていなければエラー",
+			clock:          &testClock{Now1: now},
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, GridStrategy: GridStrategy{
+				PriceBand: PriceBand{Valid: true, Source: PriceBandSourceMovingAverage, Lower: 0.1, Upper: 0.1}}},
+			arg2:  2000,
+			want1: PriceBandState{},
+			want2: ErrNoData},
+		{name: "移動平均基準で四本値がなければエラー",
+			clock:          &testClock{Now1: now},
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{}},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, GridStrategy: GridStrategy{
+				PriceBand: PriceBand{Valid: true, Source: PriceBandSourceMovingAverage, Days: 3, Lower: 0.1, Upper: 0.1}}},
+			arg2:                           2000,
+			want1:                          PriceBandState{},
+			want2:                          ErrNoData,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 3}},
+		{name: "判定結果の保存に失敗したらエラー",
+			clock:          &testClock{Now1: now},
+			strategyStore:  &testStrategyStore{SetPriceBandState1: ErrUnknown},
+			fourPriceStore: &testFourPriceStore{},
+			arg1: &Strategy{Code: "strategy-code-001", GridStrategy: GridStrategy{
+				PriceBand: PriceBand{Valid: true, Lower: 900, Upper: 1100}}},
+			arg2:                         1000,
+			want1:                        PriceBandState{Lower: 900, Upper: 1100},
+			want2:                        ErrUnknown,
+			wantSetPriceBandStateHistory: []interface{}{"strategy-code-001", PriceBandState{Lower: 900, Upper: 1100}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			logger := &testLogger{}
+			service := &priceBandService{clock: test.clock, strategyStore: test.strategyStore, fourPriceStore: test.fourPriceStore, logger: logger}
+			got1, got2 := service.Check(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) ||
+				!errors.Is(got2, test.want2) ||
+				!reflect.DeepEqual(test.wantSetPriceBandStateHistory, test.strategyStore.SetPriceBandStateHistory) ||
+				!reflect.DeepEqual(test.wantNoticeCount, logger.NoticeCount) ||
+				!reflect.DeepEqual(test.wantGetBySymbolCodeAndExchange, test.fourPriceStore.GetBySymbolCodeAndExchangeHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.want2, test.wantSetPriceBandStateHistory, test.wantNoticeCount, test.wantGetBySymbolCodeAndExchange,
+					got1, got2, test.strategyStore.SetPriceBandStateHistory, logger.NoticeCount, test.fourPriceStore.GetBySymbolCodeAndExchangeHistory)
+			}
+		})
+	}
+}
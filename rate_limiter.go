@@ -0,0 +1,166 @@
+package gridon
+
+import (
+	"sync"
+	"time"
+)
+
+// newRateLimiter - 新しい流量制限の取得
+// kabuステーションAPIの制限に合わせて、発注系は秒間5件、情報系は秒間10件を上限にする
+func newRateLimiter() IRateLimiter {
+	return &rateLimiter{
+		buckets: map[RequestCategory]*rateBucket{
+			RequestCategoryOrder: newRateBucket(RequestCategoryOrder, 5),
+			RequestCategoryInfo:  newRateBucket(RequestCategoryInfo, 10),
+		},
+	}
+}
+
+// IRateLimiter - 流量制限のインターフェース
+type IRateLimiter interface {
+	Wait(category RequestCategory, priority RequestPriority)
+	GetMetrics() []RateLimitMetrics
+}
+
+// rateLimiter - 流量制限
+// 区分ごとに上限を持ち、上限を超えたリクエストは優先度の高い順、同じ優先度なら到着順に待たせる
+type rateLimiter struct {
+	buckets map[RequestCategory]*rateBucket
+}
+
+// Wait - リクエストを出してよくなるまで待つ
+// 上限のない区分のリクエストは待たない
+func (l *rateLimiter) Wait(category RequestCategory, priority RequestPriority) {
+	if bucket, ok := l.buckets[category]; ok {
+		bucket.wait(priority)
+	}
+}
+
+// GetMetrics - 区分ごとの統計を発注系、情報系の順で取得する
+func (l *rateLimiter) GetMetrics() []RateLimitMetrics {
+	metrics := make([]RateLimitMetrics, 0)
+	for _, category := range []RequestCategory{RequestCategoryOrder, RequestCategoryInfo} {
+		if bucket, ok := l.buckets[category]; ok {
+			metrics = append(metrics, bucket.metrics())
+		}
+	}
+	return metrics
+}
+
+func newRateBucket(category RequestCategory, ratePerSecond float64) *rateBucket {
+	return &rateBucket{
+		category:      category,
+		ratePerSecond: ratePerSecond,
+		interval:      time.Duration(float64(time.Second) / ratePerSecond),
+		queue:         []*rateWaiter{},
+	}
+}
+
+// rateBucket - 区分ごとの流量制限
+// 待っているリクエストがある間だけ払い出し用のgoroutineを動かし、間隔を空けて1件ずつ払い出す
+type rateBucket struct {
+	category      RequestCategory
+	ratePerSecond float64
+	interval      time.Duration
+	next          time.Time // 次のリクエストを出してよい日時
+	queue         []*rateWaiter
+	seq           int
+	dispatching   bool
+	requests      int
+	throttled     int
+	totalWaitTime time.Duration
+	maxWaitTime   time.Duration
+	mtx           sync.Mutex
+}
+
+// rateWaiter - 払い出しを待っているリクエスト
+type rateWaiter struct {
+	priority RequestPriority
+	seq      int
+	queuedAt time.Time
+	ready    chan struct{}
+}
+
+func (b *rateBucket) wait(priority RequestPriority) {
+	b.mtx.Lock()
+	now := time.Now()
+	b.requests++
+	if len(b.queue) == 0 && !now.Before(b.next) {
+		// 待っているリクエストがなく、間隔も空いていればすぐに出す
+		b.next = now.Add(b.interval)
+		b.mtx.Unlock()
+		return
+	}
+
+	b.throttled++
+	b.seq++
+	w := &rateWaiter{priority: priority, seq: b.seq, queuedAt: now, ready: make(chan struct{})}
+	b.queue = append(b.queue, w)
+	if !b.dispatching {
+		b.dispatching = true
+		go b.dispatch()
+	}
+	b.mtx.Unlock()
+
+	<-w.ready
+}
+
+// dispatch - 待っているリクエストがなくなるまで、間隔を空けて優先度の高いリクエストから払い出す
+// 間隔を待ってから払い出すリクエストを選ぶことで、待っている間に来た優先度の高いリクエストを先に出す
+func (b *rateBucket) dispatch() {
+	for {
+		b.mtx.Lock()
+		if len(b.queue) == 0 {
+			b.dispatching = false
+			b.mtx.Unlock()
+			return
+		}
+
+		now := time.Now()
+		if d := b.next.Sub(now); d > 0 {
+			b.mtx.Unlock()
+			time.Sleep(d)
+			continue
+		}
+
+		w := b.pop()
+		b.next = now.Add(b.interval)
+		waitTime := now.Sub(w.queuedAt)
+		b.totalWaitTime += waitTime
+		if waitTime > b.maxWaitTime {
+			b.maxWaitTime = waitTime
+		}
+		b.mtx.Unlock()
+
+		close(w.ready)
+	}
+}
+
+// pop - 優先度が最も高く、最も早く来たリクエストをキューから取り出す
+func (b *rateBucket) pop() *rateWaiter {
+	idx := 0
+	for i, w := range b.queue {
+		top := b.queue[idx]
+		if w.priority.rank() < top.priority.rank() || (w.priority.rank() == top.priority.rank() && w.seq < top.seq) {
+			idx = i
+		}
+	}
+	w := b.queue[idx]
+	b.queue = append(b.queue[:idx], b.queue[idx+1:]...)
+	return w
+}
+
+func (b *rateBucket) metrics() RateLimitMetrics {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return RateLimitMetrics{
+		Category:      b.category,
+		RatePerSecond: b.ratePerSecond,
+		Requests:      b.requests,
+		Throttled:     b.throttled,
+		Waiting:       len(b.queue),
+		TotalWaitTime: b.totalWaitTime,
+		MaxWaitTime:   b.maxWaitTime,
+	}
+}
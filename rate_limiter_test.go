@@ -0,0 +1,159 @@
+package gridon
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testRateLimiter struct {
+	IRateLimiter
+	GetMetrics1     []RateLimitMetrics
+	WaitCount       int
+	WaitHistory     []interface{}
+	GetMetricsCount int
+	mtx             sync.Mutex
+}
+
+func (t *testRateLimiter) Wait(category RequestCategory, priority RequestPriority) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.WaitHistory = append(t.WaitHistory, category)
+	t.WaitHistory = append(t.WaitHistory, priority)
+	t.WaitCount++
+}
+
+func (t *testRateLimiter) GetMetrics() []RateLimitMetrics {
+	t.GetMetricsCount++
+	return t.GetMetrics1
+}
+
+func Test_newRateLimiter(t *testing.T) {
+	t.Parallel()
+	want1 := &rateLimiter{
+		buckets: map[RequestCategory]*rateBucket{
+			RequestCategoryOrder: {category: RequestCategoryOrder, ratePerSecond: 5, interval: 200 * time.Millisecond, queue: []*rateWaiter{}},
+			RequestCategoryInfo:  {category: RequestCategoryInfo, ratePerSecond: 10, interval: 100 * time.Millisecond, queue: []*rateWaiter{}},
+		},
+	}
+	got1 := newRateLimiter()
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_rateLimiter_Wait(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		arg1          RequestCategory
+		want1         []RateLimitMetrics
+		wantMinElapse time.Duration
+	}{
+		{name: "上限のない区分は待たずに統計にも残さない",
+			arg1: RequestCategoryUnspecified,
+			want1: []RateLimitMetrics{
+				{Category: RequestCategoryOrder, RatePerSecond: 20},
+				{Category: RequestCategoryInfo, RatePerSecond: 20}}},
+		{name: "上限を超えたリクエストは間隔を空けて出され、待たされた数が記録される",
+			arg1: RequestCategoryOrder,
+			want1: []RateLimitMetrics{
+				{Category: RequestCategoryOrder, RatePerSecond: 20, Requests: 3, Throttled: 2},
+				{Category: RequestCategoryInfo, RatePerSecond: 20}},
+			wantMinElapse: 100 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			limiter := &rateLimiter{buckets: map[RequestCategory]*rateBucket{
+				RequestCategoryOrder: newRateBucket(RequestCategoryOrder, 20),
+				RequestCategoryInfo:  newRateBucket(RequestCategoryInfo, 20),
+			}}
+			start := time.Now()
+			for i := 0; i < 3; i++ {
+				limiter.Wait(test.arg1, RequestPriorityNormal)
+			}
+			elapse := time.Since(start)
+
+			got1 := limiter.GetMetrics()
+			for i := range got1 {
+				got1[i].TotalWaitTime, got1[i].MaxWaitTime = 0, 0
+			}
+			if !reflect.DeepEqual(test.want1, got1) || elapse < test.wantMinElapse {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.wantMinElapse, got1, elapse)
+			}
+		})
+	}
+}
+
+func Test_rateBucket_wait_priority(t *testing.T) {
+	t.Parallel()
+	bucket := newRateBucket(RequestCategoryOrder, 20)
+
+	// 1件目で枠を使い切り、以降のリクエストを待たせる
+	bucket.wait(RequestPriorityNormal)
+
+	var mtx sync.Mutex
+	got1 := make([]RequestPriority, 0)
+	var wg sync.WaitGroup
+	for _, p := range []RequestPriority{RequestPriorityLow, RequestPriorityNormal, RequestPriorityHigh} {
+		wg.Add(1)
+		p := p
+		go func() {
+			defer wg.Done()
+			bucket.wait(p)
+			mtx.Lock()
+			defer mtx.Unlock()
+			got1 = append(got1, p)
+		}()
+		<-time.After(5 * time.Millisecond) // 到着順を固定する
+	}
+	wg.Wait()
+
+	want1 := []RequestPriority{RequestPriorityHigh, RequestPriorityNormal, RequestPriorityLow}
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_rateBucket_pop(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		queue     []*rateWaiter
+		want1     int
+		wantQueue []int
+	}{
+		{name: "優先度が高いものを取り出す",
+			queue:     []*rateWaiter{{priority: RequestPriorityLow, seq: 1}, {priority: RequestPriorityHigh, seq: 2}, {priority: RequestPriorityNormal, seq: 3}},
+			want1:     2,
+			wantQueue: []int{1, 3}},
+		{name: "優先度が同じなら早く来たものを取り出す",
+			queue:     []*rateWaiter{{priority: RequestPriorityNormal, seq: 2}, {priority: RequestPriorityNormal, seq: 1}},
+			want1:     1,
+			wantQueue: []int{2}},
+		{name: "未指定の優先度は中と同じに扱う",
+			queue:     []*rateWaiter{{priority: RequestPriorityLow, seq: 1}, {priority: RequestPriorityUnspecified, seq: 2}, {priority: RequestPriorityNormal, seq: 3}},
+			want1:     2,
+			wantQueue: []int{1, 3}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			bucket := &rateBucket{queue: test.queue}
+			got1 := bucket.pop().seq
+			gotQueue := make([]int, 0)
+			for _, w := range bucket.queue {
+				gotQueue = append(gotQueue, w.seq)
+			}
+			if !reflect.DeepEqual(test.want1, got1) || !reflect.DeepEqual(test.wantQueue, gotQueue) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.wantQueue, got1, gotQueue)
+			}
+		})
+	}
+}
@@ -42,7 +42,7 @@ func (s *rebalanceService) Rebalance(strategy *Strategy) error {
 	if err != nil {
 		return err
 	}
-	price := (symbol.AskPrice + symbol.BidPrice) / 2
+	price := s.referencePrice(symbol)
 
 	positionValue, err := s.positionValue(strategy.Code, price)
 	if err != nil {
@@ -64,6 +64,25 @@ func (s *rebalanceService) Rebalance(strategy *Strategy) error {
 	return nil
 }
 
+// referencePrice - リバランスの基準にする価格
+// 売り買い両方の気配があれば中値、値幅制限に張り付いて片側の気配しかなければその気配、どちらもなければ現値を使い、値幅制限の範囲内に収める
+func (s *rebalanceService) referencePrice(symbol *Symbol) float64 {
+	var price float64
+	switch {
+	case symbol.AskPrice > 0 && symbol.BidPrice > 0:
+		price = (symbol.AskPrice + symbol.BidPrice) / 2
+	case symbol.AskPrice > 0:
+		price = symbol.AskPrice
+	case symbol.BidPrice > 0:
+		price = symbol.BidPrice
+	default:
+		price = symbol.CurrentPrice
+	}
+
+	limit := PriceLimit{Lower: symbol.LowerLimit, Upper: symbol.UpperLimit}
+	return limit.Clamp(price)
+}
+
 // positionValue - ポジションの評価額の計算
 func (s *rebalanceService) positionValue(strategyCode string, price float64) (float64, error) {
 	positions, err := s.positionStore.GetActivePositionsByStrategyCode(strategyCode)
@@ -235,6 +235,42 @@ func Test_rebalanceService_Rebalance(t *testing.T) {
 				Runnable:          true},
 			want1:                  ErrUnknown,
 			wantEntryMarketHistory: []interface{}{"strategy-code-001", 13.0}},
+		{name: "ストップ安で買い気配がなければ、売り気配を基準に数量を計算する",
+			clock:    &testClock{Now1: time.Date(2021, 11, 10, 8, 59, 0, 0, time.Local)},
+			kabusAPI: &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 1600, BidPrice: 0, AskPrice: 1600, UpperLimit: 2400, LowerLimit: 1600}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
+				{Code: "position-code-001", StrategyCode: "strategy-code-001", Side: SideBuy, Price: 2_000, OwnedQuantity: 10},
+				{Code: "position-code-002", StrategyCode: "strategy-code-001", Side: SideBuy, Price: 2_000, OwnedQuantity: 15},
+				{Code: "position-code-003", StrategyCode: "strategy-code-001", Side: SideBuy, Price: 2_000, OwnedQuantity: 25},
+			}},
+			orderService: &testOrderService{},
+			arg1: &Strategy{
+				Code:              "strategy-code-001",
+				SymbolCode:        "1475",
+				Exchange:          ExchangeToushou,
+				Cash:              100_000,
+				RebalanceStrategy: RebalanceStrategy{Runnable: true, Timings: []time.Time{time.Date(0, 1, 1, 8, 59, 0, 0, time.Local)}},
+				Runnable:          true},
+			want1:                  nil,
+			wantEntryMarketHistory: []interface{}{"strategy-code-001", 6.0}},
+		{name: "ストップ高で売り気配がなければ、買い気配を基準に数量を計算する",
+			clock:    &testClock{Now1: time.Date(2021, 11, 10, 8, 59, 0, 0, time.Local)},
+			kabusAPI: &testKabusAPI{GetSymbol1: &Symbol{Code: "1475", Exchange: ExchangeToushou, TradingUnit: 1, CurrentPrice: 2400, BidPrice: 2400, AskPrice: 0, UpperLimit: 2400, LowerLimit: 1600}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
+				{Code: "position-code-001", StrategyCode: "strategy-code-001", Side: SideBuy, Price: 2_000, OwnedQuantity: 10},
+				{Code: "position-code-002", StrategyCode: "strategy-code-001", Side: SideBuy, Price: 2_000, OwnedQuantity: 15},
+				{Code: "position-code-003", StrategyCode: "strategy-code-001", Side: SideBuy, Price: 2_000, OwnedQuantity: 25},
+			}},
+			orderService: &testOrderService{},
+			arg1: &Strategy{
+				Code:              "strategy-code-001",
+				SymbolCode:        "1475",
+				Exchange:          ExchangeToushou,
+				Cash:              100_000,
+				RebalanceStrategy: RebalanceStrategy{Runnable: true, Timings: []time.Time{time.Date(0, 1, 1, 8, 59, 0, 0, time.Local)}},
+				Runnable:          true},
+			want1:                 nil,
+			wantExitMarketHistory: []interface{}{"strategy-code-001", 4.0, SortOrderLatest}},
 	}
 
 	for _, test := range tests {
@@ -257,6 +293,34 @@ func Test_rebalanceService_Rebalance(t *testing.T) {
 	}
 }
 
+func Test_rebalanceService_referencePrice(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg  *Symbol
+		want float64
+	}{
+		{name: "売り買い両方の気配があれば中値", arg: &Symbol{CurrentPrice: 2000, AskPrice: 2002, BidPrice: 1998, UpperLimit: 2400, LowerLimit: 1600}, want: 2000},
+		{name: "ストップ高で売り気配がなければ買い気配", arg: &Symbol{CurrentPrice: 2400, AskPrice: 0, BidPrice: 2400, UpperLimit: 2400, LowerLimit: 1600}, want: 2400},
+		{name: "ストップ安で買い気配がなければ売り気配", arg: &Symbol{CurrentPrice: 1600, AskPrice: 1600, BidPrice: 0, UpperLimit: 2400, LowerLimit: 1600}, want: 1600},
+		{name: "どちらの気配もなければ現値", arg: &Symbol{CurrentPrice: 2100, AskPrice: 0, BidPrice: 0, UpperLimit: 2400, LowerLimit: 1600}, want: 2100},
+		{name: "値幅制限の範囲外なら値幅制限に収める", arg: &Symbol{CurrentPrice: 2500, AskPrice: 0, BidPrice: 0, UpperLimit: 2400, LowerLimit: 1600}, want: 2400},
+		{name: "値幅制限がなければそのまま", arg: &Symbol{CurrentPrice: 2000, AskPrice: 0, BidPrice: 1998}, want: 1998},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &rebalanceService{}
+			got := service.referencePrice(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
 func Test_newRebalanceService(t *testing.T) {
 	t.Parallel()
 	clock := &testClock{}
@@ -0,0 +1,181 @@
+package gridon
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// reconcileInterval - 約定確認の周期でポジションを照合し直す間隔
+// 約定の反映が遅れて一時的に不一致になっても、この間隔で照合し直して止めた戦略を動かせるようにする
+const reconcileInterval = 1 * time.Minute
+
+// newReconciliationService - 新しいポジション照合サービスの取得
+func newReconciliationService(clock IClock, kabusAPI IKabusAPI, positionStore IPositionStore) IReconciliationService {
+	return &reconciliationService{
+		clock:         clock,
+		kabusAPI:      kabusAPI,
+		positionStore: positionStore,
+		reports:       map[string]*ReconciliationReport{},
+	}
+}
+
+// IReconciliationService - ポジション照合サービスのインターフェース
+type IReconciliationService interface {
+	Reconcile(strategy *Strategy, repair bool) (*ReconciliationReport, error)
+	GetReports() []*ReconciliationReport
+	IsBlocked(strategyCode string) bool
+	NeedsReconcile(strategyCode string) bool
+}
+
+// reconciliationService - ポジション照合サービス
+// gridonが持っているポジションと証券会社のポジションをポジションコードで照合し、最後の照合結果を戦略ごとに保持する
+type reconciliationService struct {
+	clock         IClock
+	kabusAPI      IKabusAPI
+	positionStore IPositionStore
+	reports       map[string]*ReconciliationReport
+	mtx           sync.Mutex
+}
+
+// Reconcile - 戦略のポジションを証券会社のポジションと照合する
+// repairを指定すると、不一致のあったポジションを証券会社の値で訂正する
+// 仮想売買の戦略は証券会社にポジションがなく、現物は証券会社側にポジションコードがないため照合しない
+// 証券会社にだけあるポジションは、どの戦略のものか判断できないため照合しない
+// 照合に失敗したらポジションが正しいか分からないので、失敗したことを結果に残して戦略を止める
+func (s *reconciliationService) Reconcile(strategy *Strategy, repair bool) (*ReconciliationReport, error) {
+	if strategy == nil {
+		return nil, ErrNilArgument
+	}
+
+	report := &ReconciliationReport{
+		StrategyCode: strategy.Code,
+		DateTime:     s.clock.Now(),
+		Mismatches:   []PositionMismatch{},
+	}
+	if strategy.PaperTrading || strategy.Product != ProductMargin {
+		report.Skipped = true
+		s.saveReport(report)
+		return report, nil
+	}
+
+	securityPositions, err := s.kabusAPI.GetPositions(strategy.Product, strategy.SymbolCode)
+	if err != nil {
+		report.Error = err.Error()
+		s.saveReport(report)
+		return nil, err
+	}
+	securityStore := make(map[string]SecurityPosition)
+	for _, sp := range securityPositions {
+		securityStore[sp.Code] = sp
+	}
+
+	positions, err := s.positionStore.GetActivePositionsByStrategyCode(strategy.Code)
+	if err != nil {
+		report.Error = err.Error()
+		s.saveReport(report)
+		return nil, err
+	}
+
+	for _, p := range positions {
+		sp, ok := securityStore[p.Code]
+		if !ok {
+			report.Mismatches = append(report.Mismatches, PositionMismatch{
+				PositionCode:  p.Code,
+				MismatchType:  PositionMismatchTypeMissing,
+				LocalQuantity: p.OwnedQuantity,
+				LocalPrice:    p.Price,
+			})
+			continue
+		}
+
+		if p.OwnedQuantity != sp.OwnedQuantity {
+			report.Mismatches = append(report.Mismatches, PositionMismatch{
+				PositionCode:     p.Code,
+				MismatchType:     PositionMismatchTypeQuantity,
+				LocalQuantity:    p.OwnedQuantity,
+				SecurityQuantity: sp.OwnedQuantity,
+				LocalPrice:       p.Price,
+				SecurityPrice:    sp.Price,
+			})
+		}
+		if p.Price != sp.Price {
+			report.Mismatches = append(report.Mismatches, PositionMismatch{
+				PositionCode:     p.Code,
+				MismatchType:     PositionMismatchTypePrice,
+				LocalQuantity:    p.OwnedQuantity,
+				SecurityQuantity: sp.OwnedQuantity,
+				LocalPrice:       p.Price,
+				SecurityPrice:    sp.Price,
+			})
+		}
+	}
+
+	if repair && len(report.Mismatches) > 0 {
+		if err := s.repair(report.Mismatches); err != nil {
+			s.saveReport(report)
+			return nil, err
+		}
+		report.Repaired = true
+	}
+
+	s.saveReport(report)
+	return report, nil
+}
+
+// repair - 不一致のあったポジションを証券会社の値で訂正する
+// 証券会社にないポジションは約定値をそのままにして保有数量を0にする
+func (s *reconciliationService) repair(mismatches []PositionMismatch) error {
+	for _, m := range mismatches {
+		price := m.SecurityPrice
+		if m.MismatchType == PositionMismatchTypeMissing {
+			price = m.LocalPrice
+		}
+		if err := s.positionStore.Correct(m.PositionCode, price, m.SecurityQuantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveReport - 戦略の最後の照合結果として保持する
+func (s *reconciliationService) saveReport(report *ReconciliationReport) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.reports[report.StrategyCode] = report
+}
+
+// GetReports - 戦略ごとの最後の照合結果を戦略コード順で取得する
+func (s *reconciliationService) GetReports() []*ReconciliationReport {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	reports := make([]*ReconciliationReport, 0)
+	for _, r := range s.reports {
+		reports = append(reports, r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].StrategyCode < reports[j].StrategyCode
+	})
+	return reports
+}
+
+// IsBlocked - 最後の照合結果に未解決の不一致があり、戦略を動かしてはいけないか
+func (s *reconciliationService) IsBlocked(strategyCode string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	report, ok := s.reports[strategyCode]
+	return ok && report.IsBlocked()
+}
+
+// NeedsReconcile - 最後の照合から照合間隔が過ぎていて、照合し直すべきか
+// 修復済みの戦略も、その後に不一致が起きていないかを照合し直す
+func (s *reconciliationService) NeedsReconcile(strategyCode string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	report, ok := s.reports[strategyCode]
+	return !ok || !s.clock.Now().Before(report.DateTime.Add(reconcileInterval))
+}
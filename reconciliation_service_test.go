@@ -0,0 +1,271 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testReconciliationService struct {
+	IReconciliationService
+	Reconcile1          *ReconciliationReport
+	Reconcile2          error
+	ReconcileCount      int
+	ReconcileHistory    []interface{}
+	GetReports1         []*ReconciliationReport
+	IsBlocked1          bool
+	IsBlockedCount      int
+	NeedsReconcile1     bool
+	NeedsReconcileCount int
+	mtx                 sync.Mutex
+}
+
+func (t *testReconciliationService) Reconcile(strategy *Strategy, repair bool) (*ReconciliationReport, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.ReconcileHistory = append(t.ReconcileHistory, strategy, repair)
+	t.ReconcileCount++
+	return t.Reconcile1, t.Reconcile2
+}
+func (t *testReconciliationService) GetReports() []*ReconciliationReport {
+	return t.GetReports1
+}
+func (t *testReconciliationService) IsBlocked(string) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.IsBlockedCount++
+	return t.IsBlocked1
+}
+func (t *testReconciliationService) NeedsReconcile(string) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.NeedsReconcileCount++
+	return t.NeedsReconcile1
+}
+
+func Test_newReconciliationService(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{}
+	kabusAPI := &testKabusAPI{}
+	positionStore := &testPositionStore{}
+	want1 := &reconciliationService{
+		clock:         clock,
+		kabusAPI:      kabusAPI,
+		positionStore: positionStore,
+		reports:       map[string]*ReconciliationReport{},
+	}
+	got1 := newReconciliationService(clock, kabusAPI, positionStore)
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_reconciliationService_Reconcile(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)
+	tests := []struct {
+		name               string
+		kabusAPI           *testKabusAPI
+		positionStore      *testPositionStore
+		arg1               *Strategy
+		arg2               bool
+		want1              *ReconciliationReport
+		want2              error
+		wantReports        map[string]*ReconciliationReport
+		wantCorrectHistory []interface{}
+	}{
+		{name: "引数がnilならエラー",
+			kabusAPI:      &testKabusAPI{},
+			positionStore: &testPositionStore{},
+			arg1:          nil,
+			want2:         ErrNilArgument,
+			wantReports:   map[string]*ReconciliationReport{}},
+		{name: "仮想売買の戦略は照合しない",
+			kabusAPI:      &testKabusAPI{},
+			positionStore: &testPositionStore{},
+			arg1:          &Strategy{Code: "strategy-code-001", Product: ProductMargin, PaperTrading: true},
+			want1:         &ReconciliationReport{StrategyCode: "strategy-code-001", DateTime: now, Skipped: true, Mismatches: []PositionMismatch{}},
+			wantReports: map[string]*ReconciliationReport{
+				"strategy-code-001": {StrategyCode: "strategy-code-001", DateTime: now, Skipped: true, Mismatches: []PositionMismatch{}}}},
+		{name: "現物の戦略は照合しない",
+			kabusAPI:      &testKabusAPI{},
+			positionStore: &testPositionStore{},
+			arg1:          &Strategy{Code: "strategy-code-001", Product: ProductStock},
+			want1:         &ReconciliationReport{StrategyCode: "strategy-code-001", DateTime: now, Skipped: true, Mismatches: []PositionMismatch{}},
+			wantReports: map[string]*ReconciliationReport{
+				"strategy-code-001": {StrategyCode: "strategy-code-001", DateTime: now, Skipped: true, Mismatches: []PositionMismatch{}}}},
+		{name: "証券会社のポジション一覧の取得に失敗したら失敗した結果を残してエラー",
+			kabusAPI:      &testKabusAPI{GetPositions2: ErrUnknown},
+			positionStore: &testPositionStore{},
+			arg1:          &Strategy{Code: "strategy-code-001", Product: ProductMargin},
+			want2:         ErrUnknown,
+			wantReports: map[string]*ReconciliationReport{
+				"strategy-code-001": {StrategyCode: "strategy-code-001", DateTime: now, Mismatches: []PositionMismatch{}, Error: ErrUnknown.Error()}}},
+		{name: "ポジション一覧の取得に失敗したら失敗した結果を残してエラー",
+			kabusAPI:      &testKabusAPI{GetPositions1: []SecurityPosition{}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode2: ErrUnknown},
+			arg1:          &Strategy{Code: "strategy-code-001", Product: ProductMargin},
+			want2:         ErrUnknown,
+			wantReports: map[string]*ReconciliationReport{
+				"strategy-code-001": {StrategyCode: "strategy-code-001", DateTime: now, Mismatches: []PositionMismatch{}, Error: ErrUnknown.Error()}}},
+		{name: "全てのポジションが一致していれば不一致なし",
+			kabusAPI: &testKabusAPI{GetPositions1: []SecurityPosition{
+				{Code: "position-code-001", Price: 1000, OwnedQuantity: 2},
+				{Code: "position-code-999", Price: 1000, OwnedQuantity: 5}}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Code: "position-code-001", Price: 1000, OwnedQuantity: 2}}},
+			arg1:          &Strategy{Code: "strategy-code-001", Product: ProductMargin},
+			want1:         &ReconciliationReport{StrategyCode: "strategy-code-001", DateTime: now, Mismatches: []PositionMismatch{}},
+			wantReports: map[string]*ReconciliationReport{
+				"strategy-code-001": {StrategyCode: "strategy-code-001", DateTime: now, Mismatches: []PositionMismatch{}}}},
+		{name: "数量、約定値の不一致と、証券会社にないポジションを報告する",
+			kabusAPI: &testKabusAPI{GetPositions1: []SecurityPosition{
+				{Code: "position-code-001", Price: 1000, OwnedQuantity: 1},
+				{Code: "position-code-002", Price: 1001, OwnedQuantity: 2}}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
+				{Code: "position-code-001", Price: 1000, OwnedQuantity: 2},
+				{Code: "position-code-002", Price: 1000, OwnedQuantity: 2},
+				{Code: "position-code-003", Price: 999, OwnedQuantity: 3}}},
+			arg1: &Strategy{Code: "strategy-code-001", Product: ProductMargin},
+			want1: &ReconciliationReport{StrategyCode: "strategy-code-001", DateTime: now, Mismatches: []PositionMismatch{
+				{PositionCode: "position-code-001", MismatchType: PositionMismatchTypeQuantity, LocalQuantity: 2, SecurityQuantity: 1, LocalPrice: 1000, SecurityPrice: 1000},
+				{PositionCode: "position-code-002", MismatchType: PositionMismatchTypePrice, LocalQuantity: 2, SecurityQuantity: 2, LocalPrice: 1000, SecurityPrice: 1001},
+				{PositionCode: "position-code-003", MismatchType: PositionMismatchTypeMissing, LocalQuantity: 3, LocalPrice: 999}}},
+			wantReports: map[string]*ReconciliationReport{
+				"strategy-code-001": {StrategyCode: "strategy-code-001", DateTime: now, Mismatches: []PositionMismatch{
+					{PositionCode: "position-code-001", MismatchType: PositionMismatchTypeQuantity, LocalQuantity: 2, SecurityQuantity: 1, LocalPrice: 1000, SecurityPrice: 1000},
+					{PositionCode: "position-code-002", MismatchType: PositionMismatchTypePrice, LocalQuantity: 2, SecurityQuantity: 2, LocalPrice: 1000, SecurityPrice: 1001},
+					{PositionCode: "position-code-003", MismatchType: PositionMismatchTypeMissing, LocalQuantity: 3, LocalPrice: 999}}}}},
+		{name: "修復を指定したら証券会社の値で訂正し、証券会社にないポジションは数量を0にする",
+			kabusAPI: &testKabusAPI{GetPositions1: []SecurityPosition{
+				{Code: "position-code-001", Price: 1000, OwnedQuantity: 1}}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
+				{Code: "position-code-001", Price: 1000, OwnedQuantity: 2},
+				{Code: "position-code-003", Price: 999, OwnedQuantity: 3}}},
+			arg1: &Strategy{Code: "strategy-code-001", Product: ProductMargin},
+			arg2: true,
+			want1: &ReconciliationReport{StrategyCode: "strategy-code-001", DateTime: now, Repaired: true, Mismatches: []PositionMismatch{
+				{PositionCode: "position-code-001", MismatchType: PositionMismatchTypeQuantity, LocalQuantity: 2, SecurityQuantity: 1, LocalPrice: 1000, SecurityPrice: 1000},
+				{PositionCode: "position-code-003", MismatchType: PositionMismatchTypeMissing, LocalQuantity: 3, LocalPrice: 999}}},
+			wantReports: map[string]*ReconciliationReport{
+				"strategy-code-001": {StrategyCode: "strategy-code-001", DateTime: now, Repaired: true, Mismatches: []PositionMismatch{
+					{PositionCode: "position-code-001", MismatchType: PositionMismatchTypeQuantity, LocalQuantity: 2, SecurityQuantity: 1, LocalPrice: 1000, SecurityPrice: 1000},
+					{PositionCode: "position-code-003", MismatchType: PositionMismatchTypeMissing, LocalQuantity: 3, LocalPrice: 999}}}},
+			wantCorrectHistory: []interface{}{"position-code-001", 1000.0, 1.0, "position-code-003", 999.0, 0.0}},
+		{name: "修復に失敗したら未修復の結果を残してエラー",
+			kabusAPI: &testKabusAPI{GetPositions1: []SecurityPosition{}},
+			positionStore: &testPositionStore{
+				GetActivePositionsByStrategyCode1: []*Position{{Code: "position-code-001", Price: 1000, OwnedQuantity: 2}},
+				Correct1:                          ErrUnknown},
+			arg1:  &Strategy{Code: "strategy-code-001", Product: ProductMargin},
+			arg2:  true,
+			want2: ErrUnknown,
+			wantReports: map[string]*ReconciliationReport{
+				"strategy-code-001": {StrategyCode: "strategy-code-001", DateTime: now, Mismatches: []PositionMismatch{
+					{PositionCode: "position-code-001", MismatchType: PositionMismatchTypeMissing, LocalQuantity: 2, LocalPrice: 1000}}}},
+			wantCorrectHistory: []interface{}{"position-code-001", 1000.0, 0.0}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &reconciliationService{
+				clock:         &testClock{Now1: now},
+				kabusAPI:      test.kabusAPI,
+				positionStore: test.positionStore,
+				reports:       map[string]*ReconciliationReport{},
+			}
+			got1, got2 := service.Reconcile(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) ||
+				!reflect.DeepEqual(test.wantReports, service.reports) ||
+				!reflect.DeepEqual(test.wantCorrectHistory, test.positionStore.CorrectHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.want2, test.wantReports, test.wantCorrectHistory,
+					got1, got2, service.reports, test.positionStore.CorrectHistory)
+			}
+		})
+	}
+}
+
+func Test_reconciliationService_GetReports(t *testing.T) {
+	t.Parallel()
+	service := &reconciliationService{reports: map[string]*ReconciliationReport{
+		"strategy-code-002": {StrategyCode: "strategy-code-002"},
+		"strategy-code-001": {StrategyCode: "strategy-code-001"},
+		"strategy-code-003": {StrategyCode: "strategy-code-003"},
+	}}
+	want1 := []*ReconciliationReport{{StrategyCode: "strategy-code-001"}, {StrategyCode: "strategy-code-002"}, {StrategyCode: "strategy-code-003"}}
+	got1 := service.GetReports()
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_reconciliationService_IsBlocked(t *testing.T) {
+	t.Parallel()
+	service := &reconciliationService{reports: map[string]*ReconciliationReport{
+		"strategy-code-001": {StrategyCode: "strategy-code-001", Mismatches: []PositionMismatch{}},
+		"strategy-code-002": {StrategyCode: "strategy-code-002", Mismatches: []PositionMismatch{{PositionCode: "position-code-001"}}},
+		"strategy-code-003": {StrategyCode: "strategy-code-003", Mismatches: []PositionMismatch{{PositionCode: "position-code-001"}}, Repaired: true},
+		"strategy-code-004": {StrategyCode: "strategy-code-004", Mismatches: []PositionMismatch{}, Error: ErrUnknown.Error()},
+		"strategy-code-005": {StrategyCode: "strategy-code-005", Skipped: true, Mismatches: []PositionMismatch{}},
+	}}
+	tests := []struct {
+		name  string
+		arg1  string
+		want1 bool
+	}{
+		{name: "照合結果がなければ止めない", arg1: "strategy-code-000", want1: false},
+		{name: "不一致がなければ止めない", arg1: "strategy-code-001", want1: false},
+		{name: "未修復の不一致があれば止める", arg1: "strategy-code-002", want1: true},
+		{name: "不一致が修復済みなら止めない", arg1: "strategy-code-003", want1: false},
+		{name: "照合に失敗していたら止める", arg1: "strategy-code-004", want1: true},
+		{name: "照合対象外の戦略は止めない", arg1: "strategy-code-005", want1: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := service.IsBlocked(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_reconciliationService_NeedsReconcile(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)
+	service := &reconciliationService{
+		clock: &testClock{Now1: now},
+		reports: map[string]*ReconciliationReport{
+			"strategy-code-001": {StrategyCode: "strategy-code-001", DateTime: now.Add(-59 * time.Second)},
+			"strategy-code-002": {StrategyCode: "strategy-code-002", DateTime: now.Add(-1 * time.Minute)},
+			"strategy-code-003": {StrategyCode: "strategy-code-003", DateTime: now.Add(-1 * time.Minute), Mismatches: []PositionMismatch{{PositionCode: "position-code-001"}}, Repaired: true},
+		}}
+	tests := []struct {
+		name  string
+		arg1  string
+		want1 bool
+	}{
+		{name: "照合結果がなければ照合する", arg1: "strategy-code-000", want1: true},
+		{name: "最後の照合から照合間隔が過ぎていなければ照合しない", arg1: "strategy-code-001", want1: false},
+		{name: "最後の照合から照合間隔が過ぎていれば照合する", arg1: "strategy-code-002", want1: true},
+		{name: "修復済みの戦略も照合間隔が過ぎていれば照合する", arg1: "strategy-code-003", want1: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := service.NeedsReconcile(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package gridon
+
+import "fmt"
+
+// newRiskExitService - 新しい損切り・利確サービスの取得
+func newRiskExitService(clock IClock, kabusAPI IKabusAPI, strategyStore IStrategyStore, positionStore IPositionStore, orderService IOrderService, logger ILogger) IRiskExitService {
+	return &riskExitService{
+		clock:         clock,
+		kabusAPI:      kabusAPI,
+		strategyStore: strategyStore,
+		positionStore: positionStore,
+		orderService:  orderService,
+		logger:        logger,
+	}
+}
+
+// IRiskExitService - 損切り・利確サービスのインターフェース
+type IRiskExitService interface {
+	Check(strategy *Strategy) error
+}
+
+// riskExitService - 損切り・利確サービス
+type riskExitService struct {
+	clock         IClock
+	kabusAPI      IKabusAPI
+	strategyStore IStrategyStore
+	positionStore IPositionStore
+	orderService  IOrderService
+	logger        ILogger
+}
+
+// Check - 損切り・利確の条件を判定し、条件に達していたら注文を全て取り消してポジションを全てエグジットし、戦略を一時停止する
+// 一時停止中の戦略は、取消によって解放されたポジションが残っていればエグジットを続ける
+func (s *riskExitService) Check(strategy *Strategy) error {
+	if strategy == nil {
+		return ErrNilArgument
+	}
+
+	now := s.clock.Now()
+	if !s.clock.IsTradingTime(now) {
+		return nil
+	}
+
+	if strategy.IsPaused() {
+		if strategy.PauseReason.IsExit() {
+			return s.orderService.ForceExitAll(strategy)
+		}
+		return nil
+	}
+
+	if !strategy.IsRunnable() || !strategy.RiskExitStrategy.Runnable {
+		return nil
+	}
+
+	symbol, err := s.kabusAPI.GetSymbol(strategy.SymbolCode, strategy.Exchange)
+	if err != nil {
+		return err
+	}
+	if symbol.CurrentPrice <= 0 {
+		return nil
+	}
+
+	profit, err := s.unrealizedProfit(strategy.Code, symbol.CurrentPrice)
+	if err != nil {
+		return err
+	}
+
+	reason := strategy.RiskExitStrategy.PauseReason(symbol.CurrentPrice, profit, strategy.Cash)
+	if reason == PauseReasonUnspecified {
+		return nil
+	}
+
+	// 先に一時停止しておき、取消やエグジットの途中でグリッドの注文が出ないようにする
+	if err := s.strategyStore.Pause(strategy.Code, reason, now); err != nil {
+		return err
+	}
+	s.logger.Notice(fmt.Sprintf("%s を一時停止します(reason = %s, price = %.2f, profit = %.2f, cash = %.2f)", strategy.Code, reason, symbol.CurrentPrice, profit, strategy.Cash))
+
+	if err := s.orderService.ForceCancelAll(strategy); err != nil {
+		return err
+	}
+	return s.orderService.ForceExitAll(strategy)
+}
+
+// unrealizedProfit - 保有中のポジションの現在値での含み損益
+func (s *riskExitService) unrealizedProfit(strategyCode string, price float64) (float64, error) {
+	positions, err := s.positionStore.GetActivePositionsByStrategyCode(strategyCode)
+	if err != nil {
+		return 0, err
+	}
+
+	var profit float64
+	for _, p := range positions {
+		switch p.Side {
+		case SideBuy:
+			profit += (price - p.Price) * p.OwnedQuantity
+		case SideSell:
+			profit += (p.Price - price) * p.OwnedQuantity
+		}
+	}
+	return profit, nil
+}
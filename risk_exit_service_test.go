@@ -0,0 +1,229 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testRiskExitService struct {
+	IRiskExitService
+	Check1       error
+	CheckCount   int
+	CheckHistory []interface{}
+}
+
+func (t *testRiskExitService) Check(strategy *Strategy) error {
+	t.CheckHistory = append(t.CheckHistory, strategy)
+	t.CheckCount++
+	return t.Check1
+}
+
+func Test_newRiskExitService(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{}
+	kabusAPI := &testKabusAPI{}
+	strategyStore := &testStrategyStore{}
+	positionStore := &testPositionStore{}
+	orderService := &testOrderService{}
+	logger := &testLogger{}
+	want1 := &riskExitService{
+		clock:         clock,
+		kabusAPI:      kabusAPI,
+		strategyStore: strategyStore,
+		positionStore: positionStore,
+		orderService:  orderService,
+		logger:        logger,
+	}
+	got1 := newRiskExitService(clock, kabusAPI, strategyStore, positionStore, orderService, logger)
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_riskExitService_Check(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                    string
+		clock                   *testClock
+		kabusAPI                *testKabusAPI
+		strategyStore           *testStrategyStore
+		positionStore           *testPositionStore
+		orderService            *testOrderService
+		arg1                    *Strategy
+		want1                   error
+		wantPauseHistory        []interface{}
+		wantForceCancelAllCount int
+		wantForceExitAllCount   int
+	}{
+		{name: "引数がnilならエラー",
+			clock:         &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			kabusAPI:      &testKabusAPI{},
+			strategyStore: &testStrategyStore{},
+			positionStore: &testPositionStore{},
+			orderService:  &testOrderService{},
+			arg1:          nil,
+			want1:         ErrNilArgument},
+		{name: "取引時間でなければ何もしない",
+			clock:         &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: false},
+			kabusAPI:      &testKabusAPI{},
+			strategyStore: &testStrategyStore{},
+			positionStore: &testPositionStore{},
+			orderService:  &testOrderService{},
+			arg1:          &Strategy{Code: "strategy-code-001", Runnable: true, PauseReason: PauseReasonMaxLoss},
+			want1:         nil},
+		{name: "エグジットする理由で一時停止中なら残ったポジションのエグジットを続ける",
+			clock:                 &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			kabusAPI:              &testKabusAPI{},
+			strategyStore:         &testStrategyStore{},
+			positionStore:         &testPositionStore{},
+			orderService:          &testOrderService{},
+			arg1:                  &Strategy{Code: "strategy-code-001", Runnable: true, PauseReason: PauseReasonMaxLoss},
+			want1:                 nil,
+			wantForceExitAllCount: 1},
+		{name: "エグジットしない理由で一時停止中なら何もしない",
+			clock:         &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			kabusAPI:      &testKabusAPI{},
+			strategyStore: &testStrategyStore{},
+			positionStore: &testPositionStore{},
+			orderService:  &testOrderService{},
+			arg1:          &Strategy{Code: "strategy-code-001", Runnable: true, PauseReason: "other"},
+			want1:         nil},
+		{name: "戦略が実行不可なら何もしない",
+			clock:         &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			kabusAPI:      &testKabusAPI{},
+			strategyStore: &testStrategyStore{},
+			positionStore: &testPositionStore{},
+			orderService:  &testOrderService{},
+			arg1:          &Strategy{Code: "strategy-code-001", Runnable: false, RiskExitStrategy: RiskExitStrategy{Runnable: true, MaxLoss: 1}},
+			want1:         nil},
+		{name: "損切り・利確戦略が実行不可なら何もしない",
+			clock:         &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			kabusAPI:      &testKabusAPI{},
+			strategyStore: &testStrategyStore{},
+			positionStore: &testPositionStore{},
+			orderService:  &testOrderService{},
+			arg1:          &Strategy{Code: "strategy-code-001", Runnable: true, RiskExitStrategy: RiskExitStrategy{Runnable: false, MaxLoss: 1}},
+			want1:         nil},
+		{name: "銘柄情報の取得に失敗したらエラー",
+			clock:         &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			kabusAPI:      &testKabusAPI{GetSymbol2: ErrUnknown},
+			strategyStore: &testStrategyStore{},
+			positionStore: &testPositionStore{},
+			orderService:  &testOrderService{},
+			arg1:          &Strategy{Code: "strategy-code-001", Runnable: true, RiskExitStrategy: RiskExitStrategy{Runnable: true, MaxLoss: 1}},
+			want1:         ErrUnknown},
+		{name: "ポジションの取得に失敗したらエラー",
+			clock:         &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			kabusAPI:      &testKabusAPI{GetSymbol1: &Symbol{CurrentPrice: 2100}},
+			strategyStore: &testStrategyStore{},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode2: ErrUnknown},
+			orderService:  &testOrderService{},
+			arg1:          &Strategy{Code: "strategy-code-001", Runnable: true, RiskExitStrategy: RiskExitStrategy{Runnable: true, MaxLoss: 1}},
+			want1:         ErrUnknown},
+		{name: "条件に達していなければ何もしない",
+			clock:         &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			kabusAPI:      &testKabusAPI{GetSymbol1: &Symbol{CurrentPrice: 2100}},
+			strategyStore: &testStrategyStore{},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Code: "position-code-001", Side: SideBuy, Price: 2110, OwnedQuantity: 4}}},
+			orderService:  &testOrderService{},
+			arg1:          &Strategy{Code: "strategy-code-001", Runnable: true, RiskExitStrategy: RiskExitStrategy{Runnable: true, MaxLoss: 50}},
+			want1:         nil},
+		{name: "条件に達したら一時停止して全取消と全エグジットをする",
+			clock:                   &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			kabusAPI:                &testKabusAPI{GetSymbol1: &Symbol{CurrentPrice: 2100}},
+			strategyStore:           &testStrategyStore{},
+			positionStore:           &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Code: "position-code-001", Side: SideBuy, Price: 2110, OwnedQuantity: 5}}},
+			orderService:            &testOrderService{},
+			arg1:                    &Strategy{Code: "strategy-code-001", Runnable: true, RiskExitStrategy: RiskExitStrategy{Runnable: true, MaxLoss: 50}},
+			want1:                   nil,
+			wantPauseHistory:        []interface{}{"strategy-code-001", PauseReasonMaxLoss, time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local)},
+			wantForceCancelAllCount: 1,
+			wantForceExitAllCount:   1},
+		{name: "一時停止に失敗したら取消もエグジットもせずにエラー",
+			clock:            &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			kabusAPI:         &testKabusAPI{GetSymbol1: &Symbol{CurrentPrice: 2000}},
+			strategyStore:    &testStrategyStore{Pause1: ErrUnknown},
+			positionStore:    &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{}},
+			orderService:     &testOrderService{},
+			arg1:             &Strategy{Code: "strategy-code-001", Runnable: true, RiskExitStrategy: RiskExitStrategy{Runnable: true, LowerPrice: 2000}},
+			want1:            ErrUnknown,
+			wantPauseHistory: []interface{}{"strategy-code-001", PauseReasonLowerPrice, time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local)}},
+		{name: "全取消に失敗したらエグジットせずにエラー",
+			clock:                   &testClock{Now1: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local), IsTradingTime1: true},
+			kabusAPI:                &testKabusAPI{GetSymbol1: &Symbol{CurrentPrice: 2000}},
+			strategyStore:           &testStrategyStore{},
+			positionStore:           &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{}},
+			orderService:            &testOrderService{ForceCancelAll1: ErrUnknown},
+			arg1:                    &Strategy{Code: "strategy-code-001", Runnable: true, RiskExitStrategy: RiskExitStrategy{Runnable: true, LowerPrice: 2000}},
+			want1:                   ErrUnknown,
+			wantPauseHistory:        []interface{}{"strategy-code-001", PauseReasonLowerPrice, time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local)},
+			wantForceCancelAllCount: 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &riskExitService{
+				clock:         test.clock,
+				kabusAPI:      test.kabusAPI,
+				strategyStore: test.strategyStore,
+				positionStore: test.positionStore,
+				orderService:  test.orderService,
+				logger:        &testLogger{},
+			}
+			got1 := service.Check(test.arg1)
+			if !errors.Is(got1, test.want1) ||
+				!reflect.DeepEqual(test.wantPauseHistory, test.strategyStore.PauseHistory) ||
+				!reflect.DeepEqual(test.wantForceCancelAllCount, test.orderService.ForceCancelAllCount) ||
+				!reflect.DeepEqual(test.wantForceExitAllCount, test.orderService.ForceExitAllCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantPauseHistory, test.wantForceCancelAllCount, test.wantForceExitAllCount,
+					got1, test.strategyStore.PauseHistory, test.orderService.ForceCancelAllCount, test.orderService.ForceExitAllCount)
+			}
+		})
+	}
+}
+
+func Test_riskExitService_unrealizedProfit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		positionStore *testPositionStore
+		arg1          string
+		arg2          float64
+		want1         float64
+		want2         error
+	}{
+		{name: "ポジションの取得に失敗したらエラー",
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode2: ErrUnknown},
+			arg1:          "strategy-code-001",
+			arg2:          2100,
+			want1:         0,
+			want2:         ErrUnknown},
+		{name: "買いポジションと売りポジションの含み損益を合計する",
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
+				{Code: "position-code-001", Side: SideBuy, Price: 2090, OwnedQuantity: 4, HoldQuantity: 4},
+				{Code: "position-code-002", Side: SideBuy, Price: 2110, OwnedQuantity: 2},
+				{Code: "position-code-003", Side: SideSell, Price: 2095, OwnedQuantity: 3},
+			}},
+			arg1:  "strategy-code-001",
+			arg2:  2100,
+			want1: 40 - 20 - 15,
+			want2: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &riskExitService{positionStore: test.positionStore}
+			got1, got2 := service.unrealizedProfit(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package gridon
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// newRiskManager - 新しいリスク管理の取得
+func newRiskManager(clock IClock, kabusAPI IKabusAPI, strategyStore IStrategyStore, orderStore IOrderStore, positionStore IPositionStore, tradeStore ITradeStore, riskSettingStore IRiskSettingStore, logger ILogger) IRiskManager {
+	return &riskManager{
+		clock:            clock,
+		kabusAPI:         kabusAPI,
+		strategyStore:    strategyStore,
+		orderStore:       orderStore,
+		positionStore:    positionStore,
+		tradeStore:       tradeStore,
+		riskSettingStore: riskSettingStore,
+		logger:           logger,
+		orderTimes:       map[string][]time.Time{},
+		strategyProfits:  map[string]float64{},
+	}
+}
+
+// IRiskManager - リスク管理のインターフェース
+type IRiskManager interface {
+	Check(strategy *Strategy, order *Order) error
+	Record(strategy *Strategy)
+	Evaluate(strategies []*Strategy) error
+	IsKilled() bool
+	SetKillSwitch(on bool, reason string) error
+	SetLimit(limit RiskLimit) error
+	GetStatus() RiskStatus
+	Restore() error
+}
+
+// riskManager - リスク管理
+// 注文の送信前に戦略ごとの上限と全戦略合計の上限を確認し、当日の損失が全戦略合計の上限に達したらキルスイッチを入れる
+// キルスイッチは手動で切るまで入ったままになり、全戦略合計の上限と一緒にDBに保存して再起動後も引き継ぐ
+type riskManager struct {
+	clock             IClock
+	kabusAPI          IKabusAPI
+	strategyStore     IStrategyStore
+	orderStore        IOrderStore
+	positionStore     IPositionStore
+	tradeStore        ITradeStore
+	riskSettingStore  IRiskSettingStore
+	logger            ILogger
+	limit             RiskLimit
+	killSwitch        bool
+	killReason        string
+	killedDateTime    time.Time
+	orderTimes        map[string][]time.Time
+	allOrderTimes     []time.Time
+	dailyProfit       float64
+	strategyProfits   map[string]float64
+	evaluatedDateTime time.Time
+	mtx               sync.Mutex
+}
+
+// Check - 注文を送信してよいかを確認する
+// エントリー注文だけを確認し、エグジット注文はポジションを減らす注文で、止めると損失を止められなくなるのでキルスイッチが入っていても通す
+// 送信した注文の記録は、送信に成功したときにRecordで行なう
+func (s *riskManager) Check(strategy *Strategy, order *Order) error {
+	if strategy == nil || order == nil {
+		return ErrNilArgument
+	}
+	if order.TradeType == TradeTypeExit {
+		return nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.killSwitch {
+		return ErrKillSwitch
+	}
+
+	now := s.clock.Now()
+	s.orderTimes[strategy.Code] = s.recentOrderTimes(s.orderTimes[strategy.Code], now)
+	s.allOrderTimes = s.recentOrderTimes(s.allOrderTimes, now)
+	if strategy.RiskLimit.ExceedsOrdersPerMinute(len(s.orderTimes[strategy.Code])) {
+		return fmt.Errorf("strategy=%s, orders per minute=%d: %w", strategy.Code, len(s.orderTimes[strategy.Code]), ErrRiskLimitExceeded)
+	}
+	if s.limit.ExceedsOrdersPerMinute(len(s.allOrderTimes)) {
+		return fmt.Errorf("orders per minute=%d: %w", len(s.allOrderTimes), ErrRiskLimitExceeded)
+	}
+
+	strategies, err := s.strategyStore.GetStrategies()
+	if err != nil {
+		return err
+	}
+	var exposure, allExposure float64
+	var openOrders, allOpenOrders int
+	for _, st := range strategies {
+		e, o, err := s.exposure(st)
+		if err != nil {
+			return err
+		}
+		if st.Code == strategy.Code {
+			exposure, openOrders = e, o
+		}
+		allExposure += e
+		allOpenOrders += o
+	}
+
+	if strategy.RiskLimit.ExceedsOpenOrders(openOrders) {
+		return fmt.Errorf("strategy=%s, open orders=%d: %w", strategy.Code, openOrders, ErrRiskLimitExceeded)
+	}
+	if s.limit.ExceedsOpenOrders(allOpenOrders) {
+		return fmt.Errorf("open orders=%d: %w", allOpenOrders, ErrRiskLimitExceeded)
+	}
+
+	if profit := s.strategyProfits[strategy.Code]; strategy.RiskLimit.ExceedsDailyLoss(profit) {
+		return fmt.Errorf("strategy=%s, daily profit=%.2f: %w", strategy.Code, profit, ErrRiskLimitExceeded)
+	}
+
+	amount := s.estimatedPrice(strategy, order) * order.OrderQuantity
+	if strategy.RiskLimit.ExceedsGrossExposure(exposure + amount) {
+		return fmt.Errorf("strategy=%s, gross exposure=%.2f: %w", strategy.Code, exposure+amount, ErrRiskLimitExceeded)
+	}
+	if s.limit.ExceedsGrossExposure(allExposure + amount) {
+		return fmt.Errorf("gross exposure=%.2f: %w", allExposure+amount, ErrRiskLimitExceeded)
+	}
+	return nil
+}
+
+// Record - 送信に成功した注文の日時を、直近1分間の注文数の確認のために記録する
+// 弾かれた注文や送信に失敗した注文で注文数の枠を使わないよう、送信の成功後に呼ぶ
+// エグジット注文も証券会社への注文数には含まれるので記録する
+func (s *riskManager) Record(strategy *Strategy) {
+	if strategy == nil {
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := s.clock.Now()
+	s.orderTimes[strategy.Code] = append(s.recentOrderTimes(s.orderTimes[strategy.Code], now), now)
+	s.allOrderTimes = append(s.recentOrderTimes(s.allOrderTimes, now), now)
+}
+
+// recentOrderTimes - 注文日時の一覧から直近1分間のものだけを返す
+func (s *riskManager) recentOrderTimes(times []time.Time, now time.Time) []time.Time {
+	from := now.Add(-1 * time.Minute)
+	res := make([]time.Time, 0, len(times))
+	for _, t := range times {
+		if t.After(from) {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// exposure - 戦略の保有ポジションとエントリー注文の約定代金の合計と、注文中の注文数
+func (s *riskManager) exposure(strategy *Strategy) (float64, int, error) {
+	positions, err := s.positionStore.GetActivePositionsByStrategyCode(strategy.Code)
+	if err != nil {
+		return 0, 0, err
+	}
+	orders, err := s.orderStore.GetActiveOrdersByStrategyCode(strategy.Code)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var exposure float64
+	for _, p := range positions {
+		exposure += p.Price * p.OwnedQuantity
+	}
+	for _, o := range orders {
+		if o.TradeType != TradeTypeEntry {
+			continue
+		}
+		exposure += s.estimatedPrice(strategy, o) * (o.OrderQuantity - o.ContractQuantity)
+	}
+	return exposure, len(orders), nil
+}
+
+// estimatedPrice - 注文が約定する価格の見込み
+// 指値があれば指値、なければ逆指値の発火価格、どちらもなければ戦略の基準価格とする
+func (s *riskManager) estimatedPrice(strategy *Strategy, order *Order) float64 {
+	switch {
+	case order.Price > 0:
+		return order.Price
+	case order.TriggerPrice > 0:
+		return order.TriggerPrice
+	}
+	return strategy.BasePrice
+}
+
+// Evaluate - 戦略ごとの当日の確定損益と含み損益を評価し、全戦略合計の損失が上限に達していたらキルスイッチを入れる
+func (s *riskManager) Evaluate(strategies []*Strategy) error {
+	now := s.clock.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	summaries, err := s.tradeStore.GetStrategySummaries(today, today.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	profits := make(map[string]float64)
+	for _, summary := range summaries {
+		profits[summary.StrategyCode] += summary.NetProfit
+	}
+
+	for _, strategy := range strategies {
+		if strategy == nil {
+			continue
+		}
+		profit, err := s.unrealizedProfit(strategy)
+		if err != nil {
+			return err
+		}
+		profits[strategy.Code] += profit
+	}
+
+	var total float64
+	for _, p := range profits {
+		total += p
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.strategyProfits = profits
+	s.dailyProfit = total
+	s.evaluatedDateTime = now
+
+	if !s.killSwitch && s.limit.ExceedsDailyLoss(total) {
+		s.killSwitch = true
+		s.killReason = fmt.Sprintf("daily loss limit exceeded(profit = %.2f, limit = %.2f)", total, s.limit.MaxDailyLoss)
+		s.killedDateTime = now
+		s.logger.Notice(fmt.Sprintf("キルスイッチを入れます(reason = %s)", s.killReason))
+		return s.save()
+	}
+	return nil
+}
+
+// unrealizedProfit - 戦略の保有中のポジションの現在値での含み損益
+// ポジションがなければ銘柄情報を取得しない
+func (s *riskManager) unrealizedProfit(strategy *Strategy) (float64, error) {
+	positions, err := s.positionStore.GetActivePositionsByStrategyCode(strategy.Code)
+	if err != nil {
+		return 0, err
+	}
+	if len(positions) == 0 {
+		return 0, nil
+	}
+
+	symbol, err := s.kabusAPI.GetSymbol(strategy.SymbolCode, strategy.Exchange)
+	if err != nil {
+		return 0, err
+	}
+	if symbol.CurrentPrice <= 0 {
+		return 0, nil
+	}
+
+	var profit float64
+	for _, p := range positions {
+		switch p.Side {
+		case SideBuy:
+			profit += (symbol.CurrentPrice - p.Price) * p.OwnedQuantity
+		case SideSell:
+			profit += (p.Price - symbol.CurrentPrice) * p.OwnedQuantity
+		}
+	}
+	return profit, nil
+}
+
+// IsKilled - キルスイッチが入っているかどうか
+func (s *riskManager) IsKilled() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.killSwitch
+}
+
+// SetKillSwitch - キルスイッチを手動で入れるか切る
+// 保存に失敗しても、切り替えたキルスイッチはそのまま効かせる
+func (s *riskManager) SetKillSwitch(on bool, reason string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.killSwitch = on
+	if on {
+		s.killReason = reason
+		s.killedDateTime = s.clock.Now()
+		s.logger.Notice(fmt.Sprintf("キルスイッチを入れます(reason = %s)", reason))
+	} else {
+		s.killReason = ""
+		s.killedDateTime = time.Time{}
+		s.logger.Notice(fmt.Sprintf("キルスイッチを切ります(reason = %s)", reason))
+	}
+	return s.save()
+}
+
+// SetLimit - 全戦略合計の上限を設定する
+func (s *riskManager) SetLimit(limit RiskLimit) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.limit = limit
+	return s.save()
+}
+
+// save - 全戦略合計の上限とキルスイッチの状態を保存する
+// ロックを取った状態で呼び出す
+func (s *riskManager) save() error {
+	return s.riskSettingStore.Save(&RiskSetting{
+		Limit:          s.limit,
+		KillSwitch:     s.killSwitch,
+		KillReason:     s.killReason,
+		KilledDateTime: s.killedDateTime,
+	})
+}
+
+// Restore - 保存されている全戦略合計の上限とキルスイッチの状態を読み込む
+// 保存されていなければ初期状態のままにする
+func (s *riskManager) Restore() error {
+	setting, err := s.riskSettingStore.Get()
+	if errors.Is(err, ErrNoData) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.limit = setting.Limit
+	s.killSwitch = setting.KillSwitch
+	s.killReason = setting.KillReason
+	s.killedDateTime = setting.KilledDateTime
+	if s.killSwitch {
+		s.logger.Notice(fmt.Sprintf("キルスイッチが入った状態で起動します(reason = %s)", s.killReason))
+	}
+	return nil
+}
+
+// GetStatus - リスク管理の状態を返す
+func (s *riskManager) GetStatus() RiskStatus {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	profits := make(map[string]float64)
+	for code, p := range s.strategyProfits {
+		profits[code] = p
+	}
+	return RiskStatus{
+		KillSwitch:        s.killSwitch,
+		KillReason:        s.killReason,
+		KilledDateTime:    s.killedDateTime,
+		Limit:             s.limit,
+		DailyProfit:       s.dailyProfit,
+		StrategyProfits:   profits,
+		EvaluatedDateTime: s.evaluatedDateTime,
+	}
+}
@@ -0,0 +1,758 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testRiskManager struct {
+	IRiskManager
+	Check1               error
+	CheckCount           int
+	CheckHistory         []interface{}
+	RecordCount          int
+	RecordHistory        []interface{}
+	Evaluate1            error
+	EvaluateCount        int
+	IsKilled1            bool
+	SetKillSwitch1       error
+	SetKillSwitchHistory []interface{}
+	SetLimit1            error
+	SetLimitHistory      []RiskLimit
+	GetStatus1           RiskStatus
+	Restore1             error
+}
+
+func (t *testRiskManager) Check(strategy *Strategy, order *Order) error {
+	t.CheckHistory = append(t.CheckHistory, strategy, order)
+	t.CheckCount++
+	return t.Check1
+}
+func (t *testRiskManager) Record(strategy *Strategy) {
+	t.RecordHistory = append(t.RecordHistory, strategy)
+	t.RecordCount++
+}
+func (t *testRiskManager) Evaluate([]*Strategy) error {
+	t.EvaluateCount++
+	return t.Evaluate1
+}
+func (t *testRiskManager) IsKilled() bool { return t.IsKilled1 }
+func (t *testRiskManager) SetKillSwitch(on bool, reason string) error {
+	t.SetKillSwitchHistory = append(t.SetKillSwitchHistory, on, reason)
+	return t.SetKillSwitch1
+}
+func (t *testRiskManager) SetLimit(limit RiskLimit) error {
+	t.SetLimitHistory = append(t.SetLimitHistory, limit)
+	return t.SetLimit1
+}
+func (t *testRiskManager) GetStatus() RiskStatus { return t.GetStatus1 }
+func (t *testRiskManager) Restore() error        { return t.Restore1 }
+
+func Test_newRiskManager(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{}
+	kabusAPI := &testKabusAPI{}
+	strategyStore := &testStrategyStore{}
+	orderStore := &testOrderStore{}
+	positionStore := &testPositionStore{}
+	tradeStore := &testTradeStore{}
+	riskSettingStore := &testRiskSettingStore{}
+	logger := &testLogger{}
+	want1 := &riskManager{
+		clock:            clock,
+		kabusAPI:         kabusAPI,
+		strategyStore:    strategyStore,
+		orderStore:       orderStore,
+		positionStore:    positionStore,
+		tradeStore:       tradeStore,
+		riskSettingStore: riskSettingStore,
+		logger:           logger,
+		orderTimes:       map[string][]time.Time{},
+		strategyProfits:  map[string]float64{},
+	}
+	got1 := newRiskManager(clock, kabusAPI, strategyStore, orderStore, positionStore, tradeStore, riskSettingStore, logger)
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_riskManager_Check(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name              string
+		strategyStore     *testStrategyStore
+		orderStore        *testOrderStore
+		positionStore     *testPositionStore
+		limit             RiskLimit
+		killSwitch        bool
+		orderTimes        map[string][]time.Time
+		allOrderTimes     []time.Time
+		strategyProfits   map[string]float64
+		arg1              *Strategy
+		arg2              *Order
+		want1             error
+		wantOrderTimes    map[string][]time.Time
+		wantAllOrderTimes []time.Time
+	}{
+		{name: "strategyがnilならエラー",
+			strategyStore:     &testStrategyStore{},
+			orderStore:        &testOrderStore{},
+			positionStore:     &testPositionStore{},
+			orderTimes:        map[string][]time.Time{},
+			arg1:              nil,
+			arg2:              &Order{},
+			want1:             ErrNilArgument,
+			wantOrderTimes:    map[string][]time.Time{},
+			wantAllOrderTimes: nil},
+		{name: "orderがnilならエラー",
+			strategyStore:     &testStrategyStore{},
+			orderStore:        &testOrderStore{},
+			positionStore:     &testPositionStore{},
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001"},
+			arg2:              nil,
+			want1:             ErrNilArgument,
+			wantOrderTimes:    map[string][]time.Time{},
+			wantAllOrderTimes: nil},
+		{name: "キルスイッチが入っていたらエラー",
+			strategyStore:     &testStrategyStore{},
+			orderStore:        &testOrderStore{},
+			positionStore:     &testPositionStore{},
+			killSwitch:        true,
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001"},
+			arg2:              &Order{TradeType: TradeTypeEntry},
+			want1:             ErrKillSwitch,
+			wantOrderTimes:    map[string][]time.Time{},
+			wantAllOrderTimes: nil},
+		{name: "戦略の直近1分間の注文数が上限に達していたらエラー",
+			strategyStore: &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore:    &testOrderStore{},
+			positionStore: &testPositionStore{},
+			orderTimes:    map[string][]time.Time{"strategy-code-001": {now.Add(-30 * time.Second), now.Add(-10 * time.Second)}},
+			allOrderTimes: []time.Time{now.Add(-30 * time.Second), now.Add(-10 * time.Second)},
+			arg1:          &Strategy{Code: "strategy-code-001", RiskLimit: RiskLimit{MaxOrdersPerMinute: 2}},
+			arg2:          &Order{TradeType: TradeTypeEntry},
+			want1:         ErrRiskLimitExceeded,
+			wantOrderTimes: map[string][]time.Time{
+				"strategy-code-001": {now.Add(-30 * time.Second), now.Add(-10 * time.Second)}},
+			wantAllOrderTimes: []time.Time{now.Add(-30 * time.Second), now.Add(-10 * time.Second)}},
+		{name: "1分以上前の注文は数えずに捨てる",
+			strategyStore: &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore:    &testOrderStore{},
+			positionStore: &testPositionStore{},
+			orderTimes:    map[string][]time.Time{"strategy-code-001": {now.Add(-1 * time.Minute), now.Add(-10 * time.Second)}},
+			allOrderTimes: []time.Time{now.Add(-1 * time.Minute), now.Add(-10 * time.Second)},
+			arg1:          &Strategy{Code: "strategy-code-001", RiskLimit: RiskLimit{MaxOrdersPerMinute: 2}},
+			arg2:          &Order{TradeType: TradeTypeEntry},
+			want1:         nil,
+			wantOrderTimes: map[string][]time.Time{
+				"strategy-code-001": {now.Add(-10 * time.Second)}},
+			wantAllOrderTimes: []time.Time{now.Add(-10 * time.Second)}},
+		{name: "全戦略の直近1分間の注文数が上限に達していたらエラー",
+			strategyStore: &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore:    &testOrderStore{},
+			positionStore: &testPositionStore{},
+			limit:         RiskLimit{MaxOrdersPerMinute: 2},
+			orderTimes:    map[string][]time.Time{"strategy-code-002": {now.Add(-30 * time.Second), now.Add(-10 * time.Second)}},
+			allOrderTimes: []time.Time{now.Add(-30 * time.Second), now.Add(-10 * time.Second)},
+			arg1:          &Strategy{Code: "strategy-code-001"},
+			arg2:          &Order{TradeType: TradeTypeEntry},
+			want1:         ErrRiskLimitExceeded,
+			wantOrderTimes: map[string][]time.Time{
+				"strategy-code-001": {},
+				"strategy-code-002": {now.Add(-30 * time.Second), now.Add(-10 * time.Second)}},
+			wantAllOrderTimes: []time.Time{now.Add(-30 * time.Second), now.Add(-10 * time.Second)}},
+		{name: "戦略一覧の取得に失敗したらエラー",
+			strategyStore:     &testStrategyStore{GetStrategies2: ErrUnknown},
+			orderStore:        &testOrderStore{},
+			positionStore:     &testPositionStore{},
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001"},
+			arg2:              &Order{TradeType: TradeTypeEntry},
+			want1:             ErrUnknown,
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {}},
+			wantAllOrderTimes: []time.Time{}},
+		{name: "注文一覧の取得に失敗したらエラー",
+			strategyStore:     &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore:        &testOrderStore{GetActiveOrdersByStrategyCode2: ErrUnknown},
+			positionStore:     &testPositionStore{},
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001"},
+			arg2:              &Order{TradeType: TradeTypeEntry},
+			want1:             ErrUnknown,
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {}},
+			wantAllOrderTimes: []time.Time{}},
+		{name: "戦略の注文中の注文数が上限に達していたらエラー",
+			strategyStore:     &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore:        &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{{TradeType: TradeTypeEntry}, {TradeType: TradeTypeExit}}},
+			positionStore:     &testPositionStore{},
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001", RiskLimit: RiskLimit{MaxOpenOrders: 2}},
+			arg2:              &Order{TradeType: TradeTypeEntry},
+			want1:             ErrRiskLimitExceeded,
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {}},
+			wantAllOrderTimes: []time.Time{}},
+		{name: "全戦略の注文中の注文数が上限に達していたらエラー",
+			strategyStore:     &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}, {Code: "strategy-code-002"}}},
+			orderStore:        &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{{TradeType: TradeTypeExit}}},
+			positionStore:     &testPositionStore{},
+			limit:             RiskLimit{MaxOpenOrders: 2},
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001", RiskLimit: RiskLimit{MaxOpenOrders: 2}},
+			arg2:              &Order{TradeType: TradeTypeEntry},
+			want1:             ErrRiskLimitExceeded,
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {}},
+			wantAllOrderTimes: []time.Time{}},
+		{name: "エントリーで戦略の当日の損失が上限に達していたらエラー",
+			strategyStore:     &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore:        &testOrderStore{},
+			positionStore:     &testPositionStore{},
+			orderTimes:        map[string][]time.Time{},
+			strategyProfits:   map[string]float64{"strategy-code-001": -10_000},
+			arg1:              &Strategy{Code: "strategy-code-001", RiskLimit: RiskLimit{MaxDailyLoss: 10_000}},
+			arg2:              &Order{TradeType: TradeTypeEntry, Price: 1000, OrderQuantity: 100},
+			want1:             ErrRiskLimitExceeded,
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {}},
+			wantAllOrderTimes: []time.Time{}},
+		{name: "エグジットなら戦略の当日の損失が上限に達していても注文できる",
+			strategyStore:     &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore:        &testOrderStore{},
+			positionStore:     &testPositionStore{},
+			orderTimes:        map[string][]time.Time{},
+			strategyProfits:   map[string]float64{"strategy-code-001": -10_000},
+			arg1:              &Strategy{Code: "strategy-code-001", RiskLimit: RiskLimit{MaxDailyLoss: 10_000}},
+			arg2:              &Order{TradeType: TradeTypeExit, Price: 1000, OrderQuantity: 100},
+			want1:             nil,
+			wantOrderTimes:    map[string][]time.Time{},
+			wantAllOrderTimes: nil},
+		{name: "エグジットならキルスイッチが入っていても注文できる",
+			strategyStore:     &testStrategyStore{},
+			orderStore:        &testOrderStore{},
+			positionStore:     &testPositionStore{},
+			killSwitch:        true,
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001"},
+			arg2:              &Order{TradeType: TradeTypeExit, ExecutionType: ExecutionTypeStopMarket, TriggerPrice: 990, OrderQuantity: 100},
+			want1:             nil,
+			wantOrderTimes:    map[string][]time.Time{},
+			wantAllOrderTimes: nil},
+		{name: "エグジットなら直近1分間の注文数や注文中の注文数が上限に達していても注文できる",
+			strategyStore:     &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore:        &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{{TradeType: TradeTypeEntry}, {TradeType: TradeTypeExit}}},
+			positionStore:     &testPositionStore{},
+			limit:             RiskLimit{MaxOrdersPerMinute: 2, MaxOpenOrders: 2},
+			orderTimes:        map[string][]time.Time{"strategy-code-001": {now.Add(-30 * time.Second), now.Add(-10 * time.Second)}},
+			allOrderTimes:     []time.Time{now.Add(-30 * time.Second), now.Add(-10 * time.Second)},
+			arg1:              &Strategy{Code: "strategy-code-001", RiskLimit: RiskLimit{MaxOrdersPerMinute: 2, MaxOpenOrders: 2}},
+			arg2:              &Order{TradeType: TradeTypeExit, ExecutionType: ExecutionTypeMarket, OrderQuantity: 100},
+			want1:             nil,
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {now.Add(-30 * time.Second), now.Add(-10 * time.Second)}},
+			wantAllOrderTimes: []time.Time{now.Add(-30 * time.Second), now.Add(-10 * time.Second)}},
+		{name: "エントリーで戦略の約定代金の合計が上限を超えるならエラー",
+			strategyStore: &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore: &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{
+				{TradeType: TradeTypeEntry, Price: 990, OrderQuantity: 100, ContractQuantity: 0},
+				{TradeType: TradeTypeExit, Price: 1010, OrderQuantity: 100}}},
+			positionStore:     &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Price: 1000, OwnedQuantity: 100}}},
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001", RiskLimit: RiskLimit{MaxGrossExposure: 296_999}},
+			arg2:              &Order{TradeType: TradeTypeEntry, Price: 980, OrderQuantity: 100},
+			want1:             ErrRiskLimitExceeded,
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {}},
+			wantAllOrderTimes: []time.Time{}},
+		{name: "エントリーで戦略の約定代金の合計が上限以内なら注文できる",
+			strategyStore: &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore: &testOrderStore{GetActiveOrdersByStrategyCode1: []*Order{
+				{TradeType: TradeTypeEntry, Price: 990, OrderQuantity: 100, ContractQuantity: 0},
+				{TradeType: TradeTypeExit, Price: 1010, OrderQuantity: 100}}},
+			positionStore:     &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Price: 1000, OwnedQuantity: 100}}},
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001", RiskLimit: RiskLimit{MaxGrossExposure: 297_000}},
+			arg2:              &Order{TradeType: TradeTypeEntry, Price: 980, OrderQuantity: 100},
+			want1:             nil,
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {}},
+			wantAllOrderTimes: []time.Time{}},
+		{name: "成行のエントリーは基準価格で約定代金を見込む",
+			strategyStore:     &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore:        &testOrderStore{},
+			positionStore:     &testPositionStore{},
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001", BasePrice: 1000, RiskLimit: RiskLimit{MaxGrossExposure: 99_999}},
+			arg2:              &Order{TradeType: TradeTypeEntry, ExecutionType: ExecutionTypeMarket, OrderQuantity: 100},
+			want1:             ErrRiskLimitExceeded,
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {}},
+			wantAllOrderTimes: []time.Time{}},
+		{name: "エントリーで全戦略の約定代金の合計が上限を超えるならエラー",
+			strategyStore:     &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}, {Code: "strategy-code-002"}}},
+			orderStore:        &testOrderStore{},
+			positionStore:     &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Price: 1000, OwnedQuantity: 100}}},
+			limit:             RiskLimit{MaxGrossExposure: 299_999},
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001", RiskLimit: RiskLimit{MaxGrossExposure: 200_000}},
+			arg2:              &Order{TradeType: TradeTypeEntry, Price: 1000, OrderQuantity: 100},
+			want1:             ErrRiskLimitExceeded,
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {}},
+			wantAllOrderTimes: []time.Time{}},
+		{name: "上限がなければ注文でき、確認だけでは注文日時を記録しない",
+			strategyStore:     &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderStore:        &testOrderStore{},
+			positionStore:     &testPositionStore{},
+			orderTimes:        map[string][]time.Time{},
+			arg1:              &Strategy{Code: "strategy-code-001"},
+			arg2:              &Order{TradeType: TradeTypeEntry, Price: 1000, OrderQuantity: 100},
+			want1:             nil,
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {}},
+			wantAllOrderTimes: []time.Time{}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			manager := &riskManager{
+				clock:           &testClock{Now1: now},
+				strategyStore:   test.strategyStore,
+				orderStore:      test.orderStore,
+				positionStore:   test.positionStore,
+				limit:           test.limit,
+				killSwitch:      test.killSwitch,
+				orderTimes:      test.orderTimes,
+				allOrderTimes:   test.allOrderTimes,
+				strategyProfits: test.strategyProfits,
+			}
+			got1 := manager.Check(test.arg1, test.arg2)
+			if !errors.Is(got1, test.want1) ||
+				!reflect.DeepEqual(test.wantOrderTimes, manager.orderTimes) ||
+				!reflect.DeepEqual(test.wantAllOrderTimes, manager.allOrderTimes) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantOrderTimes, test.wantAllOrderTimes,
+					got1, manager.orderTimes, manager.allOrderTimes)
+			}
+		})
+	}
+}
+
+func Test_riskManager_Record(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name              string
+		orderTimes        map[string][]time.Time
+		allOrderTimes     []time.Time
+		arg1              *Strategy
+		wantOrderTimes    map[string][]time.Time
+		wantAllOrderTimes []time.Time
+	}{
+		{name: "strategyがnilなら何もしない",
+			orderTimes:        map[string][]time.Time{},
+			arg1:              nil,
+			wantOrderTimes:    map[string][]time.Time{},
+			wantAllOrderTimes: nil},
+		{name: "戦略と全戦略の注文日時に記録する",
+			orderTimes:        map[string][]time.Time{"strategy-code-002": {now.Add(-10 * time.Second)}},
+			allOrderTimes:     []time.Time{now.Add(-10 * time.Second)},
+			arg1:              &Strategy{Code: "strategy-code-001"},
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {now}, "strategy-code-002": {now.Add(-10 * time.Second)}},
+			wantAllOrderTimes: []time.Time{now.Add(-10 * time.Second), now}},
+		{name: "記録するときに1分以上前の注文日時は捨てる",
+			orderTimes:        map[string][]time.Time{"strategy-code-001": {now.Add(-1 * time.Minute), now.Add(-10 * time.Second)}},
+			allOrderTimes:     []time.Time{now.Add(-1 * time.Minute), now.Add(-10 * time.Second)},
+			arg1:              &Strategy{Code: "strategy-code-001"},
+			wantOrderTimes:    map[string][]time.Time{"strategy-code-001": {now.Add(-10 * time.Second), now}},
+			wantAllOrderTimes: []time.Time{now.Add(-10 * time.Second), now}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			manager := &riskManager{
+				clock:         &testClock{Now1: now},
+				orderTimes:    test.orderTimes,
+				allOrderTimes: test.allOrderTimes,
+			}
+			manager.Record(test.arg1)
+			if !reflect.DeepEqual(test.wantOrderTimes, manager.orderTimes) || !reflect.DeepEqual(test.wantAllOrderTimes, manager.allOrderTimes) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.wantOrderTimes, test.wantAllOrderTimes, manager.orderTimes, manager.allOrderTimes)
+			}
+		})
+	}
+}
+
+func Test_riskManager_Evaluate(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name                 string
+		kabusAPI             *testKabusAPI
+		positionStore        *testPositionStore
+		tradeStore           *testTradeStore
+		riskSettingStore     *testRiskSettingStore
+		limit                RiskLimit
+		killSwitch           bool
+		arg                  []*Strategy
+		want                 error
+		wantStrategyProfits  map[string]float64
+		wantDailyProfit      float64
+		wantKillSwitch       bool
+		wantKilledDateTime   time.Time
+		wantGetSymbolCount   int
+		wantNoticeCount      int
+		wantSummariesHistory []interface{}
+		wantSaveHistory      []interface{}
+	}{
+		{name: "取引の集計に失敗したらエラー",
+			kabusAPI:             &testKabusAPI{},
+			positionStore:        &testPositionStore{},
+			tradeStore:           &testTradeStore{GetStrategySummaries2: ErrUnknown},
+			arg:                  []*Strategy{{Code: "strategy-code-001"}},
+			want:                 ErrUnknown,
+			wantStrategyProfits:  map[string]float64{},
+			wantSummariesHistory: []interface{}{time.Date(2022, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2022, 2, 2, 0, 0, 0, 0, time.Local)}},
+		{name: "ポジションの取得に失敗したらエラー",
+			kabusAPI:             &testKabusAPI{},
+			positionStore:        &testPositionStore{GetActivePositionsByStrategyCode2: ErrUnknown},
+			tradeStore:           &testTradeStore{},
+			arg:                  []*Strategy{{Code: "strategy-code-001"}},
+			want:                 ErrUnknown,
+			wantStrategyProfits:  map[string]float64{},
+			wantSummariesHistory: []interface{}{time.Date(2022, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2022, 2, 2, 0, 0, 0, 0, time.Local)}},
+		{name: "銘柄情報の取得に失敗したらエラー",
+			kabusAPI:             &testKabusAPI{GetSymbol2: ErrUnknown},
+			positionStore:        &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Side: SideBuy, Price: 1000, OwnedQuantity: 100}}},
+			tradeStore:           &testTradeStore{},
+			arg:                  []*Strategy{{Code: "strategy-code-001"}},
+			want:                 ErrUnknown,
+			wantStrategyProfits:  map[string]float64{},
+			wantGetSymbolCount:   1,
+			wantSummariesHistory: []interface{}{time.Date(2022, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2022, 2, 2, 0, 0, 0, 0, time.Local)}},
+		{name: "ポジションがなければ銘柄情報を取得せず、確定損益だけを評価する",
+			kabusAPI:      &testKabusAPI{},
+			positionStore: &testPositionStore{},
+			tradeStore: &testTradeStore{GetStrategySummaries1: []*TradeSummary{
+				{StrategyCode: "strategy-code-001", NetProfit: 1_000},
+				{StrategyCode: "strategy-code-002", NetProfit: -3_000}}},
+			arg:                  []*Strategy{{Code: "strategy-code-001"}, {Code: "strategy-code-002"}},
+			want:                 nil,
+			wantStrategyProfits:  map[string]float64{"strategy-code-001": 1_000, "strategy-code-002": -3_000},
+			wantDailyProfit:      -2_000,
+			wantSummariesHistory: []interface{}{time.Date(2022, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2022, 2, 2, 0, 0, 0, 0, time.Local)}},
+		{name: "確定損益に現在値での含み損益を加える",
+			kabusAPI: &testKabusAPI{GetSymbol1: &Symbol{CurrentPrice: 990}},
+			positionStore: &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{
+				{Side: SideBuy, Price: 1000, OwnedQuantity: 100},
+				{Side: SideSell, Price: 1000, OwnedQuantity: 300}}},
+			tradeStore:           &testTradeStore{GetStrategySummaries1: []*TradeSummary{{StrategyCode: "strategy-code-001", NetProfit: 1_000}}},
+			arg:                  []*Strategy{{Code: "strategy-code-001"}},
+			want:                 nil,
+			wantStrategyProfits:  map[string]float64{"strategy-code-001": 3_000},
+			wantDailyProfit:      3_000,
+			wantGetSymbolCount:   1,
+			wantSummariesHistory: []interface{}{time.Date(2022, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2022, 2, 2, 0, 0, 0, 0, time.Local)}},
+		{name: "全戦略合計の損失が上限に達したらキルスイッチを入れる",
+			kabusAPI:             &testKabusAPI{GetSymbol1: &Symbol{CurrentPrice: 900}},
+			positionStore:        &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Side: SideBuy, Price: 1000, OwnedQuantity: 100}}},
+			tradeStore:           &testTradeStore{},
+			limit:                RiskLimit{MaxDailyLoss: 10_000},
+			arg:                  []*Strategy{{Code: "strategy-code-001"}},
+			want:                 nil,
+			wantStrategyProfits:  map[string]float64{"strategy-code-001": -10_000},
+			wantDailyProfit:      -10_000,
+			wantKillSwitch:       true,
+			wantKilledDateTime:   now,
+			wantGetSymbolCount:   1,
+			wantNoticeCount:      1,
+			wantSummariesHistory: []interface{}{time.Date(2022, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2022, 2, 2, 0, 0, 0, 0, time.Local)},
+			wantSaveHistory: []interface{}{&RiskSetting{
+				Limit:          RiskLimit{MaxDailyLoss: 10_000},
+				KillSwitch:     true,
+				KillReason:     "daily loss limit exceeded(profit = -10000.00, limit = 10000.00)",
+				KilledDateTime: now}}},
+		{name: "キルスイッチを入れた状態の保存に失敗したら、キルスイッチは入れたままエラー",
+			kabusAPI:             &testKabusAPI{GetSymbol1: &Symbol{CurrentPrice: 900}},
+			positionStore:        &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Side: SideBuy, Price: 1000, OwnedQuantity: 100}}},
+			tradeStore:           &testTradeStore{},
+			riskSettingStore:     &testRiskSettingStore{Save1: ErrUnknown},
+			limit:                RiskLimit{MaxDailyLoss: 10_000},
+			arg:                  []*Strategy{{Code: "strategy-code-001"}},
+			want:                 ErrUnknown,
+			wantStrategyProfits:  map[string]float64{"strategy-code-001": -10_000},
+			wantDailyProfit:      -10_000,
+			wantKillSwitch:       true,
+			wantKilledDateTime:   now,
+			wantGetSymbolCount:   1,
+			wantNoticeCount:      1,
+			wantSummariesHistory: []interface{}{time.Date(2022, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2022, 2, 2, 0, 0, 0, 0, time.Local)},
+			wantSaveHistory: []interface{}{&RiskSetting{
+				Limit:          RiskLimit{MaxDailyLoss: 10_000},
+				KillSwitch:     true,
+				KillReason:     "daily loss limit exceeded(profit = -10000.00, limit = 10000.00)",
+				KilledDateTime: now}}},
+		{name: "損失が上限未満ならキルスイッチを入れない",
+			kabusAPI:             &testKabusAPI{GetSymbol1: &Symbol{CurrentPrice: 901}},
+			positionStore:        &testPositionStore{GetActivePositionsByStrategyCode1: []*Position{{Side: SideBuy, Price: 1000, OwnedQuantity: 100}}},
+			tradeStore:           &testTradeStore{},
+			limit:                RiskLimit{MaxDailyLoss: 10_000},
+			arg:                  []*Strategy{{Code: "strategy-code-001"}},
+			want:                 nil,
+			wantStrategyProfits:  map[string]float64{"strategy-code-001": -9_900},
+			wantDailyProfit:      -9_900,
+			wantGetSymbolCount:   1,
+			wantSummariesHistory: []interface{}{time.Date(2022, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2022, 2, 2, 0, 0, 0, 0, time.Local)}},
+		{name: "キルスイッチは損失が戻っても入ったままにする",
+			kabusAPI:             &testKabusAPI{},
+			positionStore:        &testPositionStore{},
+			tradeStore:           &testTradeStore{},
+			limit:                RiskLimit{MaxDailyLoss: 10_000},
+			killSwitch:           true,
+			arg:                  []*Strategy{{Code: "strategy-code-001"}},
+			want:                 nil,
+			wantStrategyProfits:  map[string]float64{"strategy-code-001": 0},
+			wantKillSwitch:       true,
+			wantSummariesHistory: []interface{}{time.Date(2022, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2022, 2, 2, 0, 0, 0, 0, time.Local)}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			logger := &testLogger{}
+			riskSettingStore := test.riskSettingStore
+			if riskSettingStore == nil {
+				riskSettingStore = &testRiskSettingStore{}
+			}
+			manager := &riskManager{
+				clock:            &testClock{Now1: now},
+				kabusAPI:         test.kabusAPI,
+				positionStore:    test.positionStore,
+				tradeStore:       test.tradeStore,
+				riskSettingStore: riskSettingStore,
+				logger:           logger,
+				limit:            test.limit,
+				killSwitch:       test.killSwitch,
+				strategyProfits:  map[string]float64{},
+			}
+			got := manager.Evaluate(test.arg)
+			if !errors.Is(got, test.want) ||
+				!reflect.DeepEqual(test.wantStrategyProfits, manager.strategyProfits) ||
+				!reflect.DeepEqual(test.wantDailyProfit, manager.dailyProfit) ||
+				!reflect.DeepEqual(test.wantKillSwitch, manager.killSwitch) ||
+				!reflect.DeepEqual(test.wantKilledDateTime, manager.killedDateTime) ||
+				!reflect.DeepEqual(test.wantGetSymbolCount, test.kabusAPI.GetSymbolCount) ||
+				!reflect.DeepEqual(test.wantNoticeCount, logger.NoticeCount) ||
+				!reflect.DeepEqual(test.wantSummariesHistory, test.tradeStore.GetStrategySummariesHistory) ||
+				!reflect.DeepEqual(test.wantSaveHistory, riskSettingStore.SaveHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v, %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v, %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want, test.wantStrategyProfits, test.wantDailyProfit, test.wantKillSwitch, test.wantKilledDateTime, test.wantGetSymbolCount, test.wantNoticeCount, test.wantSummariesHistory, test.wantSaveHistory,
+					got, manager.strategyProfits, manager.dailyProfit, manager.killSwitch, manager.killedDateTime, test.kabusAPI.GetSymbolCount, logger.NoticeCount, test.tradeStore.GetStrategySummariesHistory, riskSettingStore.SaveHistory)
+			}
+		})
+	}
+}
+
+func Test_riskManager_SetKillSwitch(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name               string
+		killSwitch         bool
+		killReason         string
+		killedDateTime     time.Time
+		riskSettingStore   *testRiskSettingStore
+		arg1               bool
+		arg2               string
+		want               error
+		wantKillSwitch     bool
+		wantKillReason     string
+		wantKilledDateTime time.Time
+		wantSaveHistory    []interface{}
+	}{
+		{name: "入れると理由と日時を記録して保存する",
+			riskSettingStore:   &testRiskSettingStore{},
+			arg1:               true,
+			arg2:               "manual",
+			want:               nil,
+			wantKillSwitch:     true,
+			wantKillReason:     "manual",
+			wantKilledDateTime: now,
+			wantSaveHistory:    []interface{}{&RiskSetting{Limit: RiskLimit{MaxDailyLoss: 10_000}, KillSwitch: true, KillReason: "manual", KilledDateTime: now}}},
+		{name: "切ると理由と日時を消して保存する",
+			killSwitch:         true,
+			killReason:         "daily loss limit exceeded",
+			killedDateTime:     now.Add(-1 * time.Hour),
+			riskSettingStore:   &testRiskSettingStore{},
+			arg1:               false,
+			arg2:               "resume",
+			want:               nil,
+			wantKillSwitch:     false,
+			wantKillReason:     "",
+			wantKilledDateTime: time.Time{},
+			wantSaveHistory:    []interface{}{&RiskSetting{Limit: RiskLimit{MaxDailyLoss: 10_000}}}},
+		{name: "保存に失敗してもキルスイッチは切り替えてエラーを返す",
+			riskSettingStore:   &testRiskSettingStore{Save1: ErrUnknown},
+			arg1:               true,
+			arg2:               "manual",
+			want:               ErrUnknown,
+			wantKillSwitch:     true,
+			wantKillReason:     "manual",
+			wantKilledDateTime: now,
+			wantSaveHistory:    []interface{}{&RiskSetting{Limit: RiskLimit{MaxDailyLoss: 10_000}, KillSwitch: true, KillReason: "manual", KilledDateTime: now}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			manager := &riskManager{
+				clock:            &testClock{Now1: now},
+				riskSettingStore: test.riskSettingStore,
+				logger:           &testLogger{},
+				limit:            RiskLimit{MaxDailyLoss: 10_000},
+				killSwitch:       test.killSwitch,
+				killReason:       test.killReason,
+				killedDateTime:   test.killedDateTime,
+			}
+			got := manager.SetKillSwitch(test.arg1, test.arg2)
+			if !errors.Is(got, test.want) ||
+				!reflect.DeepEqual(test.wantKillSwitch, manager.killSwitch) ||
+				!reflect.DeepEqual(test.wantKillReason, manager.killReason) ||
+				!reflect.DeepEqual(test.wantKilledDateTime, manager.killedDateTime) ||
+				!reflect.DeepEqual(test.wantKillSwitch, manager.IsKilled()) ||
+				!reflect.DeepEqual(test.wantSaveHistory, test.riskSettingStore.SaveHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want, test.wantKillSwitch, test.wantKillReason, test.wantKilledDateTime, test.wantSaveHistory,
+					got, manager.killSwitch, manager.killReason, manager.killedDateTime, test.riskSettingStore.SaveHistory)
+			}
+		})
+	}
+}
+
+func Test_riskManager_SetLimit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		store           *testRiskSettingStore
+		arg             RiskLimit
+		want            error
+		wantLimit       RiskLimit
+		wantSaveHistory []interface{}
+	}{
+		{name: "上限を設定してキルスイッチの状態と一緒に保存する",
+			store:           &testRiskSettingStore{},
+			arg:             RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000},
+			want:            nil,
+			wantLimit:       RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000},
+			wantSaveHistory: []interface{}{&RiskSetting{Limit: RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000}, KillSwitch: true, KillReason: "manual"}}},
+		{name: "保存に失敗しても上限は設定してエラーを返す",
+			store:           &testRiskSettingStore{Save1: ErrUnknown},
+			arg:             RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000},
+			want:            ErrUnknown,
+			wantLimit:       RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000},
+			wantSaveHistory: []interface{}{&RiskSetting{Limit: RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000}, KillSwitch: true, KillReason: "manual"}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			manager := &riskManager{riskSettingStore: test.store, killSwitch: true, killReason: "manual"}
+			got := manager.SetLimit(test.arg)
+			if !errors.Is(got, test.want) ||
+				!reflect.DeepEqual(test.wantLimit, manager.limit) ||
+				!reflect.DeepEqual(test.wantSaveHistory, test.store.SaveHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					test.want, test.wantLimit, test.wantSaveHistory,
+					got, manager.limit, test.store.SaveHistory)
+			}
+		})
+	}
+}
+
+func Test_riskManager_Restore(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name               string
+		store              *testRiskSettingStore
+		want               error
+		wantLimit          RiskLimit
+		wantKillSwitch     bool
+		wantKillReason     string
+		wantKilledDateTime time.Time
+		wantNoticeCount    int
+	}{
+		{name: "保存されていなければ初期状態のままにする",
+			store: &testRiskSettingStore{Get2: ErrNoData},
+			want:  nil},
+		{name: "読み込みに失敗したらエラー",
+			store: &testRiskSettingStore{Get2: ErrUnknown},
+			want:  ErrUnknown},
+		{name: "保存されている上限を読み込む",
+			store:     &testRiskSettingStore{Get1: &RiskSetting{Limit: RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000}}},
+			want:      nil,
+			wantLimit: RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000}},
+		{name: "キルスイッチが入っていたら入ったままにして通知する",
+			store: &testRiskSettingStore{Get1: &RiskSetting{
+				Limit:          RiskLimit{MaxDailyLoss: 30_000},
+				KillSwitch:     true,
+				KillReason:     "manual",
+				KilledDateTime: time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)}},
+			want:               nil,
+			wantLimit:          RiskLimit{MaxDailyLoss: 30_000},
+			wantKillSwitch:     true,
+			wantKillReason:     "manual",
+			wantKilledDateTime: time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local),
+			wantNoticeCount:    1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			logger := &testLogger{}
+			manager := &riskManager{riskSettingStore: test.store, logger: logger}
+			got := manager.Restore()
+			if !errors.Is(got, test.want) ||
+				!reflect.DeepEqual(test.wantLimit, manager.limit) ||
+				!reflect.DeepEqual(test.wantKillSwitch, manager.IsKilled()) ||
+				!reflect.DeepEqual(test.wantKillReason, manager.killReason) ||
+				!reflect.DeepEqual(test.wantKilledDateTime, manager.killedDateTime) ||
+				!reflect.DeepEqual(test.wantNoticeCount, logger.NoticeCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want, test.wantLimit, test.wantKillSwitch, test.wantKillReason, test.wantKilledDateTime, test.wantNoticeCount,
+					got, manager.limit, manager.killSwitch, manager.killReason, manager.killedDateTime, logger.NoticeCount)
+			}
+		})
+	}
+}
+
+func Test_riskManager_GetStatus(t *testing.T) {
+	t.Parallel()
+	manager := &riskManager{
+		killSwitch:        true,
+		killReason:        "manual",
+		killedDateTime:    time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local),
+		dailyProfit:       -1_000,
+		strategyProfits:   map[string]float64{"strategy-code-001": -1_000},
+		evaluatedDateTime: time.Date(2022, 2, 1, 10, 0, 4, 0, time.Local),
+		riskSettingStore:  &testRiskSettingStore{},
+	}
+	_ = manager.SetLimit(RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000})
+
+	want1 := RiskStatus{
+		KillSwitch:        true,
+		KillReason:        "manual",
+		KilledDateTime:    time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local),
+		Limit:             RiskLimit{MaxOpenOrders: 10, MaxDailyLoss: 30_000},
+		DailyProfit:       -1_000,
+		StrategyProfits:   map[string]float64{"strategy-code-001": -1_000},
+		EvaluatedDateTime: time.Date(2022, 2, 1, 10, 0, 4, 0, time.Local),
+	}
+	got1 := manager.GetStatus()
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
@@ -0,0 +1,59 @@
+package gridon
+
+import (
+	"sync"
+)
+
+var (
+	riskSettingStoreSingleton    IRiskSettingStore
+	riskSettingStoreSingletonMtx sync.Mutex
+)
+
+// getRiskSettingStore - リスク管理設定ストアの取得
+func getRiskSettingStore(db IDB) IRiskSettingStore {
+	riskSettingStoreSingletonMtx.Lock()
+	defer riskSettingStoreSingletonMtx.Unlock()
+
+	if riskSettingStoreSingleton == nil {
+		riskSettingStoreSingleton = &riskSettingStore{
+			db: db,
+		}
+	}
+
+	return riskSettingStoreSingleton
+}
+
+// IRiskSettingStore - リスク管理設定ストアのインターフェース
+type IRiskSettingStore interface {
+	Get() (*RiskSetting, error)
+	Save(riskSetting *RiskSetting) error
+}
+
+// riskSettingStore - リスク管理設定ストア
+// 起動時に読み込むのと、設定やキルスイッチが変わったときに保存するだけなので、メモリには持たない
+type riskSettingStore struct {
+	db  IDB
+	mtx sync.Mutex
+}
+
+// Get - リスク管理の設定と状態の取得
+// 保存されていなければErrNoData
+func (s *riskSettingStore) Get() (*RiskSetting, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.db.GetRiskSetting()
+}
+
+// Save - リスク管理の設定と状態の保存
+// キルスイッチが入ったまま再起動しても切れないように、同期的にDBに保存する
+func (s *riskSettingStore) Save(riskSetting *RiskSetting) error {
+	if riskSetting == nil {
+		return ErrNilArgument
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.db.SaveRiskSetting(riskSetting)
+}
@@ -0,0 +1,97 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testRiskSettingStore struct {
+	IRiskSettingStore
+	Get1        *RiskSetting
+	Get2        error
+	Save1       error
+	SaveHistory []interface{}
+}
+
+func (t *testRiskSettingStore) Get() (*RiskSetting, error) { return t.Get1, t.Get2 }
+func (t *testRiskSettingStore) Save(riskSetting *RiskSetting) error {
+	t.SaveHistory = append(t.SaveHistory, riskSetting)
+	return t.Save1
+}
+
+func Test_getRiskSettingStore(t *testing.T) {
+	t.Parallel()
+
+	db := &testDB{}
+	want1 := &riskSettingStore{db: db}
+	got1 := getRiskSettingStore(db)
+
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_riskSettingStore_Get(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		db    *testDB
+		want1 *RiskSetting
+		want2 error
+	}{
+		{name: "DBになければErrNoData", db: &testDB{GetRiskSetting2: ErrNoData}, want1: nil, want2: ErrNoData},
+		{name: "DBにあれば返す",
+			db:    &testDB{GetRiskSetting1: &RiskSetting{Limit: RiskLimit{MaxDailyLoss: 30_000}, KillSwitch: true, KillReason: "manual", KilledDateTime: time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)}},
+			want1: &RiskSetting{Limit: RiskLimit{MaxDailyLoss: 30_000}, KillSwitch: true, KillReason: "manual", KilledDateTime: time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)},
+			want2: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &riskSettingStore{db: test.db}
+			got1, got2 := store.Get()
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_riskSettingStore_Save(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                       string
+		db                         *testDB
+		arg                        *RiskSetting
+		want                       error
+		wantSaveRiskSettingHistory []interface{}
+	}{
+		{name: "nilならエラー", db: &testDB{}, arg: nil, want: ErrNilArgument},
+		{name: "DBの保存に失敗したらエラー",
+			db:                         &testDB{SaveRiskSetting1: ErrUnknown},
+			arg:                        &RiskSetting{KillSwitch: true},
+			want:                       ErrUnknown,
+			wantSaveRiskSettingHistory: []interface{}{&RiskSetting{KillSwitch: true}}},
+		{name: "DBに保存できたらnil",
+			db:                         &testDB{},
+			arg:                        &RiskSetting{KillSwitch: true},
+			want:                       nil,
+			wantSaveRiskSettingHistory: []interface{}{&RiskSetting{KillSwitch: true}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &riskSettingStore{db: test.db}
+			got := store.Save(test.arg)
+			if !errors.Is(got, test.want) || !reflect.DeepEqual(test.wantSaveRiskSettingHistory, test.db.SaveRiskSettingHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want, test.wantSaveRiskSettingHistory, got, test.db.SaveRiskSettingHistory)
+			}
+		})
+	}
+}
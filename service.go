@@ -32,7 +32,34 @@ func NewService() (IService, error) {
 	orderStore := getOrderStore(db)
 	positionStore := getPositionStore(db)
 	fourPriceStore := getFourPriceStore(db)
-	kabusAPI := newKabusAPI(kabucom)
+	tradeStore := getTradeStore(db)
+	feeStore := getFeeStore(db)
+	metricsService := newMetricsService(
+		newClock(),
+		positionStore,
+		orderStore,
+		fourPriceStore,
+		getEquitySnapshotStore(db),
+		tradeStore)
+	rateLimiter := newRateLimiter()
+	kabusAPI := newPaperKabusAPI(newKabusAPI(kabucom, rateLimiter, RetryConfig{
+		MaxRetries:      3,
+		InitialInterval: 200 * time.Millisecond,
+		MaxInterval:     1 * time.Second,
+		Multiplier:      2,
+		Timeout:         3 * time.Second,
+	}), newClock())
+	reconciliationService := newReconciliationService(newClock(), kabusAPI, positionStore)
+	riskManager := newRiskManager(newClock(), kabusAPI, strategyStore, orderStore, positionStore, tradeStore, getRiskSettingStore(db), logger)
+	if err := riskManager.Restore(); err != nil {
+		return nil, err
+	}
+	circuitBreaker := newCircuitBreaker(newClock(), newLogNotifier(logger), CircuitBreakerConfig{
+		Threshold: 5,
+		CoolDown:  5 * time.Minute,
+	})
+	priceBandService := newPriceBandService(newClock(), strategyStore, fourPriceStore, logger)
+	trendFilterService := newTrendFilterService(newClock(), strategyStore, fourPriceStore, logger)
 
 	return &service{
 		logger:        logger,
@@ -45,7 +72,10 @@ func NewService() (IService, error) {
 			strategyStore,
 			orderStore,
 			positionStore,
-			newClock()),
+			tradeStore,
+			feeStore,
+			newClock(),
+			priceBandService),
 		rebalanceService: newRebalanceService(
 			newClock(),
 			kabusAPI,
@@ -56,6 +86,7 @@ func NewService() (IService, error) {
 				strategyStore,
 				orderStore,
 				positionStore,
+				riskManager,
 				logger)),
 		gridService: newGridService(
 			newClock(),
@@ -67,15 +98,20 @@ func NewService() (IService, error) {
 				strategyStore,
 				orderStore,
 				positionStore,
+				riskManager,
 				logger),
 			strategyStore,
-			fourPriceStore),
+			fourPriceStore,
+			positionStore,
+			priceBandService,
+			trendFilterService),
 		orderService: newOrderService(
 			newClock(),
 			kabusAPI,
 			strategyStore,
 			orderStore,
 			positionStore,
+			riskManager,
 			logger),
 		strategyService: newStrategyService(
 			kabusAPI,
@@ -83,10 +119,40 @@ func NewService() (IService, error) {
 		webService: NewWebService(
 			":18083",
 			strategyStore,
-			kabusAPI),
+			kabusAPI,
+			tradeStore,
+			metricsService,
+			reconciliationService,
+			rateLimiter,
+			riskManager,
+			circuitBreaker),
 		priceService: newPriceService(
 			kabusAPI,
 			fourPriceStore),
+		metricsService:        metricsService,
+		reconciliationService: reconciliationService,
+		riskManager:           riskManager,
+		circuitBreaker:        circuitBreaker,
+		orphanOrderService: newOrphanOrderService(
+			newClock(),
+			kabusAPI,
+			orderStore,
+			positionStore,
+			logger),
+		riskExitService: newRiskExitService(
+			newClock(),
+			kabusAPI,
+			strategyStore,
+			positionStore,
+			newOrderService(
+				newClock(),
+				kabusAPI,
+				strategyStore,
+				orderStore,
+				positionStore,
+				riskManager,
+				logger),
+			logger),
 	}, nil
 }
 
@@ -97,22 +163,28 @@ type IService interface {
 
 // service - gridonサービス
 type service struct {
-	logger             ILogger
-	clock              IClock
-	strategyStore      IStrategyStore
-	orderStore         IOrderStore
-	positionStore      IPositionStore
-	contractService    IContractService
-	rebalanceService   IRebalanceService
-	gridService        IGridService
-	orderService       IOrderService
-	strategyService    IStrategyService
-	webService         IWebService
-	priceService       IPriceService
-	contractRunning    bool
-	contractRunningMtx sync.Mutex
-	orderRunning       bool
-	orderRunningMtx    sync.Mutex
+	logger                ILogger
+	clock                 IClock
+	strategyStore         IStrategyStore
+	orderStore            IOrderStore
+	positionStore         IPositionStore
+	contractService       IContractService
+	rebalanceService      IRebalanceService
+	gridService           IGridService
+	orderService          IOrderService
+	strategyService       IStrategyService
+	webService            IWebService
+	priceService          IPriceService
+	metricsService        IMetricsService
+	reconciliationService IReconciliationService
+	orphanOrderService    IOrphanOrderService
+	riskExitService       IRiskExitService
+	riskManager           IRiskManager
+	circuitBreaker        ICircuitBreaker
+	contractRunning       bool
+	contractRunningMtx    sync.Mutex
+	orderRunning          bool
+	orderRunningMtx       sync.Mutex
 }
 
 func (s *service) Start() error {
@@ -127,6 +199,12 @@ func (s *service) Start() error {
 		return err
 	}
 
+	// 再起動前の疑似注文板の注文を失効させ、送信中のまま止まった注文を確定させ、証券会社にだけある注文を処理し、証券会社のポジションと照合して不一致のある戦略を止めておく
+	s.expirePaperOrderTask()
+	s.settlePendingOrderTask()
+	s.resolveOrphanOrderTask()
+	s.reconcileTask()
+
 	// Webサーバ起動
 	go s.startWebServerTask()
 
@@ -139,7 +217,7 @@ func (s *service) Start() error {
 	// 注文に関するスケジューラの起動 (リバランス、グリッド、全エグジット)
 	go s.orderScheduler()
 
-	// 日次で実行するスケジューラの起動 (四本値の保存)
+	// 日次で実行するスケジューラの起動 (四本値の保存、ポジションの照合)
 	go s.dailyScheduler()
 
 	select {}
@@ -210,6 +288,11 @@ func (s *service) contractTask() {
 		return
 	}
 
+	// 当日の損益の評価 評価できなくても、前回の評価のままで約定確認は続ける
+	if err := s.riskManager.Evaluate(strategies); err != nil {
+		s.logger.Warning(fmt.Errorf("約定確認処理のリスク評価でエラーが発生しました: %w", err))
+	}
+
 	// 約定確認の実行
 	var wg sync.WaitGroup
 	for _, strategy := range strategies {
@@ -218,19 +301,63 @@ func (s *service) contractTask() {
 		go func() {
 			defer wg.Done()
 
+			if err := s.orderService.SettlePendingOrders(strategy); err != nil {
+				s.logger.Warning(fmt.Errorf("%s の送信中の注文の確定処理でエラーが発生しました: %w", strategy.Code, err))
+				return
+			}
+
 			if err := s.contractService.Confirm(strategy); err != nil {
 				s.logger.Warning(fmt.Errorf("%s の約定確認処理でエラーが発生しました: %w", strategy.Code, err))
+				s.circuitBreaker.RecordFailure(strategy.Code, CircuitOperationConfirm, err)
 				return
 			}
+			s.circuitBreaker.RecordSuccess(strategy.Code, CircuitOperationConfirm)
 
 			if err := s.contractService.ConfirmGridEnd(strategy); err != nil {
 				s.logger.Warning(fmt.Errorf("%s のグリッド終了時約定確認処理でエラーが発生しました: %w", strategy.Code, err))
 				return
 			}
 
+			// 損切り・利確の条件に達した戦略は一時停止するので、グリッド処理は何もしなくなる
+			if err := s.riskExitService.Check(strategy); err != nil {
+				s.logger.Warning(fmt.Errorf("%s の損切り・利確処理でエラーが発生しました: %w", strategy.Code, err))
+				return
+			}
+
+			// キルスイッチが入っていたら、注文を全て取り消してグリッドの注文を出さない
+			if s.riskManager.IsKilled() {
+				if err := s.orderService.ForceCancelAll(strategy); err != nil {
+					s.logger.Warning(fmt.Errorf("%s のキルスイッチによる全取消処理でエラーが発生しました: %w", strategy.Code, err))
+				}
+				return
+			}
+
+			// 約定を反映した後で、照合間隔が過ぎていればポジションを照合し直す
+			if s.reconciliationService.NeedsReconcile(strategy.Code) {
+				s.reconcile(strategy)
+			}
+
+			// ポジションの照合に失敗したか、未解決の不一致がある戦略は注文を出さない
+			if s.reconciliationService.IsBlocked(strategy.Code) {
+				return
+			}
+
+			// 失敗が続いてサーキットブレーカーが開いている戦略は、冷却期間が過ぎるまで注文を出さない
+			if s.circuitBreaker.IsOpen(strategy.Code) {
+				return
+			}
+
+			// 整地するタイミングでなければ注文を出さないので、成功としても数えない
+			if !s.gridService.IsRunnable(strategy) {
+				return
+			}
+
 			if err := s.gridService.Leveling(strategy); err != nil {
 				s.logger.Warning(fmt.Errorf("%s のグリッド処理でエラーが発生しました: %w", strategy.Code, err))
+				s.circuitBreaker.RecordFailure(strategy.Code, CircuitOperationLeveling, err)
+				return
 			}
+			s.circuitBreaker.RecordSuccess(strategy.Code, CircuitOperationLeveling)
 		}()
 	}
 	wg.Wait()
@@ -288,8 +415,12 @@ func (s *service) orderTask() {
 			defer wg.Done()
 
 			// rebalanceの実行
-			if err := s.rebalanceService.Rebalance(strategy); err != nil {
-				s.logger.Warning(fmt.Errorf("%s のリバランス処理でエラーが発生しました: %w", strategy.Code, err))
+			// キルスイッチが入っているか、サーキットブレーカーが開いているか、ポジションの照合で未解決の不一致があれば
+			// リバランスの注文は出さず、取消とエグジットだけを行なう
+			if !s.riskManager.IsKilled() && !s.circuitBreaker.IsOpen(strategy.Code) && !s.reconciliationService.IsBlocked(strategy.Code) {
+				if err := s.rebalanceService.Rebalance(strategy); err != nil {
+					s.logger.Warning(fmt.Errorf("%s のリバランス処理でエラーが発生しました: %w", strategy.Code, err))
+				}
 			}
 
 			if err := s.orderService.CancelAll(strategy); err != nil {
@@ -312,6 +443,7 @@ func (s *service) dailyScheduler() {
 	for {
 		<-time.After(s.clock.NextAfternoonClosingDuration(s.clock.Now()) + 1*time.Minute) // 後場引けの1分後に動き出すようにする
 		go s.dailyTask()
+		go s.reconcileTask()
 	}
 }
 
@@ -333,8 +465,94 @@ func (s *service) dailyTask() {
 
 			if err := s.priceService.SaveFourPrice(strategy.SymbolCode, strategy.Exchange); err != nil {
 				s.logger.Warning(fmt.Errorf("%s の四本値保存処理でエラーが発生しました: %w", strategy.Code, err))
+				return
+			}
+
+			// 保存した四本値の終値で評価額を記録する
+			if err := s.metricsService.SaveEquitySnapshot(strategy); err != nil {
+				s.logger.Warning(fmt.Errorf("%s の評価額記録処理でエラーが発生しました: %w", strategy.Code, err))
 			}
 		}()
 	}
 	wg.Wait()
 }
+
+// reconcileTask - ポジション照合のタスク
+// 起動時と引け後に全戦略を照合し、それ以外は約定確認の周期で照合間隔が過ぎた戦略を照合し直す
+func (s *service) reconcileTask() {
+	// 戦略一覧の取得
+	strategies, err := s.strategyStore.GetStrategies()
+	if err != nil {
+		s.logger.Warning(fmt.Errorf("ポジション照合処理の戦略一覧取得でエラーが発生しました: %w", err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, strategy := range strategies {
+		strategy := strategy
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.reconcile(strategy)
+		}()
+	}
+	wg.Wait()
+}
+
+// reconcile - 戦略のポジションを照合する
+func (s *service) reconcile(strategy *Strategy) {
+	report, err := s.reconciliationService.Reconcile(strategy, false)
+	if err != nil {
+		s.logger.Warning(fmt.Errorf("%s のポジション照合処理でエラーが発生したため、注文を止めます: %w", strategy.Code, err))
+		return
+	}
+	if report.IsBlocked() {
+		s.logger.Warning(fmt.Errorf("%s のポジション照合で不一致があったため、注文を止めます: %+v", strategy.Code, report.Mismatches))
+	}
+}
+
+// settlePendingOrderTask - 送信中の注文の確定処理のタスク
+func (s *service) settlePendingOrderTask() {
+	// 戦略一覧の取得
+	strategies, err := s.strategyStore.GetStrategies()
+	if err != nil {
+		s.logger.Warning(fmt.Errorf("送信中の注文の確定処理の戦略一覧取得でエラーが発生しました: %w", err))
+		return
+	}
+
+	for _, strategy := range strategies {
+		if err := s.orderService.SettlePendingOrders(strategy); err != nil {
+			s.logger.Warning(fmt.Errorf("%s の送信中の注文の確定処理でエラーが発生しました: %w", strategy.Code, err))
+		}
+	}
+}
+
+// expirePaperOrderTask - 再起動前の疑似注文板の注文を失効させるタスク
+func (s *service) expirePaperOrderTask() {
+	// 戦略一覧の取得
+	strategies, err := s.strategyStore.GetStrategies()
+	if err != nil {
+		s.logger.Warning(fmt.Errorf("疑似注文板の注文の失効処理の戦略一覧取得でエラーが発生しました: %w", err))
+		return
+	}
+
+	for _, strategy := range strategies {
+		if err := s.orderService.ExpirePaperOrders(strategy); err != nil {
+			s.logger.Warning(fmt.Errorf("%s の疑似注文板の注文の失効処理でエラーが発生しました: %w", strategy.Code, err))
+		}
+	}
+}
+
+// resolveOrphanOrderTask - 孤立注文の処理のタスク
+func (s *service) resolveOrphanOrderTask() {
+	// 戦略一覧の取得
+	strategies, err := s.strategyStore.GetStrategies()
+	if err != nil {
+		s.logger.Warning(fmt.Errorf("孤立注文処理の戦略一覧取得でエラーが発生しました: %w", err))
+		return
+	}
+
+	if err := s.orphanOrderService.Resolve(strategies); err != nil {
+		s.logger.Warning(fmt.Errorf("孤立注文処理でエラーが発生しました: %w", err))
+	}
+}
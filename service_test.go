@@ -126,19 +126,32 @@ func Test_service_contractTask(t *testing.T) {
 		name                    string
 		logger                  *testLogger
 		strategyStore           *testStrategyStore
+		orderService            *testOrderService
 		contractService         *testContractService
 		gridService             *testGridService
 		contractRunning         bool
+		blocked                 bool
+		needsReconcile          bool
+		reconcileErr            error
+		riskExitErr             error
+		evaluateErr             error
+		killed                  bool
+		circuitOpen             bool
 		wantWarningCount        int
 		wantConfirmCount        int
 		wantConfirmGridEndCount int
 		wantLevelingCount       int
+		wantForceCancelAllCount int
+		wantFailureHistory      []interface{}
+		wantSuccessCount        int
+		wantReconcileCount      int
 	}{
 		{name: "実行中なら何もせず終了",
 			logger:            &testLogger{},
 			strategyStore:     &testStrategyStore{},
+			orderService:      &testOrderService{},
 			contractService:   &testContractService{},
-			gridService:       &testGridService{},
+			gridService:       &testGridService{IsRunnable1: true},
 			contractRunning:   true,
 			wantWarningCount:  0,
 			wantConfirmCount:  0,
@@ -146,8 +159,9 @@ func Test_service_contractTask(t *testing.T) {
 		{name: "戦略一覧取得に失敗したらエラーを吐いて終了",
 			logger:            &testLogger{},
 			strategyStore:     &testStrategyStore{GetStrategies2: ErrUnknown},
+			orderService:      &testOrderService{},
 			contractService:   &testContractService{},
-			gridService:       &testGridService{},
+			gridService:       &testGridService{IsRunnable1: true},
 			contractRunning:   false,
 			wantWarningCount:  1,
 			wantConfirmCount:  0,
@@ -155,66 +169,219 @@ func Test_service_contractTask(t *testing.T) {
 		{name: "戦略がなければ何もせずに終了",
 			logger:            &testLogger{},
 			strategyStore:     &testStrategyStore{GetStrategies1: []*Strategy{}},
+			orderService:      &testOrderService{},
 			contractService:   &testContractService{},
-			gridService:       &testGridService{},
+			gridService:       &testGridService{IsRunnable1: true},
 			contractRunning:   false,
 			wantWarningCount:  0,
 			wantConfirmCount:  0,
 			wantLevelingCount: 0},
-		{name: "約定確認でエラーが発生したらエラーを吐いて終了",
+		{name: "送信中の注文の確定でエラーが発生したらエラーを吐いて終了",
 			logger:            &testLogger{},
 			strategyStore:     &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
-			contractService:   &testContractService{Confirm1: ErrUnknown},
-			gridService:       &testGridService{},
+			orderService:      &testOrderService{SettlePendingOrders1: ErrUnknown},
+			contractService:   &testContractService{},
+			gridService:       &testGridService{IsRunnable1: true},
 			contractRunning:   false,
 			wantWarningCount:  1,
-			wantConfirmCount:  1,
+			wantConfirmCount:  0,
 			wantLevelingCount: 0},
+		{name: "約定確認でエラーが発生したらエラーを吐いて終了",
+			logger:             &testLogger{},
+			strategyStore:      &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:       &testOrderService{},
+			contractService:    &testContractService{Confirm1: ErrUnknown},
+			gridService:        &testGridService{IsRunnable1: true},
+			contractRunning:    false,
+			wantWarningCount:   1,
+			wantConfirmCount:   1,
+			wantLevelingCount:  0,
+			wantFailureHistory: []interface{}{"strategy-code-001", CircuitOperationConfirm, ErrUnknown}},
 		{name: "グリッド終了時約定確認でエラーが発生したらエラーを吐いて終了",
 			logger:                  &testLogger{},
 			strategyStore:           &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:            &testOrderService{},
 			contractService:         &testContractService{ConfirmGridEnd1: ErrUnknown},
-			gridService:             &testGridService{},
+			gridService:             &testGridService{IsRunnable1: true},
 			contractRunning:         false,
 			wantWarningCount:        1,
 			wantConfirmCount:        1,
 			wantConfirmGridEndCount: 1,
-			wantLevelingCount:       0},
+			wantLevelingCount:       0,
+			wantSuccessCount:        1},
+		{name: "損切り・利確の判定でエラーが発生したらエラーを吐いて終了",
+			logger:                  &testLogger{},
+			strategyStore:           &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:            &testOrderService{},
+			contractService:         &testContractService{},
+			gridService:             &testGridService{IsRunnable1: true},
+			contractRunning:         false,
+			riskExitErr:             ErrUnknown,
+			wantWarningCount:        1,
+			wantConfirmCount:        1,
+			wantConfirmGridEndCount: 1,
+			wantLevelingCount:       0,
+			wantSuccessCount:        1},
 		{name: "グリッドの整地でエラーが発生したらエラーを吐いて終了",
 			logger:                  &testLogger{},
 			strategyStore:           &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:            &testOrderService{},
 			contractService:         &testContractService{},
-			gridService:             &testGridService{Leveling1: ErrUnknown},
+			gridService:             &testGridService{IsRunnable1: true, Leveling1: ErrUnknown},
 			contractRunning:         false,
 			wantWarningCount:        1,
 			wantConfirmCount:        1,
 			wantConfirmGridEndCount: 1,
-			wantLevelingCount:       1},
+			wantLevelingCount:       1,
+			wantFailureHistory:      []interface{}{"strategy-code-001", CircuitOperationLeveling, ErrUnknown},
+			wantSuccessCount:        1},
 		{name: "戦略の数だけ約定確認とグリッドの整地をする",
 			logger: &testLogger{},
 			strategyStore: &testStrategyStore{GetStrategies1: []*Strategy{
 				{Code: "strategy-code-001"},
 				{Code: "strategy-code-002"},
 				{Code: "strategy-code-003"}}},
+			orderService:            &testOrderService{},
 			contractService:         &testContractService{},
-			gridService:             &testGridService{},
+			gridService:             &testGridService{IsRunnable1: true},
 			contractRunning:         false,
 			wantWarningCount:        0,
 			wantConfirmCount:        3,
 			wantConfirmGridEndCount: 3,
-			wantLevelingCount:       3},
+			wantLevelingCount:       3,
+			wantSuccessCount:        6},
+		{name: "グリッドの整地をするタイミングでなければ整地をせず、整地の成功にも数えない",
+			logger:                  &testLogger{},
+			strategyStore:           &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:            &testOrderService{},
+			contractService:         &testContractService{},
+			gridService:             &testGridService{IsRunnable1: false},
+			contractRunning:         false,
+			wantWarningCount:        0,
+			wantConfirmCount:        1,
+			wantConfirmGridEndCount: 1,
+			wantLevelingCount:       0,
+			wantSuccessCount:        1},
+		{name: "ポジション照合で止められている戦略は約定確認だけしてグリッドの整地をしない",
+			logger:                  &testLogger{},
+			strategyStore:           &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:            &testOrderService{},
+			contractService:         &testContractService{},
+			gridService:             &testGridService{IsRunnable1: true},
+			contractRunning:         false,
+			blocked:                 true,
+			wantWarningCount:        0,
+			wantConfirmCount:        1,
+			wantConfirmGridEndCount: 1,
+			wantLevelingCount:       0,
+			wantSuccessCount:        1},
+		{name: "照合間隔が過ぎていればポジションを照合し直してから整地する",
+			logger:                  &testLogger{},
+			strategyStore:           &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:            &testOrderService{},
+			contractService:         &testContractService{},
+			gridService:             &testGridService{IsRunnable1: true},
+			contractRunning:         false,
+			needsReconcile:          true,
+			wantWarningCount:        0,
+			wantConfirmCount:        1,
+			wantConfirmGridEndCount: 1,
+			wantLevelingCount:       1,
+			wantSuccessCount:        2,
+			wantReconcileCount:      1},
+		{name: "照合し直しでエラーが発生したらエラーを吐いてグリッドの整地をしない",
+			logger:                  &testLogger{},
+			strategyStore:           &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:            &testOrderService{},
+			contractService:         &testContractService{},
+			gridService:             &testGridService{IsRunnable1: true},
+			contractRunning:         false,
+			needsReconcile:          true,
+			reconcileErr:            ErrUnknown,
+			blocked:                 true,
+			wantWarningCount:        1,
+			wantConfirmCount:        1,
+			wantConfirmGridEndCount: 1,
+			wantLevelingCount:       0,
+			wantSuccessCount:        1,
+			wantReconcileCount:      1},
+		{name: "リスク評価でエラーが発生したらエラーを吐いて約定確認とグリッドの整地を続ける",
+			logger:                  &testLogger{},
+			strategyStore:           &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:            &testOrderService{},
+			contractService:         &testContractService{},
+			gridService:             &testGridService{IsRunnable1: true},
+			contractRunning:         false,
+			evaluateErr:             ErrUnknown,
+			wantWarningCount:        1,
+			wantConfirmCount:        1,
+			wantConfirmGridEndCount: 1,
+			wantLevelingCount:       1,
+			wantSuccessCount:        2},
+		{name: "キルスイッチが入っていたら約定確認をして注文を全て取り消し、グリッドの整地をしない",
+			logger:                  &testLogger{},
+			strategyStore:           &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:            &testOrderService{},
+			contractService:         &testContractService{},
+			gridService:             &testGridService{IsRunnable1: true},
+			contractRunning:         false,
+			killed:                  true,
+			wantWarningCount:        0,
+			wantConfirmCount:        1,
+			wantConfirmGridEndCount: 1,
+			wantLevelingCount:       0,
+			wantForceCancelAllCount: 1,
+			wantSuccessCount:        1},
+		{name: "キルスイッチによる全取消でエラーが発生したらエラーを吐いて終了",
+			logger:                  &testLogger{},
+			strategyStore:           &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:            &testOrderService{ForceCancelAll1: ErrUnknown},
+			contractService:         &testContractService{},
+			gridService:             &testGridService{IsRunnable1: true},
+			contractRunning:         false,
+			killed:                  true,
+			wantWarningCount:        1,
+			wantConfirmCount:        1,
+			wantConfirmGridEndCount: 1,
+			wantLevelingCount:       0,
+			wantForceCancelAllCount: 1,
+			wantSuccessCount:        1},
+		{name: "サーキットブレーカーが開いている戦略は約定確認だけしてグリッドの整地をしない",
+			logger:                  &testLogger{},
+			strategyStore:           &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:            &testOrderService{},
+			contractService:         &testContractService{},
+			gridService:             &testGridService{IsRunnable1: true},
+			contractRunning:         false,
+			circuitOpen:             true,
+			wantWarningCount:        0,
+			wantConfirmCount:        1,
+			wantConfirmGridEndCount: 1,
+			wantLevelingCount:       0,
+			wantSuccessCount:        1},
 	}
 
 	for _, test := range tests {
 		test := test
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
+			circuitBreaker := &testCircuitBreaker{IsOpen1: test.circuitOpen}
+			reconciliationService := &testReconciliationService{
+				Reconcile1:      &ReconciliationReport{Mismatches: []PositionMismatch{}},
+				Reconcile2:      test.reconcileErr,
+				IsBlocked1:      test.blocked,
+				NeedsReconcile1: test.needsReconcile}
 			service := &service{
-				logger:          test.logger,
-				strategyStore:   test.strategyStore,
-				contractService: test.contractService,
-				gridService:     test.gridService,
-				contractRunning: test.contractRunning,
+				logger:                test.logger,
+				strategyStore:         test.strategyStore,
+				orderService:          test.orderService,
+				contractService:       test.contractService,
+				gridService:           test.gridService,
+				contractRunning:       test.contractRunning,
+				reconciliationService: reconciliationService,
+				riskExitService:       &testRiskExitService{Check1: test.riskExitErr},
+				riskManager:           &testRiskManager{Evaluate1: test.evaluateErr, IsKilled1: test.killed},
+				circuitBreaker:        circuitBreaker,
 			}
 			service.contractTask()
 
@@ -224,10 +391,14 @@ func Test_service_contractTask(t *testing.T) {
 			if !reflect.DeepEqual(test.wantWarningCount, test.logger.WarningCount) ||
 				!reflect.DeepEqual(test.wantConfirmCount, test.contractService.ConfirmCount) ||
 				!reflect.DeepEqual(test.wantConfirmGridEndCount, test.contractService.ConfirmGridEndCount) ||
-				!reflect.DeepEqual(test.wantLevelingCount, test.gridService.LevelingCount) {
-				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v\n", t.Name(),
-					test.wantWarningCount, test.wantConfirmCount, test.wantConfirmGridEndCount, test.wantLevelingCount,
-					test.logger.WarningCount, test.contractService.ConfirmCount, test.contractService.ConfirmGridEndCount, test.gridService.LevelingCount)
+				!reflect.DeepEqual(test.wantLevelingCount, test.gridService.LevelingCount) ||
+				!reflect.DeepEqual(test.wantForceCancelAllCount, test.orderService.ForceCancelAllCount) ||
+				!reflect.DeepEqual(test.wantFailureHistory, circuitBreaker.RecordFailureHistory) ||
+				!reflect.DeepEqual(test.wantSuccessCount, circuitBreaker.RecordSuccessCount) ||
+				!reflect.DeepEqual(test.wantReconcileCount, reconciliationService.ReconcileCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v, %+v, %+v, %+v\n", t.Name(),
+					test.wantWarningCount, test.wantConfirmCount, test.wantConfirmGridEndCount, test.wantLevelingCount, test.wantForceCancelAllCount, test.wantFailureHistory, test.wantSuccessCount, test.wantReconcileCount,
+					test.logger.WarningCount, test.contractService.ConfirmCount, test.contractService.ConfirmGridEndCount, test.gridService.LevelingCount, test.orderService.ForceCancelAllCount, circuitBreaker.RecordFailureHistory, circuitBreaker.RecordSuccessCount, reconciliationService.ReconcileCount)
 			}
 		})
 	}
@@ -242,6 +413,9 @@ func Test_service_orderTask(t *testing.T) {
 		rebalanceService   *testRebalanceService
 		orderService       *testOrderService
 		orderRunning       bool
+		blocked            bool
+		killed             bool
+		circuitOpen        bool
 		wantWarningCount   int
 		wantRebalanceCount int
 		wantCancelAllCount int
@@ -310,6 +484,39 @@ func Test_service_orderTask(t *testing.T) {
 			wantRebalanceCount: 3,
 			wantCancelAllCount: 3,
 			wantExitAllCount:   3},
+		{name: "ポジション照合で止められている戦略はリバランスせず、全取消と全エグジットだけを実行する",
+			logger:             &testLogger{},
+			strategyStore:      &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			rebalanceService:   &testRebalanceService{},
+			orderService:       &testOrderService{},
+			orderRunning:       false,
+			blocked:            true,
+			wantWarningCount:   0,
+			wantRebalanceCount: 0,
+			wantCancelAllCount: 1,
+			wantExitAllCount:   1},
+		{name: "キルスイッチが入っていたらリバランスせず、全取消と全エグジットだけを実行する",
+			logger:             &testLogger{},
+			strategyStore:      &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			rebalanceService:   &testRebalanceService{},
+			orderService:       &testOrderService{},
+			orderRunning:       false,
+			killed:             true,
+			wantWarningCount:   0,
+			wantRebalanceCount: 0,
+			wantCancelAllCount: 1,
+			wantExitAllCount:   1},
+		{name: "サーキットブレーカーが開いていたらリバランスせず、全取消と全エグジットだけを実行する",
+			logger:             &testLogger{},
+			strategyStore:      &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			rebalanceService:   &testRebalanceService{},
+			orderService:       &testOrderService{},
+			orderRunning:       false,
+			circuitOpen:        true,
+			wantWarningCount:   0,
+			wantRebalanceCount: 0,
+			wantCancelAllCount: 1,
+			wantExitAllCount:   1},
 	}
 
 	for _, test := range tests {
@@ -317,11 +524,14 @@ func Test_service_orderTask(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 			service := &service{
-				logger:           test.logger,
-				strategyStore:    test.strategyStore,
-				rebalanceService: test.rebalanceService,
-				orderService:     test.orderService,
-				orderRunning:     test.orderRunning,
+				logger:                test.logger,
+				strategyStore:         test.strategyStore,
+				rebalanceService:      test.rebalanceService,
+				orderService:          test.orderService,
+				orderRunning:          test.orderRunning,
+				reconciliationService: &testReconciliationService{IsBlocked1: test.blocked},
+				riskManager:           &testRiskManager{IsKilled1: test.killed},
+				circuitBreaker:        &testCircuitBreaker{IsOpen1: test.circuitOpen},
 			}
 			service.orderTask()
 
@@ -377,12 +587,17 @@ func Test_service_Start(t *testing.T) {
 			t.Parallel()
 			var got1 error
 			service := &service{
-				logger:        &testLogger{},
-				clock:         &testClock{},
-				strategyStore: test.strategyStore,
-				orderStore:    test.orderStore,
-				positionStore: test.positionStore,
-				webService:    &testWebService{},
+				logger:                &testLogger{},
+				clock:                 &testClock{},
+				strategyStore:         test.strategyStore,
+				orderStore:            test.orderStore,
+				positionStore:         test.positionStore,
+				webService:            &testWebService{},
+				reconciliationService: &testReconciliationService{},
+				orphanOrderService:    &testOrphanOrderService{},
+				orderService:          &testOrderService{},
+				riskManager:           &testRiskManager{},
+				circuitBreaker:        &testCircuitBreaker{},
 			}
 			go func() {
 				got1 = service.Start()
@@ -486,33 +701,47 @@ func Test_service_startWebServerTask(t *testing.T) {
 func Test_service_dailyTask(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
-		name                   string
-		strategyStore          *testStrategyStore
-		priceService           *testPriceService
-		logger                 *testLogger
-		wantSaveFourPriceCount int
-		wantWarningCount       int
+		name                        string
+		strategyStore               *testStrategyStore
+		priceService                *testPriceService
+		metricsService              *testMetricsService
+		logger                      *testLogger
+		wantSaveFourPriceCount      int
+		wantSaveEquitySnapshotCount int
+		wantWarningCount            int
 	}{
 		{name: "戦略一覧の取得に失敗したらログを吐いて終了",
 			strategyStore:    &testStrategyStore{GetStrategies2: ErrUnknown},
 			priceService:     &testPriceService{},
+			metricsService:   &testMetricsService{},
 			logger:           &testLogger{},
 			wantWarningCount: 1},
 		{name: "四本値の保存に失敗したらログを吐いて終了",
 			strategyStore:          &testStrategyStore{GetStrategies1: []*Strategy{{SymbolCode: "1475", Exchange: ExchangeToushou}}},
 			priceService:           &testPriceService{SaveFourPrice1: ErrUnknown},
+			metricsService:         &testMetricsService{},
 			logger:                 &testLogger{},
 			wantSaveFourPriceCount: 1,
 			wantWarningCount:       1},
-		{name: "四本値の保存でエラーがなければそのまま終了",
+		{name: "評価額の記録に失敗したらログを吐いて終了",
+			strategyStore:               &testStrategyStore{GetStrategies1: []*Strategy{{SymbolCode: "1475", Exchange: ExchangeToushou}}},
+			priceService:                &testPriceService{SaveFourPrice1: nil},
+			metricsService:              &testMetricsService{SaveEquitySnapshot1: ErrUnknown},
+			logger:                      &testLogger{},
+			wantSaveFourPriceCount:      1,
+			wantSaveEquitySnapshotCount: 1,
+			wantWarningCount:            1},
+		{name: "四本値の保存と評価額の記録でエラーがなければそのまま終了",
 			strategyStore: &testStrategyStore{GetStrategies1: []*Strategy{
 				{SymbolCode: "1475", Exchange: ExchangeToushou},
 				{SymbolCode: "1476", Exchange: ExchangeToushou},
 			}},
-			priceService:           &testPriceService{SaveFourPrice1: nil},
-			logger:                 &testLogger{},
-			wantSaveFourPriceCount: 2,
-			wantWarningCount:       0},
+			priceService:                &testPriceService{SaveFourPrice1: nil},
+			metricsService:              &testMetricsService{},
+			logger:                      &testLogger{},
+			wantSaveFourPriceCount:      2,
+			wantSaveEquitySnapshotCount: 2,
+			wantWarningCount:            0},
 	}
 
 	for _, test := range tests {
@@ -520,15 +749,210 @@ func Test_service_dailyTask(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 			service := &service{
-				logger:        test.logger,
-				strategyStore: test.strategyStore,
-				priceService:  test.priceService}
+				logger:         test.logger,
+				strategyStore:  test.strategyStore,
+				priceService:   test.priceService,
+				metricsService: test.metricsService}
 			service.dailyTask()
 			if !reflect.DeepEqual(test.wantSaveFourPriceCount, test.priceService.SaveFourPriceCount) ||
+				!reflect.DeepEqual(test.wantSaveEquitySnapshotCount, test.metricsService.SaveEquitySnapshotCount) ||
+				!reflect.DeepEqual(test.wantWarningCount, test.logger.WarningCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					test.wantSaveFourPriceCount, test.wantSaveEquitySnapshotCount, test.wantWarningCount,
+					test.priceService.SaveFourPriceCount, test.metricsService.SaveEquitySnapshotCount, test.logger.WarningCount)
+			}
+		})
+	}
+}
+
+func Test_service_reconcileTask(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                  string
+		strategyStore         *testStrategyStore
+		reconciliationService *testReconciliationService
+		logger                *testLogger
+		wantReconcileCount    int
+		wantWarningCount      int
+	}{
+		{name: "戦略一覧の取得に失敗したらログを吐いて終了",
+			strategyStore:         &testStrategyStore{GetStrategies2: ErrUnknown},
+			reconciliationService: &testReconciliationService{},
+			logger:                &testLogger{},
+			wantWarningCount:      1},
+		{name: "照合に失敗したらログを吐いて終了",
+			strategyStore:         &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			reconciliationService: &testReconciliationService{Reconcile2: ErrUnknown},
+			logger:                &testLogger{},
+			wantReconcileCount:    1,
+			wantWarningCount:      1},
+		{name: "未解決の不一致があればログを吐く",
+			strategyStore: &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			reconciliationService: &testReconciliationService{Reconcile1: &ReconciliationReport{
+				StrategyCode: "strategy-code-001",
+				Mismatches:   []PositionMismatch{{PositionCode: "position-code-001", MismatchType: PositionMismatchTypeMissing}}}},
+			logger:             &testLogger{},
+			wantReconcileCount: 1,
+			wantWarningCount:   1},
+		{name: "不一致がなければ戦略の数だけ照合して終了",
+			strategyStore: &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}, {Code: "strategy-code-002"}}},
+			reconciliationService: &testReconciliationService{Reconcile1: &ReconciliationReport{
+				StrategyCode: "strategy-code-001",
+				Mismatches:   []PositionMismatch{}}},
+			logger:             &testLogger{},
+			wantReconcileCount: 2,
+			wantWarningCount:   0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			service := &service{
+				logger:                test.logger,
+				strategyStore:         test.strategyStore,
+				reconciliationService: test.reconciliationService}
+			service.reconcileTask()
+			if !reflect.DeepEqual(test.wantReconcileCount, test.reconciliationService.ReconcileCount) ||
 				!reflect.DeepEqual(test.wantWarningCount, test.logger.WarningCount) {
 				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(),
-					test.wantSaveFourPriceCount, test.wantWarningCount,
-					test.wantWarningCount, test.logger.WarningCount)
+					test.wantReconcileCount, test.wantWarningCount,
+					test.reconciliationService.ReconcileCount, test.logger.WarningCount)
+			}
+		})
+	}
+}
+
+func Test_service_expirePaperOrderTask(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                       string
+		strategyStore              *testStrategyStore
+		orderService               *testOrderService
+		wantExpirePaperOrdersCount int
+		wantWarningCount           int
+	}{
+		{name: "戦略一覧の取得に失敗したらログを吐いて終了",
+			strategyStore:    &testStrategyStore{GetStrategies2: ErrUnknown},
+			orderService:     &testOrderService{},
+			wantWarningCount: 1},
+		{name: "疑似注文板の注文の失効に失敗したらログを吐いて次の戦略に進む",
+			strategyStore:              &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}, {Code: "strategy-code-002"}}},
+			orderService:               &testOrderService{ExpirePaperOrders1: ErrUnknown},
+			wantExpirePaperOrdersCount: 2,
+			wantWarningCount:           2},
+		{name: "疑似注文板の注文の失効に成功すればログを吐かずに終了",
+			strategyStore:              &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:               &testOrderService{},
+			wantExpirePaperOrdersCount: 1,
+			wantWarningCount:           0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			logger := &testLogger{}
+			service := &service{
+				logger:        logger,
+				strategyStore: test.strategyStore,
+				orderService:  test.orderService}
+			service.expirePaperOrderTask()
+			if !reflect.DeepEqual(test.wantExpirePaperOrdersCount, test.orderService.ExpirePaperOrdersCount) ||
+				!reflect.DeepEqual(test.wantWarningCount, logger.WarningCount) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(),
+					test.wantExpirePaperOrdersCount, test.wantWarningCount,
+					test.orderService.ExpirePaperOrdersCount, logger.WarningCount)
+			}
+		})
+	}
+}
+
+func Test_service_settlePendingOrderTask(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                         string
+		strategyStore                *testStrategyStore
+		orderService                 *testOrderService
+		wantSettlePendingOrdersCount int
+		wantWarningCount             int
+	}{
+		{name: "戦略一覧の取得に失敗したらログを吐いて終了",
+			strategyStore:    &testStrategyStore{GetStrategies2: ErrUnknown},
+			orderService:     &testOrderService{},
+			wantWarningCount: 1},
+		{name: "送信中の注文の確定に失敗したらログを吐いて次の戦略に進む",
+			strategyStore:                &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}, {Code: "strategy-code-002"}}},
+			orderService:                 &testOrderService{SettlePendingOrders1: ErrUnknown},
+			wantSettlePendingOrdersCount: 2,
+			wantWarningCount:             2},
+		{name: "送信中の注文の確定に成功すればログを吐かずに終了",
+			strategyStore:                &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orderService:                 &testOrderService{},
+			wantSettlePendingOrdersCount: 1,
+			wantWarningCount:             0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			logger := &testLogger{}
+			service := &service{
+				logger:        logger,
+				strategyStore: test.strategyStore,
+				orderService:  test.orderService}
+			service.settlePendingOrderTask()
+			if !reflect.DeepEqual(test.wantSettlePendingOrdersCount, test.orderService.SettlePendingOrdersCount) ||
+				!reflect.DeepEqual(test.wantWarningCount, logger.WarningCount) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(),
+					test.wantSettlePendingOrdersCount, test.wantWarningCount,
+					test.orderService.SettlePendingOrdersCount, logger.WarningCount)
+			}
+		})
+	}
+}
+
+func Test_service_resolveOrphanOrderTask(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name               string
+		strategyStore      *testStrategyStore
+		orphanOrderService *testOrphanOrderService
+		wantResolveCount   int
+		wantWarningCount   int
+	}{
+		{name: "戦略一覧の取得に失敗したらログを吐いて終了",
+			strategyStore:      &testStrategyStore{GetStrategies2: ErrUnknown},
+			orphanOrderService: &testOrphanOrderService{},
+			wantWarningCount:   1},
+		{name: "孤立注文の処理に失敗したらログを吐いて終了",
+			strategyStore:      &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orphanOrderService: &testOrphanOrderService{Resolve1: ErrUnknown},
+			wantResolveCount:   1,
+			wantWarningCount:   1},
+		{name: "孤立注文の処理に成功すればログを吐かずに終了",
+			strategyStore:      &testStrategyStore{GetStrategies1: []*Strategy{{Code: "strategy-code-001"}}},
+			orphanOrderService: &testOrphanOrderService{},
+			wantResolveCount:   1,
+			wantWarningCount:   0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			logger := &testLogger{}
+			service := &service{
+				logger:             logger,
+				strategyStore:      test.strategyStore,
+				orphanOrderService: test.orphanOrderService}
+			service.resolveOrphanOrderTask()
+			if !reflect.DeepEqual(test.wantResolveCount, test.orphanOrderService.ResolveCount) ||
+				!reflect.DeepEqual(test.wantWarningCount, logger.WarningCount) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(),
+					test.wantResolveCount, test.wantWarningCount,
+					test.orphanOrderService.ResolveCount, logger.WarningCount)
 			}
 		})
 	}
@@ -39,6 +39,9 @@ type IStrategyStore interface {
 	SetMaxContractPrice(strategyCode string, contractPrice float64, contractDateTime time.Time) error
 	SetMinContractPrice(strategyCode string, contractPrice float64, contractDateTime time.Time) error
 	SetSymbolInfo(strategyCode string, tickGroup TickGroup, tradingUnit float64) error
+	Pause(strategyCode string, reason PauseReason, pausedDateTime time.Time) error
+	SetPriceBandState(strategyCode string, state PriceBandState) error
+	SetTrendFilterState(strategyCode string, state TrendFilterState) error
 	Save(strategy *Strategy) error
 	DeleteByCode(code string) error
 }
@@ -131,16 +134,18 @@ func (s *strategyStore) SetBasePrice(strategyCode string, basePrice float64, bas
 }
 
 // SetContractPrice - 最終約定情報をセットする
-// 約定値は基準価格にもなるので、更新時に基準価格も更新する
+// 最終約定価格を基準価格にする戦略なら、約定値は基準価格にもなるので、更新時に基準価格も更新する
 func (s *strategyStore) SetContractPrice(strategyCode string, contractPrice float64, contractDateTime time.Time) error {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	if _, ok := s.store[strategyCode]; ok {
-		s.store[strategyCode].LastContractPrice = contractPrice
-		s.store[strategyCode].LastContractDateTime = contractDateTime
-		s.store[strategyCode].BasePrice = contractPrice
-		s.store[strategyCode].BasePriceDateTime = contractDateTime
+	if strategy, ok := s.store[strategyCode]; ok {
+		strategy.LastContractPrice = contractPrice
+		strategy.LastContractDateTime = contractDateTime
+		if strategy.GridStrategy.BasePriceSource.IsLastContract() {
+			strategy.BasePrice = contractPrice
+			strategy.BasePriceDateTime = contractDateTime
+		}
 
 		go s.db.SaveStrategy(s.store[strategyCode])
 	}
@@ -193,6 +198,50 @@ func (s *strategyStore) SetSymbolInfo(strategyCode string, tickGroup TickGroup,
 	return nil
 }
 
+// Pause - 戦略を一時停止する
+// 一時停止を解除するには、一時停止の理由を空にした戦略を保存しなおす
+func (s *strategyStore) Pause(strategyCode string, reason PauseReason, pausedDateTime time.Time) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.store[strategyCode]; ok {
+		s.store[strategyCode].PauseReason = reason
+		s.store[strategyCode].PausedDateTime = pausedDateTime
+
+		go s.db.SaveStrategy(s.store[strategyCode])
+	}
+
+	return nil
+}
+
+// SetPriceBandState - 価格帯の判定結果をセットする
+func (s *strategyStore) SetPriceBandState(strategyCode string, state PriceBandState) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.store[strategyCode]; ok {
+		s.store[strategyCode].PriceBandState = state
+
+		go s.db.SaveStrategy(s.store[strategyCode])
+	}
+
+	return nil
+}
+
+// SetTrendFilterState - トレンドフィルタの判定結果をセットする
+func (s *strategyStore) SetTrendFilterState(strategyCode string, state TrendFilterState) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.store[strategyCode]; ok {
+		s.store[strategyCode].TrendFilterState = state
+
+		go s.db.SaveStrategy(s.store[strategyCode])
+	}
+
+	return nil
+}
+
 // Save - 戦略の保存
 func (s *strategyStore) Save(strategy *Strategy) error {
 	if strategy == nil {
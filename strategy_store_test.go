@@ -27,6 +27,15 @@ type testStrategyStore struct {
 	SetSymbolInfo1             error
 	SetSymbolInfoCount         int
 	SetSymbolInfoHistory       []interface{}
+	Pause1                     error
+	PauseHistory               []interface{}
+	PauseCount                 int
+	SetPriceBandState1         error
+	SetPriceBandStateHistory   []interface{}
+	SetPriceBandStateCount     int
+	SetTrendFilterState1       error
+	SetTrendFilterStateHistory []interface{}
+	SetTrendFilterStateCount   int
 	SetContractPrice1          error
 	SetContractPriceHistory    []interface{}
 	SetContractPriceCount      int
@@ -98,6 +107,25 @@ func (t *testStrategyStore) SetSymbolInfo(strategyCode string, tickGroup TickGro
 	t.SetSymbolInfoCount++
 	return t.SetSymbolInfo1
 }
+func (t *testStrategyStore) Pause(strategyCode string, reason PauseReason, pausedDateTime time.Time) error {
+	t.PauseHistory = append(t.PauseHistory, strategyCode)
+	t.PauseHistory = append(t.PauseHistory, reason)
+	t.PauseHistory = append(t.PauseHistory, pausedDateTime)
+	t.PauseCount++
+	return t.Pause1
+}
+func (t *testStrategyStore) SetPriceBandState(strategyCode string, state PriceBandState) error {
+	t.SetPriceBandStateHistory = append(t.SetPriceBandStateHistory, strategyCode)
+	t.SetPriceBandStateHistory = append(t.SetPriceBandStateHistory, state)
+	t.SetPriceBandStateCount++
+	return t.SetPriceBandState1
+}
+func (t *testStrategyStore) SetTrendFilterState(strategyCode string, state TrendFilterState) error {
+	t.SetTrendFilterStateHistory = append(t.SetTrendFilterStateHistory, strategyCode)
+	t.SetTrendFilterStateHistory = append(t.SetTrendFilterStateHistory, state)
+	t.SetTrendFilterStateCount++
+	return t.SetTrendFilterState1
+}
 func (t *testStrategyStore) Save(strategy *Strategy) error {
 	t.SaveHistory = append(t.SaveHistory, strategy)
 	t.SaveCount++
@@ -572,6 +600,18 @@ func Test_strategyStore_SetContractPrice(t *testing.T) {
 				"strategy-code-002": {Code: "strategy-code-002", BasePrice: 10_000, BasePriceDateTime: time.Date(2021, 10, 26, 10, 0, 0, 0, time.Local), LastContractPrice: 10_000, LastContractDateTime: time.Date(2021, 10, 26, 10, 0, 0, 0, time.Local)},
 				"strategy-code-003": {Code: "strategy-code-003"}},
 			wantStrategySaveCount: 1},
+		{name: "最終約定価格以外を基準価格にする戦略なら、基準価格は更新しない",
+			db: &testDB{},
+			store: map[string]*Strategy{
+				"strategy-code-001": {Code: "strategy-code-001", BasePrice: 9_900, BasePriceDateTime: time.Date(2021, 10, 26, 9, 0, 0, 0, time.Local), GridStrategy: GridStrategy{BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeVWAP}}},
+			},
+			arg1:  "strategy-code-001",
+			arg2:  10_000,
+			arg3:  time.Date(2021, 10, 26, 10, 0, 0, 0, time.Local),
+			want1: nil,
+			wantStore: map[string]*Strategy{
+				"strategy-code-001": {Code: "strategy-code-001", BasePrice: 9_900, BasePriceDateTime: time.Date(2021, 10, 26, 9, 0, 0, 0, time.Local), LastContractPrice: 10_000, LastContractDateTime: time.Date(2021, 10, 26, 10, 0, 0, 0, time.Local), GridStrategy: GridStrategy{BasePriceSource: BasePriceSource{Type: BasePriceSourceTypeVWAP}}}},
+			wantStrategySaveCount: 1},
 	}
 
 	for _, test := range tests {
@@ -783,3 +823,126 @@ func Test_strategyStore_DeleteByCode(t *testing.T) {
 		})
 	}
 }
+
+func Test_strategyStore_Pause(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                  string
+		db                    *testDB
+		store                 map[string]*Strategy
+		arg1                  string
+		arg2                  PauseReason
+		arg3                  time.Time
+		want1                 error
+		wantStore             map[string]*Strategy
+		wantStrategySaveCount int
+	}{
+		{name: "該当する戦略がなければ変更なし",
+			db: &testDB{},
+			store: map[string]*Strategy{
+				"strategy-code-001": {Code: "strategy-code-001"},
+				"strategy-code-002": {Code: "strategy-code-002"},
+			},
+			arg1:  "",
+			arg2:  PauseReasonMaxLoss,
+			arg3:  time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+			want1: nil,
+			wantStore: map[string]*Strategy{
+				"strategy-code-001": {Code: "strategy-code-001"},
+				"strategy-code-002": {Code: "strategy-code-002"}},
+			wantStrategySaveCount: 0},
+		{name: "該当する戦略があれば一時停止の理由と日時を更新する",
+			db: &testDB{},
+			store: map[string]*Strategy{
+				"strategy-code-001": {Code: "strategy-code-001"},
+				"strategy-code-002": {Code: "strategy-code-002"},
+			},
+			arg1:  "strategy-code-002",
+			arg2:  PauseReasonMaxLoss,
+			arg3:  time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local),
+			want1: nil,
+			wantStore: map[string]*Strategy{
+				"strategy-code-001": {Code: "strategy-code-001"},
+				"strategy-code-002": {Code: "strategy-code-002", PauseReason: PauseReasonMaxLoss, PausedDateTime: time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local)}},
+			wantStrategySaveCount: 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &strategyStore{store: test.store, db: test.db}
+			got1 := store.Pause(test.arg1, test.arg2, test.arg3)
+
+			time.Sleep(100 * time.Millisecond) // 非同期処理が実行されることの確認のため少し待機
+
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantStore, store.store) || !reflect.DeepEqual(test.wantStrategySaveCount, test.db.SaveStrategyCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantStore, test.wantStrategySaveCount,
+					got1, store.store, test.db.SaveStrategyCount)
+			}
+		})
+	}
+}
+
+func Test_strategyStore_SetTrendFilterState(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                  string
+		db                    *testDB
+		store                 map[string]*Strategy
+		arg1                  string
+		arg2                  TrendFilterState
+		want1                 error
+		wantStore             map[string]*Strategy
+		wantStrategySaveCount int
+	}{
+		{name: "該当する戦略がなければ変更なし",
+			db: &testDB{},
+			store: map[string]*Strategy{
+				"strategy-code-001": {Code: "strategy-code-001"},
+				"strategy-code-002": {Code: "strategy-code-002"},
+				"strategy-code-003": {Code: "strategy-code-003"},
+			},
+			arg1:  "",
+			arg2:  TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: time.Date(2021, 10, 26, 10, 0, 0, 0, time.Local)},
+			want1: nil,
+			wantStore: map[string]*Strategy{
+				"strategy-code-001": {Code: "strategy-code-001"},
+				"strategy-code-002": {Code: "strategy-code-002"},
+				"strategy-code-003": {Code: "strategy-code-003"}},
+			wantStrategySaveCount: 0},
+		{name: "該当する戦略があれば更新する",
+			db: &testDB{},
+			store: map[string]*Strategy{
+				"strategy-code-001": {Code: "strategy-code-001"},
+				"strategy-code-002": {Code: "strategy-code-002"},
+				"strategy-code-003": {Code: "strategy-code-003"},
+			},
+			arg1:  "strategy-code-002",
+			arg2:  TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: time.Date(2021, 10, 26, 10, 0, 0, 0, time.Local)},
+			want1: nil,
+			wantStore: map[string]*Strategy{
+				"strategy-code-001": {Code: "strategy-code-001"},
+				"strategy-code-002": {Code: "strategy-code-002", TrendFilterState: TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: time.Date(2021, 10, 26, 10, 0, 0, 0, time.Local)}},
+				"strategy-code-003": {Code: "strategy-code-003"}},
+			wantStrategySaveCount: 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &strategyStore{store: test.store, db: test.db}
+			got1 := store.SetTrendFilterState(test.arg1, test.arg2)
+
+			time.Sleep(100 * time.Millisecond) // 非同期処理が実行されることの確認のため少し待機
+
+			if !errors.Is(got1, test.want1) || !reflect.DeepEqual(test.wantStore, store.store) || !reflect.DeepEqual(test.wantStrategySaveCount, test.db.SaveStrategyCount) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v\ngot: %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.wantStore, test.wantStrategySaveCount,
+					got1, store.store, test.db.SaveStrategyCount)
+			}
+		})
+	}
+}
@@ -45,6 +45,7 @@ type ITick interface {
 	GetTick(tickGroup TickGroup, price float64) float64
 	TickAddedPrice(tickGroup TickGroup, price float64, tick int) float64
 	Ticks(tickGroup TickGroup, a float64, b float64) int
+	RoundedPrice(tickGroup TickGroup, price float64, rounding Rounding) float64
 }
 
 // tick - ティック計算
@@ -165,3 +166,15 @@ func (t *tick) Ticks(tickGroup TickGroup, a float64, b float64) int {
 
 	return tick
 }
+
+// RoundedPrice - 呼値単位に端数処理した価格
+// 端数処理が未指定なら四捨五入する
+func (t *tick) RoundedPrice(tickGroup TickGroup, price float64, rounding Rounding) float64 {
+	if rounding == RoundingUnspecified {
+		rounding = RoundingRound
+	}
+
+	unit := t.GetTick(tickGroup, price)
+	n := math.Round(price/unit*1_000_000) / 1_000_000 // 浮動小数点の誤差で切り捨て・切り上げがずれないようにする
+	return math.Round(rounding.Calc(n)*unit*10) / 10  // 小数点以下第一で四捨五入
+}
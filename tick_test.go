@@ -126,6 +126,39 @@ func Test_tick_TickAddedPrice(t *testing.T) {
 	}
 }
 
+func Test_tick_RoundedPrice(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg1 TickGroup
+		arg2 float64
+		arg3 Rounding
+		want float64
+	}{
+		{name: "呼値単位ちょうどならそのまま", arg1: TickGroupOther, arg2: 2100, arg3: RoundingRound, want: 2100},
+		{name: "四捨五入なら近い方の呼値に丸める", arg1: TickGroupOther, arg2: 2100.6, arg3: RoundingRound, want: 2101},
+		{name: "切り捨てなら下の呼値に丸める", arg1: TickGroupOther, arg2: 2100.6, arg3: RoundingFloor, want: 2100},
+		{name: "切り上げなら上の呼値に丸める", arg1: TickGroupOther, arg2: 2100.4, arg3: RoundingCeil, want: 2101},
+		{name: "端数処理が未指定なら四捨五入する", arg1: TickGroupOther, arg2: 3013, arg3: RoundingUnspecified, want: 3015},
+		{name: "価格帯ごとの呼値単位で丸める", arg1: TickGroupOther, arg2: 3012, arg3: RoundingFloor, want: 3010},
+		{name: "TOPIX100テーブルで0.1円単位に丸める", arg1: TickGroupTopix100, arg2: 250.56, arg3: RoundingRound, want: 250.6},
+		{name: "TOPIX100テーブルで浮動小数点の誤差があっても呼値ちょうどなら切り捨てない", arg1: TickGroupTopix100, arg2: 250.3, arg3: RoundingFloor, want: 250.3},
+		{name: "TOPIX100テーブルで浮動小数点の誤差があっても呼値ちょうどなら切り上げない", arg1: TickGroupTopix100, arg2: 250.3, arg3: RoundingCeil, want: 250.3},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			tick := &tick{}
+			got := tick.RoundedPrice(test.arg1, test.arg2, test.arg3)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
 func Test_newTick(t *testing.T) {
 	t.Parallel()
 	want1 := &tick{}
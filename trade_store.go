@@ -0,0 +1,175 @@
+package gridon
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	tradeStoreSingleton    ITradeStore
+	tradeStoreSingletonMtx sync.Mutex
+)
+
+// getTradeStore - 取引ストアの取得
+func getTradeStore(db IDB) ITradeStore {
+	tradeStoreSingletonMtx.Lock()
+	defer tradeStoreSingletonMtx.Unlock()
+
+	if tradeStoreSingleton == nil {
+		tradeStoreSingleton = &tradeStore{
+			db: db,
+		}
+	}
+
+	return tradeStoreSingleton
+}
+
+// ITradeStore - 取引ストアのインターフェース
+type ITradeStore interface {
+	Save(trade *Trade) error
+	GetByStrategyCode(strategyCode string, from time.Time, to time.Time) ([]*Trade, error)
+	GetStrategySummaries(from time.Time, to time.Time) ([]*TradeSummary, error)
+	GetDailySummaries(strategyCode string, from time.Time, to time.Time) ([]*TradeSummary, error)
+}
+
+// tradeStore - 取引ストア
+// 取引は増え続けるのでメモリには持たず、集計のたびにDBから読み込む
+type tradeStore struct {
+	db  IDB
+	mtx sync.Mutex
+}
+
+// Save - 取引の保存
+// メモリに持たないため、保存直後の集計にも含まれるよう同期的にDBに保存する
+func (s *tradeStore) Save(trade *Trade) error {
+	if trade == nil {
+		return ErrNilArgument
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.db.SaveTrade(trade)
+}
+
+// GetByStrategyCode - 戦略を指定して、エグジット約定日時がfrom以降to未満の取引を取り出す
+func (s *tradeStore) GetByStrategyCode(strategyCode string, from time.Time, to time.Time) ([]*Trade, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	trades, err := s.db.GetTrades(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Trade, 0)
+	for _, t := range trades {
+		if t.StrategyCode == strategyCode {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// GetStrategySummaries - エグジット約定日時がfrom以降to未満の取引と、同じ期間に計上した費用を戦略ごとに集計する
+func (s *tradeStore) GetStrategySummaries(from time.Time, to time.Time) ([]*TradeSummary, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	trades, err := s.db.GetTrades(from, to)
+	if err != nil {
+		return nil, err
+	}
+	fees, err := s.db.GetFees(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*TradeSummary, 0)
+	index := map[string]*TradeSummary{}
+	get := func(strategyCode string) *TradeSummary {
+		summary, ok := index[strategyCode]
+		if !ok {
+			summary = &TradeSummary{StrategyCode: strategyCode}
+			index[strategyCode] = summary
+			summaries = append(summaries, summary)
+		}
+		return summary
+	}
+	for _, t := range trades {
+		s.add(get(t.StrategyCode), t)
+	}
+	for _, f := range fees {
+		s.addFee(get(f.StrategyCode), f)
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].StrategyCode < summaries[j].StrategyCode
+	})
+	return summaries, nil
+}
+
+// GetDailySummaries - 戦略を指定して、エグジット約定日時がfrom以降to未満の取引と、同じ期間に計上した費用を日ごとに集計する
+func (s *tradeStore) GetDailySummaries(strategyCode string, from time.Time, to time.Time) ([]*TradeSummary, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	trades, err := s.db.GetTrades(from, to)
+	if err != nil {
+		return nil, err
+	}
+	fees, err := s.db.GetFees(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// エントリーだけの日もあるので、取引と費用のどちらかがあればその日の集計を作る
+	summaries := make([]*TradeSummary, 0)
+	index := map[time.Time]*TradeSummary{}
+	get := func(dt time.Time) *TradeSummary {
+		date := time.Date(dt.Year(), dt.Month(), dt.Day(), 0, 0, 0, 0, dt.Location())
+		summary, ok := index[date]
+		if !ok {
+			summary = &TradeSummary{StrategyCode: strategyCode, Date: date}
+			index[date] = summary
+			summaries = append(summaries, summary)
+		}
+		return summary
+	}
+	for _, t := range trades {
+		if t.StrategyCode == strategyCode {
+			s.add(get(t.ExitDateTime), t)
+		}
+	}
+	for _, f := range fees {
+		if f.StrategyCode == strategyCode {
+			s.addFee(get(f.DateTime), f)
+		}
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Date.Before(summaries[j].Date)
+	})
+	return summaries, nil
+}
+
+// add - 集計に取引を加える
+func (s *tradeStore) add(summary *TradeSummary, trade *Trade) {
+	summary.Profit += trade.Profit
+	summary.NetProfit += trade.Profit
+	summary.Quantity += trade.Quantity
+	summary.Count++
+	switch {
+	case trade.Profit > 0:
+		summary.WinCount++
+	case trade.Profit < 0:
+		summary.LossCount++
+	}
+}
+
+// addFee - 集計に費用を加える
+func (s *tradeStore) addFee(summary *TradeSummary, fee *Fee) {
+	summary.Fee += fee.Fee
+	summary.NetProfit -= fee.Fee
+}
@@ -0,0 +1,230 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testTradeStore struct {
+	ITradeStore
+	Save1                       error
+	SaveHistory                 []interface{}
+	GetByStrategyCode1          []*Trade
+	GetByStrategyCode2          error
+	GetByStrategyCodeHistory    []interface{}
+	GetStrategySummaries1       []*TradeSummary
+	GetStrategySummaries2       error
+	GetStrategySummariesHistory []interface{}
+	GetDailySummaries1          []*TradeSummary
+	GetDailySummaries2          error
+	GetDailySummariesHistory    []interface{}
+}
+
+func (t *testTradeStore) Save(trade *Trade) error {
+	t.SaveHistory = append(t.SaveHistory, trade)
+	return t.Save1
+}
+func (t *testTradeStore) GetByStrategyCode(strategyCode string, from time.Time, to time.Time) ([]*Trade, error) {
+	t.GetByStrategyCodeHistory = append(t.GetByStrategyCodeHistory, strategyCode, from, to)
+	return t.GetByStrategyCode1, t.GetByStrategyCode2
+}
+func (t *testTradeStore) GetStrategySummaries(from time.Time, to time.Time) ([]*TradeSummary, error) {
+	t.GetStrategySummariesHistory = append(t.GetStrategySummariesHistory, from, to)
+	return t.GetStrategySummaries1, t.GetStrategySummaries2
+}
+func (t *testTradeStore) GetDailySummaries(strategyCode string, from time.Time, to time.Time) ([]*TradeSummary, error) {
+	t.GetDailySummariesHistory = append(t.GetDailySummariesHistory, strategyCode, from, to)
+	return t.GetDailySummaries1, t.GetDailySummaries2
+}
+
+func Test_getTradeStore(t *testing.T) {
+	t.Parallel()
+
+	db := &testDB{}
+	want1 := &tradeStore{db: db}
+	got1 := getTradeStore(db)
+
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_tradeStore_Save(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                 string
+		db                   *testDB
+		arg                  *Trade
+		want                 error
+		wantSaveTradeHistory []interface{}
+	}{
+		{name: "nilならエラー", db: &testDB{}, arg: nil, want: ErrNilArgument, wantSaveTradeHistory: nil},
+		{name: "DBの保存に失敗したらエラー",
+			db:                   &testDB{SaveTrade1: ErrUnknown},
+			arg:                  &Trade{Code: "trade-code-001"},
+			want:                 ErrUnknown,
+			wantSaveTradeHistory: []interface{}{&Trade{Code: "trade-code-001"}}},
+		{name: "DBに保存できたらnil",
+			db:                   &testDB{},
+			arg:                  &Trade{Code: "trade-code-001"},
+			want:                 nil,
+			wantSaveTradeHistory: []interface{}{&Trade{Code: "trade-code-001"}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &tradeStore{db: test.db}
+			got := store.Save(test.arg)
+			if !errors.Is(got, test.want) || !reflect.DeepEqual(test.wantSaveTradeHistory, test.db.SaveTradeHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want, test.wantSaveTradeHistory, got, test.db.SaveTradeHistory)
+			}
+		})
+	}
+}
+
+func Test_tradeStore_GetByStrategyCode(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		db    *testDB
+		arg1  string
+		want1 []*Trade
+		want2 error
+	}{
+		{name: "DBがエラーを返したらエラー", db: &testDB{GetTrades2: ErrUnknown}, arg1: "strategy-code-001", want1: nil, want2: ErrUnknown},
+		{name: "指定した戦略の取引だけを返す",
+			db: &testDB{GetTrades1: []*Trade{
+				{Code: "trade-code-001", StrategyCode: "strategy-code-001"},
+				{Code: "trade-code-002", StrategyCode: "strategy-code-002"},
+				{Code: "trade-code-003", StrategyCode: "strategy-code-001"},
+			}},
+			arg1: "strategy-code-001",
+			want1: []*Trade{
+				{Code: "trade-code-001", StrategyCode: "strategy-code-001"},
+				{Code: "trade-code-003", StrategyCode: "strategy-code-001"},
+			},
+			want2: nil},
+		{name: "該当する取引がなければ空配列", db: &testDB{GetTrades1: []*Trade{}}, arg1: "strategy-code-001", want1: []*Trade{}, want2: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &tradeStore{db: test.db}
+			got1, got2 := store.GetByStrategyCode(test.arg1, time.Time{}, time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local))
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_tradeStore_GetStrategySummaries(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		db    *testDB
+		want1 []*TradeSummary
+		want2 error
+	}{
+		{name: "DBがエラーを返したらエラー", db: &testDB{GetTrades2: ErrUnknown}, want1: nil, want2: ErrUnknown},
+		{name: "取引がなければ空配列", db: &testDB{GetTrades1: []*Trade{}}, want1: []*TradeSummary{}, want2: nil},
+		{name: "費用を取得できなければエラー", db: &testDB{GetTrades1: []*Trade{}, GetFees2: ErrUnknown}, want1: nil, want2: ErrUnknown},
+		{name: "費用は戦略ごとに合計し、確定損益から引いた損益も返す",
+			db: &testDB{
+				GetTrades1: []*Trade{{StrategyCode: "strategy-code-001", Quantity: 1, Profit: 100}},
+				GetFees1: []*Fee{
+					{StrategyCode: "strategy-code-001", Fee: 30},
+					{StrategyCode: "strategy-code-002", Fee: 5},
+					{StrategyCode: "strategy-code-001", Fee: 20},
+				}},
+			want1: []*TradeSummary{
+				{StrategyCode: "strategy-code-001", Profit: 100, Quantity: 1, Count: 1, WinCount: 1, Fee: 50, NetProfit: 50},
+				{StrategyCode: "strategy-code-002", Fee: 5, NetProfit: -5},
+			},
+			want2: nil},
+		{name: "戦略ごとに集計し、戦略コード順に返す",
+			db: &testDB{GetTrades1: []*Trade{
+				{StrategyCode: "strategy-code-002", Quantity: 1, Profit: 10},
+				{StrategyCode: "strategy-code-001", Quantity: 2, Profit: -20},
+				{StrategyCode: "strategy-code-002", Quantity: 3, Profit: 0},
+				{StrategyCode: "strategy-code-002", Quantity: 1, Profit: -5},
+			}},
+			want1: []*TradeSummary{
+				{StrategyCode: "strategy-code-001", Profit: -20, Quantity: 2, Count: 1, WinCount: 0, LossCount: 1, NetProfit: -20},
+				{StrategyCode: "strategy-code-002", Profit: 5, Quantity: 5, Count: 3, WinCount: 1, LossCount: 1, NetProfit: 5},
+			},
+			want2: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &tradeStore{db: test.db}
+			got1, got2 := store.GetStrategySummaries(time.Time{}, time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local))
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_tradeStore_GetDailySummaries(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		db    *testDB
+		arg1  string
+		want1 []*TradeSummary
+		want2 error
+	}{
+		{name: "DBがエラーを返したらエラー", db: &testDB{GetTrades2: ErrUnknown}, arg1: "strategy-code-001", want1: nil, want2: ErrUnknown},
+		{name: "エントリーだけの日も費用があれば集計に含める",
+			db: &testDB{
+				GetTrades1: []*Trade{
+					{StrategyCode: "strategy-code-001", ExitDateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Quantity: 1, Profit: 10},
+				},
+				GetFees1: []*Fee{
+					{StrategyCode: "strategy-code-001", Fee: 3, DateTime: time.Date(2022, 1, 24, 14, 0, 0, 0, time.Local)},
+					{StrategyCode: "strategy-code-002", Fee: 100, DateTime: time.Date(2022, 1, 24, 14, 0, 0, 0, time.Local)},
+					{StrategyCode: "strategy-code-001", Fee: 4, DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local)},
+				}},
+			arg1: "strategy-code-001",
+			want1: []*TradeSummary{
+				{StrategyCode: "strategy-code-001", Date: time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), Fee: 3, NetProfit: -3},
+				{StrategyCode: "strategy-code-001", Date: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local), Profit: 10, Quantity: 1, Count: 1, WinCount: 1, Fee: 4, NetProfit: 6},
+			},
+			want2: nil},
+		{name: "指定した戦略の取引を日ごとに集計する",
+			db: &testDB{GetTrades1: []*Trade{
+				{StrategyCode: "strategy-code-001", ExitDateTime: time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local), Quantity: 1, Profit: 10},
+				{StrategyCode: "strategy-code-002", ExitDateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local), Quantity: 1, Profit: 100},
+				{StrategyCode: "strategy-code-001", ExitDateTime: time.Date(2022, 1, 24, 14, 0, 0, 0, time.Local), Quantity: 1, Profit: -3},
+				{StrategyCode: "strategy-code-001", ExitDateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Quantity: 2, Profit: 8},
+			}},
+			arg1: "strategy-code-001",
+			want1: []*TradeSummary{
+				{StrategyCode: "strategy-code-001", Date: time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), Profit: 7, Quantity: 2, Count: 2, WinCount: 1, LossCount: 1, NetProfit: 7},
+				{StrategyCode: "strategy-code-001", Date: time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local), Profit: 8, Quantity: 2, Count: 1, WinCount: 1, LossCount: 0, NetProfit: 8},
+			},
+			want2: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store := &tradeStore{db: test.db}
+			got1, got2 := store.GetDailySummaries(test.arg1, time.Time{}, time.Date(2022, 1, 26, 0, 0, 0, 0, time.Local))
+			if !reflect.DeepEqual(test.want1, got1) || !errors.Is(got2, test.want2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package gridon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// newTrendFilterService - 新しいトレンドフィルタサービスの取得
+func newTrendFilterService(clock IClock, strategyStore IStrategyStore, fourPriceStore IFourPriceStore, logger ILogger) ITrendFilterService {
+	return &trendFilterService{
+		clock:          clock,
+		strategyStore:  strategyStore,
+		fourPriceStore: fourPriceStore,
+		logger:         logger,
+	}
+}
+
+// ITrendFilterService - トレンドフィルタサービスのインターフェース
+type ITrendFilterService interface {
+	Check(strategy *Strategy, price float64) (TrendFilterState, error)
+}
+
+// trendFilterService - トレンドフィルタサービス
+type trendFilterService struct {
+	clock          IClock
+	strategyStore  IStrategyStore
+	fourPriceStore IFourPriceStore
+	logger         ILogger
+}
+
+// Check - 前日までの四本値と現在の価格からトレンドを判定し、フィルタが効いているかを返す
+// フィルタの効き始めと効き終わりだけを戦略に記録して通知する
+func (s *trendFilterService) Check(strategy *Strategy, price float64) (TrendFilterState, error) {
+	if strategy == nil {
+		return TrendFilterState{}, ErrNilArgument
+	}
+
+	prev := strategy.TrendFilterState
+	filter := strategy.GridStrategy.TrendFilter
+
+	// フィルタが無効になっていたら、前回の判定結果を消しておく
+	if !filter.Valid {
+		if prev != (TrendFilterState{}) {
+			if err := s.strategyStore.SetTrendFilterState(strategy.Code, TrendFilterState{}); err != nil {
+				return TrendFilterState{}, err
+			}
+		}
+		return TrendFilterState{}, nil
+	}
+
+	now := s.clock.Now()
+	var active bool
+	value, err := s.value(strategy, price, now)
+	switch {
+	case errors.Is(err, ErrNotEnoughPrices):
+		// 上場直後や運用開始直後で判定に必要な四本値が揃っていなければ、グリッドを止めないようにフィルタは効いていないものとする
+	case err != nil:
+		return TrendFilterState{}, err
+	default:
+		active = filter.active(strategy.EntrySide, value)
+	}
+	if active == prev.Active {
+		return prev, nil
+	}
+
+	state := TrendFilterState{Active: active, Value: value, ChangedDateTime: now}
+	if active {
+		s.logger.Notice(fmt.Sprintf("%s のトレンドフィルタが効きました(type = %s, value = %.4f, threshold = %.4f, action = %s)", strategy.Code, filter.Type, value, filter.Threshold, filter.Action))
+	} else {
+		s.logger.Notice(fmt.Sprintf("%s のトレンドフィルタが外れました(type = %s, value = %.4f, threshold = %.4f)", strategy.Code, filter.Type, value, filter.Threshold))
+	}
+	if err := s.strategyStore.SetTrendFilterState(strategy.Code, state); err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+// value - トレンドの判定に使う指標の値
+// どの判定方法でも、上昇なら正、下落なら負の割合になる
+func (s *trendFilterService) value(strategy *Strategy, price float64, now time.Time) (float64, error) {
+	filter := strategy.GridStrategy.TrendFilter
+	days := filter.Days
+	if filter.Type == TrendFilterTypeBreakout {
+		days = 1
+	}
+	if days <= 0 || price <= 0 {
+		return 0, ErrNoData
+	}
+
+	fps, err := s.prevFourPrices(strategy, now, days)
+	if err != nil {
+		return 0, err
+	}
+
+	closes := make([]float64, 0, days)
+	for _, fp := range fps {
+		closes = append(closes, fp.Close)
+	}
+
+	switch filter.Type {
+	case TrendFilterTypeMASlope:
+		// 前日までの移動平均と、現在の価格を当日の終値とみなした移動平均の変化率
+		prevAverage := average(closes)
+		currentAverage := average(append([]float64{price}, closes[:days-1]...))
+		return currentAverage/prevAverage - 1, nil
+	case TrendFilterTypeBreakout:
+		// 前日の値幅の中なら0、抜けていれば抜けた先の高値・安値からの変化率
+		switch {
+		case 0 < fps[0].High && fps[0].High < price:
+			return price/fps[0].High - 1, nil
+		case price < fps[0].Low:
+			return price/fps[0].Low - 1, nil
+		}
+		return 0, nil
+	default:
+		// 前日までの移動平均からの現在の価格の乖離率
+		return price/average(closes) - 1, nil
+	}
+}
+
+// prevFourPrices - 当日より前の四本値を新しい方から指定日数分
+// 引け後に当日の四本値が保存されていることもあるので、1日分多く取得して当日の分を除く
+// 指定日数分がなければ判定できないのでErrNotEnoughPrices
+func (s *trendFilterService) prevFourPrices(strategy *Strategy, now time.Time, days int) ([]*FourPrice, error) {
+	fps, err := s.fourPriceStore.GetBySymbolCodeAndExchange(strategy.SymbolCode, strategy.Exchange, days+1)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	prev := make([]*FourPrice, 0, days)
+	for _, fp := range fps {
+		if fp == nil || !fp.DateTime.Before(today) || fp.Close <= 0 {
+			continue
+		}
+		prev = append(prev, fp)
+		if len(prev) >= days {
+			return prev, nil
+		}
+	}
+	return nil, ErrNotEnoughPrices
+}
@@ -0,0 +1,274 @@
+package gridon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testTrendFilterService struct {
+	ITrendFilterService
+	Check1       TrendFilterState
+	Check2       error
+	CheckCount   int
+	CheckHistory []interface{}
+}
+
+func (t *testTrendFilterService) Check(strategy *Strategy, price float64) (TrendFilterState, error) {
+	t.CheckHistory = append(t.CheckHistory, strategy)
+	t.CheckHistory = append(t.CheckHistory, price)
+	t.CheckCount++
+	return t.Check1, t.Check2
+}
+
+func Test_newTrendFilterService(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{}
+	strategyStore := &testStrategyStore{}
+	fourPriceStore := &testFourPriceStore{}
+	logger := &testLogger{}
+	want1 := &trendFilterService{
+		clock:          clock,
+		strategyStore:  strategyStore,
+		fourPriceStore: fourPriceStore,
+		logger:         logger,
+	}
+	got1 := newTrendFilterService(clock, strategyStore, fourPriceStore, logger)
+	if !reflect.DeepEqual(want1, got1) {
+		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
+	}
+}
+
+func Test_trendFilterService_Check(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2021, 11, 5, 10, 0, 0, 0, time.Local)
+	before := time.Date(2021, 11, 5, 9, 30, 0, 0, time.Local)
+	fourPrices := []*FourPrice{
+		{DateTime: time.Date(2021, 11, 4, 15, 0, 0, 0, time.Local), High: 2100, Low: 1600, Close: 2000},
+		{DateTime: time.Date(2021, 11, 2, 15, 0, 0, 0, time.Local), Close: 2100},
+		{DateTime: time.Date(2021, 11, 1, 15, 0, 0, 0, time.Local), Close: 1900},
+	}
+	tests := []struct {
+		name                           string
+		strategyStore                  *testStrategyStore
+		fourPriceStore                 *testFourPriceStore
+		arg1                           *Strategy
+		arg2                           float64
+		want1                          TrendFilterState
+		want2                          error
+		wantSetTrendFilterStateHistory []interface{}
+		wantNoticeCount                int
+		wantGetBySymbolCodeAndExchange []interface{}
+	}{
+		{name: "strategyがnilならエラー",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{},
+			arg1:           nil,
+			arg2:           2000,
+			want1:          TrendFilterState{},
+			want2:          ErrNilArgument},
+		{name: "フィルタが無効で判定結果がなければ何もしない",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{},
+			arg1:           &Strategy{Code: "strategy-code-001"},
+			arg2:           2000,
+			want1:          TrendFilterState{},
+			want2:          nil},
+		{name: "フィルタが無効で前回の判定結果が残っていれば消す",
+			strategyStore:                  &testStrategyStore{},
+			fourPriceStore:                 &testFourPriceStore{},
+			arg1:                           &Strategy{Code: "strategy-code-001", TrendFilterState: TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: before}},
+			arg2:                           2000,
+			want1:                          TrendFilterState{},
+			want2:                          nil,
+			wantSetTrendFilterStateHistory: []interface{}{"strategy-code-001", TrendFilterState{}}},
+		{name: "買いエントリーで移動平均から下にしきい値を超えて乖離したら、フィルタを効かせて記録して通知する",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: fourPrices},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Days: 3, Threshold: 0.1}}},
+			arg2:                           1500,
+			want1:                          TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now},
+			want2:                          nil,
+			wantSetTrendFilterStateHistory: []interface{}{"strategy-code-001", TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now}},
+			wantNoticeCount:                1,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 4}},
+		{name: "判定方法が未指定なら移動平均からの乖離で判定する",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: fourPrices},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Days: 3, Threshold: 0.1}}},
+			arg2:                           1500,
+			want1:                          TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now},
+			want2:                          nil,
+			wantSetTrendFilterStateHistory: []interface{}{"strategy-code-001", TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now}},
+			wantNoticeCount:                1,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 4}},
+		{name: "フィルタが効いたままなら記録も通知もせず、前回の判定結果を返す",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: fourPrices},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy,
+				GridStrategy:     GridStrategy{TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Days: 3, Threshold: 0.1}},
+				TrendFilterState: TrendFilterState{Active: true, Value: -0.125, ChangedDateTime: before}},
+			arg2:                           1500,
+			want1:                          TrendFilterState{Active: true, Value: -0.125, ChangedDateTime: before},
+			want2:                          nil,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 4}},
+		{name: "乖離がしきい値の中に戻ったら、フィルタを外して記録して通知する",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: fourPrices},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy,
+				GridStrategy:     GridStrategy{TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Days: 3, Threshold: 0.1}},
+				TrendFilterState: TrendFilterState{Active: true, Value: -0.125, ChangedDateTime: before}},
+			arg2:                           2000,
+			want1:                          TrendFilterState{Active: false, Value: 0, ChangedDateTime: now},
+			want2:                          nil,
+			wantSetTrendFilterStateHistory: []interface{}{"strategy-code-001", TrendFilterState{Active: false, Value: 0, ChangedDateTime: now}},
+			wantNoticeCount:                1,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 4}},
+		{name: "売りエントリーなら上への乖離でフィルタを効かせる",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: fourPrices},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideSell, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Days: 3, Threshold: 0.1}}},
+			arg2:                           2500,
+			want1:                          TrendFilterState{Active: true, Value: 0.25, ChangedDateTime: now},
+			want2:                          nil,
+			wantSetTrendFilterStateHistory: []interface{}{"strategy-code-001", TrendFilterState{Active: true, Value: 0.25, ChangedDateTime: now}},
+			wantNoticeCount:                1,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 4}},
+		{name: "売りエントリーなら下への乖離ではフィルタを効かせない",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: fourPrices},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideSell, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Days: 3, Threshold: 0.1}}},
+			arg2:                           1500,
+			want1:                          TrendFilterState{},
+			want2:                          nil,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 4}},
+		{name: "移動平均の傾きなら、現在の価格を当日の終値とみなした移動平均の変化率で判定する",
+			strategyStore: &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{
+				{DateTime: time.Date(2021, 11, 4, 15, 0, 0, 0, time.Local), Close: 2000},
+				{DateTime: time.Date(2021, 11, 2, 15, 0, 0, 0, time.Local), Close: 2000}}},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMASlope, Days: 2, Threshold: 0.1}}},
+			arg2:                           1000,
+			want1:                          TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now},
+			want2:                          nil,
+			wantSetTrendFilterStateHistory: []interface{}{"strategy-code-001", TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now}},
+			wantNoticeCount:                1,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 3}},
+		{name: "ブレイクなら前日の安値を下に抜けた割合で判定する",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: fourPrices[:2]},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeBreakout}}},
+			arg2:                           1200,
+			want1:                          TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now},
+			want2:                          nil,
+			wantSetTrendFilterStateHistory: []interface{}{"strategy-code-001", TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now}},
+			wantNoticeCount:                1,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 2}},
+		{name: "ブレイクで前日の値幅の中ならフィルタを効かせない",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: fourPrices[:2]},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeBreakout}}},
+			arg2:                           1600,
+			want1:                          TrendFilterState{},
+			want2:                          nil,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 2}},
+		{name: "当日の四本値が保存されていたら、それを除いて計算する",
+			strategyStore: &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{
+				{DateTime: time.Date(2021, 11, 5, 15, 0, 0, 0, time.Local), Close: 1000},
+				{DateTime: time.Date(2021, 11, 4, 15, 0, 0, 0, time.Local), Close: 2000},
+				{DateTime: time.Date(2021, 11, 2, 15, 0, 0, 0, time.Local), Close: 2000}}},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Days: 2, Threshold: 0.1}}},
+			arg2:                           1500,
+			want1:                          TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now},
+			want2:                          nil,
+			wantSetTrendFilterStateHistory: []interface{}{"strategy-code-001", TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now}},
+			wantNoticeCount:                1,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 3}},
+		{name: "指定日数分の四本値がなければフィルタは効いていないものとする",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: fourPrices[:2]},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Days: 3, Threshold: 0.1}}},
+			arg2:                           1500,
+			want1:                          TrendFilterState{},
+			want2:                          nil,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 4}},
+		{name: "四本値が1つもない銘柄ならフィルタは効いていないものとする",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{}},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeBreakout, Threshold: 0.1}}},
+			arg2:                           1500,
+			want1:                          TrendFilterState{},
+			want2:                          nil,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 2}},
+		{name: "四本値が足りなくなったら、効いていたフィルタを外す",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: []*FourPrice{}},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, TrendFilterState: TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: before},
+				GridStrategy: GridStrategy{TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Days: 3, Threshold: 0.1}}},
+			arg2:                           1500,
+			want1:                          TrendFilterState{Active: false, Value: 0, ChangedDateTime: now},
+			want2:                          nil,
+			wantSetTrendFilterStateHistory: []interface{}{"strategy-code-001", TrendFilterState{Active: false, Value: 0, ChangedDateTime: now}},
+			wantNoticeCount:                1,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 4}},
+		{name: "移動平均の日数が指定されていなければエラー",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Threshold: 0.1}}},
+			arg2:  1500,
+			want1: TrendFilterState{},
+			want2: ErrNoData},
+		{name: "四本値の取得に失敗したらエラー",
+			strategyStore:  &testStrategyStore{},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange2: ErrUnknown},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Days: 3, Threshold: 0.1}}},
+			arg2:                           1500,
+			want1:                          TrendFilterState{},
+			want2:                          ErrUnknown,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 4}},
+		{name: "判定結果の保存に失敗したらエラー",
+			strategyStore:  &testStrategyStore{SetTrendFilterState1: ErrUnknown},
+			fourPriceStore: &testFourPriceStore{GetBySymbolCodeAndExchange1: fourPrices},
+			arg1: &Strategy{Code: "strategy-code-001", SymbolCode: "1475", Exchange: ExchangeToushou, EntrySide: SideBuy, GridStrategy: GridStrategy{
+				TrendFilter: TrendFilter{Valid: true, Type: TrendFilterTypeMADeviation, Days: 3, Threshold: 0.1}}},
+			arg2:                           1500,
+			want1:                          TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now},
+			want2:                          ErrUnknown,
+			wantSetTrendFilterStateHistory: []interface{}{"strategy-code-001", TrendFilterState{Active: true, Value: -0.25, ChangedDateTime: now}},
+			wantNoticeCount:                1,
+			wantGetBySymbolCodeAndExchange: []interface{}{"1475", ExchangeToushou, 4}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			logger := &testLogger{}
+			service := &trendFilterService{clock: &testClock{Now1: now}, strategyStore: test.strategyStore, fourPriceStore: test.fourPriceStore, logger: logger}
+			got1, got2 := service.Check(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) ||
+				!errors.Is(got2, test.want2) ||
+				!reflect.DeepEqual(test.wantSetTrendFilterStateHistory, test.strategyStore.SetTrendFilterStateHistory) ||
+				!reflect.DeepEqual(test.wantNoticeCount, logger.NoticeCount) ||
+				!reflect.DeepEqual(test.wantGetBySymbolCodeAndExchange, test.fourPriceStore.GetBySymbolCodeAndExchangeHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %+v, %+v, %+v\ngot: %+v, %+v, %+v, %+v, %+v\n", t.Name(),
+					test.want1, test.want2, test.wantSetTrendFilterStateHistory, test.wantNoticeCount, test.wantGetBySymbolCodeAndExchange,
+					got1, got2, test.strategyStore.SetTrendFilterStateHistory, logger.NoticeCount, test.fourPriceStore.GetBySymbolCodeAndExchangeHistory)
+			}
+		})
+	}
+}
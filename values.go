@@ -1,6 +1,8 @@
 package gridon
 
 import (
+	"math"
+	"sort"
 	"time"
 )
 
@@ -14,6 +16,10 @@ type Symbol struct {
 	BidPrice             float64   // 最良買い気配値
 	AskPrice             float64   // 最良売り気配値
 	TickGroup            TickGroup // 呼値グループ
+	UpperLimit           float64   // 値幅上限
+	LowerLimit           float64   // 値幅下限
+	OpeningPrice         float64   // 始値
+	VWAP                 float64   // 売買高加重平均価格
 }
 
 // SecurityOrder - 証券会社の注文
@@ -26,7 +32,9 @@ type SecurityOrder struct {
 	MarginTradeType  MarginTradeType // 信用取引区分
 	TradeType        TradeType       // 取引種別
 	Side             Side            // 方向
+	ExecutionType    ExecutionType   // 執行条件
 	Price            float64         // 指値価格
+	TriggerPrice     float64         // 逆指値の発火価格
 	OrderQuantity    float64         // 注文数量
 	ContractQuantity float64         // 約定数量
 	AccountType      AccountType     // 口座種別
@@ -46,6 +54,20 @@ type Contract struct {
 	ContractDateTime time.Time // 約定日時
 }
 
+// SecurityPosition - 証券会社のポジション
+type SecurityPosition struct {
+	Code             string          // ポジションコード
+	SymbolCode       string          // 銘柄コード
+	Exchange         Exchange        // 市場
+	Product          Product         // 商品種別
+	MarginTradeType  MarginTradeType // 信用取引区分
+	Side             Side            // 売買方向
+	Price            float64         // 約定値
+	OwnedQuantity    float64         // 保有数量
+	HoldQuantity     float64         // 拘束数量
+	ContractDateTime time.Time       // 約定日時
+}
+
 // HoldPosition - 拘束ポジション
 type HoldPosition struct {
 	PositionCode     string  // ポジションコード
@@ -69,19 +91,31 @@ type OrderResult struct {
 
 // Account - 口座情報
 type Account struct {
-	Password    string      // 注文パスワード
-	AccountType AccountType // 口座種別
+	Password     string       // 注文パスワード
+	AccountType  AccountType  // 口座種別
+	DeliveryType DeliveryType // 現物買いの受渡区分 (未指定ならお預かり金)
+	FundType     FundType     // 現物買いの資産区分 (未指定なら信用代用)
 }
 
 // GridStrategy - グリッド戦略
 type GridStrategy struct {
-	Runnable           bool               // 実行可能かどうか
-	Quantity           float64            // 1グリッドに乗せる数量
-	BaseWidth          int                // 基準となるグリッド幅(tick数)
-	NumberOfGrids      int                // 指値注文を入れておくグリッドの本数
-	TimeRanges         []TimeRange        // 戦略動作時刻範囲
-	DynamicGridPrevDay DynamicGridPrevDay // 前日の価格幅からの動的なグリッド幅
-	DynamicGridMinMax  DynamicGridMinMax  // 最小・最大約定値からの動的なグリッド幅
+	Runnable              bool                  // 実行可能かどうか
+	Quantity              float64               // 1グリッドに乗せる数量
+	BaseWidth             int                   // 基準となるグリッド幅(tick数)
+	NumberOfGrids         int                   // 指値注文を入れておくグリッドの本数
+	TimeRanges            []TimeRange           // 戦略動作時刻範囲
+	DynamicGridPrevDay    DynamicGridPrevDay    // 前日の価格幅からの動的なグリッド幅
+	DynamicGridMinMax     DynamicGridMinMax     // 最小・最大約定値からの動的なグリッド幅
+	DynamicGridVolatility DynamicGridVolatility // 過去数日のボラティリティからの動的なグリッド幅
+	Spacing               GridSpacing           // グリッドの間隔の取り方
+	WidthRate             float64               // 幾何級数の間隔で、1つ内側のグリッドから何%離すか(1% = 0.01)
+	Upper                 GridSideStrategy      // 基準価格より上のグリッドの設定
+	Lower                 GridSideStrategy      // 基準価格より下のグリッドの設定
+	InventorySkew         InventorySkew         // 保有数量に応じてエントリー側のグリッドを減らす設定
+	QuantityProfile       QuantityProfile       // グリッドごとの数量の決め方
+	PriceBand             PriceBand             // グリッドを置く価格帯
+	BasePriceSource       BasePriceSource       // 基準価格の取り方
+	TrendFilter           TrendFilter           // 強いトレンドのときにエントリー側のグリッドを止めるか減らすフィルタ
 }
 
 // IsRunnable - グリッド戦略が実行可能かどうか
@@ -99,6 +133,148 @@ func (v *GridStrategy) IsRunnable(now time.Time) bool {
 	return false
 }
 
+// geometricRate - 幾何級数の間隔で、1つ内側のグリッドから離す割合
+// 動的なグリッド幅で計算したティック数とBaseWidthの比をWidthRateにかけ、動的なグリッド幅を割合にも反映する
+func (v *GridStrategy) geometricRate(width int) float64 {
+	if v.BaseWidth <= 0 {
+		return v.WidthRate
+	}
+	return v.WidthRate * float64(width) / float64(v.BaseWidth)
+}
+
+// upperGrid - 基準価格より上のグリッドの設定
+// 上側の設定が無効なら共通の設定を使う
+func (v *GridStrategy) upperGrid() GridSideStrategy {
+	return v.sideGrid(v.Upper)
+}
+
+// lowerGrid - 基準価格より下のグリッドの設定
+// 下側の設定が無効なら共通の設定を使う
+func (v *GridStrategy) lowerGrid() GridSideStrategy {
+	return v.sideGrid(v.Lower)
+}
+
+// sideGrid - 片側のグリッドの設定が有効ならそのまま、無効なら共通の設定を返す
+func (v *GridStrategy) sideGrid(side GridSideStrategy) GridSideStrategy {
+	if side.Valid {
+		return side
+	}
+	return GridSideStrategy{Valid: true, Width: v.BaseWidth, NumberOfGrids: v.NumberOfGrids, Quantity: v.Quantity}
+}
+
+// sideWidth - 動的なグリッド幅を片側のグリッド幅とBaseWidthの比で調整した幅
+// 片側のグリッド幅がBaseWidthと同じなら動的なグリッド幅をそのまま返す
+func (v *GridStrategy) sideWidth(width int, sideWidth int) int {
+	if sideWidth <= 0 {
+		return 0
+	}
+	if v.BaseWidth <= 0 {
+		return sideWidth
+	}
+	if sideWidth == v.BaseWidth {
+		return width
+	}
+
+	w := int(math.Round(float64(width) * float64(sideWidth) / float64(v.BaseWidth)))
+	if w < 1 {
+		return 1
+	}
+	return w
+}
+
+// GridSideStrategy - 片側のグリッドの設定
+type GridSideStrategy struct {
+	Valid         bool    // 有効・無効
+	Width         int     // グリッド幅(tick数)
+	NumberOfGrids int     // 指値注文を入れておくグリッドの本数
+	Quantity      float64 // 1グリッドに乗せる数量
+}
+
+// InventorySkew - 保有数量に応じてエントリー側のグリッドを減らす設定
+type InventorySkew struct {
+	Valid        bool    // 有効・無効
+	StepQuantity float64 // 保有数量がこの数量に達するごとにグリッドを減らす
+	ReduceGrids  int     // 1段階ごとに減らすグリッドの本数
+	MinGrids     int     // エントリー側に残すグリッドの最低本数
+}
+
+// numberOfGrids - 保有数量に応じて減らしたエントリー側のグリッドの本数
+// 減らした結果が最低本数を下回るなら最低本数にし、元の本数より増やすことはない
+func (v *InventorySkew) numberOfGrids(numberOfGrids int, ownedQuantity float64) int {
+	if !v.Valid || v.StepQuantity <= 0 || v.ReduceGrids <= 0 {
+		return numberOfGrids
+	}
+
+	n := numberOfGrids - int(math.Floor(ownedQuantity/v.StepQuantity))*v.ReduceGrids
+	if n < v.MinGrids {
+		n = v.MinGrids
+	}
+	if n > numberOfGrids {
+		n = numberOfGrids
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// BasePriceSource - 基準価格の取り方
+// 最終約定価格以外では、約定に引っ張られず、指定した間隔ごとに計算しなおした価格を基準価格にする
+type BasePriceSource struct {
+	Type     BasePriceSourceType // 種類
+	Days     int                 // moving_averageで、何日分の終値の平均をとるか
+	Interval time.Duration       // 最終約定価格以外で、基準価格を計算しなおす間隔(0なら毎回計算しなおす)
+}
+
+// IsLastContract - 最終約定価格を基準価格にするか
+func (v *BasePriceSource) IsLastContract() bool {
+	return v.Type == BasePriceSourceTypeUnspecified || v.Type == BasePriceSourceTypeLastContract
+}
+
+// QuantityProfile - グリッドごとの数量の決め方
+type QuantityProfile struct {
+	Type       QuantityProfileType // 種類
+	Step       float64             // linearで、1つ外側のグリッドごとに増やす数量
+	Rate       float64             // geometricで、1つ外側のグリッドごとにかける倍率
+	Quantities []float64           // customで、基準価格の隣から外側に向かって並べた数量。足りない分は最後の数量を使う
+}
+
+// quantity - 基準価格の隣を0として、level本目のグリッドに乗せる数量
+// 種類が指定されていれば売買単位の倍数に切り捨てる
+func (v *QuantityProfile) quantity(quantity float64, level int, tradingUnit float64) float64 {
+	q := quantity
+	switch v.Type {
+	case QuantityProfileTypeUnspecified:
+		return quantity
+	case QuantityProfileTypeLinear:
+		q = quantity + v.Step*float64(level)
+	case QuantityProfileTypeGeometric:
+		q = quantity * math.Pow(v.Rate, float64(level))
+	case QuantityProfileTypeCustom:
+		if len(v.Quantities) > 0 {
+			if level < len(v.Quantities) {
+				q = v.Quantities[level]
+			} else {
+				q = v.Quantities[len(v.Quantities)-1]
+			}
+		}
+	}
+	return floorToTradingUnit(q, tradingUnit)
+}
+
+// floorToTradingUnit - 数量を売買単位の倍数に切り捨てる
+// 売買単位がなければそのまま返す
+func floorToTradingUnit(quantity float64, tradingUnit float64) float64 {
+	if quantity <= 0 {
+		return 0
+	}
+	if tradingUnit <= 0 {
+		return quantity
+	}
+	n := math.Round(quantity/tradingUnit*1_000_000) / 1_000_000 // 浮動小数点の誤差で切り捨てがずれないようにする
+	return math.Round(math.Floor(n)*tradingUnit*1_000_000) / 1_000_000
+}
+
 // DynamicGridPrevDay - 前日の価格幅からの動的なグリッド幅
 type DynamicGridPrevDay struct {
 	Valid         bool      // 有効・無効
@@ -144,6 +320,249 @@ func (v *DynamicGridMinMax) width(width int, diff int) int {
 	return w
 }
 
+// PriceBand - グリッドを置く価格帯
+// 価格帯の外にはグリッドの注文を置かない
+type PriceBand struct {
+	Valid  bool            // 有効・無効
+	Source PriceBandSource // 価格帯の基準
+	Upper  float64         // 絶対価格なら上限価格、それ以外なら基準から上に何%までか(1% = 0.01)。0なら上限なし
+	Lower  float64         // 絶対価格なら下限価格、それ以外なら基準から下に何%までか(1% = 0.01)。0なら下限なし
+	Days   int             // 移動平均で何日分の終値を使うか
+	Policy PriceBandPolicy // 基準価格が価格帯を外れたときの対応
+}
+
+// bounds - 基準の価格から計算した下限価格と上限価格
+func (v *PriceBand) bounds(reference float64) (float64, float64) {
+	if v.Source == PriceBandSourceUnspecified || v.Source == PriceBandSourceAbsolute {
+		return v.Lower, v.Upper
+	}
+
+	var lower, upper float64
+	if v.Lower > 0 {
+		lower = reference * (1 - v.Lower)
+	}
+	if v.Upper > 0 {
+		upper = reference * (1 + v.Upper)
+	}
+	return lower, upper
+}
+
+// PriceBandState - 価格帯の判定結果
+type PriceBandState struct {
+	Lower           float64   // 下限価格(0なら下限なし)
+	Upper           float64   // 上限価格(0なら上限なし)
+	OutOfBand       bool      // 価格が価格帯の外にあるかどうか
+	ChangedDateTime time.Time // 価格帯の内外が変わった日時
+}
+
+// In - 価格が価格帯の中にあるかどうか
+func (v *PriceBandState) In(price float64) bool {
+	if v.Lower > 0 && price < v.Lower {
+		return false
+	}
+	if v.Upper > 0 && v.Upper < price {
+		return false
+	}
+	return true
+}
+
+// TrendFilter - 強いトレンドのときにエントリー側のグリッドを止めるか減らすフィルタ
+// エントリーと逆方向のトレンド(買いなら下落、売りなら上昇)がしきい値を超えている間だけ効く
+type TrendFilter struct {
+	Valid        bool              // 有効・無効
+	Type         TrendFilterType   // トレンドの判定方法
+	Days         int               // 移動平均をとる日数
+	Threshold    float64           // 判定のしきい値(1% = 0.01)
+	Action       TrendFilterAction // フィルタが効いている間の対応
+	QuantityRate float64           // reduce_entryで、エントリー側の数量にかける割合(50% = 0.5)
+}
+
+// IsStopEntry - フィルタが効いている間、エントリーを止めるか
+func (v *TrendFilter) IsStopEntry() bool {
+	return v.Action == TrendFilterActionUnspecified || v.Action == TrendFilterActionStopEntry
+}
+
+// active - 指標の値がエントリーと逆方向にしきい値を超えているか
+func (v *TrendFilter) active(entrySide Side, value float64) bool {
+	switch entrySide {
+	case SideBuy:
+		return value < -v.Threshold
+	case SideSell:
+		return v.Threshold < value
+	}
+	return false
+}
+
+// entryQuantity - フィルタが効いている間のエントリー側の数量
+func (v *TrendFilter) entryQuantity(quantity float64, tradingUnit float64) float64 {
+	return floorToTradingUnit(quantity*v.QuantityRate, tradingUnit)
+}
+
+// TrendFilterState - トレンドフィルタの判定結果
+type TrendFilterState struct {
+	Active          bool      // フィルタが効いているか
+	Value           float64   // 判定が変わったときの指標の値
+	ChangedDateTime time.Time // 判定が変わった日時
+}
+
+// PriceBandReport - 戦略ごとの価格帯の状態
+type PriceBandReport struct {
+	StrategyCode string         // 戦略コード
+	PriceBand    PriceBand      // 価格帯の設定
+	State        PriceBandState // 最後の判定結果
+}
+
+// dailyPriceLimitTable - 東証の基準値段ごとの制限値幅
+var dailyPriceLimitTable = []struct {
+	Upper float64 // 基準値段がこの価格未満なら
+	Width float64 // 制限値幅
+}{
+	{Upper: 100, Width: 30},
+	{Upper: 200, Width: 50},
+	{Upper: 500, Width: 80},
+	{Upper: 700, Width: 100},
+	{Upper: 1_000, Width: 150},
+	{Upper: 1_500, Width: 300},
+	{Upper: 2_000, Width: 400},
+	{Upper: 3_000, Width: 500},
+	{Upper: 5_000, Width: 700},
+	{Upper: 7_000, Width: 1_000},
+	{Upper: 10_000, Width: 1_500},
+	{Upper: 15_000, Width: 3_000},
+	{Upper: 20_000, Width: 4_000},
+	{Upper: 30_000, Width: 5_000},
+	{Upper: 50_000, Width: 7_000},
+	{Upper: 70_000, Width: 10_000},
+	{Upper: 100_000, Width: 15_000},
+	{Upper: 150_000, Width: 30_000},
+	{Upper: 200_000, Width: 40_000},
+	{Upper: 300_000, Width: 50_000},
+	{Upper: 500_000, Width: 70_000},
+	{Upper: 700_000, Width: 100_000},
+	{Upper: 1_000_000, Width: 150_000},
+	{Upper: 1_500_000, Width: 300_000},
+	{Upper: 2_000_000, Width: 400_000},
+	{Upper: 3_000_000, Width: 500_000},
+	{Upper: 5_000_000, Width: 700_000},
+	{Upper: 7_000_000, Width: 1_000_000},
+	{Upper: 10_000_000, Width: 1_500_000},
+	{Upper: 15_000_000, Width: 3_000_000},
+	{Upper: 20_000_000, Width: 4_000_000},
+	{Upper: 30_000_000, Width: 5_000_000},
+	{Upper: 50_000_000, Width: 7_000_000},
+	{Upper: math.Inf(1), Width: 10_000_000},
+}
+
+// PriceLimit - 値幅制限で注文できる価格の範囲
+type PriceLimit struct {
+	Lower float64 // 値幅下限(0なら下限なし)
+	Upper float64 // 値幅上限(0なら上限なし)
+}
+
+// dailyPriceLimit - 基準値段(前日終値)から東証の制限値幅の表で求めた値幅制限
+// 基準値段がわからなければ制限なしとする
+func dailyPriceLimit(basePrice float64) PriceLimit {
+	if basePrice <= 0 {
+		return PriceLimit{}
+	}
+
+	for _, r := range dailyPriceLimitTable {
+		if basePrice < r.Upper {
+			return PriceLimit{Lower: math.Max(basePrice-r.Width, 1), Upper: basePrice + r.Width}
+		}
+	}
+	return PriceLimit{}
+}
+
+// In - 価格が値幅制限の範囲内にあるか
+func (v *PriceLimit) In(price float64) bool {
+	if v.Lower > 0 && price < v.Lower {
+		return false
+	}
+	if v.Upper > 0 && v.Upper < price {
+		return false
+	}
+	return true
+}
+
+// Clamp - 価格を値幅制限の範囲内に収めたもの
+func (v *PriceLimit) Clamp(price float64) float64 {
+	if v.Lower > 0 && price < v.Lower {
+		return v.Lower
+	}
+	if v.Upper > 0 && v.Upper < price {
+		return v.Upper
+	}
+	return price
+}
+
+// DynamicGridVolatility - 過去数日のボラティリティからの動的なグリッド幅
+// 1日だけ静かな日や荒れた日があってもグリッド幅が振れないよう、複数日の平均を使い、外れ値を除くこともできる
+type DynamicGridVolatility struct {
+	Valid     bool           // 有効・無効
+	Type      VolatilityType // ボラティリティの計算方法
+	Days      int            // 何日分の四本値から計算するか
+	Trim      int            // 外れ値として大きい方と小さい方からそれぞれ除く日数
+	Rate      float64        // ボラティリティの何%を計算の対象にするか(100% = 1)
+	Rounding  Rounding       // 端数処理
+	Operation Operation      // 演算子
+}
+
+// width - 計算後グリッド幅
+// volatilityは単純な価格差ではなくtick数
+func (v *DynamicGridVolatility) width(width int, volatility float64) int {
+	if !v.Valid || v.Days <= 0 {
+		return width
+	}
+
+	w := int(v.Operation.Calc(float64(width), v.Rounding.Calc(volatility*v.Rate)))
+	if w < 1 {
+		return 1
+	}
+	return w
+}
+
+// trimmedValues - 大きい方と小さい方からそれぞれtrim個を除いた値の一覧
+// 除きすぎて値がなくなる場合は、中央の値を残す
+func trimmedValues(values []float64, trim int) []float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	if max := (len(sorted) - 1) / 2; trim > max {
+		trim = max
+	}
+	if trim <= 0 {
+		return sorted
+	}
+	return sorted[trim : len(sorted)-trim]
+}
+
+// average - 平均値
+func average(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// standardDeviation - 標準偏差
+func standardDeviation(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	avg := average(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - avg) * (v - avg)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
 // RebalanceStrategy - リバランス戦略
 type RebalanceStrategy struct {
 	Runnable bool        // 実行可能かどうか
@@ -204,6 +623,103 @@ func (v *ExitStrategy) ExecutionType(now time.Time) ExecutionType {
 	return ExecutionTypeUnspecified
 }
 
+// ProtectiveStopStrategy - 保護用の逆指値戦略
+// グリッドの最も外側より先に逆指値のエグジット注文を置いておき、全てのグリッドを飛び越える急変に備える
+// 他のエグジット注文に拘束されていないポジションだけを対象にする
+type ProtectiveStopStrategy struct {
+	Runnable      bool          // 実行可能かどうか
+	ExecutionType ExecutionType // 執行条件 (逆指値(成行)か逆指値(指値))
+	Width         int           // 最も外側のグリッドから発火価格までのティック数
+	LimitWidth    int           // 発火価格から指値までのティック数 (逆指値(指値)のときだけ使う)
+}
+
+// IsRunnable - 保護用の逆指値戦略が実行可能かどうか
+func (v *ProtectiveStopStrategy) IsRunnable() bool {
+	return v.Runnable && v.ExecutionType.IsStop()
+}
+
+// RiskExitStrategy - 損切り・利確戦略
+// 含み損益や現在値が条件に達したら、注文を全て取り消してポジションを全てエグジットし、戦略を一時停止する
+// 各条件は0なら判定しない
+type RiskExitStrategy struct {
+	Runnable     bool    // 実行可能かどうか
+	MaxLoss      float64 // 許容する含み損の額
+	MaxLossRate  float64 // 運用中現金に対する許容する含み損の割合
+	LowerPrice   float64 // 現在値の下限
+	UpperPrice   float64 // 現在値の上限
+	TargetProfit float64 // 利確する含み益の額
+}
+
+// PauseReason - 現在値と含み損益から戦略を一時停止する理由を返す
+// どの条件にも当てはまらなければ未指定を返す
+func (v *RiskExitStrategy) PauseReason(price float64, profit float64, cash float64) PauseReason {
+	if !v.Runnable {
+		return PauseReasonUnspecified
+	}
+
+	switch {
+	case v.MaxLoss > 0 && -profit >= v.MaxLoss:
+		return PauseReasonMaxLoss
+	case v.MaxLossRate > 0 && cash > 0 && -profit >= cash*v.MaxLossRate:
+		return PauseReasonMaxLossRate
+	case v.LowerPrice > 0 && price <= v.LowerPrice:
+		return PauseReasonLowerPrice
+	case v.UpperPrice > 0 && price >= v.UpperPrice:
+		return PauseReasonUpperPrice
+	case v.TargetProfit > 0 && profit >= v.TargetProfit:
+		return PauseReasonTargetProfit
+	}
+	return PauseReasonUnspecified
+}
+
+// RiskLimit - 注文や損失の上限
+// 各上限は0なら判定しない
+type RiskLimit struct {
+	MaxGrossExposure   float64 // 保有ポジションとエントリー注文の約定代金の合計の上限
+	MaxOpenOrders      int     // 注文中の注文数の上限
+	MaxOrdersPerMinute int     // 1分間に送信する注文数の上限
+	MaxDailyLoss       float64 // 当日の確定損益と含み損益の合計で許容する損失の額
+}
+
+// ExceedsGrossExposure - 約定代金の合計が上限を超えるかどうか
+func (v *RiskLimit) ExceedsGrossExposure(exposure float64) bool {
+	return v.MaxGrossExposure > 0 && exposure > v.MaxGrossExposure
+}
+
+// ExceedsOpenOrders - 注文中の注文数がすでに上限に達していて、これ以上注文できないかどうか
+func (v *RiskLimit) ExceedsOpenOrders(count int) bool {
+	return v.MaxOpenOrders > 0 && count >= v.MaxOpenOrders
+}
+
+// ExceedsOrdersPerMinute - 直近1分間の注文数がすでに上限に達していて、これ以上注文できないかどうか
+func (v *RiskLimit) ExceedsOrdersPerMinute(count int) bool {
+	return v.MaxOrdersPerMinute > 0 && count >= v.MaxOrdersPerMinute
+}
+
+// ExceedsDailyLoss - 当日の損益が許容する損失に達したかどうか
+func (v *RiskLimit) ExceedsDailyLoss(profit float64) bool {
+	return v.MaxDailyLoss > 0 && -profit >= v.MaxDailyLoss
+}
+
+// RiskSetting - 再起動しても引き継ぐリスク管理の設定と状態
+type RiskSetting struct {
+	Limit          RiskLimit // 全戦略合計の上限
+	KillSwitch     bool      // キルスイッチが入っているか
+	KillReason     string    // キルスイッチが入った理由
+	KilledDateTime time.Time // キルスイッチが入った日時
+}
+
+// RiskStatus - リスク管理の状態
+type RiskStatus struct {
+	KillSwitch        bool               // キルスイッチが入っているか
+	KillReason        string             // キルスイッチが入った理由
+	KilledDateTime    time.Time          // キルスイッチが入った日時
+	Limit             RiskLimit          // 全戦略合計の上限
+	DailyProfit       float64            // 全戦略合計の当日の確定損益と含み損益
+	StrategyProfits   map[string]float64 // 戦略ごとの当日の確定損益と含み損益
+	EvaluatedDateTime time.Time          // 損益を評価した日時
+}
+
 // CancelStrategy - 全取消戦略
 type CancelStrategy struct {
 	Runnable bool        // 実行可能かどうか
@@ -247,8 +763,217 @@ func (v *TimeRange) In(target time.Time) bool {
 	}
 }
 
+// OrphanOrderStrategy - 孤立注文の処理戦略
+// 証券会社にだけある注文中の注文のうち、銘柄、方向、注文日時、価格が戦略と合うものを孤立注文として処理する
+type OrphanOrderStrategy struct {
+	Policy     OrphanOrderPolicy // 処理方針
+	TimeWindow int               // 注文日時が何分前までの注文を対象にするか, 0なら制限しない
+	PriceRange float64           // 基準価格から指値がどれだけ離れた注文まで対象にするか, 0なら制限しない
+}
+
+// IsTarget - 孤立注文がこの戦略の処理対象になるか
+func (v *OrphanOrderStrategy) IsTarget(basePrice float64, order SecurityOrder, now time.Time) bool {
+	if v.Policy == OrphanOrderPolicyUnspecified {
+		return false
+	}
+	if v.TimeWindow > 0 && order.OrderDateTime.Before(now.Add(-time.Duration(v.TimeWindow)*time.Minute)) {
+		return false
+	}
+	if v.PriceRange > 0 && order.Price > 0 && math.Abs(order.Price-basePrice) > v.PriceRange {
+		return false
+	}
+	return true
+}
+
+// FeeStrategy - 手数料等の費用の設定
+type FeeStrategy struct {
+	CommissionType     CommissionType   // 手数料プラン
+	FlatCommission     float64          // 1約定ごとの手数料(CommissionTypeFlat)
+	DailyTiers         []CommissionTier // 1日の約定代金ごとの手数料(CommissionTypeDailyTiered) 約定代金の上限の昇順
+	CommissionTaxRate  float64          // 手数料にかかる消費税率 (例: 0.1)
+	MarginInterestRate float64          // 信用買建の年率の金利 (例: 0.028)
+	LendingFeeRate     float64          // 信用売建の年率の貸株料 (例: 0.011)
+}
+
+// CommissionTier - 1日の約定代金に応じた手数料の段階
+type CommissionTier struct {
+	Amount     float64 // 1日の約定代金の上限
+	Commission float64 // 1日の約定代金が上限以下のときの1日の手数料
+}
+
+// Commission - その日のこれまでの約定代金がdailyAmountのとき、約定代金amountの約定にかかる税込の手数料
+// 段階制の場合は、約定後の段階の手数料と約定前の段階の手数料の差額になる
+func (v *FeeStrategy) Commission(dailyAmount float64, amount float64) float64 {
+	switch v.CommissionType {
+	case CommissionTypeFlat:
+		return v.withTax(v.FlatCommission)
+	case CommissionTypeDailyTiered:
+		before := 0.0
+		if dailyAmount > 0 {
+			before = v.withTax(v.dailyCommission(dailyAmount))
+		}
+		return v.withTax(v.dailyCommission(dailyAmount+amount)) - before
+	}
+	return 0
+}
+
+// dailyCommission - 1日の約定代金に対する税抜の手数料
+// 全ての段階の上限を超えたら最後の段階の手数料にする
+func (v *FeeStrategy) dailyCommission(dailyAmount float64) float64 {
+	if len(v.DailyTiers) == 0 {
+		return 0
+	}
+	for _, t := range v.DailyTiers {
+		if dailyAmount <= t.Amount {
+			return t.Commission
+		}
+	}
+	return v.DailyTiers[len(v.DailyTiers)-1].Commission
+}
+
+// withTax - 消費税を加えて円未満を切り捨てる
+func (v *FeeStrategy) withTax(commission float64) float64 {
+	return math.Floor(commission * (1 + v.CommissionTaxRate))
+}
+
+// HoldingCost - 信用建玉を返済したときにかかる金利か貸株料の種別と金額
+// 日数は新規約定日から返済約定日までの両端入れで数え、新規約定日が分からなければ1日とする
+func (v *FeeStrategy) HoldingCost(product Product, entrySide Side, amount float64, entryDateTime time.Time, exitDateTime time.Time) (FeeType, float64) {
+	if product != ProductMargin {
+		return FeeTypeUnspecified, 0
+	}
+
+	var feeType FeeType
+	var rate float64
+	switch entrySide {
+	case SideBuy:
+		feeType, rate = FeeTypeMarginInterest, v.MarginInterestRate
+	case SideSell:
+		feeType, rate = FeeTypeLendingFee, v.LendingFeeRate
+	}
+	if rate <= 0 {
+		return FeeTypeUnspecified, 0
+	}
+
+	days := 1
+	if !entryDateTime.IsZero() {
+		entryDate := time.Date(entryDateTime.Year(), entryDateTime.Month(), entryDateTime.Day(), 0, 0, 0, 0, time.UTC)
+		exitDate := time.Date(exitDateTime.Year(), exitDateTime.Month(), exitDateTime.Day(), 0, 0, 0, 0, time.UTC)
+		if d := int(exitDate.Sub(entryDate).Hours()/24) + 1; d > 1 {
+			days = d
+		}
+	}
+
+	return feeType, math.Floor(amount * rate * float64(days) / 365)
+}
+
 // SymbolKey - 一意に特定できる銘柄情報
 type SymbolKey struct {
 	SymbolCode string   // 銘柄コード
 	Exchange   Exchange // 市場
 }
+
+// TradeSummary - 確定した取引の集計
+type TradeSummary struct {
+	StrategyCode string    // 戦略コード
+	Date         time.Time // 日付 日別の集計でなければゼロ値
+	Profit       float64   // 確定損益の合計
+	Quantity     float64   // 数量の合計
+	Count        int       // 取引の件数
+	WinCount     int       // 利益の出た取引の件数
+	LossCount    int       // 損失の出た取引の件数
+	Fee          float64   // 手数料等の費用の合計
+	NetProfit    float64   // 確定損益から費用を引いた損益
+}
+
+// StrategyMetrics - 評価額のスナップショットと取引から計算した戦略の成績
+type StrategyMetrics struct {
+	StrategyCode    string    // 戦略コード
+	From            time.Time // 最初のスナップショットの日付
+	To              time.Time // 最後のスナップショットの日付
+	Days            int       // スナップショットの日数
+	StartEquity     float64   // 最初の評価額
+	EndEquity       float64   // 最後の評価額
+	Return          float64   // 期間の収益率
+	MaxDrawdown     float64   // 評価額の最大値からの最大下落幅
+	MaxDrawdownRate float64   // 最大ドローダウンの下落率
+	Sharpe          float64   // 日次収益率から年率換算したシャープレシオ
+	Sortino         float64   // 日次収益率から年率換算したソルティノレシオ
+	TradeCount      int       // 期間中に確定した取引(グリッドの往復)の件数
+	WinRate         float64   // 利益の出た取引の割合
+	AverageProfit   float64   // 取引1件あたりの平均確定損益
+}
+
+// PositionMismatch - ポジション照合で見つかった不一致
+type PositionMismatch struct {
+	PositionCode     string               // ポジションコード
+	MismatchType     PositionMismatchType // 不一致の種類
+	LocalQuantity    float64              // gridonが持っている保有数量
+	SecurityQuantity float64              // 証券会社が持っている保有数量
+	LocalPrice       float64              // gridonが持っている約定値
+	SecurityPrice    float64              // 証券会社が持っている約定値
+}
+
+// ReconciliationReport - ポジション照合の結果
+type ReconciliationReport struct {
+	StrategyCode string             // 戦略コード
+	DateTime     time.Time          // 照合日時
+	Skipped      bool               // 照合対象外の戦略か
+	Mismatches   []PositionMismatch // 不一致
+	Repaired     bool               // 不一致を証券会社の値で修復したか
+	Error        string             // 照合に失敗したときのエラー
+}
+
+// IsBlocked - 照合に失敗したか未解決の不一致があり、戦略を動かしてはいけないか
+func (v *ReconciliationReport) IsBlocked() bool {
+	return !v.Skipped && (v.Error != "" || len(v.Mismatches) > 0 && !v.Repaired)
+}
+
+// RateLimitMetrics - 流量制限の区分ごとの統計
+type RateLimitMetrics struct {
+	Category      RequestCategory // 流量制限の区分
+	RatePerSecond float64         // 1秒あたりに許可するリクエスト数
+	Requests      int             // リクエスト数
+	Throttled     int             // 流量制限で待たされたリクエスト数
+	Waiting       int             // 現在待っているリクエスト数
+	TotalWaitTime time.Duration   // 待たされた時間の合計
+	MaxWaitTime   time.Duration   // 待たされた時間の最大
+}
+
+// RetryConfig - kabuステーションAPIの参照系リクエストの再試行の設定
+type RetryConfig struct {
+	MaxRetries      int           // 最大再試行回数 (0なら再試行しない)
+	InitialInterval time.Duration // 1回目の再試行までの間隔
+	MaxInterval     time.Duration // 再試行の間隔の上限 (0なら上限なし)
+	Multiplier      float64       // 再試行ごとに間隔にかける倍率 (1未満なら間隔を変えない)
+	Timeout         time.Duration // 1回のリクエストの期限 (0なら期限なし、再試行しない注文系のリクエストにも使う)
+}
+
+// NextInterval - 次の再試行までの間隔
+func (v RetryConfig) NextInterval(interval time.Duration) time.Duration {
+	next := interval
+	if v.Multiplier > 1 {
+		next = time.Duration(float64(interval) * v.Multiplier)
+	}
+	if v.MaxInterval > 0 && next > v.MaxInterval {
+		next = v.MaxInterval
+	}
+	return next
+}
+
+// CircuitBreakerConfig - 戦略ごとのサーキットブレーカーの設定
+type CircuitBreakerConfig struct {
+	Threshold int           // 同じ種類のエラーが連続で何回発生したら開くか (0なら開かない)
+	CoolDown  time.Duration // 開いてから半開にするまでの時間
+}
+
+// CircuitBreakerState - 戦略ごとのサーキットブレーカーの状態
+type CircuitBreakerState struct {
+	StrategyCode   string           // 戦略コード
+	State          CircuitState     // 状態
+	Failures       map[string]int   // 直近に連続しているエラーの種類とその連続失敗回数
+	Operation      CircuitOperation // 直近に失敗した処理
+	Reason         string           // 開いた理由
+	OpenedDateTime time.Time        // 開いた日時
+	HalfOpenAt     time.Time        // 半開にする日時
+}
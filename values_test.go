@@ -107,6 +107,324 @@ func Test_GridStrategy_IsRunnable(t *testing.T) {
 	}
 }
 
+func Test_GridStrategy_geometricRate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		gridStrategy GridStrategy
+		arg1         int
+		want1        float64
+	}{
+		{name: "BaseWidthが0ならWidthRateをそのまま返す",
+			gridStrategy: GridStrategy{BaseWidth: 0, WidthRate: 0.01},
+			arg1:         4,
+			want1:        0.01},
+		{name: "動的なグリッド幅がBaseWidthと同じならWidthRateをそのまま返す",
+			gridStrategy: GridStrategy{BaseWidth: 2, WidthRate: 0.01},
+			arg1:         2,
+			want1:        0.01},
+		{name: "動的なグリッド幅がBaseWidthの2倍ならWidthRateの2倍を返す",
+			gridStrategy: GridStrategy{BaseWidth: 2, WidthRate: 0.01},
+			arg1:         4,
+			want1:        0.02},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.gridStrategy.geometricRate(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_GridStrategy_upperGrid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		gridStrategy GridStrategy
+		want1        GridSideStrategy
+	}{
+		{name: "上側の設定が無効なら共通の設定を返す",
+			gridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4, Upper: GridSideStrategy{Valid: false, Width: 5, NumberOfGrids: 6, Quantity: 7}},
+			want1:        GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 3, Quantity: 4}},
+		{name: "上側の設定が有効なら上側の設定を返す",
+			gridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4, Upper: GridSideStrategy{Valid: true, Width: 5, NumberOfGrids: 6, Quantity: 7}},
+			want1:        GridSideStrategy{Valid: true, Width: 5, NumberOfGrids: 6, Quantity: 7}},
+		{name: "上側の設定が有効なら本数が0でもそのまま返す",
+			gridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4, Upper: GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 0, Quantity: 4}},
+			want1:        GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 0, Quantity: 4}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.gridStrategy.upperGrid()
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_GridStrategy_lowerGrid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		gridStrategy GridStrategy
+		want1        GridSideStrategy
+	}{
+		{name: "下側の設定が無効なら共通の設定を返す",
+			gridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4, Lower: GridSideStrategy{Valid: false, Width: 5, NumberOfGrids: 6, Quantity: 7}},
+			want1:        GridSideStrategy{Valid: true, Width: 2, NumberOfGrids: 3, Quantity: 4}},
+		{name: "下側の設定が有効なら下側の設定を返す",
+			gridStrategy: GridStrategy{BaseWidth: 2, NumberOfGrids: 3, Quantity: 4, Lower: GridSideStrategy{Valid: true, Width: 5, NumberOfGrids: 6, Quantity: 7}},
+			want1:        GridSideStrategy{Valid: true, Width: 5, NumberOfGrids: 6, Quantity: 7}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.gridStrategy.lowerGrid()
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_GridStrategy_sideWidth(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		gridStrategy GridStrategy
+		arg1         int
+		arg2         int
+		want1        int
+	}{
+		{name: "片側のグリッド幅が0なら0",
+			gridStrategy: GridStrategy{BaseWidth: 2},
+			arg1:         4,
+			arg2:         0,
+			want1:        0},
+		{name: "BaseWidthが0なら片側のグリッド幅をそのまま返す",
+			gridStrategy: GridStrategy{BaseWidth: 0},
+			arg1:         4,
+			arg2:         3,
+			want1:        3},
+		{name: "片側のグリッド幅がBaseWidthと同じなら動的なグリッド幅をそのまま返す",
+			gridStrategy: GridStrategy{BaseWidth: 2},
+			arg1:         5,
+			arg2:         2,
+			want1:        5},
+		{name: "片側のグリッド幅がBaseWidthの2倍なら動的なグリッド幅の2倍を返す",
+			gridStrategy: GridStrategy{BaseWidth: 2},
+			arg1:         3,
+			arg2:         4,
+			want1:        6},
+		{name: "計算結果は四捨五入する",
+			gridStrategy: GridStrategy{BaseWidth: 4},
+			arg1:         5,
+			arg2:         3,
+			want1:        4},
+		{name: "計算結果が1未満なら1",
+			gridStrategy: GridStrategy{BaseWidth: 10},
+			arg1:         1,
+			arg2:         1,
+			want1:        1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.gridStrategy.sideWidth(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_InventorySkew_numberOfGrids(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		inventorySkew InventorySkew
+		arg1          int
+		arg2          float64
+		want1         int
+	}{
+		{name: "無効ならそのまま返す",
+			inventorySkew: InventorySkew{Valid: false, StepQuantity: 4, ReduceGrids: 1},
+			arg1:          5,
+			arg2:          8,
+			want1:         5},
+		{name: "StepQuantityが0ならそのまま返す",
+			inventorySkew: InventorySkew{Valid: true, StepQuantity: 0, ReduceGrids: 1},
+			arg1:          5,
+			arg2:          8,
+			want1:         5},
+		{name: "ReduceGridsが0ならそのまま返す",
+			inventorySkew: InventorySkew{Valid: true, StepQuantity: 4, ReduceGrids: 0},
+			arg1:          5,
+			arg2:          8,
+			want1:         5},
+		{name: "保有数量がStepQuantityに届かなければそのまま返す",
+			inventorySkew: InventorySkew{Valid: true, StepQuantity: 4, ReduceGrids: 1},
+			arg1:          5,
+			arg2:          3,
+			want1:         5},
+		{name: "保有数量がStepQuantityの何倍かに応じてReduceGridsずつ減らす",
+			inventorySkew: InventorySkew{Valid: true, StepQuantity: 4, ReduceGrids: 2},
+			arg1:          5,
+			arg2:          9,
+			want1:         1},
+		{name: "減らした結果が最低本数を下回るなら最低本数を返す",
+			inventorySkew: InventorySkew{Valid: true, StepQuantity: 4, ReduceGrids: 1, MinGrids: 2},
+			arg1:          5,
+			arg2:          40,
+			want1:         2},
+		{name: "最低本数が元の本数より多くても元の本数より増やさない",
+			inventorySkew: InventorySkew{Valid: true, StepQuantity: 4, ReduceGrids: 1, MinGrids: 10},
+			arg1:          5,
+			arg2:          40,
+			want1:         5},
+		{name: "減らした結果が0未満なら0を返す",
+			inventorySkew: InventorySkew{Valid: true, StepQuantity: 4, ReduceGrids: 1},
+			arg1:          5,
+			arg2:          40,
+			want1:         0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.inventorySkew.numberOfGrids(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_QuantityProfile_quantity(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		quantityProfile QuantityProfile
+		arg1            float64
+		arg2            int
+		arg3            float64
+		want1           float64
+	}{
+		{name: "未指定なら売買単位に関わらずそのまま返す",
+			quantityProfile: QuantityProfile{Type: QuantityProfileTypeUnspecified, Step: 100},
+			arg1:            150,
+			arg2:            2,
+			arg3:            100,
+			want1:           150},
+		{name: "constantなら売買単位に切り捨てて返す",
+			quantityProfile: QuantityProfile{Type: QuantityProfileTypeConstant},
+			arg1:            150,
+			arg2:            2,
+			arg3:            100,
+			want1:           100},
+		{name: "linearなら外側のグリッドほどStepずつ増やす",
+			quantityProfile: QuantityProfile{Type: QuantityProfileTypeLinear, Step: 100},
+			arg1:            100,
+			arg2:            2,
+			arg3:            100,
+			want1:           300},
+		{name: "linearで基準価格の隣ならそのまま",
+			quantityProfile: QuantityProfile{Type: QuantityProfileTypeLinear, Step: 100},
+			arg1:            100,
+			arg2:            0,
+			arg3:            100,
+			want1:           100},
+		{name: "geometricなら外側のグリッドほどRate倍ずつ増やす",
+			quantityProfile: QuantityProfile{Type: QuantityProfileTypeGeometric, Rate: 2},
+			arg1:            100,
+			arg2:            3,
+			arg3:            100,
+			want1:           800},
+		{name: "geometricで売買単位に満たない端数は切り捨てる",
+			quantityProfile: QuantityProfile{Type: QuantityProfileTypeGeometric, Rate: 1.5},
+			arg1:            100,
+			arg2:            1,
+			arg3:            100,
+			want1:           100},
+		{name: "customならグリッドごとに指定した数量を返す",
+			quantityProfile: QuantityProfile{Type: QuantityProfileTypeCustom, Quantities: []float64{100, 200, 500}},
+			arg1:            100,
+			arg2:            1,
+			arg3:            100,
+			want1:           200},
+		{name: "customで指定した数量が足りなければ最後の数量を返す",
+			quantityProfile: QuantityProfile{Type: QuantityProfileTypeCustom, Quantities: []float64{100, 200, 500}},
+			arg1:            100,
+			arg2:            5,
+			arg3:            100,
+			want1:           500},
+		{name: "customで数量の指定がなければ基準の数量を返す",
+			quantityProfile: QuantityProfile{Type: QuantityProfileTypeCustom},
+			arg1:            100,
+			arg2:            5,
+			arg3:            100,
+			want1:           100},
+		{name: "linearでStepが負で数量が0を下回るなら0",
+			quantityProfile: QuantityProfile{Type: QuantityProfileTypeLinear, Step: -100},
+			arg1:            100,
+			arg2:            3,
+			arg3:            100,
+			want1:           0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.quantityProfile.quantity(test.arg1, test.arg2, test.arg3)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_floorToTradingUnit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		arg1  float64
+		arg2  float64
+		want1 float64
+	}{
+		{name: "数量が0以下なら0", arg1: -100, arg2: 100, want1: 0},
+		{name: "売買単位が0ならそのまま", arg1: 150, arg2: 0, want1: 150},
+		{name: "売買単位の倍数に切り捨てる", arg1: 250, arg2: 100, want1: 200},
+		{name: "売買単位ちょうどならそのまま", arg1: 300, arg2: 100, want1: 300},
+		{name: "浮動小数点の誤差があっても売買単位ちょうどなら切り捨てない", arg1: 0.3, arg2: 0.1, want1: 0.3},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := floorToTradingUnit(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
 func Test_RebalanceStrategy_IsRunnable(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -544,6 +862,128 @@ func Test_DynamicGridMinMax_width(t *testing.T) {
 	}
 }
 
+func Test_DynamicGridVolatility_width(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                  string
+		dynamicGridVolatility DynamicGridVolatility
+		arg1                  int
+		arg2                  float64
+		want1                 int
+	}{
+		{name: "無効ならwidthが返される",
+			dynamicGridVolatility: DynamicGridVolatility{Valid: false, Days: 5, Rate: 1, Rounding: RoundingRound, Operation: OperationOverwrite},
+			arg1:                  2,
+			arg2:                  10,
+			want1:                 2},
+		{name: "日数が0ならwidthが返される",
+			dynamicGridVolatility: DynamicGridVolatility{Valid: true, Days: 0, Rate: 1, Rounding: RoundingRound, Operation: OperationOverwrite},
+			arg1:                  2,
+			arg2:                  10,
+			want1:                 2},
+		{name: "上書きならvolatility * rateを端数処理した値が返される",
+			dynamicGridVolatility: DynamicGridVolatility{Valid: true, Days: 5, Rate: 0.3, Rounding: RoundingRound, Operation: OperationOverwrite},
+			arg1:                  2,
+			arg2:                  12.5,
+			want1:                 4},
+		{name: "加算ならwidth + volatility * rateを端数処理した値が返される",
+			dynamicGridVolatility: DynamicGridVolatility{Valid: true, Days: 5, Rate: 0.3, Rounding: RoundingFloor, Operation: OperationPlus},
+			arg1:                  2,
+			arg2:                  12.5,
+			want1:                 5},
+		{name: "計算結果が1未満なら1が返される",
+			dynamicGridVolatility: DynamicGridVolatility{Valid: true, Days: 5, Rate: 0.1, Rounding: RoundingFloor, Operation: OperationOverwrite},
+			arg1:                  2,
+			arg2:                  5,
+			want1:                 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.dynamicGridVolatility.width(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_trimmedValues(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		arg1  []float64
+		arg2  int
+		want1 []float64
+	}{
+		{name: "trimが0なら並べ替えただけの値が返される", arg1: []float64{3, 1, 2}, arg2: 0, want1: []float64{1, 2, 3}},
+		{name: "大きい方と小さい方からtrim個ずつ除かれる", arg1: []float64{5, 1, 4, 2, 3}, arg2: 1, want1: []float64{2, 3, 4}},
+		{name: "除きすぎる場合は中央の値が残される", arg1: []float64{5, 1, 4, 2, 3}, arg2: 3, want1: []float64{3}},
+		{name: "偶数個で除きすぎる場合は中央の2つが残される", arg1: []float64{1, 2, 3, 4}, arg2: 3, want1: []float64{2, 3}},
+		{name: "3つの値から1つずつ除かれると中央の値が残される", arg1: []float64{3, 1, 2}, arg2: 1, want1: []float64{2}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := trimmedValues(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_average(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		arg1  []float64
+		want1 float64
+	}{
+		{name: "値がなければ0", arg1: []float64{}, want1: 0},
+		{name: "平均値が返される", arg1: []float64{1, 2, 3, 6}, want1: 3},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := average(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_standardDeviation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		arg1  []float64
+		want1 float64
+	}{
+		{name: "値がなければ0", arg1: []float64{}, want1: 0},
+		{name: "すべて同じ値なら0", arg1: []float64{3, 3, 3}, want1: 0},
+		{name: "標準偏差が返される", arg1: []float64{2, 4, 4, 4, 5, 5, 7, 9}, want1: 2},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := standardDeviation(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
 func Test_DynamicGridPrevDay_width(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -606,3 +1046,667 @@ func Test_DynamicGridPrevDay_width(t *testing.T) {
 		})
 	}
 }
+
+func Test_FeeStrategy_Commission(t *testing.T) {
+	t.Parallel()
+	tiers := []CommissionTier{{Amount: 1_000_000, Commission: 0}, {Amount: 2_000_000, Commission: 1_000}, {Amount: 3_000_000, Commission: 2_000}}
+	tests := []struct {
+		name        string
+		feeStrategy FeeStrategy
+		arg1        float64
+		arg2        float64
+		want1       float64
+	}{
+		{name: "手数料プランがなければ0", feeStrategy: FeeStrategy{FlatCommission: 100}, arg1: 0, arg2: 100_000, want1: 0},
+		{name: "定額なら約定代金に関係なく定額に消費税を加えて切り捨てる",
+			feeStrategy: FeeStrategy{CommissionType: CommissionTypeFlat, FlatCommission: 55, CommissionTaxRate: 0.1},
+			arg1:        500_000,
+			arg2:        100_000,
+			want1:       60},
+		{name: "段階制で段階が変わらなければ0",
+			feeStrategy: FeeStrategy{CommissionType: CommissionTypeDailyTiered, DailyTiers: tiers, CommissionTaxRate: 0.1},
+			arg1:        1_200_000,
+			arg2:        100_000,
+			want1:       0},
+		{name: "段階制でその日の最初の約定なら約定後の段階の手数料",
+			feeStrategy: FeeStrategy{CommissionType: CommissionTypeDailyTiered, DailyTiers: tiers, CommissionTaxRate: 0.1},
+			arg1:        0,
+			arg2:        1_500_000,
+			want1:       1_100},
+		{name: "段階制で段階が上がったら差額",
+			feeStrategy: FeeStrategy{CommissionType: CommissionTypeDailyTiered, DailyTiers: tiers, CommissionTaxRate: 0.1},
+			arg1:        1_500_000,
+			arg2:        1_000_000,
+			want1:       1_100},
+		{name: "段階制で全ての段階を超えたら最後の段階の手数料",
+			feeStrategy: FeeStrategy{CommissionType: CommissionTypeDailyTiered, DailyTiers: tiers},
+			arg1:        1_500_000,
+			arg2:        5_000_000,
+			want1:       1_000},
+		{name: "段階制で段階がなければ0",
+			feeStrategy: FeeStrategy{CommissionType: CommissionTypeDailyTiered},
+			arg1:        0,
+			arg2:        1_000_000,
+			want1:       0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.feeStrategy.Commission(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_FeeStrategy_HoldingCost(t *testing.T) {
+	t.Parallel()
+	feeStrategy := FeeStrategy{MarginInterestRate: 0.0365, LendingFeeRate: 0.073}
+	tests := []struct {
+		name        string
+		feeStrategy FeeStrategy
+		arg1        Product
+		arg2        Side
+		arg3        float64
+		arg4        time.Time
+		arg5        time.Time
+		want1       FeeType
+		want2       float64
+	}{
+		{name: "現物なら費用はかからない",
+			feeStrategy: feeStrategy,
+			arg1:        ProductStock,
+			arg2:        SideBuy,
+			arg3:        1_000_000,
+			arg4:        time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local),
+			arg5:        time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local),
+			want1:       FeeTypeUnspecified,
+			want2:       0},
+		{name: "信用買建で金利が0なら費用はかからない",
+			feeStrategy: FeeStrategy{LendingFeeRate: 0.011},
+			arg1:        ProductMargin,
+			arg2:        SideBuy,
+			arg3:        1_000_000,
+			arg4:        time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local),
+			arg5:        time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local),
+			want1:       FeeTypeUnspecified,
+			want2:       0},
+		{name: "信用買建の日計りなら1日分の金利",
+			feeStrategy: feeStrategy,
+			arg1:        ProductMargin,
+			arg2:        SideBuy,
+			arg3:        1_000_000,
+			arg4:        time.Date(2022, 1, 24, 9, 0, 0, 0, time.Local),
+			arg5:        time.Date(2022, 1, 24, 14, 0, 0, 0, time.Local),
+			want1:       FeeTypeMarginInterest,
+			want2:       100},
+		{name: "信用売建で日をまたいだら両端入れの日数分の貸株料",
+			feeStrategy: feeStrategy,
+			arg1:        ProductMargin,
+			arg2:        SideSell,
+			arg3:        1_000_000,
+			arg4:        time.Date(2022, 1, 24, 14, 0, 0, 0, time.Local),
+			arg5:        time.Date(2022, 1, 26, 9, 0, 0, 0, time.Local),
+			want1:       FeeTypeLendingFee,
+			want2:       600},
+		{name: "新規約定日時が分からなければ1日分",
+			feeStrategy: feeStrategy,
+			arg1:        ProductMargin,
+			arg2:        SideSell,
+			arg3:        1_000_000,
+			arg4:        time.Time{},
+			arg5:        time.Date(2022, 1, 26, 9, 0, 0, 0, time.Local),
+			want1:       FeeTypeLendingFee,
+			want2:       200},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1, got2 := test.feeStrategy.HoldingCost(test.arg1, test.arg2, test.arg3, test.arg4, test.arg5)
+			if !reflect.DeepEqual(test.want1, got1) || !reflect.DeepEqual(test.want2, got2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_OrphanOrderStrategy_IsTarget(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 1, 25, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name                string
+		orphanOrderStrategy OrphanOrderStrategy
+		arg1                float64
+		arg2                SecurityOrder
+		want1               bool
+	}{
+		{name: "処理方針が未指定なら対象外",
+			orphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyUnspecified},
+			arg1:                2000,
+			arg2:                SecurityOrder{Price: 2000, OrderDateTime: now},
+			want1:               false},
+		{name: "制限なしなら対象",
+			orphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyAdopt},
+			arg1:                2000,
+			arg2:                SecurityOrder{Price: 1000, OrderDateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local)},
+			want1:               true},
+		{name: "注文日時が時間幅より前なら対象外",
+			orphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyCancel, TimeWindow: 30},
+			arg1:                2000,
+			arg2:                SecurityOrder{Price: 2000, OrderDateTime: time.Date(2022, 1, 25, 9, 29, 59, 0, time.Local)},
+			want1:               false},
+		{name: "注文日時が時間幅ちょうどなら対象",
+			orphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyCancel, TimeWindow: 30},
+			arg1:                2000,
+			arg2:                SecurityOrder{Price: 2000, OrderDateTime: time.Date(2022, 1, 25, 9, 30, 0, 0, time.Local)},
+			want1:               true},
+		{name: "指値が基準価格から価格幅より離れていたら対象外",
+			orphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyAdopt, PriceRange: 10},
+			arg1:                2000,
+			arg2:                SecurityOrder{Price: 2011, OrderDateTime: now},
+			want1:               false},
+		{name: "指値が基準価格から価格幅ちょうどなら対象",
+			orphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyAdopt, PriceRange: 10},
+			arg1:                2000,
+			arg2:                SecurityOrder{Price: 1990, OrderDateTime: now},
+			want1:               true},
+		{name: "成行なら価格幅によらず対象",
+			orphanOrderStrategy: OrphanOrderStrategy{Policy: OrphanOrderPolicyAdopt, PriceRange: 10},
+			arg1:                2000,
+			arg2:                SecurityOrder{Price: 0, OrderDateTime: now},
+			want1:               true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.orphanOrderStrategy.IsTarget(test.arg1, test.arg2, now)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_RetryConfig_NextInterval(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		retryConfig RetryConfig
+		arg1        time.Duration
+		want1       time.Duration
+	}{
+		{name: "倍率が1未満なら間隔を変えない", retryConfig: RetryConfig{Multiplier: 0}, arg1: 100 * time.Millisecond, want1: 100 * time.Millisecond},
+		{name: "倍率をかけた間隔を返す", retryConfig: RetryConfig{Multiplier: 2}, arg1: 100 * time.Millisecond, want1: 200 * time.Millisecond},
+		{name: "上限を超えたら上限を返す", retryConfig: RetryConfig{Multiplier: 2, MaxInterval: 150 * time.Millisecond}, arg1: 100 * time.Millisecond, want1: 150 * time.Millisecond},
+		{name: "上限が0なら上限なし", retryConfig: RetryConfig{Multiplier: 3, MaxInterval: 0}, arg1: time.Second, want1: 3 * time.Second},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.retryConfig.NextInterval(test.arg1)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_ProtectiveStopStrategy_IsRunnable(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                   string
+		protectiveStopStrategy ProtectiveStopStrategy
+		want1                  bool
+	}{
+		{name: "実行不可ならfalse", protectiveStopStrategy: ProtectiveStopStrategy{Runnable: false, ExecutionType: ExecutionTypeStopMarket}, want1: false},
+		{name: "実行可能でも執行条件が逆指値でなければfalse", protectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeLimit}, want1: false},
+		{name: "実行可能で執行条件が逆指値(成行)ならtrue", protectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopMarket}, want1: true},
+		{name: "実行可能で執行条件が逆指値(指値)ならtrue", protectiveStopStrategy: ProtectiveStopStrategy{Runnable: true, ExecutionType: ExecutionTypeStopLimit}, want1: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.protectiveStopStrategy.IsRunnable()
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_RiskExitStrategy_PauseReason(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		riskExitStrategy RiskExitStrategy
+		arg1             float64
+		arg2             float64
+		arg3             float64
+		want1            PauseReason
+	}{
+		{name: "実行不可なら条件に達していても未指定",
+			riskExitStrategy: RiskExitStrategy{Runnable: false, MaxLoss: 100},
+			arg1:             2100,
+			arg2:             -200,
+			arg3:             100_000,
+			want1:            PauseReasonUnspecified},
+		{name: "含み損が上限額に達したら上限額",
+			riskExitStrategy: RiskExitStrategy{Runnable: true, MaxLoss: 100},
+			arg1:             2100,
+			arg2:             -100,
+			arg3:             100_000,
+			want1:            PauseReasonMaxLoss},
+		{name: "含み損が上限額未満なら未指定",
+			riskExitStrategy: RiskExitStrategy{Runnable: true, MaxLoss: 100},
+			arg1:             2100,
+			arg2:             -99,
+			arg3:             100_000,
+			want1:            PauseReasonUnspecified},
+		{name: "含み損が運用中現金に対する上限割合に達したら上限割合",
+			riskExitStrategy: RiskExitStrategy{Runnable: true, MaxLossRate: 0.01},
+			arg1:             2100,
+			arg2:             -1_000,
+			arg3:             100_000,
+			want1:            PauseReasonMaxLossRate},
+		{name: "運用中現金がなければ上限割合は判定しない",
+			riskExitStrategy: RiskExitStrategy{Runnable: true, MaxLossRate: 0.01},
+			arg1:             2100,
+			arg2:             -1_000,
+			arg3:             0,
+			want1:            PauseReasonUnspecified},
+		{name: "現在値が下限以下なら下限",
+			riskExitStrategy: RiskExitStrategy{Runnable: true, LowerPrice: 2000, UpperPrice: 2200},
+			arg1:             2000,
+			arg3:             100_000,
+			want1:            PauseReasonLowerPrice},
+		{name: "現在値が上限以上なら上限",
+			riskExitStrategy: RiskExitStrategy{Runnable: true, LowerPrice: 2000, UpperPrice: 2200},
+			arg1:             2200,
+			arg3:             100_000,
+			want1:            PauseReasonUpperPrice},
+		{name: "含み益が利確額に達したら利確",
+			riskExitStrategy: RiskExitStrategy{Runnable: true, TargetProfit: 500},
+			arg1:             2100,
+			arg2:             500,
+			arg3:             100_000,
+			want1:            PauseReasonTargetProfit},
+		{name: "条件が全て0なら何も判定しない",
+			riskExitStrategy: RiskExitStrategy{Runnable: true},
+			arg1:             2100,
+			arg2:             -100_000,
+			arg3:             100_000,
+			want1:            PauseReasonUnspecified},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1 := test.riskExitStrategy.PauseReason(test.arg1, test.arg2, test.arg3)
+			if !reflect.DeepEqual(test.want1, got1) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want1, got1)
+			}
+		})
+	}
+}
+
+func Test_RiskLimit_ExceedsGrossExposure(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		riskLimit RiskLimit
+		arg       float64
+		want      bool
+	}{
+		{name: "上限が0なら判定しない", riskLimit: RiskLimit{}, arg: 1_000_000, want: false},
+		{name: "上限と同じならfalse", riskLimit: RiskLimit{MaxGrossExposure: 1_000_000}, arg: 1_000_000, want: false},
+		{name: "上限を超えたらtrue", riskLimit: RiskLimit{MaxGrossExposure: 1_000_000}, arg: 1_000_001, want: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.riskLimit.ExceedsGrossExposure(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_RiskLimit_ExceedsOpenOrders(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		riskLimit RiskLimit
+		arg       int
+		want      bool
+	}{
+		{name: "上限が0なら判定しない", riskLimit: RiskLimit{}, arg: 100, want: false},
+		{name: "上限未満ならfalse", riskLimit: RiskLimit{MaxOpenOrders: 10}, arg: 9, want: false},
+		{name: "上限に達していたらtrue", riskLimit: RiskLimit{MaxOpenOrders: 10}, arg: 10, want: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.riskLimit.ExceedsOpenOrders(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_RiskLimit_ExceedsOrdersPerMinute(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		riskLimit RiskLimit
+		arg       int
+		want      bool
+	}{
+		{name: "上限が0なら判定しない", riskLimit: RiskLimit{}, arg: 100, want: false},
+		{name: "上限未満ならfalse", riskLimit: RiskLimit{MaxOrdersPerMinute: 5}, arg: 4, want: false},
+		{name: "上限に達していたらtrue", riskLimit: RiskLimit{MaxOrdersPerMinute: 5}, arg: 5, want: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.riskLimit.ExceedsOrdersPerMinute(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_RiskLimit_ExceedsDailyLoss(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		riskLimit RiskLimit
+		arg       float64
+		want      bool
+	}{
+		{name: "上限が0なら判定しない", riskLimit: RiskLimit{}, arg: -100_000, want: false},
+		{name: "損失が上限未満ならfalse", riskLimit: RiskLimit{MaxDailyLoss: 10_000}, arg: -9_999, want: false},
+		{name: "損失が上限に達したらtrue", riskLimit: RiskLimit{MaxDailyLoss: 10_000}, arg: -10_000, want: true},
+		{name: "利益が出ていればfalse", riskLimit: RiskLimit{MaxDailyLoss: 10_000}, arg: 10_000, want: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.riskLimit.ExceedsDailyLoss(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_PriceBand_bounds(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		priceBand PriceBand
+		arg       float64
+		want1     float64
+		want2     float64
+	}{
+		{name: "基準が未指定なら絶対価格として扱う", priceBand: PriceBand{Lower: 900, Upper: 1100}, arg: 2000, want1: 900, want2: 1100},
+		{name: "絶対価格ならそのまま返す", priceBand: PriceBand{Source: PriceBandSourceAbsolute, Lower: 900, Upper: 1100}, arg: 2000, want1: 900, want2: 1100},
+		{name: "前日終値基準なら基準価格からの割合で返す", priceBand: PriceBand{Source: PriceBandSourcePrevClose, Lower: 0.1, Upper: 0.05}, arg: 2000, want1: 1800, want2: 2100},
+		{name: "移動平均基準なら基準価格からの割合で返す", priceBand: PriceBand{Source: PriceBandSourceMovingAverage, Lower: 0.5, Upper: 0.5}, arg: 2000, want1: 1000, want2: 3000},
+		{name: "割合が0なら、その側は制限なし", priceBand: PriceBand{Source: PriceBandSourcePrevClose, Lower: 0, Upper: 0.05}, arg: 2000, want1: 0, want2: 2100},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got1, got2 := test.priceBand.bounds(test.arg)
+			if !reflect.DeepEqual(test.want1, got1) || !reflect.DeepEqual(test.want2, got2) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.want1, test.want2, got1, got2)
+			}
+		})
+	}
+}
+
+func Test_PriceBandState_In(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		state PriceBandState
+		arg   float64
+		want  bool
+	}{
+		{name: "上下限がなければtrue", state: PriceBandState{}, arg: 1000, want: true},
+		{name: "上下限の間ならtrue", state: PriceBandState{Lower: 900, Upper: 1100}, arg: 1000, want: true},
+		{name: "下限ちょうどならtrue", state: PriceBandState{Lower: 900, Upper: 1100}, arg: 900, want: true},
+		{name: "上限ちょうどならtrue", state: PriceBandState{Lower: 900, Upper: 1100}, arg: 1100, want: true},
+		{name: "下限未満ならfalse", state: PriceBandState{Lower: 900, Upper: 1100}, arg: 899, want: false},
+		{name: "上限超ならfalse", state: PriceBandState{Lower: 900, Upper: 1100}, arg: 1101, want: false},
+		{name: "下限がなければ、上限以下ならいくら安くてもtrue", state: PriceBandState{Upper: 1100}, arg: 1, want: true},
+		{name: "上限がなければ、下限以上ならいくら高くてもtrue", state: PriceBandState{Lower: 900}, arg: 100000, want: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.state.In(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_dailyPriceLimit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		arg  float64
+		want PriceLimit
+	}{
+		{name: "基準値段がなければ制限なし", arg: 0, want: PriceLimit{}},
+		{name: "100円未満なら30円", arg: 99, want: PriceLimit{Lower: 69, Upper: 129}},
+		{name: "100円なら50円", arg: 100, want: PriceLimit{Lower: 50, Upper: 150}},
+		{name: "2,000円未満なら400円", arg: 1_999, want: PriceLimit{Lower: 1_599, Upper: 2_399}},
+		{name: "2,000円なら500円", arg: 2_000, want: PriceLimit{Lower: 1_500, Upper: 2_500}},
+		{name: "10,000円なら3,000円", arg: 10_000, want: PriceLimit{Lower: 7_000, Upper: 13_000}},
+		{name: "50,000,000円以上なら10,000,000円", arg: 50_000_000, want: PriceLimit{Lower: 40_000_000, Upper: 60_000_000}},
+		{name: "下限は1円より下にならない", arg: 20, want: PriceLimit{Lower: 1, Upper: 50}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := dailyPriceLimit(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_PriceLimit_In(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		priceLimit PriceLimit
+		arg        float64
+		want       bool
+	}{
+		{name: "制限がなければtrue", priceLimit: PriceLimit{}, arg: 1000, want: true},
+		{name: "範囲内ならtrue", priceLimit: PriceLimit{Lower: 1600, Upper: 2400}, arg: 2000, want: true},
+		{name: "値幅下限ちょうどならtrue", priceLimit: PriceLimit{Lower: 1600, Upper: 2400}, arg: 1600, want: true},
+		{name: "値幅上限ちょうどならtrue", priceLimit: PriceLimit{Lower: 1600, Upper: 2400}, arg: 2400, want: true},
+		{name: "値幅下限未満ならfalse", priceLimit: PriceLimit{Lower: 1600, Upper: 2400}, arg: 1599, want: false},
+		{name: "値幅上限超ならfalse", priceLimit: PriceLimit{Lower: 1600, Upper: 2400}, arg: 2401, want: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.priceLimit.In(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_PriceLimit_Clamp(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		priceLimit PriceLimit
+		arg        float64
+		want       float64
+	}{
+		{name: "制限がなければそのまま", priceLimit: PriceLimit{}, arg: 1000, want: 1000},
+		{name: "範囲内ならそのまま", priceLimit: PriceLimit{Lower: 1600, Upper: 2400}, arg: 2000, want: 2000},
+		{name: "値幅下限未満なら値幅下限", priceLimit: PriceLimit{Lower: 1600, Upper: 2400}, arg: 1500, want: 1600},
+		{name: "値幅上限超なら値幅上限", priceLimit: PriceLimit{Lower: 1600, Upper: 2400}, arg: 2500, want: 2400},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.priceLimit.Clamp(test.arg)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_BasePriceSource_IsLastContract(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		basePriceSource BasePriceSource
+		want            bool
+	}{
+		{name: "未指定ならtrue", basePriceSource: BasePriceSource{Type: BasePriceSourceTypeUnspecified}, want: true},
+		{name: "最終約定価格ならtrue", basePriceSource: BasePriceSource{Type: BasePriceSourceTypeLastContract}, want: true},
+		{name: "VWAPならfalse", basePriceSource: BasePriceSource{Type: BasePriceSourceTypeVWAP}, want: false},
+		{name: "移動平均ならfalse", basePriceSource: BasePriceSource{Type: BasePriceSourceTypeMovingAverage}, want: false},
+		{name: "始値ならfalse", basePriceSource: BasePriceSource{Type: BasePriceSourceTypeOpen}, want: false},
+		{name: "仲値ならfalse", basePriceSource: BasePriceSource{Type: BasePriceSourceTypeMid}, want: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.basePriceSource.IsLastContract()
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_TrendFilter_IsStopEntry(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		trendFilter TrendFilter
+		want        bool
+	}{
+		{name: "未指定ならtrue", trendFilter: TrendFilter{Action: TrendFilterActionUnspecified}, want: true},
+		{name: "エントリー停止ならtrue", trendFilter: TrendFilter{Action: TrendFilterActionStopEntry}, want: true},
+		{name: "エントリー削減ならfalse", trendFilter: TrendFilter{Action: TrendFilterActionReduceEntry}, want: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.trendFilter.IsStopEntry()
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_TrendFilter_active(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		trendFilter TrendFilter
+		arg1        Side
+		arg2        float64
+		want        bool
+	}{
+		{name: "買いエントリーで下にしきい値を超えていればtrue", trendFilter: TrendFilter{Threshold: 0.1}, arg1: SideBuy, arg2: -0.11, want: true},
+		{name: "買いエントリーで下にしきい値ちょうどならfalse", trendFilter: TrendFilter{Threshold: 0.1}, arg1: SideBuy, arg2: -0.1, want: false},
+		{name: "買いエントリーで上にしきい値を超えていてもfalse", trendFilter: TrendFilter{Threshold: 0.1}, arg1: SideBuy, arg2: 0.11, want: false},
+		{name: "売りエントリーで上にしきい値を超えていればtrue", trendFilter: TrendFilter{Threshold: 0.1}, arg1: SideSell, arg2: 0.11, want: true},
+		{name: "売りエントリーで上にしきい値ちょうどならfalse", trendFilter: TrendFilter{Threshold: 0.1}, arg1: SideSell, arg2: 0.1, want: false},
+		{name: "売りエントリーで下にしきい値を超えていてもfalse", trendFilter: TrendFilter{Threshold: 0.1}, arg1: SideSell, arg2: -0.11, want: false},
+		{name: "エントリー方向が未指定ならfalse", trendFilter: TrendFilter{Threshold: 0.1}, arg1: SideUnspecified, arg2: -0.11, want: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.trendFilter.active(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
+
+func Test_TrendFilter_entryQuantity(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		trendFilter TrendFilter
+		arg1        float64
+		arg2        float64
+		want        float64
+	}{
+		{name: "数量に割合をかける", trendFilter: TrendFilter{QuantityRate: 0.5}, arg1: 4, arg2: 1, want: 2},
+		{name: "売買単位未満は切り捨てる", trendFilter: TrendFilter{QuantityRate: 0.5}, arg1: 300, arg2: 100, want: 100},
+		{name: "売買単位に満たなければ0", trendFilter: TrendFilter{QuantityRate: 0.2}, arg1: 400, arg2: 100, want: 0},
+		{name: "割合が0なら0", trendFilter: TrendFilter{}, arg1: 4, arg2: 1, want: 0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := test.trendFilter.entryQuantity(test.arg1, test.arg2)
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), test.want, got)
+			}
+		})
+	}
+}
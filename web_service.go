@@ -5,15 +5,22 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"time"
 )
 
 // NewWebService - 新しいWebサービスの取得
-func NewWebService(port string, strategyStore IStrategyStore, kabusAPI IKabusAPI) IWebService {
+func NewWebService(port string, strategyStore IStrategyStore, kabusAPI IKabusAPI, tradeStore ITradeStore, metricsService IMetricsService, reconciliationService IReconciliationService, rateLimiter IRateLimiter, riskManager IRiskManager, circuitBreaker ICircuitBreaker) IWebService {
 	return &webService{
-		port:          port,
-		strategyStore: strategyStore,
-		kabusAPI:      kabusAPI,
-		routes:        map[string]map[string]http.Handler{},
+		port:                  port,
+		strategyStore:         strategyStore,
+		kabusAPI:              kabusAPI,
+		tradeStore:            tradeStore,
+		metricsService:        metricsService,
+		reconciliationService: reconciliationService,
+		rateLimiter:           rateLimiter,
+		riskManager:           riskManager,
+		circuitBreaker:        circuitBreaker,
+		routes:                map[string]map[string]http.Handler{},
 	}
 }
 
@@ -24,10 +31,16 @@ type IWebService interface {
 
 // webService - Webサービス
 type webService struct {
-	port          string
-	strategyStore IStrategyStore
-	kabusAPI      IKabusAPI
-	routes        map[string]map[string]http.Handler
+	port                  string
+	strategyStore         IStrategyStore
+	kabusAPI              IKabusAPI
+	tradeStore            ITradeStore
+	metricsService        IMetricsService
+	reconciliationService IReconciliationService
+	rateLimiter           IRateLimiter
+	riskManager           IRiskManager
+	circuitBreaker        ICircuitBreaker
+	routes                map[string]map[string]http.Handler
 }
 
 // StartWebServer - Webサービスの開始
@@ -46,6 +59,40 @@ func (s *webService) StartWebServer() error {
 			"GET":  http.HandlerFunc(s.getStrategies),
 			"POST": http.HandlerFunc(s.postSaveStrategy),
 		},
+		"/api/trades": {
+			"GET": http.HandlerFunc(s.getTrades),
+		},
+		"/api/trades/strategies": {
+			"GET": http.HandlerFunc(s.getStrategyTradeSummaries),
+		},
+		"/api/trades/daily": {
+			"GET": http.HandlerFunc(s.getDailyTradeSummaries),
+		},
+		"/api/metrics": {
+			"GET": http.HandlerFunc(s.getMetrics),
+		},
+		"/api/reconciliations": {
+			"GET":  http.HandlerFunc(s.getReconciliations),
+			"POST": http.HandlerFunc(s.postReconcile),
+		},
+		"/api/rate-limits": {
+			"GET": http.HandlerFunc(s.getRateLimits),
+		},
+		"/api/risk": {
+			"GET": http.HandlerFunc(s.getRiskStatus),
+		},
+		"/api/risk/kill-switch": {
+			"POST": http.HandlerFunc(s.postKillSwitch),
+		},
+		"/api/risk/limit": {
+			"POST": http.HandlerFunc(s.postRiskLimit),
+		},
+		"/api/circuit-breakers": {
+			"GET": http.HandlerFunc(s.getCircuitBreakers),
+		},
+		"/api/price-bands": {
+			"GET": http.HandlerFunc(s.getPriceBands),
+		},
 	}
 
 	return http.Serve(ln, s)
@@ -141,3 +188,218 @@ func (s *webService) postSaveStrategy(w http.ResponseWriter, req *http.Request)
 
 	_ = json.NewEncoder(w).Encode(strategy)
 }
+
+// getTrades - 戦略を指定して取引一覧の取得
+func (s *webService) getTrades(w http.ResponseWriter, req *http.Request) {
+	from, to, err := s.parseDateRange(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trades, err := s.tradeStore.GetByStrategyCode(req.FormValue("code"), from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(trades)
+}
+
+// getStrategyTradeSummaries - 戦略ごとの損益集計の取得
+func (s *webService) getStrategyTradeSummaries(w http.ResponseWriter, req *http.Request) {
+	from, to, err := s.parseDateRange(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	summaries, err := s.tradeStore.GetStrategySummaries(from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(summaries)
+}
+
+// getDailyTradeSummaries - 戦略を指定して日ごとの損益集計の取得
+func (s *webService) getDailyTradeSummaries(w http.ResponseWriter, req *http.Request) {
+	from, to, err := s.parseDateRange(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	summaries, err := s.tradeStore.GetDailySummaries(req.FormValue("code"), from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(summaries)
+}
+
+// getMetrics - 戦略の成績の取得
+// 戦略コードの指定がなければ全ての戦略の成績を返す
+func (s *webService) getMetrics(w http.ResponseWriter, req *http.Request) {
+	from, to, err := s.parseDateRange(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	codes := make([]string, 0)
+	if code := req.FormValue("code"); code != "" {
+		codes = append(codes, code)
+	} else {
+		strategies, err := s.strategyStore.GetStrategies()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, strategy := range strategies {
+			codes = append(codes, strategy.Code)
+		}
+	}
+
+	metrics := make([]*StrategyMetrics, 0)
+	for _, code := range codes {
+		m, err := s.metricsService.GetMetrics(code, from, to)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		metrics = append(metrics, m)
+	}
+
+	_ = json.NewEncoder(w).Encode(metrics)
+}
+
+// getReconciliations - 戦略ごとの最後のポジション照合結果の取得
+func (s *webService) getReconciliations(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(s.reconciliationService.GetReports())
+}
+
+// postReconcile - 戦略のポジション照合の実行
+// repair=trueを指定すると、不一致のあったポジションを証券会社の値で訂正して戦略の停止を解除する
+func (s *webService) postReconcile(w http.ResponseWriter, req *http.Request) {
+	strategy, err := s.strategyStore.GetByCode(req.FormValue("code"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := s.reconciliationService.Reconcile(strategy, req.FormValue("repair") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// parseDateRange - リクエストのfrom, to(yyyy-mm-dd)から期間を作る
+// toはその日を含むように翌日の0時を返し、指定がなければ期間の制限をしない
+func (s *webService) parseDateRange(req *http.Request) (time.Time, time.Time, error) {
+	from := time.Time{}
+	to := time.Date(9999, 12, 31, 0, 0, 0, 0, time.Local)
+
+	if v := req.FormValue("from"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = d
+	}
+	if v := req.FormValue("to"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = d.AddDate(0, 0, 1)
+	}
+
+	return from, to, nil
+}
+
+// getRateLimits - kabuステーションAPIの流量制限の統計の取得
+func (s *webService) getRateLimits(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(s.rateLimiter.GetMetrics())
+}
+
+// getRiskStatus - リスク管理の状態の取得
+func (s *webService) getRiskStatus(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(s.riskManager.GetStatus())
+}
+
+// postKillSwitch - キルスイッチの切り替え
+// on=trueでキルスイッチを入れ、on=falseで切る
+func (s *webService) postKillSwitch(w http.ResponseWriter, req *http.Request) {
+	var on bool
+	switch req.FormValue("on") {
+	case "true":
+		on = true
+	case "false":
+		on = false
+	default:
+		http.Error(w, "on must be true or false", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.riskManager.SetKillSwitch(on, req.FormValue("reason")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(s.riskManager.GetStatus())
+}
+
+// postRiskLimit - 全戦略合計の上限の設定
+func (s *webService) postRiskLimit(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	limit := RiskLimit{}
+	if err := json.NewDecoder(req.Body).Decode(&limit); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.riskManager.SetLimit(limit); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(s.riskManager.GetStatus())
+}
+
+// getCircuitBreakers - 戦略ごとのサーキットブレーカーの状態の取得
+func (s *webService) getCircuitBreakers(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(s.circuitBreaker.GetStates())
+}
+
+// getPriceBands - 価格帯が有効な戦略ごとの価格帯の状態の取得
+// outOfBand=trueを指定すると、価格帯の外にある戦略だけを返す
+func (s *webService) getPriceBands(w http.ResponseWriter, req *http.Request) {
+	strategies, err := s.strategyStore.GetStrategies()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	onlyOutOfBand := req.FormValue("outOfBand") == "true"
+	reports := make([]PriceBandReport, 0)
+	for _, strategy := range strategies {
+		if !strategy.GridStrategy.PriceBand.Valid {
+			continue
+		}
+		if onlyOutOfBand && !strategy.PriceBandState.OutOfBand {
+			continue
+		}
+		reports = append(reports, PriceBandReport{
+			StrategyCode: strategy.Code,
+			PriceBand:    strategy.GridStrategy.PriceBand,
+			State:        strategy.PriceBandState,
+		})
+	}
+
+	_ = json.NewEncoder(w).Encode(reports)
+}
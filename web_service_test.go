@@ -28,13 +28,25 @@ func Test_NewWebService(t *testing.T) {
 	t.Parallel()
 	strategyStore := &testStrategyStore{}
 	kabusAPI := &testKabusAPI{}
+	tradeStore := &testTradeStore{}
+	metricsService := &testMetricsService{}
+	reconciliationService := &testReconciliationService{}
+	rateLimiter := &testRateLimiter{}
+	riskManager := &testRiskManager{}
+	circuitBreaker := &testCircuitBreaker{}
 	want1 := &webService{
-		port:          ":18083",
-		strategyStore: strategyStore,
-		kabusAPI:      kabusAPI,
-		routes:        map[string]map[string]http.Handler{},
+		port:                  ":18083",
+		strategyStore:         strategyStore,
+		kabusAPI:              kabusAPI,
+		tradeStore:            tradeStore,
+		metricsService:        metricsService,
+		reconciliationService: reconciliationService,
+		rateLimiter:           rateLimiter,
+		riskManager:           riskManager,
+		circuitBreaker:        circuitBreaker,
+		routes:                map[string]map[string]http.Handler{},
 	}
-	got1 := NewWebService(":18083", strategyStore, kabusAPI)
+	got1 := NewWebService(":18083", strategyStore, kabusAPI, tradeStore, metricsService, reconciliationService, rateLimiter, riskManager, circuitBreaker)
 	if !reflect.DeepEqual(want1, got1) {
 		t.Errorf("%s error\nwant: %+v\ngot: %+v\n", t.Name(), want1, got1)
 	}
@@ -241,7 +253,7 @@ func Test_webService_getStrategies(t *testing.T) {
 				},
 			}},
 			wantStatusCode: 200,
-			wantBody:       `[{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"topix100","TradingUnit":1,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"Quantity":1,"BaseWidth":12,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"DynamicGridPrevDay":{"Valid":false,"Rate":0,"NumberOfGrids":0,"Rounding":"","Operation":""},"DynamicGridMinMax":{"Valid":false,"Divide":0,"Rounding":"","Operation":""}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"Account":{"Password":"Password1234","AccountType":"specific"},"Runnable":true},{"Code":"1458-sell","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"sell","Cash":885680,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"topix100","TradingUnit":1,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"Quantity":1,"BaseWidth":12,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"DynamicGridPrevDay":{"Valid":true,"Rate":0.8,"NumberOfGrids":6,"Rounding":"round","Operation":""},"DynamicGridMinMax":{"Valid":true,"Divide":5,"Rounding":"ceil","Operation":"+"}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"Account":{"Password":"Password1234","AccountType":"specific"},"Runnable":true}]`},
+			wantBody:       `[{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"topix100","TradingUnit":1,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"Quantity":1,"BaseWidth":12,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"DynamicGridPrevDay":{"Valid":false,"Rate":0,"NumberOfGrids":0,"Rounding":"","Operation":""},"DynamicGridMinMax":{"Valid":false,"Divide":0,"Rounding":"","Operation":""},"DynamicGridVolatility":{"Valid":false,"Type":"","Days":0,"Trim":0,"Rate":0,"Rounding":"","Operation":""},"Spacing":"","WidthRate":0,"Upper":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"Lower":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"InventorySkew":{"Valid":false,"StepQuantity":0,"ReduceGrids":0,"MinGrids":0},"QuantityProfile":{"Type":"","Step":0,"Rate":0,"Quantities":null},"PriceBand":{"Valid":false,"Source":"","Upper":0,"Lower":0,"Days":0,"Policy":""},"BasePriceSource":{"Type":"","Days":0,"Interval":0},"TrendFilter":{"Valid":false,"Type":"","Days":0,"Threshold":0,"Action":"","QuantityRate":0}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"ProtectiveStopStrategy":{"Runnable":false,"ExecutionType":"","Width":0,"LimitWidth":0},"RiskExitStrategy":{"Runnable":false,"MaxLoss":0,"MaxLossRate":0,"LowerPrice":0,"UpperPrice":0,"TargetProfit":0},"RiskLimit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":0},"FeeStrategy":{"CommissionType":"","FlatCommission":0,"DailyTiers":null,"CommissionTaxRate":0,"MarginInterestRate":0,"LendingFeeRate":0},"OrphanOrderStrategy":{"Policy":"","TimeWindow":0,"PriceRange":0},"OrderExpireDay":"","Account":{"Password":"Password1234","AccountType":"specific","DeliveryType":"","FundType":""},"PaperTrading":false,"Runnable":true,"PauseReason":"","PausedDateTime":"0001-01-01T00:00:00Z","PriceBandState":{"Lower":0,"Upper":0,"OutOfBand":false,"ChangedDateTime":"0001-01-01T00:00:00Z"},"TrendFilterState":{"Active":false,"Value":0,"ChangedDateTime":"0001-01-01T00:00:00Z"}},{"Code":"1458-sell","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"sell","Cash":885680,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"topix100","TradingUnit":1,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"Quantity":1,"BaseWidth":12,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"DynamicGridPrevDay":{"Valid":true,"Rate":0.8,"NumberOfGrids":6,"Rounding":"round","Operation":""},"DynamicGridMinMax":{"Valid":true,"Divide":5,"Rounding":"ceil","Operation":"+"},"DynamicGridVolatility":{"Valid":false,"Type":"","Days":0,"Trim":0,"Rate":0,"Rounding":"","Operation":""},"Spacing":"","WidthRate":0,"Upper":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"Lower":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"InventorySkew":{"Valid":false,"StepQuantity":0,"ReduceGrids":0,"MinGrids":0},"QuantityProfile":{"Type":"","Step":0,"Rate":0,"Quantities":null},"PriceBand":{"Valid":false,"Source":"","Upper":0,"Lower":0,"Days":0,"Policy":""},"BasePriceSource":{"Type":"","Days":0,"Interval":0},"TrendFilter":{"Valid":false,"Type":"","Days":0,"Threshold":0,"Action":"","QuantityRate":0}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"ProtectiveStopStrategy":{"Runnable":false,"ExecutionType":"","Width":0,"LimitWidth":0},"RiskExitStrategy":{"Runnable":false,"MaxLoss":0,"MaxLossRate":0,"LowerPrice":0,"UpperPrice":0,"TargetProfit":0},"RiskLimit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":0},"FeeStrategy":{"CommissionType":"","FlatCommission":0,"DailyTiers":null,"CommissionTaxRate":0,"MarginInterestRate":0,"LendingFeeRate":0},"OrphanOrderStrategy":{"Policy":"","TimeWindow":0,"PriceRange":0},"OrderExpireDay":"","Account":{"Password":"Password1234","AccountType":"specific","DeliveryType":"","FundType":""},"PaperTrading":false,"Runnable":true,"PauseReason":"","PausedDateTime":"0001-01-01T00:00:00Z","PriceBandState":{"Lower":0,"Upper":0,"OutOfBand":false,"ChangedDateTime":"0001-01-01T00:00:00Z"},"TrendFilterState":{"Active":false,"Value":0,"ChangedDateTime":"0001-01-01T00:00:00Z"}}]`},
 	}
 
 	for _, test := range tests {
@@ -308,14 +320,14 @@ func Test_webService_postSaveStrategy(t *testing.T) {
 		{name: "銘柄情報取得に失敗したらエラー",
 			strategyStore:        &testStrategyStore{},
 			kabusAPI:             &testKabusAPI{GetSymbol2: ErrUnknown},
-			body:                 `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","TickGroup":"topix100","RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"BaseWidth":12,"Quantity":1,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}]},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"Account":{"Password":"Password1234","AccountType":"specific"}}`,
+			body:                 `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","TickGroup":"topix100","RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"BaseWidth":12,"Quantity":1,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}]},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"ProtectiveStopStrategy":{"Runnable":false,"ExecutionType":"","Width":0,"LimitWidth":0},"RiskExitStrategy":{"Runnable":false,"MaxLoss":0,"MaxLossRate":0,"LowerPrice":0,"UpperPrice":0,"TargetProfit":0},"RiskLimit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":0},"FeeStrategy":{"CommissionType":"","FlatCommission":0,"DailyTiers":null,"CommissionTaxRate":0,"MarginInterestRate":0,"LendingFeeRate":0},"OrphanOrderStrategy":{"Policy":"","TimeWindow":0,"PriceRange":0},"OrderExpireDay":"","Account":{"Password":"Password1234","AccountType":"specific","DeliveryType":"","FundType":""}}`,
 			wantStatusCode:       http.StatusInternalServerError,
 			wantBody:             `unknown`,
 			wantGetSymbolHistory: []interface{}{"1458", ExchangeToushou}},
 		{name: "saveに失敗したらエラー",
 			strategyStore:        &testStrategyStore{Save1: ErrUnknown},
 			kabusAPI:             &testKabusAPI{GetSymbol1: &Symbol{Code: "1458", Exchange: ExchangeToushou, TradingUnit: 1, TickGroup: TickGroupTopix100}},
-			body:                 `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"BaseWidth":12,"Quantity":1,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}]},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"Account":{"Password":"Password1234","AccountType":"specific"},"Runnable":true}`,
+			body:                 `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"BaseWidth":12,"Quantity":1,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}]},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"ProtectiveStopStrategy":{"Runnable":false,"ExecutionType":"","Width":0,"LimitWidth":0},"RiskExitStrategy":{"Runnable":false,"MaxLoss":0,"MaxLossRate":0,"LowerPrice":0,"UpperPrice":0,"TargetProfit":0},"RiskLimit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":0},"FeeStrategy":{"CommissionType":"","FlatCommission":0,"DailyTiers":null,"CommissionTaxRate":0,"MarginInterestRate":0,"LendingFeeRate":0},"OrphanOrderStrategy":{"Policy":"","TimeWindow":0,"PriceRange":0},"OrderExpireDay":"","Account":{"Password":"Password1234","AccountType":"specific","DeliveryType":"","FundType":""},"Runnable":true}`,
 			wantStatusCode:       http.StatusInternalServerError,
 			wantBody:             `unknown`,
 			wantGetSymbolHistory: []interface{}{"1458", ExchangeToushou},
@@ -370,9 +382,9 @@ func Test_webService_postSaveStrategy(t *testing.T) {
 		{name: "saveに成功したら保存したstrategyを返す",
 			strategyStore:        &testStrategyStore{},
 			kabusAPI:             &testKabusAPI{GetSymbol1: &Symbol{Code: "1458", Exchange: ExchangeToushou, TradingUnit: 1, TickGroup: TickGroupTopix100}},
-			body:                 `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"BaseWidth":12,"Quantity":1,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"GridType":"min_max","DynamicGridMinMax":{"Divide":5,"Rounding":"ceil","Operation":"+"}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"Account":{"Password":"Password1234","AccountType":"specific"},"Runnable":true}`,
+			body:                 `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"BaseWidth":12,"Quantity":1,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"GridType":"min_max","DynamicGridMinMax":{"Divide":5,"Rounding":"ceil","Operation":"+"}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"ProtectiveStopStrategy":{"Runnable":false,"ExecutionType":"","Width":0,"LimitWidth":0},"RiskExitStrategy":{"Runnable":false,"MaxLoss":0,"MaxLossRate":0,"LowerPrice":0,"UpperPrice":0,"TargetProfit":0},"RiskLimit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":0},"FeeStrategy":{"CommissionType":"","FlatCommission":0,"DailyTiers":null,"CommissionTaxRate":0,"MarginInterestRate":0,"LendingFeeRate":0},"OrphanOrderStrategy":{"Policy":"","TimeWindow":0,"PriceRange":0},"OrderExpireDay":"","Account":{"Password":"Password1234","AccountType":"specific","DeliveryType":"","FundType":""},"Runnable":true}`,
 			wantStatusCode:       http.StatusOK,
-			wantBody:             `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"topix100","TradingUnit":1,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"Quantity":1,"BaseWidth":12,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"DynamicGridPrevDay":{"Valid":false,"Rate":0,"NumberOfGrids":0,"Rounding":"","Operation":""},"DynamicGridMinMax":{"Valid":false,"Divide":5,"Rounding":"ceil","Operation":"+"}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"Account":{"Password":"Password1234","AccountType":"specific"},"Runnable":true}`,
+			wantBody:             `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"topix100","TradingUnit":1,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"Quantity":1,"BaseWidth":12,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"DynamicGridPrevDay":{"Valid":false,"Rate":0,"NumberOfGrids":0,"Rounding":"","Operation":""},"DynamicGridMinMax":{"Valid":false,"Divide":5,"Rounding":"ceil","Operation":"+"},"DynamicGridVolatility":{"Valid":false,"Type":"","Days":0,"Trim":0,"Rate":0,"Rounding":"","Operation":""},"Spacing":"","WidthRate":0,"Upper":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"Lower":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"InventorySkew":{"Valid":false,"StepQuantity":0,"ReduceGrids":0,"MinGrids":0},"QuantityProfile":{"Type":"","Step":0,"Rate":0,"Quantities":null},"PriceBand":{"Valid":false,"Source":"","Upper":0,"Lower":0,"Days":0,"Policy":""},"BasePriceSource":{"Type":"","Days":0,"Interval":0},"TrendFilter":{"Valid":false,"Type":"","Days":0,"Threshold":0,"Action":"","QuantityRate":0}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"ProtectiveStopStrategy":{"Runnable":false,"ExecutionType":"","Width":0,"LimitWidth":0},"RiskExitStrategy":{"Runnable":false,"MaxLoss":0,"MaxLossRate":0,"LowerPrice":0,"UpperPrice":0,"TargetProfit":0},"RiskLimit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":0},"FeeStrategy":{"CommissionType":"","FlatCommission":0,"DailyTiers":null,"CommissionTaxRate":0,"MarginInterestRate":0,"LendingFeeRate":0},"OrphanOrderStrategy":{"Policy":"","TimeWindow":0,"PriceRange":0},"OrderExpireDay":"","Account":{"Password":"Password1234","AccountType":"specific","DeliveryType":"","FundType":""},"PaperTrading":false,"Runnable":true,"PauseReason":"","PausedDateTime":"0001-01-01T00:00:00Z","PriceBandState":{"Lower":0,"Upper":0,"OutOfBand":false,"ChangedDateTime":"0001-01-01T00:00:00Z"},"TrendFilterState":{"Active":false,"Value":0,"ChangedDateTime":"0001-01-01T00:00:00Z"}}`,
 			wantGetSymbolHistory: []interface{}{"1458", ExchangeToushou},
 			wantSaveStrategyHistory: []interface{}{&Strategy{
 				Code:                 "1458-buy",
@@ -431,9 +443,9 @@ func Test_webService_postSaveStrategy(t *testing.T) {
 		{name: "rebalance戦略のsaveに成功したら保存したstrategyを返す",
 			strategyStore:        &testStrategyStore{},
 			kabusAPI:             &testKabusAPI{GetSymbol1: &Symbol{Code: "1458", Exchange: ExchangeToushou, TradingUnit: 1, TickGroup: TickGroupOther}},
-			body:                 `{"Code":"1475-rebalance","SymbolCode":"1475","Exchange":"toushou","Product":"stock","EntrySide":"buy","Cash":75056,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"Account":{"Password":"Password1234","AccountType":"specific"},"Runnable":true}`,
+			body:                 `{"Code":"1475-rebalance","SymbolCode":"1475","Exchange":"toushou","Product":"stock","EntrySide":"buy","Cash":75056,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"OrderExpireDay":"","Account":{"Password":"Password1234","AccountType":"specific","DeliveryType":"","FundType":""},"Runnable":true}`,
 			wantStatusCode:       http.StatusOK,
-			wantBody:             `{"Code":"1475-rebalance","SymbolCode":"1475","Exchange":"toushou","Product":"stock","MarginTradeType":"","EntrySide":"buy","Cash":75056,"BasePrice":0,"BasePriceDateTime":"0001-01-01T00:00:00Z","LastContractPrice":0,"LastContractDateTime":"0001-01-01T00:00:00Z","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"other","TradingUnit":1,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":false,"Quantity":0,"BaseWidth":0,"NumberOfGrids":0,"TimeRanges":null,"DynamicGridPrevDay":{"Valid":false,"Rate":0,"NumberOfGrids":0,"Rounding":"","Operation":""},"DynamicGridMinMax":{"Valid":false,"Divide":0,"Rounding":"","Operation":""}},"CancelStrategy":{"Runnable":false,"Timings":null},"ExitStrategy":{"Runnable":false,"Conditions":null},"Account":{"Password":"Password1234","AccountType":"specific"},"Runnable":true}`,
+			wantBody:             `{"Code":"1475-rebalance","SymbolCode":"1475","Exchange":"toushou","Product":"stock","MarginTradeType":"","EntrySide":"buy","Cash":75056,"BasePrice":0,"BasePriceDateTime":"0001-01-01T00:00:00Z","LastContractPrice":0,"LastContractDateTime":"0001-01-01T00:00:00Z","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"other","TradingUnit":1,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":false,"Quantity":0,"BaseWidth":0,"NumberOfGrids":0,"TimeRanges":null,"DynamicGridPrevDay":{"Valid":false,"Rate":0,"NumberOfGrids":0,"Rounding":"","Operation":""},"DynamicGridMinMax":{"Valid":false,"Divide":0,"Rounding":"","Operation":""},"DynamicGridVolatility":{"Valid":false,"Type":"","Days":0,"Trim":0,"Rate":0,"Rounding":"","Operation":""},"Spacing":"","WidthRate":0,"Upper":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"Lower":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"InventorySkew":{"Valid":false,"StepQuantity":0,"ReduceGrids":0,"MinGrids":0},"QuantityProfile":{"Type":"","Step":0,"Rate":0,"Quantities":null},"PriceBand":{"Valid":false,"Source":"","Upper":0,"Lower":0,"Days":0,"Policy":""},"BasePriceSource":{"Type":"","Days":0,"Interval":0},"TrendFilter":{"Valid":false,"Type":"","Days":0,"Threshold":0,"Action":"","QuantityRate":0}},"CancelStrategy":{"Runnable":false,"Timings":null},"ExitStrategy":{"Runnable":false,"Conditions":null},"ProtectiveStopStrategy":{"Runnable":false,"ExecutionType":"","Width":0,"LimitWidth":0},"RiskExitStrategy":{"Runnable":false,"MaxLoss":0,"MaxLossRate":0,"LowerPrice":0,"UpperPrice":0,"TargetProfit":0},"RiskLimit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":0},"FeeStrategy":{"CommissionType":"","FlatCommission":0,"DailyTiers":null,"CommissionTaxRate":0,"MarginInterestRate":0,"LendingFeeRate":0},"OrphanOrderStrategy":{"Policy":"","TimeWindow":0,"PriceRange":0},"OrderExpireDay":"","Account":{"Password":"Password1234","AccountType":"specific","DeliveryType":"","FundType":""},"PaperTrading":false,"Runnable":true,"PauseReason":"","PausedDateTime":"0001-01-01T00:00:00Z","PriceBandState":{"Lower":0,"Upper":0,"OutOfBand":false,"ChangedDateTime":"0001-01-01T00:00:00Z"},"TrendFilterState":{"Active":false,"Value":0,"ChangedDateTime":"0001-01-01T00:00:00Z"}}`,
 			wantGetSymbolHistory: []interface{}{"1475", ExchangeToushou},
 			wantSaveStrategyHistory: []interface{}{&Strategy{
 				Code:        "1475-rebalance",
@@ -571,7 +583,7 @@ func Test_webService_getStrategy(t *testing.T) {
 			}},
 			params:               "?code=1458-buy",
 			wantStatusCode:       http.StatusOK,
-			wantBody:             `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"topix100","TradingUnit":1,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"Quantity":1,"BaseWidth":12,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"DynamicGridPrevDay":{"Valid":false,"Rate":0,"NumberOfGrids":0,"Rounding":"","Operation":""},"DynamicGridMinMax":{"Valid":true,"Divide":5,"Rounding":"ceil","Operation":"+"}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"Account":{"Password":"Password1234","AccountType":"specific"},"Runnable":true}`,
+			wantBody:             `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"topix100","TradingUnit":1,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"Quantity":1,"BaseWidth":12,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"DynamicGridPrevDay":{"Valid":false,"Rate":0,"NumberOfGrids":0,"Rounding":"","Operation":""},"DynamicGridMinMax":{"Valid":true,"Divide":5,"Rounding":"ceil","Operation":"+"},"DynamicGridVolatility":{"Valid":false,"Type":"","Days":0,"Trim":0,"Rate":0,"Rounding":"","Operation":""},"Spacing":"","WidthRate":0,"Upper":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"Lower":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"InventorySkew":{"Valid":false,"StepQuantity":0,"ReduceGrids":0,"MinGrids":0},"QuantityProfile":{"Type":"","Step":0,"Rate":0,"Quantities":null},"PriceBand":{"Valid":false,"Source":"","Upper":0,"Lower":0,"Days":0,"Policy":""},"BasePriceSource":{"Type":"","Days":0,"Interval":0},"TrendFilter":{"Valid":false,"Type":"","Days":0,"Threshold":0,"Action":"","QuantityRate":0}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"ProtectiveStopStrategy":{"Runnable":false,"ExecutionType":"","Width":0,"LimitWidth":0},"RiskExitStrategy":{"Runnable":false,"MaxLoss":0,"MaxLossRate":0,"LowerPrice":0,"UpperPrice":0,"TargetProfit":0},"RiskLimit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":0},"FeeStrategy":{"CommissionType":"","FlatCommission":0,"DailyTiers":null,"CommissionTaxRate":0,"MarginInterestRate":0,"LendingFeeRate":0},"OrphanOrderStrategy":{"Policy":"","TimeWindow":0,"PriceRange":0},"OrderExpireDay":"","Account":{"Password":"Password1234","AccountType":"specific","DeliveryType":"","FundType":""},"PaperTrading":false,"Runnable":true,"PauseReason":"","PausedDateTime":"0001-01-01T00:00:00Z","PriceBandState":{"Lower":0,"Upper":0,"OutOfBand":false,"ChangedDateTime":"0001-01-01T00:00:00Z"},"TrendFilterState":{"Active":false,"Value":0,"ChangedDateTime":"0001-01-01T00:00:00Z"}}`,
 			wantGetByCodeHistory: []interface{}{"1458-buy"}},
 	}
 
@@ -750,7 +762,7 @@ func Test_webService_deleteStrategy(t *testing.T) {
 				DeleteByCode1: nil},
 			params:                  "?code=1458-buy",
 			wantStatusCode:          http.StatusOK,
-			wantBody:                `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"topix100","TradingUnit":0,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"Quantity":1,"BaseWidth":12,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"DynamicGridPrevDay":{"Valid":false,"Rate":0,"NumberOfGrids":0,"Rounding":"","Operation":""},"DynamicGridMinMax":{"Valid":false,"Divide":5,"Rounding":"ceil","Operation":"+"}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"Account":{"Password":"Password1234","AccountType":"specific"},"Runnable":true}`,
+			wantBody:                `{"Code":"1458-buy","SymbolCode":"1458","Exchange":"toushou","Product":"margin","MarginTradeType":"day","EntrySide":"buy","Cash":858010,"BasePrice":17995,"BasePriceDateTime":"2021-12-17T15:00:00+09:00","LastContractPrice":17995,"LastContractDateTime":"2021-12-17T15:00:00+09:00","MaxContractPrice":0,"MaxContractDateTime":"0001-01-01T00:00:00Z","MinContractPrice":0,"MinContractDateTime":"0001-01-01T00:00:00Z","TickGroup":"topix100","TradingUnit":0,"RebalanceStrategy":{"Runnable":true,"Timings":["0000-01-01T08:59:00+09:00","0000-01-01T12:29:00+09:00"]},"GridStrategy":{"Runnable":true,"Quantity":1,"BaseWidth":12,"NumberOfGrids":3,"TimeRanges":[{"Start":"0000-01-01T09:00:00+09:00","End":"0000-01-01T11:28:00+09:00"},{"Start":"0000-01-01T12:30:00+09:00","End":"0000-01-01T14:58:00+09:00"}],"DynamicGridPrevDay":{"Valid":false,"Rate":0,"NumberOfGrids":0,"Rounding":"","Operation":""},"DynamicGridMinMax":{"Valid":false,"Divide":5,"Rounding":"ceil","Operation":"+"},"DynamicGridVolatility":{"Valid":false,"Type":"","Days":0,"Trim":0,"Rate":0,"Rounding":"","Operation":""},"Spacing":"","WidthRate":0,"Upper":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"Lower":{"Valid":false,"Width":0,"NumberOfGrids":0,"Quantity":0},"InventorySkew":{"Valid":false,"StepQuantity":0,"ReduceGrids":0,"MinGrids":0},"QuantityProfile":{"Type":"","Step":0,"Rate":0,"Quantities":null},"PriceBand":{"Valid":false,"Source":"","Upper":0,"Lower":0,"Days":0,"Policy":""},"BasePriceSource":{"Type":"","Days":0,"Interval":0},"TrendFilter":{"Valid":false,"Type":"","Days":0,"Threshold":0,"Action":"","QuantityRate":0}},"CancelStrategy":{"Runnable":true,"Timings":["0000-01-01T11:28:00+09:00","0000-01-01T14:58:00+09:00"]},"ExitStrategy":{"Runnable":true,"Conditions":[{"ExecutionType":"market_morning_close","Timing":"0000-01-01T11:29:00+09:00"},{"ExecutionType":"market_afternoon_close","Timing":"0000-01-01T14:59:00+09:00"}]},"ProtectiveStopStrategy":{"Runnable":false,"ExecutionType":"","Width":0,"LimitWidth":0},"RiskExitStrategy":{"Runnable":false,"MaxLoss":0,"MaxLossRate":0,"LowerPrice":0,"UpperPrice":0,"TargetProfit":0},"RiskLimit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":0},"FeeStrategy":{"CommissionType":"","FlatCommission":0,"DailyTiers":null,"CommissionTaxRate":0,"MarginInterestRate":0,"LendingFeeRate":0},"OrphanOrderStrategy":{"Policy":"","TimeWindow":0,"PriceRange":0},"OrderExpireDay":"","Account":{"Password":"Password1234","AccountType":"specific","DeliveryType":"","FundType":""},"PaperTrading":false,"Runnable":true,"PauseReason":"","PausedDateTime":"0001-01-01T00:00:00Z","PriceBandState":{"Lower":0,"Upper":0,"OutOfBand":false,"ChangedDateTime":"0001-01-01T00:00:00Z"},"TrendFilterState":{"Active":false,"Value":0,"ChangedDateTime":"0001-01-01T00:00:00Z"}}`,
 			wantGetByCodeHistory:    []interface{}{"1458-buy"},
 			wantDeleteByCodeHistory: []interface{}{"1458-buy"}},
 	}
@@ -792,3 +804,625 @@ func Test_webService_deleteStrategy(t *testing.T) {
 		})
 	}
 }
+
+func Test_webService_getTrades(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                         string
+		tradeStore                   *testTradeStore
+		params                       string
+		wantStatusCode               int
+		wantBody                     string
+		wantGetByStrategyCodeHistory []interface{}
+	}{
+		{name: "日付が読めなければエラー",
+			tradeStore:                   &testTradeStore{},
+			params:                       "?code=1458-buy&from=2022/01/24",
+			wantStatusCode:               http.StatusBadRequest,
+			wantBody:                     `parsing time "2022/01/24" as "2006-01-02": cannot parse "/01/24" as "-"`,
+			wantGetByStrategyCodeHistory: nil},
+		{name: "storeがエラーを返したらエラー",
+			tradeStore:                   &testTradeStore{GetByStrategyCode2: ErrUnknown},
+			params:                       "?code=1458-buy&from=2022-01-24&to=2022-01-25",
+			wantStatusCode:               http.StatusInternalServerError,
+			wantBody:                     ErrUnknown.Error(),
+			wantGetByStrategyCodeHistory: []interface{}{"1458-buy", time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), time.Date(2022, 1, 26, 0, 0, 0, 0, time.Local)},
+		},
+		{name: "期間の指定がなければ制限なしで取引の一覧を返す",
+			tradeStore: &testTradeStore{GetByStrategyCode1: []*Trade{
+				{Code: "contract-001-position-001", StrategyCode: "1458-buy", Side: SideBuy, EntryPrice: 17995, ExitPrice: 18005, ExitDateTime: time.Date(2022, 1, 24, 10, 0, 0, 0, time.Local), Quantity: 1, Profit: 10},
+			}},
+			params:                       "?code=1458-buy",
+			wantStatusCode:               http.StatusOK,
+			wantBody:                     `[{"Code":"contract-001-position-001","StrategyCode":"1458-buy","SymbolCode":"","Exchange":"","Product":"","Side":"buy","EntryPositionCode":"","EntryPrice":17995,"EntryDateTime":"0001-01-01T00:00:00Z","ExitOrderCode":"","ExitPrice":18005,"ExitDateTime":"2022-01-24T10:00:00+09:00","Quantity":1,"Profit":10}]`,
+			wantGetByStrategyCodeHistory: []interface{}{"1458-buy", time.Time{}, time.Date(9999, 12, 31, 0, 0, 0, 0, time.Local)},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &webService{tradeStore: test.tradeStore}
+			ts := httptest.NewServer(http.HandlerFunc(service.getTrades))
+			defer ts.Close()
+
+			res, err := http.Get(fmt.Sprintf("%s%s", ts.URL, test.params))
+			if err != nil {
+				t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("%s read body error\nerr: %+v\n", t.Name(), err)
+			}
+			strBody := strings.Trim(string(body), "\n")
+
+			if !reflect.DeepEqual(test.wantStatusCode, res.StatusCode) ||
+				!reflect.DeepEqual(test.wantBody, strBody) ||
+				!reflect.DeepEqual(test.wantGetByStrategyCodeHistory, test.tradeStore.GetByStrategyCodeHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %v\ngot: %+v, %+v, %v\n", t.Name(),
+					test.wantStatusCode, test.wantBody, test.wantGetByStrategyCodeHistory,
+					res.StatusCode, strBody, test.tradeStore.GetByStrategyCodeHistory)
+			}
+		})
+	}
+}
+
+func Test_webService_getStrategyTradeSummaries(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                            string
+		tradeStore                      *testTradeStore
+		params                          string
+		wantStatusCode                  int
+		wantBody                        string
+		wantGetStrategySummariesHistory []interface{}
+	}{
+		{name: "storeがエラーを返したらエラー",
+			tradeStore:                      &testTradeStore{GetStrategySummaries2: ErrUnknown},
+			params:                          "?to=2022-01-24",
+			wantStatusCode:                  http.StatusInternalServerError,
+			wantBody:                        ErrUnknown.Error(),
+			wantGetStrategySummariesHistory: []interface{}{time.Time{}, time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local)}},
+		{name: "戦略ごとの集計を返す",
+			tradeStore: &testTradeStore{GetStrategySummaries1: []*TradeSummary{
+				{StrategyCode: "1458-buy", Profit: 15, Quantity: 3, Count: 3, WinCount: 2, LossCount: 1},
+			}},
+			params:                          "?from=2022-01-24&to=2022-01-24",
+			wantStatusCode:                  http.StatusOK,
+			wantBody:                        `[{"StrategyCode":"1458-buy","Date":"0001-01-01T00:00:00Z","Profit":15,"Quantity":3,"Count":3,"WinCount":2,"LossCount":1,"Fee":0,"NetProfit":0}]`,
+			wantGetStrategySummariesHistory: []interface{}{time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local)}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &webService{tradeStore: test.tradeStore}
+			ts := httptest.NewServer(http.HandlerFunc(service.getStrategyTradeSummaries))
+			defer ts.Close()
+
+			res, err := http.Get(fmt.Sprintf("%s%s", ts.URL, test.params))
+			if err != nil {
+				t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("%s read body error\nerr: %+v\n", t.Name(), err)
+			}
+			strBody := strings.Trim(string(body), "\n")
+
+			if !reflect.DeepEqual(test.wantStatusCode, res.StatusCode) ||
+				!reflect.DeepEqual(test.wantBody, strBody) ||
+				!reflect.DeepEqual(test.wantGetStrategySummariesHistory, test.tradeStore.GetStrategySummariesHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %v\ngot: %+v, %+v, %v\n", t.Name(),
+					test.wantStatusCode, test.wantBody, test.wantGetStrategySummariesHistory,
+					res.StatusCode, strBody, test.tradeStore.GetStrategySummariesHistory)
+			}
+		})
+	}
+}
+
+func Test_webService_getDailyTradeSummaries(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                         string
+		tradeStore                   *testTradeStore
+		params                       string
+		wantStatusCode               int
+		wantBody                     string
+		wantGetDailySummariesHistory []interface{}
+	}{
+		{name: "日付が読めなければエラー",
+			tradeStore:                   &testTradeStore{},
+			params:                       "?code=1458-buy&to=20220124",
+			wantStatusCode:               http.StatusBadRequest,
+			wantBody:                     `parsing time "20220124" as "2006-01-02": cannot parse "0124" as "-"`,
+			wantGetDailySummariesHistory: nil},
+		{name: "日ごとの集計を返す",
+			tradeStore: &testTradeStore{GetDailySummaries1: []*TradeSummary{
+				{StrategyCode: "1458-buy", Date: time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), Profit: 15, Quantity: 3, Count: 3, WinCount: 2, LossCount: 1},
+			}},
+			params:                       "?code=1458-buy&from=2022-01-24",
+			wantStatusCode:               http.StatusOK,
+			wantBody:                     `[{"StrategyCode":"1458-buy","Date":"2022-01-24T00:00:00+09:00","Profit":15,"Quantity":3,"Count":3,"WinCount":2,"LossCount":1,"Fee":0,"NetProfit":0}]`,
+			wantGetDailySummariesHistory: []interface{}{"1458-buy", time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), time.Date(9999, 12, 31, 0, 0, 0, 0, time.Local)}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &webService{tradeStore: test.tradeStore}
+			ts := httptest.NewServer(http.HandlerFunc(service.getDailyTradeSummaries))
+			defer ts.Close()
+
+			res, err := http.Get(fmt.Sprintf("%s%s", ts.URL, test.params))
+			if err != nil {
+				t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("%s read body error\nerr: %+v\n", t.Name(), err)
+			}
+			strBody := strings.Trim(string(body), "\n")
+
+			if !reflect.DeepEqual(test.wantStatusCode, res.StatusCode) ||
+				!reflect.DeepEqual(test.wantBody, strBody) ||
+				!reflect.DeepEqual(test.wantGetDailySummariesHistory, test.tradeStore.GetDailySummariesHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %v\ngot: %+v, %+v, %v\n", t.Name(),
+					test.wantStatusCode, test.wantBody, test.wantGetDailySummariesHistory,
+					res.StatusCode, strBody, test.tradeStore.GetDailySummariesHistory)
+			}
+		})
+	}
+}
+
+func Test_webService_getMetrics(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                  string
+		strategyStore         *testStrategyStore
+		metricsService        *testMetricsService
+		params                string
+		wantStatusCode        int
+		wantBody              string
+		wantGetMetricsHistory []interface{}
+	}{
+		{name: "日付が読めなければエラー",
+			strategyStore:  &testStrategyStore{},
+			metricsService: &testMetricsService{},
+			params:         "?from=2022-1-24",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `parsing time "2022-1-24" as "2006-01-02": cannot parse "1-24" as "01"`},
+		{name: "戦略コードがなければ戦略一覧を取得し、取得に失敗したらエラー",
+			strategyStore:  &testStrategyStore{GetStrategies2: ErrUnknown},
+			metricsService: &testMetricsService{},
+			params:         "",
+			wantStatusCode: http.StatusInternalServerError,
+			wantBody:       ErrUnknown.Error()},
+		{name: "成績の計算に失敗したらエラー",
+			strategyStore:         &testStrategyStore{},
+			metricsService:        &testMetricsService{GetMetrics2: ErrUnknown},
+			params:                "?code=1458-buy",
+			wantStatusCode:        http.StatusInternalServerError,
+			wantBody:              ErrUnknown.Error(),
+			wantGetMetricsHistory: []interface{}{"1458-buy", time.Time{}, time.Date(9999, 12, 31, 0, 0, 0, 0, time.Local)}},
+		{name: "戦略コードがなければ全ての戦略の成績を返す",
+			strategyStore:  &testStrategyStore{GetStrategies1: []*Strategy{{Code: "1458-buy"}, {Code: "1458-sell"}}},
+			metricsService: &testMetricsService{GetMetrics1: &StrategyMetrics{StrategyCode: "1458-buy", Days: 1, StartEquity: 1000, EndEquity: 1000}},
+			params:         "?from=2022-01-24&to=2022-01-24",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `[{"StrategyCode":"1458-buy","From":"0001-01-01T00:00:00Z","To":"0001-01-01T00:00:00Z","Days":1,"StartEquity":1000,"EndEquity":1000,"Return":0,"MaxDrawdown":0,"MaxDrawdownRate":0,"Sharpe":0,"Sortino":0,"TradeCount":0,"WinRate":0,"AverageProfit":0},{"StrategyCode":"1458-buy","From":"0001-01-01T00:00:00Z","To":"0001-01-01T00:00:00Z","Days":1,"StartEquity":1000,"EndEquity":1000,"Return":0,"MaxDrawdown":0,"MaxDrawdownRate":0,"Sharpe":0,"Sortino":0,"TradeCount":0,"WinRate":0,"AverageProfit":0}]`,
+			wantGetMetricsHistory: []interface{}{
+				"1458-buy", time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local),
+				"1458-sell", time.Date(2022, 1, 24, 0, 0, 0, 0, time.Local), time.Date(2022, 1, 25, 0, 0, 0, 0, time.Local),
+			}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &webService{strategyStore: test.strategyStore, metricsService: test.metricsService}
+			ts := httptest.NewServer(http.HandlerFunc(service.getMetrics))
+			defer ts.Close()
+
+			res, err := http.Get(fmt.Sprintf("%s%s", ts.URL, test.params))
+			if err != nil {
+				t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("%s read body error\nerr: %+v\n", t.Name(), err)
+			}
+			strBody := strings.Trim(string(body), "\n")
+
+			if !reflect.DeepEqual(test.wantStatusCode, res.StatusCode) ||
+				!reflect.DeepEqual(test.wantBody, strBody) ||
+				!reflect.DeepEqual(test.wantGetMetricsHistory, test.metricsService.GetMetricsHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %v\ngot: %+v, %+v, %v\n", t.Name(),
+					test.wantStatusCode, test.wantBody, test.wantGetMetricsHistory,
+					res.StatusCode, strBody, test.metricsService.GetMetricsHistory)
+			}
+		})
+	}
+}
+
+func Test_webService_getReconciliations(t *testing.T) {
+	t.Parallel()
+	reconciliationService := &testReconciliationService{GetReports1: []*ReconciliationReport{
+		{StrategyCode: "1458-buy", DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Mismatches: []PositionMismatch{
+			{PositionCode: "position-code-001", MismatchType: PositionMismatchTypeQuantity, LocalQuantity: 2, SecurityQuantity: 1, LocalPrice: 1000, SecurityPrice: 1000}}},
+	}}
+	service := &webService{reconciliationService: reconciliationService}
+	ts := httptest.NewServer(http.HandlerFunc(service.getReconciliations))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("%s read body error\nerr: %+v\n", t.Name(), err)
+	}
+
+	wantBody := `[{"StrategyCode":"1458-buy","DateTime":"2022-01-25T09:00:00+09:00","Skipped":false,"Mismatches":[{"PositionCode":"position-code-001","MismatchType":"quantity","LocalQuantity":2,"SecurityQuantity":1,"LocalPrice":1000,"SecurityPrice":1000}],"Repaired":false,"Error":""}]`
+	if !reflect.DeepEqual(http.StatusOK, res.StatusCode) || !reflect.DeepEqual(wantBody, strings.Trim(string(body), "\n")) {
+		t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), http.StatusOK, wantBody, res.StatusCode, string(body))
+	}
+}
+
+func Test_webService_postReconcile(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                  string
+		strategyStore         *testStrategyStore
+		reconciliationService *testReconciliationService
+		params                string
+		wantStatusCode        int
+		wantBody              string
+		wantReconcileHistory  []interface{}
+	}{
+		{name: "戦略が取得できなければエラー",
+			strategyStore:         &testStrategyStore{GetByCode2: ErrNoData},
+			reconciliationService: &testReconciliationService{},
+			params:                "?code=1458-buy",
+			wantStatusCode:        http.StatusBadRequest,
+			wantBody:              ErrNoData.Error()},
+		{name: "照合に失敗したらエラー",
+			strategyStore:         &testStrategyStore{GetByCode1: &Strategy{Code: "1458-buy"}},
+			reconciliationService: &testReconciliationService{Reconcile2: ErrUnknown},
+			params:                "?code=1458-buy",
+			wantStatusCode:        http.StatusInternalServerError,
+			wantBody:              ErrUnknown.Error(),
+			wantReconcileHistory:  []interface{}{&Strategy{Code: "1458-buy"}, false}},
+		{name: "repair=trueなら修復を指定して照合し、結果を返す",
+			strategyStore:         &testStrategyStore{GetByCode1: &Strategy{Code: "1458-buy"}},
+			reconciliationService: &testReconciliationService{Reconcile1: &ReconciliationReport{StrategyCode: "1458-buy", DateTime: time.Date(2022, 1, 25, 9, 0, 0, 0, time.Local), Mismatches: []PositionMismatch{}, Repaired: false}},
+			params:                "?code=1458-buy&repair=true",
+			wantStatusCode:        http.StatusOK,
+			wantBody:              `{"StrategyCode":"1458-buy","DateTime":"2022-01-25T09:00:00+09:00","Skipped":false,"Mismatches":[],"Repaired":false,"Error":""}`,
+			wantReconcileHistory:  []interface{}{&Strategy{Code: "1458-buy"}, true}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &webService{strategyStore: test.strategyStore, reconciliationService: test.reconciliationService}
+			ts := httptest.NewServer(http.HandlerFunc(service.postReconcile))
+			defer ts.Close()
+
+			res, err := http.Post(fmt.Sprintf("%s%s", ts.URL, test.params), "application/json", nil)
+			if err != nil {
+				t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("%s read body error\nerr: %+v\n", t.Name(), err)
+			}
+			strBody := strings.Trim(string(body), "\n")
+
+			if !reflect.DeepEqual(test.wantStatusCode, res.StatusCode) ||
+				!reflect.DeepEqual(test.wantBody, strBody) ||
+				!reflect.DeepEqual(test.wantReconcileHistory, test.reconciliationService.ReconcileHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %v\ngot: %+v, %+v, %v\n", t.Name(),
+					test.wantStatusCode, test.wantBody, test.wantReconcileHistory,
+					res.StatusCode, strBody, test.reconciliationService.ReconcileHistory)
+			}
+		})
+	}
+}
+
+func Test_webService_getRateLimits(t *testing.T) {
+	t.Parallel()
+	rateLimiter := &testRateLimiter{GetMetrics1: []RateLimitMetrics{
+		{Category: RequestCategoryOrder, RatePerSecond: 5, Requests: 10, Throttled: 3, Waiting: 1, TotalWaitTime: 600 * time.Millisecond, MaxWaitTime: 400 * time.Millisecond},
+		{Category: RequestCategoryInfo, RatePerSecond: 10, Requests: 20},
+	}}
+	service := &webService{rateLimiter: rateLimiter}
+	ts := httptest.NewServer(http.HandlerFunc(service.getRateLimits))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("%s read body error\nerr: %+v\n", t.Name(), err)
+	}
+
+	wantBody := `[{"Category":"order","RatePerSecond":5,"Requests":10,"Throttled":3,"Waiting":1,"TotalWaitTime":600000000,"MaxWaitTime":400000000},{"Category":"info","RatePerSecond":10,"Requests":20,"Throttled":0,"Waiting":0,"TotalWaitTime":0,"MaxWaitTime":0}]`
+	if !reflect.DeepEqual(http.StatusOK, res.StatusCode) || !reflect.DeepEqual(wantBody, strings.Trim(string(body), "\n")) {
+		t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), http.StatusOK, wantBody, res.StatusCode, string(body))
+	}
+}
+
+func Test_webService_getRiskStatus(t *testing.T) {
+	t.Parallel()
+	riskManager := &testRiskManager{GetStatus1: RiskStatus{
+		KillSwitch:        true,
+		KillReason:        "manual",
+		KilledDateTime:    time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local),
+		Limit:             RiskLimit{MaxDailyLoss: 10000},
+		DailyProfit:       -12000,
+		StrategyProfits:   map[string]float64{"1458-buy": -12000},
+		EvaluatedDateTime: time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local),
+	}}
+	service := &webService{riskManager: riskManager}
+	ts := httptest.NewServer(http.HandlerFunc(service.getRiskStatus))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("%s read body error\nerr: %+v\n", t.Name(), err)
+	}
+
+	wantBody := `{"KillSwitch":true,"KillReason":"manual","KilledDateTime":"2022-02-01T10:00:00+09:00","Limit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":10000},"DailyProfit":-12000,"StrategyProfits":{"1458-buy":-12000},"EvaluatedDateTime":"2022-02-01T10:00:00+09:00"}`
+	if !reflect.DeepEqual(http.StatusOK, res.StatusCode) || !reflect.DeepEqual(wantBody, strings.Trim(string(body), "\n")) {
+		t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), http.StatusOK, wantBody, res.StatusCode, string(body))
+	}
+}
+
+func Test_webService_postKillSwitch(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                     string
+		riskManager              *testRiskManager
+		params                   string
+		wantStatusCode           int
+		wantBody                 string
+		wantSetKillSwitchHistory []interface{}
+	}{
+		{name: "onの指定がなければエラー",
+			riskManager:    &testRiskManager{},
+			params:         "",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "on must be true or false"},
+		{name: "onがtrueでもfalseでもなければエラー",
+			riskManager:    &testRiskManager{},
+			params:         "?on=1",
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       "on must be true or false"},
+		{name: "キルスイッチの状態の保存に失敗したらエラー",
+			riskManager:              &testRiskManager{SetKillSwitch1: ErrUnknown},
+			params:                   "?on=true&reason=manual",
+			wantStatusCode:           http.StatusInternalServerError,
+			wantBody:                 "unknown",
+			wantSetKillSwitchHistory: []interface{}{true, "manual"}},
+		{name: "on=trueならキルスイッチを入れて状態を返す",
+			riskManager:              &testRiskManager{},
+			params:                   "?on=true&reason=manual",
+			wantStatusCode:           http.StatusOK,
+			wantBody:                 `{"KillSwitch":false,"KillReason":"","KilledDateTime":"0001-01-01T00:00:00Z","Limit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":0},"DailyProfit":0,"StrategyProfits":null,"EvaluatedDateTime":"0001-01-01T00:00:00Z"}`,
+			wantSetKillSwitchHistory: []interface{}{true, "manual"}},
+		{name: "on=falseならキルスイッチを切って状態を返す",
+			riskManager:              &testRiskManager{},
+			params:                   "?on=false",
+			wantStatusCode:           http.StatusOK,
+			wantBody:                 `{"KillSwitch":false,"KillReason":"","KilledDateTime":"0001-01-01T00:00:00Z","Limit":{"MaxGrossExposure":0,"MaxOpenOrders":0,"MaxOrdersPerMinute":0,"MaxDailyLoss":0},"DailyProfit":0,"StrategyProfits":null,"EvaluatedDateTime":"0001-01-01T00:00:00Z"}`,
+			wantSetKillSwitchHistory: []interface{}{false, ""}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			riskManager := test.riskManager
+			service := &webService{riskManager: riskManager}
+			ts := httptest.NewServer(http.HandlerFunc(service.postKillSwitch))
+			defer ts.Close()
+
+			res, err := http.Post(fmt.Sprintf("%s%s", ts.URL, test.params), "application/json", nil)
+			if err != nil {
+				t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("%s read body error\nerr: %+v\n", t.Name(), err)
+			}
+			strBody := strings.Trim(string(body), "\n")
+
+			if !reflect.DeepEqual(test.wantStatusCode, res.StatusCode) ||
+				!reflect.DeepEqual(test.wantBody, strBody) ||
+				!reflect.DeepEqual(test.wantSetKillSwitchHistory, riskManager.SetKillSwitchHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v, %v\ngot: %+v, %+v, %v\n", t.Name(),
+					test.wantStatusCode, test.wantBody, test.wantSetKillSwitchHistory,
+					res.StatusCode, strBody, riskManager.SetKillSwitchHistory)
+			}
+		})
+	}
+}
+
+func Test_webService_postRiskLimit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                string
+		riskManager         *testRiskManager
+		body                string
+		wantStatusCode      int
+		wantSetLimitHistory []RiskLimit
+	}{
+		{name: "bodyがjsonでなければエラー",
+			riskManager:    &testRiskManager{},
+			body:           "foo",
+			wantStatusCode: http.StatusBadRequest},
+		{name: "上限の保存に失敗したらエラー",
+			riskManager:         &testRiskManager{SetLimit1: ErrUnknown},
+			body:                `{"MaxDailyLoss":30000}`,
+			wantStatusCode:      http.StatusInternalServerError,
+			wantSetLimitHistory: []RiskLimit{{MaxDailyLoss: 30_000}}},
+		{name: "bodyの上限を設定して状態を返す",
+			riskManager:         &testRiskManager{},
+			body:                `{"MaxGrossExposure":1000000,"MaxOpenOrders":20,"MaxOrdersPerMinute":10,"MaxDailyLoss":30000}`,
+			wantStatusCode:      http.StatusOK,
+			wantSetLimitHistory: []RiskLimit{{MaxGrossExposure: 1_000_000, MaxOpenOrders: 20, MaxOrdersPerMinute: 10, MaxDailyLoss: 30_000}}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			riskManager := test.riskManager
+			service := &webService{riskManager: riskManager}
+			ts := httptest.NewServer(http.HandlerFunc(service.postRiskLimit))
+			defer ts.Close()
+
+			res, err := http.Post(ts.URL, "application/json", strings.NewReader(test.body))
+			if err != nil {
+				t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+			}
+			defer res.Body.Close()
+
+			if !reflect.DeepEqual(test.wantStatusCode, res.StatusCode) ||
+				!reflect.DeepEqual(test.wantSetLimitHistory, riskManager.SetLimitHistory) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(),
+					test.wantStatusCode, test.wantSetLimitHistory,
+					res.StatusCode, riskManager.SetLimitHistory)
+			}
+		})
+	}
+}
+
+func Test_webService_getCircuitBreakers(t *testing.T) {
+	t.Parallel()
+	circuitBreaker := &testCircuitBreaker{GetStates1: []CircuitBreakerState{
+		{
+			StrategyCode:   "1458-buy",
+			State:          CircuitStateOpen,
+			Failures:       map[string]int{"can not get base price": 5},
+			Reason:         "can not get base price x5: can not get base price",
+			OpenedDateTime: time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local),
+			HalfOpenAt:     time.Date(2022, 2, 1, 10, 5, 0, 0, time.Local),
+		},
+		{StrategyCode: "1459-sell", State: CircuitStateClosed, Failures: map[string]int{}},
+	}}
+	service := &webService{circuitBreaker: circuitBreaker}
+	ts := httptest.NewServer(http.HandlerFunc(service.getCircuitBreakers))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("%s read body error\nerr: %+v\n", t.Name(), err)
+	}
+
+	wantBody := `[{"StrategyCode":"1458-buy","State":"open","Failures":{"can not get base price":5},"Operation":"","Reason":"can not get base price x5: can not get base price","OpenedDateTime":"2022-02-01T10:00:00+09:00","HalfOpenAt":"2022-02-01T10:05:00+09:00"},{"StrategyCode":"1459-sell","State":"closed","Failures":{},"Operation":"","Reason":"","OpenedDateTime":"0001-01-01T00:00:00Z","HalfOpenAt":"0001-01-01T00:00:00Z"}]`
+	if !reflect.DeepEqual(http.StatusOK, res.StatusCode) || !reflect.DeepEqual(wantBody, strings.Trim(string(body), "\n")) {
+		t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), http.StatusOK, wantBody, res.StatusCode, string(body))
+	}
+}
+
+func Test_webService_getPriceBands(t *testing.T) {
+	t.Parallel()
+	strategies := []*Strategy{
+		{Code: "1458-buy",
+			GridStrategy:   GridStrategy{PriceBand: PriceBand{Valid: true, Source: PriceBandSourceAbsolute, Upper: 2200, Lower: 1800, Policy: PriceBandPolicyCancelEntries}},
+			PriceBandState: PriceBandState{Lower: 1800, Upper: 2200, OutOfBand: true, ChangedDateTime: time.Date(2022, 2, 1, 10, 0, 0, 0, time.Local)}},
+		{Code: "1458-sell",
+			GridStrategy:   GridStrategy{PriceBand: PriceBand{Valid: true, Source: PriceBandSourcePrevClose, Upper: 0.05, Lower: 0.05}},
+			PriceBandState: PriceBandState{Lower: 1900, Upper: 2100}},
+		{Code: "1459-buy"},
+	}
+	tests := []struct {
+		name           string
+		strategyStore  *testStrategyStore
+		params         string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{name: "戦略一覧の取得に失敗したらエラー",
+			strategyStore:  &testStrategyStore{GetStrategies2: ErrUnknown},
+			params:         "",
+			wantStatusCode: http.StatusInternalServerError,
+			wantBody:       ErrUnknown.Error()},
+		{name: "価格帯が有効な戦略の価格帯の状態を返す",
+			strategyStore:  &testStrategyStore{GetStrategies1: strategies},
+			params:         "",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `[{"StrategyCode":"1458-buy","PriceBand":{"Valid":true,"Source":"absolute","Upper":2200,"Lower":1800,"Days":0,"Policy":"cancel_entries"},"State":{"Lower":1800,"Upper":2200,"OutOfBand":true,"ChangedDateTime":"2022-02-01T10:00:00+09:00"}},{"StrategyCode":"1458-sell","PriceBand":{"Valid":true,"Source":"prev_close","Upper":0.05,"Lower":0.05,"Days":0,"Policy":""},"State":{"Lower":1900,"Upper":2100,"OutOfBand":false,"ChangedDateTime":"0001-01-01T00:00:00Z"}}]`},
+		{name: "outOfBand=trueなら価格帯の外にある戦略だけを返す",
+			strategyStore:  &testStrategyStore{GetStrategies1: strategies},
+			params:         "?outOfBand=true",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `[{"StrategyCode":"1458-buy","PriceBand":{"Valid":true,"Source":"absolute","Upper":2200,"Lower":1800,"Days":0,"Policy":"cancel_entries"},"State":{"Lower":1800,"Upper":2200,"OutOfBand":true,"ChangedDateTime":"2022-02-01T10:00:00+09:00"}}]`},
+		{name: "価格帯が有効な戦略がなければ空配列を返す",
+			strategyStore:  &testStrategyStore{GetStrategies1: []*Strategy{{Code: "1459-buy"}}},
+			params:         "",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `[]`},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &webService{strategyStore: test.strategyStore}
+			ts := httptest.NewServer(http.HandlerFunc(service.getPriceBands))
+			defer ts.Close()
+
+			res, err := http.Get(fmt.Sprintf("%s%s", ts.URL, test.params))
+			if err != nil {
+				t.Errorf("%s request error\nerr: %+v\n", t.Name(), err)
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("%s read body error\nerr: %+v\n", t.Name(), err)
+			}
+			strBody := strings.Trim(string(body), "\n")
+
+			if !reflect.DeepEqual(test.wantStatusCode, res.StatusCode) || !reflect.DeepEqual(test.wantBody, strBody) {
+				t.Errorf("%s error\nwant: %+v, %+v\ngot: %+v, %+v\n", t.Name(), test.wantStatusCode, test.wantBody, res.StatusCode, strBody)
+			}
+		})
+	}
+}